@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/daioru/todo-app/internal/config"
 	"github.com/daioru/todo-app/internal/handlers"
 	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/daioru/todo-app/internal/pkg/clock"
 	"github.com/daioru/todo-app/internal/pkg/db"
+	"github.com/daioru/todo-app/internal/pkg/password"
 	"github.com/daioru/todo-app/internal/repository"
 	"github.com/daioru/todo-app/internal/services"
 	"github.com/gin-gonic/gin"
@@ -48,6 +55,20 @@ func main() {
 	//Repositories
 	userRepo := repository.NewUserRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	digestRepo := repository.NewDigestRepository(db)
+	accessTokenRepo := repository.NewAccessTokenRepository(db)
 
 	//JWT
 	err = godotenv.Load()
@@ -60,15 +81,132 @@ func main() {
 		log.Fatal().Msg("No jwtSecret in .env")
 	}
 
+	//Mailer
+	if smtpPassword := os.Getenv("SMTPPASSWORD"); smtpPassword != "" {
+		cfg.Mail.SMTP.Password = smtpPassword
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatal().Msgf("Failed to init mailer: %v", err)
+	}
+
+	//Inbound mail
+	if inboundSecret := os.Getenv("INBOUNDSECRET"); inboundSecret != "" {
+		cfg.Inbound.Secret = inboundSecret
+	}
+
+	//Chat commands
+	if chatSecret := os.Getenv("CHATSECRET"); chatSecret != "" {
+		cfg.Chat.SigningSecret = chatSecret
+	}
+
+	//OIDC providers
+	var oidcProviders []services.OIDCProvider
+	for _, p := range cfg.OIDC.Providers {
+		if secret := os.Getenv("OIDC_" + strings.ToUpper(p.Name) + "_CLIENTSECRET"); secret != "" {
+			p.ClientSecret = secret
+		}
+		oidcProviders = append(oidcProviders, services.OIDCProvider{Client: oidc.NewProvider(p), Settings: p})
+	}
+
 	//Services
-	authService := services.NewAuthService(userRepo)
-	taskService := services.NewTaskService(taskRepo)
+	mfaService := services.NewMFAService(mfaRepo, userRepo, cfg.Auth.MFAIssuer, clock.New())
+	throttleService := services.NewLoginThrottleService(loginAttemptRepo, auditRepo, cfg.Auth.Lockout, clock.New())
+	hasher := password.NewHasher(cfg.Auth.PasswordHashing)
+	accountService := services.NewAccountService(userRepo, tokenRepo, mail, hasher, cfg.Auth.PasswordPolicy, cfg.Mail, clock.New())
+	authService := services.NewAuthService(userRepo, mfaService, throttleService, hasher, cfg.Auth.PasswordPolicy, accountService)
+	oidcService := services.NewOIDCService(oidcProviders, identityRepo, userRepo, authService, cfg.OIDC.StateTTL, clock.New())
+	notificationService := services.NewNotificationService(notificationRepo, clock.New())
+	eventService := services.NewEventService(eventRepo, workspaceRepo, cfg.Events, clock.New())
+	webhookService := services.NewWebhookService(webhookRepo, cfg.Webhooks, clock.New())
+	taskService := services.NewTaskService(taskRepo, workspaceRepo, notificationService)
+	outboxRelay := services.NewOutboxRelay(outboxRepo, cfg.Outbox, clock.New())
+	outboxRelay.Register("task_events", eventService.HandleOutbox)
+	outboxRelay.Register("webhooks", webhookService.HandleOutbox)
+	outboxRelay.Register("status_notifications", taskService.HandleOutbox)
+	reminderService := services.NewReminderService(reminderRepo, taskRepo, services.ReminderChannels{
+		models.ReminderChannelInApp:   notificationService,
+		models.ReminderChannelEmail:   services.NewEmailReminderChannel(userRepo, mail),
+		models.ReminderChannelWebhook: webhookService,
+	}, cfg.Reminders, clock.New())
+	digestService := services.NewDigestService(digestRepo, userRepo, notificationService, mail, cfg.Digest, clock.New())
+	transferService := services.NewTaskTransferService(taskRepo, workspaceRepo, userRepo, cfg.Import)
+	appImportService := services.NewAppImportService(taskRepo, workspaceRepo, cfg.Import)
+	calendarService := services.NewCalendarService(tokenRepo, taskRepo, cfg.Calendar, clock.New())
+	inboundMailService := services.NewInboundMailService(tokenRepo, taskService, taskRepo, cfg.Inbound, clock.New())
+	chatService := services.NewChatService(tokenRepo, identityRepo, userRepo, taskService, cfg.Chat, clock.New())
+	quickAddService := services.NewQuickAddService(userRepo, taskService, clock.New())
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo, userRepo, clock.New())
+	caldavService := services.NewCalDAVService(taskService, workspaceRepo, taskRepo)
+	workspaceService := services.NewWorkspaceService(workspaceRepo, userRepo, clock.New())
+	presenceService := services.NewPresenceService(userRepo)
+	commentService := services.NewCommentService(commentRepo, taskRepo, userRepo, clock.New())
+	profileService := services.NewProfileService(userRepo, workspaceRepo, mfaService, hasher, throttleService)
+	exportService := services.NewExportService(userRepo, taskRepo, identityRepo, mfaRepo, auditRepo, workspaceRepo, commentRepo, notificationRepo, webhookRepo, reminderRepo, digestRepo, accessTokenRepo, tokenRepo, clock.New())
 
 	//Handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, cfg.OIDC.SuccessURL, int(cfg.OIDC.StateTTL.Seconds()))
+	profileHandler := handlers.NewProfileHandler(profileService, exportService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	eventHandler := handlers.NewEventHandler(eventService, cfg.Events.Heartbeat)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	reminderHandler := handlers.NewReminderHandler(reminderService)
+	digestHandler := handlers.NewDigestHandler(digestService)
+	transferHandler := handlers.NewTaskTransferHandler(transferService, cfg.Import.MaxBytes)
+	appImportHandler := handlers.NewAppImportHandler(appImportService, cfg.Import.MaxBytes)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	inboundMailHandler := handlers.NewInboundMailHandler(inboundMailService, cfg.Inbound.Secret, cfg.Inbound.MaxBytes)
+	inboundSMTPServer := handlers.NewInboundSMTPServer(inboundMailService, cfg.Inbound.SMTP, cfg.Inbound.MaxBytes)
+	chatHandler := handlers.NewChatHandler(chatService)
+	quickAddHandler := handlers.NewQuickAddHandler(quickAddService)
+	accessTokenHandler := handlers.NewAccessTokenHandler(accessTokenService)
+	caldavHandler := handlers.NewCalDAVHandler(caldavService)
+	wsHandler := handlers.NewWSHandler(taskService, workspaceService, eventService, presenceService, cfg.WebSocket)
 
-	handlers := handlers.NewHandlers(authHandler, taskHandler)
+	handlers := handlers.NewHandlers(
+		authHandler,
+		taskHandler,
+		mfaHandler,
+		accountHandler,
+		oidcHandler,
+		profileHandler,
+		workspaceHandler,
+		commentHandler,
+		notificationHandler,
+		eventHandler,
+		wsHandler,
+		webhookHandler,
+		reminderHandler,
+		digestHandler,
+		transferHandler,
+		calendarHandler,
+		accessTokenHandler,
+		caldavHandler,
+		appImportHandler,
+		inboundMailHandler,
+		chatHandler,
+		quickAddHandler,
+	)
+
+	//Background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go eventService.Run(ctx)
+	go webhookService.Run(ctx)
+	go outboxRelay.Run(ctx)
+	go reminderService.Run(ctx)
+	go digestService.Run(ctx)
+	if cfg.Inbound.SMTP.Enabled {
+		go inboundSMTPServer.Run(ctx)
+	}
 
 	//Server
 	gin.SetMode(gin.ReleaseMode)
@@ -56,8 +56,180 @@ func ReadConfigYML(configYML string) error {
 	return nil
 }
 
+type Lockout struct {
+	MaxAttempts     int           `yaml:"maxAttempts"`
+	IPMaxAttempts   int           `yaml:"ipMaxAttempts"`
+	BaseDelay       time.Duration `yaml:"baseDelay"`
+	MaxDelay        time.Duration `yaml:"maxDelay"`
+	LockoutDuration time.Duration `yaml:"lockoutDuration"`
+	ResetAfter      time.Duration `yaml:"resetAfter"`
+}
+
+type PasswordPolicy struct {
+	MinLength      int  `yaml:"minLength"`
+	MaxLength      int  `yaml:"maxLength"`
+	RequireUpper   bool `yaml:"requireUpper"`
+	RequireLower   bool `yaml:"requireLower"`
+	RequireDigit   bool `yaml:"requireDigit"`
+	RequireSymbol  bool `yaml:"requireSymbol"`
+	RejectUsername bool `yaml:"rejectUsername"`
+	RejectCommon   bool `yaml:"rejectCommon"`
+}
+
+type Argon2 struct {
+	Time    uint32 `yaml:"time"`
+	Memory  uint32 `yaml:"memory"`
+	Threads uint8  `yaml:"threads"`
+}
+
+type PasswordHashing struct {
+	Algorithm  string `yaml:"algorithm"`
+	BcryptCost int    `yaml:"bcryptCost"`
+	Argon2     Argon2 `yaml:"argon2"`
+}
+
+type Auth struct {
+	MFAIssuer       string          `yaml:"mfaIssuer"`
+	Lockout         Lockout         `yaml:"lockout"`
+	PasswordPolicy  PasswordPolicy  `yaml:"passwordPolicy"`
+	PasswordHashing PasswordHashing `yaml:"passwordHashing"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type Mail struct {
+	Driver          string        `yaml:"driver"`
+	From            string        `yaml:"from"`
+	Dir             string        `yaml:"dir"`
+	SMTP            SMTP          `yaml:"smtp"`
+	VerifyURL       string        `yaml:"verifyURL"`
+	ResetURL        string        `yaml:"resetURL"`
+	VerificationTTL time.Duration `yaml:"verificationTTL"`
+	ResetTTL        time.Duration `yaml:"resetTTL"`
+}
+
+type OIDCProvider struct {
+	Name           string   `yaml:"name"`
+	Issuer         string   `yaml:"issuer"`
+	ClientID       string   `yaml:"clientID"`
+	ClientSecret   string   `yaml:"clientSecret"`
+	RedirectURL    string   `yaml:"redirectURL"`
+	Scopes         []string `yaml:"scopes"`
+	AutoProvision  bool     `yaml:"autoProvision"`
+	AllowedDomains []string `yaml:"allowedDomains"`
+}
+
+type OIDC struct {
+	Providers  []OIDCProvider `yaml:"providers"`
+	StateTTL   time.Duration  `yaml:"stateTTL"`
+	SuccessURL string         `yaml:"successURL"`
+}
+
+type Events struct {
+	Retention        time.Duration `yaml:"retention"`
+	Heartbeat        time.Duration `yaml:"heartbeat"`
+	ReplayLimit      int           `yaml:"replayLimit"`
+	SubscriberBuffer int           `yaml:"subscriberBuffer"`
+}
+
+type WebSocket struct {
+	SendBuffer     int           `yaml:"sendBuffer"`
+	MaxMessageSize int64         `yaml:"maxMessageSize"`
+	PingInterval   time.Duration `yaml:"pingInterval"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+}
+
+type Webhooks struct {
+	Timeout        time.Duration `yaml:"timeout"`
+	PollInterval   time.Duration `yaml:"pollInterval"`
+	BatchSize      int           `yaml:"batchSize"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	RetryBaseDelay time.Duration `yaml:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `yaml:"retryMaxDelay"`
+	DisableAfter   int           `yaml:"disableAfter"`
+	// AllowPrivateNetworks lets webhooks reach loopback and private
+	// addresses, for local development only
+	AllowPrivateNetworks bool `yaml:"allowPrivateNetworks"`
+}
+
+type Outbox struct {
+	PollInterval   time.Duration `yaml:"pollInterval"`
+	BatchSize      int           `yaml:"batchSize"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	RetryBaseDelay time.Duration `yaml:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `yaml:"retryMaxDelay"`
+	Retention      time.Duration `yaml:"retention"`
+}
+
+type Reminders struct {
+	PollInterval time.Duration `yaml:"pollInterval"`
+	BatchSize    int           `yaml:"batchSize"`
+	MaxSnooze    time.Duration `yaml:"maxSnooze"`
+}
+
+type Digest struct {
+	PollInterval    time.Duration `yaml:"pollInterval"`
+	BatchSize       int           `yaml:"batchSize"`
+	DefaultSendTime string        `yaml:"defaultSendTime"`
+}
+
+type Import struct {
+	MaxBytes  int64 `yaml:"maxBytes"`
+	MaxRows   int   `yaml:"maxRows"`
+	BatchSize int   `yaml:"batchSize"`
+}
+
+type Calendar struct {
+	FeedURL  string        `yaml:"feedURL"`
+	TokenTTL time.Duration `yaml:"tokenTTL"`
+}
+
+type InboundSMTP struct {
+	Enabled        bool          `yaml:"enabled"`
+	Addr           string        `yaml:"addr"`
+	Hostname       string        `yaml:"hostname"`
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxRecipients  int           `yaml:"maxRecipients"`
+	MaxConnections int           `yaml:"maxConnections"`
+}
+
+type Inbound struct {
+	Domain     string        `yaml:"domain"`
+	LocalPart  string        `yaml:"localPart"`
+	AddressTTL time.Duration `yaml:"addressTTL"`
+	MaxBytes   int64         `yaml:"maxBytes"`
+	Secret     string        `yaml:"secret"`
+	SMTP       InboundSMTP   `yaml:"smtp"`
+}
+
+type Chat struct {
+	SigningSecret string        `yaml:"signingSecret"`
+	Command       string        `yaml:"command"`
+	MaxSkew       time.Duration `yaml:"maxSkew"`
+	LinkTTL       time.Duration `yaml:"linkTTL"`
+	ListLimit     int           `yaml:"listLimit"`
+}
+
 type Config struct {
-	DB DB `yaml:"db"`
+	DB        DB        `yaml:"db"`
+	Auth      Auth      `yaml:"auth"`
+	Mail      Mail      `yaml:"mail"`
+	OIDC      OIDC      `yaml:"oidc"`
+	Events    Events    `yaml:"events"`
+	WebSocket WebSocket `yaml:"websocket"`
+	Webhooks  Webhooks  `yaml:"webhooks"`
+	Outbox    Outbox    `yaml:"outbox"`
+	Reminders Reminders `yaml:"reminders"`
+	Digest    Digest    `yaml:"digest"`
+	Import    Import    `yaml:"import"`
+	Calendar  Calendar  `yaml:"calendar"`
+	Inbound   Inbound   `yaml:"inbound"`
+	Chat      Chat      `yaml:"chat"`
 }
 
 func GetConfigInstance() Config {
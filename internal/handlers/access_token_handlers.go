@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IAccessTokenService interface {
+	CreateToken(userID int, name string) (*models.PersonalAccessToken, error)
+	ListTokens(userID int) ([]models.PersonalAccessToken, error)
+	DeleteToken(id, userID int) error
+	Authenticate(username, token string) (*models.User, error)
+}
+
+type AccessTokenHandler struct {
+	service IAccessTokenService
+}
+
+func NewAccessTokenHandler(service IAccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{service: service}
+}
+
+// @Summary CreateAccessToken
+// @Description issue a personal access token for clients such as CalDAV apps, the token is only returned here
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags me
+// @Param input body models.AccessTokenRequest true "token name"
+// @Success 201 {object} models.PersonalAccessToken
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/tokens [post]
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	var req models.AccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	token, err := h.service.CreateToken(c.GetInt("user_id"), req.Name)
+	if err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// @Summary ListAccessTokens
+// @Description list the personal access tokens of the current user without their values
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 200 {array} models.PersonalAccessToken
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/tokens [get]
+func (h *AccessTokenHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.service.ListTokens(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary DeleteAccessToken
+// @Description revoke personal access token with {id}
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Param id path int true "Token ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/tokens/{id} [delete]
+func (h *AccessTokenHandler) DeleteToken(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteToken(id, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, services.ErrAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token deleted successfully"})
+}
+
+// BasicAuth authenticates requests with the username and a personal access
+// token as password, for clients that cannot keep a session cookie
+func (h *AccessTokenHandler) BasicAuth(realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, token, ok := c.Request.BasicAuth()
+		if ok {
+			user, err := h.service.Authenticate(username, token)
+			switch {
+			case err == nil:
+				c.Set("user_id", user.ID)
+				c.Next()
+				return
+			case !errors.Is(err, services.ErrInvalidCredentials):
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenService) CreateToken(userID int, name string) (*models.PersonalAccessToken, error) {
+	args := m.Called(userID, name)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenService) ListTokens(userID int) ([]models.PersonalAccessToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenService) DeleteToken(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenService) Authenticate(username, token string) (*models.User, error) {
+	args := m.Called(username, token)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func TestCreateAccessTokenHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAccessTokenService)
+		handler := handlers.NewAccessTokenHandler(mockService)
+
+		createdAt := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+		mockService.On("CreateToken", 1, "Phone").Return(&models.PersonalAccessToken{
+			ID: 3, UserID: 1, Name: "Phone", TokenHash: "hash", Token: "tdp_secret", CreatedAt: createdAt,
+		}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/me/tokens", `{"name":"Phone"}`, nil)
+		handler.CreateToken(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"id": 3, "name": "Phone", "token": "tdp_secret", "created_at": "2026-10-19T08:00:00Z"}`, w.Body.String())
+	})
+
+	t.Run("Invalid name", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAccessTokenService)
+		handler := handlers.NewAccessTokenHandler(mockService)
+
+		mockService.On("CreateToken", 1, "x").Return((*models.PersonalAccessToken)(nil),
+			fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("name", "too short")))
+
+		c, w := newWorkspaceContext(http.MethodPost, "/me/tokens", `{"name":"x"}`, nil)
+		handler.CreateToken(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestDeleteAccessTokenHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"Not found", services.ErrAccessTokenNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockAccessTokenService)
+			handler := handlers.NewAccessTokenHandler(mockService)
+
+			mockService.On("DeleteToken", 3, 1).Return(tt.serviceErr)
+
+			c, w := newWorkspaceContext(http.MethodDelete, "/me/tokens/3", "", gin.Params{{Key: "id", Value: "3"}})
+			handler.DeleteToken(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockAccessTokenService)
+	handler := handlers.NewAccessTokenHandler(mockService)
+	mockService.On("Authenticate", "alice", "tdp_secret").Return(&models.User{ID: 1, Username: "alice"}, nil)
+
+	r := gin.New()
+	r.GET("/", handler.BasicAuth("todo-app"), func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", c.GetInt("user_id"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "tdp_secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+}
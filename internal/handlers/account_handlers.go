@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IAccountService interface {
+	SetEmail(userID int, email string) error
+	ResendVerification(userID int) error
+	VerifyEmail(token string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+}
+
+type AccountHandler struct {
+	service IAccountService
+}
+
+func NewAccountHandler(service IAccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// @Summary SetEmail
+// @Description set or change email of the current user and send a verification link
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags account
+// @Param input body EmailData true "email address"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/email [post]
+func (h *AccountHandler) SetEmail(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.SetEmail(c.GetInt("user_id"), req.Email); err != nil {
+		if errors.Is(err, repository.ErrUniqueEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email already in use"})
+			return
+		}
+
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// @Summary ResendVerification
+// @Description send a new email verification link
+// @Security Auth
+// @Produce  json
+// @Tags account
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/email/resend [post]
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	if err := h.service.ResendVerification(c.GetInt("user_id")); err != nil {
+		if errors.Is(err, services.ErrNoEmail) || errors.Is(err, services.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// @Summary VerifyEmail
+// @Description confirm email address with the token from the verification link
+// @Produce  json
+// @Tags account
+// @Param token query string true "verification token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/email/verify [get]
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.VerifyEmail(token); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// @Summary ForgotPassword
+// @Description send a password reset link if the email belongs to an account with verified email
+// @Accept  json
+// @Produce  json
+// @Tags account
+// @Param input body EmailData true "email address"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password/forgot [post]
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+// @Summary ResetPassword
+// @Description set a new password using the token from the reset link
+// @Accept  json
+// @Produce  json
+// @Tags account
+// @Param input body PasswordResetData true "reset token and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password/reset [post]
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
@@ -0,0 +1,192 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountService struct {
+	mock.Mock
+}
+
+func (m *MockAccountService) SetEmail(userID int, email string) error {
+	args := m.Called(userID, email)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) ResendVerification(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) RequestPasswordReset(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) ResetPassword(token, newPassword string) error {
+	args := m.Called(token, newPassword)
+	return args.Error(0)
+}
+
+func TestSetEmailHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"Email taken", repository.ErrUniqueEmail, http.StatusBadRequest},
+		{"Invalid email", fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("email", "invalid email address")), http.StatusBadRequest},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockAccountService)
+			handler := handlers.NewAccountHandler(mockService)
+
+			mockService.On("SetEmail", 1, "alice@example.com").Return(tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/auth/email", bytes.NewBufferString(`{"email":"alice@example.com"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", 1)
+
+			handler.SetEmail(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAccountService)
+		handler := handlers.NewAccountHandler(mockService)
+
+		mockService.On("VerifyEmail", "abc").Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/email/verify?token=abc", nil)
+
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAccountService)
+		handler := handlers.NewAccountHandler(mockService)
+
+		mockService.On("VerifyEmail", "abc").Return(services.ErrInvalidToken)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/email/verify?token=abc", nil)
+
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), services.ErrInvalidToken.Error())
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAccountService)
+		handler := handlers.NewAccountHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/email/verify", nil)
+
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "VerifyEmail", mock.Anything)
+	})
+}
+
+func TestForgotPasswordHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockAccountService)
+	handler := handlers.NewAccountHandler(mockService)
+
+	mockService.On("RequestPasswordReset", "nobody@example.com").Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBufferString(`{"email":"nobody@example.com"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.ForgotPassword(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"Invalid token", services.ErrInvalidToken, http.StatusBadRequest},
+		{"Weak password", fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("password", "too short")), http.StatusBadRequest},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockAccountService)
+			handler := handlers.NewAccountHandler(mockService)
+
+			mockService.On("ResetPassword", "abc", "N3w-Passw0rd").Return(tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBufferString(`{"token":"abc","new_password":"N3w-Passw0rd"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.ResetPassword(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IAppImportService interface {
+	Import(r io.Reader, req models.AppImportRequest) (*models.AppImportReport, error)
+}
+
+type AppImportHandler struct {
+	service  IAppImportService
+	maxBytes int64
+}
+
+func NewAppImportHandler(service IAppImportService, maxBytes int64) *AppImportHandler {
+	return &AppImportHandler{service: service, maxBytes: maxBytes}
+}
+
+// @Summary PreviewAppImport
+// @Description count the projects, labels, tasks, checklist items and comments an export file of Todoist, Trello or Microsoft To Do would bring in and list the warnings, nothing is stored
+// @Security Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Tags tasks
+// @Param source path string true "app the file was exported from" Enums(todoist, trello, mstodo)
+// @Param file formData file true "Todoist project .csv or backup .json, Trello board .json, Microsoft To Do lists .json"
+// @Param workspace_id formData int false "import into this workspace"
+// @Success 200 {object} models.AppImportReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/import/{source}/preview [post]
+func (h *AppImportHandler) PreviewImport(c *gin.Context) {
+	h.importFile(c, true)
+}
+
+// @Summary ImportFromApp
+// @Description create tasks from an export file of Todoist, Trello or Microsoft To Do. Projects, lists and labels become tags, checklists and subtasks a markdown task list in the description, comments are added as written by the user. Tasks that cannot be stored are skipped with a warning, tasks imported before are skipped as duplicates
+// @Security Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Tags tasks
+// @Param source path string true "app the file was exported from" Enums(todoist, trello, mstodo)
+// @Param file formData file true "Todoist project .csv or backup .json, Trello board .json, Microsoft To Do lists .json"
+// @Param workspace_id formData int false "import into this workspace"
+// @Success 200 {object} models.AppImportReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/import/{source} [post]
+func (h *AppImportHandler) Import(c *gin.Context) {
+	h.importFile(c, false)
+}
+
+func (h *AppImportHandler) importFile(c *gin.Context, dryRun bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file larger than %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	req := models.AppImportRequest{
+		UserID:   c.GetInt("user_id"),
+		Source:   c.Param("source"),
+		FileName: header.Filename,
+		DryRun:   dryRun,
+	}
+
+	if raw := c.PostForm("workspace_id"); raw != "" {
+		workspaceID, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace ID"})
+			return
+		}
+		req.WorkspaceID = &workspaceID
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.Import(file, req)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownImportSource) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "source must be todoist, trello or mstodo"})
+			return
+		}
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if abortWorkspaceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
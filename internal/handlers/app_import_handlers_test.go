@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAppImportService struct {
+	mock.Mock
+}
+
+func (m *MockAppImportService) Import(r io.Reader, req models.AppImportRequest) (*models.AppImportReport, error) {
+	args := m.Called(r, req)
+	report, _ := args.Get(0).(*models.AppImportReport)
+	return report, args.Error(1)
+}
+
+func TestAppImportHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Preview", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAppImportService)
+		handler := handlers.NewAppImportHandler(mockService, 1<<20)
+
+		workspaceID := 10
+		mockService.On("Import", mock.Anything, models.AppImportRequest{
+			UserID:      1,
+			WorkspaceID: &workspaceID,
+			Source:      models.ImportSourceTrello,
+			FileName:    "board.json",
+			DryRun:      true,
+		}).Return(&models.AppImportReport{Source: models.ImportSourceTrello, DryRun: true, Tasks: 2, Warnings: []string{"1 archived cards left out"}}, nil)
+
+		c, w := newImportContext(t, "board.json", `{"cards": []}`, map[string]string{"workspace_id": "10"})
+		c.Params = gin.Params{{Key: "source", Value: models.ImportSourceTrello}}
+		handler.PreviewImport(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"tasks":2`)
+		assert.Contains(t, w.Body.String(), "archived cards")
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAppImportService)
+		handler := handlers.NewAppImportHandler(mockService, 1<<20)
+
+		mockService.On("Import", mock.Anything, mock.MatchedBy(func(req models.AppImportRequest) bool {
+			return !req.DryRun && req.Source == models.ImportSourceTodoist && req.FileName == "Renovation.csv"
+		})).Return(&models.AppImportReport{Source: models.ImportSourceTodoist, Tasks: 3, Created: 3}, nil)
+
+		c, w := newImportContext(t, "Renovation.csv", "TYPE,CONTENT\ntask,Paint\n", nil)
+		c.Params = gin.Params{{Key: "source", Value: models.ImportSourceTodoist}}
+		handler.Import(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"created":3`)
+	})
+
+	t.Run("Unknown source", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAppImportService)
+		handler := handlers.NewAppImportHandler(mockService, 1<<20)
+
+		mockService.On("Import", mock.Anything, mock.Anything).Return(nil, services.ErrUnknownImportSource)
+
+		c, w := newImportContext(t, "export.json", "{}", nil)
+		c.Params = gin.Params{{Key: "source", Value: "asana"}}
+		handler.Import(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Not an export", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAppImportService)
+		handler := handlers.NewAppImportHandler(mockService, 1<<20)
+
+		mockService.On("Import", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("file", "not a Microsoft To Do export, no lists")))
+
+		c, w := newImportContext(t, "export.json", "{}", nil)
+		c.Params = gin.Params{{Key: "source", Value: models.ImportSourceMSTodo}}
+		handler.Import(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
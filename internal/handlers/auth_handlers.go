@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/daioru/todo-app/internal/models"
 	"github.com/daioru/todo-app/internal/repository"
@@ -14,7 +16,9 @@ import (
 
 type IAuthService interface {
 	RegisterUser(user *models.User) error
-	LoginUser(username, password string) (string, error)
+	LoginUser(username, password, ip string) (string, error)
+	LoginMFA(mfaToken, code, ip string) (string, error)
+	ChangePassword(userID int, currentPassword, newPassword string) error
 }
 
 type AuthHandler struct {
@@ -49,6 +53,16 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			return
 		}
 
+		if err == repository.ErrUniqueEmail {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email already in use"})
+			return
+		}
+
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
 		return
 	}
@@ -63,8 +77,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Tags auth
 // @Param input body UserData true "user info"
 // @Success 200
+// @Success 202 {object} MFARequiredResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -74,8 +90,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.LoginUser(req.Username, req.Password)
+	token, err := h.service.LoginUser(req.Username, req.Password, c.ClientIP())
 	if err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrMFARequired) {
+			c.JSON(http.StatusAccepted, MFARequiredResponse{MFARequired: true, MFAToken: token})
+			return
+		}
 		if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, services.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "ivalid credentials"})
 			return
@@ -84,8 +107,107 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("Authorization", token, 3600*24*3, "", "", false, true)
+	setAuthCookie(c, token)
 
 	c.AbortWithStatus(http.StatusOK)
 }
+
+// @Summary LoginMFA
+// @Description second login step for users with two-factor authentication, accepts a TOTP or recovery code
+// @Accept  json
+// @Produce  json
+// @Tags auth
+// @Param input body MFALoginData true "mfa token and code"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	token, err := h.service.LoginMFA(req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMFAToken) || errors.Is(err, services.ErrInvalidMFACode) ||
+			errors.Is(err, services.ErrMFANotEnrolled) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	setAuthCookie(c, token)
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// @Summary ChangePassword
+// @Description change password of the current user, requires the current password
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags auth
+// @Param input body ChangePasswordData true "current and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	err := h.service.ChangePassword(c.GetInt("user_id"), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid current password"})
+			return
+		}
+
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// abortLocked answers 429 with Retry-After when the login is throttled
+func abortLocked(c *gin.Context, err error) bool {
+	var locked *services.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": services.ErrTooManyAttempts.Error()})
+	return true
+}
+
+func setAuthCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", token, 3600*24*3, "", "", false, true)
+}
@@ -3,11 +3,14 @@ package handlers_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/models"
 	"github.com/daioru/todo-app/internal/repository"
 	"github.com/daioru/todo-app/internal/services"
@@ -25,11 +28,21 @@ func (m *MockAuthService) RegisterUser(user *models.User) error {
 	return args.Error(0)
 }
 
-func (m *MockAuthService) LoginUser(username, password string) (string, error) {
-	args := m.Called(username, password)
+func (m *MockAuthService) LoginUser(username, password, ip string) (string, error) {
+	args := m.Called(username, password, ip)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockAuthService) LoginMFA(mfaToken, code, ip string) (string, error) {
+	args := m.Called(mfaToken, code, ip)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+	args := m.Called(userID, currentPassword, newPassword)
+	return args.Error(0)
+}
+
 func TestRegister(t *testing.T) {
 	t.Parallel()
 	gin.SetMode(gin.TestMode)
@@ -109,6 +122,27 @@ func TestRegister(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "server side error")
 	})
+
+	t.Run("Weak password", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		user := models.User{Username: "testuser", Password: "123"}
+		mockService.On("RegisterUser", &user).
+			Return(fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("password", "must be at least 8 characters")))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"username": "testuser", "password": "123"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Register(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "must be at least 8 characters")
+	})
 }
 
 func TestLogin(t *testing.T) {
@@ -120,7 +154,7 @@ func TestLogin(t *testing.T) {
 		mockService := new(MockAuthService)
 		handler := handlers.NewAuthHandler(mockService)
 
-		mockService.On("LoginUser", "testuser", "password123").Return("valid-token", nil)
+		mockService.On("LoginUser", "testuser", "password123", "192.0.2.1").Return("valid-token", nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -157,7 +191,7 @@ func TestLogin(t *testing.T) {
 		mockService := new(MockAuthService)
 		handler := handlers.NewAuthHandler(mockService)
 
-		mockService.On("LoginUser", "testuser", "wrongpassword").Return("", services.ErrInvalidCredentials)
+		mockService.On("LoginUser", "testuser", "wrongpassword", "192.0.2.1").Return("", services.ErrInvalidCredentials)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -176,7 +210,7 @@ func TestLogin(t *testing.T) {
 		mockService := new(MockAuthService)
 		handler := handlers.NewAuthHandler(mockService)
 
-		mockService.On("LoginUser", "testuser", "password123").Return("", errors.New("DB error"))
+		mockService.On("LoginUser", "testuser", "password123", "192.0.2.1").Return("", errors.New("DB error"))
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -189,4 +223,197 @@ func TestLogin(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "server side error")
 	})
+
+	t.Run("Second factor required", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("LoginUser", "testuser", "password123", "192.0.2.1").Return("pending-token", services.ErrMFARequired)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"username": "testuser", "password": "password123"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"mfa_token":"pending-token"`)
+		assert.Empty(t, w.Header().Get("Set-Cookie"))
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestLoginMFA(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Successful verification", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("LoginMFA", "pending-token", "123456", "192.0.2.1").Return("valid-token", nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"mfa_token": "pending-token", "code": "123456"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.LoginMFA(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Set-Cookie"), "Authorization=valid-token")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("LoginMFA", "pending-token", "000000", "192.0.2.1").Return("", services.ErrInvalidMFACode)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"mfa_token": "pending-token", "code": "000000"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.LoginMFA(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("Missing code", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBufferString(`{"mfa_token": "pending-token"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.LoginMFA(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "LoginMFA")
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("LoginMFA", "pending-token", "000000", "192.0.2.1").
+			Return("", &services.LockedError{RetryAfter: 1500 * time.Millisecond})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"mfa_token": "pending-token", "code": "000000"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.LoginMFA(c)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockAuthService)
+	handler := handlers.NewAuthHandler(mockService)
+
+	mockService.On("LoginUser", "testuser", "password123", "192.0.2.1").
+		Return("", &services.LockedError{RetryAfter: 15 * time.Minute})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	reqBody := `{"username": "testuser", "password": "password123"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "900", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "too many login attempts")
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(body string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/password", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", 1)
+		return c, w
+	}
+
+	t.Run("Successful change", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("ChangePassword", 1, "Old1password", "New1password").Return(nil)
+
+		c, w := newContext(`{"current_password": "Old1password", "new_password": "New1password"}`)
+		handler.ChangePassword(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("ChangePassword", 1, "wrong", "New1password").Return(services.ErrInvalidCredentials)
+
+		c, w := newContext(`{"current_password": "wrong", "new_password": "New1password"}`)
+		handler.ChangePassword(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Policy violation", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		mockService.On("ChangePassword", 1, "Old1password", "weak").
+			Return(fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("password", "is too common")))
+
+		c, w := newContext(`{"current_password": "Old1password", "new_password": "weak"}`)
+		handler.ChangePassword(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "is too common")
+	})
+
+	t.Run("Missing fields", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockAuthService)
+		handler := handlers.NewAuthHandler(mockService)
+
+		c, w := newContext(`{"new_password": "New1password"}`)
+		handler.ChangePassword(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ChangePassword")
+	})
 }
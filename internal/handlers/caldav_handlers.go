@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// DAVPrefix is the path the CalDAV server is mounted at
+const DAVPrefix = "/dav"
+
+const (
+	davAllow       = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+	davContentType = "text/calendar; charset=utf-8; component=VTODO"
+)
+
+type ICalDAVService interface {
+	Calendars(userID int) ([]models.DAVCalendar, error)
+	Calendar(userID int, calendarID string) (*models.DAVCalendar, []models.DAVObject, error)
+	Object(userID int, calendarID, name string) (*models.DAVObject, error)
+	PutObject(userID int, calendarID, name string, r io.Reader, conds models.DAVConditions) (bool, error)
+	DeleteObject(userID int, calendarID, name, ifMatch string) error
+}
+
+// CalDAVHandler serves the tasks of the user to CalDAV clients. The server
+// has one principal, the authenticated user, whose calendar home lists a
+// calendar per task list:
+//
+//	/dav/principals/me/
+//	/dav/calendars/
+//	/dav/calendars/{calendar}/
+//	/dav/calendars/{calendar}/{uid}.ics
+type CalDAVHandler struct {
+	service ICalDAVService
+}
+
+func NewCalDAVHandler(service ICalDAVService) *CalDAVHandler {
+	return &CalDAVHandler{service: service}
+}
+
+// davPath is a parsed path below DAVPrefix
+type davPath struct {
+	principal bool
+	home      bool
+	calendar  string
+	object    string
+}
+
+func parseDAVPath(path string) (davPath, bool) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return davPath{}, true
+	}
+
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "principals" && parts[1] == "me":
+		return davPath{principal: true}, true
+	case parts[0] != "calendars" || len(parts) > 3:
+		return davPath{}, false
+	case len(parts) == 1:
+		return davPath{home: true}, true
+	case len(parts) == 2:
+		return davPath{calendar: parts[1]}, true
+	default:
+		return davPath{calendar: parts[1], object: parts[2]}, true
+	}
+}
+
+func calendarHref(calendarID string) string {
+	return DAVPrefix + "/calendars/" + url.PathEscape(calendarID) + "/"
+}
+
+func objectHref(calendarID, name string) string {
+	return calendarHref(calendarID) + url.PathEscape(name)
+}
+
+// WellKnown points clients discovering the server to its root
+func (h *CalDAVHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, DAVPrefix+"/")
+}
+
+// Options advertises the DAV classes of the server, it needs no authentication
+func (h *CalDAVHandler) Options(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", davAllow)
+	c.Status(http.StatusOK)
+}
+
+// Serve dispatches a request below DAVPrefix on its method
+func (h *CalDAVHandler) Serve(c *gin.Context) {
+	path, ok := parseDAVPath(c.Param("path"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	switch c.Request.Method {
+	case "PROPFIND":
+		h.propfind(c, path)
+	case "REPORT":
+		h.report(c, path)
+	case http.MethodGet, http.MethodHead:
+		h.get(c, path)
+	case http.MethodPut:
+		h.put(c, path)
+	case http.MethodDelete:
+		h.delete(c, path)
+	default:
+		c.Header("Allow", davAllow)
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *CalDAVHandler) propfind(c *gin.Context, path davPath) {
+	req, err := readDAVRequest(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	userID := c.GetInt("user_id")
+	children := c.GetHeader("Depth") != "0"
+
+	var resources []davResource
+	switch {
+	case path.principal:
+		resources = append(resources, principalResource())
+	case path.home:
+		resources = append(resources, homeResource())
+		if children {
+			calendars, err := h.service.Calendars(userID)
+			if err != nil {
+				abortDAVError(c, err)
+				return
+			}
+			for _, calendar := range calendars {
+				resources = append(resources, calendarResource(&calendar))
+			}
+		}
+	case path.calendar != "":
+		calendar, objects, err := h.service.Calendar(userID, path.calendar)
+		if err != nil {
+			abortDAVError(c, err)
+			return
+		}
+		if path.object != "" {
+			object := findDAVObject(objects, path.object)
+			if object == nil {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			resources = append(resources, objectResource(calendar.ID, object))
+			break
+		}
+		resources = append(resources, calendarResource(calendar))
+		if children {
+			for i := range objects {
+				resources = append(resources, objectResource(calendar.ID, &objects[i]))
+			}
+		}
+	default:
+		resources = append(resources, rootResource())
+		if children {
+			resources = append(resources, principalResource(), homeResource())
+		}
+	}
+
+	writeMultistatus(c, resources, req.props())
+}
+
+// report answers calendar-query and calendar-multiget on a calendar
+func (h *CalDAVHandler) report(c *gin.Context, path davPath) {
+	req, err := readDAVRequest(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if path.calendar == "" {
+		davForbidden(c, "supported-report")
+		return
+	}
+
+	calendar, objects, err := h.service.Calendar(c.GetInt("user_id"), path.calendar)
+	if err != nil {
+		abortDAVError(c, err)
+		return
+	}
+	if path.object != "" {
+		object := findDAVObject(objects, path.object)
+		if object == nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		objects = []models.DAVObject{*object}
+	}
+
+	var resources []davResource
+	switch req.XMLName {
+	case xml.Name{Space: calDAVNS, Local: "calendar-query"}:
+		if req.Filter.matchesTodos() {
+			for i := range objects {
+				resources = append(resources, objectResource(calendar.ID, &objects[i]))
+			}
+		}
+	case xml.Name{Space: calDAVNS, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			resources = append(resources, multigetResource(calendar.ID, objects, href))
+		}
+	default:
+		davForbidden(c, "supported-report")
+		return
+	}
+
+	writeMultistatus(c, resources, req.props())
+}
+
+func (h *CalDAVHandler) get(c *gin.Context, path davPath) {
+	if path.object == "" {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	object, err := h.service.Object(c.GetInt("user_id"), path.calendar, path.object)
+	if err != nil {
+		abortDAVError(c, err)
+		return
+	}
+
+	c.Header("ETag", object.ETag)
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Type", davContentType)
+		c.Status(http.StatusOK)
+		return
+	}
+	c.Data(http.StatusOK, davContentType, []byte(object.Data))
+}
+
+// put creates or replaces a to-do. No ETag is returned as the stored resource
+// is not the one sent by the client, clients fetch it again
+func (h *CalDAVHandler) put(c *gin.Context, path davPath) {
+	if path.object == "" {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	conds := models.DAVConditions{
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+	created, err := h.service.PutObject(c.GetInt("user_id"), path.calendar, path.object,
+		io.LimitReader(c.Request.Body, davBodyLimit), conds)
+	if err != nil {
+		abortDAVError(c, err)
+		return
+	}
+
+	if created {
+		c.Header("Location", objectHref(path.calendar, path.object))
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CalDAVHandler) delete(c *gin.Context, path davPath) {
+	if path.object == "" {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := h.service.DeleteObject(c.GetInt("user_id"), path.calendar, path.object, c.GetHeader("If-Match"))
+	if err != nil {
+		abortDAVError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func rootResource() davResource {
+	return davResource{href: DAVPrefix + "/", props: []davProp{
+		{xml.Name{Space: davNS, Local: "resourcetype"}, "<d:collection/>"},
+		{xml.Name{Space: davNS, Local: "current-user-principal"}, davHref(DAVPrefix + "/principals/me/")},
+	}}
+}
+
+func principalResource() davResource {
+	return davResource{href: DAVPrefix + "/principals/me/", props: []davProp{
+		{xml.Name{Space: davNS, Local: "resourcetype"}, "<d:principal/>"},
+		{xml.Name{Space: davNS, Local: "current-user-principal"}, davHref(DAVPrefix + "/principals/me/")},
+		{xml.Name{Space: davNS, Local: "principal-URL"}, davHref(DAVPrefix + "/principals/me/")},
+		{xml.Name{Space: calDAVNS, Local: "calendar-home-set"}, davHref(DAVPrefix + "/calendars/")},
+	}}
+}
+
+func homeResource() davResource {
+	return davResource{href: DAVPrefix + "/calendars/", props: []davProp{
+		{xml.Name{Space: davNS, Local: "resourcetype"}, "<d:collection/>"},
+		{xml.Name{Space: davNS, Local: "current-user-principal"}, davHref(DAVPrefix + "/principals/me/")},
+	}}
+}
+
+func calendarResource(calendar *models.DAVCalendar) davResource {
+	privileges := "<d:privilege><d:read/></d:privilege>"
+	if !calendar.ReadOnly {
+		privileges += "<d:privilege><d:write/></d:privilege>"
+	}
+
+	return davResource{href: calendarHref(calendar.ID), props: []davProp{
+		{xml.Name{Space: davNS, Local: "resourcetype"}, "<d:collection/><c:calendar/>"},
+		{xml.Name{Space: davNS, Local: "displayname"}, davEscape(calendar.Name)},
+		{xml.Name{Space: davNS, Local: "current-user-principal"}, davHref(DAVPrefix + "/principals/me/")},
+		{xml.Name{Space: davNS, Local: "current-user-privilege-set"}, privileges},
+		{xml.Name{Space: davNS, Local: "supported-report-set"},
+			"<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+				"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"},
+		{xml.Name{Space: calDAVNS, Local: "supported-calendar-component-set"}, `<c:comp name="VTODO"/>`},
+		{xml.Name{Space: calServerNS, Local: "getctag"}, davEscape(calendar.CTag)},
+	}}
+}
+
+func objectResource(calendarID string, object *models.DAVObject) davResource {
+	return davResource{href: objectHref(calendarID, object.Name), props: []davProp{
+		{xml.Name{Space: davNS, Local: "resourcetype"}, ""},
+		{xml.Name{Space: davNS, Local: "getetag"}, davEscape(object.ETag)},
+		{xml.Name{Space: davNS, Local: "getcontenttype"}, davContentType},
+		{calendarDataProp, davEscape(object.Data)},
+	}}
+}
+
+// multigetResource resolves one href of a calendar-multiget, hrefs outside
+// the calendar or of missing resources are reported as not found
+func multigetResource(calendarID string, objects []models.DAVObject, href string) davResource {
+	notFound := davResource{href: href, status: http.StatusNotFound}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return notFound
+	}
+	name, ok := strings.CutPrefix(u.Path, calendarHref(calendarID))
+	if !ok {
+		return notFound
+	}
+	object := findDAVObject(objects, name)
+	if object == nil {
+		return notFound
+	}
+
+	resource := objectResource(calendarID, object)
+	resource.href = href
+	return resource
+}
+
+func findDAVObject(objects []models.DAVObject, name string) *models.DAVObject {
+	for i := range objects {
+		if objects[i].Name == name {
+			return &objects[i]
+		}
+	}
+	return nil
+}
+
+func writeMultistatus(c *gin.Context, resources []davResource, names []xml.Name) {
+	m := newMultistatus()
+	for _, resource := range resources {
+		m.add(resource, names)
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", m.bytes())
+}
+
+// davForbidden answers with a DAV:error body naming the failed precondition
+func davForbidden(c *gin.Context, precondition string) {
+	body := xml.Header + `<d:error xmlns:d="DAV:"><d:` + precondition + `/></d:error>`
+	c.Data(http.StatusForbidden, "application/xml; charset=utf-8", []byte(body))
+}
+
+func abortDAVError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDAVNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDAVPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDAVUIDConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.As(err, &baseErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+	}
+}
@@ -0,0 +1,348 @@
+package handlers_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCalDAVService struct {
+	mock.Mock
+}
+
+func (m *MockCalDAVService) Calendars(userID int) ([]models.DAVCalendar, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.DAVCalendar), args.Error(1)
+}
+
+func (m *MockCalDAVService) Calendar(userID int, calendarID string) (*models.DAVCalendar, []models.DAVObject, error) {
+	args := m.Called(userID, calendarID)
+	return args.Get(0).(*models.DAVCalendar), args.Get(1).([]models.DAVObject), args.Error(2)
+}
+
+func (m *MockCalDAVService) Object(userID int, calendarID, name string) (*models.DAVObject, error) {
+	args := m.Called(userID, calendarID, name)
+	return args.Get(0).(*models.DAVObject), args.Error(1)
+}
+
+func (m *MockCalDAVService) PutObject(userID int, calendarID, name string, r io.Reader, conds models.DAVConditions) (bool, error) {
+	body, _ := io.ReadAll(r)
+	args := m.Called(userID, calendarID, name, string(body), conds)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCalDAVService) DeleteObject(userID int, calendarID, name, ifMatch string) error {
+	args := m.Called(userID, calendarID, name, ifMatch)
+	return args.Error(0)
+}
+
+var (
+	davCalendar = &models.DAVCalendar{ID: "personal", Name: "Personal", CTag: `"ctag-1"`}
+	davObjects  = []models.DAVObject{
+		{Name: "task-1@todo-app.ics", ETag: `"etag-1"`, TaskID: 1,
+			Data: "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:task-1@todo-app\r\nSUMMARY:Buy milk & bread\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"},
+		{Name: "abc-123.ics", ETag: `"etag-2"`, TaskID: 2,
+			Data: "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:abc-123\r\nSUMMARY:Pay rent\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"},
+	}
+)
+
+// newDAVServer mounts the CalDAV handler the way the router does, with every
+// request authenticated as alice
+func newDAVServer(service *MockCalDAVService) *gin.Engine {
+	tokens := new(MockAccessTokenService)
+	tokens.On("Authenticate", "alice", "tdp_secret").Return(&models.User{ID: 1, Username: "alice"}, nil)
+	tokens.On("Authenticate", mock.Anything, mock.Anything).Return((*models.User)(nil), services.ErrInvalidCredentials)
+
+	handler := handlers.NewCalDAVHandler(service)
+	auth := handlers.NewAccessTokenHandler(tokens).BasicAuth("todo-app")
+
+	r := gin.New()
+	r.GET("/.well-known/caldav", handler.WellKnown)
+	dav := r.Group(handlers.DAVPrefix)
+	dav.OPTIONS("/*path", handler.Options)
+	for _, method := range []string{"PROPFIND", "REPORT", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete} {
+		dav.Handle(method, "/*path", auth, handler.Serve)
+	}
+	return r
+}
+
+func serveDAV(r *gin.Engine, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetBasicAuth("alice", "tdp_secret")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCalDAVAuth(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+	r := newDAVServer(new(MockCalDAVService))
+
+	req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+	req.SetBasicAuth("alice", "password")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="todo-app", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+
+	// clients probe the server before they send credentials
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/dav/calendars/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1, 3, calendar-access", w.Header().Get("DAV"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/caldav", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/dav/", w.Header().Get("Location"))
+}
+
+func TestCalDAVDiscovery(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Principal", func(t *testing.T) {
+		t.Parallel()
+		r := newDAVServer(new(MockCalDAVService))
+
+		// DAVx5 resource detection
+		body := `<?xml version='1.0' encoding='UTF-8' ?><propfind xmlns="DAV:" xmlns:CAL="urn:ietf:params:xml:ns:caldav">` +
+			`<prop><current-user-principal /><CAL:calendar-home-set /></prop></propfind>`
+		w := serveDAV(r, "PROPFIND", "/dav/", body, map[string]string{"Depth": "0"})
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Contains(t, w.Body.String(), "<d:response><d:href>/dav/</d:href><d:propstat><d:prop>"+
+			"<d:current-user-principal><d:href>/dav/principals/me/</d:href></d:current-user-principal>"+
+			"</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>"+
+			"<d:propstat><d:prop><c:calendar-home-set/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>")
+
+		w = serveDAV(r, "PROPFIND", "/dav/principals/me/", body, map[string]string{"Depth": "0"})
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Contains(t, w.Body.String(), "<c:calendar-home-set><d:href>/dav/calendars/</d:href></c:calendar-home-set>")
+		assert.NotContains(t, w.Body.String(), "404 Not Found")
+	})
+
+	t.Run("Calendars", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		workspaceID := 7
+		mockService.On("Calendars", 1).Return([]models.DAVCalendar{
+			*davCalendar,
+			{ID: "workspace-7", Name: "R&D", WorkspaceID: &workspaceID, ReadOnly: true, CTag: `"ctag-2"`},
+		}, nil)
+
+		// Thunderbird calendar listing
+		body := `<?xml version="1.0" encoding="UTF-8"?>
+<D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:A="http://apple.com/ns/ical/">
+  <D:prop>
+    <D:resourcetype/>
+    <D:displayname/>
+    <CS:getctag/>
+    <C:supported-calendar-component-set/>
+    <D:current-user-privilege-set/>
+    <A:calendar-color/>
+  </D:prop>
+</D:propfind>`
+		w := serveDAV(r, "PROPFIND", "/dav/calendars/", body, map[string]string{"Depth": "1"})
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		got := w.Body.String()
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/</d:href><d:propstat><d:prop>"+
+			"<d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Personal</d:displayname>"+
+			"<cs:getctag>&#34;ctag-1&#34;</cs:getctag><c:supported-calendar-component-set><c:comp name=\"VTODO\"/></c:supported-calendar-component-set>"+
+			"<d:current-user-privilege-set><d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege></d:current-user-privilege-set>")
+		assert.Contains(t, got, "<d:href>/dav/calendars/workspace-7/</d:href>")
+		assert.Contains(t, got, "<d:displayname>R&amp;D</d:displayname>")
+		assert.Contains(t, got, "<d:current-user-privilege-set><d:privilege><d:read/></d:privilege></d:current-user-privilege-set>")
+		assert.Contains(t, got, `<calendar-color xmlns="http://apple.com/ns/ical/"/>`)
+	})
+
+	t.Run("Unknown calendar", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Calendar", 1, "inbox").Return((*models.DAVCalendar)(nil), []models.DAVObject(nil), services.ErrDAVNotFound)
+
+		w := serveDAV(r, "PROPFIND", "/dav/calendars/inbox/", "", map[string]string{"Depth": "0"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestCalDAVSync(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Etags", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Calendar", 1, "personal").Return(davCalendar, davObjects, nil)
+
+		// tasks.org listing the resources of a calendar
+		body := `<?xml version="1.0" encoding="UTF-8"?><d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">` +
+			`<d:prop><cs:getctag/><d:getetag/></d:prop></d:propfind>`
+		w := serveDAV(r, "PROPFIND", "/dav/calendars/personal/", body, map[string]string{"Depth": "1"})
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		got := w.Body.String()
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/</d:href><d:propstat><d:prop><cs:getctag>&#34;ctag-1&#34;</cs:getctag></d:prop>")
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/task-1@todo-app.ics</d:href><d:propstat><d:prop><d:getetag>&#34;etag-1&#34;</d:getetag></d:prop>")
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/abc-123.ics</d:href>")
+		assert.NotContains(t, got, "calendar-data")
+	})
+
+	t.Run("Calendar query", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Calendar", 1, "personal").Return(davCalendar, davObjects, nil)
+
+		// DAVx5 listing the to-dos of a task list
+		body := `<?xml version='1.0' encoding='UTF-8' ?><CAL:calendar-query xmlns="DAV:" xmlns:CAL="urn:ietf:params:xml:ns:caldav">` +
+			`<prop><getetag /></prop><CAL:filter><CAL:comp-filter name="VCALENDAR"><CAL:comp-filter name="VTODO" /></CAL:comp-filter></CAL:filter></CAL:calendar-query>`
+		w := serveDAV(r, "REPORT", "/dav/calendars/personal/", body, map[string]string{"Depth": "1"})
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Equal(t, 2, strings.Count(w.Body.String(), "<d:getetag>"))
+
+		// the calendars hold no events
+		body = strings.Replace(body, `"VTODO"`, `"VEVENT"`, 1)
+		w = serveDAV(r, "REPORT", "/dav/calendars/personal/", body, map[string]string{"Depth": "1"})
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.NotContains(t, w.Body.String(), "<d:response>")
+	})
+
+	t.Run("Multiget", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Calendar", 1, "personal").Return(davCalendar, davObjects, nil)
+
+		body := `<?xml version="1.0" encoding="UTF-8"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <D:href>/dav/calendars/personal/task-1%40todo-app.ics</D:href>
+  <D:href>/dav/calendars/personal/gone.ics</D:href>
+</C:calendar-multiget>`
+		w := serveDAV(r, "REPORT", "/dav/calendars/personal/", body, map[string]string{"Depth": "1"})
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		got := w.Body.String()
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/task-1%40todo-app.ics</d:href><d:propstat><d:prop>"+
+			"<d:getetag>&#34;etag-1&#34;</d:getetag><c:calendar-data>BEGIN:VCALENDAR&#xD;&#xA;")
+		assert.Contains(t, got, "SUMMARY:Buy milk &amp; bread")
+		assert.Contains(t, got, "<d:href>/dav/calendars/personal/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>")
+	})
+
+	t.Run("Unsupported report", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Calendar", 1, "personal").Return(davCalendar, davObjects, nil)
+
+		body := `<d:sync-collection xmlns:d="DAV:"><d:sync-token/><d:sync-level>1</d:sync-level><d:prop><d:getetag/></d:prop></d:sync-collection>`
+		w := serveDAV(r, "REPORT", "/dav/calendars/personal/", body, nil)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "<d:supported-report/>")
+	})
+}
+
+func TestCalDAVObjectHandlers(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Get", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("Object", 1, "personal", "abc-123.ics").Return(&davObjects[1], nil)
+		mockService.On("Object", 1, "personal", "gone.ics").Return((*models.DAVObject)(nil), services.ErrDAVNotFound)
+
+		w := serveDAV(r, http.MethodGet, "/dav/calendars/personal/abc-123.ics", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"etag-2"`, w.Header().Get("ETag"))
+		assert.Equal(t, "text/calendar; charset=utf-8; component=VTODO", w.Header().Get("Content-Type"))
+		assert.Equal(t, davObjects[1].Data, w.Body.String())
+
+		w = serveDAV(r, http.MethodGet, "/dav/calendars/personal/gone.ics", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		t.Parallel()
+
+		todo := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:new\r\nSUMMARY:New\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+		tests := []struct {
+			name       string
+			headers    map[string]string
+			created    bool
+			serviceErr error
+			wantStatus int
+		}{
+			{"Created", map[string]string{"If-None-Match": "*"}, true, nil, http.StatusCreated},
+			{"Updated", map[string]string{"If-Match": `"etag-1"`}, false, nil, http.StatusNoContent},
+			{"Precondition failed", map[string]string{"If-Match": `"stale"`}, false, services.ErrDAVPreconditionFailed, http.StatusPreconditionFailed},
+			{"UID conflict", nil, false, services.ErrDAVUIDConflict, http.StatusConflict},
+			{"Read only", nil, false, services.ErrWorkspaceForbidden, http.StatusForbidden},
+			{"Server error", nil, false, errors.New("db down"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+				mockService := new(MockCalDAVService)
+				r := newDAVServer(mockService)
+
+				conds := models.DAVConditions{IfMatch: tt.headers["If-Match"], IfNoneMatch: tt.headers["If-None-Match"]}
+				mockService.On("PutObject", 1, "personal", "new.ics", todo, conds).Return(tt.created, tt.serviceErr)
+
+				w := serveDAV(r, http.MethodPut, "/dav/calendars/personal/new.ics", todo, tt.headers)
+
+				assert.Equal(t, tt.wantStatus, w.Code)
+				assert.Empty(t, w.Header().Get("ETag"))
+				if tt.created {
+					assert.Equal(t, "/dav/calendars/personal/new.ics", w.Header().Get("Location"))
+				}
+				mockService.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalDAVService)
+		r := newDAVServer(mockService)
+
+		mockService.On("DeleteObject", 1, "personal", "abc-123.ics", `"etag-2"`).Return(nil)
+
+		w := serveDAV(r, http.MethodDelete, "/dav/calendars/personal/abc-123.ics", "", map[string]string{"If-Match": `"etag-2"`})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serveDAV(r, http.MethodDelete, "/dav/calendars/personal/", "", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	davNS        = "DAV:"
+	calDAVNS     = "urn:ietf:params:xml:ns:caldav"
+	calServerNS  = "http://calendarserver.org/ns/"
+	davBodyLimit = 1 << 20
+)
+
+// davPrefixes are the namespace prefixes declared on every multistatus
+var davPrefixes = map[string]string{
+	davNS:       "d",
+	calDAVNS:    "c",
+	calServerNS: "cs",
+}
+
+var calendarDataProp = xml.Name{Space: calDAVNS, Local: "calendar-data"}
+
+// davRequest is the body of a PROPFIND or REPORT request, only the parts the
+// server understands are decoded
+type davRequest struct {
+	XMLName xml.Name
+	AllProp *struct{}    `xml:"DAV: allprop"`
+	Prop    davPropNames `xml:"DAV: prop"`
+	Hrefs   []string     `xml:"DAV: href"`
+	Filter  davFilter    `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type davPropNames struct {
+	Names []davName `xml:",any"`
+}
+
+type davName struct {
+	XMLName xml.Name
+}
+
+type davFilter struct {
+	CompFilters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type davCompFilter struct {
+	Name        string          `xml:"name,attr"`
+	CompFilters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// props returns the requested property names, nil asks for all properties
+func (r *davRequest) props() []xml.Name {
+	if r.AllProp != nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(r.Prop.Names))
+	for _, name := range r.Prop.Names {
+		names = append(names, name.XMLName)
+	}
+	return names
+}
+
+// matchesTodos reports whether a calendar-query filter selects VTODO
+// components. Time ranges and property filters are not evaluated, clients get
+// every to-do of the calendar and filter them themselves
+func (f davFilter) matchesTodos() bool {
+	if len(f.CompFilters) == 0 {
+		return true
+	}
+	for _, calendar := range f.CompFilters {
+		if calendar.Name != "VCALENDAR" {
+			continue
+		}
+		if len(calendar.CompFilters) == 0 {
+			return true
+		}
+		for _, component := range calendar.CompFilters {
+			if component.Name == "VTODO" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readDAVRequest decodes the XML body of a request, an empty body asks for
+// all properties
+func readDAVRequest(body io.Reader) (*davRequest, error) {
+	var req davRequest
+	err := xml.NewDecoder(io.LimitReader(body, davBodyLimit)).Decode(&req)
+	if errors.Is(err, io.EOF) {
+		return &davRequest{AllProp: &struct{}{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Prop.Names) == 0 {
+		req.AllProp = &struct{}{}
+	}
+	return &req, nil
+}
+
+// davProp is a property of a resource with its value as inner XML
+type davProp struct {
+	name  xml.Name
+	value string
+}
+
+// davResource is one response of a multistatus. A resource without props is
+// reported with status alone
+type davResource struct {
+	href   string
+	props  []davProp
+	status int
+}
+
+// multistatus writes the 207 response of a PROPFIND or REPORT. Requested
+// properties a resource does not have are listed with status 404, with no
+// names every property except the calendar data is returned
+type multistatus struct {
+	b bytes.Buffer
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.b.WriteString(xml.Header)
+	m.b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	return m
+}
+
+func (m *multistatus) add(resource davResource, names []xml.Name) {
+	m.b.WriteString("<d:response><d:href>")
+	m.b.WriteString(davEscape(resource.href))
+	m.b.WriteString("</d:href>")
+
+	if resource.status != 0 {
+		m.status(resource.status)
+		m.b.WriteString("</d:response>")
+		return
+	}
+
+	var found []davProp
+	var missing []xml.Name
+	if names == nil {
+		for _, prop := range resource.props {
+			if prop.name != calendarDataProp {
+				found = append(found, prop)
+			}
+		}
+	}
+	for _, name := range names {
+		if prop, ok := findProp(resource.props, name); ok {
+			found = append(found, prop)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(found) > 0 {
+		m.b.WriteString("<d:propstat><d:prop>")
+		for _, prop := range found {
+			m.element(prop.name, prop.value)
+		}
+		m.b.WriteString("</d:prop>")
+		m.status(http.StatusOK)
+		m.b.WriteString("</d:propstat>")
+	}
+	if len(missing) > 0 {
+		m.b.WriteString("<d:propstat><d:prop>")
+		for _, name := range missing {
+			m.element(name, "")
+		}
+		m.b.WriteString("</d:prop>")
+		m.status(http.StatusNotFound)
+		m.b.WriteString("</d:propstat>")
+	}
+
+	m.b.WriteString("</d:response>")
+}
+
+func (m *multistatus) bytes() []byte {
+	m.b.WriteString("</d:multistatus>")
+	return m.b.Bytes()
+}
+
+func (m *multistatus) status(code int) {
+	m.b.WriteString("<d:status>HTTP/1.1 ")
+	m.b.WriteString(strconv.Itoa(code))
+	m.b.WriteString(" ")
+	m.b.WriteString(http.StatusText(code))
+	m.b.WriteString("</d:status>")
+}
+
+// element writes a property, names outside the known namespaces declare
+// their namespace as default
+func (m *multistatus) element(name xml.Name, value string) {
+	tag := name.Local
+	attrs := ""
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		attrs = ` xmlns="` + davEscape(name.Space) + `"`
+	}
+
+	if value == "" {
+		m.b.WriteString("<" + tag + attrs + "/>")
+		return
+	}
+	m.b.WriteString("<" + tag + attrs + ">" + value + "</" + tag + ">")
+}
+
+func findProp(props []davProp, name xml.Name) (davProp, bool) {
+	for _, prop := range props {
+		if prop.name == name {
+			return prop, true
+		}
+	}
+	return davProp{}, false
+}
+
+func davEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func davHref(href string) string {
+	return "<d:href>" + davEscape(href) + "</d:href>"
+}
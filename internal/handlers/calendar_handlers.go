@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ICalendarService interface {
+	CreateFeed(userID int) (*models.CalendarFeed, error)
+	RevokeFeed(userID int) error
+	Feed(w io.Writer, token string, events bool) error
+}
+
+type CalendarHandler struct {
+	service ICalendarService
+}
+
+func NewCalendarHandler(service ICalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// @Summary CreateCalendarFeed
+// @Description issue the secret URL of the user's iCalendar feed, an earlier URL stops working
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 201 {object} models.CalendarFeed
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/calendar [post]
+func (h *CalendarHandler) CreateFeed(c *gin.Context) {
+	feed, err := h.service.CreateFeed(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, feed)
+}
+
+// @Summary RevokeCalendarFeed
+// @Description stop the iCalendar feed URL of the user from working
+// @Security Auth
+// @Tags me
+// @Success 200 {object} SuccessResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/calendar [delete]
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	if err := h.service.RevokeFeed(c.GetInt("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed revoked"})
+}
+
+// @Summary CalendarFeed
+// @Description RFC 5545 calendar with a VTODO for every task with a due date visible to the owner of the token, for subscribing from calendar apps. No cookie is needed, the token in the URL authorizes the request
+// @Produce  text/calendar
+// @Tags calendar
+// @Param token path string true "feed token, optionally with the .ics extension"
+// @Param events query bool false "also add a VEVENT at the due time of every task"
+// @Success 200 {string} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /calendar/{token} [get]
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	events := false
+	if raw := c.Query("events"); raw != "" {
+		var err error
+		if events, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid events"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", services.ExportContentType(models.TransferFormatICS))
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Status(http.StatusOK)
+
+	if err := h.service.Feed(c.Writer, token, events); err != nil {
+		// once the calendar is sent the status can no longer change
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		if errors.Is(err, services.ErrCalendarFeedNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCalendarService struct {
+	mock.Mock
+}
+
+func (m *MockCalendarService) CreateFeed(userID int) (*models.CalendarFeed, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarService) RevokeFeed(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockCalendarService) Feed(w io.Writer, token string, events bool) error {
+	args := m.Called(w, token, events)
+	if calendar := args.String(0); calendar != "" {
+		io.WriteString(w, calendar)
+	}
+	return args.Error(1)
+}
+
+func TestCreateCalendarFeedHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		expiresAt := time.Date(2036, 10, 19, 8, 0, 0, 0, time.UTC)
+		mockService.On("CreateFeed", 1).Return(&models.CalendarFeed{URL: "http://localhost/api/calendar/abc.ics", ExpiresAt: expiresAt}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/me/calendar", "", nil)
+		handler.CreateFeed(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"url": "http://localhost/api/calendar/abc.ics", "expires_at": "2036-10-19T08:00:00Z"}`, w.Body.String())
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		mockService.On("RevokeFeed", 1).Return(nil)
+
+		c, w := newWorkspaceContext(http.MethodDelete, "/me/calendar", "", nil)
+		handler.RevokeFeed(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestCalendarFeedHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		mockService.On("Feed", mock.Anything, "abc", true).Return("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", nil)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/calendar/abc.ics?events=true", "", gin.Params{{Key: "token", Value: "abc.ics"}})
+		handler.Feed(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", w.Body.String())
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		mockService.On("Feed", mock.Anything, "old", false).Return("", services.ErrCalendarFeedNotFound)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/calendar/old", "", gin.Params{{Key: "token", Value: "old"}})
+		handler.Feed(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Failure after the calendar started", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		mockService.On("Feed", mock.Anything, "abc", false).Return("BEGIN:VCALENDAR\r\n", errors.New("connection reset"))
+
+		c, w := newWorkspaceContext(http.MethodGet, "/calendar/abc.ics", "", gin.Params{{Key: "token", Value: "abc.ics"}})
+		handler.Feed(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "BEGIN:VCALENDAR\r\n", w.Body.String())
+		assert.Len(t, c.Errors, 1)
+	})
+
+	t.Run("Invalid events", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCalendarService)
+		handler := handlers.NewCalendarHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/calendar/abc.ics?events=maybe", "", gin.Params{{Key: "token", Value: "abc.ics"}})
+		handler.Feed(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Feed", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
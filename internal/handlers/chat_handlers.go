@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxChatCommandBytes bounds the form of a slash command request
+const maxChatCommandBytes = 64 << 10
+
+type IChatService interface {
+	VerifyRequest(timestamp, signature string, body []byte) error
+	CreateLinkCode(userID int) (*models.ChatLinkCode, error)
+	HandleCommand(cmd models.ChatCommand) (*models.ChatReply, error)
+}
+
+type ChatHandler struct {
+	service IChatService
+}
+
+func NewChatHandler(service IChatService) *ChatHandler {
+	return &ChatHandler{service: service}
+}
+
+// @Summary CreateChatLinkCode
+// @Description issue a one-time code that links the team chat account sending it with the link command to the user. An earlier code stops working
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 201 {object} models.ChatLinkCode
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/chat [post]
+func (h *ChatHandler) CreateLinkCode(c *gin.Context) {
+	code, err := h.service.CreateLinkCode(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, code)
+}
+
+// @Summary ChatCommand
+// @Description run a slash command of the team chat: add <task>, list, done <id>, link <code>, unlink or help. The request is signed with the chat signing secret, X-Chat-Signature is v0= and the hex HMAC-SHA256 of v0:<timestamp>:<body>. Mistakes in the command are answered in the reply
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Tags chat
+// @Param X-Chat-Timestamp header string true "Unix time of the request"
+// @Param X-Chat-Signature header string true "request signature"
+// @Param team_id formData string false "chat team"
+// @Param user_id formData string true "chat user"
+// @Param command formData string false "slash command, e.g. /todo"
+// @Param text formData string false "text after the command"
+// @Success 200 {object} models.ChatReply
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /chat/commands [post]
+func (h *ChatHandler) Command(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxChatCommandBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	// the signature covers the raw body, so it is checked before parsing
+	if err := h.service.VerifyRequest(c.GetHeader("X-Chat-Timestamp"), c.GetHeader("X-Chat-Signature"), body); err != nil {
+		switch {
+		case errors.Is(err, services.ErrChatDisabled):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidChatSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		}
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil || form.Get("user_id") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	reply, err := h.service.HandleCommand(models.ChatCommand{
+		TeamID:   form.Get("team_id"),
+		UserID:   form.Get("user_id"),
+		UserName: form.Get("user_name"),
+		Command:  form.Get("command"),
+		Text:     form.Get("text"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reply)
+}
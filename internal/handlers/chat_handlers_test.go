@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockChatService struct {
+	mock.Mock
+}
+
+func (m *MockChatService) VerifyRequest(timestamp, signature string, body []byte) error {
+	args := m.Called(timestamp, signature, string(body))
+	return args.Error(0)
+}
+
+func (m *MockChatService) CreateLinkCode(userID int) (*models.ChatLinkCode, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.ChatLinkCode), args.Error(1)
+}
+
+func (m *MockChatService) HandleCommand(cmd models.ChatCommand) (*models.ChatReply, error) {
+	args := m.Called(cmd)
+	return args.Get(0).(*models.ChatReply), args.Error(1)
+}
+
+func TestCreateChatLinkCodeHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expiresAt := time.Date(2026, 10, 19, 8, 10, 0, 0, time.UTC)
+	mockService.On("CreateLinkCode", 1).Return(&models.ChatLinkCode{Code: "abcd", Command: "/todo link abcd", ExpiresAt: expiresAt}, nil)
+
+	c, w := newWorkspaceContext(http.MethodPost, "/me/chat", "", nil)
+	handler.CreateLinkCode(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"code": "abcd", "command": "/todo link abcd", "expires_at": "2026-10-19T08:10:00Z"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestChatCommandHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	const body = "team_id=T1&user_id=U1&user_name=sam&command=%2Ftodo&text=add+Buy+milk+tomorrow+%23home"
+
+	tests := []struct {
+		name     string
+		body     string
+		verify   error
+		reply    *models.ChatReply
+		replyErr error
+		want     int
+	}{
+		{name: "Success", body: body, reply: &models.ChatReply{ResponseType: models.ChatResponseEphemeral, Text: "Added *7* Buy milk", Blocks: []models.ChatBlock{}}, want: http.StatusOK},
+		{name: "Disabled", body: body, verify: services.ErrChatDisabled, want: http.StatusNotFound},
+		{name: "Bad signature", body: body, verify: services.ErrInvalidChatSignature, want: http.StatusUnauthorized},
+		{name: "No user", body: "text=list", want: http.StatusBadRequest},
+		{name: "Too large", body: strings.Repeat("x", 70<<10), want: http.StatusRequestEntityTooLarge},
+		{name: "Failure", body: body, replyErr: errors.New("db down"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockChatService)
+			handler := handlers.NewChatHandler(mockService)
+
+			mockService.On("VerifyRequest", "1792396800", "v0=abc", tt.body).Return(tt.verify)
+			mockService.On("HandleCommand", models.ChatCommand{TeamID: "T1", UserID: "U1", UserName: "sam", Command: "/todo", Text: "add Buy milk tomorrow #home"}).
+				Return(tt.reply, tt.replyErr)
+
+			c, w := newWorkspaceContext(http.MethodPost, "/chat/commands", tt.body, nil)
+			c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.Request.Header.Set("X-Chat-Timestamp", "1792396800")
+			c.Request.Header.Set("X-Chat-Signature", "v0=abc")
+			handler.Command(c)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.JSONEq(t, `{"response_type": "ephemeral", "text": "Added *7* Buy milk", "blocks": []}`, w.Body.String())
+			}
+			if tt.verify != nil {
+				mockService.AssertNotCalled(t, "HandleCommand", mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ICommentService interface {
+	ListComments(taskID, userID int) ([]models.Comment, error)
+	CreateComment(taskID, userID int, req models.CommentRequest) (*models.Comment, error)
+	UpdateComment(taskID, commentID, userID int, body string) (*models.Comment, error)
+	DeleteComment(taskID, commentID, userID int) error
+}
+
+type CommentHandler struct {
+	service ICommentService
+}
+
+func NewCommentHandler(service ICommentService) *CommentHandler {
+	return &CommentHandler{service: service}
+}
+
+// @Summary ListComments
+// @Description list comments of task with {id} oldest first, replies carry parent_id
+// @Security Auth
+// @Produce  json
+// @Tags comments
+// @Param id path int true "Task ID"
+// @Success 200 {object} []models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/comments [get]
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	comments, err := h.service.ListComments(taskID, c.GetInt("user_id"))
+	if err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// @Summary CreateComment
+// @Description comment on task with {id}, @username mentions notify users who can see the task
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags comments
+// @Param id path int true "Task ID"
+// @Param input body CommentData true "markdown body and optional parent comment"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/comments [post]
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	comment, err := h.service.CreateComment(taskID, c.GetInt("user_id"), req)
+	if err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// @Summary UpdateComment
+// @Description edit own comment, newly mentioned users are notified
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags comments
+// @Param id path int true "Task ID"
+// @Param comment_id path int true "Comment ID"
+// @Param input body UpdateCommentData true "markdown body"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/comments/{comment_id} [patch]
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := pathID(c, "comment_id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	comment, err := h.service.UpdateComment(taskID, commentID, c.GetInt("user_id"), req.Body)
+	if err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// @Summary DeleteComment
+// @Description delete own comment, replies to it are kept without parent_id
+// @Security Auth
+// @Produce  json
+// @Tags comments
+// @Param id path int true "Task ID"
+// @Param comment_id path int true "Comment ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/comments/{comment_id} [delete]
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := pathID(c, "comment_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComment(taskID, commentID, c.GetInt("user_id")); err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+func (h *CommentHandler) abort(c *gin.Context, taskID int, err error) {
+	switch {
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCommentAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.As(err, &baseErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		abortTaskError(c, taskID, err)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCommentService struct {
+	mock.Mock
+}
+
+func (m *MockCommentService) ListComments(taskID, userID int) ([]models.Comment, error) {
+	args := m.Called(taskID, userID)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) CreateComment(taskID, userID int, req models.CommentRequest) (*models.Comment, error) {
+	args := m.Called(taskID, userID, req)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) UpdateComment(taskID, commentID, userID int, body string) (*models.Comment, error) {
+	args := m.Called(taskID, commentID, userID, body)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) DeleteComment(taskID, commentID, userID int) error {
+	args := m.Called(taskID, commentID, userID)
+	return args.Error(0)
+}
+
+func TestCreateCommentHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Reply", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCommentService)
+		handler := handlers.NewCommentHandler(mockService)
+
+		req := models.CommentRequest{Body: "Agreed", ParentID: intPtr(3)}
+		mockService.On("CreateComment", 42, 1, req).Return(&models.Comment{ID: 7, TaskID: 42, ParentID: intPtr(3), Body: "Agreed"}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/tasks/42/comments", `{"body":"Agreed","parent_id":3}`, gin.Params{{Key: "id", Value: "42"}})
+		handler.CreateComment(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"parent_id":3`)
+	})
+
+	t.Run("Missing body", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCommentService)
+		handler := handlers.NewCommentHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/tasks/42/comments", `{}`, gin.Params{{Key: "id", Value: "42"}})
+		handler.CreateComment(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Hidden task", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockCommentService)
+		handler := handlers.NewCommentHandler(mockService)
+
+		mockService.On("CreateComment", 42, 1, models.CommentRequest{Body: "Hi"}).Return((*models.Comment)(nil), repository.ErrNoRowsUpdated)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/tasks/42/comments", `{"body":"Hi"}`, gin.Params{{Key: "id", Value: "42"}})
+		handler.CreateComment(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "task with id: 42")
+	})
+}
+
+func TestCommentErrorMapping(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"Unknown comment", services.ErrCommentNotFound, http.StatusNotFound},
+		{"Not the author", services.ErrNotCommentAuthor, http.StatusForbidden},
+		{"Validation error", helpers.NewSpecificValidationError("body", "cannot be blank"), http.StatusBadRequest},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockCommentService)
+			handler := handlers.NewCommentHandler(mockService)
+
+			mockService.On("UpdateComment", 42, 7, 1, "edit").Return((*models.Comment)(nil), tt.err)
+
+			c, w := newWorkspaceContext(http.MethodPatch, "/tasks/42/comments/7", `{"body":"edit"}`,
+				gin.Params{{Key: "id", Value: "42"}, {Key: "comment_id", Value: "7"}})
+			handler.UpdateComment(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestDeleteCommentHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockCommentService)
+	handler := handlers.NewCommentHandler(mockService)
+
+	mockService.On("DeleteComment", 42, 7, 1).Return(nil)
+
+	c, w := newWorkspaceContext(http.MethodDelete, "/tasks/42/comments/7", "",
+		gin.Params{{Key: "id", Value: "42"}, {Key: "comment_id", Value: "7"}})
+	handler.DeleteComment(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type IDigestService interface {
+	GetSettings(userID int) (*models.DigestSettings, error)
+	UpdateSettings(userID int, update models.DigestSettingsUpdate) (*models.DigestSettings, error)
+	Preview(userID int) (*models.RenderedDigest, error)
+}
+
+type DigestHandler struct {
+	service IDigestService
+}
+
+func NewDigestHandler(service IDigestService) *DigestHandler {
+	return &DigestHandler{service: service}
+}
+
+// @Summary GetDigestSettings
+// @Description daily digest settings of the current user, an empty timezone follows the profile
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 200 {object} models.DigestSettings
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/digest [get]
+func (h *DigestHandler) GetSettings(c *gin.Context) {
+	settings, err := h.service.GetSettings(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary UpdateDigestSettings
+// @Description opt in or out of the daily digest and set the local send time (HH:MM) and time zone, only the given fields are changed
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags me
+// @Param input body models.DigestSettingsUpdate true "digest settings"
+// @Success 200 {object} models.DigestSettings
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/digest [put]
+func (h *DigestHandler) UpdateSettings(c *gin.Context) {
+	var update models.DigestSettingsUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.GetInt("user_id"), update)
+	if err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary PreviewDigest
+// @Description the daily digest of the current user as it would be sent now: the sections and rendered mail as json, or only the text or html body
+// @Security Auth
+// @Produce  json
+// @Produce  plain
+// @Produce  html
+// @Tags me
+// @Param format query string false "json (default), text or html" Enums(json, text, html)
+// @Success 200 {object} models.RenderedDigest
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/digest/preview [get]
+func (h *DigestHandler) Preview(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = models.DigestFormatJSON
+	}
+	if format != models.DigestFormatJSON && format != models.DigestFormatText && format != models.DigestFormatHTML {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, text or html"})
+		return
+	}
+
+	rendered, err := h.service.Preview(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	switch format {
+	case models.DigestFormatText:
+		c.String(http.StatusOK, rendered.Text)
+	case models.DigestFormatHTML:
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+	default:
+		c.JSON(http.StatusOK, rendered)
+	}
+}
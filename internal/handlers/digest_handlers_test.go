@@ -0,0 +1,114 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDigestService struct {
+	mock.Mock
+}
+
+func (m *MockDigestService) GetSettings(userID int) (*models.DigestSettings, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.DigestSettings), args.Error(1)
+}
+
+func (m *MockDigestService) UpdateSettings(userID int, update models.DigestSettingsUpdate) (*models.DigestSettings, error) {
+	args := m.Called(userID, update)
+	return args.Get(0).(*models.DigestSettings), args.Error(1)
+}
+
+func (m *MockDigestService) Preview(userID int) (*models.RenderedDigest, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.RenderedDigest), args.Error(1)
+}
+
+func TestUpdateDigestSettingsHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockDigestService)
+		handler := handlers.NewDigestHandler(mockService)
+
+		mockService.On("UpdateSettings", 1, mock.MatchedBy(func(u models.DigestSettingsUpdate) bool {
+			return *u.Enabled && *u.SendTime == "07:30" && u.Timezone == nil
+		})).Return(&models.DigestSettings{UserID: 1, Enabled: true, SendTime: "07:30"}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPut, "/me/digest", `{"enabled": true, "send_time": "07:30"}`, nil)
+		handler.UpdateSettings(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"enabled": true, "send_time": "07:30", "timezone": ""}`, w.Body.String())
+	})
+
+	t.Run("Invalid send time", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockDigestService)
+		handler := handlers.NewDigestHandler(mockService)
+
+		mockService.On("UpdateSettings", 1, mock.Anything).Return((*models.DigestSettings)(nil), helpers.NewSpecificValidationError("send_time", "must be HH:MM"))
+
+		c, w := newWorkspaceContext(http.MethodPut, "/me/digest", `{"send_time": "7"}`, nil)
+		handler.UpdateSettings(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "must be HH:MM")
+	})
+}
+
+func TestPreviewDigestHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	rendered := &models.RenderedDigest{
+		Digest:  &models.Digest{Date: "2026-10-19", Timezone: "UTC"},
+		Subject: "Daily digest for 2026-10-19: nothing to do",
+		Text:    "Hi alice,",
+		HTML:    "<p>Hi alice,</p>",
+	}
+
+	for _, tc := range []struct {
+		name, format, contentType, body string
+	}{
+		{"JSON", "", "application/json", `"subject":"Daily digest for 2026-10-19: nothing to do"`},
+		{"Text", "text", "text/plain", "Hi alice,"},
+		{"HTML", "html", "text/html", "<p>Hi alice,</p>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockDigestService)
+			handler := handlers.NewDigestHandler(mockService)
+
+			mockService.On("Preview", 1).Return(rendered, nil)
+
+			c, w := newWorkspaceContext(http.MethodGet, "/me/digest/preview?format="+tc.format, "", nil)
+			handler.Preview(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), tc.contentType)
+			assert.Contains(t, w.Body.String(), tc.body)
+		})
+	}
+
+	t.Run("Unknown format", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockDigestService)
+		handler := handlers.NewDigestHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/me/digest/preview?format=pdf", "", nil)
+		handler.Preview(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Preview", mock.Anything)
+	})
+}
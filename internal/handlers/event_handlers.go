@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IEventService interface {
+	Subscribe(userID int) *services.Subscription
+	Unsubscribe(sub *services.Subscription)
+	Replay(userID int, lastID int64) ([]models.TaskEvent, bool, error)
+}
+
+type EventHandler struct {
+	service   IEventService
+	heartbeat time.Duration
+}
+
+func NewEventHandler(service IEventService, heartbeat time.Duration) *EventHandler {
+	return &EventHandler{service: service, heartbeat: heartbeat}
+}
+
+// @Summary Events
+// @Description Server-Sent Events stream of task.created, task.updated and task.deleted events for tasks the user can see.
+// @Description Reconnecting with Last-Event-ID replays missed events, a "reset" event means the client has to reload its tasks.
+// @Security Auth
+// @Produce  text/event-stream
+// @Tags events
+// @Param Last-Event-ID header int false "id of the last received event"
+// @Success 200 {object} models.TaskEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /events [get]
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	var lastID int64
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		// EventSource polyfills that can't set headers pass it in the query
+		raw = c.Query("lastEventId")
+	}
+	if raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		lastID = id
+	}
+
+	// subscribe before replaying so no event falls between the two, duplicates
+	// are skipped by id
+	sub := h.service.Subscribe(userID)
+	defer h.service.Unsubscribe(sub)
+
+	var replay []models.TaskEvent
+	var reset bool
+	if lastID > 0 {
+		var err error
+		replay, reset, err = h.service.Replay(userID, lastID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	if reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		if writeEvent(w, event) != nil {
+			return
+		}
+		lastID = event.ID
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			if writeEvent(w, event) != nil {
+				return
+			}
+			lastID = event.ID
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, event models.TaskEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
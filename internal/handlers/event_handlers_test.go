@@ -0,0 +1,146 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEventService struct {
+	mock.Mock
+}
+
+func (m *MockEventService) Subscribe(userID int) *services.Subscription {
+	args := m.Called(userID)
+	return args.Get(0).(*services.Subscription)
+}
+
+func (m *MockEventService) Unsubscribe(sub *services.Subscription) {
+	m.Called(sub)
+}
+
+func (m *MockEventService) Replay(userID int, lastID int64) ([]models.TaskEvent, bool, error) {
+	args := m.Called(userID, lastID)
+	return args.Get(0).([]models.TaskEvent), args.Bool(1), args.Error(2)
+}
+
+func newEventContext(lastEventID string) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := newWorkspaceContext(http.MethodGet, "/events", "", nil)
+	if lastEventID != "" {
+		c.Request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return c, w
+}
+
+func TestEventStream(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Resume skips duplicates", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockEventService)
+		handler := handlers.NewEventHandler(mockService, time.Minute)
+
+		live := make(chan models.TaskEvent, 2)
+		live <- models.TaskEvent{ID: 7, Type: models.TaskEventUpdated, TaskID: 3}
+		live <- models.TaskEvent{ID: 8, Type: models.TaskEventDeleted, TaskID: 3}
+		close(live)
+		sub := &services.Subscription{UserID: 1, Events: live}
+
+		mockService.On("Subscribe", 1).Return(sub)
+		mockService.On("Unsubscribe", sub).Return()
+		mockService.On("Replay", 1, int64(5)).Return([]models.TaskEvent{
+			{ID: 6, Type: models.TaskEventCreated, TaskID: 3},
+			{ID: 7, Type: models.TaskEventUpdated, TaskID: 3},
+		}, false, nil)
+
+		c, w := newEventContext("5")
+		handler.Stream(c)
+
+		body := w.Body.String()
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Contains(t, body, "id: 6\nevent: task.created\ndata: {")
+		assert.Equal(t, 1, strings.Count(body, "id: 7\n"))
+		assert.Contains(t, body, "id: 8\nevent: task.deleted\n")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Reset when events are gone", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockEventService)
+		handler := handlers.NewEventHandler(mockService, time.Minute)
+
+		live := make(chan models.TaskEvent)
+		close(live)
+		sub := &services.Subscription{UserID: 1, Events: live}
+
+		mockService.On("Subscribe", 1).Return(sub)
+		mockService.On("Unsubscribe", sub).Return()
+		mockService.On("Replay", 1, int64(5)).Return([]models.TaskEvent(nil), true, nil)
+
+		c, w := newEventContext("5")
+		handler.Stream(c)
+
+		assert.Contains(t, w.Body.String(), "event: reset\n")
+	})
+
+	t.Run("Heartbeat until disconnect", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockEventService)
+		handler := handlers.NewEventHandler(mockService, 5*time.Millisecond)
+
+		sub := &services.Subscription{UserID: 1, Events: make(chan models.TaskEvent)}
+		mockService.On("Subscribe", 1).Return(sub)
+		mockService.On("Unsubscribe", sub).Return()
+
+		c, w := newEventContext("")
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		handler.Stream(c)
+
+		assert.Contains(t, w.Body.String(), ": heartbeat\n\n")
+		mockService.AssertNotCalled(t, "Replay", mock.Anything, mock.Anything)
+		mockService.AssertCalled(t, "Unsubscribe", sub)
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockEventService)
+		handler := handlers.NewEventHandler(mockService, time.Minute)
+
+		c, w := newEventContext("abc")
+		handler.Stream(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Subscribe", mock.Anything)
+	})
+
+	t.Run("Replay error", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockEventService)
+		handler := handlers.NewEventHandler(mockService, time.Minute)
+
+		sub := &services.Subscription{UserID: 1, Events: make(chan models.TaskEvent)}
+		mockService.On("Subscribe", 1).Return(sub)
+		mockService.On("Unsubscribe", sub).Return()
+		mockService.On("Replay", 1, int64(5)).Return([]models.TaskEvent(nil), false, errors.New("db down"))
+
+		c, w := newEventContext("5")
+		handler.Stream(c)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IInboundMailService interface {
+	CreateAddress(userID int) (*models.InboundAddress, error)
+	RevokeAddress(userID int) error
+	Recipient(address string) (int, error)
+	Receive(r io.Reader, recipients []string) (*models.InboundMailResult, error)
+}
+
+type InboundMailHandler struct {
+	service  IInboundMailService
+	secret   string
+	maxBytes int64
+}
+
+// NewInboundMailHandler accepts messages from a mail server that presents
+// the secret as a bearer token, an empty secret turns the endpoint off
+func NewInboundMailHandler(service IInboundMailService, secret string, maxBytes int64) *InboundMailHandler {
+	return &InboundMailHandler{service: service, secret: secret, maxBytes: maxBytes}
+}
+
+// @Summary CreateInboundAddress
+// @Description issue the secret email address of the user, mail sent to it becomes tasks. An earlier address stops working
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 201 {object} models.InboundAddress
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/inbox [post]
+func (h *InboundMailHandler) CreateAddress(c *gin.Context) {
+	address, err := h.service.CreateAddress(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// @Summary RevokeInboundAddress
+// @Description stop the inbound email address of the user from accepting mail
+// @Security Auth
+// @Tags me
+// @Success 200 {object} SuccessResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/inbox [delete]
+func (h *InboundMailHandler) RevokeAddress(c *gin.Context) {
+	if err := h.service.RevokeAddress(c.GetInt("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Inbound address revoked"})
+}
+
+// @Summary ReceiveMail
+// @Description create tasks from a raw RFC 822 message forwarded by a mail server, one for every user whose inbound address it is sent to. The subject is the title, #tag words add tags and !low, !medium or !high set the priority; the text part, or the HTML part without tags, is the description. Attachments are not stored, their names are returned
+// @Accept  message/rfc822
+// @Produce  json
+// @Tags inbound
+// @Param Authorization header string true "Bearer and the inbound secret of the server configuration"
+// @Param recipient query []string false "envelope recipients, by default the To, Cc and Delivered-To headers" collectionFormat(multi)
+// @Success 201 {object} models.InboundMailResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /inbound/mail [post]
+func (h *InboundMailHandler) Receive(c *gin.Context) {
+	if h.secret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "inbound mail is disabled"})
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid inbound secret"})
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("message larger than %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message"})
+		return
+	}
+
+	result, err := h.service.Receive(bytes.NewReader(raw), c.QueryArray("recipient"))
+	if err != nil {
+		if errors.Is(err, services.ErrInboundAddressNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
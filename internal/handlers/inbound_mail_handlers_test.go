@@ -0,0 +1,242 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockInboundMailService struct {
+	mock.Mock
+}
+
+func (m *MockInboundMailService) CreateAddress(userID int) (*models.InboundAddress, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.InboundAddress), args.Error(1)
+}
+
+func (m *MockInboundMailService) RevokeAddress(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockInboundMailService) Recipient(address string) (int, error) {
+	args := m.Called(address)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockInboundMailService) Receive(r io.Reader, recipients []string) (*models.InboundMailResult, error) {
+	message, _ := io.ReadAll(r)
+	args := m.Called(string(message), recipients)
+	return args.Get(0).(*models.InboundMailResult), args.Error(1)
+}
+
+const inboundMessage = "Subject: Call the plumber #house\r\n\r\nThe sink leaks\r\n"
+
+func TestCreateInboundAddressHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockInboundMailService)
+	handler := handlers.NewInboundMailHandler(mockService, "", 1<<20)
+
+	expiresAt := time.Date(2036, 10, 19, 8, 0, 0, 0, time.UTC)
+	mockService.On("CreateAddress", 1).Return(&models.InboundAddress{Address: "tasks+abc@todo.example.com", ExpiresAt: expiresAt}, nil)
+	mockService.On("RevokeAddress", 1).Return(nil)
+
+	c, w := newWorkspaceContext(http.MethodPost, "/me/inbox", "", nil)
+	handler.CreateAddress(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"address": "tasks+abc@todo.example.com", "expires_at": "2036-10-19T08:00:00Z"}`, w.Body.String())
+
+	c, w = newWorkspaceContext(http.MethodDelete, "/me/inbox", "", nil)
+	handler.RevokeAddress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestReceiveMailHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	newRequest := func(target, secret, body string) (*gin.Context, func() int) {
+		c, w := newWorkspaceContext(http.MethodPost, target, body, nil)
+		c.Request.Header.Set("Content-Type", "message/rfc822")
+		if secret != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+secret)
+		}
+		return c, func() int { return w.Code }
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockInboundMailService)
+		handler := handlers.NewInboundMailHandler(mockService, "s3cret", 1<<20)
+
+		recipients := []string{"tasks+abc@todo.example.com", "tasks+def@todo.example.com"}
+		mockService.On("Receive", inboundMessage, recipients).Return(&models.InboundMailResult{
+			Tasks: []models.Task{{ID: 7, UserID: 1, Title: "Call the plumber"}}, IgnoredAttachments: []string{},
+		}, nil)
+
+		c, code := newRequest("/inbound/mail?recipient=tasks%2Babc%40todo.example.com&recipient=tasks%2Bdef%40todo.example.com", "s3cret", inboundMessage)
+		handler.Receive(c)
+
+		assert.Equal(t, http.StatusCreated, code())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name   string
+			secret string
+			sent   string
+			body   string
+			err    error
+			want   int
+		}{
+			{name: "Disabled", sent: "s3cret", body: inboundMessage, want: http.StatusNotFound},
+			{name: "Wrong secret", secret: "s3cret", sent: "guess", body: inboundMessage, want: http.StatusUnauthorized},
+			{name: "No secret", secret: "s3cret", body: inboundMessage, want: http.StatusUnauthorized},
+			{name: "Too large", secret: "s3cret", sent: "s3cret", body: strings.Repeat("x", 2048), want: http.StatusRequestEntityTooLarge},
+			{name: "Unknown address", secret: "s3cret", sent: "s3cret", body: inboundMessage, err: services.ErrInboundAddressNotFound, want: http.StatusNotFound},
+			{name: "Invalid message", secret: "s3cret", sent: "s3cret", body: inboundMessage,
+				err: fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("message", "malformed header")), want: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockService := new(MockInboundMailService)
+				handler := handlers.NewInboundMailHandler(mockService, tt.secret, 1024)
+				mockService.On("Receive", mock.Anything, mock.Anything).Return((*models.InboundMailResult)(nil), tt.err)
+
+				c, code := newRequest("/inbound/mail", tt.sent, tt.body)
+				handler.Receive(c)
+
+				assert.Equal(t, tt.want, code())
+				if tt.err == nil {
+					mockService.AssertNotCalled(t, "Receive", mock.Anything, mock.Anything)
+				}
+			})
+		}
+	})
+}
+
+func TestInboundSMTPServer(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockInboundMailService)
+	mockService.On("Recipient", "tasks+abc@todo.example.com").Return(1, nil)
+	mockService.On("Recipient", mock.Anything).Return(0, services.ErrInboundAddressNotFound)
+	// the dot reader of the session turns CRLF into LF
+	mockService.On("Receive", mock.MatchedBy(func(message string) bool {
+		return strings.HasSuffix(message, strings.ReplaceAll(inboundMessage, "\r\n", "\n"))
+	}), []string{"tasks+abc@todo.example.com"}).Return(&models.InboundMailResult{}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := handlers.NewInboundSMTPServer(mockService, config.InboundSMTP{Hostname: "todo.example.com", Timeout: 5 * time.Second, MaxRecipients: 2}, 1024)
+	go server.Serve(ctx, listener)
+
+	addr := listener.Addr().String()
+	message := "From: sam@example.com\r\nTo: tasks+abc@todo.example.com\r\n" + inboundMessage
+
+	t.Run("Delivered", func(t *testing.T) {
+		err := smtp.SendMail(addr, nil, "sam@example.com", []string{"tasks+abc@todo.example.com"}, []byte(message))
+		require.NoError(t, err)
+		mockService.AssertCalled(t, "Receive", mock.Anything, []string{"tasks+abc@todo.example.com"})
+	})
+
+	t.Run("Unknown recipient", func(t *testing.T) {
+		err := smtp.SendMail(addr, nil, "sam@example.com", []string{"tasks+nope@todo.example.com"}, []byte(message))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "550")
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		err := smtp.SendMail(addr, nil, "sam@example.com", []string{"tasks+abc@todo.example.com"}, []byte(message+strings.Repeat("x", 2048)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "552")
+	})
+
+	t.Run("Command order", func(t *testing.T) {
+		client, err := smtp.Dial(addr)
+		require.NoError(t, err)
+		defer client.Close()
+
+		id, err := client.Text.Cmd("RCPT TO:<tasks+abc@todo.example.com>")
+		require.NoError(t, err)
+		client.Text.StartResponse(id)
+		code, _, _ := client.Text.ReadResponse(0)
+		client.Text.EndResponse(id)
+		assert.Equal(t, 503, code)
+
+		require.NoError(t, client.Hello("client.example.com"))
+		require.NoError(t, client.Mail("sam@example.com"))
+		_, err = client.Data()
+		assert.ErrorContains(t, err, "503")
+		require.NoError(t, client.Quit())
+	})
+
+	t.Run("Line too long", func(t *testing.T) {
+		client, err := smtp.Dial(addr)
+		require.NoError(t, err)
+		defer client.Close()
+
+		_, err = client.Text.Cmd("NOOP %s", strings.Repeat("x", 1000))
+		require.NoError(t, err)
+		code, _, _ := client.Text.ReadResponse(0)
+		assert.Equal(t, 500, code)
+	})
+}
+
+func TestInboundSMTPServerConnectionLimit(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := handlers.NewInboundSMTPServer(new(MockInboundMailService), config.InboundSMTP{Hostname: "todo.example.com", Timeout: 5 * time.Second, MaxConnections: 1}, 1024)
+	go server.Serve(ctx, listener)
+
+	first, err := smtp.Dial(listener.Addr().String())
+	require.NoError(t, err)
+
+	_, err = smtp.Dial(listener.Addr().String())
+	assert.ErrorContains(t, err, "421")
+
+	// the slot is free again once the first session ends
+	require.NoError(t, first.Quit())
+	assert.Eventually(t, func() bool {
+		client, err := smtp.Dial(listener.Addr().String())
+		if err != nil {
+			return false
+		}
+		client.Quit()
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/rs/zerolog"
+)
+
+// smtpMaxLine is the longest command line of RFC 5321, CRLF included
+const smtpMaxLine = 1000
+
+var errSMTPLineTooLong = errors.New("line too long")
+
+// InboundSMTPServer is a minimal SMTP listener for the inbound addresses. It
+// has no TLS or authentication and is meant to receive the mail of a relay in
+// front of it: recipients are checked at RCPT and the message is handed to the
+// inbound mail service after DATA
+type InboundSMTPServer struct {
+	service  IInboundMailService
+	cfg      config.InboundSMTP
+	maxBytes int64
+	log      zerolog.Logger
+}
+
+func NewInboundSMTPServer(service IInboundMailService, cfg config.InboundSMTP, maxBytes int64) *InboundSMTPServer {
+	return &InboundSMTPServer{service: service, cfg: cfg, maxBytes: maxBytes, log: logger.GetLogger()}
+}
+
+// Run listens on the configured address until ctx is done
+func (s *InboundSMTPServer) Run(ctx context.Context) {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		s.log.Error().Err(err).Str("addr", s.cfg.Addr).Msg("Failed to start SMTP listener")
+		return
+	}
+
+	s.log.Info().Str("addr", s.cfg.Addr).Msg("SMTP listener started")
+	if err := s.Serve(ctx, listener); err != nil {
+		s.log.Error().Err(err).Msg("SMTP listener stopped")
+	}
+}
+
+// Serve accepts SMTP sessions on l until ctx is done. Connections over the
+// configured limit are turned away with a temporary error
+func (s *InboundSMTPServer) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var sessions chan struct{}
+	if s.cfg.MaxConnections > 0 {
+		sessions = make(chan struct{}, s.cfg.MaxConnections)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if sessions == nil {
+			go s.session(conn)
+			continue
+		}
+
+		select {
+		case sessions <- struct{}{}:
+			go func() {
+				defer func() { <-sessions }()
+				s.session(conn)
+			}()
+		default:
+			conn.SetWriteDeadline(time.Now().Add(time.Second))
+			io.WriteString(conn, "421 4.3.2 Too many connections, try again later\r\n")
+			conn.Close()
+		}
+	}
+}
+
+// smtpSession is the state of one connection, reset by RSET and after DATA
+type smtpSession struct {
+	greeted    bool
+	sender     *string
+	recipients []string
+}
+
+func (s *InboundSMTPServer) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReaderSize(conn, smtpMaxLine)
+	w := textproto.NewWriter(bufio.NewWriter(conn))
+
+	reply := func(lines ...string) bool {
+		if s.cfg.Timeout > 0 {
+			conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+		}
+		for _, line := range lines {
+			if err := w.PrintfLine("%s", line); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !reply("220 " + s.cfg.Hostname + " ESMTP ready") {
+		return
+	}
+
+	state := smtpSession{}
+	for {
+		line, err := readSMTPLine(r)
+		if err != nil {
+			if errors.Is(err, errSMTPLineTooLong) {
+				reply("500 5.5.2 Line too long")
+			}
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		var lines []string
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			state = smtpSession{greeted: true}
+			lines = []string{"250 " + s.cfg.Hostname}
+		case "EHLO":
+			state = smtpSession{greeted: true}
+			lines = []string{"250-" + s.cfg.Hostname, "250-SIZE " + strconv.FormatInt(s.maxBytes, 10), "250 8BITMIME"}
+		case "MAIL":
+			lines = []string{s.mail(&state, arg)}
+		case "RCPT":
+			lines = []string{s.rcpt(&state, arg)}
+		case "DATA":
+			if len(state.recipients) == 0 {
+				lines = []string{"503 5.5.1 RCPT first"}
+				break
+			}
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			lines = []string{s.data(&state, textproto.NewReader(r).DotReader())}
+		case "RSET":
+			state = smtpSession{greeted: state.greeted}
+			lines = []string{"250 2.0.0 OK"}
+		case "NOOP":
+			lines = []string{"250 2.0.0 OK"}
+		case "VRFY":
+			lines = []string{"252 2.5.0 Cannot verify user"}
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			lines = []string{"502 5.5.2 Command not implemented"}
+		}
+
+		if !reply(lines...) {
+			return
+		}
+	}
+}
+
+func (s *InboundSMTPServer) mail(state *smtpSession, arg string) string {
+	if !state.greeted {
+		return "503 5.5.1 EHLO first"
+	}
+	if state.sender != nil {
+		return "503 5.5.1 Sender already given"
+	}
+
+	sender, params, ok := smtpPath(arg, "FROM:")
+	if !ok {
+		return "501 5.5.4 Syntax: MAIL FROM:<address>"
+	}
+	for _, param := range strings.Fields(params) {
+		if key, value, _ := strings.Cut(param, "="); strings.EqualFold(key, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > s.maxBytes {
+				return "552 5.3.4 Message too large"
+			}
+		}
+	}
+
+	state.sender = &sender
+	return "250 2.1.0 OK"
+}
+
+func (s *InboundSMTPServer) rcpt(state *smtpSession, arg string) string {
+	if state.sender == nil {
+		return "503 5.5.1 MAIL first"
+	}
+
+	recipient, _, ok := smtpPath(arg, "TO:")
+	if !ok || recipient == "" {
+		return "501 5.5.4 Syntax: RCPT TO:<address>"
+	}
+	if s.cfg.MaxRecipients > 0 && len(state.recipients) >= s.cfg.MaxRecipients {
+		return "452 4.5.3 Too many recipients"
+	}
+
+	if _, err := s.service.Recipient(recipient); err != nil {
+		if errors.Is(err, services.ErrInboundAddressNotFound) {
+			return "550 5.1.1 No such mailbox"
+		}
+		s.log.Error().Err(err).Msg("Failed to look up inbound address")
+		return "451 4.3.0 Try again later"
+	}
+
+	state.recipients = append(state.recipients, recipient)
+	return "250 2.1.5 OK"
+}
+
+func (s *InboundSMTPServer) data(state *smtpSession, r io.Reader) string {
+	recipients := state.recipients
+	*state = smtpSession{greeted: true}
+
+	raw, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return "451 4.3.0 Failed to read the message"
+	}
+	if int64(len(raw)) > s.maxBytes {
+		io.Copy(io.Discard, r)
+		return "552 5.3.4 Message too large"
+	}
+
+	if _, err := s.service.Receive(bytes.NewReader(raw), recipients); err != nil {
+		if errors.Is(err, services.ErrInboundAddressNotFound) {
+			return "550 5.1.1 No such mailbox"
+		}
+		if errors.As(err, &baseErr) {
+			return "554 5.6.0 " + err.Error()
+		}
+		s.log.Error().Err(err).Msg("Failed to create task from email")
+		return "451 4.3.0 Try again later"
+	}
+	return "250 2.0.0 OK"
+}
+
+// readSMTPLine reads a command line without its line ending. The buffer of r
+// holds the longest line allowed, a longer one is an error
+func readSMTPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return "", errSMTPLineTooLong
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// smtpPath reads the <address> of a MAIL FROM or RCPT TO argument and returns
+// the ESMTP parameters after it
+func smtpPath(arg, prefix string) (string, string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	rest := strings.TrimSpace(arg[len(prefix):])
+	end := strings.Index(rest, ">")
+	if !strings.HasPrefix(rest, "<") || end < 0 {
+		return "", "", false
+	}
+	return rest[1:end], strings.TrimSpace(rest[end+1:]), true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IMFAService interface {
+	Enroll(userID int) (*models.MFAEnrollment, error)
+	PendingQRCode(userID int) ([]byte, error)
+	Confirm(userID int, code string) ([]string, error)
+}
+
+type MFAHandler struct {
+	service IMFAService
+}
+
+func NewMFAHandler(service IMFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+// @Summary EnrollMFA
+// @Description start TOTP enrollment, returns secret, otpauth provisioning URI and QR code PNG (base64)
+// @Security Auth
+// @Produce  json
+// @Tags mfa
+// @Success 200 {object} models.MFAEnrollment
+// @Failure 401
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	enrollment, err := h.service.Enroll(c.GetInt("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// @Summary EnrollMFAQRCode
+// @Description QR code of the pending TOTP enrollment as PNG image, for the secret returned by /auth/mfa/enroll
+// @Security Auth
+// @Produce  png
+// @Tags mfa
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/enroll/qr [get]
+func (h *MFAHandler) EnrollQRCode(c *gin.Context) {
+	png, err := h.service.PendingQRCode(c.GetInt("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFANotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		}
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// @Summary ConfirmMFA
+// @Description confirm TOTP enrollment with a valid code, returns one-time recovery codes
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags mfa
+// @Param input body MFACodeData true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	codes, err := h.service.Confirm(c.GetInt("user_id"), req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrMFANotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
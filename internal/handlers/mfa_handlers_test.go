@@ -0,0 +1,161 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) Enroll(userID int) (*models.MFAEnrollment, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockMFAService) PendingQRCode(userID int) ([]byte, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(userID int, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestEnrollMFA(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Successful enrollment", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("Enroll", 1).Return(&models.MFAEnrollment{
+			Secret:          "SECRET",
+			ProvisioningURI: "otpauth://totp/TODO%20App:alice?secret=SECRET",
+			QRCode:          []byte("png"),
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/mfa/enroll", nil)
+		c.Set("user_id", 1)
+
+		handler.Enroll(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"provisioning_uri":"otpauth://totp/TODO%20App:alice?secret=SECRET"`)
+		assert.Contains(t, w.Body.String(), `"qr_png":"cG5n"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("QR code image", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("PendingQRCode", 1).Return([]byte("png"), nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/mfa/enroll/qr", nil)
+		c.Set("user_id", 1)
+
+		handler.EnrollQRCode(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "png", w.Body.String())
+		mockService.AssertNotCalled(t, "Enroll", mock.Anything)
+	})
+
+	t.Run("QR code without enrollment", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("PendingQRCode", 1).Return([]byte(nil), services.ErrMFANotEnrolled)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/mfa/enroll/qr", nil)
+		c.Set("user_id", 1)
+
+		handler.EnrollQRCode(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("Enroll", 1).Return((*models.MFAEnrollment)(nil), services.ErrMFAAlreadyEnabled)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/mfa/enroll", nil)
+		c.Set("user_id", 1)
+
+		handler.Enroll(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Successful confirmation", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("Confirm", 1, "123456").Return([]string{"abcde-fghjk"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/mfa/confirm", bytes.NewBufferString(`{"code": "123456"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", 1)
+
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"recovery_codes":["abcde-fghjk"]`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockMFAService)
+		handler := handlers.NewMFAHandler(mockService)
+
+		mockService.On("Confirm", 1, "000000").Return([]string(nil), services.ErrInvalidMFACode)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/mfa/confirm", bytes.NewBufferString(`{"code": "000000"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", 1)
+
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid two-factor code")
+	})
+}
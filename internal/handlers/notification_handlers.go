@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type INotificationService interface {
+	ListNotifications(userID int, filter models.NotificationFilter) (*models.NotificationPage, error)
+	MarkRead(notificationID, userID int) error
+	MarkAllRead(userID int) (int64, error)
+	GetPreferences(userID int) ([]models.NotificationPreference, error)
+	UpdatePreferences(userID int, updates map[string]bool) ([]models.NotificationPreference, error)
+}
+
+type NotificationHandler struct {
+	service INotificationService
+}
+
+func NewNotificationHandler(service INotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// @Summary ListNotifications
+// @Description list notifications of the current user newest first
+// @Security Auth
+// @Produce  json
+// @Tags notifications
+// @Param unread query bool false "only unread notifications"
+// @Param limit query int false "page size, 20 by default, at most 100"
+// @Param offset query int false "number of notifications to skip"
+// @Success 200 {object} models.NotificationPage
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/ [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	var filter models.NotificationFilter
+	var err error
+
+	if raw := c.Query("unread"); raw != "" {
+		if filter.UnreadOnly, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unread flag"})
+			return
+		}
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	if raw := c.Query("offset"); raw != "" {
+		if filter.Offset, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+	}
+
+	page, err := h.service.ListNotifications(c.GetInt("user_id"), filter)
+	if err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// @Summary MarkRead
+// @Description mark notification with {id} as read
+// @Security Auth
+// @Produce  json
+// @Tags notifications
+// @Param id path int true "Notification ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	notificationID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.MarkRead(notificationID, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// @Summary MarkAllRead
+// @Description mark all notifications of the current user as read
+// @Security Auth
+// @Produce  json
+// @Tags notifications
+// @Success 200 {object} MarkAllReadResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/read [post]
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	updated, err := h.service.MarkAllRead(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// @Summary GetNotificationPreferences
+// @Description list notification types with their enabled flag for the current user
+// @Security Auth
+// @Produce  json
+// @Tags notifications
+// @Success 200 {object} []models.NotificationPreference
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	preferences, err := h.service.GetPreferences(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// @Summary UpdateNotificationPreferences
+// @Description switch notification types on or off, types missing in the body keep their setting
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags notifications
+// @Param input body map[string]bool true "notification type to enabled flag, e.g. {\"comment_mention\": false}"
+// @Success 200 {object} []models.NotificationPreference
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var updates map[string]bool
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	preferences, err := h.service.UpdatePreferences(c.GetInt("user_id"), updates)
+	if err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
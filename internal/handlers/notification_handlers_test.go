@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotificationService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationService) ListNotifications(userID int, filter models.NotificationFilter) (*models.NotificationPage, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).(*models.NotificationPage), args.Error(1)
+}
+
+func (m *MockNotificationService) MarkRead(notificationID, userID int) error {
+	args := m.Called(notificationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) MarkAllRead(userID int) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationService) GetPreferences(userID int) ([]models.NotificationPreference, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.NotificationPreference), args.Error(1)
+}
+
+func (m *MockNotificationService) UpdatePreferences(userID int, updates map[string]bool) ([]models.NotificationPreference, error) {
+	args := m.Called(userID, updates)
+	return args.Get(0).([]models.NotificationPreference), args.Error(1)
+}
+
+func TestListNotificationsHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Unread page", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockNotificationService)
+		handler := handlers.NewNotificationHandler(mockService)
+
+		mockService.On("ListNotifications", 1, models.NotificationFilter{UnreadOnly: true, Limit: 10, Offset: 20}).
+			Return(&models.NotificationPage{Notifications: []models.Notification{{ID: 9}}, UnreadCount: 31, Limit: 10, Offset: 20, HasMore: true}, nil)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/notifications/?unread=true&limit=10&offset=20", "", nil)
+		handler.ListNotifications(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"unread_count":31`)
+		assert.Contains(t, w.Body.String(), `"has_more":true`)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockNotificationService)
+		handler := handlers.NewNotificationHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/notifications/?limit=ten", "", nil)
+		handler.ListNotifications(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListNotifications", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Limit out of range", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockNotificationService)
+		handler := handlers.NewNotificationHandler(mockService)
+
+		mockService.On("ListNotifications", 1, models.NotificationFilter{Limit: 1000}).
+			Return((*models.NotificationPage)(nil), helpers.NewSpecificValidationError("limit", "must be between 1 and 100"))
+
+		c, w := newWorkspaceContext(http.MethodGet, "/notifications/?limit=1000", "", nil)
+		handler.ListNotifications(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestMarkReadHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"Foreign notification", services.ErrNotificationNotFound, http.StatusNotFound},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockNotificationService)
+			handler := handlers.NewNotificationHandler(mockService)
+
+			mockService.On("MarkRead", 9, 1).Return(tt.err)
+
+			c, w := newWorkspaceContext(http.MethodPost, "/notifications/9/read", "", gin.Params{{Key: "id", Value: "9"}})
+			handler.MarkRead(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestUpdatePreferencesHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockNotificationService)
+		handler := handlers.NewNotificationHandler(mockService)
+
+		mockService.On("UpdatePreferences", 1, map[string]bool{"comment_mention": false}).
+			Return([]models.NotificationPreference{{Type: "comment_mention", Enabled: false}}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPut, "/notifications/preferences", `{"comment_mention":false}`, nil)
+		handler.UpdatePreferences(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"type":"comment_mention","enabled":false}]`, w.Body.String())
+	})
+
+	t.Run("Not a flag", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockNotificationService)
+		handler := handlers.NewNotificationHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodPut, "/notifications/preferences", `{"comment_mention":"off"}`, nil)
+		handler.UpdatePreferences(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "UpdatePreferences", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc/"
+)
+
+type IOIDCService interface {
+	Providers() []string
+	Begin(ctx context.Context, provider string, linkUserID int) (string, string, error)
+	Complete(ctx context.Context, provider, stateToken, state, code string) (string, error)
+}
+
+type OIDCHandler struct {
+	service    IOIDCService
+	successURL string
+	stateTTL   int
+}
+
+// NewOIDCHandler creates the handler. After a successful callback the
+// browser is redirected to successURL, or gets a JSON answer if it's empty
+func NewOIDCHandler(service IOIDCService, successURL string, stateTTLSeconds int) *OIDCHandler {
+	return &OIDCHandler{service: service, successURL: successURL, stateTTL: stateTTLSeconds}
+}
+
+// @Summary OIDCProviders
+// @Description list configured single sign-on providers
+// @Produce  json
+// @Tags oidc
+// @Success 200 {object} OIDCProvidersResponse
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, OIDCProvidersResponse{Providers: h.service.Providers()})
+}
+
+// @Summary OIDCLogin
+// @Description redirect to the provider's login page (authorization code flow with PKCE)
+// @Tags oidc
+// @Param provider path string true "provider name"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	h.begin(c, 0)
+}
+
+// @Summary OIDCLink
+// @Description link a provider account to the current user, redirects to the provider's login page
+// @Security Auth
+// @Tags oidc
+// @Param provider path string true "provider name"
+// @Success 302
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/link [get]
+func (h *OIDCHandler) Link(c *gin.Context) {
+	h.begin(c, c.GetInt("user_id"))
+}
+
+// @Summary OIDCCallback
+// @Description redirect target of the provider, sets auth cookie
+// @Produce  json
+// @Tags oidc
+// @Param provider path string true "provider name"
+// @Param code query string true "authorization code"
+// @Param state query string true "state"
+// @Success 200 {object} SuccessResponse
+// @Success 202 {object} MFARequiredResponse
+// @Success 302
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", false, true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "provider error: " + providerErr})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" || stateToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidOIDCState.Error()})
+		return
+	}
+
+	token, err := h.service.Complete(c.Request.Context(), c.Param("provider"), stateToken, c.Query("state"), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFARequired):
+			h.requireMFA(c, token)
+		case errors.Is(err, services.ErrUnknownOIDCProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidOIDCState), errors.Is(err, services.ErrIdentityAlreadyLinked):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, oidc.ErrExchange):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "sign in with provider failed"})
+		case errors.Is(err, services.ErrOIDCProvisioningDisabled), errors.Is(err, services.ErrOIDCDomainNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		}
+		return
+	}
+
+	setAuthCookie(c, token)
+
+	if h.successURL != "" {
+		c.Redirect(http.StatusFound, h.successURL)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signed in"})
+}
+
+// requireMFA hands the mfa pending token to the client, which finishes the
+// login at /auth/login/mfa. The token is passed in the fragment of the success
+// URL so it doesn't reach server logs
+func (h *OIDCHandler) requireMFA(c *gin.Context, token string) {
+	if h.successURL != "" {
+		c.Redirect(http.StatusFound, h.successURL+"#mfa_token="+url.QueryEscape(token))
+		return
+	}
+	c.JSON(http.StatusAccepted, MFARequiredResponse{MFARequired: true, MFAToken: token})
+}
+
+func (h *OIDCHandler) begin(c *gin.Context, linkUserID int) {
+	authURL, stateToken, err := h.service.Begin(c.Request.Context(), c.Param("provider"), linkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, stateToken, h.stateTTL, oidcStateCookiePath, "", false, true)
+	c.Redirect(http.StatusFound, authURL)
+}
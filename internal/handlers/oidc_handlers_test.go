@@ -0,0 +1,230 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) Providers() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m *MockOIDCService) Begin(ctx context.Context, provider string, linkUserID int) (string, string, error) {
+	args := m.Called(provider, linkUserID)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCService) Complete(ctx context.Context, provider, stateToken, state, code string) (string, error) {
+	args := m.Called(provider, stateToken, state, code)
+	return args.String(0), args.Error(1)
+}
+
+func newOIDCContext(w *httptest.ResponseRecorder, target string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	c.Params = gin.Params{{Key: "provider", Value: "company"}}
+	return c
+}
+
+func TestOIDCLoginHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Redirects and stores state", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		mockService.On("Begin", "company", 0).Return("https://sso.example.com/authorize?state=s", "signed-state", nil)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/login")
+
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://sso.example.com/authorize?state=s", w.Header().Get("Location"))
+		assert.Contains(t, w.Header().Get("Set-Cookie"), "oidc_state=signed-state")
+		assert.Contains(t, w.Header().Get("Set-Cookie"), "HttpOnly")
+	})
+
+	t.Run("Link uses current user", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		mockService.On("Begin", "company", 1).Return("https://sso.example.com/authorize", "signed-state", nil)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/link")
+		c.Set("user_id", 1)
+
+		handler.Link(c)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		mockService.On("Begin", "company", 0).Return("", "", services.ErrUnknownOIDCProvider)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/login")
+
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Provider unavailable", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		mockService.On("Begin", "company", 0).Return("", "", errors.New("discovery failed"))
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/login")
+
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
+
+func TestOIDCCallbackHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Sets auth cookie and redirects", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "http://localhost:8080/", 600)
+
+		mockService.On("Complete", "company", "signed-state", "s", "code").Return("session-token", nil)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/callback?code=code&state=s")
+		c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+
+		handler.Callback(c)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "http://localhost:8080/", w.Header().Get("Location"))
+		cookies := w.Header().Values("Set-Cookie")
+		assert.Contains(t, cookies[0], "oidc_state=;")
+		assert.Contains(t, cookies[1], "Authorization=session-token")
+	})
+
+	t.Run("Second factor required", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "http://localhost:8080/", 600)
+
+		mockService.On("Complete", "company", "signed-state", "s", "code").Return("mfa-token", services.ErrMFARequired)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/callback?code=code&state=s")
+		c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+
+		handler.Callback(c)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "http://localhost:8080/#mfa_token=mfa-token", w.Header().Get("Location"))
+		assert.NotContains(t, w.Header().Get("Set-Cookie"), "Authorization=")
+	})
+
+	t.Run("Second factor required without success URL", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		mockService.On("Complete", "company", "signed-state", "s", "code").Return("mfa-token", services.ErrMFARequired)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/callback?code=code&state=s")
+		c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+
+		handler.Callback(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.JSONEq(t, `{"mfa_required": true, "mfa_token": "mfa-token"}`, w.Body.String())
+		assert.NotContains(t, w.Header().Get("Set-Cookie"), "Authorization=")
+	})
+
+	t.Run("Missing state cookie", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/callback?code=code&state=s")
+
+		handler.Callback(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Provider returned error", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockOIDCService)
+		handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+		w := httptest.NewRecorder()
+		c := newOIDCContext(w, "/auth/oidc/company/callback?error=access_denied&state=s")
+
+		handler.Callback(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "access_denied")
+	})
+
+	errorCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"Invalid state", services.ErrInvalidOIDCState, http.StatusBadRequest},
+		{"Exchange failed", oidc.ErrExchange, http.StatusUnauthorized},
+		{"Provisioning disabled", services.ErrOIDCProvisioningDisabled, http.StatusForbidden},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockOIDCService)
+			handler := handlers.NewOIDCHandler(mockService, "", 600)
+
+			mockService.On("Complete", "company", "signed-state", "s", "code").Return("", tt.err)
+
+			w := httptest.NewRecorder()
+			c := newOIDCContext(w, "/auth/oidc/company/callback?code=code&state=s")
+			c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+
+			handler.Callback(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NotContains(t, w.Header().Get("Set-Cookie"), "Authorization=")
+		})
+	}
+}
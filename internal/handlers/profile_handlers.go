@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IProfileService interface {
+	GetProfile(userID int) (*models.Profile, error)
+	UpdateProfile(userID int, updates map[string]interface{}) (*models.Profile, error)
+	DeleteAccount(userID int, req models.DeleteAccountRequest) error
+}
+
+type IExportService interface {
+	BuildExport(userID int) (*models.DataExport, error)
+	WriteArchive(w io.Writer, export *models.DataExport) error
+}
+
+type ProfileHandler struct {
+	service  IProfileService
+	exporter IExportService
+}
+
+func NewProfileHandler(service IProfileService, exporter IExportService) *ProfileHandler {
+	return &ProfileHandler{service: service, exporter: exporter}
+}
+
+// @Summary GetProfile
+// @Description get profile of the current user
+// @Security Auth
+// @Produce  json
+// @Tags me
+// @Success 200 {object} models.Profile
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me [get]
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	profile, err := h.service.GetProfile(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary UpdateProfile
+// @Description update profile of the current user, only the given fields are changed
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags me
+// @Param input body UpdateProfileData true "profile fields"
+// @Success 200 {object} models.Profile
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me [patch]
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(c.GetInt("user_id"), updates)
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueUser) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username already taken"})
+			return
+		}
+
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary DeleteAccount
+// @Description delete the current user with all tasks and related data, requires the password. Accounts created through SSO have no password and send a current TOTP or recovery code in code instead, without 2FA they have to set a password first. Tasks created in workspaces pass to the workspace owner, owners have to delete their workspaces first
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags me
+// @Param input body DeleteAccountData true "password, or 2FA code for accounts without a password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me [delete]
+func (h *ProfileHandler) DeleteAccount(c *gin.Context) {
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.DeleteAccount(c.GetInt("user_id"), req); err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid password"})
+			return
+		}
+
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid code"})
+			return
+		}
+
+		if errors.Is(err, services.ErrNoLocalPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors.Is(err, services.ErrOwnsWorkspace) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.SetCookie("Authorization", "", -1, "", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// @Summary ExportData
+// @Description download a ZIP archive with the profile, tasks and all related data as JSON
+// @Security Auth
+// @Produce  application/zip
+// @Tags me
+// @Success 200 {file} file
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /me/export [get]
+func (h *ProfileHandler) ExportData(c *gin.Context) {
+	export, err := h.exporter.BuildExport(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	filename := fmt.Sprintf("todo-export-%s.zip", export.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	if err := h.exporter.WriteArchive(c.Writer, export); err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,199 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) GetProfile(userID int) (*models.Profile, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) UpdateProfile(userID int, updates map[string]interface{}) (*models.Profile, error) {
+	args := m.Called(userID, updates)
+	return args.Get(0).(*models.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) DeleteAccount(userID int, req models.DeleteAccountRequest) error {
+	args := m.Called(userID, req)
+	return args.Error(0)
+}
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) BuildExport(userID int) (*models.DataExport, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockExportService) WriteArchive(w io.Writer, export *models.DataExport) error {
+	args := m.Called(w, export)
+	_, _ = w.Write([]byte("PK"))
+	return args.Error(0)
+}
+
+func TestGetProfileHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockProfileService)
+	handler := handlers.NewProfileHandler(mockService, new(MockExportService))
+
+	mockService.On("GetProfile", 1).Return(&models.Profile{ID: 1, Username: "alice", Timezone: "UTC"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/me", nil)
+	c.Set("user_id", 1)
+
+	handler.GetProfile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+	assert.NotContains(t, w.Body.String(), "password")
+}
+
+func TestUpdateProfileHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"Username taken", repository.ErrUniqueUser, http.StatusBadRequest},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockProfileService)
+			handler := handlers.NewProfileHandler(mockService, new(MockExportService))
+
+			mockService.On("UpdateProfile", 1, map[string]interface{}{"username": "bob"}).
+				Return(&models.Profile{ID: 1, Username: "bob"}, tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/me", bytes.NewBufferString(`{"username":"bob"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", 1)
+
+			handler.UpdateProfile(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteAccountHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		req        models.DeleteAccountRequest
+		serviceErr error
+		wantStatus int
+	}{
+		{"Success", `{"password":"secret"}`, models.DeleteAccountRequest{Password: "secret"}, nil, http.StatusOK},
+		{"Success with 2FA code", `{"code":"123456"}`, models.DeleteAccountRequest{Code: "123456"}, nil, http.StatusOK},
+		{"Wrong password", `{"password":"secret"}`, models.DeleteAccountRequest{Password: "secret"}, services.ErrInvalidCredentials, http.StatusForbidden},
+		{"Wrong 2FA code", `{"code":"000000"}`, models.DeleteAccountRequest{Code: "000000"}, services.ErrInvalidMFACode, http.StatusForbidden},
+		{"No local password", `{"code":"123456"}`, models.DeleteAccountRequest{Code: "123456"}, services.ErrNoLocalPassword, http.StatusBadRequest},
+		{"Workspace owner", `{"password":"secret"}`, models.DeleteAccountRequest{Password: "secret"}, services.ErrOwnsWorkspace, http.StatusConflict},
+		{"Throttled", `{"password":"secret"}`, models.DeleteAccountRequest{Password: "secret"}, &services.LockedError{RetryAfter: time.Minute}, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockProfileService)
+			handler := handlers.NewProfileHandler(mockService, new(MockExportService))
+
+			mockService.On("DeleteAccount", 1, tt.req).Return(tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodDelete, "/me", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", 1)
+
+			handler.DeleteAccount(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.serviceErr == nil {
+				assert.Contains(t, w.Header().Get("Set-Cookie"), "Authorization=;")
+			}
+		})
+	}
+}
+
+func TestExportDataHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockExporter := new(MockExportService)
+		handler := handlers.NewProfileHandler(new(MockProfileService), mockExporter)
+
+		export := &models.DataExport{Username: "alice", ExportedAt: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}
+		mockExporter.On("BuildExport", 1).Return(export, nil)
+		mockExporter.On("WriteArchive", mock.Anything, export).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/me/export", nil)
+		c.Set("user_id", 1)
+
+		handler.ExportData(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="todo-export-20250301-090000.zip"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "PK", w.Body.String())
+	})
+
+	t.Run("Collect error", func(t *testing.T) {
+		t.Parallel()
+		mockExporter := new(MockExportService)
+		handler := handlers.NewProfileHandler(new(MockProfileService), mockExporter)
+
+		mockExporter.On("BuildExport", 1).Return((*models.DataExport)(nil), errors.New("db down"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/me/export", nil)
+		c.Set("user_id", 1)
+
+		handler.ExportData(c)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		mockExporter.AssertNotCalled(t, "WriteArchive", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type IQuickAddService interface {
+	QuickAdd(userID int, req models.QuickAddRequest) (*models.QuickAddResult, error)
+}
+
+type QuickAddHandler struct {
+	service IQuickAddService
+}
+
+func NewQuickAddHandler(service IQuickAddService) *QuickAddHandler {
+	return &QuickAddHandler{service: service}
+}
+
+// @Summary QuickAddTask
+// @Description create a task from one line of text such as "Submit report next Friday 5pm !high #work +reports every 2 weeks". Dates and times are read in the time zone of the profile; #tag and +project words become tags, !high, !medium or !low the priority and a repetition the recurrence, the other words the title. The recognized spans are returned with the task, with dry_run the task is only parsed
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags tasks
+// @Param input body models.QuickAddRequest true "text of the task"
+// @Success 200 {object} models.QuickAddResult "dry run"
+// @Success 201 {object} models.QuickAddResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/quick [post]
+func (h *QuickAddHandler) QuickAdd(c *gin.Context) {
+	var req models.QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	result, err := h.service.QuickAdd(c.GetInt("user_id"), req)
+	if err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if abortWorkspaceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockQuickAddService struct {
+	mock.Mock
+}
+
+func (m *MockQuickAddService) QuickAdd(userID int, req models.QuickAddRequest) (*models.QuickAddResult, error) {
+	args := m.Called(userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.QuickAddResult), args.Error(1)
+}
+
+func TestQuickAddHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	result := &models.QuickAddResult{
+		Task:  models.Task{ID: 7, UserID: 1, Title: "Buy milk", Status: "pending", Tags: models.StringList{"home"}},
+		Spans: []models.QuickAddSpan{{Kind: models.QuickAddTag, Text: "#home", Value: "home", Start: 9, End: 14}},
+	}
+	validation := fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("title", "title is required"))
+
+	tests := []struct {
+		name   string
+		body   string
+		req    *models.QuickAddRequest
+		result *models.QuickAddResult
+		err    error
+		want   int
+	}{
+		{name: "Created", body: `{"text": "Buy milk #home"}`, req: &models.QuickAddRequest{Text: "Buy milk #home"}, result: result, want: http.StatusCreated},
+		{name: "Dry run", body: `{"text": "Buy milk #home", "dry_run": true}`, req: &models.QuickAddRequest{Text: "Buy milk #home", DryRun: true}, result: result, want: http.StatusOK},
+		{name: "No text", body: `{}`, want: http.StatusBadRequest},
+		{name: "Without a title", body: `{"text": "#home"}`, req: &models.QuickAddRequest{Text: "#home"}, err: validation, want: http.StatusBadRequest},
+		{name: "Forbidden workspace", body: `{"text": "Buy milk", "workspace_id": 3}`, req: &models.QuickAddRequest{Text: "Buy milk", WorkspaceID: intPtr(3)}, err: services.ErrWorkspaceForbidden, want: http.StatusForbidden},
+		{name: "Failure", body: `{"text": "Buy milk"}`, req: &models.QuickAddRequest{Text: "Buy milk"}, err: errors.New("db down"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockQuickAddService)
+			handler := handlers.NewQuickAddHandler(mockService)
+			if tt.req != nil {
+				mockService.On("QuickAdd", 1, *tt.req).Return(tt.result, tt.err)
+			}
+
+			c, w := newWorkspaceContext(http.MethodPost, "/api/tasks/quick", tt.body, nil)
+			handler.QuickAdd(c)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.result != nil {
+				assert.Contains(t, w.Body.String(), `"spans":[{"kind":"tag","text":"#home","value":"home","start":9,"end":14}]`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IReminderService interface {
+	CreateReminder(reminder *models.Reminder) error
+	GetReminders(userID int, taskID *int) ([]models.Reminder, error)
+	SnoozeReminder(id, userID, minutes int) (*models.Reminder, error)
+	DismissReminder(id, userID int) error
+	DeleteReminder(id, userID int) error
+}
+
+type ReminderHandler struct {
+	service IReminderService
+}
+
+func NewReminderHandler(service IReminderService) *ReminderHandler {
+	return &ReminderHandler{service: service}
+}
+
+// @Summary CreateReminder
+// @Description remind the current user of task with {id} at remind_at or before_due_minutes before its due date, through in_app (default), email or webhook
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags reminders
+// @Param id path int true "Task ID"
+// @Param input body ReminderData true "reminder"
+// @Success 201 {object} models.Reminder
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/reminders [post]
+func (h *ReminderHandler) CreateReminder(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var reminder models.Reminder
+	if err := c.ShouldBindJSON(&reminder); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	reminder.TaskID = taskID
+	reminder.UserID = c.GetInt("user_id")
+	if err := h.service.CreateReminder(&reminder); err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reminder)
+}
+
+// @Summary ListTaskReminders
+// @Description reminders of the current user on task with {id} that are not dismissed, next to fire first
+// @Security Auth
+// @Produce  json
+// @Tags reminders
+// @Param id path int true "Task ID"
+// @Success 200 {array} models.Reminder
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/reminders [get]
+func (h *ReminderHandler) ListTaskReminders(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	reminders, err := h.service.GetReminders(c.GetInt("user_id"), &taskID)
+	if err != nil {
+		h.abort(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// @Summary ListReminders
+// @Description reminders of the current user that are not dismissed, next to fire first
+// @Security Auth
+// @Produce  json
+// @Tags reminders
+// @Success 200 {array} models.Reminder
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /reminders/ [get]
+func (h *ReminderHandler) ListReminders(c *gin.Context) {
+	reminders, err := h.service.GetReminders(c.GetInt("user_id"), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// @Summary SnoozeReminder
+// @Description fire reminder with {id} again in the given minutes, also after it fired
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags reminders
+// @Param id path int true "Reminder ID"
+// @Param input body models.SnoozeReminderRequest true "minutes to snooze"
+// @Success 200 {object} models.Reminder
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reminders/{id}/snooze [post]
+func (h *ReminderHandler) SnoozeReminder(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.SnoozeReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	reminder, err := h.service.SnoozeReminder(id, c.GetInt("user_id"), req.Minutes)
+	if err != nil {
+		h.abort(c, 0, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// @Summary DismissReminder
+// @Description stop reminder with {id}, it is no longer listed or fired
+// @Security Auth
+// @Produce  json
+// @Tags reminders
+// @Param id path int true "Reminder ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reminders/{id}/dismiss [post]
+func (h *ReminderHandler) DismissReminder(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DismissReminder(id, c.GetInt("user_id")); err != nil {
+		h.abort(c, 0, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder dismissed"})
+}
+
+// @Summary DeleteReminder
+// @Description delete reminder with {id}
+// @Security Auth
+// @Produce  json
+// @Tags reminders
+// @Param id path int true "Reminder ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reminders/{id} [delete]
+func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReminder(id, c.GetInt("user_id")); err != nil {
+		h.abort(c, 0, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder deleted"})
+}
+
+func (h *ReminderHandler) abort(c *gin.Context, taskID int, err error) {
+	switch {
+	case errors.Is(err, services.ErrReminderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &baseErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		abortTaskError(c, taskID, err)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReminderService struct {
+	mock.Mock
+}
+
+func (m *MockReminderService) CreateReminder(reminder *models.Reminder) error {
+	args := m.Called(reminder)
+	return args.Error(0)
+}
+
+func (m *MockReminderService) GetReminders(userID int, taskID *int) ([]models.Reminder, error) {
+	args := m.Called(userID, taskID)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderService) SnoozeReminder(id, userID, minutes int) (*models.Reminder, error) {
+	args := m.Called(id, userID, minutes)
+	return args.Get(0).(*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderService) DismissReminder(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockReminderService) DeleteReminder(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func TestCreateReminderHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Before due", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockReminderService)
+		handler := handlers.NewReminderHandler(mockService)
+
+		mockService.On("CreateReminder", mock.MatchedBy(func(r *models.Reminder) bool {
+			return r.TaskID == 42 && r.UserID == 1 && *r.BeforeDueMinutes == 60 && r.RemindAt == nil &&
+				len(r.Channels) == 2 && r.Channels[1] == models.ReminderChannelEmail
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.Reminder).ID = 7
+		}).Return(nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/tasks/42/reminders", `{"before_due_minutes": 60, "channels": ["in_app", "email"]}`, gin.Params{{Key: "id", Value: "42"}})
+		handler.CreateReminder(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":7`)
+	})
+
+	t.Run("Task without due date", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockReminderService)
+		handler := handlers.NewReminderHandler(mockService)
+
+		mockService.On("CreateReminder", mock.Anything).Return(helpers.NewSpecificValidationError("before_due_minutes", "task has no due date"))
+
+		c, w := newWorkspaceContext(http.MethodPost, "/tasks/42/reminders", `{"before_due_minutes": 60}`, gin.Params{{Key: "id", Value: "42"}})
+		handler.CreateReminder(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "task has no due date")
+	})
+}
+
+func TestSnoozeReminderHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockReminderService)
+		handler := handlers.NewReminderHandler(mockService)
+
+		mockService.On("SnoozeReminder", 7, 1, 15).Return(&models.Reminder{ID: 7, Status: models.ReminderPending}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/reminders/7/snooze", `{"minutes": 15}`, gin.Params{{Key: "id", Value: "7"}})
+		handler.SnoozeReminder(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	})
+
+	t.Run("Foreign reminder", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockReminderService)
+		handler := handlers.NewReminderHandler(mockService)
+
+		mockService.On("SnoozeReminder", 7, 1, 15).Return((*models.Reminder)(nil), services.ErrReminderNotFound)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/reminders/7/snooze", `{"minutes": 15}`, gin.Params{{Key: "id", Value: "7"}})
+		handler.SnoozeReminder(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDismissReminderHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockReminderService)
+	handler := handlers.NewReminderHandler(mockService)
+
+	mockService.On("DismissReminder", 7, 1).Return(nil)
+
+	c, w := newWorkspaceContext(http.MethodPost, "/reminders/7/dismiss", "", gin.Params{{Key: "id", Value: "7"}})
+	handler.DismissReminder(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/daioru/todo-app/internal/middlewares"
 	"github.com/gin-gonic/gin"
 	files "github.com/swaggo/files"
@@ -8,14 +10,77 @@ import (
 )
 
 type Handlers struct {
-	authHandler *AuthHandler
-	taskHandler *TaskHandler
+	authHandler         *AuthHandler
+	taskHandler         *TaskHandler
+	mfaHandler          *MFAHandler
+	accountHandler      *AccountHandler
+	oidcHandler         *OIDCHandler
+	profileHandler      *ProfileHandler
+	workspaceHandler    *WorkspaceHandler
+	commentHandler      *CommentHandler
+	notificationHandler *NotificationHandler
+	eventHandler        *EventHandler
+	wsHandler           *WSHandler
+	webhookHandler      *WebhookHandler
+	reminderHandler     *ReminderHandler
+	digestHandler       *DigestHandler
+	transferHandler     *TaskTransferHandler
+	calendarHandler     *CalendarHandler
+	accessTokenHandler  *AccessTokenHandler
+	caldavHandler       *CalDAVHandler
+	appImportHandler    *AppImportHandler
+	inboundMailHandler  *InboundMailHandler
+	chatHandler         *ChatHandler
+	quickAddHandler     *QuickAddHandler
 }
 
-func NewHandlers(authHandler *AuthHandler, taskHandler *TaskHandler) *Handlers {
+func NewHandlers(
+	authHandler *AuthHandler,
+	taskHandler *TaskHandler,
+	mfaHandler *MFAHandler,
+	accountHandler *AccountHandler,
+	oidcHandler *OIDCHandler,
+	profileHandler *ProfileHandler,
+	workspaceHandler *WorkspaceHandler,
+	commentHandler *CommentHandler,
+	notificationHandler *NotificationHandler,
+	eventHandler *EventHandler,
+	wsHandler *WSHandler,
+	webhookHandler *WebhookHandler,
+	reminderHandler *ReminderHandler,
+	digestHandler *DigestHandler,
+	transferHandler *TaskTransferHandler,
+	calendarHandler *CalendarHandler,
+	accessTokenHandler *AccessTokenHandler,
+	caldavHandler *CalDAVHandler,
+	appImportHandler *AppImportHandler,
+	inboundMailHandler *InboundMailHandler,
+	chatHandler *ChatHandler,
+	quickAddHandler *QuickAddHandler,
+) *Handlers {
 	return &Handlers{
-		authHandler: authHandler,
-		taskHandler: taskHandler,
+		authHandler:         authHandler,
+		taskHandler:         taskHandler,
+		mfaHandler:          mfaHandler,
+		accountHandler:      accountHandler,
+		oidcHandler:         oidcHandler,
+		profileHandler:      profileHandler,
+		workspaceHandler:    workspaceHandler,
+		commentHandler:      commentHandler,
+		notificationHandler: notificationHandler,
+		eventHandler:        eventHandler,
+		wsHandler:           wsHandler,
+		webhookHandler:      webhookHandler,
+		reminderHandler:     reminderHandler,
+		digestHandler:       digestHandler,
+		transferHandler:     transferHandler,
+		calendarHandler:     calendarHandler,
+		accessTokenHandler:  accessTokenHandler,
+		caldavHandler:       caldavHandler,
+		appImportHandler:    appImportHandler,
+		inboundMailHandler:  inboundMailHandler,
+		chatHandler:         chatHandler,
+		quickAddHandler:     quickAddHandler,
 	}
 }
 
@@ -26,14 +91,139 @@ func (h *Handlers) RegisterRoutes(r *gin.Engine) {
 		{
 			auth.POST("/register", h.authHandler.Register)
 			auth.POST("/login", h.authHandler.Login)
+			auth.POST("/login/mfa", h.authHandler.LoginMFA)
+			auth.POST("/password", middlewares.AuthMiddleware(), h.authHandler.ChangePassword)
+			auth.POST("/password/forgot", h.accountHandler.ForgotPassword)
+			auth.POST("/password/reset", h.accountHandler.ResetPassword)
+
+			auth.POST("/email", middlewares.AuthMiddleware(), h.accountHandler.SetEmail)
+			auth.POST("/email/resend", middlewares.AuthMiddleware(), h.accountHandler.ResendVerification)
+			auth.GET("/email/verify", h.accountHandler.VerifyEmail)
+
+			mfa := auth.Group("/mfa", middlewares.AuthMiddleware())
+			{
+				mfa.POST("/enroll", h.mfaHandler.Enroll)
+				mfa.GET("/enroll/qr", h.mfaHandler.EnrollQRCode)
+				mfa.POST("/confirm", h.mfaHandler.Confirm)
+			}
+
+			oidc := auth.Group("/oidc")
+			{
+				oidc.GET("/providers", h.oidcHandler.Providers)
+				oidc.GET("/:provider/login", h.oidcHandler.Login)
+				oidc.GET("/:provider/link", middlewares.AuthMiddleware(), h.oidcHandler.Link)
+				oidc.GET("/:provider/callback", h.oidcHandler.Callback)
+			}
 		}
 
+		me := api.Group("/me", middlewares.AuthMiddleware())
+		{
+			me.GET("", h.profileHandler.GetProfile)
+			me.PATCH("", h.profileHandler.UpdateProfile)
+			me.DELETE("", h.profileHandler.DeleteAccount)
+			me.GET("/export", h.profileHandler.ExportData)
+			me.GET("/digest", h.digestHandler.GetSettings)
+			me.PUT("/digest", h.digestHandler.UpdateSettings)
+			me.GET("/digest/preview", h.digestHandler.Preview)
+			me.POST("/calendar", h.calendarHandler.CreateFeed)
+			me.DELETE("/calendar", h.calendarHandler.RevokeFeed)
+			me.POST("/inbox", h.inboundMailHandler.CreateAddress)
+			me.DELETE("/inbox", h.inboundMailHandler.RevokeAddress)
+			me.POST("/chat", h.chatHandler.CreateLinkCode)
+			me.GET("/tokens", h.accessTokenHandler.ListTokens)
+			me.POST("/tokens", h.accessTokenHandler.CreateToken)
+			me.DELETE("/tokens/:id", h.accessTokenHandler.DeleteToken)
+		}
+
+		api.GET("/calendar/:token", h.calendarHandler.Feed)
+		api.POST("/inbound/mail", h.inboundMailHandler.Receive)
+		api.POST("/chat/commands", h.chatHandler.Command)
+
+		workspaces := api.Group("/workspaces", middlewares.AuthMiddleware())
+		{
+			workspaces.POST("/", h.workspaceHandler.CreateWorkspace)
+			workspaces.GET("/", h.workspaceHandler.ListWorkspaces)
+			workspaces.GET("/:id", h.workspaceHandler.GetWorkspace)
+			workspaces.PATCH("/:id", h.workspaceHandler.RenameWorkspace)
+			workspaces.DELETE("/:id", h.workspaceHandler.DeleteWorkspace)
+			workspaces.GET("/:id/members", h.workspaceHandler.ListMembers)
+			workspaces.PATCH("/:id/members/:user_id", h.workspaceHandler.UpdateMemberRole)
+			workspaces.DELETE("/:id/members/:user_id", h.workspaceHandler.RemoveMember)
+			workspaces.POST("/:id/invitations", h.workspaceHandler.Invite)
+		}
+
+		invitations := api.Group("/invitations", middlewares.AuthMiddleware())
+		{
+			invitations.GET("/", h.workspaceHandler.ListInvitations)
+			invitations.POST("/:id/accept", h.workspaceHandler.AcceptInvitation)
+			invitations.POST("/:id/decline", h.workspaceHandler.DeclineInvitation)
+		}
+
+		notifications := api.Group("/notifications", middlewares.AuthMiddleware())
+		{
+			notifications.GET("/", h.notificationHandler.ListNotifications)
+			notifications.POST("/read", h.notificationHandler.MarkAllRead)
+			notifications.POST("/:id/read", h.notificationHandler.MarkRead)
+			notifications.GET("/preferences", h.notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", h.notificationHandler.UpdatePreferences)
+		}
+
+		webhooks := api.Group("/webhooks", middlewares.AuthMiddleware())
+		{
+			webhooks.POST("/", h.webhookHandler.CreateWebhook)
+			webhooks.GET("/", h.webhookHandler.ListWebhooks)
+			webhooks.GET("/:id", h.webhookHandler.GetWebhook)
+			webhooks.PATCH("/:id", h.webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", h.webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", h.webhookHandler.ListDeliveries)
+			webhooks.POST("/:id/test", h.webhookHandler.TestWebhook)
+		}
+
+		reminders := api.Group("/reminders", middlewares.AuthMiddleware())
+		{
+			reminders.GET("/", h.reminderHandler.ListReminders)
+			reminders.POST("/:id/snooze", h.reminderHandler.SnoozeReminder)
+			reminders.POST("/:id/dismiss", h.reminderHandler.DismissReminder)
+			reminders.DELETE("/:id", h.reminderHandler.DeleteReminder)
+		}
+
+		api.GET("/events", middlewares.AuthMiddleware(), h.eventHandler.Stream)
+		api.GET("/ws", middlewares.AuthMiddleware(), h.wsHandler.Connect)
+
 		tasks := api.Group("/tasks", middlewares.AuthMiddleware())
 		{
 			tasks.POST("/", h.taskHandler.CreateTask)
 			tasks.GET("/", h.taskHandler.GetTasks)
+			tasks.GET("/assigned", h.taskHandler.GetAssignedTasks)
+			tasks.POST("/quick", h.quickAddHandler.QuickAdd)
+			tasks.GET("/export", h.transferHandler.ExportTasks)
+			tasks.POST("/import", h.transferHandler.ImportTasks)
+			tasks.POST("/import/:source", h.appImportHandler.Import)
+			tasks.POST("/import/:source/preview", h.appImportHandler.PreviewImport)
 			tasks.PUT("/:id", h.taskHandler.UpdateTask)
 			tasks.DELETE("/:id", h.taskHandler.DeleteTask)
+			tasks.PUT("/:id/assignee", h.taskHandler.AssignTask)
+			tasks.DELETE("/:id/assignee", h.taskHandler.UnassignTask)
+			tasks.GET("/:id/watchers", h.taskHandler.GetWatchers)
+			tasks.POST("/:id/watchers", h.taskHandler.WatchTask)
+			tasks.DELETE("/:id/watchers", h.taskHandler.UnwatchTask)
+			tasks.GET("/:id/comments", h.commentHandler.ListComments)
+			tasks.POST("/:id/comments", h.commentHandler.CreateComment)
+			tasks.PATCH("/:id/comments/:comment_id", h.commentHandler.UpdateComment)
+			tasks.DELETE("/:id/comments/:comment_id", h.commentHandler.DeleteComment)
+			tasks.GET("/:id/reminders", h.reminderHandler.ListTaskReminders)
+			tasks.POST("/:id/reminders", h.reminderHandler.CreateReminder)
+		}
+	}
+
+	r.GET("/.well-known/caldav", h.caldavHandler.WellKnown)
+	r.Handle("PROPFIND", "/.well-known/caldav", h.caldavHandler.WellKnown)
+
+	dav := r.Group(DAVPrefix)
+	{
+		dav.OPTIONS("/*path", h.caldavHandler.Options)
+		for _, method := range []string{"PROPFIND", "REPORT", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete} {
+			dav.Handle(method, "/*path", h.accessTokenHandler.BasicAuth("todo-app"), h.caldavHandler.Serve)
 		}
 	}
 
@@ -2,6 +2,7 @@ package handlers
 
 type UserData struct {
 	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"optional"`
 	Password string `json:"password" validate:"required"`
 }
 
@@ -14,13 +15,118 @@ type ErrorResponse struct {
 }
 
 type CreateTaskData struct {
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Status      string `json:"status" validate:"required"`
+	Title       string   `json:"title" validate:"required"`
+	Description string   `json:"description" validate:"required"`
+	Status      string   `json:"status" validate:"required"`
+	WorkspaceID int      `json:"workspace_id" validate:"optional"`
+	Priority    string   `json:"priority" validate:"optional"`
+	Recurrence  string   `json:"recurrence" validate:"optional"`
+	Tags        []string `json:"tags" validate:"optional"`
+	DueAt       string   `json:"due_at" validate:"optional"`
 }
 
 type UpdateTaskData struct {
-	Title       string `json:"title" validate:"optional"`
-	Description string `json:"description" validate:"optional"`
-	Status      string `json:"status" validate:"optional"`
+	Title       string   `json:"title" validate:"optional"`
+	Description string   `json:"description" validate:"optional"`
+	Status      string   `json:"status" validate:"optional"`
+	Priority    string   `json:"priority" validate:"optional"`
+	Recurrence  string   `json:"recurrence" validate:"optional"`
+	Tags        []string `json:"tags" validate:"optional"`
+	DueAt       string   `json:"due_at" validate:"optional"`
+}
+
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type MFALoginData struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFACodeData struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ChangePasswordData struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type EmailData struct {
+	Email string `json:"email" validate:"required"`
+}
+
+type PasswordResetData struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+type UpdateProfileData struct {
+	Username    string `json:"username" validate:"optional"`
+	DisplayName string `json:"display_name" validate:"optional"`
+	Timezone    string `json:"timezone" validate:"optional"`
+	Locale      string `json:"locale" validate:"optional"`
+}
+
+type DeleteAccountData struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type WorkspaceData struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type InvitationData struct {
+	Username string `json:"username" validate:"required"`
+	Role     string `json:"role" validate:"optional" enums:"admin,member,viewer"`
+}
+
+type MemberRoleData struct {
+	Role string `json:"role" validate:"required" enums:"admin,member,viewer"`
+}
+
+type AssignTaskData struct {
+	UserID int `json:"user_id" validate:"required"`
+}
+
+type CommentData struct {
+	Body     string `json:"body" validate:"required"`
+	ParentID int    `json:"parent_id" validate:"optional"`
+}
+
+type UpdateCommentData struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type MarkAllReadResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+type WebhookData struct {
+	URL        string   `json:"url" validate:"required"`
+	Secret     string   `json:"secret" validate:"optional"`
+	EventTypes []string `json:"event_types" validate:"required" enums:"task.created,task.updated,task.deleted,task.reminder"`
+}
+
+type UpdateWebhookData struct {
+	URL        string   `json:"url" validate:"optional"`
+	EventTypes []string `json:"event_types" validate:"optional" enums:"task.created,task.updated,task.deleted,task.reminder"`
+	Enabled    bool     `json:"enabled" validate:"optional"`
+}
+
+type ReminderData struct {
+	RemindAt         string   `json:"remind_at" validate:"optional"`
+	BeforeDueMinutes int      `json:"before_due_minutes" validate:"optional"`
+	Channels         []string `json:"channels" validate:"optional" enums:"in_app,email,webhook"`
 }
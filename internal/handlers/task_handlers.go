@@ -9,6 +9,7 @@ import (
 	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/models"
 	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,9 +17,14 @@ var baseErr *helpers.BaseValidationError
 
 type ITaskService interface {
 	CreateTask(task *models.Task) error
-	GetTasksByUserID(userID int) ([]models.Task, error)
+	GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error)
 	UpdateTask(updates map[string]interface{}) error
 	DeleteTask(taskID, userID int) error
+	AssignTask(taskID, userID, assigneeID int) error
+	UnassignTask(taskID, userID int) error
+	WatchTask(taskID, userID int) error
+	UnwatchTask(taskID, userID int) error
+	GetWatchers(taskID, userID int) ([]models.TaskWatcher, error)
 }
 
 type TaskHandler struct {
@@ -30,7 +36,7 @@ func NewTaskHandler(taskService ITaskService) *TaskHandler {
 }
 
 // @Summary CreateTask
-// @Description create new task
+// @Description create new task, personal or in a workspace where the user is at least a member
 // @Security Auth
 // @Accept  json
 // @Produce  json
@@ -39,6 +45,8 @@ func NewTaskHandler(taskService ITaskService) *TaskHandler {
 // @Success 201 {object} models.Task
 // @Failure 400 {object} ErrorResponse
 // @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /tasks/ [post]
 func (h *TaskHandler) CreateTask(c *gin.Context) {
@@ -55,6 +63,10 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 			return
 		}
 
+		if abortWorkspaceError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
 		return
 	}
@@ -63,19 +75,31 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 }
 
 // @Summary GetTasks
-// @Description get all user tasks
+// @Description get personal tasks of the user and tasks of all the user's workspaces
 // @Security Auth
 // @Accept  json
 // @Produce  json
 // @Tags tasks
+// @Param workspace_id query int false "only tasks of this workspace"
+// @Param assignee query string false "only tasks assigned to this user ID, or to the current user with 'me'"
+// @Param tag query string false "only tasks with this tag, e.g. a project"
 // @Success 200 {object} []models.Task
+// @Failure 400 {object} ErrorResponse
 // @Failure 401
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /tasks/ [get]
 func (h *TaskHandler) GetTasks(c *gin.Context) {
-	userID := c.GetInt("user_id")
-	tasks, err := h.service.GetTasksByUserID(userID)
+	filter, ok := taskFilter(c)
+	if !ok {
+		return
+	}
+
+	tasks, err := h.service.GetTasks(c.GetInt("user_id"), filter)
 	if err != nil {
+		if abortWorkspaceError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
 		return
 	}
@@ -94,6 +118,7 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 // @Success 200
 // @Failure 400 {object} ErrorResponse
 // @Failure 401
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
@@ -125,6 +150,10 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 			return
 		}
 
+		if abortWorkspaceError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
 		return
 	}
@@ -142,6 +171,7 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
@@ -159,9 +189,219 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 			})
 			return
 		}
+		if abortWorkspaceError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted"})
 }
+
+// @Summary GetAssignedTasks
+// @Description get tasks assigned to the current user across personal tasks and all workspaces
+// @Security Auth
+// @Produce  json
+// @Tags tasks
+// @Success 200 {object} []models.Task
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/assigned [get]
+func (h *TaskHandler) GetAssignedTasks(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	tasks, err := h.service.GetTasks(userID, models.TaskFilter{AssigneeID: &userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// @Summary AssignTask
+// @Description assign task with {id} to a user who can see it, the assignee is notified and starts watching the task
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags tasks
+// @Param id path int true "Task ID"
+// @Param input body AssignTaskData true "assignee"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/assignee [put]
+func (h *TaskHandler) AssignTask(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.AssignTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.AssignTask(taskID, c.GetInt("user_id"), req.UserID); err != nil {
+		abortTaskError(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task assigned"})
+}
+
+// @Summary UnassignTask
+// @Description remove the assignee of task with {id}
+// @Security Auth
+// @Produce  json
+// @Tags tasks
+// @Param id path int true "Task ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/assignee [delete]
+func (h *TaskHandler) UnassignTask(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnassignTask(taskID, c.GetInt("user_id")); err != nil {
+		abortTaskError(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task unassigned"})
+}
+
+// @Summary GetWatchers
+// @Description list users watching task with {id}
+// @Security Auth
+// @Produce  json
+// @Tags tasks
+// @Param id path int true "Task ID"
+// @Success 200 {object} []models.TaskWatcher
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/watchers [get]
+func (h *TaskHandler) GetWatchers(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	watchers, err := h.service.GetWatchers(taskID, c.GetInt("user_id"))
+	if err != nil {
+		abortTaskError(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, watchers)
+}
+
+// @Summary WatchTask
+// @Description start watching task with {id}
+// @Security Auth
+// @Produce  json
+// @Tags tasks
+// @Param id path int true "Task ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/watchers [post]
+func (h *TaskHandler) WatchTask(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.WatchTask(taskID, c.GetInt("user_id")); err != nil {
+		abortTaskError(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watching task"})
+}
+
+// @Summary UnwatchTask
+// @Description stop watching task with {id}
+// @Security Auth
+// @Produce  json
+// @Tags tasks
+// @Param id path int true "Task ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/{id}/watchers [delete]
+func (h *TaskHandler) UnwatchTask(c *gin.Context) {
+	taskID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnwatchTask(taskID, c.GetInt("user_id")); err != nil {
+		abortTaskError(c, taskID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stopped watching task"})
+}
+
+// abortTaskError writes the response for errors of endpoints nested under a task
+func abortTaskError(c *gin.Context, taskID int, err error) {
+	if err == repository.ErrNoRowsUpdated {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("task with id: %d doesn't exist or access denied", taskID),
+		})
+		return
+	}
+
+	if errors.Is(err, services.ErrInvalidAssignee) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if abortWorkspaceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+}
+
+// taskFilter reads the workspace_id and assignee query parameters of a task
+// listing, it aborts with 400 when they are invalid
+func taskFilter(c *gin.Context) (models.TaskFilter, bool) {
+	var filter models.TaskFilter
+	if raw := c.Query("workspace_id"); raw != "" {
+		workspaceID, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace ID"})
+			return filter, false
+		}
+		filter.WorkspaceID = &workspaceID
+	}
+
+	if raw := c.Query("assignee"); raw != "" {
+		assigneeID := c.GetInt("user_id")
+		if raw != "me" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee, use a user ID or 'me'"})
+				return filter, false
+			}
+			assigneeID = id
+		}
+		filter.AssigneeID = &assigneeID
+	}
+
+	filter.Tag = c.Query("tag")
+	return filter, true
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/models"
 	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,8 +27,13 @@ func (m *MockTaskService) CreateTask(task *models.Task) error {
 	return args.Error(0)
 }
 
-func (m *MockTaskService) GetTasksByUserID(userID int) ([]models.Task, error) {
-	args := m.Called(userID)
+func (m *MockTaskService) GetTask(taskID, userID int) (*models.Task, error) {
+	args := m.Called(taskID, userID)
+	return args.Get(0).(*models.Task), args.Error(1)
+}
+
+func (m *MockTaskService) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	args := m.Called(userID, filter)
 	return args.Get(0).([]models.Task), args.Error(1)
 }
 
@@ -41,6 +47,31 @@ func (m *MockTaskService) DeleteTask(taskID, userID int) error {
 	return args.Error(0)
 }
 
+func (m *MockTaskService) AssignTask(taskID, userID, assigneeID int) error {
+	args := m.Called(taskID, userID, assigneeID)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) UnassignTask(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) WatchTask(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) UnwatchTask(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) GetWatchers(taskID, userID int) ([]models.TaskWatcher, error) {
+	args := m.Called(taskID, userID)
+	return args.Get(0).([]models.TaskWatcher), args.Error(1)
+}
+
 func TestCreateTask(t *testing.T) {
 	t.Parallel()
 	gin.SetMode(gin.TestMode)
@@ -146,7 +177,7 @@ func TestGetTasks(t *testing.T) {
 		handler := handlers.NewTaskHandler(mockService)
 
 		tasks := []models.Task{{Title: "Task 1"}, {Title: "Task 2"}}
-		mockService.On("GetTasksByUserID", 1).Return(tasks, nil)
+		mockService.On("GetTasks", 1, models.TaskFilter{}).Return(tasks, nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -164,7 +195,7 @@ func TestGetTasks(t *testing.T) {
 		mockService := new(MockTaskService)
 		handler := handlers.NewTaskHandler(mockService)
 
-		mockService.On("GetTasksByUserID", 1).Return(([]models.Task)(nil), errors.New("DB error"))
+		mockService.On("GetTasks", 1, models.TaskFilter{}).Return(([]models.Task)(nil), errors.New("DB error"))
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -175,6 +206,59 @@ func TestGetTasks(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
+
+	t.Run("Workspace filter", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockService)
+
+		workspaceID := 10
+		mockService.On("GetTasks", 1, models.TaskFilter{WorkspaceID: &workspaceID}).Return([]models.Task{}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/tasks/?workspace_id=10", nil)
+		c.Set("user_id", 1)
+
+		handler.GetTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Foreign workspace", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockService)
+
+		workspaceID := 20
+		mockService.On("GetTasks", 1, models.TaskFilter{WorkspaceID: &workspaceID}).Return(([]models.Task)(nil), services.ErrWorkspaceNotFound)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/tasks/?workspace_id=20", nil)
+		c.Set("user_id", 1)
+
+		handler.GetTasks(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Invalid workspace id", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/tasks/?workspace_id=abc", nil)
+		c.Set("user_id", 1)
+
+		handler.GetTasks(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "GetTasks", mock.Anything, mock.Anything)
+	})
 }
 
 func TestUpdateTask(t *testing.T) {
@@ -264,6 +348,28 @@ func TestUpdateTask(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("Read-only workspace role", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockService)
+
+		updates := map[string]interface{}{"id": 1, "title": "Updated Task", "user_id": 1}
+		mockService.On("UpdateTask", updates).Return(services.ErrWorkspaceForbidden)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		reqBody := `{"title": "Updated Task"}`
+		c.Request = httptest.NewRequest(http.MethodPut, "/tasks/1", bytes.NewBufferString(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		c.Set("user_id", 1)
+
+		handler.UpdateTask(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
 	t.Run("Updates validation failed", func(t *testing.T) {
 		t.Parallel()
 		mockService := new(MockTaskService)
@@ -393,3 +499,126 @@ func TestDeleteTask(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+func TestAssigneeFilter(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantFilter *int
+		wantStatus int
+	}{
+		{"Me", "/tasks/?assignee=me", intPtr(1), http.StatusOK},
+		{"User ID", "/tasks/?assignee=7", intPtr(7), http.StatusOK},
+		{"Invalid", "/tasks/?assignee=bob", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockTaskService)
+			handler := handlers.NewTaskHandler(mockService)
+			if tt.wantFilter != nil {
+				mockService.On("GetTasks", 1, models.TaskFilter{AssigneeID: tt.wantFilter}).Return([]models.Task{}, nil)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, tt.query, nil)
+			c.Set("user_id", 1)
+
+			handler.GetTasks(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAssignedTasks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockTaskService)
+	handler := handlers.NewTaskHandler(mockService)
+
+	workspaceID := 10
+	mockService.On("GetTasks", 1, models.TaskFilter{AssigneeID: intPtr(1)}).
+		Return([]models.Task{{ID: 1}, {ID: 2, WorkspaceID: &workspaceID}}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/tasks/assigned", nil)
+	c.Set("user_id", 1)
+
+	handler.GetAssignedTasks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"workspace_id":10`)
+}
+
+func TestAssignTaskHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"Success", `{"user_id": 2}`, nil, http.StatusOK},
+		{"Assignee without access", `{"user_id": 2}`, services.ErrInvalidAssignee, http.StatusBadRequest},
+		{"Hidden task", `{"user_id": 2}`, repository.ErrNoRowsUpdated, http.StatusBadRequest},
+		{"Viewer", `{"user_id": 2}`, services.ErrWorkspaceForbidden, http.StatusForbidden},
+		{"Missing user", `{}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockTaskService)
+			handler := handlers.NewTaskHandler(mockService)
+			mockService.On("AssignTask", 5, 1, 2).Return(tt.err)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/tasks/5/assignee", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: "5"}}
+			c.Set("user_id", 1)
+
+			handler.AssignTask(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestWatchTaskHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockTaskService)
+	handler := handlers.NewTaskHandler(mockService)
+	mockService.On("WatchTask", 5, 1).Return(nil)
+	mockService.On("UnwatchTask", 5, 1).Return(repository.ErrNoRowsUpdated)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/tasks/5/watchers", nil)
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	c.Set("user_id", 1)
+	handler.WatchTask(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/tasks/5/watchers", nil)
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	c.Set("user_id", 1)
+	handler.UnwatchTask(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ITaskTransferService interface {
+	Export(w io.Writer, userID int, filter models.TaskFilter, format string) error
+	Import(r io.Reader, req models.ImportRequest) (*models.ImportReport, error)
+}
+
+type TaskTransferHandler struct {
+	service  ITaskTransferService
+	maxBytes int64
+}
+
+func NewTaskTransferHandler(service ITaskTransferService, maxBytes int64) *TaskTransferHandler {
+	return &TaskTransferHandler{service: service, maxBytes: maxBytes}
+}
+
+// @Summary ExportTasks
+// @Description download the tasks visible to the user, streamed as csv, a json array, ndjson, an iCalendar file of VTODOs, a todo.txt file or a markdown checklist
+// @Security Auth
+// @Produce  json
+// @Produce  plain
+// @Produce  text/calendar
+// @Produce  text/markdown
+// @Tags tasks
+// @Param format query string false "csv (default), json, ndjson, ics, txt (todo.txt) or markdown" Enums(csv, json, ndjson, ics, txt, markdown)
+// @Param workspace_id query int false "only tasks of this workspace"
+// @Param assignee query string false "only tasks assigned to this user ID, or to the current user with 'me'"
+// @Param tag query string false "only tasks with this tag, e.g. a project"
+// @Success 200 {array} models.Task
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/export [get]
+func (h *TaskTransferHandler) ExportTasks(c *gin.Context) {
+	format := c.DefaultQuery("format", models.TransferFormatCSV)
+	contentType := services.ExportContentType(format)
+	if contentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, json, ndjson, ics, txt or markdown"})
+		return
+	}
+
+	filter, ok := taskFilter(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, services.ExportExtension(format)))
+	c.Status(http.StatusOK)
+
+	if err := h.service.Export(c.Writer, c.GetInt("user_id"), filter, format); err != nil {
+		// once rows are sent the status can no longer change
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		if abortWorkspaceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+	}
+}
+
+// @Summary ImportTasks
+// @Description create tasks from an uploaded csv, json, ndjson, iCalendar, todo.txt or markdown file, the VTODO and VEVENT components of a calendar and the outermost checklist items of a markdown document become tasks, nested items are not tasks of their own and stay a checklist in the description. Every row is validated first, with dry_run or when a row is invalid nothing is stored and the report lists the errors. Rows with an external_id already imported are skipped
+// @Security Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Tags tasks
+// @Param file formData file true "tasks file"
+// @Param format formData string false "csv, json, ndjson, ics, txt (todo.txt) or markdown, by default taken from the file extension"
+// @Param mapping formData string false "JSON object of task field to source column, e.g. {\"title\": \"Name\"}"
+// @Param dry_run formData bool false "only validate"
+// @Param workspace_id formData int false "import into this workspace"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} models.ImportReport
+// @Failure 500 {object} ErrorResponse
+// @Router /tasks/import [post]
+func (h *TaskTransferHandler) ImportTasks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file larger than %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	req := models.ImportRequest{
+		UserID: c.GetInt("user_id"),
+		Format: c.PostForm("format"),
+	}
+	if req.Format == "" {
+		req.Format = services.ImportFormat(filepath.Ext(header.Filename))
+	}
+
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of field to column"})
+			return
+		}
+	}
+
+	if raw := c.PostForm("dry_run"); raw != "" {
+		if req.DryRun, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+			return
+		}
+	}
+
+	if raw := c.PostForm("workspace_id"); raw != "" {
+		workspaceID, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace ID"})
+			return
+		}
+		req.WorkspaceID = &workspaceID
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.Import(file, req)
+	if err != nil {
+		if errors.Is(err, services.ErrImportInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, report)
+			return
+		}
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if abortWorkspaceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
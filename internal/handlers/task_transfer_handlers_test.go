@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTaskTransferService struct {
+	mock.Mock
+}
+
+func (m *MockTaskTransferService) Export(w io.Writer, userID int, filter models.TaskFilter, format string) error {
+	args := m.Called(w, userID, filter, format)
+	if rows := args.String(0); rows != "" {
+		io.WriteString(w, rows)
+	}
+	return args.Error(1)
+}
+
+func (m *MockTaskTransferService) Import(r io.Reader, req models.ImportRequest) (*models.ImportReport, error) {
+	args := m.Called(r, req)
+	return args.Get(0).(*models.ImportReport), args.Error(1)
+}
+
+func newImportContext(t *testing.T, filename, content string, fields map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	io.WriteString(part, content)
+	for key, value := range fields {
+		assert.NoError(t, form.WriteField(key, value))
+	}
+	assert.NoError(t, form.Close())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/tasks/import", &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	c.Set("user_id", 1)
+	return c, w
+}
+
+func TestExportTasksHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("CSV", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		workspaceID := 10
+		mockService.On("Export", mock.Anything, 1, models.TaskFilter{WorkspaceID: &workspaceID}, models.TransferFormatCSV).Return("id,title\n1,Ship\n", nil)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/tasks/export?workspace_id=10", "", nil)
+		handler.ExportTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="tasks.csv"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "id,title\n1,Ship\n", w.Body.String())
+	})
+
+	t.Run("Markdown of a project", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		mockService.On("Export", mock.Anything, 1, models.TaskFilter{Tag: "Renovation"}, models.TransferFormatMarkdown).Return("- [ ] Buy paint #Renovation\n", nil)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/tasks/export?format=markdown&tag=Renovation", "", nil)
+		handler.ExportTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="tasks.md"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "- [ ] Buy paint #Renovation\n", w.Body.String())
+	})
+
+	t.Run("Workspace not found", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		mockService.On("Export", mock.Anything, 1, mock.Anything, models.TransferFormatNDJSON).Return("", services.ErrWorkspaceNotFound)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/tasks/export?format=ndjson&workspace_id=10", "", nil)
+		handler.ExportTasks(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/tasks/export?format=xlsx", "", nil)
+		handler.ExportTasks(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Export", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestImportTasksHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Dry run", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		mockService.On("Import", mock.Anything, models.ImportRequest{
+			UserID:  1,
+			Format:  models.TransferFormatCSV,
+			Mapping: map[string]string{"title": "Name"},
+			DryRun:  true,
+		}).Return(&models.ImportReport{DryRun: true, Total: 1, Valid: 1}, nil)
+
+		c, w := newImportContext(t, "tasks.CSV", "Name\nShip\n", map[string]string{"mapping": `{"title": "Name"}`, "dry_run": "true"})
+		handler.ImportTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"dry_run":true`)
+	})
+
+	t.Run("Invalid rows", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 1<<20)
+
+		report := &models.ImportReport{Total: 1, Invalid: 1, Errors: []models.ImportRowError{{Row: 1, Error: "field 'title': cannot be blank"}}}
+		mockService.On("Import", mock.Anything, mock.Anything).Return(report, services.ErrImportInvalid)
+
+		c, w := newImportContext(t, "tasks.json", `[{"title": ""}]`, nil)
+		handler.ImportTasks(c)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "cannot be blank")
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockTaskTransferService)
+		handler := handlers.NewTaskTransferHandler(mockService, 64)
+
+		c, w := newImportContext(t, "tasks.csv", string(bytes.Repeat([]byte("title\n"), 100)), nil)
+		handler.ImportTasks(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
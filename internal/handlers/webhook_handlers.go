@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IWebhookService interface {
+	CreateWebhook(webhook *models.Webhook) error
+	GetWebhooks(userID int) ([]models.Webhook, error)
+	GetWebhook(id, userID int) (*models.Webhook, error)
+	UpdateWebhook(id, userID int, update models.WebhookUpdate) (*models.Webhook, error)
+	DeleteWebhook(id, userID int) error
+	GetDeliveries(id, userID int) ([]models.WebhookDelivery, error)
+	SendTestEvent(id, userID int) (*models.WebhookDelivery, error)
+}
+
+type WebhookHandler struct {
+	service IWebhookService
+}
+
+func NewWebhookHandler(service IWebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// @Summary CreateWebhook
+// @Description create a webhook receiving task events, the secret signing deliveries is generated when empty and only returned here
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags webhooks
+// @Param input body WebhookData true "webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/ [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var webhook models.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	webhook.UserID = c.GetInt("user_id")
+	if err := h.service.CreateWebhook(&webhook); err != nil {
+		if errors.As(err, &baseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// @Summary ListWebhooks
+// @Description list webhooks of the current user
+// @Security Auth
+// @Produce  json
+// @Tags webhooks
+// @Success 200 {array} models.Webhook
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/ [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.GetWebhooks(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary GetWebhook
+// @Description get webhook with {id}
+// @Security Auth
+// @Produce  json
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(id, c.GetInt("user_id"))
+	if err != nil {
+		abortWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary UpdateWebhook
+// @Description change url, event types or enabled flag of webhook with {id}, enabling resets the failure counter
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Param input body UpdateWebhookData true "fields to change"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var update models.WebhookUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(id, c.GetInt("user_id"), update)
+	if err != nil {
+		abortWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary DeleteWebhook
+// @Description delete webhook with {id} together with its delivery log
+// @Security Auth
+// @Produce  json
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(id, c.GetInt("user_id")); err != nil {
+		abortWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// @Summary ListDeliveries
+// @Description latest deliveries of webhook with {id} with status, attempts and response codes, newest first
+// @Security Auth
+// @Produce  json
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(id, c.GetInt("user_id"))
+	if err != nil {
+		abortWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary TestWebhook
+// @Description send a webhook.test event to webhook with {id} right away and return the recorded delivery
+// @Security Auth
+// @Produce  json
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id}/test [post]
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.SendTestEvent(id, c.GetInt("user_id"))
+	if err != nil {
+		abortWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func abortWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &baseErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+	}
+}
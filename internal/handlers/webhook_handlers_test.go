@@ -0,0 +1,158 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateWebhook(webhook *models.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetWebhooks(userID int) ([]models.Webhook, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) GetWebhook(id, userID int) (*models.Webhook, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) UpdateWebhook(id, userID int, update models.WebhookUpdate) (*models.Webhook, error) {
+	args := m.Called(id, userID, update)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetDeliveries(id, userID int) ([]models.WebhookDelivery, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) SendTestEvent(id, userID int) (*models.WebhookDelivery, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWebhookService)
+		handler := handlers.NewWebhookHandler(mockService)
+
+		mockService.On("CreateWebhook", mock.MatchedBy(func(w *models.Webhook) bool {
+			return w.UserID == 1 && w.URL == "https://ci.example.com/hook" && len(w.EventTypes) == 1
+		})).Run(func(args mock.Arguments) {
+			w := args.Get(0).(*models.Webhook)
+			w.ID = 5
+			w.Secret = "generated"
+		}).Return(nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/webhooks/", `{"url": "https://ci.example.com/hook", "event_types": ["task.created"]}`, nil)
+		handler.CreateWebhook(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"secret":"generated"`)
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWebhookService)
+		handler := handlers.NewWebhookHandler(mockService)
+
+		mockService.On("CreateWebhook", mock.Anything).Return(helpers.NewSpecificValidationError("url", "cannot be empty"))
+
+		c, w := newWorkspaceContext(http.MethodPost, "/webhooks/", `{"event_types": ["task.created"]}`, nil)
+		handler.CreateWebhook(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "cannot be empty")
+	})
+}
+
+func TestUpdateWebhookHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Enable", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWebhookService)
+		handler := handlers.NewWebhookHandler(mockService)
+
+		mockService.On("UpdateWebhook", 5, 1, mock.MatchedBy(func(u models.WebhookUpdate) bool {
+			return u.Enabled != nil && *u.Enabled && u.URL == nil && u.EventTypes == nil
+		})).Return(&models.Webhook{ID: 5, Enabled: true}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPatch, "/webhooks/5", `{"enabled": true}`, gin.Params{{Key: "id", Value: "5"}})
+		handler.UpdateWebhook(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"enabled":true`)
+	})
+
+	t.Run("Foreign webhook", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWebhookService)
+		handler := handlers.NewWebhookHandler(mockService)
+
+		mockService.On("UpdateWebhook", 5, 1, mock.Anything).Return((*models.Webhook)(nil), services.ErrWebhookNotFound)
+
+		c, w := newWorkspaceContext(http.MethodPatch, "/webhooks/5", `{"enabled": false}`, gin.Params{{Key: "id", Value: "5"}})
+		handler.UpdateWebhook(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestTestWebhookHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockWebhookService)
+	handler := handlers.NewWebhookHandler(mockService)
+
+	code := http.StatusBadGateway
+	mockService.On("SendTestEvent", 5, 1).Return(&models.WebhookDelivery{ID: 7, WebhookID: 5, EventType: models.WebhookEventTest, Status: models.DeliveryPending, Attempts: 1, ResponseCode: &code}, nil)
+
+	c, w := newWorkspaceContext(http.MethodPost, "/webhooks/5/test", "", gin.Params{{Key: "id", Value: "5"}})
+	handler.TestWebhook(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"response_code":502`)
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestListDeliveriesHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockWebhookService)
+	handler := handlers.NewWebhookHandler(mockService)
+
+	c, w := newWorkspaceContext(http.MethodGet, "/webhooks/abc/deliveries", "", gin.Params{{Key: "id", Value: "abc"}})
+	handler.ListDeliveries(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetDeliveries", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type IWorkspaceService interface {
+	CreateWorkspace(userID int, name string) (*models.Workspace, error)
+	ListWorkspaces(userID int) ([]models.Workspace, error)
+	GetWorkspace(workspaceID, userID int) (*models.Workspace, error)
+	RenameWorkspace(workspaceID, userID int, name string) (*models.Workspace, error)
+	DeleteWorkspace(workspaceID, userID int) error
+	ListMembers(workspaceID, userID int) ([]models.WorkspaceMember, error)
+	UpdateMemberRole(workspaceID, actorID, memberID int, role models.WorkspaceRole) error
+	RemoveMember(workspaceID, actorID, memberID int) error
+	Invite(workspaceID, actorID int, username string, role models.WorkspaceRole) (*models.WorkspaceInvitation, error)
+	ListInvitations(userID int) ([]models.WorkspaceInvitation, error)
+	AcceptInvitation(invitationID, userID int) error
+	DeclineInvitation(invitationID, userID int) error
+}
+
+type WorkspaceHandler struct {
+	service IWorkspaceService
+}
+
+func NewWorkspaceHandler(service IWorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{service: service}
+}
+
+// @Summary CreateWorkspace
+// @Description create a shared workspace, the current user becomes its owner
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags workspaces
+// @Param input body WorkspaceData true "workspace name"
+// @Success 201 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/ [post]
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req models.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	workspace, err := h.service.CreateWorkspace(c.GetInt("user_id"), req.Name)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// @Summary ListWorkspaces
+// @Description list workspaces the current user is a member of, with the user's role
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Success 200 {object} []models.Workspace
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/ [get]
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	workspaces, err := h.service.ListWorkspaces(c.GetInt("user_id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// @Summary GetWorkspace
+// @Description get workspace with {id}, requires membership
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Success 200 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id} [get]
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	workspace, err := h.service.GetWorkspace(workspaceID, c.GetInt("user_id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// @Summary RenameWorkspace
+// @Description rename workspace with {id}, requires the admin role
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Param input body WorkspaceData true "workspace name"
+// @Success 200 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id} [patch]
+func (h *WorkspaceHandler) RenameWorkspace(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	workspace, err := h.service.RenameWorkspace(workspaceID, c.GetInt("user_id"), req.Name)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// @Summary DeleteWorkspace
+// @Description delete workspace with {id} and all its tasks, only the owner may do it
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id} [delete]
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWorkspace(workspaceID, c.GetInt("user_id")); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted"})
+}
+
+// @Summary ListMembers
+// @Description list members of workspace with {id}
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Success 200 {object} []models.WorkspaceMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id}/members [get]
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	members, err := h.service.ListMembers(workspaceID, c.GetInt("user_id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// @Summary UpdateMemberRole
+// @Description change the role of a member, requires the admin role; only the owner manages admins
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Param user_id path int true "Member user ID"
+// @Param input body MemberRoleData true "new role"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id}/members/{user_id} [patch]
+func (h *WorkspaceHandler) UpdateMemberRole(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	var req models.MemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.UpdateMemberRole(workspaceID, c.GetInt("user_id"), memberID, req.Role); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+// @Summary RemoveMember
+// @Description remove a member from the workspace or leave it when {user_id} is the current user
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Param user_id path int true "Member user ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id}/members/{user_id} [delete]
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveMember(workspaceID, c.GetInt("user_id"), memberID); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// @Summary Invite
+// @Description invite a user by username, requires the admin role; role defaults to member
+// @Security Auth
+// @Accept  json
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Workspace ID"
+// @Param input body InvitationData true "invitee"
+// @Success 201 {object} models.WorkspaceInvitation
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{id}/invitations [post]
+func (h *WorkspaceHandler) Invite(c *gin.Context) {
+	workspaceID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	invitation, err := h.service.Invite(workspaceID, c.GetInt("user_id"), req.Username, req.Role)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// @Summary ListInvitations
+// @Description list pending workspace invitations of the current user
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Success 200 {object} []models.WorkspaceInvitation
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /invitations/ [get]
+func (h *WorkspaceHandler) ListInvitations(c *gin.Context) {
+	invitations, err := h.service.ListInvitations(c.GetInt("user_id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// @Summary AcceptInvitation
+// @Description accept invitation with {id} and join the workspace
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /invitations/{id}/accept [post]
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	invitationID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.AcceptInvitation(invitationID, c.GetInt("user_id")); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted"})
+}
+
+// @Summary DeclineInvitation
+// @Description decline invitation with {id}
+// @Security Auth
+// @Produce  json
+// @Tags workspaces
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /invitations/{id}/decline [post]
+func (h *WorkspaceHandler) DeclineInvitation(c *gin.Context) {
+	invitationID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeclineInvitation(invitationID, c.GetInt("user_id")); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (h *WorkspaceHandler) abort(c *gin.Context, err error) {
+	if errors.As(err, &baseErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if abortWorkspaceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+}
+
+// abortWorkspaceError writes the response for membership and invitation errors
+func abortWorkspaceError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound),
+		errors.Is(err, services.ErrMemberNotFound),
+		errors.Is(err, services.ErrInvitationNotFound),
+		errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWorkspaceForbidden),
+		errors.Is(err, services.ErrOwnerImmutable):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyMember),
+		errors.Is(err, services.ErrInvitationPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+func pathID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,264 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWorkspaceService struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceService) CreateWorkspace(userID int, name string) (*models.Workspace, error) {
+	args := m.Called(userID, name)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) ListWorkspaces(userID int) ([]models.Workspace, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) GetWorkspace(workspaceID, userID int) (*models.Workspace, error) {
+	args := m.Called(workspaceID, userID)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) RenameWorkspace(workspaceID, userID int, name string) (*models.Workspace, error) {
+	args := m.Called(workspaceID, userID, name)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceService) DeleteWorkspace(workspaceID, userID int) error {
+	args := m.Called(workspaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) ListMembers(workspaceID, userID int) ([]models.WorkspaceMember, error) {
+	args := m.Called(workspaceID, userID)
+	return args.Get(0).([]models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockWorkspaceService) UpdateMemberRole(workspaceID, actorID, memberID int, role models.WorkspaceRole) error {
+	args := m.Called(workspaceID, actorID, memberID, role)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) RemoveMember(workspaceID, actorID, memberID int) error {
+	args := m.Called(workspaceID, actorID, memberID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) Invite(workspaceID, actorID int, username string, role models.WorkspaceRole) (*models.WorkspaceInvitation, error) {
+	args := m.Called(workspaceID, actorID, username, role)
+	return args.Get(0).(*models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceService) ListInvitations(userID int) ([]models.WorkspaceInvitation, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceService) AcceptInvitation(invitationID, userID int) error {
+	args := m.Called(invitationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) DeclineInvitation(invitationID, userID int) error {
+	args := m.Called(invitationID, userID)
+	return args.Error(0)
+}
+
+func newWorkspaceContext(method, target, body string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("user_id", 1)
+	return c, w
+}
+
+func TestCreateWorkspaceHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		mockService.On("CreateWorkspace", 1, "Team").Return(&models.Workspace{ID: 10, Name: "Team", Role: models.RoleOwner}, nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/workspaces/", `{"name":"Team"}`, nil)
+		handler.CreateWorkspace(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"role":"owner"`)
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		mockService.On("CreateWorkspace", 1, " ").Return((*models.Workspace)(nil), helpers.NewSpecificValidationError("name", "cannot be blank"))
+
+		c, w := newWorkspaceContext(http.MethodPost, "/workspaces/", `{"name":" "}`, nil)
+		handler.CreateWorkspace(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Missing name", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/workspaces/", `{}`, nil)
+		handler.CreateWorkspace(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "CreateWorkspace", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorkspaceErrorMapping(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"Not a member", services.ErrWorkspaceNotFound, http.StatusNotFound},
+		{"Role too low", services.ErrWorkspaceForbidden, http.StatusForbidden},
+		{"Owner", services.ErrOwnerImmutable, http.StatusForbidden},
+		{"Unknown member", services.ErrMemberNotFound, http.StatusNotFound},
+		{"Server error", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockWorkspaceService)
+			handler := handlers.NewWorkspaceHandler(mockService)
+
+			mockService.On("UpdateMemberRole", 10, 1, 3, models.RoleViewer).Return(tt.err)
+
+			c, w := newWorkspaceContext(http.MethodPatch, "/workspaces/10/members/3", `{"role":"viewer"}`,
+				gin.Params{{Key: "id", Value: "10"}, {Key: "user_id", Value: "3"}})
+			handler.UpdateMemberRole(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestGetWorkspaceHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Invalid id", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/workspaces/abc", "", gin.Params{{Key: "id", Value: "abc"}})
+		handler.GetWorkspace(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "GetWorkspace", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Foreign workspace", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		mockService.On("GetWorkspace", 20, 1).Return((*models.Workspace)(nil), services.ErrWorkspaceNotFound)
+
+		c, w := newWorkspaceContext(http.MethodGet, "/workspaces/20", "", gin.Params{{Key: "id", Value: "20"}})
+		handler.GetWorkspace(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestInviteHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"Success", nil, http.StatusCreated},
+		{"Unknown user", repository.ErrUserNotFound, http.StatusNotFound},
+		{"Already member", services.ErrAlreadyMember, http.StatusConflict},
+		{"Pending invitation", services.ErrInvitationPending, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := new(MockWorkspaceService)
+			handler := handlers.NewWorkspaceHandler(mockService)
+
+			mockService.On("Invite", 10, 1, "bob", models.RoleViewer).
+				Return(&models.WorkspaceInvitation{ID: 7, WorkspaceID: 10, InviteeID: 9, Role: models.RoleViewer}, tt.err)
+
+			c, w := newWorkspaceContext(http.MethodPost, "/workspaces/10/invitations", `{"username":"bob","role":"viewer"}`,
+				gin.Params{{Key: "id", Value: "10"}})
+			handler.Invite(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRespondInvitationHandler(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Accept", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		mockService.On("AcceptInvitation", 7, 1).Return(nil)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/invitations/7/accept", "", gin.Params{{Key: "id", Value: "7"}})
+		handler.AcceptInvitation(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Decline someone else's invitation", func(t *testing.T) {
+		t.Parallel()
+		mockService := new(MockWorkspaceService)
+		handler := handlers.NewWorkspaceHandler(mockService)
+
+		mockService.On("DeclineInvitation", 7, 1).Return(services.ErrInvitationNotFound)
+
+		c, w := newWorkspaceContext(http.MethodPost, "/invitations/7/decline", "", gin.Params{{Key: "id", Value: "7"}})
+		handler.DeclineInvitation(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/middlewares"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// wsCloseSessionExpired closes connections whose token expired without a new
+// one sent in an auth message
+const wsCloseSessionExpired = 4001
+
+type IWSTaskService interface {
+	GetTask(taskID, userID int) (*models.Task, error)
+	CreateTask(task *models.Task) error
+	UpdateTask(updates map[string]interface{}) error
+	DeleteTask(taskID, userID int) error
+}
+
+type IWSWorkspaceService interface {
+	GetWorkspace(workspaceID, userID int) (*models.Workspace, error)
+}
+
+type IPresenceService interface {
+	Connect(userID int) (*services.PresenceSession, error)
+	Join(session *services.PresenceSession, taskID int)
+	Leave(session *services.PresenceSession, taskID int)
+	Disconnect(session *services.PresenceSession)
+}
+
+type WSHandler struct {
+	tasks      IWSTaskService
+	workspaces IWSWorkspaceService
+	events     IEventService
+	presence   IPresenceService
+	cfg        config.WebSocket
+	upgrader   websocket.Upgrader
+	log        zerolog.Logger
+}
+
+func NewWSHandler(
+	tasks IWSTaskService,
+	workspaces IWSWorkspaceService,
+	events IEventService,
+	presence IPresenceService,
+	cfg config.WebSocket,
+) *WSHandler {
+	return &WSHandler{
+		tasks:      tasks,
+		workspaces: workspaces,
+		events:     events,
+		presence:   presence,
+		cfg:        cfg,
+		log:        logger.GetLogger(),
+	}
+}
+
+// @Summary WebSocket
+// @Description Bidirectional WebSocket for collaborative clients. Clients send JSON messages of type
+// @Description "subscribe"/"unsubscribe" with a topic ("workspace:<id>", "task:<id>" or "personal"),
+// @Description "mutate" with action create, update or delete, and "auth" with a fresh token before the
+// @Description current one expires. The server sends "event", "presence", "result", "error" and "reset".
+// @Security Auth
+// @Tags events
+// @Success 101
+// @Failure 401
+// @Failure 500 {object} ErrorResponse
+// @Router /ws [get]
+func (h *WSHandler) Connect(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	session, err := h.presence.Connect(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server side error"})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader already answered with an HTTP error
+		h.presence.Disconnect(session)
+		return
+	}
+
+	client := &wsClient{
+		h:         h,
+		conn:      conn,
+		userID:    userID,
+		expiresAt: c.GetTime("token_expires_at"),
+		send:      make(chan models.WSServerMessage, h.cfg.SendBuffer),
+		done:      make(chan struct{}),
+		topics:    make(map[string]struct{}),
+		events:    h.events.Subscribe(userID),
+		presence:  session,
+	}
+	client.run()
+}
+
+// wsClient is one WebSocket connection. The read loop handles client messages
+// and queues replies, the write loop is the only writer to the connection
+type wsClient struct {
+	h        *WSHandler
+	conn     *websocket.Conn
+	userID   int
+	send     chan models.WSServerMessage
+	done     chan struct{}
+	events   *services.Subscription
+	presence *services.PresenceSession
+
+	mu        sync.Mutex
+	expiresAt time.Time
+	topics    map[string]struct{}
+	closeCode int
+	closeText string
+}
+
+func (cl *wsClient) run() {
+	writerDone := make(chan struct{})
+	go func() {
+		cl.writeLoop()
+		cl.conn.Close()
+		close(writerDone)
+	}()
+
+	cl.readLoop()
+	close(cl.done)
+	<-writerDone
+
+	cl.h.events.Unsubscribe(cl.events)
+	cl.h.presence.Disconnect(cl.presence)
+}
+
+func (cl *wsClient) readLoop() {
+	cl.conn.SetReadLimit(cl.h.cfg.MaxMessageSize)
+	pongWait := 2 * cl.h.cfg.PingInterval
+	cl.conn.SetReadDeadline(time.Now().Add(pongWait))
+	cl.conn.SetPongHandler(func(string) error {
+		return cl.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg models.WSClientMessage
+		if err := cl.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				if !cl.reply(models.WSServerMessage{Type: models.WSError, Error: "invalid message"}) {
+					return
+				}
+				continue
+			}
+			return
+		}
+
+		if !cl.reply(cl.handle(msg)) {
+			return
+		}
+	}
+}
+
+// reply queues a message for the write loop. A client that doesn't read its
+// replies is disconnected instead of buffering without limit
+func (cl *wsClient) reply(msg models.WSServerMessage) bool {
+	select {
+	case cl.send <- msg:
+		return true
+	default:
+		cl.setClose(websocket.CloseTryAgainLater, "send queue full")
+		return false
+	}
+}
+
+// handle answers a client message. Once the session token expired only a new
+// token is accepted, the connection is closed at the next ping otherwise
+func (cl *wsClient) handle(msg models.WSClientMessage) models.WSServerMessage {
+	cl.mu.Lock()
+	expired := time.Now().After(cl.expiresAt)
+	cl.mu.Unlock()
+	if expired && msg.Type != models.WSAuth {
+		return wsError(msg, "session expired")
+	}
+
+	switch msg.Type {
+	case models.WSAuth:
+		return cl.handleAuth(msg)
+	case models.WSSubscribe:
+		return cl.handleSubscribe(msg)
+	case models.WSUnsubscribe:
+		return cl.handleUnsubscribe(msg)
+	case models.WSMutate:
+		return cl.handleMutate(msg)
+	default:
+		return wsError(msg, "unknown message type")
+	}
+}
+
+func (cl *wsClient) handleAuth(msg models.WSClientMessage) models.WSServerMessage {
+	userID, expiresAt, err := middlewares.ParseToken(msg.Token)
+	if err != nil || userID != cl.userID {
+		return wsError(msg, "invalid token")
+	}
+
+	cl.mu.Lock()
+	cl.expiresAt = expiresAt
+	cl.mu.Unlock()
+
+	return models.WSServerMessage{Type: models.WSResult, ID: msg.ID}
+}
+
+func (cl *wsClient) handleSubscribe(msg models.WSClientMessage) models.WSServerMessage {
+	kind, id, ok := parseTopic(msg.Topic)
+	if !ok {
+		return wsError(msg, "invalid topic")
+	}
+
+	result := models.WSServerMessage{Type: models.WSResult, ID: msg.ID, Topic: msg.Topic}
+	switch kind {
+	case "task":
+		task, err := cl.h.tasks.GetTask(id, cl.userID)
+		if err != nil {
+			return wsError(msg, wsErrorMessage(id, err))
+		}
+		result.Task = task
+	case "workspace":
+		if _, err := cl.h.workspaces.GetWorkspace(id, cl.userID); err != nil {
+			return wsError(msg, wsErrorMessage(0, err))
+		}
+	}
+
+	cl.mu.Lock()
+	cl.topics[msg.Topic] = struct{}{}
+	cl.mu.Unlock()
+
+	if kind == "task" {
+		cl.h.presence.Join(cl.presence, id)
+	}
+
+	return result
+}
+
+func (cl *wsClient) handleUnsubscribe(msg models.WSClientMessage) models.WSServerMessage {
+	kind, id, ok := parseTopic(msg.Topic)
+	if !ok {
+		return wsError(msg, "invalid topic")
+	}
+
+	cl.mu.Lock()
+	delete(cl.topics, msg.Topic)
+	cl.mu.Unlock()
+
+	if kind == "task" {
+		cl.h.presence.Leave(cl.presence, id)
+	}
+
+	return models.WSServerMessage{Type: models.WSResult, ID: msg.ID, Topic: msg.Topic}
+}
+
+// handleMutate runs a task change through TaskService, with the same
+// validation and access checks as the REST endpoints
+func (cl *wsClient) handleMutate(msg models.WSClientMessage) models.WSServerMessage {
+	switch msg.Action {
+	case models.WSCreateTask:
+		var task models.Task
+		if err := json.Unmarshal(msg.Data, &task); err != nil {
+			return wsError(msg, "invalid data")
+		}
+
+		task.UserID = cl.userID
+		if err := cl.h.tasks.CreateTask(&task); err != nil {
+			return wsError(msg, wsErrorMessage(0, err))
+		}
+		return models.WSServerMessage{Type: models.WSResult, ID: msg.ID, Task: &task}
+
+	case models.WSUpdateTask:
+		var updates map[string]interface{}
+		if err := json.Unmarshal(msg.Data, &updates); err != nil || updates == nil {
+			return wsError(msg, "invalid data")
+		}
+
+		updates["id"] = msg.TaskID
+		updates["user_id"] = cl.userID
+		if err := cl.h.tasks.UpdateTask(updates); err != nil {
+			return wsError(msg, wsErrorMessage(msg.TaskID, err))
+		}
+
+		task, err := cl.h.tasks.GetTask(msg.TaskID, cl.userID)
+		if err != nil {
+			return wsError(msg, wsErrorMessage(msg.TaskID, err))
+		}
+		return models.WSServerMessage{Type: models.WSResult, ID: msg.ID, Task: task}
+
+	case models.WSDeleteTask:
+		if err := cl.h.tasks.DeleteTask(msg.TaskID, cl.userID); err != nil {
+			return wsError(msg, wsErrorMessage(msg.TaskID, err))
+		}
+		return models.WSServerMessage{Type: models.WSResult, ID: msg.ID}
+
+	default:
+		return wsError(msg, "unknown mutation")
+	}
+}
+
+func (cl *wsClient) writeLoop() {
+	ticker := time.NewTicker(cl.h.cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cl.done:
+			cl.mu.Lock()
+			code, text := cl.closeCode, cl.closeText
+			cl.mu.Unlock()
+			if code == 0 {
+				code = websocket.CloseNormalClosure
+			}
+			cl.writeClose(code, text)
+			return
+
+		case msg := <-cl.send:
+			if !cl.write(msg) {
+				return
+			}
+
+		case event, ok := <-cl.events.Events:
+			if !ok {
+				// the subscriber fell behind and lost events, the client reloads
+				// its state and events continue from a new subscription
+				cl.events = cl.h.events.Subscribe(cl.userID)
+				if !cl.write(models.WSServerMessage{Type: models.WSReset}) {
+					return
+				}
+				continue
+			}
+			if topic, ok := cl.matchTopic(event); ok {
+				if !cl.write(models.WSServerMessage{Type: models.WSEvent, Topic: topic, Event: &event}) {
+					return
+				}
+			}
+
+		case update, ok := <-cl.presence.Updates:
+			if !ok {
+				return
+			}
+			topic := fmt.Sprintf("task:%d", update.TaskID)
+			if cl.subscribed(topic) {
+				if !cl.write(models.WSServerMessage{Type: models.WSPresence, Topic: topic, Viewers: update.Viewers}) {
+					return
+				}
+			}
+
+		case <-ticker.C:
+			cl.mu.Lock()
+			expired := time.Now().After(cl.expiresAt)
+			cl.mu.Unlock()
+			if expired {
+				cl.writeClose(wsCloseSessionExpired, "session expired")
+				return
+			}
+
+			cl.conn.SetWriteDeadline(time.Now().Add(cl.h.cfg.WriteTimeout))
+			if err := cl.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (cl *wsClient) write(msg models.WSServerMessage) bool {
+	cl.conn.SetWriteDeadline(time.Now().Add(cl.h.cfg.WriteTimeout))
+	if err := cl.conn.WriteJSON(msg); err != nil {
+		cl.h.log.Debug().Err(err).Int("user_id", cl.userID).Msg("WebSocket write failed")
+		return false
+	}
+	return true
+}
+
+func (cl *wsClient) writeClose(code int, text string) {
+	cl.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(cl.h.cfg.WriteTimeout))
+}
+
+func (cl *wsClient) setClose(code int, text string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.closeCode, cl.closeText = code, text
+}
+
+func (cl *wsClient) subscribed(topic string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	_, ok := cl.topics[topic]
+	return ok
+}
+
+// matchTopic returns the subscribed topic the event belongs to, the task topic
+// wins over the workspace one
+func (cl *wsClient) matchTopic(event models.TaskEvent) (string, bool) {
+	candidates := []string{fmt.Sprintf("task:%d", event.TaskID)}
+	if event.WorkspaceID != nil {
+		candidates = append(candidates, fmt.Sprintf("workspace:%d", *event.WorkspaceID))
+	} else {
+		candidates = append(candidates, "personal")
+	}
+
+	for _, topic := range candidates {
+		if cl.subscribed(topic) {
+			return topic, true
+		}
+	}
+	return "", false
+}
+
+func parseTopic(topic string) (string, int, bool) {
+	if topic == "personal" {
+		return topic, 0, true
+	}
+
+	kind, rawID, found := strings.Cut(topic, ":")
+	if !found || (kind != "task" && kind != "workspace") {
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		return "", 0, false
+	}
+	return kind, id, true
+}
+
+func wsError(msg models.WSClientMessage, text string) models.WSServerMessage {
+	return models.WSServerMessage{Type: models.WSError, ID: msg.ID, Topic: msg.Topic, Error: text}
+}
+
+// wsErrorMessage is the WebSocket counterpart of abortTaskError
+func wsErrorMessage(taskID int, err error) string {
+	switch {
+	case errors.Is(err, repository.ErrNoRowsUpdated):
+		return fmt.Sprintf("task with id: %d doesn't exist or access denied", taskID)
+	case errors.As(err, &baseErr),
+		errors.Is(err, services.ErrInvalidAssignee),
+		errors.Is(err, services.ErrWorkspaceNotFound),
+		errors.Is(err, services.ErrWorkspaceForbidden):
+		return err.Error()
+	default:
+		return "server side error"
+	}
+}
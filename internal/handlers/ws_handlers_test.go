@@ -0,0 +1,237 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/handlers"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type presenceUsers struct{}
+
+func (presenceUsers) GetUserByID(id int) (*models.User, error) {
+	return &models.User{ID: id, Username: "alice"}, nil
+}
+
+type wsFixture struct {
+	tasks      *MockTaskService
+	workspaces *MockWorkspaceService
+	live       chan models.TaskEvent
+	conn       *websocket.Conn
+}
+
+func newWSFixture(t *testing.T) *wsFixture {
+	return newWSFixtureUntil(t, time.Now().Add(time.Hour))
+}
+
+// newWSFixtureUntil connects with a session token valid until expiresAt
+func newWSFixtureUntil(t *testing.T, expiresAt time.Time) *wsFixture {
+	gin.SetMode(gin.TestMode)
+
+	f := &wsFixture{
+		tasks:      new(MockTaskService),
+		workspaces: new(MockWorkspaceService),
+		live:       make(chan models.TaskEvent, 4),
+	}
+
+	events := new(MockEventService)
+	sub := &services.Subscription{UserID: 1, Events: f.live}
+	events.On("Subscribe", 1).Return(sub)
+	events.On("Unsubscribe", sub).Return()
+
+	handler := handlers.NewWSHandler(f.tasks, f.workspaces, events, services.NewPresenceService(presenceUsers{}), config.WebSocket{
+		SendBuffer:     8,
+		MaxMessageSize: 4096,
+		PingInterval:   time.Minute,
+		WriteTimeout:   time.Second,
+	})
+
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) {
+		c.Set("user_id", 1)
+		c.Set("token_expires_at", expiresAt)
+	}, handler.Connect)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	f.conn = conn
+
+	return f
+}
+
+func (f *wsFixture) send(t *testing.T, msg models.WSClientMessage) {
+	require.NoError(t, f.conn.WriteJSON(msg))
+}
+
+// wsMessage mirrors models.WSServerMessage with the task left undecoded
+type wsMessage struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id"`
+	Topic   string            `json:"topic"`
+	Event   *models.TaskEvent `json:"event"`
+	Task    map[string]any    `json:"task"`
+	Viewers []models.Viewer   `json:"viewers"`
+	Error   string            `json:"error"`
+}
+
+func (f *wsFixture) read(t *testing.T) wsMessage {
+	var msg wsMessage
+	f.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, f.conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestWebSocket(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Subscribe to task joins presence", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+		f.tasks.On("GetTask", 42, 1).Return(&models.Task{ID: 42, Title: "Test"}, nil)
+
+		f.send(t, models.WSClientMessage{Type: models.WSSubscribe, ID: "1", Topic: "task:42"})
+
+		// the reply and the presence update are queued separately and may
+		// arrive in either order
+		received := make(map[string]wsMessage)
+		for range 2 {
+			msg := f.read(t)
+			received[msg.Type] = msg
+		}
+
+		result := received[models.WSResult]
+		assert.Equal(t, "1", result.ID)
+		require.NotNil(t, result.Task)
+		assert.Equal(t, "Test", result.Task["title"])
+
+		presence := received[models.WSPresence]
+		assert.Equal(t, "task:42", presence.Topic)
+		assert.Equal(t, []models.Viewer{{UserID: 1, Username: "alice"}}, presence.Viewers)
+	})
+
+	t.Run("Subscribe to foreign workspace", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+		f.workspaces.On("GetWorkspace", 10, 1).Return((*models.Workspace)(nil), services.ErrWorkspaceNotFound)
+
+		f.send(t, models.WSClientMessage{Type: models.WSSubscribe, ID: "1", Topic: "workspace:10"})
+
+		msg := f.read(t)
+		assert.Equal(t, models.WSError, msg.Type)
+		assert.Equal(t, services.ErrWorkspaceNotFound.Error(), msg.Error)
+	})
+
+	t.Run("Events are filtered by topic", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+		f.workspaces.On("GetWorkspace", 10, 1).Return(&models.Workspace{ID: 10}, nil)
+
+		f.send(t, models.WSClientMessage{Type: models.WSSubscribe, ID: "1", Topic: "workspace:10"})
+		assert.Equal(t, models.WSResult, f.read(t).Type)
+
+		f.live <- models.TaskEvent{ID: 1, Type: models.TaskEventUpdated, TaskID: 5, WorkspaceID: intPtr(20)}
+		f.live <- models.TaskEvent{ID: 2, Type: models.TaskEventUpdated, TaskID: 6}
+		f.live <- models.TaskEvent{ID: 3, Type: models.TaskEventCreated, TaskID: 7, WorkspaceID: intPtr(10)}
+
+		msg := f.read(t)
+		assert.Equal(t, models.WSEvent, msg.Type)
+		assert.Equal(t, "workspace:10", msg.Topic)
+		assert.Equal(t, int64(3), msg.Event.ID)
+	})
+
+	t.Run("Mutation result", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+		f.tasks.On("UpdateTask", mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["id"] == 42 && updates["user_id"] == 1 && updates["status"] == "done"
+		})).Return(nil)
+		f.tasks.On("GetTask", 42, 1).Return(&models.Task{ID: 42, Status: "done"}, nil)
+
+		f.send(t, models.WSClientMessage{
+			Type:   models.WSMutate,
+			ID:     "2",
+			Action: models.WSUpdateTask,
+			TaskID: 42,
+			Data:   json.RawMessage(`{"status":"done"}`),
+		})
+
+		msg := f.read(t)
+		assert.Equal(t, models.WSResult, msg.Type)
+		assert.Equal(t, "2", msg.ID)
+		assert.Equal(t, "done", msg.Task["status"])
+	})
+
+	t.Run("Mutation validation error", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+		validationErr := helpers.NewSpecificValidationError("title", "cannot be empty")
+		f.tasks.On("CreateTask", mock.Anything).Return(validationErr)
+
+		f.send(t, models.WSClientMessage{
+			Type:   models.WSMutate,
+			ID:     "3",
+			Action: models.WSCreateTask,
+			Data:   json.RawMessage(`{"status":"pending"}`),
+		})
+
+		msg := f.read(t)
+		assert.Equal(t, models.WSError, msg.Type)
+		assert.Equal(t, "3", msg.ID)
+		assert.Equal(t, validationErr.Error(), msg.Error)
+	})
+
+	t.Run("Expired session is rejected before the next ping", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixtureUntil(t, time.Now().Add(-time.Second))
+
+		f.send(t, models.WSClientMessage{
+			Type:   models.WSMutate,
+			ID:     "5",
+			Action: models.WSCreateTask,
+			Data:   json.RawMessage(`{"title":"Late","status":"pending"}`),
+		})
+		msg := f.read(t)
+		assert.Equal(t, models.WSError, msg.Type)
+		assert.Equal(t, "5", msg.ID)
+		assert.Equal(t, "session expired", msg.Error)
+
+		f.send(t, models.WSClientMessage{Type: models.WSSubscribe, ID: "6", Topic: "task:42"})
+		msg = f.read(t)
+		assert.Equal(t, "session expired", msg.Error)
+
+		// a new token is still accepted
+		f.send(t, models.WSClientMessage{Type: models.WSAuth, ID: "7", Token: "garbage"})
+		msg = f.read(t)
+		assert.Equal(t, "invalid token", msg.Error)
+
+		f.tasks.AssertNotCalled(t, "CreateTask", mock.Anything)
+		f.tasks.AssertNotCalled(t, "GetTask", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid token is rejected", func(t *testing.T) {
+		t.Parallel()
+		f := newWSFixture(t)
+
+		f.send(t, models.WSClientMessage{Type: models.WSAuth, ID: "4", Token: "garbage"})
+
+		msg := f.read(t)
+		assert.Equal(t, models.WSError, msg.Type)
+		assert.Equal(t, "invalid token", msg.Error)
+	})
+}
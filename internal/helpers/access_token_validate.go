@@ -0,0 +1,13 @@
+package helpers
+
+import "fmt"
+
+func ValidateAccessTokenName(name string) error {
+	if name == "" {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("name", "cannot be blank"))
+	}
+	if len(name) > 100 {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("name", "field too long"))
+	}
+	return nil
+}
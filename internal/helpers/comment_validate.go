@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 10000
+
+// mentionPattern matches @username that isn't part of an email address or a word
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.-]*\w)`)
+
+func ValidateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("validation failed: %w", NewSpecificValidationError("body", "cannot be blank"))
+	}
+
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("validation failed: %w", NewSpecificValidationError("body", "field too long"))
+	}
+
+	return body, nil
+}
+
+// ParseMentions returns the distinct usernames mentioned in a markdown body in
+// order of appearance. Code spans and blocks are skipped
+func ParseMentions(body string) []string {
+	seen := make(map[string]bool)
+	var mentions []string
+
+	for _, text := range stripCode(body) {
+		for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			name := match[1]
+			if !seen[name] {
+				seen[name] = true
+				mentions = append(mentions, name)
+			}
+		}
+	}
+
+	return mentions
+}
+
+// stripCode splits the body into the parts outside fenced blocks and inline code
+func stripCode(body string) []string {
+	var parts []string
+	inFence := false
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		segments := strings.Split(line, "`")
+		for i := 0; i < len(segments); i += 2 {
+			parts = append(parts, segments[i])
+		}
+	}
+
+	return parts
+}
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+var sendTimePattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// ValidateDigestSettings checks the send time is a "HH:MM" local time and the
+// time zone is empty or a known IANA name
+func ValidateDigestSettings(settings *models.DigestSettings) error {
+	if !sendTimePattern.MatchString(settings.SendTime) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("send_time", "must be HH:MM"))
+	}
+
+	if settings.Timezone != "" {
+		if settings.Timezone == "Local" {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("timezone", "unknown time zone"))
+		}
+		if _, err := time.LoadLocation(settings.Timezone); err != nil {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("timezone", "unknown time zone"))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package helpers
+
+import (
+	"fmt"
+	"net/mail"
+)
+
+const maxEmailLength = 320
+
+func ValidateEmail(email string) error {
+	if email == "" {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("email", "cannot be blank"))
+	}
+
+	if len(email) > maxEmailLength {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("email", "field too long"))
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("email", "invalid address"))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ValidatePage checks limit and offset of a paginated list. A zero limit means
+// the default page size
+func ValidatePage(limit, offset int) (int, error) {
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+
+	if limit < 0 || limit > MaxPageLimit {
+		return 0, fmt.Errorf("validation failed: %w", NewSpecificValidationError("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit)))
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("validation failed: %w", NewSpecificValidationError("offset", "cannot be negative"))
+	}
+
+	return limit, nil
+}
+
+func ValidateNotificationType(notificationType string) error {
+	if !slices.Contains(models.NotificationTypes, notificationType) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("type", fmt.Sprintf("unknown notification type %q", notificationType)))
+	}
+
+	return nil
+}
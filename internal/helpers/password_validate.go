@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/daioru/todo-app/internal/config"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = func() map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordsList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			set[strings.ToLower(line)] = true
+		}
+	}
+	return set
+}()
+
+// Короче этого имя пользователя не проверяется на вхождение в пароль
+const minUsernameCheckLength = 3
+
+func ValidatePassword(policy config.PasswordPolicy, username, password string) error {
+	if password == "" {
+		return passwordError("cannot be blank")
+	}
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return passwordError(fmt.Sprintf("must be at least %d characters", policy.MinLength))
+	}
+
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		return passwordError("field too long")
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if policy.RequireUpper && !upper {
+		return passwordError("must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		return passwordError("must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		return passwordError("must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		return passwordError("must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if policy.RejectUsername && len(username) >= minUsernameCheckLength &&
+		strings.Contains(lowered, strings.ToLower(username)) {
+		return passwordError("must not contain the username")
+	}
+
+	if policy.RejectCommon && commonPasswords[lowered] {
+		return passwordError("is too common")
+	}
+
+	return nil
+}
+
+func passwordError(msg string) error {
+	return fmt.Errorf("validation failed: %w", NewSpecificValidationError("password", msg))
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/language"
+
+	_ "time/tzdata"
+)
+
+const (
+	maxUsernameLength    = 64
+	maxDisplayNameLength = 100
+)
+
+var allowedProfileFields = map[string]bool{
+	"username":     true,
+	"display_name": true,
+	"timezone":     true,
+	"locale":       true,
+}
+
+// ValidateProfileUpdates checks a PATCH /me body and returns the updates in
+// normalized form: trimmed strings and canonical locale tags
+func ValidateProfileUpdates(updates map[string]interface{}) (map[string]interface{}, error) {
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("", "no fields to update"))
+	}
+
+	valid := make(map[string]interface{}, len(updates))
+	for key, raw := range updates {
+		if !allowedProfileFields[key] {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "field not allowed"))
+		}
+
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "must be a string"))
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "username":
+			if err := ValidateUsername(value); err != nil {
+				return nil, err
+			}
+		case "display_name":
+			if len([]rune(value)) > maxDisplayNameLength {
+				return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "field too long"))
+			}
+		case "timezone":
+			if value == "" || value == "Local" {
+				return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "unknown time zone"))
+			}
+			if _, err := time.LoadLocation(value); err != nil {
+				return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "unknown time zone"))
+			}
+		case "locale":
+			tag, err := language.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError(key, "invalid language tag"))
+			}
+			value = tag.String()
+		}
+
+		valid[key] = value
+	}
+
+	return valid, nil
+}
+
+func ValidateUsername(username string) error {
+	if username == "" {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("username", "cannot be blank"))
+	}
+
+	if len(username) > maxUsernameLength {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("username", "field too long"))
+	}
+
+	for _, r := range username {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("username", "must not contain control characters"))
+		}
+	}
+
+	return nil
+}
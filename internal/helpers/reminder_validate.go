@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// maxBeforeDueMinutes caps relative reminders at 30 days before the due date
+const maxBeforeDueMinutes = 30 * 24 * 60
+
+// ValidateReminder checks that a reminder has either an absolute time or an
+// offset before the due date, and known channels, each at most once
+func ValidateReminder(reminder *models.Reminder) error {
+	if (reminder.RemindAt == nil) == (reminder.BeforeDueMinutes == nil) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("remind_at", "set either remind_at or before_due_minutes"))
+	}
+
+	if reminder.BeforeDueMinutes != nil && (*reminder.BeforeDueMinutes < 0 || *reminder.BeforeDueMinutes > maxBeforeDueMinutes) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("before_due_minutes", fmt.Sprintf("must be between 0 and %d", maxBeforeDueMinutes)))
+	}
+
+	if len(reminder.Channels) == 0 {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("channels", "cannot be empty"))
+	}
+
+	for i, channel := range reminder.Channels {
+		if !slices.Contains(models.ReminderChannels, channel) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("channels", fmt.Sprintf("unknown channel %q", channel)))
+		}
+		if slices.Contains(reminder.Channels[:i], channel) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("channels", fmt.Sprintf("duplicate channel %q", channel)))
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,10 @@ package helpers
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/daioru/todo-app/internal/models"
 )
@@ -23,5 +27,104 @@ func ValidateTaskFields(task *models.Task) error {
 		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("title", "status too long"))
 	}
 
+	if err := validatePriority(task.Priority); err != nil {
+		return err
+	}
+
+	if err := validateRecurrence(task.Recurrence); err != nil {
+		return err
+	}
+
+	if err := validateExternalID(task.ExternalID); err != nil {
+		return err
+	}
+
+	return validateTags(task.Tags)
+}
+
+// validateExternalID checks the id an imported task had in the system it came
+// from, calendar files carry it as the UID
+func validateExternalID(externalID *string) error {
+	if externalID == nil {
+		return nil
+	}
+
+	if len(*externalID) > 255 {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("external_id", "field too long"))
+	}
+
+	if strings.ContainsFunc(*externalID, unicode.IsControl) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("external_id", "cannot contain control characters"))
+	}
+
+	return nil
+}
+
+func validatePriority(priority string) error {
+	if priority != "" && !slices.Contains(models.TaskPriorities, priority) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("priority", "must be low, medium, high or empty"))
+	}
+	return nil
+}
+
+var recurrenceFrequencies = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+// validateRecurrence checks that a non-empty recurrence is an RRULE value of
+// NAME=VALUE parts with a known FREQ, the other parts are kept as they are
+func validateRecurrence(recurrence string) error {
+	if recurrence == "" {
+		return nil
+	}
+
+	if len(recurrence) > 255 {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("recurrence", "field too long"))
+	}
+
+	if strings.ContainsFunc(recurrence, unicode.IsControl) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("recurrence", "cannot contain control characters"))
+	}
+
+	freq := ""
+	for _, part := range strings.Split(recurrence, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" || value == "" {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("recurrence", "must be an RRULE such as FREQ=WEEKLY;BYDAY=MO"))
+		}
+		if name == "FREQ" {
+			freq = value
+		}
+	}
+
+	if !slices.Contains(recurrenceFrequencies, freq) {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("recurrence", "FREQ must be one of "+strings.Join(recurrenceFrequencies, ", ")))
+	}
+
+	return nil
+}
+
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// validateTags checks that tags are distinct single words, commas are not
+// allowed as files list tags separated by them
+func validateTags(tags []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", fmt.Sprintf("at most %d tags", maxTags)))
+	}
+
+	for i, tag := range tags {
+		if tag == "" || strings.ContainsFunc(tag, unicode.IsSpace) || strings.Contains(tag, ",") {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", "must be words without spaces or commas"))
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", "tag too long"))
+		}
+		if slices.Contains(tags[:i], tag) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", fmt.Sprintf("duplicate tag %q", tag)))
+		}
+	}
+
 	return nil
 }
@@ -2,6 +2,8 @@ package helpers
 
 import (
 	"fmt"
+
+	"github.com/daioru/todo-app/internal/models"
 )
 
 var allowedFields = map[string]bool{
@@ -10,6 +12,10 @@ var allowedFields = map[string]bool{
 	"title":       true,
 	"description": true,
 	"status":      true,
+	"priority":    true,
+	"recurrence":  true,
+	"tags":        true,
+	"due_at":      true,
 }
 
 func ValidateUpdates(updates map[string]interface{}) (map[string]interface{}, error) {
@@ -27,7 +33,80 @@ func ValidateUpdates(updates map[string]interface{}) (map[string]interface{}, er
 		return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("", "no fields to update"))
 	}
 
-	return FilterAllowedFields(updates)
+	updates, err := FilterAllowedFields(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	if due, ok := updates["due_at"]; ok && due != nil {
+		raw, isString := due.(string)
+		if !isString {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("due_at", "must be a time or null"))
+		}
+		dueAt, err := models.ParseJSONTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("due_at", "must be an RFC 1123 or RFC 3339 time"))
+		}
+		// stored without a time zone, see repository.dueAt
+		updates["due_at"] = dueAt.UTC()
+	}
+
+	if priority, ok := updates["priority"]; ok {
+		raw, isString := priority.(string)
+		if !isString {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("priority", "must be a string"))
+		}
+		if err := validatePriority(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if recurrence, ok := updates["recurrence"]; ok {
+		raw, isString := recurrence.(string)
+		if !isString {
+			return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("recurrence", "must be a string"))
+		}
+		if err := validateRecurrence(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if tags, ok := updates["tags"]; ok {
+		list, err := tagList(tags)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateTags(list); err != nil {
+			return nil, err
+		}
+		updates["tags"] = list
+	}
+
+	return updates, nil
+}
+
+// tagList converts tags decoded from JSON to a list, null clears the tags
+func tagList(value interface{}) (models.StringList, error) {
+	switch v := value.(type) {
+	case nil:
+		return models.StringList{}, nil
+	case models.StringList:
+		return v, nil
+	case []string:
+		return models.StringList(v), nil
+	case []interface{}:
+		list := make(models.StringList, 0, len(v))
+		for _, item := range v {
+			tag, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", "must be a list of strings"))
+			}
+			list = append(list, tag)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("validation failed: %w", NewSpecificValidationError("tags", "must be a list of strings"))
+	}
 }
 
 // Функция для фильтрации разрешенных полей
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+const maxWebhookURLLength = 2048
+
+func ValidateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("url", "cannot be empty"))
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("url", "field too long"))
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("url", "must be an absolute http or https URL"))
+	}
+
+	return nil
+}
+
+// ValidateWebhookEventTypes checks that at least one known event type is
+// given, each at most once
+func ValidateWebhookEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("event_types", "cannot be empty"))
+	}
+
+	for i, eventType := range eventTypes {
+		if !slices.Contains(models.WebhookEventTypes, eventType) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("event_types", fmt.Sprintf("unknown event type %q", eventType)))
+		}
+		if slices.Contains(eventTypes[:i], eventType) {
+			return fmt.Errorf("validation failed: %w", NewSpecificValidationError("event_types", fmt.Sprintf("duplicate event type %q", eventType)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+func ValidateWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("validation failed: %w", NewSpecificValidationError("name", "cannot be blank"))
+	}
+
+	if utf8.RuneCountInString(name) > 100 {
+		return "", fmt.Errorf("validation failed: %w", NewSpecificValidationError("name", "field too long"))
+	}
+
+	return name, nil
+}
+
+// ValidateGrantedRole checks a role given to a member through an invitation or
+// a role change. Ownership is never granted this way
+func ValidateGrantedRole(role models.WorkspaceRole) error {
+	if !role.Valid() || role == models.RoleOwner {
+		return fmt.Errorf("validation failed: %w", NewSpecificValidationError("role", "must be one of admin, member, viewer"))
+	}
+
+	return nil
+}
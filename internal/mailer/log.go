@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/rs/zerolog"
+)
+
+// LogMailer only writes messages to the application log
+type LogMailer struct {
+	log zerolog.Logger
+}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{log: logger.GetLogger()}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.log.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("text", msg.Text).
+		Msg("Mail")
+	return nil
+}
@@ -0,0 +1,169 @@
+package mailer_test
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPlainText(t *testing.T) {
+	raw, err := mailer.Build("TODO App <noreply@todo.local>", mailer.Message{
+		To:      "alice@example.com",
+		Subject: "Привет",
+		Text:    "Hello, Alice",
+	}, time.Unix(1700000000, 0))
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Привет", subject)
+	assert.Equal(t, "alice@example.com", msg.Header.Get("To"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@todo.local>"))
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Alice", string(body))
+}
+
+func TestBuildAlternative(t *testing.T) {
+	raw, err := mailer.Build("noreply@todo.local", mailer.Message{
+		To:      "alice@example.com",
+		Subject: "Hi",
+		Text:    "plain",
+		HTML:    "<p>html</p>",
+	}, time.Now())
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+	assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, types)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := mailer.New(config.Mail{Driver: mailer.DriverFile, From: "noreply@todo.local", Dir: dir})
+	require.NoError(t, err)
+
+	err = m.Send(mailer.Message{To: "alice@example.com", Subject: "Hi", Text: "hello"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Contains(t, filepath.Base(files[0]), "alice_example.com")
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: Hi")
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	_, err := mailer.New(config.Mail{Driver: "carrier-pigeon"})
+	assert.Error(t, err)
+}
+
+// fakeSMTPServer accepts one message and returns its envelope and data
+func fakeSMTPServer(t *testing.T) (int, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+		var transcript strings.Builder
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				transcript.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					transcript.WriteString(data)
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+
+	m := mailer.NewSMTPMailer("TODO App <noreply@todo.local>", config.SMTP{Host: "127.0.0.1", Port: port})
+	err := m.Send(mailer.Message{To: "alice@example.com", Subject: "Hi", Text: "hello"})
+	require.NoError(t, err)
+
+	select {
+	case transcript := <-received:
+		assert.Contains(t, transcript, "MAIL FROM:<noreply@todo.local>")
+		assert.Contains(t, transcript, "RCPT TO:<alice@example.com>")
+		assert.Contains(t, transcript, "Subject: Hi")
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp server received nothing")
+	}
+}
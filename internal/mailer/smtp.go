@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+)
+
+type SMTPMailer struct {
+	from string
+	cfg  config.SMTP
+}
+
+func NewSMTPMailer(from string, cfg config.SMTP) *SMTPMailer {
+	return &SMTPMailer{from: from, cfg: cfg}
+}
+
+// Send delivers msg via the configured relay. net/smtp upgrades the
+// connection with STARTTLS whenever the server offers it
+func (m *SMTPMailer) Send(msg Message) error {
+	body, err := Build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	return smtp.SendMail(addr, auth, sender.Address, []string{recipient.Address}, body)
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+var ErrInvalidToken = errors.New("invalid token")
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString, err := c.Cookie("Authorization")
@@ -17,34 +19,45 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.AbortWithStatus(http.StatusUnauthorized)
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(os.Getenv("JWTSECRET")), nil
-		})
+		userID, expiresAt, err := ParseToken(tokenString)
 		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			if float64(time.Now().Unix()) > claims["exp"].(float64) {
-				c.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
-
-			userID, ok := claims["user_id"].(float64)
-			if !ok {
-				c.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
-
-			c.Set("user_id", int(userID))
-			c.Next()
-		} else {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		c.Set("user_id", userID)
+		c.Set("token_expires_at", expiresAt)
+		c.Next()
+	}
+}
+
+// ParseToken validates a session token and returns the user it was issued to
+// and when it expires. Long-lived connections use the expiry to end the session
+func ParseToken(tokenString string) (int, time.Time, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
 		}
+		return []byte(os.Getenv("JWTSECRET")), nil
+	})
+	if err != nil {
+		return 0, time.Time{}, err
 	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, time.Time{}, ErrInvalidToken
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || float64(time.Now().Unix()) > exp {
+		return 0, time.Time{}, ErrInvalidToken
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, time.Time{}, ErrInvalidToken
+	}
+
+	return int(userID), time.Unix(int64(exp), 0), nil
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PersonalAccessToken lets a user authenticate clients such as CalDAV apps with
+// HTTP Basic auth. The token itself is only returned when it is created
+type PersonalAccessToken struct {
+	ID         int        `db:"id" json:"id"`
+	UserID     int        `db:"user_id" json:"-"`
+	Name       string     `db:"name" json:"name"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Token      string     `db:"-" json:"token,omitempty"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+type AccessTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
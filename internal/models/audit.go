@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+const (
+	AuditEventLoginLockout = "login_lockout"
+)
+
+type AuditRecord struct {
+	ID        int       `db:"id" json:"id"`
+	UserID    *int      `db:"user_id" json:"user_id"`
+	Event     string    `db:"event" json:"event"`
+	Subject   string    `db:"subject" json:"subject"`
+	Details   string    `db:"details" json:"details"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// DAVPersonalCalendar is the id of the CalDAV calendar of the user's personal
+// tasks, workspace calendars are named workspace-{id}
+const DAVPersonalCalendar = "personal"
+
+// DAVCalendar is a calendar collection of the CalDAV server. CTag changes
+// whenever a task of the calendar is added, changed or removed
+type DAVCalendar struct {
+	ID          string
+	Name        string
+	WorkspaceID *int
+	ReadOnly    bool
+	CTag        string
+}
+
+// DAVObject is a task served as a calendar resource holding one VTODO. Name is
+// the UID of the task with the .ics extension
+type DAVObject struct {
+	Name   string
+	ETag   string
+	Data   string
+	TaskID int
+}
+
+// DAVConditions are the If-Match and If-None-Match headers of a request that
+// changes a calendar resource
+type DAVConditions struct {
+	IfMatch     string
+	IfNoneMatch string
+}
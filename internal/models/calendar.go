@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// CalendarFeed is the secret address of a user's iCalendar feed, anyone with
+// the URL can read the feed until it expires or is rotated
+type CalendarFeed struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	ChatResponseEphemeral = "ephemeral"
+	ChatResponseInChannel = "in_channel"
+)
+
+const (
+	ChatBlockSection = "section"
+	ChatBlockContext = "context"
+	ChatBlockDivider = "divider"
+	ChatTextMarkdown = "mrkdwn"
+)
+
+// ChatLinkCode links the chat account that sends the command to the user who
+// created the code, it can be used once until it expires
+type ChatLinkCode struct {
+	Code      string    `json:"code"`
+	Command   string    `json:"command"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChatCommand is a slash command posted by the team chat
+type ChatCommand struct {
+	TeamID   string
+	UserID   string
+	UserName string
+	Command  string
+	Text     string
+}
+
+// ChatReply is the answer to a slash command. Text is the fallback of clients
+// that don't render blocks
+type ChatReply struct {
+	ResponseType string      `json:"response_type"`
+	Text         string      `json:"text"`
+	Blocks       []ChatBlock `json:"blocks"`
+}
+
+// ChatBlock is a section with Text, a context line of Elements or a divider
+type ChatBlock struct {
+	Type     string     `json:"type"`
+	Text     *ChatText  `json:"text,omitempty"`
+	Elements []ChatText `json:"elements,omitempty"`
+}
+
+type ChatText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
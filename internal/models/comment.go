@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Comment is a markdown message on a task, replies point to their parent comment
+type Comment struct {
+	ID             int        `db:"id" json:"id"`
+	TaskID         int        `db:"task_id" json:"task_id"`
+	AuthorID       int        `db:"author_id" json:"author_id"`
+	AuthorUsername string     `db:"author_username" json:"author_username"`
+	ParentID       *int       `db:"parent_id" json:"parent_id,omitempty"`
+	Body           string     `db:"body" json:"body"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	EditedAt       *time.Time `db:"edited_at" json:"edited_at,omitempty"`
+}
+
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
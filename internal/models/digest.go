@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+const (
+	DigestFormatJSON = "json"
+	DigestFormatText = "text"
+	DigestFormatHTML = "html"
+)
+
+// DigestSettings is the opt-in of a user to the daily digest. SendTime is the
+// local "HH:MM" after which the digest goes out, an empty Timezone follows the
+// time zone of the profile
+type DigestSettings struct {
+	UserID     int        `db:"user_id" json:"-"`
+	Enabled    bool       `db:"enabled" json:"enabled"`
+	SendTime   string     `db:"send_time" json:"send_time"`
+	Timezone   string     `db:"timezone" json:"timezone"`
+	LastSentOn *time.Time `db:"last_sent_on" json:"last_sent_on,omitempty"`
+}
+
+// DigestSettingsUpdate holds the fields of a PUT request, nil fields stay unchanged
+type DigestSettingsUpdate struct {
+	Enabled  *bool   `json:"enabled"`
+	SendTime *string `json:"send_time"`
+	Timezone *string `json:"timezone"`
+}
+
+// DueDigest is a user whose digest is due, Timezone is resolved from the
+// settings or the profile
+type DueDigest struct {
+	UserID   int    `db:"user_id"`
+	Timezone string `db:"timezone"`
+}
+
+// Digest summarizes the tasks a user is responsible for on one local day: the
+// tasks it is assigned to, and its personal tasks nobody is assigned to
+type Digest struct {
+	Date               string `json:"date"`
+	Timezone           string `json:"timezone"`
+	Overdue            []Task `json:"overdue"`
+	DueToday           []Task `json:"due_today"`
+	CompletedYesterday []Task `json:"completed_yesterday"`
+	NewAssignments     []Task `json:"new_assignments"`
+}
+
+// Empty tells whether the digest has nothing to report
+func (d *Digest) Empty() bool {
+	return len(d.Overdue) == 0 && len(d.DueToday) == 0 && len(d.CompletedYesterday) == 0 && len(d.NewAssignments) == 0
+}
+
+// RenderedDigest is a digest together with the mail it is sent as
+type RenderedDigest struct {
+	Digest  *Digest `json:"digest"`
+	Subject string  `json:"subject"`
+	Text    string  `json:"text"`
+	HTML    string  `json:"html"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+const (
+	TaskEventCreated = "task.created"
+	TaskEventUpdated = "task.updated"
+	TaskEventDeleted = "task.deleted"
+)
+
+// TaskEvent is a change of a task pushed to clients over the event stream.
+// UserID and WorkspaceID are copied from the task so deleted tasks can still
+// be delivered to the users who could see them
+type TaskEvent struct {
+	ID          int64          `db:"id" json:"id"`
+	Type        string         `db:"type" json:"type"`
+	TaskID      int            `db:"task_id" json:"task_id"`
+	UserID      int            `db:"user_id" json:"-"`
+	WorkspaceID *int           `db:"workspace_id" json:"workspace_id,omitempty"`
+	ActorID     *int           `db:"actor_id" json:"actor_id,omitempty"`
+	Payload     types.JSONText `db:"payload" json:"task,omitempty" swaggertype:"object"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	// OutboxID is the outbox message the event was recorded from
+	OutboxID *int64 `db:"outbox_id" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserIdentity links an account of an external OpenID Connect provider, or of
+// the team chat, to a user
+type UserIdentity struct {
+	ID          int        `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"user_id"`
+	Provider    string     `db:"provider" json:"provider"`
+	Subject     string     `db:"subject" json:"subject"`
+	Email       string     `db:"email" json:"email"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// InboundAddress is the secret email address of a user, mail sent to it
+// becomes tasks of the user until it expires or is rotated
+type InboundAddress struct {
+	Address   string    `json:"address"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InboundMailResult lists the tasks created from a message, one for every
+// user it was addressed to. Attachments are not stored, their names are listed.
+// Duplicates counts the users who already got a task from the message
+type InboundMailResult struct {
+	Tasks              []Task   `json:"tasks"`
+	IgnoredAttachments []string `json:"ignored_attachments"`
+	Duplicates         int      `json:"duplicates"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+type UserTOTP struct {
+	UserID       int        `db:"user_id"`
+	Secret       string     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+func (t *UserTOTP) Confirmed() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+type RecoveryCode struct {
+	ID       int        `db:"id"`
+	UserID   int        `db:"user_id"`
+	CodeHash string     `db:"code_hash"`
+	UsedAt   *time.Time `db:"used_at"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          []byte `json:"qr_png"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	NotificationTaskAssigned      = "task_assigned"
+	NotificationCommentMention    = "comment_mention"
+	NotificationTaskDueSoon       = "task_due_soon"
+	NotificationTaskStatusChanged = "task_status_changed"
+	NotificationTaskReminder      = "task_reminder"
+	NotificationDailyDigest       = "daily_digest"
+)
+
+// NotificationTypes lists every event type a user can switch off in the preferences
+var NotificationTypes = []string{
+	NotificationTaskAssigned,
+	NotificationCommentMention,
+	NotificationTaskDueSoon,
+	NotificationTaskStatusChanged,
+	NotificationTaskReminder,
+	NotificationDailyDigest,
+}
+
+type Notification struct {
+	ID        int        `db:"id" json:"id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	Type      string     `db:"type" json:"type"`
+	TaskID    *int       `db:"task_id" json:"task_id,omitempty"`
+	ActorID   *int       `db:"actor_id" json:"actor_id,omitempty"`
+	CommentID *int       `db:"comment_id" json:"comment_id,omitempty"`
+	Message   string     `db:"message" json:"message"`
+	OutboxID  *int64     `db:"outbox_id" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ReadAt    *time.Time `db:"read_at" json:"read_at,omitempty"`
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// NotificationPage is one page of the inbox, newest first
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+	HasMore       bool           `json:"has_more"`
+}
+
+type NotificationPreference struct {
+	Type    string `db:"type" json:"type"`
+	Enabled bool   `db:"enabled" json:"enabled"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// OutboxMessage is a task change stored in the same transaction as the change.
+// Payload is the task after the change, or before it for deletions, Previous
+// is the task before an update
+type OutboxMessage struct {
+	ID          int64          `db:"id"`
+	Type        string         `db:"type"`
+	TaskID      int            `db:"task_id"`
+	UserID      int            `db:"user_id"`
+	WorkspaceID *int           `db:"workspace_id"`
+	ActorID     int            `db:"actor_id"`
+	Payload     types.JSONText `db:"payload"`
+	Previous    types.JSONText `db:"previous"`
+	Attempts    int            `db:"attempts"`
+	LastError   *string        `db:"last_error"`
+	AvailableAt time.Time      `db:"available_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+	ProcessedAt *time.Time     `db:"processed_at"`
+}
+
+func (m *OutboxMessage) Task() (*Task, error) {
+	var task Task
+	if err := json.Unmarshal(m.Payload, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// PreviousTask returns the task before an update, nil for other changes
+func (m *OutboxMessage) PreviousTask() (*Task, error) {
+	if len(m.Previous) == 0 {
+		return nil, nil
+	}
+
+	var task Task
+	if err := json.Unmarshal(m.Previous, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
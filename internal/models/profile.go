@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Profile is the account as shown to its owner
+type Profile struct {
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name"`
+	Timezone      string    `json:"timezone"`
+	Locale        string    `json:"locale"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func NewProfile(user *User, mfaEnabled bool) *Profile {
+	return &Profile{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified(),
+		DisplayName:   user.DisplayName,
+		Timezone:      user.Timezone,
+		Locale:        user.Locale,
+		MFAEnabled:    mfaEnabled,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// DataExport is everything stored about a user, one JSON file per entry
+type DataExport struct {
+	Username   string
+	ExportedAt time.Time
+	Files      []ExportFile
+}
+
+type ExportFile struct {
+	Name string
+	Data any
+}
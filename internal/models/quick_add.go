@@ -0,0 +1,33 @@
+package models
+
+// kinds of the spans recognized in a quick add text
+const (
+	QuickAddDue        = "due"
+	QuickAddPriority   = "priority"
+	QuickAddTag        = "tag"
+	QuickAddProject    = "project"
+	QuickAddRecurrence = "recurrence"
+)
+
+type QuickAddRequest struct {
+	Text        string `json:"text" binding:"required"`
+	WorkspaceID *int   `json:"workspace_id"`
+	// DryRun only parses the text, the task is not created
+	DryRun bool `json:"dry_run"`
+}
+
+// QuickAddSpan is a part of the text read as a field of the task. Start and
+// End count characters, Value is the part as stored: a date, a time of day, a
+// priority, a tag or an RRULE
+type QuickAddSpan struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type QuickAddResult struct {
+	Task  Task           `json:"task"`
+	Spans []QuickAddSpan `json:"spans"`
+}
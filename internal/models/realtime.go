@@ -0,0 +1,57 @@
+package models
+
+import "encoding/json"
+
+// Messages sent by WebSocket clients
+const (
+	WSAuth        = "auth"
+	WSSubscribe   = "subscribe"
+	WSUnsubscribe = "unsubscribe"
+	WSMutate      = "mutate"
+)
+
+// Messages sent by the server
+const (
+	WSEvent    = "event"
+	WSPresence = "presence"
+	WSResult   = "result"
+	WSError    = "error"
+	WSReset    = "reset"
+)
+
+// Mutations a client can send
+const (
+	WSCreateTask = "create"
+	WSUpdateTask = "update"
+	WSDeleteTask = "delete"
+)
+
+// WSClientMessage is a message from a WebSocket client. Topics are
+// "workspace:<id>" for every task of a workspace and "task:<id>" for one task,
+// the latter also marks the user as viewing the task
+type WSClientMessage struct {
+	Type   string          `json:"type"`
+	ID     string          `json:"id,omitempty"`
+	Token  string          `json:"token,omitempty"`
+	Topic  string          `json:"topic,omitempty"`
+	Action string          `json:"action,omitempty"`
+	TaskID int             `json:"task_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// WSServerMessage is a message to a WebSocket client. Replies carry the ID of
+// the client message they answer
+type WSServerMessage struct {
+	Type    string     `json:"type"`
+	ID      string     `json:"id,omitempty"`
+	Topic   string     `json:"topic,omitempty"`
+	Event   *TaskEvent `json:"event,omitempty"`
+	Task    *Task      `json:"task,omitempty"`
+	Viewers []Viewer   `json:"viewers,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type Viewer struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	ReminderChannelInApp   = "in_app"
+	ReminderChannelEmail   = "email"
+	ReminderChannelWebhook = "webhook"
+)
+
+// ReminderChannels lists the channels a reminder can be delivered through
+var ReminderChannels = []string{
+	ReminderChannelInApp,
+	ReminderChannelEmail,
+	ReminderChannelWebhook,
+}
+
+const (
+	ReminderPending   = "pending"
+	ReminderFired     = "fired"
+	ReminderDismissed = "dismissed"
+)
+
+// Reminder is set by a user on a task it can see. It fires at RemindAt or
+// BeforeDueMinutes before the due date of the task, FireAt is the resulting
+// time and is empty while a relative reminder's task has no due date
+type Reminder struct {
+	ID               int        `db:"id" json:"id"`
+	TaskID           int        `db:"task_id" json:"task_id"`
+	UserID           int        `db:"user_id" json:"user_id"`
+	RemindAt         *time.Time `db:"remind_at" json:"remind_at,omitempty"`
+	BeforeDueMinutes *int       `db:"before_due_minutes" json:"before_due_minutes,omitempty"`
+	Channels         StringList `db:"channels" json:"channels" swaggertype:"array,string"`
+	SnoozedUntil     *time.Time `db:"snoozed_until" json:"snoozed_until,omitempty"`
+	FiredAt          *time.Time `db:"fired_at" json:"fired_at,omitempty"`
+	DismissedAt      *time.Time `db:"dismissed_at" json:"dismissed_at,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	// FireAt, Status, TaskTitle and TaskDueAt are computed when the reminder is read
+	FireAt    *time.Time `db:"fire_at" json:"fire_at,omitempty"`
+	Status    string     `db:"status" json:"status"`
+	TaskTitle string     `db:"task_title" json:"task_title"`
+	TaskDueAt *time.Time `db:"task_due_at" json:"task_due_at,omitempty"`
+}
+
+type SnoozeReminderRequest struct {
+	Minutes int `json:"minutes" binding:"required"`
+}
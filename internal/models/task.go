@@ -6,20 +6,75 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// TaskStatusDone marks a task as completed, the time it was first set is kept
+// in CompletedAt
+const TaskStatusDone = "done"
+
+const (
+	TaskPriorityLow    = "low"
+	TaskPriorityMedium = "medium"
+	TaskPriorityHigh   = "high"
+)
+
+// TaskPriorities lists the priorities a task can have, an empty priority means none
+var TaskPriorities = []string{TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh}
+
 type Task struct {
-	ID          int      `db:"id" json:"id"`
-	UserID      int      `db:"user_id" json:"user_id"`
-	Title       string   `db:"title" json:"title" binding:"required"`
-	Description string   `db:"description" json:"description" binding:"required"`
-	Status      string   `db:"status" json:"status" binding:"required"`
-	CreatedAt   JSONTime `db:"created_at" json:"created_at"`
+	ID          int        `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"user_id"`
+	WorkspaceID *int       `db:"workspace_id" json:"workspace_id,omitempty"`
+	AssigneeID  *int       `db:"assignee_id" json:"assignee_id,omitempty"`
+	ExternalID  *string    `db:"external_id" json:"external_id,omitempty"`
+	Title       string     `db:"title" json:"title" binding:"required"`
+	Description string     `db:"description" json:"description" binding:"required"`
+	Status      string     `db:"status" json:"status" binding:"required"`
+	Priority    string     `db:"priority" json:"priority,omitempty"`
+	Recurrence  string     `db:"recurrence" json:"recurrence,omitempty"` // RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO
+	Tags        StringList `db:"tags" json:"tags,omitempty" swaggertype:"array,string"`
+	DueAt       *JSONTime  `db:"due_at" json:"due_at,omitempty"`
+	CreatedAt   JSONTime   `db:"created_at" json:"created_at"`
+	// CompletedAt and AssignedAt follow the status and the assignee, they are
+	// ignored on writes
+	CompletedAt *JSONTime `db:"completed_at" json:"completed_at,omitempty"`
+	AssignedAt  *JSONTime `db:"assigned_at" json:"assigned_at,omitempty"`
+	// CommentCount is computed when the task is read and ignored on writes
+	CommentCount int `db:"comment_count" json:"comment_count"`
 }
 
 func (t Task) MarshalZerologObject(e *zerolog.Event) {
 	e.Int("id", t.ID).
 		Int("user_id", t.UserID).
+		Interface("workspace_id", t.WorkspaceID).
+		Interface("assignee_id", t.AssigneeID).
+		Interface("external_id", t.ExternalID).
 		Str("title", t.Title).
 		Str("description", t.Description).
 		Str("status", t.Status).
+		Str("priority", t.Priority).
+		Str("recurrence", t.Recurrence).
+		Strs("tags", t.Tags).
+		Interface("due_at", t.DueAt).
 		Time("created_at", time.Time(t.CreatedAt))
 }
+
+// TaskFilter narrows a task listing, zero values mean no restriction
+type TaskFilter struct {
+	WorkspaceID *int
+	AssigneeID  *int
+	// Personal keeps only the tasks outside of workspaces
+	Personal bool
+	// Tag keeps only the tasks with this tag, such as the project of a
+	// todo.txt line or of an imported app
+	Tag string
+}
+
+type TaskWatcher struct {
+	TaskID    int       `db:"task_id" json:"task_id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type AssignTaskRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
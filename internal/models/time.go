@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -11,3 +12,27 @@ func (t JSONTime) MarshalJSON() ([]byte, error) {
 	formatted := fmt.Sprintf(`"%s"`, time.Time(t).Format(time.RFC1123))
 	return []byte(formatted), nil
 }
+
+func (t *JSONTime) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := ParseJSONTime(raw)
+	if err != nil {
+		return err
+	}
+
+	*t = JSONTime(parsed)
+	return nil
+}
+
+// ParseJSONTime reads a time written by MarshalJSON, RFC 3339 is accepted too
+func ParseJSONTime(raw string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC1123, raw)
+	if err != nil {
+		return time.Parse(time.RFC3339, raw)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import "time"
+
+const (
+	TransferFormatCSV    = "csv"
+	TransferFormatJSON   = "json"
+	TransferFormatNDJSON = "ndjson"
+	TransferFormatICS    = "ics"
+	// TransferFormatTodoTxt is the todo.txt format, one task per line
+	TransferFormatTodoTxt = "txt"
+	// TransferFormatMarkdown is a GitHub-flavored markdown checklist
+	TransferFormatMarkdown = "markdown"
+)
+
+// ImportFields lists the task fields an import can fill, the mapping of an
+// import names the source column of each
+var ImportFields = []string{
+	"external_id", "title", "description", "status", "priority", "recurrence", "tags", "due_at", "completed_at", "created_at",
+}
+
+// ImportRequest describes an uploaded file of tasks. Mapping maps task fields to
+// source columns, unmapped fields are read from the column of the same name
+type ImportRequest struct {
+	UserID      int
+	WorkspaceID *int
+	Format      string
+	Mapping     map[string]string
+	DryRun      bool
+}
+
+// ImportReport tells how the rows of an import were handled. Rows are numbered
+// from 1, the CSV header is not counted
+type ImportReport struct {
+	DryRun     bool             `json:"dry_run"`
+	Total      int              `json:"total"`
+	Valid      int              `json:"valid"`
+	Invalid    int              `json:"invalid"`
+	Duplicates int              `json:"duplicates"`
+	Created    int              `json:"created"`
+	Errors     []ImportRowError `json:"errors"`
+}
+
+type ImportRowError struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+const (
+	ImportSourceTodoist = "todoist"
+	ImportSourceTrello  = "trello"
+	ImportSourceMSTodo  = "mstodo"
+)
+
+// ImportedTask is a task read from the export of another app together with
+// the checklist and the comments it had there
+type ImportedTask struct {
+	Task
+	Checklist []ChecklistItem
+	Comments  []ImportedComment
+}
+
+type ChecklistItem struct {
+	Text    string
+	Checked bool
+}
+
+// ImportedComment keeps the author of a comment by name, the comment is stored
+// as written by the importing user
+type ImportedComment struct {
+	Author    string
+	Body      string
+	CreatedAt *time.Time
+}
+
+// AppImport is the content of an export file of another app. Projects and
+// lists become tags of their tasks, as do labels
+type AppImport struct {
+	Projects int
+	Labels   int
+	Tasks    []ImportedTask
+	Warnings []string
+}
+
+// AppImportRequest describes an uploaded export file of another app, the file
+// name tells its format and, for a Todoist project CSV, the project name
+type AppImportRequest struct {
+	UserID      int
+	WorkspaceID *int
+	Source      string
+	FileName    string
+	DryRun      bool
+}
+
+// AppImportReport counts what an import from another app brings in. Tasks is
+// the number of new tasks, tasks that cannot be stored are skipped with a warning
+type AppImportReport struct {
+	Source         string   `json:"source"`
+	DryRun         bool     `json:"dry_run"`
+	Projects       int      `json:"projects"`
+	Labels         int      `json:"labels"`
+	Tasks          int      `json:"tasks"`
+	ChecklistItems int      `json:"checklist_items"`
+	Comments       int      `json:"comments"`
+	Skipped        int      `json:"skipped"`
+	Duplicates     int      `json:"duplicates"`
+	Created        int      `json:"created"`
+	Warnings       []string `json:"warnings"`
+}
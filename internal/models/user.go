@@ -7,16 +7,26 @@ import (
 )
 
 type User struct {
-	ID           int       `db:"id" json:"id"`
-	Username     string    `db:"username" json:"username" binding:"required"`
-	Password     string    `db:"-" json:"password" binding:"required"`
-	PasswordHash string    `db:"password_hash" json:"-"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	ID              int        `db:"id" json:"id"`
+	Username        string     `db:"username" json:"username" binding:"required"`
+	Email           string     `db:"email" json:"email"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	DisplayName     string     `db:"display_name" json:"display_name"`
+	Timezone        string     `db:"timezone" json:"timezone"`
+	Locale          string     `db:"locale" json:"locale"`
+	Password        string     `db:"-" json:"password" binding:"required"`
+	PasswordHash    string     `db:"password_hash" json:"-"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
 }
 
 func (u User) MarshalZerologObject(e *zerolog.Event) {
 	e.Int("id", u.ID).
 		Str("username", u.Username).
+		Str("email", u.Email).
 		Str("password", u.Password).
 		Str("password_hash", u.PasswordHash).
 		Time("created_at", u.CreatedAt)
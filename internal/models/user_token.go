@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeCalendarFeed      = "calendar_feed"
+	TokenPurposeInboundMail       = "inbound_mail"
+	TokenPurposeChatLink          = "chat_link"
+)
+
+type UserToken struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// WebhookEventTest is sent by the "send test event" endpoint regardless of the
+// event types of the webhook
+const WebhookEventTest = "webhook.test"
+
+// WebhookEventReminder is sent when a reminder of the webhook owner fires
+// with the webhook channel
+const WebhookEventReminder = "task.reminder"
+
+// WebhookEventTypes lists the events a webhook can subscribe to
+var WebhookEventTypes = []string{
+	TaskEventCreated,
+	TaskEventUpdated,
+	TaskEventDeleted,
+	WebhookEventReminder,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a user's subscription to task events. The secret signs every
+// delivery and is only returned when the webhook is created
+type Webhook struct {
+	ID                  int        `db:"id" json:"id"`
+	UserID              int        `db:"user_id" json:"user_id"`
+	URL                 string     `db:"url" json:"url"`
+	Secret              string     `db:"secret" json:"secret,omitempty"`
+	EventTypes          StringList `db:"event_types" json:"event_types" swaggertype:"array,string"`
+	Enabled             bool       `db:"enabled" json:"enabled"`
+	ConsecutiveFailures int        `db:"consecutive_failures" json:"consecutive_failures"`
+	DisabledAt          *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for a webhook together with the outcome
+// of its last attempt. URL and Secret are filled when a delivery is claimed
+type WebhookDelivery struct {
+	ID            int64          `db:"id" json:"id"`
+	WebhookID     int            `db:"webhook_id" json:"webhook_id"`
+	EventType     string         `db:"event_type" json:"event_type"`
+	Payload       types.JSONText `db:"payload" json:"payload" swaggertype:"object"`
+	Status        string         `db:"status" json:"status"`
+	Attempts      int            `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time      `db:"next_attempt_at" json:"next_attempt_at"`
+	ResponseCode  *int           `db:"response_code" json:"response_code,omitempty"`
+	Error         *string        `db:"error" json:"error,omitempty"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	DeliveredAt   *time.Time     `db:"delivered_at" json:"delivered_at,omitempty"`
+	URL           string         `db:"url" json:"-"`
+	Secret        string         `db:"secret" json:"-"`
+}
+
+// WebhookPayload is the body posted to webhook URLs
+type WebhookPayload struct {
+	Event      string         `json:"event"`
+	TaskID     int            `json:"task_id,omitempty"`
+	ActorID    int            `json:"actor_id,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Task       types.JSONText `json:"task,omitempty" swaggertype:"object"`
+	Reminder   *Reminder      `json:"reminder,omitempty"`
+}
+
+// WebhookUpdate holds the fields of a PATCH request, nil fields stay unchanged
+type WebhookUpdate struct {
+	URL        *string  `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Enabled    *bool    `json:"enabled"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// WorkspaceRole is the role of a member inside a workspace. Roles are ordered,
+// every role has all permissions of the roles below it
+type WorkspaceRole string
+
+const (
+	RoleOwner  WorkspaceRole = "owner"
+	RoleAdmin  WorkspaceRole = "admin"
+	RoleMember WorkspaceRole = "member"
+	RoleViewer WorkspaceRole = "viewer"
+)
+
+var roleRanks = map[WorkspaceRole]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// WriterRoles are the roles allowed to create, edit and delete workspace tasks
+var WriterRoles = []WorkspaceRole{RoleOwner, RoleAdmin, RoleMember}
+
+func (r WorkspaceRole) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r grants everything other grants
+func (r WorkspaceRole) AtLeast(other WorkspaceRole) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// CanWrite reports whether the role may modify workspace tasks
+func (r WorkspaceRole) CanWrite() bool {
+	return r.AtLeast(RoleMember)
+}
+
+// CanManage reports whether the role may invite and manage members
+func (r WorkspaceRole) CanManage() bool {
+	return r.AtLeast(RoleAdmin)
+}
+
+type Workspace struct {
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedBy *int      `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// Role of the requesting user, filled when listing the user's workspaces
+	Role WorkspaceRole `db:"role" json:"role,omitempty"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int           `db:"workspace_id" json:"workspace_id"`
+	UserID      int           `db:"user_id" json:"user_id"`
+	Username    string        `db:"username" json:"username"`
+	Role        WorkspaceRole `db:"role" json:"role"`
+	JoinedAt    time.Time     `db:"joined_at" json:"joined_at"`
+}
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+type WorkspaceInvitation struct {
+	ID            int           `db:"id" json:"id"`
+	WorkspaceID   int           `db:"workspace_id" json:"workspace_id"`
+	WorkspaceName string        `db:"workspace_name" json:"workspace_name"`
+	InviterID     *int          `db:"inviter_id" json:"inviter_id,omitempty"`
+	InviteeID     int           `db:"invitee_id" json:"invitee_id"`
+	Role          WorkspaceRole `db:"role" json:"role"`
+	Status        string        `db:"status" json:"status"`
+	CreatedAt     time.Time     `db:"created_at" json:"created_at"`
+	RespondedAt   *time.Time    `db:"responded_at" json:"responded_at,omitempty"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type InvitationRequest struct {
+	Username string        `json:"username" binding:"required"`
+	Role     WorkspaceRole `json:"role"`
+}
+
+type MemberRoleRequest struct {
+	Role WorkspaceRole `json:"role" binding:"required"`
+}
@@ -0,0 +1,192 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "todo-app"
+	testKeyID    = "test-key"
+)
+
+// mockProvider is a minimal OpenID Connect provider: discovery, JWKS and a
+// token endpoint that checks the PKCE verifier against the recorded challenge
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize simulates the user logging in on the provider's page
+func (m *mockProvider) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, testClientID, q.Get("client_id"))
+
+	m.mu.Lock()
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+	m.mu.Unlock()
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                m.server.URL,
+		"authorization_endpoint":                m.server.URL + "/authorize",
+		"token_endpoint":                        m.server.URL + "/token",
+		"jwks_uri":                              m.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	challenge, nonce := m.challenge, m.nonce
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                m.server.URL,
+		"aud":                testClientID,
+		"sub":                "external-42",
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+	})
+	idToken.Header["kid"] = testKeyID
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func newTestProvider(m *mockProvider) *oidc.Provider {
+	return oidc.NewProvider(config.OIDCProvider{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8080/api/auth/oidc/mock/callback",
+	})
+}
+
+func TestProviderFlow(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	verifier := oidc.NewVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1", verifier)
+	require.NoError(t, err)
+	m.authorize(t, authURL)
+
+	claims, err := provider.Exchange(ctx, "good-code", verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "external-42", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "alice", claims.PreferredUsername)
+}
+
+func TestProviderRejectsWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1", oidc.NewVerifier())
+	require.NoError(t, err)
+	m.authorize(t, authURL)
+
+	_, err = provider.Exchange(ctx, "good-code", oidc.NewVerifier(), "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+}
+
+func TestProviderRejectsWrongNonce(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	verifier := oidc.NewVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1", verifier)
+	require.NoError(t, err)
+	m.authorize(t, authURL)
+
+	_, err = provider.Exchange(ctx, "good-code", verifier, "nonce-2")
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+}
@@ -0,0 +1,137 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes produced by any supported one, so the algorithm can be switched
+// without invalidating stored passwords
+type Hasher struct {
+	cfg config.PasswordHashing
+}
+
+func NewHasher(cfg config.PasswordHashing) *Hasher {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmBcrypt
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = bcrypt.DefaultCost
+	}
+	return &Hasher{cfg: cfg}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	switch h.cfg.Algorithm {
+	case AlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		return string(hash), err
+	case AlgorithmArgon2id:
+		return h.hashArgon2id(password)
+	default:
+		return "", fmt.Errorf("unsupported password hashing algorithm %q", h.cfg.Algorithm)
+	}
+}
+
+// Verify reports whether password matches hash. A mismatch is not an error.
+// An empty hash belongs to an account without a local password and never matches
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	switch {
+	case hash == "":
+		return false, nil
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or weaker parameters
+func (h *Hasher) NeedsRehash(hash string) bool {
+	switch h.cfg.Algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.cfg.BcryptCost
+	case AlgorithmArgon2id:
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || params != h.cfg.Argon2
+	default:
+		return false
+	}
+}
+
+func (h *Hasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.cfg.Argon2
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (config.Argon2, []byte, []byte, error) {
+	var params config.Argon2
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	return params, salt, key, nil
+}
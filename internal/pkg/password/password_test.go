@@ -0,0 +1,99 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/pkg/password"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var argon2Params = config.Argon2{Time: 1, Memory: 1024, Threads: 1}
+
+func TestBcrypt(t *testing.T) {
+	t.Parallel()
+	hasher := password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+
+	hash, err := hasher.Hash("secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2"))
+
+	ok, err := hasher.Verify(hash, "secret")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = hasher.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	stronger := password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	assert.True(t, stronger.NeedsRehash(hash))
+}
+
+func TestArgon2id(t *testing.T) {
+	t.Parallel()
+	hasher := password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmArgon2id, Argon2: argon2Params})
+
+	hash, err := hasher.Hash("secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := hasher.Verify(hash, "secret")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = hasher.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	tuned := password.NewHasher(config.PasswordHashing{
+		Algorithm: password.AlgorithmArgon2id,
+		Argon2:    config.Argon2{Time: 2, Memory: 1024, Threads: 1},
+	})
+	assert.True(t, tuned.NeedsRehash(hash))
+}
+
+func TestCrossAlgorithm(t *testing.T) {
+	t.Parallel()
+	bcryptHasher := password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	argonHasher := password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmArgon2id, Argon2: argon2Params})
+
+	bcryptHash, _ := bcryptHasher.Hash("secret")
+	argonHash, _ := argonHasher.Hash("secret")
+
+	ok, err := argonHasher.Verify(bcryptHash, "secret")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, argonHasher.NeedsRehash(bcryptHash))
+
+	ok, err = bcryptHasher.Verify(argonHash, "secret")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, bcryptHasher.NeedsRehash(argonHash))
+}
+
+func TestUnknownFormat(t *testing.T) {
+	t.Parallel()
+	hasher := password.NewHasher(config.PasswordHashing{})
+
+	_, err := hasher.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, password.ErrUnknownHashFormat)
+
+	_, err = hasher.Verify("$argon2id$v=19$broken", "secret")
+	assert.ErrorIs(t, err, password.ErrUnknownHashFormat)
+}
+
+func TestEmptyHashNeverMatches(t *testing.T) {
+	t.Parallel()
+	hasher := password.NewHasher(config.PasswordHashing{})
+
+	ok, err := hasher.Verify("", "")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
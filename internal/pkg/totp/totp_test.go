@@ -0,0 +1,89 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Секрет из приложения B RFC 6238 (SHA1)
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range cases {
+		code, err := totp.GenerateCode(rfcSecret, totp.Step(time.Unix(tc.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, code, "unix time %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1234567890, 0)
+
+	t.Run("Current step", func(t *testing.T) {
+		step, ok := totp.Validate(rfcSecret, "005924", now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, totp.Step(now), step)
+	})
+
+	t.Run("Previous step within skew", func(t *testing.T) {
+		_, ok := totp.Validate(rfcSecret, "005924", now.Add(totp.Period), 1)
+		assert.True(t, ok)
+	})
+
+	t.Run("Outside skew", func(t *testing.T) {
+		_, ok := totp.Validate(rfcSecret, "005924", now.Add(2*totp.Period), 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		_, ok := totp.Validate(rfcSecret, "000000", now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("Malformed code", func(t *testing.T) {
+		_, ok := totp.Validate(rfcSecret, "12345", now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalid secret", func(t *testing.T) {
+		_, ok := totp.Validate("not base32!", "005924", now, 1)
+		assert.False(t, ok)
+	})
+}
+
+func TestProvisioningURI(t *testing.T) {
+	t.Parallel()
+
+	secret, err := totp.NewSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := totp.ProvisioningURI("TODO App", "alice", secret)
+	u, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/TODO App:alice", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "TODO App", u.Query().Get("issuer"))
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var accessTokenColumns = []string{"id", "user_id", "name", "token_hash", "last_used_at", "created_at"}
+
+type AccessTokenRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewAccessTokenRepository(db *sqlx.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *AccessTokenRepository) CreateAccessToken(token *models.PersonalAccessToken) error {
+	query, args, err := r.sq.Insert("personal_access_tokens").
+		Columns("user_id", "name", "token_hash", "created_at").
+		Values(token.UserID, token.Name, token.TokenHash, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", token.UserID).
+			Err(err).
+			Msg("Failed to build CreateAccessToken query")
+		return err
+	}
+
+	err = r.db.QueryRowx(query, args...).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Int("user_id", token.UserID).
+			Err(err).
+			Msg("CreateAccessToken DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *AccessTokenRepository) GetAccessTokens(userID int) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+
+	query, args, err := r.sq.Select(accessTokenColumns...).
+		From("personal_access_tokens").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetAccessTokens query")
+		return tokens, err
+	}
+
+	err = r.db.Select(&tokens, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetAccessTokens DB execution error")
+		return tokens, err
+	}
+
+	return tokens, nil
+}
+
+func (r *AccessTokenRepository) DeleteAccessToken(id, userID int) error {
+	query, args, err := r.sq.Delete("personal_access_tokens").
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("token_id", id).
+			Err(err).
+			Msg("Failed to build DeleteAccessToken query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteAccessToken DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// UseAccessToken records the use of the token with the hash and returns it,
+// nil when no such token exists
+func (r *AccessTokenRepository) UseAccessToken(tokenHash string, now time.Time) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+
+	query, args, err := r.sq.Update("personal_access_tokens").
+		Set("last_used_at", now).
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		Suffix("RETURNING id, user_id, name, token_hash, last_used_at, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build UseAccessToken query")
+		return nil, err
+	}
+
+	err = r.db.Get(&token, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Err(err).
+			Msg("UseAccessToken DB execution error")
+		return nil, err
+	}
+
+	return &token, nil
+}
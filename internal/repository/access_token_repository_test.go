@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewAccessTokenMock(t *testing.T) (sqlmock.Sqlmock, *repository.AccessTokenRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewAccessTokenRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestUseAccessToken(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Known token", func(t *testing.T) {
+		mock, repo := NewAccessTokenMock(t)
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "name", "token_hash", "last_used_at", "created_at"}).
+			AddRow(3, 1, "Phone", "hash", now, now)
+		mock.ExpectQuery("UPDATE personal_access_tokens SET last_used_at = \\$1 WHERE token_hash = \\$2 RETURNING (.+)").
+			WithArgs(now, "hash").
+			WillReturnRows(rows)
+
+		token, err := repo.UseAccessToken("hash", now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, token.UserID)
+		assert.Equal(t, "Phone", token.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		mock, repo := NewAccessTokenMock(t)
+
+		mock.ExpectQuery("UPDATE personal_access_tokens SET last_used_at = (.+)").
+			WithArgs(now, "other").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		token, err := repo.UseAccessToken("other", now)
+		assert.NoError(t, err)
+		assert.Nil(t, token)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteAccessToken(t *testing.T) {
+	mock, repo := NewAccessTokenMock(t)
+
+	mock.ExpectExec("DELETE FROM personal_access_tokens WHERE id = \\$1 AND user_id = \\$2").
+		WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.DeleteAccessToken(3, 2)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
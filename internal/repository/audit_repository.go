@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *AuditRepository) CreateRecord(record *models.AuditRecord) error {
+	query, args, err := r.sq.Insert("audit_log").
+		Columns("user_id", "event", "subject", "details", "created_at").
+		Values(record.UserID, record.Event, record.Subject, record.Details, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("event", record.Event).
+			Err(err).
+			Msg("Failed to build CreateRecord query")
+		return err
+	}
+
+	err = r.db.QueryRow(query, args...).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateRecord DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) GetRecordsByUserID(userID int) ([]models.AuditRecord, error) {
+	var records []models.AuditRecord
+
+	query, args, err := r.sq.Select("id", "user_id", "event", "subject", "COALESCE(details, '') AS details", "created_at").
+		From("audit_log").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetRecordsByUserID query")
+		return records, err
+	}
+
+	err = r.db.Select(&records, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetRecordsByUserID DB execution error")
+		return records, err
+	}
+
+	return records, nil
+}
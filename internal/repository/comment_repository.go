@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var commentColumns = []string{
+	"c.id", "c.task_id", "c.author_id", "u.username AS author_username",
+	"c.parent_id", "c.body", "c.created_at", "c.edited_at",
+}
+
+type CommentRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewCommentRepository(db *sqlx.DB) *CommentRepository {
+	return &CommentRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+// CreateComment inserts the comment and the mention notifications in one transaction
+func (r *CommentRepository) CreateComment(comment *models.Comment, notifications []models.Notification) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("CreateComment begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Insert("task_comments").
+		Columns("task_id", "author_id", "parent_id", "body", "created_at").
+		Values(comment.TaskID, comment.AuthorID, comment.ParentID, comment.Body, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", comment.TaskID).
+			Int("author_id", comment.AuthorID).
+			Err(err).
+			Msg("Failed to build CreateComment query")
+		return err
+	}
+
+	if err = tx.QueryRow(query, args...).Scan(&comment.ID, &comment.CreatedAt); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateComment DB execution error")
+		return err
+	}
+
+	if err := r.notify(tx, comment.ID, notifications); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *CommentRepository) GetComment(id int) (*models.Comment, error) {
+	var comment models.Comment
+
+	query, args, err := r.sq.Select(commentColumns...).
+		From("task_comments c").
+		Join("users u ON u.id = c.author_id").
+		Where(squirrel.Eq{"c.id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("comment_id", id).
+			Err(err).
+			Msg("Failed to build GetComment query")
+		return nil, err
+	}
+
+	err = r.db.Get(&comment, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetComment DB execution error")
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetCommentsByTaskID returns the comments of a task oldest first, threads are
+// rebuilt by clients from parent_id
+func (r *CommentRepository) GetCommentsByTaskID(taskID int) ([]models.Comment, error) {
+	return r.selectComments(squirrel.Eq{"c.task_id": taskID}, "GetCommentsByTaskID")
+}
+
+func (r *CommentRepository) GetCommentsByAuthorID(authorID int) ([]models.Comment, error) {
+	return r.selectComments(squirrel.Eq{"c.author_id": authorID}, "GetCommentsByAuthorID")
+}
+
+// UpdateComment changes the body of a comment written by authorID and stores
+// notifications for users mentioned by the edit
+func (r *CommentRepository) UpdateComment(comment *models.Comment, authorID int, notifications []models.Notification) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("UpdateComment begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Update("task_comments").
+		Set("body", comment.Body).
+		Set("edited_at", comment.EditedAt).
+		Where(squirrel.Eq{"id": comment.ID, "author_id": authorID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("comment_id", comment.ID).
+			Int("author_id", authorID).
+			Err(err).
+			Msg("Failed to build UpdateComment query")
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("UpdateComment DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	if err := r.notify(tx, comment.ID, notifications); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteComment removes a comment written by authorID, its replies lose their parent
+func (r *CommentRepository) DeleteComment(id, authorID int) error {
+	query, args, err := r.sq.Delete("task_comments").
+		Where(squirrel.Eq{"id": id, "author_id": authorID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("comment_id", id).
+			Int("author_id", authorID).
+			Err(err).
+			Msg("Failed to build DeleteComment query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteComment DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *CommentRepository) selectComments(where squirrel.Sqlizer, name string) ([]models.Comment, error) {
+	var comments []models.Comment
+
+	query, args, err := r.sq.Select(commentColumns...).
+		From("task_comments c").
+		Join("users u ON u.id = c.author_id").
+		Where(where).
+		OrderBy("c.created_at", "c.id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msgf("Failed to build %s query", name)
+		return comments, err
+	}
+
+	err = r.db.Select(&comments, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return comments, err
+	}
+
+	return comments, nil
+}
+
+func (r *CommentRepository) notify(q sqlx.Queryer, commentID int, notifications []models.Notification) error {
+	for i := range notifications {
+		notifications[i].CommentID = &commentID
+		if err := insertNotification(q, r.sq, r.log, &notifications[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewCommentMock(t *testing.T) (sqlmock.Sqlmock, *repository.CommentRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewCommentRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateComment(t *testing.T) {
+	mock, repo := NewCommentMock(t)
+
+	taskID, actorID := 42, 3
+	comment := &models.Comment{TaskID: taskID, AuthorID: actorID, Body: "@bob take a look"}
+	notifications := []models.Notification{{
+		UserID:  2,
+		Type:    models.NotificationCommentMention,
+		TaskID:  &taskID,
+		ActorID: &actorID,
+		Message: "@bob take a look",
+	}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_comments").
+		WithArgs(taskID, actorID, nil, "@bob take a look", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	commentID := 7
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(2, models.NotificationCommentMention, &taskID, &actorID, &commentID, "@bob take a look", nil, sqlmock.AnyArg(),
+			false, models.NotificationCommentMention, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(9, time.Now()))
+	mock.ExpectCommit()
+
+	err := repo.CreateComment(comment, notifications)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, comment.ID)
+	assert.Equal(t, 7, *notifications[0].CommentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCommentRollback(t *testing.T) {
+	mock, repo := NewCommentMock(t)
+
+	taskID := 42
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_comments").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WillReturnError(errors.New("db down"))
+	mock.ExpectRollback()
+
+	err := repo.CreateComment(&models.Comment{TaskID: taskID, AuthorID: 3, Body: "hi"},
+		[]models.Notification{{UserID: 2, Type: models.NotificationCommentMention, TaskID: &taskID}})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCommentsByTaskID(t *testing.T) {
+	mock, repo := NewCommentMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "task_id", "author_id", "author_username", "parent_id", "body", "created_at", "edited_at"}).
+		AddRow(1, 42, 3, "carol", nil, "First", time.Now(), nil).
+		AddRow(2, 42, 2, "bob", 1, "Reply", time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM task_comments c JOIN users u ON u.id = c.author_id WHERE c.task_id = (.+) ORDER BY c.created_at, c.id").
+		WithArgs(42).
+		WillReturnRows(rows)
+
+	comments, err := repo.GetCommentsByTaskID(42)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Equal(t, 1, *comments[1].ParentID)
+	assert.NotNil(t, comments[1].EditedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCommentNotAuthor(t *testing.T) {
+	mock, repo := NewCommentMock(t)
+
+	editedAt := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE task_comments SET body = (.+), edited_at = (.+) WHERE author_id = (.+) AND id = (.+)").
+		WithArgs("edit", &editedAt, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.UpdateComment(&models.Comment{ID: 7, Body: "edit", EditedAt: &editedAt}, 5, nil)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteComment(t *testing.T) {
+	mock, repo := NewCommentMock(t)
+
+	mock.ExpectExec("DELETE FROM task_comments WHERE author_id = (.+) AND id = (.+)").
+		WithArgs(3, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.DeleteComment(7, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// digestZone is the digest time zone of a user, the one of the settings or the
+// profile. A zone Postgres doesn't know falls back to UTC for that user only,
+// instead of failing the whole batch
+const digestZone = "COALESCE(dz.name, 'UTC')"
+
+// digestLocalTime converts the time argument to the digest time zone
+const digestLocalTime = "(?::timestamptz AT TIME ZONE " + digestZone + ")"
+
+type DigestRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewDigestRepository(db *sqlx.DB) *DigestRepository {
+	return &DigestRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+// GetDigestSettings returns the stored settings of the user, nil if it never
+// changed them
+func (r *DigestRepository) GetDigestSettings(userID int) (*models.DigestSettings, error) {
+	var settings models.DigestSettings
+
+	query, args, err := r.sq.Select("user_id", "enabled", "send_time", "COALESCE(timezone, '') AS timezone", "last_sent_on").
+		From("digest_settings").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetDigestSettings query")
+		return nil, err
+	}
+
+	err = r.db.Get(&settings, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetDigestSettings DB execution error")
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// SaveDigestSettings creates or replaces the settings of the user, an empty
+// time zone is stored as NULL
+func (r *DigestRepository) SaveDigestSettings(settings *models.DigestSettings) error {
+	var timezone *string
+	if settings.Timezone != "" {
+		timezone = &settings.Timezone
+	}
+
+	query, args, err := r.sq.Insert("digest_settings").
+		Columns("user_id", "enabled", "send_time", "timezone", "updated_at").
+		Values(settings.UserID, settings.Enabled, settings.SendTime, timezone, time.Now()).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET enabled = EXCLUDED.enabled, send_time = EXCLUDED.send_time, " +
+			"timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", settings.UserID).
+			Err(err).
+			Msg("Failed to build SaveDigestSettings query")
+		return err
+	}
+
+	if _, err := r.db.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("SaveDigestSettings DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueDigests marks up to limit users whose send time passed today in their
+// time zone as sent and returns them. Due rows are locked with SKIP LOCKED and
+// marked in the same statement, so every digest goes out once a day. The
+// returned time zone is the one the send time was checked in
+func (r *DigestRepository) ClaimDueDigests(now time.Time, limit int) ([]models.DueDigest, error) {
+	var due []models.DueDigest
+
+	pending := squirrel.Select("d.user_id", digestZone+" AS timezone").
+		Column(squirrel.Alias(squirrel.Expr(digestLocalTime+"::date", now), "local_date")).
+		From("digest_settings d").
+		Join("users du ON du.id = d.user_id").
+		LeftJoin("pg_timezone_names dz ON dz.name = COALESCE(d.timezone, du.timezone)").
+		Where(squirrel.And{
+			squirrel.Eq{"d.enabled": true},
+			squirrel.Expr("to_char("+digestLocalTime+", 'HH24:MI') >= d.send_time", now),
+			squirrel.Expr("(d.last_sent_on IS NULL OR d.last_sent_on < "+digestLocalTime+"::date)", now),
+		}).
+		OrderBy("d.user_id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE OF d SKIP LOCKED")
+
+	query, args, err := r.sq.Update("digest_settings s").
+		Set("last_sent_on", squirrel.Expr("p.local_date")).
+		FromSelect(pending, "p").
+		Where("p.user_id = s.user_id").
+		Suffix("RETURNING s.user_id, p.timezone").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build ClaimDueDigests query")
+		return due, err
+	}
+
+	err = r.db.Select(&due, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ClaimDueDigests DB execution error")
+		return due, err
+	}
+
+	return due, nil
+}
+
+// GetOverdueTasks returns the open tasks of the user due before the time
+func (r *DigestRepository) GetOverdueTasks(userID int, before time.Time) ([]models.Task, error) {
+	return r.selectTasks(userID, "GetOverdueTasks", "due_at", squirrel.And{
+		squirrel.NotEq{"status": models.TaskStatusDone},
+		squirrel.Lt{"due_at": before},
+	})
+}
+
+// GetTasksDue returns the open tasks of the user due within [from, to)
+func (r *DigestRepository) GetTasksDue(userID int, from, to time.Time) ([]models.Task, error) {
+	return r.selectTasks(userID, "GetTasksDue", "due_at", squirrel.And{
+		squirrel.NotEq{"status": models.TaskStatusDone},
+		squirrel.GtOrEq{"due_at": from},
+		squirrel.Lt{"due_at": to},
+	})
+}
+
+// GetCompletedTasks returns the tasks of the user completed within [from, to)
+func (r *DigestRepository) GetCompletedTasks(userID int, from, to time.Time) ([]models.Task, error) {
+	return r.selectTasks(userID, "GetCompletedTasks", "completed_at", squirrel.And{
+		squirrel.Eq{"status": models.TaskStatusDone},
+		squirrel.GtOrEq{"completed_at": from},
+		squirrel.Lt{"completed_at": to},
+	})
+}
+
+// GetAssignedTasks returns the open tasks assigned to the user within [from, to)
+func (r *DigestRepository) GetAssignedTasks(userID int, from, to time.Time) ([]models.Task, error) {
+	return r.selectTasks(userID, "GetAssignedTasks", "assigned_at", squirrel.And{
+		squirrel.Eq{"assignee_id": userID},
+		squirrel.NotEq{"status": models.TaskStatusDone},
+		squirrel.GtOrEq{"assigned_at": from},
+		squirrel.Lt{"assigned_at": to},
+	})
+}
+
+func (r *DigestRepository) selectTasks(userID int, name, orderBy string, where squirrel.Sqlizer) ([]models.Task, error) {
+	var tasks []models.Task
+
+	query, args, err := r.sq.Select(taskColumns...).
+		From("tasks").
+		Where(squirrel.And{tasksResponsibleOf(userID), where}).
+		OrderBy(orderBy, "id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msgf("Failed to build %s query", name)
+		return tasks, err
+	}
+
+	err = r.db.Select(&tasks, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return tasks, err
+	}
+
+	return tasks, nil
+}
+
+// tasksResponsibleOf limits a statement to the visible tasks the user is
+// assigned to and its personal tasks nobody is assigned to
+func tasksResponsibleOf(userID int) squirrel.Sqlizer {
+	return squirrel.And{
+		tasksVisibleTo(userID),
+		squirrel.Or{
+			squirrel.Eq{"assignee_id": userID},
+			squirrel.Eq{"assignee_id": nil, "workspace_id": nil, "user_id": userID},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewDigestMock(t *testing.T) (sqlmock.Sqlmock, *repository.DigestRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewDigestRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestGetDigestSettingsNotFound(t *testing.T) {
+	mock, repo := NewDigestMock(t)
+
+	mock.ExpectQuery("SELECT user_id, enabled, send_time, COALESCE\\(timezone, ''\\) AS timezone, last_sent_on FROM digest_settings WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	settings, err := repo.GetDigestSettings(1)
+	assert.NoError(t, err)
+	assert.Nil(t, settings)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveDigestSettingsProfileTimezone(t *testing.T) {
+	mock, repo := NewDigestMock(t)
+
+	mock.ExpectExec("INSERT INTO digest_settings (.+) ON CONFLICT \\(user_id\\) DO UPDATE").
+		WithArgs(1, true, "07:30", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SaveDigestSettings(&models.DigestSettings{UserID: 1, Enabled: true, SendTime: "07:30"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueDigests(t *testing.T) {
+	mock, repo := NewDigestMock(t)
+
+	now := time.Now()
+	mock.ExpectQuery("UPDATE digest_settings s SET last_sent_on = p.local_date FROM \\(SELECT d.user_id, COALESCE\\(dz.name, 'UTC'\\) AS timezone, "+
+		"\\(\\(\\$1::timestamptz AT TIME ZONE COALESCE\\(dz.name, 'UTC'\\)\\)::date\\) AS local_date FROM digest_settings d JOIN users du ON du.id = d.user_id "+
+		"LEFT JOIN pg_timezone_names dz ON dz.name = COALESCE\\(d.timezone, du.timezone\\) WHERE \\(d.enabled = \\$2 (.+)\\) "+
+		"ORDER BY d.user_id LIMIT 50 FOR UPDATE OF d SKIP LOCKED\\) AS p WHERE p.user_id = s.user_id RETURNING s.user_id, p.timezone").
+		WithArgs(now, true, now, now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "timezone"}).AddRow(1, "Europe/Berlin"))
+
+	due, err := repo.ClaimDueDigests(now, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.DueDigest{{UserID: 1, Timezone: "Europe/Berlin"}}, due)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCompletedTasks(t *testing.T) {
+	mock, repo := NewDigestMock(t)
+
+	from := time.Now().Add(-24 * time.Hour)
+	to := time.Now()
+	mock.ExpectQuery("SELECT (.+) FROM tasks WHERE \\(\\(\\(user_id = \\$1 AND workspace_id IS NULL OR workspace_id IN (.+)\\) AND \\(assignee_id = \\$3 OR (.+)\\)\\) "+
+		"AND \\(status = \\$5 AND completed_at >= \\$6 AND completed_at < \\$7\\)\\) ORDER BY completed_at, id").
+		WithArgs(1, 1, 1, 1, models.TaskStatusDone, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status"}).AddRow(3, "Release", models.TaskStatusDone))
+
+	tasks, err := repo.GetCompletedTasks(1, from, to)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
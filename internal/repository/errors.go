@@ -5,3 +5,5 @@ import "errors"
 var ErrNoRowsUpdated error = errors.New("no rows affected")
 var ErrUniqueUser error = errors.New("username already exists")
 var ErrUserNotFound = errors.New("username not found")
+var ErrUniqueEmail = errors.New("email already in use")
+var ErrTokenNotFound = errors.New("token not found or expired")
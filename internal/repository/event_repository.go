@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskEventsChannel is the Postgres NOTIFY channel carrying ids of new task events
+const TaskEventsChannel = "task_events"
+
+var taskEventColumns = []string{
+	"id", "type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "created_at",
+}
+
+type EventRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewEventRepository(db *sqlx.DB) *EventRepository {
+	return &EventRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+// CreateEvent stores the event and notifies every app instance about it. The
+// notification is delivered when the transaction commits. An event recorded
+// from the same outbox message before is skipped and keeps a zero id
+func (r *EventRepository) CreateEvent(event *models.TaskEvent) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("CreateEvent begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Insert("task_events").
+		Columns("type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "outbox_id", "created_at").
+		Values(event.Type, event.TaskID, event.UserID, event.WorkspaceID, event.ActorID, event.Payload, event.OutboxID, time.Now()).
+		Suffix("ON CONFLICT (outbox_id) DO NOTHING RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("type", event.Type).
+			Int("task_id", event.TaskID).
+			Err(err).
+			Msg("Failed to build CreateEvent query")
+		return err
+	}
+
+	err = tx.QueryRow(query, args...).Scan(&event.ID, &event.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateEvent DB execution error")
+		return err
+	}
+
+	if _, err = tx.Exec("SELECT pg_notify($1, $2)", TaskEventsChannel, strconv.FormatInt(event.ID, 10)); err != nil {
+		r.log.Error().
+			Int64("event_id", event.ID).
+			Err(err).
+			Msg("CreateEvent notify error")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *EventRepository) GetEvent(id int64) (*models.TaskEvent, error) {
+	var event models.TaskEvent
+
+	query, args, err := r.sq.Select(taskEventColumns...).
+		From("task_events").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int64("event_id", id).
+			Err(err).
+			Msg("Failed to build GetEvent query")
+		return nil, err
+	}
+
+	err = r.db.Get(&event, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetEvent DB execution error")
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// GetEventsAfter returns retained events after lastID about tasks the user can
+// see now, oldest first
+func (r *EventRepository) GetEventsAfter(lastID int64, userID int, limit int) ([]models.TaskEvent, error) {
+	var events []models.TaskEvent
+
+	query, args, err := r.sq.Select(taskEventColumns...).
+		From("task_events").
+		Where(squirrel.And{
+			squirrel.Gt{"id": lastID},
+			tasksVisibleTo(userID),
+		}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int64("last_id", lastID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetEventsAfter query")
+		return events, err
+	}
+
+	err = r.db.Select(&events, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetEventsAfter DB execution error")
+		return events, err
+	}
+
+	return events, nil
+}
+
+// GetOldestEventID returns the id of the oldest retained event, 0 if the log is empty
+func (r *EventRepository) GetOldestEventID() (int64, error) {
+	var id int64
+
+	query, args, err := r.sq.Select("COALESCE(MIN(id), 0)").
+		From("task_events").
+		ToSql()
+	if err != nil {
+		r.log.Error().Err(err).Msg("Failed to build GetOldestEventID query")
+		return 0, err
+	}
+
+	err = r.db.Get(&id, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Err(err).
+			Msg("GetOldestEventID DB execution error")
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *EventRepository) DeleteEventsBefore(before time.Time) (int64, error) {
+	query, args, err := r.sq.Delete("task_events").
+		Where(squirrel.Lt{"created_at": before}).
+		ToSql()
+	if err != nil {
+		r.log.Error().Err(err).Msg("Failed to build DeleteEventsBefore query")
+		return 0, err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteEventsBefore DB execution error")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Listen holds a dedicated connection subscribed to TaskEventsChannel and calls
+// handle with the id of every new event. It returns when ctx is done or the
+// connection fails, callers reconnect and catch up through GetEventsAfter
+func (r *EventRepository) Listen(ctx context.Context, handle func(eventID int64)) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// the connection is reported as bad on the way out, so the pool drops it
+	// instead of handing a LISTENing session to other queries
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+TaskEventsChannel); err != nil {
+			return fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+		}
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+			}
+
+			id, err := strconv.ParseInt(notification.Payload, 10, 64)
+			if err != nil {
+				r.log.Error().Str("payload", notification.Payload).Msg("Malformed task event notification")
+				continue
+			}
+
+			handle(id)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewEventMock(t *testing.T) (sqlmock.Sqlmock, *repository.EventRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewEventRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateEvent(t *testing.T) {
+	mock, repo := NewEventMock(t)
+
+	actorID := 1
+	event := &models.TaskEvent{Type: models.TaskEventCreated, TaskID: 7, UserID: 1, ActorID: &actorID, Payload: []byte(`{"id":7}`)}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_events").
+		WithArgs(models.TaskEventCreated, 7, 1, nil, &actorID, event.Payload, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(41, time.Now()))
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs(repository.TaskEventsChannel, "41").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.CreateEvent(event)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(41), event.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateEventSkipsDuplicate(t *testing.T) {
+	mock, repo := NewEventMock(t)
+
+	outboxID := int64(42)
+	event := &models.TaskEvent{Type: models.TaskEventDeleted, TaskID: 7, UserID: 1, OutboxID: &outboxID}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_events (.+) ON CONFLICT \\(outbox_id\\) DO NOTHING").
+		WithArgs(models.TaskEventDeleted, 7, 1, nil, nil, sqlmock.AnyArg(), &outboxID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+	mock.ExpectRollback()
+
+	err := repo.CreateEvent(event)
+	assert.NoError(t, err)
+	assert.Zero(t, event.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetEventsAfter(t *testing.T) {
+	mock, repo := NewEventMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "created_at"}).
+		AddRow(42, models.TaskEventUpdated, 7, 1, 10, 2, []byte(`{"id":7}`), time.Now()).
+		AddRow(43, models.TaskEventDeleted, 8, 3, nil, 3, nil, time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM task_events WHERE \\(id > (.+) AND \\(user_id = (.+) AND workspace_id IS NULL OR workspace_id IN \\(SELECT workspace_id FROM workspace_members WHERE user_id = (.+)\\)\\)\\) ORDER BY id LIMIT 501").
+		WithArgs(int64(41), 3, 3).
+		WillReturnRows(rows)
+
+	events, err := repo.GetEventsAfter(41, 3, 501)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.JSONEq(t, `{"id":7}`, string(events[0].Payload))
+	assert.Empty(t, events[1].Payload)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOldestEventID(t *testing.T) {
+	mock, repo := NewEventMock(t)
+
+	mock.ExpectQuery("SELECT COALESCE\\(MIN\\(id\\), 0\\) FROM task_events").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+
+	id, err := repo.GetOldestEventID()
+	assert.NoError(t, err)
+	assert.Zero(t, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
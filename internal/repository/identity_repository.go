@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdentityRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewIdentityRepository(db *sqlx.DB) *IdentityRepository {
+	return &IdentityRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *IdentityRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+
+	query, args, err := r.sq.Select("id", "user_id", "provider", "subject", "COALESCE(email, '') AS email", "created_at", "last_login_at").
+		From("user_identities").
+		Where(squirrel.Eq{"provider": provider, "subject": subject}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("provider", provider).
+			Err(err).
+			Msg("Failed to build GetIdentity query")
+		return nil, err
+	}
+
+	err = r.db.Get(&identity, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetIdentity DB execution error")
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *IdentityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.createIdentity(r.db, identity)
+}
+
+// CreateUserWithIdentity provisions a user together with its first external identity
+func (r *IdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("CreateUserWithIdentity begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Insert("users").
+		Columns("username", "password_hash", "created_at", "email", "email_verified_at").
+		Values(user.Username, user.PasswordHash, time.Now(), squirrel.Expr("NULLIF(?, '')", user.Email), user.EmailVerifiedAt).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Object("user", user).
+			Err(err).
+			Msg("Failed to build CreateUserWithIdentity query")
+		return err
+	}
+
+	if err = tx.QueryRow(query, args...).Scan(&user.ID); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateUserWithIdentity DB execution error")
+		return err
+	}
+
+	identity.UserID = user.ID
+	if err := r.createIdentity(tx, identity); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TouchIdentity records a login and refreshes the email reported by the provider
+func (r *IdentityRepository) TouchIdentity(id int, email string, now time.Time) error {
+	query, args, err := r.sq.Update("user_identities").
+		Set("email", squirrel.Expr("NULLIF(?, '')", email)).
+		Set("last_login_at", now).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("identity_id", id).
+			Err(err).
+			Msg("Failed to build TouchIdentity query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("TouchIdentity DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *IdentityRepository) createIdentity(q sqlx.Queryer, identity *models.UserIdentity) error {
+	query, args, err := r.sq.Insert("user_identities").
+		Columns("user_id", "provider", "subject", "email", "created_at", "last_login_at").
+		Values(identity.UserID, identity.Provider, identity.Subject, squirrel.Expr("NULLIF(?, '')", identity.Email), time.Now(), identity.LastLoginAt).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", identity.UserID).
+			Str("provider", identity.Provider).
+			Err(err).
+			Msg("Failed to build CreateIdentity query")
+		return err
+	}
+
+	if err = q.QueryRowx(query, args...).Scan(&identity.ID, &identity.CreatedAt); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateIdentity DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *IdentityRepository) GetIdentitiesByUserID(userID int) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+
+	query, args, err := r.sq.Select("id", "user_id", "provider", "subject", "COALESCE(email, '') AS email", "created_at", "last_login_at").
+		From("user_identities").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetIdentitiesByUserID query")
+		return identities, err
+	}
+
+	err = r.db.Select(&identities, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetIdentitiesByUserID DB execution error")
+		return identities, err
+	}
+
+	return identities, nil
+}
+
+// DeleteIdentity unlinks an external identity, ErrNoRowsUpdated if it isn't linked
+func (r *IdentityRepository) DeleteIdentity(provider, subject string) error {
+	query, args, err := r.sq.Delete("user_identities").
+		Where(squirrel.Eq{"provider": provider, "subject": subject}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("provider", provider).
+			Err(err).
+			Msg("Failed to build DeleteIdentity query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteIdentity DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewIdentityMock(t *testing.T) (sqlmock.Sqlmock, *repository.IdentityRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewIdentityRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestGetIdentity(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "provider", "subject", "email", "created_at", "last_login_at"}).
+		AddRow(1, 7, "company", "ext-1", "alice@example.com", time.Now(), nil)
+	mock.ExpectQuery("SELECT (.+) FROM user_identities").
+		WithArgs("company", "ext-1").
+		WillReturnRows(rows)
+
+	identity, err := repo.GetIdentity("company", "ext-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, identity.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentityNotFound(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM user_identities").WillReturnError(sql.ErrNoRows)
+
+	identity, err := repo.GetIdentity("company", "ext-1")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUserWithIdentity(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	verifiedAt := time.Now()
+	user := &models.User{Username: "alice", Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+	identity := &models.UserIdentity{Provider: "company", Subject: "ext-1", Email: "alice@example.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("alice", "", sqlmock.AnyArg(), "alice@example.com", &verifiedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery("INSERT INTO user_identities").
+		WithArgs(11, "company", "ext-1", "alice@example.com", sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+	mock.ExpectCommit()
+
+	err := repo.CreateUserWithIdentity(user, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 11, user.ID)
+	assert.Equal(t, 11, identity.UserID)
+	assert.Equal(t, 3, identity.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUserWithIdentityRollback(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery("INSERT INTO user_identities").
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	err := repo.CreateUserWithIdentity(&models.User{Username: "alice"}, &models.UserIdentity{Provider: "company", Subject: "ext-1"})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTouchIdentity(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	now := time.Now()
+	mock.ExpectExec("UPDATE user_identities SET email = NULLIF").
+		WithArgs("alice@example.com", now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.TouchIdentity(3, "alice@example.com", now)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentitiesByUserID(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "provider", "subject", "email", "created_at", "last_login_at"}).
+		AddRow(1, 7, "company", "ext-1", "", time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM user_identities WHERE user_id = (.+) ORDER BY id").
+		WithArgs(7).
+		WillReturnRows(rows)
+
+	identities, err := repo.GetIdentitiesByUserID(7)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteIdentity(t *testing.T) {
+	mock, repo := NewIdentityMock(t)
+
+	mock.ExpectExec("DELETE FROM user_identities").
+		WithArgs("chat", "T1:U1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_identities").
+		WithArgs("chat", "T1:U2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.DeleteIdentity("chat", "T1:U1"))
+	assert.ErrorIs(t, repo.DeleteIdentity("chat", "T1:U2"), repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
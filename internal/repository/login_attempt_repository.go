@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginAttemptRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *LoginAttemptRepository) GetAttempt(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+
+	query, args, err := r.sq.Select("key", "failures", "last_failure_at", "blocked_until").
+		From("login_attempts").
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("key", key).
+			Err(err).
+			Msg("Failed to build GetAttempt query")
+		return nil, err
+	}
+
+	err = r.db.Get(&attempt, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetAttempt DB execution error")
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// RegisterFailure atomically increments the failure counter and returns it.
+// Counters whose last failure is older than resetBefore start over from 1
+func (r *LoginAttemptRepository) RegisterFailure(key string, now, resetBefore time.Time) (int, error) {
+	query, args, err := r.sq.Insert("login_attempts").
+		Columns("key", "failures", "last_failure_at").
+		Values(key, 1, now).
+		Suffix(`ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+			RETURNING failures`, resetBefore).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("key", key).
+			Err(err).
+			Msg("Failed to build RegisterFailure query")
+		return 0, err
+	}
+
+	var failures int
+	err = r.db.QueryRow(query, args...).Scan(&failures)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RegisterFailure DB execution error")
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (r *LoginAttemptRepository) Block(key string, until time.Time) error {
+	query, args, err := r.sq.Update("login_attempts").
+		Set("blocked_until", until).
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("key", key).
+			Err(err).
+			Msg("Failed to build Block query")
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("Block DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) DeleteAttempt(key string) error {
+	query, args, err := r.sq.Delete("login_attempts").
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("key", key).
+			Err(err).
+			Msg("Failed to build DeleteAttempt query")
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteAttempt DB execution error")
+		return err
+	}
+
+	return nil
+}
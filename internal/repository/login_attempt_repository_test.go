@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewLoginAttemptMock(t *testing.T) (sqlmock.Sqlmock, *repository.LoginAttemptRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewLoginAttemptRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestGetAttempt(t *testing.T) {
+	mock, repo := NewLoginAttemptMock(t)
+
+	until := time.Now().Add(time.Minute)
+	rows := sqlmock.NewRows([]string{"key", "failures", "last_failure_at", "blocked_until"}).
+		AddRow("user:alice", 3, time.Now(), until)
+	mock.ExpectQuery("SELECT (.+) FROM login_attempts").WithArgs("user:alice").WillReturnRows(rows)
+
+	attempt, err := repo.GetAttempt("user:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempt.Failures)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterFailure(t *testing.T) {
+	mock, repo := NewLoginAttemptMock(t)
+
+	now := time.Now()
+	resetBefore := now.Add(-time.Hour)
+	mock.ExpectQuery(`INSERT INTO login_attempts (.+) ON CONFLICT \(key\) DO UPDATE SET`).
+		WithArgs("user:alice", 1, now, resetBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(4))
+
+	failures, err := repo.RegisterFailure("user:alice", now, resetBefore)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, failures)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlockAndDeleteAttempt(t *testing.T) {
+	mock, repo := NewLoginAttemptMock(t)
+
+	until := time.Now().Add(time.Minute)
+	mock.ExpectExec("UPDATE login_attempts SET blocked_until").
+		WithArgs(until, "user:alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM login_attempts").
+		WithArgs("user:alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Block("user:alice", until))
+	assert.NoError(t, repo.DeleteAttempt("user:alice"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAuditRecord(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewAuditRepository(sqlx.NewDb(mockDB, "sqlmock"))
+	record := &models.AuditRecord{Event: models.AuditEventLoginLockout, Subject: "user:alice", Details: "failures=5"}
+
+	mock.ExpectQuery("INSERT INTO audit_log").
+		WithArgs(nil, record.Event, record.Subject, record.Details, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+	err = repo.CreateRecord(record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, record.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
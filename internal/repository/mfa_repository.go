@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type MFARepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *MFARepository) GetTOTP(userID int) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+
+	query, args, err := r.sq.Select("user_id", "secret", "confirmed_at", "last_used_step", "created_at").
+		From("user_totp").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetTOTP query")
+		return nil, err
+	}
+
+	err = r.db.Get(&totp, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetTOTP DB execution error")
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTP stores a new unconfirmed secret, replacing any pending enrollment
+func (r *MFARepository) SaveTOTP(totp *models.UserTOTP) error {
+	query, args, err := r.sq.Insert("user_totp").
+		Columns("user_id", "secret", "created_at").
+		Values(totp.UserID, totp.Secret, time.Now()).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, created_at = EXCLUDED.created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", totp.UserID).
+			Err(err).
+			Msg("Failed to build SaveTOTP query")
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Err(err).
+			Msg("SaveTOTP DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmTOTP enables the secret and replaces recovery codes in one transaction
+func (r *MFARepository) ConfirmTOTP(userID int, step int64, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("ConfirmTOTP begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Update("user_totp").
+		Set("confirmed_at", time.Now()).
+		Set("last_used_step", step).
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build ConfirmTOTP query")
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ConfirmTOTP DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	query, args, err = r.sq.Delete("user_recovery_codes").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build delete recovery codes query")
+		return err
+	}
+
+	if _, err = tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("Delete recovery codes DB execution error")
+		return err
+	}
+
+	insert := r.sq.Insert("user_recovery_codes").Columns("user_id", "code_hash")
+	for _, hash := range codeHashes {
+		insert = insert.Values(userID, hash)
+	}
+
+	query, args, err = insert.ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build insert recovery codes query")
+		return err
+	}
+
+	if _, err = tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Err(err).
+			Msg("Insert recovery codes DB execution error")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records the accepted time step so the same code can't be replayed
+func (r *MFARepository) UseTOTPStep(userID int, step int64) error {
+	query, args, err := r.sq.Update("user_totp").
+		Set("last_used_step", step).
+		Where(squirrel.And{
+			squirrel.Eq{"user_id": userID},
+			squirrel.Lt{"last_used_step": step},
+		}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build UseTOTPStep query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("UseTOTPStep DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks a matching unused code as used
+func (r *MFARepository) UseRecoveryCode(userID int, codeHash string) error {
+	query, args, err := r.sq.Update("user_recovery_codes").
+		Set("used_at", time.Now()).
+		Where(squirrel.Eq{
+			"user_id":   userID,
+			"code_hash": codeHash,
+			"used_at":   nil,
+		}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build UseRecoveryCode query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Err(err).
+			Msg("UseRecoveryCode DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
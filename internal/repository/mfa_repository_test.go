@@ -0,0 +1,118 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewMFAMock(t *testing.T) (sqlmock.Sqlmock, *repository.MFARepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewMFARepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestGetTOTP(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		mock, repo := NewMFAMock(t)
+
+		rows := sqlmock.NewRows([]string{"user_id", "secret", "confirmed_at", "last_used_step", "created_at"}).
+			AddRow(1, "SECRET", time.Now(), 10, time.Now())
+		mock.ExpectQuery("SELECT (.+) FROM user_totp").WithArgs(1).WillReturnRows(rows)
+
+		totp, err := repo.GetTOTP(1)
+		assert.NoError(t, err)
+		assert.True(t, totp.Confirmed())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock, repo := NewMFAMock(t)
+
+		mock.ExpectQuery("SELECT (.+) FROM user_totp").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+		totp, err := repo.GetTOTP(1)
+		assert.NoError(t, err)
+		assert.Nil(t, totp)
+		assert.False(t, totp.Confirmed())
+	})
+}
+
+func TestSaveTOTP(t *testing.T) {
+	mock, repo := NewMFAMock(t)
+
+	mock.ExpectExec("INSERT INTO user_totp (.+) ON CONFLICT").
+		WithArgs(1, "SECRET", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SaveTOTP(&models.UserTOTP{UserID: 1, Secret: "SECRET"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	t.Run("Replaces recovery codes in transaction", func(t *testing.T) {
+		mock, repo := NewMFAMock(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE user_totp").
+			WithArgs(sqlmock.AnyArg(), int64(42), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM user_recovery_codes").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("INSERT INTO user_recovery_codes").
+			WithArgs(1, "hash1", 1, "hash2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.ConfirmTOTP(1, 42, []string{"hash1", "hash2"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No pending enrollment", func(t *testing.T) {
+		mock, repo := NewMFAMock(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE user_totp").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.ConfirmTOTP(1, 42, []string{"hash1"})
+		assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	mock, repo := NewMFAMock(t)
+
+	mock.ExpectExec("UPDATE user_totp SET last_used_step = (.+) WHERE (.+) last_used_step <").
+		WithArgs(int64(43), 1, int64(43)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.UseTOTPStep(1, 43)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	mock, repo := NewMFAMock(t)
+
+	mock.ExpectExec("UPDATE user_recovery_codes SET used_at").
+		WithArgs(sqlmock.AnyArg(), "hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UseRecoveryCode(1, "hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
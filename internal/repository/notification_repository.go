@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var notificationColumns = []string{
+	"id", "user_id", "type", "task_id", "actor_id", "comment_id", "message", "created_at", "read_at",
+}
+
+type NotificationRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *NotificationRepository) CreateNotification(notification *models.Notification) error {
+	return insertNotification(r.db, r.sq, r.log, notification)
+}
+
+// GetNotifications returns notifications of the user newest first
+func (r *NotificationRepository) GetNotifications(userID int, filter models.NotificationFilter) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	stmt := r.sq.Select(notificationColumns...).
+		From("notifications").
+		Where(squirrel.Eq{"user_id": userID})
+	if filter.UnreadOnly {
+		stmt = stmt.Where(squirrel.Eq{"read_at": nil})
+	}
+
+	query, args, err := stmt.
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset)).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetNotifications query")
+		return notifications, err
+	}
+
+	err = r.db.Select(&notifications, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetNotifications DB execution error")
+		return notifications, err
+	}
+
+	return notifications, nil
+}
+
+// GetNotificationsByUserID returns every notification of the user, oldest first
+func (r *NotificationRepository) GetNotificationsByUserID(userID int) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	query, args, err := r.sq.Select(notificationColumns...).
+		From("notifications").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetNotificationsByUserID query")
+		return notifications, err
+	}
+
+	err = r.db.Select(&notifications, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetNotificationsByUserID DB execution error")
+		return notifications, err
+	}
+
+	return notifications, nil
+}
+
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+
+	query, args, err := r.sq.Select("COUNT(*)").
+		From("notifications").
+		Where(squirrel.Eq{"user_id": userID, "read_at": nil}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build CountUnread query")
+		return 0, err
+	}
+
+	err = r.db.Get(&count, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CountUnread DB execution error")
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkRead marks a notification of the user as read, an already read
+// notification keeps its original read_at
+func (r *NotificationRepository) MarkRead(id, userID int, readAt time.Time) error {
+	query, args, err := r.sq.Update("notifications").
+		Set("read_at", squirrel.Expr("COALESCE(read_at, ?)", readAt)).
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("notification_id", id).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build MarkRead query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("MarkRead DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns
+// how many were changed
+func (r *NotificationRepository) MarkAllRead(userID int, readAt time.Time) (int64, error) {
+	query, args, err := r.sq.Update("notifications").
+		Set("read_at", readAt).
+		Where(squirrel.Eq{"user_id": userID, "read_at": nil}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build MarkAllRead query")
+		return 0, err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("MarkAllRead DB execution error")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetPreferences returns the stored preferences of the user, types without a
+// row are enabled
+func (r *NotificationRepository) GetPreferences(userID int) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+
+	query, args, err := r.sq.Select("type", "enabled").
+		From("notification_preferences").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetPreferences query")
+		return preferences, err
+	}
+
+	err = r.db.Select(&preferences, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetPreferences DB execution error")
+		return preferences, err
+	}
+
+	return preferences, nil
+}
+
+// SetPreferences stores the given preferences of the user in one transaction
+func (r *NotificationRepository) SetPreferences(userID int, preferences []models.NotificationPreference) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("SetPreferences begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, preference := range preferences {
+		query, args, err := r.sq.Insert("notification_preferences").
+			Columns("user_id", "type", "enabled", "updated_at").
+			Values(userID, preference.Type, preference.Enabled, time.Now()).
+			Suffix("ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at").
+			ToSql()
+		if err != nil {
+			r.log.Error().
+				Int("user_id", userID).
+				Str("type", preference.Type).
+				Err(err).
+				Msg("Failed to build SetPreferences query")
+			return err
+		}
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			r.log.Error().
+				Str("query", query).
+				Interface("args", args).
+				Err(err).
+				Msg("SetPreferences DB execution error")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertNotification is shared by repositories that record a notification
+// in the same transaction as the change that caused it. Nothing is stored when
+// the recipient switched the type off or already got a notification for the
+// same outbox message, the notification keeps a zero ID then
+func insertNotification(q sqlx.Queryer, sq squirrel.StatementBuilderType, log zerolog.Logger, notification *models.Notification) error {
+	disabled := squirrel.Select("1").
+		From("notification_preferences").
+		Where(squirrel.Eq{"user_id": notification.UserID, "type": notification.Type, "enabled": false})
+
+	query, args, err := sq.Insert("notifications").
+		Columns("user_id", "type", "task_id", "actor_id", "comment_id", "message", "outbox_id", "created_at").
+		Select(sq.Select().
+			Column("?::integer", notification.UserID).
+			Column("?", notification.Type).
+			Column("?::integer", notification.TaskID).
+			Column("?::integer", notification.ActorID).
+			Column("?::integer", notification.CommentID).
+			Column("?", notification.Message).
+			Column("?::bigint", notification.OutboxID).
+			Column("?::timestamp", time.Now()).
+			Where(squirrel.Expr("NOT EXISTS (?)", disabled))).
+		Suffix("ON CONFLICT (user_id, outbox_id) DO NOTHING RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		log.Error().
+			Int("user_id", notification.UserID).
+			Str("type", notification.Type).
+			Err(err).
+			Msg("Failed to build CreateNotification query")
+		return err
+	}
+
+	err = q.QueryRowx(query, args...).Scan(&notification.ID, &notification.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateNotification DB execution error")
+		return err
+	}
+
+	return nil
+}
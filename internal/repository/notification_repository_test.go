@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewNotificationMock(t *testing.T) (sqlmock.Sqlmock, *repository.NotificationRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewNotificationRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateNotificationDisabledType(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	mock.ExpectQuery("INSERT INTO notifications (.+) SELECT (.+) WHERE NOT EXISTS \\(SELECT 1 FROM notification_preferences").
+		WithArgs(3, models.NotificationTaskDueSoon, nil, nil, nil, "Release", nil, sqlmock.AnyArg(),
+			false, models.NotificationTaskDueSoon, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	notification := &models.Notification{UserID: 3, Type: models.NotificationTaskDueSoon, Message: "Release"}
+	err := repo.CreateNotification(notification)
+	assert.NoError(t, err)
+	assert.Zero(t, notification.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateNotificationSameOutboxMessage(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+	taskID := 5
+	outboxID := int64(42)
+
+	mock.ExpectQuery("INSERT INTO notifications (.+) ON CONFLICT \\(user_id, outbox_id\\) DO NOTHING").
+		WithArgs(3, models.NotificationTaskStatusChanged, &taskID, nil, nil, "Release: todo → done", &outboxID, sqlmock.AnyArg(),
+			false, models.NotificationTaskStatusChanged, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	notification := &models.Notification{
+		UserID:   3,
+		Type:     models.NotificationTaskStatusChanged,
+		TaskID:   &taskID,
+		Message:  "Release: todo → done",
+		OutboxID: &outboxID,
+	}
+	err := repo.CreateNotification(notification)
+	assert.NoError(t, err)
+	assert.Zero(t, notification.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotificationsUnread(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "type", "task_id", "actor_id", "comment_id", "message", "created_at", "read_at"}).
+		AddRow(9, 3, models.NotificationTaskAssigned, 42, 1, nil, "Release", time.Now(), nil)
+	mock.ExpectQuery("SELECT (.+) FROM notifications WHERE user_id = (.+) AND read_at IS NULL ORDER BY id DESC LIMIT 21 OFFSET 20").
+		WithArgs(3).
+		WillReturnRows(rows)
+
+	notifications, err := repo.GetNotifications(3, models.NotificationFilter{UnreadOnly: true, Limit: 21, Offset: 20})
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, 42, *notifications[0].TaskID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotificationsByUserID(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "type", "task_id", "actor_id", "comment_id", "message", "created_at", "read_at"}).
+		AddRow(9, 3, models.NotificationTaskAssigned, 42, 1, nil, "Release", time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM notifications WHERE user_id = (.+) ORDER BY id$").
+		WithArgs(3).
+		WillReturnRows(rows)
+
+	notifications, err := repo.GetNotificationsByUserID(3)
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkReadForeignNotification(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	now := time.Now()
+	mock.ExpectExec("UPDATE notifications SET read_at = COALESCE\\(read_at, (.+)\\) WHERE id = (.+) AND user_id = (.+)").
+		WithArgs(now, 9, 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.MarkRead(9, 4, now)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkAllRead(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	now := time.Now()
+	mock.ExpectExec("UPDATE notifications SET read_at = (.+) WHERE read_at IS NULL AND user_id = (.+)").
+		WithArgs(now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	updated, err := repo.MarkAllRead(3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetPreferences(t *testing.T) {
+	mock, repo := NewNotificationMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO notification_preferences (.+) ON CONFLICT \\(user_id, type\\) DO UPDATE").
+		WithArgs(3, models.NotificationCommentMention, false, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.SetPreferences(3, []models.NotificationPreference{{Type: models.NotificationCommentMention, Enabled: false}})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var outboxColumns = []string{
+	"id", "type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "previous",
+	"attempts", "last_error", "available_at", "created_at", "processed_at",
+}
+
+type OutboxRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewOutboxRepository(db *sqlx.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+// ProcessBatch claims up to limit pending messages available at now and
+// passes them to handle in id order. The rows stay locked until the batch is
+// done, so relays of other instances skip them. A message is processed when
+// handle returns nil, otherwise the attempt fields handle left on the message
+// are stored and it is retried once available_at passes
+func (r *OutboxRepository) ProcessBatch(now time.Time, limit int, handle func(msg *models.OutboxMessage) error) (int, error) {
+	var messages []models.OutboxMessage
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("ProcessBatch begin transaction error")
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Select(outboxColumns...).
+		From("outbox").
+		Where(squirrel.And{
+			squirrel.Eq{"processed_at": nil},
+			squirrel.LtOrEq{"available_at": now},
+		}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build ProcessBatch query")
+		return 0, err
+	}
+
+	if err = tx.Select(&messages, query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ProcessBatch DB execution error")
+		return 0, err
+	}
+
+	for i := range messages {
+		msg := &messages[i]
+
+		stmt := r.sq.Update("outbox").
+			Where(squirrel.Eq{"id": msg.ID})
+		if handleErr := handle(msg); handleErr == nil {
+			stmt = stmt.Set("processed_at", now)
+		} else {
+			stmt = stmt.Set("available_at", msg.AvailableAt)
+		}
+
+		query, args, err = stmt.
+			Set("attempts", msg.Attempts).
+			Set("last_error", msg.LastError).
+			ToSql()
+		if err != nil {
+			r.log.Error().
+				Int64("message_id", msg.ID).
+				Err(err).
+				Msg("Failed to build ProcessBatch update query")
+			return 0, err
+		}
+
+		if _, err = tx.Exec(query, args...); err != nil {
+			r.log.Error().
+				Str("query", query).
+				Interface("args", args).
+				Err(err).
+				Msg("ProcessBatch update DB execution error")
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(messages), nil
+}
+
+// GetConsumers returns the consumers that already handled the message
+func (r *OutboxRepository) GetConsumers(messageID int64) ([]string, error) {
+	var consumers []string
+
+	query, args, err := r.sq.Select("consumer").
+		From("outbox_consumers").
+		Where(squirrel.Eq{"message_id": messageID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int64("message_id", messageID).
+			Err(err).
+			Msg("Failed to build GetConsumers query")
+		return consumers, err
+	}
+
+	err = r.db.Select(&consumers, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetConsumers DB execution error")
+		return consumers, err
+	}
+
+	return consumers, nil
+}
+
+// MarkConsumed records that the consumer handled the message, recording it
+// twice is not an error
+func (r *OutboxRepository) MarkConsumed(consumer string, messageID int64, now time.Time) error {
+	query, args, err := r.sq.Insert("outbox_consumers").
+		Columns("consumer", "message_id", "processed_at").
+		Values(consumer, messageID, now).
+		Suffix("ON CONFLICT (consumer, message_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("consumer", consumer).
+			Int64("message_id", messageID).
+			Err(err).
+			Msg("Failed to build MarkConsumed query")
+		return err
+	}
+
+	if _, err = r.db.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("MarkConsumed DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// DeleteProcessedBefore removes messages processed before the given time
+// together with their consumer records
+func (r *OutboxRepository) DeleteProcessedBefore(before time.Time) (int64, error) {
+	query, args, err := r.sq.Delete("outbox").
+		Where(squirrel.Lt{"processed_at": before}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build DeleteProcessedBefore query")
+		return 0, err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteProcessedBefore DB execution error")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// insertOutbox stores a change of the task made by actorID. It is called with
+// the transaction of the change, so the message exists exactly when the change does
+func insertOutbox(e sqlx.Execer, sq squirrel.StatementBuilderType, log zerolog.Logger, eventType string, actorID int, task, previous *models.Task) error {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	var previousPayload any
+	if previous != nil {
+		if previousPayload, err = json.Marshal(previous); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	query, args, err := sq.Insert("outbox").
+		Columns("type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "previous", "available_at", "created_at").
+		Values(eventType, task.ID, task.UserID, task.WorkspaceID, actorID, payload, previousPayload, now, now).
+		ToSql()
+	if err != nil {
+		log.Error().
+			Str("type", eventType).
+			Int("task_id", task.ID).
+			Err(err).
+			Msg("Failed to build InsertOutbox query")
+		return err
+	}
+
+	if _, err = e.Exec(query, args...); err != nil {
+		log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("InsertOutbox DB execution error")
+		return err
+	}
+
+	return nil
+}
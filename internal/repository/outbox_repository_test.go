@@ -0,0 +1,85 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewOutboxMock(t *testing.T) (sqlmock.Sqlmock, *repository.OutboxRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewOutboxRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestProcessBatch(t *testing.T) {
+	mock, repo := NewOutboxMock(t)
+
+	now := time.Now()
+	retryAt := now.Add(5 * time.Second)
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"id", "type", "task_id", "user_id", "workspace_id", "actor_id", "payload", "previous",
+		"attempts", "last_error", "available_at", "created_at", "processed_at"}).
+		AddRow(1, models.TaskEventCreated, 7, 1, nil, 1, []byte(`{"id":7}`), nil, 0, nil, now, now, nil).
+		AddRow(2, models.TaskEventUpdated, 7, 1, nil, 1, []byte(`{"id":7}`), []byte(`{"id":7}`), 0, nil, now, now, nil)
+	mock.ExpectQuery("SELECT (.+) FROM outbox WHERE \\(processed_at IS NULL AND available_at <= \\$1\\) ORDER BY id LIMIT 100 FOR UPDATE SKIP LOCKED").
+		WithArgs(now).
+		WillReturnRows(rows)
+	mock.ExpectExec("UPDATE outbox SET processed_at = \\$1, attempts = \\$2, last_error = \\$3 WHERE id = \\$4").
+		WithArgs(now, 0, nil, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET available_at = \\$1, attempts = \\$2, last_error = \\$3 WHERE id = \\$4").
+		WithArgs(retryAt, 1, sqlmock.AnyArg(), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := repo.ProcessBatch(now, 100, func(msg *models.OutboxMessage) error {
+		if msg.ID == 1 {
+			return nil
+		}
+		message := "webhooks: db down"
+		msg.Attempts++
+		msg.LastError = &message
+		msg.AvailableAt = retryAt
+		return errors.New(message)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkConsumedIsIdempotent(t *testing.T) {
+	mock, repo := NewOutboxMock(t)
+
+	now := time.Now()
+	mock.ExpectExec("INSERT INTO outbox_consumers (.+) ON CONFLICT \\(consumer, message_id\\) DO NOTHING").
+		WithArgs("webhooks", int64(42), now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.MarkConsumed("webhooks", 42, now)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteProcessedBefore(t *testing.T) {
+	mock, repo := NewOutboxMock(t)
+
+	before := time.Now().Add(-24 * time.Hour)
+	mock.ExpectExec("DELETE FROM outbox WHERE processed_at < \\$1").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := repo.DeleteProcessedBefore(before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
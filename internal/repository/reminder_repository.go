@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var reminderColumns = []string{
+	"r.id", "r.task_id", "r.user_id", "r.remind_at", "r.before_due_minutes", "r.channels",
+	"r.snoozed_until", "r.fired_at", "r.dismissed_at", "r.created_at",
+	reminderFireAt("r", "t") + " AS fire_at",
+	"CASE WHEN r.dismissed_at IS NOT NULL THEN 'dismissed' WHEN r.fired_at IS NOT NULL THEN 'fired' ELSE 'pending' END AS status",
+	"t.title AS task_title", "t.due_at AS task_due_at",
+}
+
+// reminderFireAt is the time a reminder fires: the snooze end once snoozed, the
+// absolute time or the offset before the due date of the task otherwise
+func reminderFireAt(reminder, task string) string {
+	return fmt.Sprintf("COALESCE(%[1]s.snoozed_until, %[1]s.remind_at, %[2]s.due_at - %[1]s.before_due_minutes * INTERVAL '1 minute')", reminder, task)
+}
+
+// utcTime returns t in UTC for a column without a time zone
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+type ReminderRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewReminderRepository(db *sqlx.DB) *ReminderRepository {
+	return &ReminderRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *ReminderRepository) CreateReminder(reminder *models.Reminder) error {
+	query, args, err := r.sq.Insert("reminders").
+		Columns("task_id", "user_id", "remind_at", "before_due_minutes", "channels", "created_at").
+		Values(reminder.TaskID, reminder.UserID, utcTime(reminder.RemindAt), reminder.BeforeDueMinutes, reminder.Channels, reminder.CreatedAt.UTC()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", reminder.TaskID).
+			Int("user_id", reminder.UserID).
+			Err(err).
+			Msg("Failed to build CreateReminder query")
+		return err
+	}
+
+	err = r.db.QueryRowx(query, args...).Scan(&reminder.ID, &reminder.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateReminder DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *ReminderRepository) GetReminder(id, userID int) (*models.Reminder, error) {
+	var reminder models.Reminder
+
+	query, args, err := r.sq.Select(reminderColumns...).
+		From("reminders r").
+		Join("tasks t ON t.id = r.task_id").
+		Where(squirrel.Eq{"r.id": id, "r.user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("reminder_id", id).
+			Err(err).
+			Msg("Failed to build GetReminder query")
+		return nil, err
+	}
+
+	err = r.db.Get(&reminder, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetReminder DB execution error")
+		return nil, err
+	}
+
+	return &reminder, nil
+}
+
+// GetReminders returns the reminders of the user that are not dismissed, the
+// next to fire first. A task id limits them to one task
+func (r *ReminderRepository) GetReminders(userID int, taskID *int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+
+	where := squirrel.Eq{"r.user_id": userID, "r.dismissed_at": nil}
+	if taskID != nil {
+		where["r.task_id"] = *taskID
+	}
+
+	query, args, err := r.sq.Select(reminderColumns...).
+		From("reminders r").
+		Join("tasks t ON t.id = r.task_id").
+		Where(where).
+		OrderBy("fire_at NULLS LAST", "r.id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetReminders query")
+		return reminders, err
+	}
+
+	err = r.db.Select(&reminders, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetReminders DB execution error")
+		return reminders, err
+	}
+
+	return reminders, nil
+}
+
+// GetRemindersByUserID returns every reminder of the user, dismissed ones too,
+// oldest first
+func (r *ReminderRepository) GetRemindersByUserID(userID int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+
+	query, args, err := r.sq.Select(reminderColumns...).
+		From("reminders r").
+		Join("tasks t ON t.id = r.task_id").
+		Where(squirrel.Eq{"r.user_id": userID}).
+		OrderBy("r.id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetRemindersByUserID query")
+		return reminders, err
+	}
+
+	err = r.db.Select(&reminders, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetRemindersByUserID DB execution error")
+		return reminders, err
+	}
+
+	return reminders, nil
+}
+
+// SnoozeReminder moves a reminder that is not dismissed to until, a fired
+// reminder fires again then
+func (r *ReminderRepository) SnoozeReminder(id, userID int, until time.Time) error {
+	query, args, err := r.sq.Update("reminders").
+		Set("snoozed_until", until.UTC()).
+		Set("fired_at", nil).
+		Where(squirrel.Eq{"id": id, "user_id": userID, "dismissed_at": nil}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("reminder_id", id).
+			Err(err).
+			Msg("Failed to build SnoozeReminder query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "SnoozeReminder")
+}
+
+// DismissReminder stops a reminder for good, it is kept but no longer listed
+func (r *ReminderRepository) DismissReminder(id, userID int, now time.Time) error {
+	query, args, err := r.sq.Update("reminders").
+		Set("dismissed_at", now.UTC()).
+		Where(squirrel.Eq{"id": id, "user_id": userID, "dismissed_at": nil}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("reminder_id", id).
+			Err(err).
+			Msg("Failed to build DismissReminder query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "DismissReminder")
+}
+
+func (r *ReminderRepository) DeleteReminder(id, userID int) error {
+	query, args, err := r.sq.Delete("reminders").
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("reminder_id", id).
+			Err(err).
+			Msg("Failed to build DeleteReminder query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "DeleteReminder")
+}
+
+// ClaimDueReminders marks up to limit reminders due at now as fired and returns
+// them. Due rows are locked with SKIP LOCKED and marked in the same statement,
+// so every reminder is claimed by exactly one instance. Reminders on tasks the
+// user can no longer see are left alone
+func (r *ReminderRepository) ClaimDueReminders(now time.Time, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	// the fire times are stored in UTC
+	now = now.UTC()
+
+	due := squirrel.Select("due.id").
+		From("reminders due").
+		Join("tasks dt ON dt.id = due.task_id").
+		Where(squirrel.And{
+			squirrel.Eq{"due.fired_at": nil, "due.dismissed_at": nil},
+			squirrel.Expr(reminderFireAt("due", "dt")+" <= ?", now),
+			squirrel.Or{
+				squirrel.Expr("dt.workspace_id IS NULL AND dt.user_id = due.user_id"),
+				squirrel.Expr("dt.workspace_id IN (SELECT workspace_id FROM workspace_members m WHERE m.user_id = due.user_id)"),
+			},
+		}).
+		OrderBy("due.id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE OF due SKIP LOCKED")
+
+	query, args, err := r.sq.Update("reminders r").
+		Set("fired_at", now).
+		From("tasks t").
+		Where(squirrel.And{
+			squirrel.Expr("t.id = r.task_id"),
+			squirrel.Expr("r.id IN (?)", due),
+		}).
+		Suffix("RETURNING " + strings.Join(reminderColumns, ", ")).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build ClaimDueReminders query")
+		return reminders, err
+	}
+
+	err = r.db.Select(&reminders, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ClaimDueReminders DB execution error")
+		return reminders, err
+	}
+
+	return reminders, nil
+}
+
+func (r *ReminderRepository) execAffecting(query string, args []interface{}, name string) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
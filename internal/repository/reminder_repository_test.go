@@ -0,0 +1,154 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var reminderRowColumns = []string{
+	"id", "task_id", "user_id", "remind_at", "before_due_minutes", "channels", "snoozed_until", "fired_at",
+	"dismissed_at", "created_at", "fire_at", "status", "task_title", "task_due_at",
+}
+
+func NewReminderMock(t *testing.T) (sqlmock.Sqlmock, *repository.ReminderRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewReminderRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateReminder(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	beforeDue := 60
+	reminder := &models.Reminder{TaskID: 42, UserID: 1, BeforeDueMinutes: &beforeDue, Channels: models.StringList{models.ReminderChannelInApp}}
+
+	mock.ExpectQuery("INSERT INTO reminders").
+		WithArgs(42, 1, nil, &beforeDue, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	err := repo.CreateReminder(reminder)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, reminder.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateReminderStoresUTC(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	// the columns have no time zone, 09:00 in New York is stored as 13:00
+	newYork := time.FixedZone("EDT", -4*60*60)
+	remindAt := time.Date(2026, 10, 20, 9, 0, 0, 0, newYork)
+	createdAt := time.Date(2026, 10, 19, 8, 0, 0, 0, newYork)
+	reminder := &models.Reminder{TaskID: 42, UserID: 1, RemindAt: &remindAt, Channels: models.StringList{models.ReminderChannelInApp}, CreatedAt: createdAt}
+
+	mock.ExpectQuery("INSERT INTO reminders").
+		WithArgs(42, 1, time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC), nil, sqlmock.AnyArg(), time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+
+	assert.NoError(t, repo.CreateReminder(reminder))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRemindersOfTask(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows(reminderRowColumns).
+		AddRow(7, 42, 1, nil, 60, []byte(`["in_app","email"]`), nil, nil, nil, now, now.Add(time.Hour), models.ReminderPending, "Release", now.Add(2*time.Hour))
+	mock.ExpectQuery("SELECT (.+) FROM reminders r JOIN tasks t ON t.id = r.task_id WHERE (.+) ORDER BY fire_at NULLS LAST, r.id").
+		WithArgs(42, 1).
+		WillReturnRows(rows)
+
+	taskID := 42
+	reminders, err := repo.GetReminders(1, &taskID)
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, models.StringList{models.ReminderChannelInApp, models.ReminderChannelEmail}, reminders[0].Channels)
+	assert.Equal(t, "Release", reminders[0].TaskTitle)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRemindersByUserID(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows(reminderRowColumns).
+		AddRow(7, 42, 1, now, nil, []byte(`["in_app"]`), nil, now, now, now, now, models.ReminderDismissed, "Release", nil)
+	mock.ExpectQuery("SELECT (.+) FROM reminders r JOIN tasks t ON t.id = r.task_id WHERE r.user_id = (.+) ORDER BY r.id$").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	reminders, err := repo.GetRemindersByUserID(1)
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, models.ReminderDismissed, reminders[0].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSnoozeDismissedReminder(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	until := time.Now().Add(15 * time.Minute)
+	mock.ExpectExec("UPDATE reminders SET snoozed_until = \\$1, fired_at = \\$2 WHERE dismissed_at IS NULL AND id = \\$3 AND user_id = \\$4").
+		WithArgs(until.UTC(), nil, 7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.SnoozeReminder(7, 1, until)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueReminders(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows(reminderRowColumns).
+		AddRow(7, 42, 1, now, nil, []byte(`["in_app"]`), nil, now, nil, now, now, models.ReminderFired, "Release", nil)
+	mock.ExpectQuery("UPDATE reminders r SET fired_at = \\$1 FROM tasks t WHERE \\(t.id = r.task_id AND r.id IN \\(SELECT due.id FROM reminders due JOIN tasks dt ON dt.id = due.task_id "+
+		"WHERE (.+) <= \\$2 (.+) ORDER BY due.id LIMIT 50 FOR UPDATE OF due SKIP LOCKED\\)\\) RETURNING (.+)").
+		WithArgs(now.UTC(), now.UTC()).
+		WillReturnRows(rows)
+
+	reminders, err := repo.ClaimDueReminders(now, 50)
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, models.ReminderFired, reminders[0].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueRemindersComparesUTC(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	// 09:00 in New York is compared with and stored as 13:00
+	newYork := time.FixedZone("EDT", -4*60*60)
+	now := time.Date(2026, 10, 20, 9, 0, 0, 0, newYork)
+	utc := time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("UPDATE reminders r SET fired_at = \\$1").
+		WithArgs(utc, utc).
+		WillReturnRows(sqlmock.NewRows(reminderRowColumns))
+
+	_, err := repo.ClaimDueReminders(now, 50)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDismissReminderStoresUTC(t *testing.T) {
+	mock, repo := NewReminderMock(t)
+
+	newYork := time.FixedZone("EDT", -4*60*60)
+	now := time.Date(2026, 10, 20, 9, 0, 0, 0, newYork)
+	mock.ExpectExec("UPDATE reminders SET dismissed_at = \\$1").
+		WithArgs(time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC), 7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.DismissReminder(7, 1, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
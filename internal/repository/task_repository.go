@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"fmt"
 	"time"
 
 	"github.com/daioru/todo-app/internal/logger"
@@ -13,6 +12,12 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+var taskColumns = []string{
+	"id", "user_id", "workspace_id", "assignee_id", "title", "description", "status", "priority", "recurrence", "tags",
+	"due_at", "created_at", "completed_at", "assigned_at", "external_id",
+	"(SELECT COUNT(*) FROM task_comments c WHERE c.task_id = tasks.id) AS comment_count",
+}
+
 type TaskRepository struct {
 	db  *sqlx.DB
 	sq  squirrel.StatementBuilderType
@@ -27,10 +32,21 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepository {
 	}
 }
 
+// CreateTask stores the task and its task.created outbox message in one transaction
 func (r *TaskRepository) CreateTask(task *models.Task) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("CreateTask begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
 	query, args, err := r.sq.Insert("tasks").
-		Columns("user_id", "title", "description", "status", "created_at").
-		Values(task.UserID, task.Title, task.Description, task.Status, time.Now()).
+		Columns("user_id", "workspace_id", "external_id", "title", "description", "status", "priority", "recurrence",
+			"tags", "due_at", "created_at", "completed_at").
+		Values(task.UserID, task.WorkspaceID, task.ExternalID, task.Title, task.Description, task.Status, task.Priority,
+			task.Recurrence, task.Tags, dueAt(task), now, completedAt(task.Status, now)).
 		Suffix("RETURNING id, created_at").
 		ToSql()
 	if err != nil {
@@ -41,7 +57,7 @@ func (r *TaskRepository) CreateTask(task *models.Task) error {
 		return err
 	}
 
-	err = r.db.QueryRow(query, args...).Scan(&task.ID, &task.CreatedAt)
+	err = tx.QueryRow(query, args...).Scan(&task.ID, &task.CreatedAt)
 	if err != nil {
 		r.log.Error().
 			Str("query", query).
@@ -51,13 +67,21 @@ func (r *TaskRepository) CreateTask(task *models.Task) error {
 		return err
 	}
 
-	return nil
+	if err := insertOutbox(tx, r.sq, r.log, models.TaskEventCreated, task.UserID, task, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *TaskRepository) GetTaskByID(id int) (*models.Task, error) {
+	return r.getTask(r.db, id)
+}
+
+func (r *TaskRepository) getTask(q sqlx.Queryer, id int) (*models.Task, error) {
 	var task models.Task
 
-	query, args, err := r.sq.Select("id", "user_id", "title", "description", "status", "created_at").
+	query, args, err := r.sq.Select(taskColumns...).
 		From("tasks").
 		Where(squirrel.Eq{"id": id}).
 		ToSql()
@@ -69,7 +93,7 @@ func (r *TaskRepository) GetTaskByID(id int) (*models.Task, error) {
 		return &task, err
 	}
 
-	err = r.db.Get(&task, query, args...)
+	err = sqlx.Get(q, &task, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -85,10 +109,11 @@ func (r *TaskRepository) GetTaskByID(id int) (*models.Task, error) {
 	return &task, nil
 }
 
+// GetTasksByUserID returns the tasks created by the user, personal and in workspaces
 func (r *TaskRepository) GetTasksByUserID(userID int) ([]models.Task, error) {
 	var tasks []models.Task
 
-	query, args, err := r.sq.Select("id", "user_id", "title", "description", "status", "created_at").
+	query, args, err := r.sq.Select(taskColumns...).
 		From("tasks").
 		Where(squirrel.Eq{"user_id": userID}).
 		ToSql()
@@ -113,11 +138,286 @@ func (r *TaskRepository) GetTasksByUserID(userID int) ([]models.Task, error) {
 	return tasks, nil
 }
 
+// GetVisibleTask returns the task if the user may see it, nil otherwise
+func (r *TaskRepository) GetVisibleTask(taskID, userID int) (*models.Task, error) {
+	var task models.Task
+
+	query, args, err := r.sq.Select(taskColumns...).
+		From("tasks").
+		Where(squirrel.And{
+			squirrel.Eq{"id": taskID},
+			tasksVisibleTo(userID),
+		}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", taskID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetVisibleTask query")
+		return nil, err
+	}
+
+	err = r.db.Get(&task, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetVisibleTask DB execution error")
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// GetTasks returns the tasks visible to the user narrowed by the filter
+func (r *TaskRepository) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	var tasks []models.Task
+
+	query, args, err := r.sq.Select(taskColumns...).
+		From("tasks").
+		Where(tasksFiltered(userID, filter)).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetTasks query")
+		return tasks, err
+	}
+
+	err = r.db.Select(&tasks, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetTasks DB execution error")
+		return tasks, err
+	}
+
+	return tasks, nil
+}
+
+// StreamTasks calls fn with every task visible to the user narrowed by the
+// filter, one row at a time and without holding all of them in memory
+func (r *TaskRepository) StreamTasks(userID int, filter models.TaskFilter, fn func(task *models.Task) error) error {
+	query, args, err := r.sq.Select(taskColumns...).
+		From("tasks").
+		Where(tasksFiltered(userID, filter)).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build StreamTasks query")
+		return err
+	}
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("StreamTasks DB execution error")
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.Task
+		if err := rows.StructScan(&task); err != nil {
+			return err
+		}
+		if err := fn(&task); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetExternalIDs returns which of the external ids the user already imported
+func (r *TaskRepository) GetExternalIDs(userID int, externalIDs []string) ([]string, error) {
+	var existing []string
+
+	query, args, err := r.sq.Select("external_id").
+		From("tasks").
+		Where(squirrel.Eq{"user_id": userID, "external_id": externalIDs}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetExternalIDs query")
+		return existing, err
+	}
+
+	err = r.db.Select(&existing, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetExternalIDs DB execution error")
+		return existing, err
+	}
+
+	return existing, nil
+}
+
+// ImportTasks stores the tasks in batches of batchSize within one transaction,
+// together with their task.created outbox messages. Tasks whose external id
+// their user already imported are skipped, the number of created tasks is returned
+func (r *TaskRepository) ImportTasks(tasks []models.Task, batchSize int) (int, error) {
+	return r.importTasks(tasks, nil, batchSize)
+}
+
+// ImportAppTasks stores tasks imported from another app like ImportTasks, the
+// comments of every created task are added in the same transaction as written
+// by its user
+func (r *TaskRepository) ImportAppTasks(tasks []models.ImportedTask, batchSize int) (int, error) {
+	plain := make([]models.Task, len(tasks))
+	comments := make(map[string][]models.ImportedComment)
+	for i := range tasks {
+		plain[i] = tasks[i].Task
+		if tasks[i].ExternalID != nil && len(tasks[i].Comments) > 0 {
+			comments[*tasks[i].ExternalID] = tasks[i].Comments
+		}
+	}
+
+	return r.importTasks(plain, comments, batchSize)
+}
+
+// importTasks inserts the tasks, comments are matched to the created tasks by
+// their external id
+func (r *TaskRepository) importTasks(tasks []models.Task, comments map[string][]models.ImportedComment, batchSize int) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("ImportTasks begin transaction error")
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	created := 0
+	now := time.Now().UTC()
+	for start := 0; start < len(tasks); start += batchSize {
+		batch := tasks[start:min(start+batchSize, len(tasks))]
+
+		stmt := r.sq.Insert("tasks").
+			Columns("user_id", "workspace_id", "external_id", "title", "description", "status", "priority", "recurrence",
+				"tags", "due_at", "created_at", "completed_at")
+		for i := range batch {
+			task := &batch[i]
+			stmt = stmt.Values(task.UserID, task.WorkspaceID, task.ExternalID, task.Title, task.Description, task.Status,
+				task.Priority, task.Recurrence, task.Tags, dueAt(task), importedCreatedAt(task, now), importedCompletedAt(task, now))
+		}
+
+		query, args, err := stmt.
+			Suffix("ON CONFLICT (user_id, external_id) WHERE external_id IS NOT NULL DO NOTHING").
+			Suffix("RETURNING id, user_id, workspace_id, assignee_id, external_id, title, description, status, priority, recurrence, " +
+				"tags, due_at, created_at, completed_at").
+			ToSql()
+		if err != nil {
+			r.log.Error().
+				Int("batch_size", len(batch)).
+				Err(err).
+				Msg("Failed to build ImportTasks query")
+			return 0, err
+		}
+
+		var inserted []models.Task
+		if err := tx.Select(&inserted, query, args...); err != nil {
+			r.log.Error().
+				Str("query", query).
+				Int("batch_size", len(batch)).
+				Err(err).
+				Msg("ImportTasks DB execution error")
+			return 0, err
+		}
+
+		for i := range inserted {
+			if err := insertOutbox(tx, r.sq, r.log, models.TaskEventCreated, inserted[i].UserID, &inserted[i], nil); err != nil {
+				return 0, err
+			}
+			if inserted[i].ExternalID != nil {
+				if err := r.insertImportedComments(tx, &inserted[i], comments[*inserted[i].ExternalID], now); err != nil {
+					return 0, err
+				}
+			}
+		}
+		created += len(inserted)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+func (r *TaskRepository) insertImportedComments(tx *sqlx.Tx, task *models.Task, comments []models.ImportedComment, now time.Time) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	stmt := r.sq.Insert("task_comments").Columns("task_id", "author_id", "body", "created_at")
+	for _, comment := range comments {
+		createdAt := now
+		if comment.CreatedAt != nil {
+			createdAt = *comment.CreatedAt
+		}
+		stmt = stmt.Values(task.ID, task.UserID, comment.Body, createdAt)
+	}
+
+	query, args, err := stmt.ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", task.ID).
+			Err(err).
+			Msg("Failed to build insertImportedComments query")
+		return err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Int("task_id", task.ID).
+			Err(err).
+			Msg("insertImportedComments DB execution error")
+		return err
+	}
+	return nil
+}
+
+// DeleteTask removes a task the user may modify and stores a task.deleted
+// outbox message with the removed task in the same transaction
 func (r *TaskRepository) DeleteTask(taskID, userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("DeleteTask begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	task, err := r.getTask(tx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrNoRowsUpdated
+	}
+
 	query, args, err := r.sq.Delete("tasks").
 		Where(squirrel.And{
 			squirrel.Eq{"id": taskID},
-			squirrel.Eq{"user_id": userID},
+			tasksWritableBy(userID),
 		}).
 		ToSql()
 	if err != nil {
@@ -129,7 +429,7 @@ func (r *TaskRepository) DeleteTask(taskID, userID int) error {
 		return err
 	}
 
-	result, err := r.db.Exec(query, args...)
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		r.log.Error().
 			Str("query", query).
@@ -144,12 +444,39 @@ func (r *TaskRepository) DeleteTask(taskID, userID int) error {
 		return ErrNoRowsUpdated
 	}
 
-	return nil
+	if err := insertOutbox(tx, r.sq, r.log, models.TaskEventDeleted, userID, task, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// UpdateTask changes a task the user may modify and stores a task.updated
+// outbox message with the task before and after the change in the same transaction
 func (r *TaskRepository) UpdateTask(updates map[string]interface{}) error {
+	taskID := updates["id"].(int)
+	userID := updates["user_id"].(int)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("UpdateTask begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := r.getTask(tx, taskID)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return ErrNoRowsUpdated
+	}
+
 	stmt := r.sq.Update("tasks").
-		Where(squirrel.Eq{"id": updates["id"], "user_id": updates["user_id"]})
+		Where(squirrel.And{
+			squirrel.Eq{"id": taskID},
+			tasksWritableBy(userID),
+		})
 
 	for key, value := range updates {
 		if key == "id" || key == "user_id" {
@@ -158,18 +485,23 @@ func (r *TaskRepository) UpdateTask(updates map[string]interface{}) error {
 		stmt = stmt.Set(key, value)
 	}
 
+	// a task keeps the time it was first completed until it is reopened
+	if status, ok := updates["status"]; ok {
+		stmt = stmt.Set("completed_at", squirrel.Expr("CASE WHEN ? = ? THEN COALESCE(completed_at, ?) END", status, models.TaskStatusDone, time.Now().UTC()))
+	}
+
 	query, args, err := stmt.ToSql()
 
 	if err != nil {
 		r.log.Error().
-			Int("task_id", updates["id"].(int)).
-			Int("user_id", updates["user_id"].(int)).
+			Int("task_id", taskID).
+			Int("user_id", userID).
 			Err(err).
 			Msg("Failed to build UpdateTask query")
 		return err
 	}
 
-	result, err := r.db.Exec(query, args...)
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		r.log.Error().
 			Str("query", query).
@@ -184,7 +516,263 @@ func (r *TaskRepository) UpdateTask(updates map[string]interface{}) error {
 		return ErrNoRowsUpdated
 	}
 
-	fmt.Println(result.RowsAffected())
+	if err := r.insertUpdated(tx, taskID, userID, previous); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AssignTask sets or clears the assignee of a task the user may modify. A new
+// assignee starts watching the task and gets the notification, all in one
+// transaction with the task.updated outbox message
+func (r *TaskRepository) AssignTask(taskID, userID int, assigneeID *int, notification *models.Notification) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("AssignTask begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	var assignedAt *time.Time
+	if assigneeID != nil {
+		now := time.Now().UTC()
+		assignedAt = &now
+	}
+
+	query, args, err := r.sq.Update("tasks").
+		Set("assignee_id", assigneeID).
+		Set("assigned_at", assignedAt).
+		Where(squirrel.And{
+			squirrel.Eq{"id": taskID},
+			tasksWritableBy(userID),
+		}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", taskID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build AssignTask query")
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("AssignTask DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	if assigneeID != nil {
+		if err := r.addWatcher(tx, taskID, *assigneeID); err != nil {
+			return err
+		}
+	}
+
+	if notification != nil {
+		if err := insertNotification(tx, r.sq, r.log, notification); err != nil {
+			return err
+		}
+	}
+
+	if err := r.insertUpdated(tx, taskID, userID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertUpdated stores a task.updated outbox message with the state of the task
+// within the transaction of the update
+func (r *TaskRepository) insertUpdated(tx *sqlx.Tx, taskID, userID int, previous *models.Task) error {
+	task, err := r.getTask(tx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrNoRowsUpdated
+	}
+
+	return insertOutbox(tx, r.sq, r.log, models.TaskEventUpdated, userID, task, previous)
+}
+
+func (r *TaskRepository) AddWatcher(taskID, userID int) error {
+	return r.addWatcher(r.db, taskID, userID)
+}
+
+func (r *TaskRepository) RemoveWatcher(taskID, userID int) error {
+	query, args, err := r.sq.Delete("task_watchers").
+		Where(squirrel.Eq{"task_id": taskID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", taskID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build RemoveWatcher query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RemoveWatcher DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) GetWatchers(taskID int) ([]models.TaskWatcher, error) {
+	var watchers []models.TaskWatcher
+
+	query, args, err := r.sq.Select("tw.task_id", "tw.user_id", "u.username", "tw.created_at").
+		From("task_watchers tw").
+		Join("users u ON u.id = tw.user_id").
+		Where(squirrel.Eq{"tw.task_id": taskID}).
+		OrderBy("tw.created_at", "tw.user_id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", taskID).
+			Err(err).
+			Msg("Failed to build GetWatchers query")
+		return watchers, err
+	}
+
+	err = r.db.Select(&watchers, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetWatchers DB execution error")
+		return watchers, err
+	}
+
+	return watchers, nil
+}
+
+func (r *TaskRepository) addWatcher(e sqlx.Execer, taskID, userID int) error {
+	query, args, err := r.sq.Insert("task_watchers").
+		Columns("task_id", "user_id", "created_at").
+		Values(taskID, userID, time.Now().UTC()).
+		Suffix("ON CONFLICT (task_id, user_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("task_id", taskID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build AddWatcher query")
+		return err
+	}
+
+	if _, err := e.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("AddWatcher DB execution error")
+		return err
+	}
 
 	return nil
 }
+
+// tasksFiltered limits a statement to the tasks visible to the user narrowed by the filter
+func tasksFiltered(userID int, filter models.TaskFilter) squirrel.Sqlizer {
+	where := squirrel.And{tasksVisibleTo(userID)}
+	if filter.WorkspaceID != nil {
+		where = append(where, squirrel.Eq{"workspace_id": *filter.WorkspaceID})
+	}
+	if filter.AssigneeID != nil {
+		where = append(where, squirrel.Eq{"assignee_id": *filter.AssigneeID})
+	}
+	if filter.Personal {
+		where = append(where, squirrel.Eq{"workspace_id": nil})
+	}
+	if filter.Tag != "" {
+		where = append(where, squirrel.Expr("tags @> ?::jsonb", models.StringList{filter.Tag}))
+	}
+	return where
+}
+
+// tasksVisibleTo limits a statement to the user's personal tasks and the tasks
+// of every workspace the user is a member of
+func tasksVisibleTo(userID int) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"workspace_id": nil, "user_id": userID},
+		squirrel.Expr("workspace_id IN (?)",
+			squirrel.Select("workspace_id").
+				From("workspace_members").
+				Where(squirrel.Eq{"user_id": userID})),
+	}
+}
+
+// tasksWritableBy limits a statement to the user's personal tasks and the tasks
+// of workspaces where the user has a role allowed to modify them
+func tasksWritableBy(userID int) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"workspace_id": nil, "user_id": userID},
+		squirrel.Expr("workspace_id IN (?)",
+			squirrel.Select("workspace_id").
+				From("workspace_members").
+				Where(squirrel.Eq{"user_id": userID, "role": models.WriterRoles})),
+	}
+}
+
+// dueAt returns the due date of the task as a query argument. The columns
+// have no time zone and hold UTC, the driver would keep the wall clock of any
+// other offset
+func dueAt(task *models.Task) *time.Time {
+	if task.DueAt == nil {
+		return nil
+	}
+	due := time.Time(*task.DueAt).UTC()
+	return &due
+}
+
+// completedAt returns the completion time of a task created with the status
+func completedAt(status string, now time.Time) *time.Time {
+	if status != models.TaskStatusDone {
+		return nil
+	}
+	return &now
+}
+
+// importedCompletedAt keeps the completion time a done task had in the system
+// it was imported from
+func importedCompletedAt(task *models.Task, now time.Time) *time.Time {
+	if task.Status == models.TaskStatusDone && task.CompletedAt != nil {
+		completed := time.Time(*task.CompletedAt).UTC()
+		return &completed
+	}
+	return completedAt(task.Status, now)
+}
+
+// importedCreatedAt keeps the creation time a task had in the system it was
+// imported from
+func importedCreatedAt(task *models.Task, now time.Time) time.Time {
+	if created := time.Time(task.CreatedAt); !created.IsZero() {
+		return created.UTC()
+	}
+	return now
+}
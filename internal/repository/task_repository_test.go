@@ -26,15 +26,42 @@ func TestCreateTask(t *testing.T) {
 		Status:      "pending",
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO tasks`).
-		WithArgs(task.UserID, task.Title, task.Description, task.Status, sqlmock.AnyArg()).
+		WithArgs(task.UserID, task.WorkspaceID, task.ExternalID, task.Title, task.Description, task.Status, task.Priority, task.Recurrence, "[]", nil, sqlmock.AnyArg(), nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventCreated, 1, 1, nil, 1, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	err = repo.CreateTask(task)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestCreateTaskStoresDueAtInUTC(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+	due := models.JSONTime(time.Date(2026, 10, 20, 9, 0, 0, 0, time.FixedZone("EDT", -4*60*60)))
+	task := &models.Task{UserID: 1, Title: "Call", Status: "pending", DueAt: &due}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO tasks`).
+		WithArgs(1, nil, nil, "Call", "", "pending", "", "", "[]", time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC), sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.CreateTask(task))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetTasksByUser(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -72,9 +99,20 @@ func TestUpdateTask(t *testing.T) {
 	updates["description"] = "Updated description"
 	updates["status"] = "done"
 
-	mock.ExpectExec("UPDATE tasks").
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), updates["id"], updates["user_id"]).
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "status"}).AddRow(1, 1, "Task", "pending"))
+	mock.ExpectExec(`UPDATE tasks SET (.+), completed_at = CASE WHEN \$4 = \$5 THEN COALESCE\(completed_at, \$6\) END WHERE \(id = \$7 AND \(user_id = \$8 AND workspace_id IS NULL OR workspace_id IN \(SELECT workspace_id FROM workspace_members WHERE role IN (.+) AND user_id = \$12\)\)\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "done", models.TaskStatusDone, sqlmock.AnyArg(), updates["id"], updates["user_id"], models.RoleOwner, models.RoleAdmin, models.RoleMember, updates["user_id"]).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "status"}).AddRow(1, 1, "Updated task", "done"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventUpdated, 1, 1, nil, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	err = repo.UpdateTask(updates)
 	assert.NoError(t, err)
@@ -89,11 +127,269 @@ func TestDeleteTask(t *testing.T) {
 	db := sqlx.NewDb(mockDB, "sqlmock")
 	repo := repository.NewTaskRepository(db)
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "status"}).AddRow(1, 1, "Task", "pending"))
 	mock.ExpectExec("DELETE FROM tasks").
-		WithArgs(1, 1).
+		WithArgs(1, 1, models.RoleOwner, models.RoleAdmin, models.RoleMember, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventDeleted, 1, 1, nil, 1, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	err = repo.DeleteTask(1, 1)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
-}
\ No newline at end of file
+}
+
+func TestGetTasksScopedToMembership(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "workspace_id", "title", "description", "status", "created_at"}).
+		AddRow(1, 1, nil, "Personal", "", "pending", time.Now()).
+		AddRow(2, 7, 10, "Shared", "", "pending", time.Now())
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(\(user_id = \$1 AND workspace_id IS NULL OR workspace_id IN \(SELECT workspace_id FROM workspace_members WHERE user_id = \$2\)\)\) ORDER BY id`).
+		WithArgs(1, 1).
+		WillReturnRows(rows)
+
+	tasks, err := repo.GetTasks(1, models.TaskFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Nil(t, tasks[0].WorkspaceID)
+	assert.Equal(t, 10, *tasks[1].WorkspaceID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTasksWorkspaceFilter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(\((.+)\) AND workspace_id = \$3\)`).
+		WithArgs(1, 1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	workspaceID := 10
+	_, err = repo.GetTasks(1, models.TaskFilter{WorkspaceID: &workspaceID})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVisibleTaskHidesForeignTasks(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(id = \$1 AND \((.+)workspace_members WHERE user_id = \$3\)\)\)`).
+		WithArgs(42, 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	task, err := repo.GetVisibleTask(42, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, task)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignTaskNotifies(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	assigneeID, actorID, taskID := 3, 1, 42
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE tasks SET assignee_id = \$1, assigned_at = \$2 WHERE \(id = \$3 AND (.+)workspace_members`).
+		WithArgs(&assigneeID, sqlmock.AnyArg(), taskID, actorID, models.RoleOwner, models.RoleAdmin, models.RoleMember, actorID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_watchers (.+) ON CONFLICT").
+		WithArgs(taskID, assigneeID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(assigneeID, models.NotificationTaskAssigned, &taskID, &actorID, nil, "Ship release", nil, sqlmock.AnyArg(),
+			false, models.NotificationTaskAssigned, assigneeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE id = \$1`).
+		WithArgs(taskID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "status", "assignee_id"}).AddRow(taskID, actorID, "Ship release", "pending", assigneeID))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventUpdated, taskID, actorID, nil, actorID, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	notification := &models.Notification{UserID: assigneeID, Type: models.NotificationTaskAssigned, TaskID: &taskID, ActorID: &actorID, Message: "Ship release"}
+	err = repo.AssignTask(taskID, actorID, &assigneeID, notification)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, notification.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignTaskNotWritable(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tasks SET assignee_id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assigneeID := 3
+	err = repo.AssignTask(42, 5, &assigneeID, &models.Notification{UserID: 3})
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTasksAssigneeFilter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(\((.+)\) AND assignee_id = \$3\)`).
+		WithArgs(1, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	assigneeID := 1
+	_, err = repo.GetTasks(1, models.TaskFilter{AssigneeID: &assigneeID})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTasksTagFilter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(\((.+)\) AND tags @> \$3::jsonb\)`).
+		WithArgs(1, 1, models.StringList{"work"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.GetTasks(1, models.TaskFilter{Tag: "work"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamTasks(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "title", "status"}).
+		AddRow(1, 1, "First", "pending").
+		AddRow(2, 1, "Second", "done")
+	mock.ExpectQuery(`SELECT (.+) FROM tasks WHERE \(\((.+)\)\) ORDER BY id`).
+		WithArgs(1, 1).
+		WillReturnRows(rows)
+
+	var titles []string
+	err = repo.StreamTasks(1, models.TaskFilter{}, func(task *models.Task) error {
+		titles = append(titles, task.Title)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"First", "Second"}, titles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportTasksInBatches(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	a, b := "A-1", "A-2"
+	completed := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	completedAt := models.JSONTime(completed)
+	createdAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{UserID: 1, ExternalID: &a, Title: "First", Status: "pending"},
+		{UserID: 1, ExternalID: &b, Title: "Second", Status: "done", Priority: "high", Recurrence: "FREQ=WEEKLY",
+			Tags: models.StringList{"garden", "@phone"}, CompletedAt: &completedAt},
+		{UserID: 1, Title: "Third", Status: "pending", CreatedAt: models.JSONTime(createdAt)},
+	}
+	returning := []string{"id", "user_id", "external_id", "title", "status"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO tasks \((.+)\) VALUES \((.+)\),\((.+)\) ON CONFLICT \(user_id, external_id\) WHERE external_id IS NOT NULL DO NOTHING RETURNING (.+)`).
+		WithArgs(1, nil, &a, "First", "", "pending", "", "", "[]", nil, sqlmock.AnyArg(), nil,
+			1, nil, &b, "Second", "", "done", "high", "FREQ=WEEKLY", `["garden","@phone"]`, nil, sqlmock.AnyArg(), &completed).
+		WillReturnRows(sqlmock.NewRows(returning).AddRow(10, 1, "A-2", "Second", "done"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventCreated, 10, 1, nil, 1, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO tasks \((.+)\) VALUES \((.+)\) ON CONFLICT`).
+		WithArgs(1, nil, nil, "Third", "", "pending", "", "", "[]", nil, createdAt, nil).
+		WillReturnRows(sqlmock.NewRows(returning).AddRow(11, 1, nil, "Third", "pending"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(models.TaskEventCreated, 11, 1, nil, 1, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	created, err := repo.ImportTasks(tasks, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportAppTasks(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	repo := repository.NewTaskRepository(db)
+
+	a, b := "trello:c1", "trello:c2"
+	posted := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	tasks := []models.ImportedTask{
+		{Task: models.Task{UserID: 1, ExternalID: &a, Title: "First", Status: "pending"},
+			Comments: []models.ImportedComment{{Body: "**Jo**: Draft done", CreatedAt: &posted}, {Body: "Undated"}}},
+		{Task: models.Task{UserID: 1, ExternalID: &b, Title: "Second", Status: "pending"},
+			Comments: []models.ImportedComment{{Body: "Imported before"}}},
+	}
+	returning := []string{"id", "user_id", "external_id", "title", "status"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO tasks \((.+)\) VALUES \((.+)\),\((.+)\) ON CONFLICT`).
+		WillReturnRows(sqlmock.NewRows(returning).AddRow(10, 1, "trello:c1", "First", "pending"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO task_comments \(task_id,author_id,body,created_at\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)`).
+		WithArgs(10, 1, "**Jo**: Draft done", posted, 10, 1, "Undated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	created, err := repo.ImportAppTasks(tasks, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type TokenRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewTokenRepository(db *sqlx.DB) *TokenRepository {
+	return &TokenRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *TokenRepository) CreateToken(token *models.UserToken) error {
+	query, args, err := r.sq.Insert("user_tokens").
+		Columns("user_id", "purpose", "token_hash", "expires_at", "created_at").
+		Values(token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", token.UserID).
+			Str("purpose", token.Purpose).
+			Err(err).
+			Msg("Failed to build CreateToken query")
+		return err
+	}
+
+	err = r.db.QueryRow(query, args...).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Int("user_id", token.UserID).
+			Err(err).
+			Msg("CreateToken DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeToken marks a valid token as used and returns its owner.
+// Expired, used and unknown tokens give ErrTokenNotFound
+func (r *TokenRepository) ConsumeToken(tokenHash, purpose string, now time.Time) (int, error) {
+	query, args, err := r.sq.Update("user_tokens").
+		Set("used_at", now).
+		Where(squirrel.Eq{
+			"token_hash": tokenHash,
+			"purpose":    purpose,
+			"used_at":    nil,
+		}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING user_id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("purpose", purpose).
+			Err(err).
+			Msg("Failed to build ConsumeToken query")
+		return 0, err
+	}
+
+	var userID int
+	err = r.db.QueryRow(query, args...).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrTokenNotFound
+		}
+		r.log.Error().
+			Str("query", query).
+			Str("purpose", purpose).
+			Err(err).
+			Msg("ConsumeToken DB execution error")
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// DeleteTokens invalidates all outstanding tokens of the user for purpose
+func (r *TokenRepository) DeleteTokens(userID int, purpose string) error {
+	query, args, err := r.sq.Delete("user_tokens").
+		Where(squirrel.Eq{"user_id": userID, "purpose": purpose}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Str("purpose", purpose).
+			Err(err).
+			Msg("Failed to build DeleteTokens query")
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteTokens DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// GetTokens returns the user's tokens of a purpose, oldest first
+func (r *TokenRepository) GetTokens(userID int, purpose string) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+
+	query, args, err := r.sq.Select("id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at").
+		From("user_tokens").
+		Where(squirrel.Eq{"user_id": userID, "purpose": purpose}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Str("purpose", purpose).
+			Err(err).
+			Msg("Failed to build GetTokens query")
+		return tokens, err
+	}
+
+	err = r.db.Select(&tokens, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Str("purpose", purpose).
+			Err(err).
+			Msg("GetTokens DB execution error")
+		return tokens, err
+	}
+
+	return tokens, nil
+}
+
+// FindToken returns a valid token without consuming it
+func (r *TokenRepository) FindToken(tokenHash, purpose string, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+
+	query, args, err := r.sq.Select("id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at").
+		From("user_tokens").
+		Where(squirrel.Eq{
+			"token_hash": tokenHash,
+			"purpose":    purpose,
+			"used_at":    nil,
+		}).
+		Where(squirrel.Gt{"expires_at": now}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("purpose", purpose).
+			Err(err).
+			Msg("Failed to build FindToken query")
+		return nil, err
+	}
+
+	err = r.db.Get(&token, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTokenNotFound
+		}
+		r.log.Error().
+			Str("query", query).
+			Str("purpose", purpose).
+			Err(err).
+			Msg("FindToken DB execution error")
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTokenMock(t *testing.T) (sqlmock.Sqlmock, *repository.TokenRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewTokenRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateToken(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	token := &models.UserToken{
+		UserID:    1,
+		Purpose:   models.TokenPurposeEmailVerification,
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	mock.ExpectQuery("INSERT INTO user_tokens").
+		WithArgs(1, models.TokenPurposeEmailVerification, "hash", token.ExpiresAt, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	err := repo.CreateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, token.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeToken(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE user_tokens SET used_at = \$1 WHERE (.+) AND used_at IS NULL AND expires_at > \$4 RETURNING user_id`).
+		WithArgs(now, models.TokenPurposePasswordReset, "hash", now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(3))
+
+	userID, err := repo.ConsumeToken("hash", models.TokenPurposePasswordReset, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeTokenNotFound(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	mock.ExpectQuery("UPDATE user_tokens").WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.ConsumeToken("hash", models.TokenPurposePasswordReset, time.Now())
+	assert.ErrorIs(t, err, repository.ErrTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindTokenNotFound(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM user_tokens").WillReturnError(sql.ErrNoRows)
+
+	token, err := repo.FindToken("hash", models.TokenPurposePasswordReset, time.Now())
+	assert.ErrorIs(t, err, repository.ErrTokenNotFound)
+	assert.Nil(t, token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTokens(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at"}).
+		AddRow(4, 1, models.TokenPurposeInboundMail, "hash", now.Add(time.Hour), nil, now)
+	mock.ExpectQuery("SELECT (.+) FROM user_tokens WHERE purpose = (.+) AND user_id = (.+) ORDER BY id").
+		WithArgs(models.TokenPurposeInboundMail, 1).
+		WillReturnRows(rows)
+
+	tokens, err := repo.GetTokens(1, models.TokenPurposeInboundMail)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTokens(t *testing.T) {
+	mock, repo := NewTokenMock(t)
+
+	mock.ExpectExec("DELETE FROM user_tokens").
+		WithArgs(models.TokenPurposeEmailVerification, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := repo.DeleteTokens(1, models.TokenPurposeEmailVerification)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
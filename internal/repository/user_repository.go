@@ -12,6 +12,11 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+var userColumns = []string{
+	"id", "username", "COALESCE(email, '') AS email", "email_verified_at",
+	"display_name", "timezone", "locale", "password_hash", "created_at",
+}
+
 type UserRepository struct {
 	db  *sqlx.DB
 	sq  squirrel.StatementBuilderType
@@ -59,8 +64,8 @@ func (r *UserRepository) UserExists(user *models.User) (bool, error) {
 
 func (r *UserRepository) CreateUser(user *models.User) error {
 	query, args, err := r.sq.Insert("users").
-		Columns("username", "password_hash", "created_at").
-		Values(user.Username, user.PasswordHash, time.Now()).
+		Columns("username", "password_hash", "created_at", "email").
+		Values(user.Username, user.PasswordHash, time.Now(), squirrel.Expr("NULLIF(?, '')", user.Email)).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -87,7 +92,7 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 	var user models.User
 
-	query, args, err := r.sq.Select("id", "username", "password_hash", "created_at").
+	query, args, err := r.sq.Select(userColumns...).
 		From("users").
 		Where(squirrel.Eq{"id": id}).
 		ToSql()
@@ -117,7 +122,7 @@ func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 
 func (r *UserRepository) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
-	query, args, err := r.sq.Select("id", "username", "password_hash", "created_at").
+	query, args, err := r.sq.Select(userColumns...).
 		From("users").
 		Where(squirrel.Eq{"username": username}).
 		ToSql()
@@ -144,3 +149,284 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 
 	return &user, nil
 }
+
+// GetUsersByUsernames returns the existing users among the given usernames
+func (r *UserRepository) GetUsersByUsernames(usernames []string) ([]models.User, error) {
+	var users []models.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	query, args, err := r.sq.Select(userColumns...).
+		From("users").
+		Where(squirrel.Eq{"username": usernames}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Strs("usernames", usernames).
+			Err(err).
+			Msg("Failed to build GetUsersByUsernames query")
+		return users, err
+	}
+
+	err = r.db.Select(&users, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetUsersByUsernames DB execution error")
+		return users, err
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) UpdatePasswordHash(userID int, passwordHash string) error {
+	query, args, err := r.sq.Update("users").
+		Set("password_hash", passwordHash).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build UpdatePasswordHash query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Int("user_id", userID).
+			Err(err).
+			Msg("UpdatePasswordHash DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	query, args, err := r.sq.Select(userColumns...).
+		From("users").
+		Where(squirrel.Expr("LOWER(email) = LOWER(?)", email)).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("email", email).
+			Err(err).
+			Msg("Failed to build GetUserByEmail query")
+		return nil, err
+	}
+
+	err = r.db.Get(&user, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetUserByEmail DB execution error")
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// EmailExists reports whether another user already uses the address
+func (r *UserRepository) EmailExists(email string, excludeUserID int) (bool, error) {
+	query, args, err := r.sq.Select("COUNT(*)").
+		From("users").
+		Where(squirrel.Expr("LOWER(email) = LOWER(?)", email)).
+		Where(squirrel.NotEq{"id": excludeUserID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("email", email).
+			Err(err).
+			Msg("Failed to build EmailExists query")
+		return false, err
+	}
+
+	var count int
+	err = r.db.Get(&count, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("EmailExists DB execution error")
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// SetEmail changes the address and resets its verification
+func (r *UserRepository) SetEmail(userID int, email string) error {
+	query, args, err := r.sq.Update("users").
+		Set("email", email).
+		Set("email_verified_at", nil).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build SetEmail query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("SetEmail DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *UserRepository) MarkEmailVerified(userID int) error {
+	query, args, err := r.sq.Update("users").
+		Set("email_verified_at", time.Now()).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build MarkEmailVerified query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("MarkEmailVerified DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// UpdateUser applies validated profile updates
+func (r *UserRepository) UpdateUser(userID int, updates map[string]interface{}) error {
+	query, args, err := r.sq.Update("users").
+		SetMap(updates).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build UpdateUser query")
+		return err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("UpdateUser DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// DeleteUser removes the account, related rows go with it via ON DELETE CASCADE.
+// Workspace tasks the user created are handed to the owner of the workspace
+// first, they belong to the other members as well
+func (r *UserRepository) DeleteUser(userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("DeleteUser begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Update("tasks").
+		Set("user_id", squirrel.Expr("m.user_id")).
+		From("workspace_members m").
+		Where("m.workspace_id = tasks.workspace_id").
+		Where(squirrel.Eq{"m.role": models.RoleOwner, "tasks.user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build DeleteUser tasks query")
+		return err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteUser tasks DB execution error")
+		return err
+	}
+
+	query, args, err = r.sq.Delete("users").
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build DeleteUser query")
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("DeleteUser DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return tx.Commit()
+}
@@ -32,7 +32,7 @@ func TestCreateUser(t *testing.T) {
 	}
 
 	mock.ExpectQuery(`INSERT INTO users`).
-		WithArgs(user.Username, user.PasswordHash, sqlmock.AnyArg()).
+		WithArgs(user.Username, user.PasswordHash, sqlmock.AnyArg(), user.Email).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	err := repo.CreateUser(user)
@@ -73,3 +73,158 @@ func TestGetUserByUsername(t *testing.T) {
 	assert.Equal(t, "Test username", user.Username)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUpdatePasswordHash(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectExec("UPDATE users SET password_hash").
+		WithArgs("new hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdatePasswordHash(1, "new hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "username", "email", "email_verified_at", "password_hash", "created_at"}).
+		AddRow(1, "Test username", "user@example.com", time.Now(), "Test password hash", time.Now())
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("User@Example.com").
+		WillReturnRows(rows)
+
+	user, err := repo.GetUserByEmail("User@Example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", user.Email)
+	assert.True(t, user.EmailVerified())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserByEmailNotFound(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM users").
+		WithArgs("nobody@example.com").
+		WillReturnError(sql.ErrNoRows)
+
+	user, err := repo.GetUserByEmail("nobody@example.com")
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailExists(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	exists, err := repo.EmailExists("user@example.com", 1)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetEmail(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectExec("UPDATE users SET email = (.+), email_verified_at = (.+)").
+		WithArgs("user@example.com", nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SetEmail(1, "user@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkEmailVerifiedNoRows(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectExec("UPDATE users SET email_verified_at").
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.MarkEmailVerified(1)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUser(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectExec("UPDATE users SET display_name = (.+), timezone = (.+) WHERE id = (.+)").
+		WithArgs("Alice", "Europe/Moscow", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdateUser(1, map[string]interface{}{"display_name": "Alice", "timezone": "Europe/Moscow"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tasks SET user_id = m.user_id FROM workspace_members m WHERE m.workspace_id = tasks.workspace_id AND m.role = (.+) AND tasks.user_id = (.+)").
+		WithArgs(models.RoleOwner, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM users WHERE id = (.+)").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.DeleteUser(1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUserNotFound(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tasks").
+		WithArgs(models.RoleOwner, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM users").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.DeleteUser(1)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUsersByUsernames(t *testing.T) {
+	mockDB, mock, repo := NewMock(t)
+	defer mockDB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "username"}).
+		AddRow(2, "bob").
+		AddRow(3, "carol")
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE username IN \\((.+)\\)").
+		WithArgs("bob", "carol", "nobody").
+		WillReturnRows(rows)
+
+	users, err := repo.GetUsersByUsernames([]string{"bob", "carol", "nobody"})
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	users, err = repo.GetUsersByUsernames(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var webhookColumns = []string{
+	"id", "user_id", "url", "secret", "event_types", "enabled", "consecutive_failures", "disabled_at", "created_at",
+}
+
+var deliveryColumns = []string{
+	"d.id", "d.webhook_id", "d.event_type", "d.payload", "d.status", "d.attempts", "d.next_attempt_at",
+	"d.response_code", "d.error", "d.created_at", "d.delivered_at",
+}
+
+type WebhookRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+func (r *WebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	query, args, err := r.sq.Insert("webhooks").
+		Columns("user_id", "url", "secret", "event_types", "enabled", "created_at").
+		Values(webhook.UserID, webhook.URL, webhook.Secret, webhook.EventTypes, webhook.Enabled, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", webhook.UserID).
+			Err(err).
+			Msg("Failed to build CreateWebhook query")
+		return err
+	}
+
+	err = r.db.QueryRowx(query, args...).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateWebhook DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) GetWebhooks(userID int) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+
+	query, args, err := r.sq.Select(webhookColumns...).
+		From("webhooks").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetWebhooks query")
+		return webhooks, err
+	}
+
+	err = r.db.Select(&webhooks, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetWebhooks DB execution error")
+		return webhooks, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns the webhook if it belongs to the user
+func (r *WebhookRepository) GetWebhook(id, userID int) (*models.Webhook, error) {
+	var webhook models.Webhook
+
+	query, args, err := r.sq.Select(webhookColumns...).
+		From("webhooks").
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", id).
+			Err(err).
+			Msg("Failed to build GetWebhook query")
+		return nil, err
+	}
+
+	err = r.db.Get(&webhook, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetWebhook DB execution error")
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) UpdateWebhook(webhook *models.Webhook) error {
+	query, args, err := r.sq.Update("webhooks").
+		Set("url", webhook.URL).
+		Set("event_types", webhook.EventTypes).
+		Set("enabled", webhook.Enabled).
+		Set("consecutive_failures", webhook.ConsecutiveFailures).
+		Set("disabled_at", webhook.DisabledAt).
+		Where(squirrel.Eq{"id": webhook.ID, "user_id": webhook.UserID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", webhook.ID).
+			Err(err).
+			Msg("Failed to build UpdateWebhook query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "UpdateWebhook")
+}
+
+// DeleteWebhook removes the webhook of the user together with its deliveries
+func (r *WebhookRepository) DeleteWebhook(id, userID int) error {
+	query, args, err := r.sq.Delete("webhooks").
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", id).
+			Err(err).
+			Msg("Failed to build DeleteWebhook query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "DeleteWebhook")
+}
+
+// EnqueueDeliveries queues the event of an outbox message for every enabled
+// webhook subscribed to it whose owner can see the task: the task owner for
+// personal tasks, the workspace members otherwise. Webhooks that already got
+// the message are skipped. It returns the number of queued deliveries
+func (r *WebhookRepository) EnqueueDeliveries(outboxID int64, eventType string, payload types.JSONText, ownerID int, workspaceID *int, now time.Time) (int64, error) {
+	recipients := squirrel.Sqlizer(squirrel.Eq{"user_id": ownerID})
+	if workspaceID != nil {
+		recipients = squirrel.Expr("user_id IN (?)",
+			squirrel.Select("user_id").
+				From("workspace_members").
+				Where(squirrel.Eq{"workspace_id": *workspaceID}))
+	}
+
+	return r.enqueue(&outboxID, eventType, payload, recipients, now)
+}
+
+// EnqueueUserDeliveries queues an event for the enabled webhooks of the user
+// subscribed to it
+func (r *WebhookRepository) EnqueueUserDeliveries(eventType string, payload types.JSONText, userID int, now time.Time) (int64, error) {
+	return r.enqueue(nil, eventType, payload, squirrel.Eq{"user_id": userID}, now)
+}
+
+func (r *WebhookRepository) enqueue(outboxID *int64, eventType string, payload types.JSONText, recipients squirrel.Sqlizer, now time.Time) (int64, error) {
+	query, args, err := r.sq.Insert("webhook_deliveries").
+		Columns("webhook_id", "outbox_id", "event_type", "payload", "next_attempt_at", "created_at").
+		Select(r.sq.Select("id").
+			Column("?::bigint", outboxID).
+			Column("?", eventType).
+			Column("?::jsonb", payload).
+			Column("?::timestamp", now).
+			Column("?::timestamp", now).
+			From("webhooks").
+			Where(squirrel.And{
+				squirrel.Eq{"enabled": true},
+				squirrel.Expr("event_types @> jsonb_build_array(?::text)", eventType),
+				recipients,
+			})).
+		Suffix("ON CONFLICT (webhook_id, outbox_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Str("event_type", eventType).
+			Err(err).
+			Msg("Failed to build EnqueueDeliveries query")
+		return 0, err
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("EnqueueDeliveries DB execution error")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CreateDelivery queues a single delivery, the caller picks when it becomes due
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	query, args, err := r.sq.Insert("webhook_deliveries").
+		Columns("webhook_id", "event_type", "payload", "status", "next_attempt_at", "created_at").
+		Values(delivery.WebhookID, delivery.EventType, delivery.Payload, delivery.Status, delivery.NextAttemptAt, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", delivery.WebhookID).
+			Err(err).
+			Msg("Failed to build CreateDelivery query")
+		return err
+	}
+
+	err = r.db.QueryRowx(query, args...).Scan(&delivery.ID, &delivery.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateDelivery DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDeliveries picks up to limit pending deliveries of enabled webhooks due
+// at now and moves their next attempt to leaseUntil, so other instances skip
+// them while they are being sent. Rows locked by another instance are skipped
+func (r *WebhookRepository) ClaimDeliveries(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("ClaimDeliveries begin transaction error")
+		return deliveries, err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Select(append(deliveryColumns, "w.url", "w.secret")...).
+		From("webhook_deliveries d").
+		Join("webhooks w ON w.id = d.webhook_id").
+		Where(squirrel.And{
+			squirrel.Eq{"d.status": models.DeliveryPending, "w.enabled": true},
+			squirrel.LtOrEq{"d.next_attempt_at": now},
+		}).
+		OrderBy("d.next_attempt_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE OF d SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build ClaimDeliveries query")
+		return deliveries, err
+	}
+
+	if err = tx.Select(&deliveries, query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ClaimDeliveries DB execution error")
+		return deliveries, err
+	}
+
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]int64, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+
+	query, args, err = r.sq.Update("webhook_deliveries").
+		Set("next_attempt_at", leaseUntil).
+		Where(squirrel.Eq{"id": ids}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msg("Failed to build ClaimDeliveries lease query")
+		return nil, err
+	}
+
+	if _, err = tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("ClaimDeliveries lease DB execution error")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt and updates the
+// failure streak of its webhook. After disableAfter failures in a row the
+// webhook is disabled, the returned value tells whether it is still enabled
+func (r *WebhookRepository) RecordAttempt(delivery *models.WebhookDelivery, succeeded bool, disableAfter int, now time.Time) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("RecordAttempt begin transaction error")
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Update("webhook_deliveries").
+		Set("status", delivery.Status).
+		Set("attempts", delivery.Attempts).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("response_code", delivery.ResponseCode).
+		Set("error", delivery.Error).
+		Set("delivered_at", delivery.DeliveredAt).
+		Where(squirrel.Eq{"id": delivery.ID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int64("delivery_id", delivery.ID).
+			Err(err).
+			Msg("Failed to build RecordAttempt query")
+		return false, err
+	}
+
+	if _, err = tx.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RecordAttempt DB execution error")
+		return false, err
+	}
+
+	stmt := r.sq.Update("webhooks").Where(squirrel.Eq{"id": delivery.WebhookID})
+	if succeeded {
+		stmt = stmt.Set("consecutive_failures", 0)
+	} else {
+		stmt = stmt.
+			Set("consecutive_failures", squirrel.Expr("consecutive_failures + 1")).
+			Set("enabled", squirrel.Expr("enabled AND consecutive_failures + 1 < ?", disableAfter)).
+			Set("disabled_at", squirrel.Expr("CASE WHEN enabled AND consecutive_failures + 1 >= ? THEN ?::timestamp ELSE disabled_at END", disableAfter, now))
+	}
+
+	query, args, err = stmt.Suffix("RETURNING enabled").ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", delivery.WebhookID).
+			Err(err).
+			Msg("Failed to build RecordAttempt webhook query")
+		return false, err
+	}
+
+	var enabled bool
+	if err = tx.QueryRowx(query, args...).Scan(&enabled); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RecordAttempt webhook DB execution error")
+		return false, err
+	}
+
+	return enabled, tx.Commit()
+}
+
+// GetDeliveries returns the latest deliveries of the user's webhook, newest first
+func (r *WebhookRepository) GetDeliveries(webhookID, userID, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	query, args, err := r.sq.Select(deliveryColumns...).
+		From("webhook_deliveries d").
+		Join("webhooks w ON w.id = d.webhook_id").
+		Where(squirrel.Eq{"d.webhook_id": webhookID, "w.user_id": userID}).
+		OrderBy("d.id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("webhook_id", webhookID).
+			Err(err).
+			Msg("Failed to build GetDeliveries query")
+		return deliveries, err
+	}
+
+	err = r.db.Select(&deliveries, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetDeliveries DB execution error")
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDeliveriesByUserID returns every delivery of the user's webhooks, oldest first
+func (r *WebhookRepository) GetDeliveriesByUserID(userID int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	query, args, err := r.sq.Select(deliveryColumns...).
+		From("webhook_deliveries d").
+		Join("webhooks w ON w.id = d.webhook_id").
+		Where(squirrel.Eq{"w.user_id": userID}).
+		OrderBy("d.id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetDeliveriesByUserID query")
+		return deliveries, err
+	}
+
+	err = r.db.Select(&deliveries, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetDeliveriesByUserID DB execution error")
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) execAffecting(query string, args []interface{}, name string) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
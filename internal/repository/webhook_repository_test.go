@@ -0,0 +1,127 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewWebhookMock(t *testing.T) (sqlmock.Sqlmock, *repository.WebhookRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewWebhookRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestGetWebhook(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "url", "secret", "event_types", "enabled", "consecutive_failures", "disabled_at", "created_at"}).
+		AddRow(5, 1, "https://ci.example.com/hook", "s3cret", []byte(`["task.created","task.deleted"]`), true, 0, nil, time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id = (.+) AND user_id = (.+)").
+		WithArgs(5, 1).
+		WillReturnRows(rows)
+
+	webhook, err := repo.GetWebhook(5, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StringList{models.TaskEventCreated, models.TaskEventDeleted}, webhook.EventTypes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnqueueDeliveriesForWorkspaceTask(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	now := time.Now()
+	workspaceID := 10
+	mock.ExpectExec("INSERT INTO webhook_deliveries (.+) SELECT id, (.+) FROM webhooks WHERE \\(enabled = \\$6 AND event_types @> jsonb_build_array\\(\\$7::text\\) AND user_id IN \\(SELECT user_id FROM workspace_members WHERE workspace_id = \\$8\\)\\) ON CONFLICT \\(webhook_id, outbox_id\\) DO NOTHING").
+		WithArgs(int64(42), models.TaskEventUpdated, sqlmock.AnyArg(), now, now, true, models.TaskEventUpdated, workspaceID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	queued, err := repo.EnqueueDeliveries(42, models.TaskEventUpdated, []byte(`{}`), 1, &workspaceID, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), queued)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDeliveries(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	now := time.Now()
+	lease := now.Add(20 * time.Second)
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at",
+		"response_code", "error", "created_at", "delivered_at", "url", "secret"}).
+		AddRow(7, 5, models.TaskEventCreated, []byte(`{}`), models.DeliveryPending, 1, now, 500, "unexpected response status 500", now, nil, "https://ci.example.com/hook", "s3cret").
+		AddRow(8, 5, models.TaskEventUpdated, []byte(`{}`), models.DeliveryPending, 0, now, nil, nil, now, nil, "https://ci.example.com/hook", "s3cret")
+	mock.ExpectQuery("SELECT (.+) FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE (.+) ORDER BY d.next_attempt_at LIMIT 20 FOR UPDATE OF d SKIP LOCKED").
+		WithArgs(models.DeliveryPending, true, now).
+		WillReturnRows(rows)
+	mock.ExpectExec("UPDATE webhook_deliveries SET next_attempt_at = (.+) WHERE id IN \\((.+),(.+)\\)").
+		WithArgs(lease, int64(7), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	deliveries, err := repo.ClaimDeliveries(now, lease, 20)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, "s3cret", deliveries[0].Secret)
+	assert.Equal(t, 500, *deliveries[0].ResponseCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDeliveriesByUserID(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at",
+		"response_code", "error", "created_at", "delivered_at"}).
+		AddRow(7, 5, models.TaskEventCreated, []byte(`{}`), models.DeliverySucceeded, 1, now, 204, nil, now, now)
+	mock.ExpectQuery("SELECT (.+) FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE w.user_id = (.+) ORDER BY d.id$").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	deliveries, err := repo.GetDeliveriesByUserID(1)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordFailedAttemptDisablesWebhook(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	now := time.Now()
+	code := 503
+	delivery := &models.WebhookDelivery{ID: 7, WebhookID: 5, Status: models.DeliveryPending, Attempts: 2, NextAttemptAt: now, ResponseCode: &code}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = (.+) WHERE id = (.+)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1, enabled = enabled AND consecutive_failures \\+ 1 < \\$1, disabled_at = CASE (.+) END WHERE id = \\$4 RETURNING enabled").
+		WithArgs(15, 15, now, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+	mock.ExpectCommit()
+
+	enabled, err := repo.RecordAttempt(delivery, false, 15, now)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteForeignWebhook(t *testing.T) {
+	mock, repo := NewWebhookMock(t)
+
+	mock.ExpectExec("DELETE FROM webhooks WHERE id = (.+) AND user_id = (.+)").
+		WithArgs(5, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.DeleteWebhook(5, 2)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,519 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/rs/zerolog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var invitationColumns = []string{
+	"i.id", "i.workspace_id", "w.name AS workspace_name", "i.inviter_id", "i.invitee_id",
+	"i.role", "i.status", "i.created_at", "i.responded_at",
+}
+
+type WorkspaceRepository struct {
+	db  *sqlx.DB
+	sq  squirrel.StatementBuilderType
+	log zerolog.Logger
+}
+
+func NewWorkspaceRepository(db *sqlx.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{
+		db:  db,
+		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		log: logger.GetLogger(),
+	}
+}
+
+// CreateWorkspace inserts the workspace and makes ownerID its owner
+func (r *WorkspaceRepository) CreateWorkspace(workspace *models.Workspace, ownerID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("CreateWorkspace begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Insert("workspaces").
+		Columns("name", "created_by", "created_at").
+		Values(workspace.Name, ownerID, time.Now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", ownerID).
+			Err(err).
+			Msg("Failed to build CreateWorkspace query")
+		return err
+	}
+
+	if err = tx.QueryRow(query, args...).Scan(&workspace.ID, &workspace.CreatedAt); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateWorkspace DB execution error")
+		return err
+	}
+
+	if err := r.addMember(tx, workspace.ID, ownerID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	workspace.CreatedBy = &ownerID
+	workspace.Role = models.RoleOwner
+	return tx.Commit()
+}
+
+func (r *WorkspaceRepository) GetWorkspace(id int) (*models.Workspace, error) {
+	var workspace models.Workspace
+
+	query, args, err := r.sq.Select("id", "name", "created_by", "created_at").
+		From("workspaces").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", id).
+			Err(err).
+			Msg("Failed to build GetWorkspace query")
+		return nil, err
+	}
+
+	err = r.db.Get(&workspace, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetWorkspace DB execution error")
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
+// GetWorkspacesByUserID returns the workspaces the user is a member of with the user's role
+func (r *WorkspaceRepository) GetWorkspacesByUserID(userID int) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+
+	query, args, err := r.sq.Select("w.id", "w.name", "w.created_by", "w.created_at", "m.role").
+		From("workspaces w").
+		Join("workspace_members m ON m.workspace_id = w.id").
+		Where(squirrel.Eq{"m.user_id": userID}).
+		OrderBy("w.id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetWorkspacesByUserID query")
+		return workspaces, err
+	}
+
+	err = r.db.Select(&workspaces, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetWorkspacesByUserID DB execution error")
+		return workspaces, err
+	}
+
+	return workspaces, nil
+}
+
+func (r *WorkspaceRepository) RenameWorkspace(id int, name string) error {
+	query, args, err := r.sq.Update("workspaces").
+		Set("name", name).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", id).
+			Err(err).
+			Msg("Failed to build RenameWorkspace query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "RenameWorkspace")
+}
+
+// DeleteWorkspace removes the workspace together with its tasks, members and invitations
+func (r *WorkspaceRepository) DeleteWorkspace(id int) error {
+	query, args, err := r.sq.Delete("workspaces").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", id).
+			Err(err).
+			Msg("Failed to build DeleteWorkspace query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "DeleteWorkspace")
+}
+
+// GetMemberRole returns the user's role in the workspace, empty if the user is not a member
+func (r *WorkspaceRepository) GetMemberRole(workspaceID, userID int) (models.WorkspaceRole, error) {
+	var role models.WorkspaceRole
+
+	query, args, err := r.sq.Select("role").
+		From("workspace_members").
+		Where(squirrel.Eq{"workspace_id": workspaceID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", workspaceID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetMemberRole query")
+		return "", err
+	}
+
+	err = r.db.Get(&role, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetMemberRole DB execution error")
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (r *WorkspaceRepository) GetMembers(workspaceID int) ([]models.WorkspaceMember, error) {
+	var members []models.WorkspaceMember
+
+	query, args, err := r.sq.Select("m.workspace_id", "m.user_id", "u.username", "m.role", "m.joined_at").
+		From("workspace_members m").
+		Join("users u ON u.id = m.user_id").
+		Where(squirrel.Eq{"m.workspace_id": workspaceID}).
+		OrderBy("m.joined_at", "m.user_id").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", workspaceID).
+			Err(err).
+			Msg("Failed to build GetMembers query")
+		return members, err
+	}
+
+	err = r.db.Select(&members, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetMembers DB execution error")
+		return members, err
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) UpdateMemberRole(workspaceID, userID int, role models.WorkspaceRole) error {
+	query, args, err := r.sq.Update("workspace_members").
+		Set("role", role).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", workspaceID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build UpdateMemberRole query")
+		return err
+	}
+
+	return r.execAffecting(query, args, "UpdateMemberRole")
+}
+
+// RemoveMember removes the member together with their watches and assignments
+// of the workspace tasks, which they can't see anymore
+func (r *WorkspaceRepository) RemoveMember(workspaceID, userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("RemoveMember begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.Delete("workspace_members").
+		Where(squirrel.Eq{"workspace_id": workspaceID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", workspaceID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build RemoveMember query")
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RemoveMember DB execution error")
+		return err
+	}
+	if c, _ := result.RowsAffected(); c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	workspaceTasks := squirrel.Select("id").From("tasks").Where(squirrel.Eq{"workspace_id": workspaceID})
+
+	unwatch := r.sq.Delete("task_watchers").
+		Where(squirrel.Eq{"user_id": userID}).
+		Where(squirrel.Expr("task_id IN (?)", workspaceTasks))
+	unassign := r.sq.Update("tasks").
+		Set("assignee_id", nil).
+		Where(squirrel.Eq{"workspace_id": workspaceID, "assignee_id": userID})
+
+	for _, statement := range []squirrel.Sqlizer{unwatch, unassign} {
+		query, args, err := statement.ToSql()
+		if err != nil {
+			r.log.Error().
+				Int("workspace_id", workspaceID).
+				Int("user_id", userID).
+				Err(err).
+				Msg("Failed to build RemoveMember cleanup query")
+			return err
+		}
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			r.log.Error().
+				Str("query", query).
+				Interface("args", args).
+				Err(err).
+				Msg("RemoveMember cleanup DB execution error")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *WorkspaceRepository) CreateInvitation(invitation *models.WorkspaceInvitation) error {
+	query, args, err := r.sq.Insert("workspace_invitations").
+		Columns("workspace_id", "inviter_id", "invitee_id", "role", "status", "created_at").
+		Values(invitation.WorkspaceID, invitation.InviterID, invitation.InviteeID, invitation.Role, models.InvitationPending, time.Now()).
+		Suffix("RETURNING id, status, created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", invitation.WorkspaceID).
+			Int("invitee_id", invitation.InviteeID).
+			Err(err).
+			Msg("Failed to build CreateInvitation query")
+		return err
+	}
+
+	err = r.db.QueryRow(query, args...).Scan(&invitation.ID, &invitation.Status, &invitation.CreatedAt)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("CreateInvitation DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) GetInvitation(id int) (*models.WorkspaceInvitation, error) {
+	return r.getInvitation(squirrel.Eq{"i.id": id}, "GetInvitation")
+}
+
+// GetPendingInvitation returns the open invitation of the user to the workspace, nil if there is none
+func (r *WorkspaceRepository) GetPendingInvitation(workspaceID, inviteeID int) (*models.WorkspaceInvitation, error) {
+	return r.getInvitation(squirrel.Eq{
+		"i.workspace_id": workspaceID,
+		"i.invitee_id":   inviteeID,
+		"i.status":       models.InvitationPending,
+	}, "GetPendingInvitation")
+}
+
+func (r *WorkspaceRepository) GetPendingInvitationsByUserID(userID int) ([]models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+
+	query, args, err := r.sq.Select(invitationColumns...).
+		From("workspace_invitations i").
+		Join("workspaces w ON w.id = i.workspace_id").
+		Where(squirrel.Eq{"i.invitee_id": userID, "i.status": models.InvitationPending}).
+		OrderBy("i.created_at").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build GetPendingInvitationsByUserID query")
+		return invitations, err
+	}
+
+	err = r.db.Select(&invitations, query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("GetPendingInvitationsByUserID DB execution error")
+		return invitations, err
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation closes a pending invitation and adds the invitee to the workspace
+func (r *WorkspaceRepository) AcceptInvitation(invitation *models.WorkspaceInvitation, now time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		r.log.Error().Err(err).Msg("AcceptInvitation begin transaction error")
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.respond(tx, invitation.ID, models.InvitationAccepted, now); err != nil {
+		return err
+	}
+
+	if err := r.addMember(tx, invitation.WorkspaceID, invitation.InviteeID, invitation.Role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *WorkspaceRepository) DeclineInvitation(id int, now time.Time) error {
+	return r.respond(r.db, id, models.InvitationDeclined, now)
+}
+
+func (r *WorkspaceRepository) getInvitation(where squirrel.Sqlizer, name string) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+
+	query, args, err := r.sq.Select(invitationColumns...).
+		From("workspace_invitations i").
+		Join("workspaces w ON w.id = i.workspace_id").
+		Where(where).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Err(err).
+			Msgf("Failed to build %s query", name)
+		return nil, err
+	}
+
+	err = r.db.Get(&invitation, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// respond moves a pending invitation to the given status, only once
+func (r *WorkspaceRepository) respond(e sqlx.Execer, id int, status string, now time.Time) error {
+	query, args, err := r.sq.Update("workspace_invitations").
+		Set("status", status).
+		Set("responded_at", now).
+		Where(squirrel.Eq{"id": id, "status": models.InvitationPending}).
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("invitation_id", id).
+			Err(err).
+			Msg("Failed to build RespondInvitation query")
+		return err
+	}
+
+	result, err := e.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("RespondInvitation DB execution error")
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) addMember(e sqlx.Execer, workspaceID, userID int, role models.WorkspaceRole) error {
+	query, args, err := r.sq.Insert("workspace_members").
+		Columns("workspace_id", "user_id", "role", "joined_at").
+		Values(workspaceID, userID, role, time.Now()).
+		Suffix("ON CONFLICT (workspace_id, user_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		r.log.Error().
+			Int("workspace_id", workspaceID).
+			Int("user_id", userID).
+			Err(err).
+			Msg("Failed to build AddMember query")
+		return err
+	}
+
+	if _, err := e.Exec(query, args...); err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msg("AddMember DB execution error")
+		return err
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) execAffecting(query string, args []interface{}, name string) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error().
+			Str("query", query).
+			Interface("args", args).
+			Err(err).
+			Msgf("%s DB execution error", name)
+		return err
+	}
+
+	c, _ := result.RowsAffected()
+	if c == 0 {
+		return ErrNoRowsUpdated
+	}
+
+	return nil
+}
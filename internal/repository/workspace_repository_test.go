@@ -0,0 +1,180 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewWorkspaceMock(t *testing.T) (sqlmock.Sqlmock, *repository.WorkspaceRepository) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return mock, repository.NewWorkspaceRepository(sqlx.NewDb(mockDB, "sqlmock"))
+}
+
+func TestCreateWorkspace(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO workspaces").
+		WithArgs("Team", 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+	mock.ExpectExec("INSERT INTO workspace_members (.+) ON CONFLICT").
+		WithArgs(10, 1, models.RoleOwner, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	workspace := &models.Workspace{Name: "Team"}
+	err := repo.CreateWorkspace(workspace, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, workspace.ID)
+	assert.Equal(t, models.RoleOwner, workspace.Role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateWorkspaceRollsBack(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO workspaces").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+	mock.ExpectExec("INSERT INTO workspace_members").
+		WillReturnError(errors.New("fk violation"))
+	mock.ExpectRollback()
+
+	err := repo.CreateWorkspace(&models.Workspace{Name: "Team"}, 1)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberRole(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectQuery("SELECT role FROM workspace_members WHERE user_id = (.+) AND workspace_id = (.+)").
+		WithArgs(3, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("viewer"))
+
+	role, err := repo.GetMemberRole(10, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberRoleNotMember(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectQuery("SELECT role FROM workspace_members").
+		WithArgs(5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+
+	role, err := repo.GetMemberRole(10, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWorkspacesByUserID(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "created_by", "created_at", "role"}).
+		AddRow(10, "Team", 1, time.Now(), "member")
+	mock.ExpectQuery("SELECT (.+) FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = (.+)").
+		WithArgs(3).
+		WillReturnRows(rows)
+
+	workspaces, err := repo.GetWorkspacesByUserID(3)
+	assert.NoError(t, err)
+	assert.Len(t, workspaces, 1)
+	assert.Equal(t, models.RoleMember, workspaces[0].Role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveMember(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM workspace_members").
+		WithArgs(3, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_watchers WHERE user_id = (.+) AND task_id IN \\(SELECT id FROM tasks WHERE workspace_id = (.+)\\)").
+		WithArgs(3, 10).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE tasks SET assignee_id = (.+) WHERE assignee_id = (.+) AND workspace_id = (.+)").
+		WithArgs(nil, 3, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.RemoveMember(10, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveMemberNotFound(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM workspace_members").
+		WithArgs(3, 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.RemoveMember(10, 3)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE workspace_invitations SET status = (.+), responded_at = (.+) WHERE id = (.+) AND status = (.+)").
+		WithArgs(models.InvitationAccepted, now, 7, models.InvitationPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO workspace_members").
+		WithArgs(10, 9, models.RoleViewer, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.AcceptInvitation(&models.WorkspaceInvitation{ID: 7, WorkspaceID: 10, InviteeID: 9, Role: models.RoleViewer}, now)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitationAlreadyAnswered(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE workspace_invitations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.AcceptInvitation(&models.WorkspaceInvitation{ID: 7, WorkspaceID: 10, InviteeID: 9, Role: models.RoleViewer}, now)
+	assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPendingInvitationsByUserID(t *testing.T) {
+	mock, repo := NewWorkspaceMock(t)
+
+	rows := sqlmock.NewRows([]string{"id", "workspace_id", "workspace_name", "inviter_id", "invitee_id", "role", "status", "created_at", "responded_at"}).
+		AddRow(7, 10, "Team", 1, 9, "member", "pending", time.Now(), nil)
+	mock.ExpectQuery("SELECT (.+) FROM workspace_invitations i JOIN workspaces w ON w.id = i.workspace_id WHERE i.invitee_id = (.+) AND i.status = (.+)").
+		WithArgs(9, models.InvitationPending).
+		WillReturnRows(rows)
+
+	invitations, err := repo.GetPendingInvitationsByUserID(9)
+	assert.NoError(t, err)
+	assert.Len(t, invitations, 1)
+	assert.Equal(t, "Team", invitations[0].WorkspaceName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
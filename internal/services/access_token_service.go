@@ -0,0 +1,102 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+// accessTokenPrefix marks personal access tokens so they are easy to spot in
+// configuration files and secret scanners
+const accessTokenPrefix = "tdp_"
+
+type IAccessTokenRepository interface {
+	CreateAccessToken(token *models.PersonalAccessToken) error
+	GetAccessTokens(userID int) ([]models.PersonalAccessToken, error)
+	DeleteAccessToken(id, userID int) error
+	UseAccessToken(tokenHash string, now time.Time) (*models.PersonalAccessToken, error)
+}
+
+type IAccessTokenUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+// AccessTokenService manages the personal access tokens of users and
+// authenticates clients that present one
+type AccessTokenService struct {
+	repo  IAccessTokenRepository
+	users IAccessTokenUserRepository
+	clock clock.Clock
+}
+
+func NewAccessTokenService(repo IAccessTokenRepository, users IAccessTokenUserRepository, clock clock.Clock) *AccessTokenService {
+	return &AccessTokenService{repo: repo, users: users, clock: clock}
+}
+
+// CreateToken issues a named token, the returned token is the only place its
+// value appears
+func (s *AccessTokenService) CreateToken(userID int, name string) (*models.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if err := helpers.ValidateAccessTokenName(name); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, tokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	value := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	token := &models.PersonalAccessToken{UserID: userID, Name: name, TokenHash: hashToken(value)}
+	if err := s.repo.CreateAccessToken(token); err != nil {
+		return nil, err
+	}
+
+	token.Token = value
+	return token, nil
+}
+
+func (s *AccessTokenService) ListTokens(userID int) ([]models.PersonalAccessToken, error) {
+	return s.repo.GetAccessTokens(userID)
+}
+
+func (s *AccessTokenService) DeleteToken(id, userID int) error {
+	err := s.repo.DeleteAccessToken(id, userID)
+	if errors.Is(err, repository.ErrNoRowsUpdated) {
+		return ErrAccessTokenNotFound
+	}
+	return err
+}
+
+// Authenticate returns the user of a token presented with HTTP Basic auth. The
+// username must be the one of the token owner
+func (s *AccessTokenService) Authenticate(username, token string) (*models.User, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, ErrInvalidCredentials
+	}
+
+	stored, err := s.repo.UseAccessToken(hashToken(token), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.users.GetUserByID(stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !strings.EqualFold(user.Username, username) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
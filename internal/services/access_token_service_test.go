@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAccessTokenRepo struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenRepo) CreateAccessToken(token *models.PersonalAccessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepo) GetAccessTokens(userID int) ([]models.PersonalAccessToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenRepo) DeleteAccessToken(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepo) UseAccessToken(tokenHash string, now time.Time) (*models.PersonalAccessToken, error) {
+	args := m.Called(tokenHash, now)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	repo := new(MockAccessTokenRepo)
+	service := services.NewAccessTokenService(repo, new(MockUserRepo), &FakeClock{now: time.Now()})
+
+	repo.On("CreateAccessToken", mock.MatchedBy(func(token *models.PersonalAccessToken) bool {
+		return token.UserID == 1 && token.Name == "Phone"
+	})).Return(nil)
+
+	token, err := service.CreateToken(1, "  Phone ")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token.Token, "tdp_"))
+
+	sum := sha256.Sum256([]byte(token.Token))
+	assert.Equal(t, hex.EncodeToString(sum[:]), token.TokenHash, "only the hash is stored")
+
+	_, err = service.CreateToken(1, " ")
+	assert.ErrorContains(t, err, "validation failed")
+	repo.AssertNumberOfCalls(t, "CreateAccessToken", 1)
+}
+
+func TestDeleteAccessToken(t *testing.T) {
+	repo := new(MockAccessTokenRepo)
+	service := services.NewAccessTokenService(repo, new(MockUserRepo), &FakeClock{now: time.Now()})
+
+	repo.On("DeleteAccessToken", 3, 1).Return(nil)
+	repo.On("DeleteAccessToken", 4, 1).Return(repository.ErrNoRowsUpdated)
+
+	assert.NoError(t, service.DeleteToken(3, 1))
+	assert.ErrorIs(t, service.DeleteToken(4, 1), services.ErrAccessTokenNotFound)
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	value := "tdp_secret"
+	sum := sha256.Sum256([]byte(value))
+	hash := hex.EncodeToString(sum[:])
+
+	repo := new(MockAccessTokenRepo)
+	users := new(MockUserRepo)
+	service := services.NewAccessTokenService(repo, users, clock)
+
+	repo.On("UseAccessToken", hash, clock.now).Return(&models.PersonalAccessToken{ID: 3, UserID: 1}, nil)
+	repo.On("UseAccessToken", mock.Anything, clock.now).Return((*models.PersonalAccessToken)(nil), nil)
+	users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice"}, nil)
+
+	user, err := service.Authenticate("Alice", value)
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	for _, tc := range []struct {
+		name     string
+		username string
+		token    string
+	}{
+		{"other user", "bob", value},
+		{"unknown token", "alice", "tdp_other"},
+		{"password", "alice", "secret"},
+	} {
+		_, err := service.Authenticate(tc.username, tc.token)
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials, tc.name)
+	}
+	repo.AssertNumberOfCalls(t, "UseAccessToken", 3)
+}
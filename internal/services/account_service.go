@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const tokenSize = 32
+
+type IAccountRepository interface {
+	GetUserByID(id int) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	EmailExists(email string, excludeUserID int) (bool, error)
+	SetEmail(userID int, email string) error
+	MarkEmailVerified(userID int) error
+	UpdatePasswordHash(userID int, passwordHash string) error
+}
+
+type ITokenRepository interface {
+	CreateToken(token *models.UserToken) error
+	FindToken(tokenHash, purpose string, now time.Time) (*models.UserToken, error)
+	ConsumeToken(tokenHash, purpose string, now time.Time) (int, error)
+	DeleteTokens(userID int, purpose string) error
+}
+
+// AccountService handles email verification and password reset. Tokens are
+// single-use, expiring and stored as SHA-256 hashes
+type AccountService struct {
+	repo    IAccountRepository
+	tokens  ITokenRepository
+	mailer  mailer.Mailer
+	hasher  IPasswordHasher
+	policy  config.PasswordPolicy
+	mailCfg config.Mail
+	clock   clock.Clock
+	log     zerolog.Logger
+}
+
+func NewAccountService(
+	repo IAccountRepository,
+	tokens ITokenRepository,
+	mailer mailer.Mailer,
+	hasher IPasswordHasher,
+	policy config.PasswordPolicy,
+	mailCfg config.Mail,
+	clock clock.Clock,
+) *AccountService {
+	return &AccountService{
+		repo:    repo,
+		tokens:  tokens,
+		mailer:  mailer,
+		hasher:  hasher,
+		policy:  policy,
+		mailCfg: mailCfg,
+		clock:   clock,
+		log:     logger.GetLogger(),
+	}
+}
+
+// SetEmail changes the address of the user and sends a verification link to it
+func (s *AccountService) SetEmail(userID int, email string) error {
+	if err := helpers.ValidateEmail(email); err != nil {
+		return err
+	}
+
+	exists, err := s.repo.EmailExists(email, userID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return repository.ErrUniqueEmail
+	}
+
+	if err := s.repo.SetEmail(userID, email); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+
+	return s.SendVerification(user)
+}
+
+func (s *AccountService) ResendVerification(userID int) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	return s.SendVerification(user)
+}
+
+// SendVerification issues a new verification token, older ones stop working
+func (s *AccountService) SendVerification(user *models.User) error {
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+
+	token, err := s.issueToken(user.ID, models.TokenPurposeEmailVerification, s.mailCfg.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := buildLink(s.mailCfg.VerifyURL, token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, link, s.mailCfg.VerificationTTL),
+		HTML: fmt.Sprintf(`<p>Hi %s,</p><p>Please confirm your email address:</p><p><a href="%s">Confirm email</a></p><p>The link expires in %s.</p>`,
+			html.EscapeString(user.Username), html.EscapeString(link), s.mailCfg.VerificationTTL),
+	})
+}
+
+func (s *AccountService) VerifyEmail(token string) error {
+	userID, err := s.tokens.ConsumeToken(hashToken(token), models.TokenPurposeEmailVerification, s.clock.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	return s.repo.MarkEmailVerified(userID)
+}
+
+// RequestPasswordReset mails a reset link if the address belongs to a user
+// with a verified email. It doesn't reveal whether the address is known
+func (s *AccountService) RequestPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			s.log.Info().Str("email", email).Msg("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	if !user.EmailVerified() {
+		s.log.Info().Int("user_id", user.ID).Msg("Password reset requested for unverified email")
+		return nil
+	}
+
+	token, err := s.issueToken(user.ID, models.TokenPurposePasswordReset, s.mailCfg.ResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := buildLink(s.mailCfg.ResetURL, token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account. Open the link below to choose a new password:\n\n%s\n\nThe link expires in %s. If it wasn't you, ignore this email.\n",
+			user.Username, link, s.mailCfg.ResetTTL),
+		HTML: fmt.Sprintf(`<p>Hi %s,</p><p>Someone requested a password reset for your account.</p><p><a href="%s">Choose a new password</a></p><p>The link expires in %s. If it wasn't you, ignore this email.</p>`,
+			html.EscapeString(user.Username), html.EscapeString(link), s.mailCfg.ResetTTL),
+	})
+}
+
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	hash := hashToken(token)
+	now := s.clock.Now()
+
+	found, err := s.tokens.FindToken(hash, models.TokenPurposePasswordReset, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(found.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidToken
+	}
+
+	if err := helpers.ValidatePassword(s.policy, user.Username, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.tokens.ConsumeToken(hash, models.TokenPurposePasswordReset, now); err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePasswordHash(user.ID, passwordHash); err != nil {
+		return err
+	}
+
+	if err := s.tokens.DeleteTokens(user.ID, models.TokenPurposePasswordReset); err != nil {
+		s.log.Error().Err(err).Int("user_id", user.ID).Msg("Failed to delete remaining reset tokens")
+	}
+
+	s.log.Info().Int("user_id", user.ID).Msg("Password reset")
+	return nil
+}
+
+func (s *AccountService) issueToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	return issueUserToken(s.tokens, userID, purpose, s.clock.Now().Add(ttl))
+}
+
+// issueUserToken stores the hash of a new random token for the purpose and
+// returns the token, the user's older tokens of the purpose are deleted
+func issueUserToken(tokens ITokenRepository, userID int, purpose string, expiresAt time.Time) (string, error) {
+	buf := make([]byte, tokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	if err := storeUserToken(tokens, userID, purpose, token, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// storeUserToken replaces the user's tokens of the purpose with the hash of token
+func storeUserToken(tokens ITokenRepository, userID int, purpose, token string, expiresAt time.Time) error {
+	if err := tokens.DeleteTokens(userID, purpose); err != nil {
+		return err
+	}
+
+	return tokens.CreateToken(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	})
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func buildLink(template, token string) string {
+	return strings.ReplaceAll(template, "{token}", url.QueryEscape(token))
+}
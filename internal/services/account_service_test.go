@@ -0,0 +1,289 @@
+package services_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenRepo struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepo) CreateToken(token *models.UserToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepo) FindToken(tokenHash, purpose string, now time.Time) (*models.UserToken, error) {
+	args := m.Called(tokenHash, purpose, now)
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *MockTokenRepo) ConsumeToken(tokenHash, purpose string, now time.Time) (int, error) {
+	args := m.Called(tokenHash, purpose, now)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTokenRepo) GetTokens(userID int, purpose string) ([]models.UserToken, error) {
+	args := m.Called(userID, purpose)
+	return args.Get(0).([]models.UserToken), args.Error(1)
+}
+
+func (m *MockTokenRepo) DeleteTokens(userID int, purpose string) error {
+	args := m.Called(userID, purpose)
+	return args.Error(0)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(msg mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+var testMailConfig = config.Mail{
+	VerifyURL:       "http://localhost:8080/api/auth/email/verify?token={token}",
+	ResetURL:        "http://localhost:3000/reset?token={token}",
+	VerificationTTL: 24 * time.Hour,
+	ResetTTL:        time.Hour,
+}
+
+// tokenFromMessage extracts the raw token from the link in a sent message
+func tokenFromMessage(t *testing.T, msg mailer.Message) string {
+	for _, field := range strings.Fields(msg.Text) {
+		if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatal("no token link in message")
+	return ""
+}
+
+func newAccountService(repo *MockUserRepo, tokens *MockTokenRepo, mail *MockMailer, clock *FakeClock) *services.AccountService {
+	return services.NewAccountService(repo, tokens, mail, testHasher, strictPolicy, testMailConfig, clock)
+}
+
+func TestSetEmail(t *testing.T) {
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, mockTokens, mockMailer := new(MockUserRepo), new(MockTokenRepo), new(MockMailer)
+		service := newAccountService(mockRepo, mockTokens, mockMailer, clock)
+
+		user := &models.User{ID: 1, Username: "alice", Email: "alice@example.com"}
+		mockRepo.On("EmailExists", "alice@example.com", 1).Return(false, nil)
+		mockRepo.On("SetEmail", 1, "alice@example.com").Return(nil)
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockTokens.On("DeleteTokens", 1, models.TokenPurposeEmailVerification).Return(nil)
+		mockTokens.On("CreateToken", mock.MatchedBy(func(token *models.UserToken) bool {
+			return token.UserID == 1 &&
+				token.Purpose == models.TokenPurposeEmailVerification &&
+				token.ExpiresAt.Equal(clock.now.Add(24*time.Hour))
+		})).Return(nil)
+
+		var sent mailer.Message
+		mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(mailer.Message)
+		}).Return(nil)
+
+		err := service.SetEmail(1, "alice@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", sent.To)
+
+		// only the hash of the mailed token is stored
+		token := tokenFromMessage(t, sent)
+		stored := mockTokens.Calls[1].Arguments.Get(0).(*models.UserToken)
+		assert.NotEqual(t, token, stored.TokenHash)
+		assert.Len(t, stored.TokenHash, 64)
+		mockRepo.AssertExpectations(t)
+		mockTokens.AssertExpectations(t)
+	})
+
+	t.Run("Invalid email", func(t *testing.T) {
+		service := newAccountService(new(MockUserRepo), new(MockTokenRepo), new(MockMailer), clock)
+
+		err := service.SetEmail(1, "not an email")
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Email taken", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := newAccountService(mockRepo, new(MockTokenRepo), new(MockMailer), clock)
+
+		mockRepo.On("EmailExists", "bob@example.com", 1).Return(true, nil)
+
+		err := service.SetEmail(1, "bob@example.com")
+		assert.ErrorIs(t, err, repository.ErrUniqueEmail)
+		mockRepo.AssertNotCalled(t, "SetEmail", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Already verified", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := newAccountService(mockRepo, new(MockTokenRepo), new(MockMailer), clock)
+
+		verifiedAt := clock.now
+		mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+
+		err := service.ResendVerification(1)
+		assert.ErrorIs(t, err, services.ErrEmailAlreadyVerified)
+	})
+
+	t.Run("No email", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := newAccountService(mockRepo, new(MockTokenRepo), new(MockMailer), clock)
+
+		mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+
+		err := service.ResendVerification(1)
+		assert.ErrorIs(t, err, services.ErrNoEmail)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, mockTokens := new(MockUserRepo), new(MockTokenRepo)
+		service := newAccountService(mockRepo, mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("ConsumeToken", mock.Anything, models.TokenPurposeEmailVerification, clock.now).Return(1, nil)
+		mockRepo.On("MarkEmailVerified", 1).Return(nil)
+
+		err := service.VerifyEmail("token")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired or used token", func(t *testing.T) {
+		mockRepo, mockTokens := new(MockUserRepo), new(MockTokenRepo)
+		service := newAccountService(mockRepo, mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("ConsumeToken", mock.Anything, models.TokenPurposeEmailVerification, clock.now).Return(0, repository.ErrTokenNotFound)
+
+		err := service.VerifyEmail("token")
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+	})
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Verified email", func(t *testing.T) {
+		mockRepo, mockTokens, mockMailer := new(MockUserRepo), new(MockTokenRepo), new(MockMailer)
+		service := newAccountService(mockRepo, mockTokens, mockMailer, clock)
+
+		verifiedAt := clock.now
+		user := &models.User{ID: 1, Username: "alice", Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+		mockRepo.On("GetUserByEmail", "alice@example.com").Return(user, nil)
+		mockTokens.On("DeleteTokens", 1, models.TokenPurposePasswordReset).Return(nil)
+		mockTokens.On("CreateToken", mock.MatchedBy(func(token *models.UserToken) bool {
+			return token.Purpose == models.TokenPurposePasswordReset && token.ExpiresAt.Equal(clock.now.Add(time.Hour))
+		})).Return(nil)
+		mockMailer.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+			return msg.To == "alice@example.com" && strings.Contains(msg.Text, "http://localhost:3000/reset?token=")
+		})).Return(nil)
+
+		err := service.RequestPasswordReset("alice@example.com")
+		assert.NoError(t, err)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockRepo, mockMailer := new(MockUserRepo), new(MockMailer)
+		service := newAccountService(mockRepo, new(MockTokenRepo), mockMailer, clock)
+
+		mockRepo.On("GetUserByEmail", "nobody@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
+
+		err := service.RequestPasswordReset("nobody@example.com")
+		assert.NoError(t, err)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		mockRepo, mockMailer := new(MockUserRepo), new(MockMailer)
+		service := newAccountService(mockRepo, new(MockTokenRepo), mockMailer, clock)
+
+		mockRepo.On("GetUserByEmail", "alice@example.com").Return(&models.User{ID: 1, Email: "alice@example.com"}, nil)
+
+		err := service.RequestPasswordReset("alice@example.com")
+		assert.NoError(t, err)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+	user := &models.User{ID: 1, Username: "alice"}
+	found := &models.UserToken{ID: 5, UserID: 1, Purpose: models.TokenPurposePasswordReset}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, mockTokens := new(MockUserRepo), new(MockTokenRepo)
+		service := newAccountService(mockRepo, mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("FindToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return(found, nil)
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockTokens.On("ConsumeToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return(1, nil)
+		mockRepo.On("UpdatePasswordHash", 1, mock.MatchedBy(func(hash string) bool {
+			ok, _ := testHasher.Verify(hash, "N3w-Passw0rd")
+			return ok
+		})).Return(nil)
+		mockTokens.On("DeleteTokens", 1, models.TokenPurposePasswordReset).Return(nil)
+
+		err := service.ResetPassword("token", "N3w-Passw0rd")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockTokens.AssertExpectations(t)
+	})
+
+	t.Run("Weak password keeps token", func(t *testing.T) {
+		mockRepo, mockTokens := new(MockUserRepo), new(MockTokenRepo)
+		service := newAccountService(mockRepo, mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("FindToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return(found, nil)
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+
+		err := service.ResetPassword("token", "short")
+		assert.ErrorAs(t, err, &baseErr)
+		mockTokens.AssertNotCalled(t, "ConsumeToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockTokens := new(MockTokenRepo)
+		service := newAccountService(new(MockUserRepo), mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("FindToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return((*models.UserToken)(nil), repository.ErrTokenNotFound)
+
+		err := service.ResetPassword("token", "N3w-Passw0rd")
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("Token consumed concurrently", func(t *testing.T) {
+		mockRepo, mockTokens := new(MockUserRepo), new(MockTokenRepo)
+		service := newAccountService(mockRepo, mockTokens, new(MockMailer), clock)
+
+		mockTokens.On("FindToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return(found, nil)
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockTokens.On("ConsumeToken", mock.Anything, models.TokenPurposePasswordReset, clock.now).Return(0, repository.ErrTokenNotFound)
+
+		err := service.ResetPassword("token", "N3w-Passw0rd")
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "UpdatePasswordHash", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+)
+
+const (
+	maxImportedTitle   = 100
+	maxImportedTag     = 50
+	maxImportedTags    = 20
+	maxImportedComment = 10000
+)
+
+// Importer reads the export file of another app. The file name tells the
+// format when an app exports more than one
+type Importer interface {
+	Parse(r io.Reader, fileName string) (*models.AppImport, error)
+}
+
+type IAppImportRepository interface {
+	IExternalIDRepository
+	ImportAppTasks(tasks []models.ImportedTask, batchSize int) (int, error)
+}
+
+// AppImportService brings the projects, tasks and comments of other apps in
+type AppImportService struct {
+	repo       IAppImportRepository
+	workspaces IWorkspaceMembership
+	importers  map[string]Importer
+	cfg        config.Import
+}
+
+func NewAppImportService(repo IAppImportRepository, workspaces IWorkspaceMembership, cfg config.Import) *AppImportService {
+	return &AppImportService{
+		repo:       repo,
+		workspaces: workspaces,
+		importers: map[string]Importer{
+			models.ImportSourceTodoist: TodoistImporter{},
+			models.ImportSourceTrello:  TrelloImporter{},
+			models.ImportSourceMSTodo:  MSTodoImporter{},
+		},
+		cfg: cfg,
+	}
+}
+
+// Import converts the export file to tasks and stores them in one transaction
+// unless it is a dry run, the report of a dry run previews the import. Tasks
+// that fail validation are skipped with a warning, tasks already imported by
+// the user are skipped as duplicates
+func (s *AppImportService) Import(r io.Reader, req models.AppImportRequest) (*models.AppImportReport, error) {
+	importer, ok := s.importers[req.Source]
+	if !ok {
+		return nil, ErrUnknownImportSource
+	}
+
+	if req.WorkspaceID != nil {
+		if _, err := authorizeWorkspace(s.workspaces, *req.WorkspaceID, req.UserID, models.RoleMember); err != nil {
+			return nil, err
+		}
+	}
+
+	parsed, err := importer.Parse(r, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.MaxRows > 0 && len(parsed.Tasks) > s.cfg.MaxRows {
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("file", fmt.Sprintf("more than %d tasks", s.cfg.MaxRows)))
+	}
+
+	report := &models.AppImportReport{
+		Source:   req.Source,
+		DryRun:   req.DryRun,
+		Projects: parsed.Projects,
+		Labels:   parsed.Labels,
+		Warnings: append([]string{}, parsed.Warnings...),
+	}
+
+	tasks := make([]models.ImportedTask, 0, len(parsed.Tasks))
+	seen := make(map[string]bool)
+	for i := range parsed.Tasks {
+		task := &parsed.Tasks[i]
+		report.Warnings = append(report.Warnings, prepareImportedTask(task)...)
+
+		if err := helpers.ValidateTaskFields(&task.Task); err != nil {
+			report.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("task %q skipped: %s", task.Title, rowError(err)))
+			continue
+		}
+
+		if task.ExternalID != nil {
+			if seen[*task.ExternalID] {
+				report.Duplicates++
+				continue
+			}
+			seen[*task.ExternalID] = true
+		}
+
+		task.UserID = req.UserID
+		task.WorkspaceID = req.WorkspaceID
+		tasks = append(tasks, *task)
+	}
+
+	tasks, duplicates, err := skipImported(s.repo, req.UserID, tasks, func(task models.ImportedTask) *string { return task.ExternalID })
+	if err != nil {
+		return nil, err
+	}
+	report.Duplicates += duplicates
+
+	report.Tasks = len(tasks)
+	for _, task := range tasks {
+		report.ChecklistItems += len(task.Checklist)
+		report.Comments += len(task.Comments)
+	}
+
+	if req.DryRun || len(tasks) == 0 {
+		return report, nil
+	}
+
+	created, err := s.repo.ImportAppTasks(tasks, s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Duplicates += len(tasks) - created
+	report.Created = created
+	return report, nil
+}
+
+// prepareImportedTask fits a task of another app into the limits of ours: long
+// titles are shortened and kept whole in the description, project and label
+// names become tags, the checklist is appended to the description as a
+// markdown task list and comments name their original author
+func prepareImportedTask(task *models.ImportedTask) []string {
+	var warnings []string
+
+	task.Title = strings.Join(strings.Fields(task.Title), " ")
+	task.Description = strings.TrimSpace(task.Description)
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+
+	if len(task.Title) > maxImportedTitle {
+		full := task.Title
+		task.Title = truncateBytes(full, maxImportedTitle-len("…")) + "…"
+		task.Description = strings.TrimSpace(full + "\n\n" + task.Description)
+		warnings = append(warnings, fmt.Sprintf("title of task %q shortened", task.Title))
+	}
+
+	tags := make(models.StringList, 0, len(task.Tags))
+	for _, name := range task.Tags {
+		tag := importedTag(name)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxImportedTags {
+		warnings = append(warnings, fmt.Sprintf("task %q has more than %d labels, the rest are dropped", task.Title, maxImportedTags))
+		tags = tags[:maxImportedTags]
+	}
+	task.Tags = tags
+
+	if len(task.Checklist) > 0 {
+		var b strings.Builder
+		for _, item := range task.Checklist {
+			mark := " "
+			if item.Checked {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", mark, strings.Join(strings.Fields(item.Text), " "))
+		}
+		task.Description = strings.TrimSpace(task.Description + "\n\n" + b.String())
+	}
+
+	comments := task.Comments[:0]
+	for _, comment := range task.Comments {
+		body := strings.TrimSpace(comment.Body)
+		if body == "" {
+			continue
+		}
+		if author := strings.TrimSpace(comment.Author); author != "" {
+			body = fmt.Sprintf("**%s**: %s", author, body)
+		}
+		if utf8.RuneCountInString(body) > maxImportedComment {
+			body = string([]rune(body)[:maxImportedComment])
+			warnings = append(warnings, fmt.Sprintf("comment on task %q shortened", task.Title))
+		}
+		comment.Body = body
+		comments = append(comments, comment)
+	}
+	task.Comments = comments
+
+	return warnings
+}
+
+// importedTag turns a project, list or label name into a tag: runs of spaces
+// and commas become a dash
+func importedTag(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	tag := strings.Join(words, "-")
+	if utf8.RuneCountInString(tag) > maxImportedTag {
+		tag = string([]rune(tag)[:maxImportedTag])
+	}
+	return tag
+}
+
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// appTimeLayouts are the layouts of the dates in app exports, times without
+// an offset are read in the location passed to parseAppTime
+var appTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.9999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseAppTime(raw string, loc *time.Location) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range appTimeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func appJSONTime(t time.Time) *models.JSONTime {
+	jt := models.JSONTime(t)
+	return &jt
+}
@@ -0,0 +1,252 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAppImportRepo struct {
+	mock.Mock
+}
+
+func (m *MockAppImportRepo) GetExternalIDs(userID int, externalIDs []string) ([]string, error) {
+	args := m.Called(userID, externalIDs)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAppImportRepo) ImportAppTasks(tasks []models.ImportedTask, batchSize int) (int, error) {
+	args := m.Called(tasks, batchSize)
+	return args.Int(0), args.Error(1)
+}
+
+var appImportConfig = config.Import{MaxRows: 10, BatchSize: 5}
+
+// importFixture imports a file of testdata with the given number of tasks and
+// returns the tasks handed to the repository
+func importFixture(t *testing.T, source, name string, count int) ([]models.ImportedTask, *models.AppImportReport) {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer file.Close()
+
+	var imported []models.ImportedTask
+	repo := new(MockAppImportRepo)
+	repo.On("GetExternalIDs", 1, mock.Anything).Return([]string{}, nil)
+	repo.On("ImportAppTasks", mock.Anything, appImportConfig.BatchSize).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.ImportedTask)
+	}).Return(count, nil)
+
+	service := services.NewAppImportService(repo, new(MockWorkspaceRepo), appImportConfig)
+	report, err := service.Import(file, models.AppImportRequest{UserID: 1, Source: source, FileName: name})
+	require.NoError(t, err)
+	return imported, report
+}
+
+func day(t *models.JSONTime) string {
+	if t == nil {
+		return ""
+	}
+	return time.Time(*t).Format("2006-01-02 15:04")
+}
+
+func commentBodies(task models.ImportedTask) []string {
+	bodies := []string{}
+	for _, comment := range task.Comments {
+		bodies = append(bodies, comment.Body)
+	}
+	return bodies
+}
+
+func TestImportTodoistCSVExternalIDs(t *testing.T) {
+	header := "TYPE,CONTENT,PRIORITY,INDENT\n"
+	before, err := services.TodoistImporter{}.Parse(strings.NewReader(header+
+		"task,Buy paint,1,1\n"+
+		"task,Call Sam,1,1\n"+
+		"task,Call Sam,1,1\n"), "Renovation.csv")
+	require.NoError(t, err)
+
+	// a task added at the top must not change the ids of the others
+	after, err := services.TodoistImporter{}.Parse(strings.NewReader(header+
+		"task,Order tiles,1,1\n"+
+		"task,Buy paint,1,1\n"+
+		"task,Call Sam,1,1\n"+
+		"task,Call Sam,1,1\n"), "Renovation.csv")
+	require.NoError(t, err)
+
+	ids := func(result *models.AppImport) []string {
+		var ids []string
+		for _, task := range result.Tasks {
+			ids = append(ids, *task.ExternalID)
+		}
+		return ids
+	}
+	assert.Equal(t, ids(before), ids(after)[1:])
+	assert.NotEqual(t, ids(before)[1], ids(before)[2])
+
+	other, err := services.TodoistImporter{}.Parse(strings.NewReader(header+"task,Buy paint,1,1\n"), "Garden.csv")
+	require.NoError(t, err)
+	assert.NotEqual(t, ids(before)[0], ids(other)[0])
+}
+
+func TestImportTodoistCSV(t *testing.T) {
+	tasks, report := importFixture(t, models.ImportSourceTodoist, "Renovation.csv", 3)
+
+	assert.Equal(t, 1, report.Projects)
+	assert.Equal(t, 2, report.Labels)
+	assert.Equal(t, 3, report.Tasks)
+	assert.Equal(t, 3, report.Created)
+	assert.Equal(t, 1, report.ChecklistItems)
+	assert.Equal(t, 1, report.Comments)
+	assert.Equal(t, []string{`due date "next monday" of task "Call the electrician" is not a date, kept in the description`}, report.Warnings)
+	require.Len(t, tasks, 3)
+
+	paint := tasks[0]
+	assert.Regexp(t, `^todoist:csv:[0-9a-f]{32}$`, *paint.ExternalID)
+	assert.Equal(t, "Buy paint", paint.Title)
+	assert.Equal(t, "Matte white for the living room\n\n- [ ] Pick colour samples", paint.Description)
+	assert.Equal(t, models.TaskPriorityHigh, paint.Priority)
+	assert.Equal(t, "pending", paint.Status)
+	assert.Equal(t, models.StringList{"Renovation", "errand", "hardware-store"}, paint.Tags)
+	assert.Equal(t, "2026-10-20 00:00", day(paint.DueAt))
+	assert.Equal(t, []string{"**Sam**: Ask for the eco line"}, commentBodies(paint))
+	assert.Equal(t, 1, paint.UserID)
+
+	plants := tasks[1]
+	assert.Equal(t, models.StringList{"Renovation", "Kitchen"}, plants.Tags)
+	assert.Equal(t, models.TaskPriorityLow, plants.Priority)
+	assert.Equal(t, "FREQ=DAILY", plants.Recurrence)
+	assert.Nil(t, plants.DueAt)
+
+	electrician := tasks[2]
+	assert.Equal(t, models.TaskPriorityMedium, electrician.Priority)
+	assert.Equal(t, "Due: next monday", electrician.Description)
+}
+
+func TestImportTodoistJSON(t *testing.T) {
+	tasks, report := importFixture(t, models.ImportSourceTodoist, "todoist_backup.json", 2)
+
+	assert.Equal(t, &models.AppImportReport{
+		Source: models.ImportSourceTodoist, Projects: 2, Labels: 2, Tasks: 2, ChecklistItems: 2, Comments: 2, Created: 2, Warnings: []string{},
+	}, report)
+	require.Len(t, tasks, 2)
+
+	review := tasks[0]
+	assert.Equal(t, "todoist:6X7rM8997g3RQmvh", *review.ExternalID)
+	assert.Equal(t, "Slides and numbers\n\n- [x] Collect sales numbers\n- [ ] Ask finance for the export", review.Description)
+	assert.Equal(t, models.TaskPriorityHigh, review.Priority)
+	assert.Equal(t, models.StringList{"Work", "Q4-Planning", "urgent", "deep-work"}, review.Tags)
+	assert.Equal(t, "2026-10-24 15:00", day(review.DueAt))
+	assert.Equal(t, time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC), time.Time(review.CreatedAt))
+	// the note on a subtask of a subtask lands on the top-level task
+	assert.Equal(t, []string{"**Robin Lee**: Finance is on holiday until Monday"}, commentBodies(review))
+
+	gym := tasks[1]
+	assert.Equal(t, models.TaskStatusDone, gym.Status)
+	assert.Equal(t, "2026-10-10 18:45", day(gym.CompletedAt))
+	assert.Equal(t, "FREQ=MONTHLY", gym.Recurrence)
+	assert.Equal(t, "2026-10-10 00:00", day(gym.DueAt))
+	assert.Equal(t, models.TaskPriorityLow, gym.Priority)
+	assert.Equal(t, []string{"Price went up"}, commentBodies(gym))
+}
+
+func TestImportTrello(t *testing.T) {
+	tasks, report := importFixture(t, models.ImportSourceTrello, "trello_board.json", 2)
+
+	assert.Equal(t, &models.AppImportReport{
+		Source: models.ImportSourceTrello, Projects: 2, Labels: 2, Tasks: 2, ChecklistItems: 3, Comments: 2, Created: 2,
+		Warnings: []string{"2 archived cards left out"},
+	}, report)
+	require.Len(t, tasks, 2)
+
+	release := tasks[0]
+	assert.Equal(t, "trello:c1", *release.ExternalID)
+	assert.Equal(t, models.StringList{"Product-Launch", "Doing", "Marketing", "red"}, release.Tags)
+	assert.Equal(t, "Draft for **Monday**\n\n- [x] Outline\n- [ ] Quote from the CEO\n- [ ] Legal sign-off", release.Description)
+	assert.Equal(t, "2026-10-22 09:00", day(release.DueAt))
+	assert.Equal(t, []string{"**Jo Smith**: First draft done", "**Kim Park**: Second draft is in the drive"}, commentBodies(release))
+	assert.Equal(t, time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC), *release.Comments[0].CreatedAt)
+
+	venue := tasks[1]
+	assert.Equal(t, models.StringList{"Product-Launch", "To-Do"}, venue.Tags)
+	assert.Equal(t, models.TaskStatusDone, venue.Status)
+	assert.Equal(t, "2026-09-30 16:20", day(venue.CompletedAt))
+}
+
+func TestImportMSTodo(t *testing.T) {
+	tasks, report := importFixture(t, models.ImportSourceMSTodo, "mstodo_lists.json", 3)
+
+	assert.Equal(t, &models.AppImportReport{
+		Source: models.ImportSourceMSTodo, Projects: 2, Labels: 1, Tasks: 3, ChecklistItems: 2, Created: 3,
+		Warnings: []string{`recurrence "hourly" of task "File the tax return" is not supported`},
+	}, report)
+	require.Len(t, tasks, 3)
+
+	shop := tasks[0]
+	assert.Equal(t, "mstodo:AlMKXwbQAAAJws6wcAAAA=", *shop.ExternalID)
+	assert.Equal(t, "Don't forget oat milk\n\n- [x] Bread\n- [ ] Apples", shop.Description)
+	assert.Equal(t, models.TaskPriorityHigh, shop.Priority)
+	assert.Equal(t, "pending", shop.Status)
+	assert.Equal(t, models.StringList{"Groceries", "Orange-category"}, shop.Tags)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=SA", shop.Recurrence)
+	assert.Equal(t, "2026-10-21 00:00", day(shop.DueAt))
+
+	tax := tasks[1]
+	assert.Equal(t, models.TaskStatusDone, tax.Status)
+	assert.Equal(t, "2026-09-28 00:00", day(tax.CompletedAt))
+	assert.Empty(t, tax.Recurrence)
+	assert.Equal(t, "", tax.Priority)
+
+	trip := tasks[2]
+	assert.Equal(t, "in progress", trip.Status)
+	assert.Equal(t, models.TaskPriorityLow, trip.Priority)
+}
+
+func TestAppImportPreview(t *testing.T) {
+	board := `{"name": "Board", "lists": [{"id": "l1", "name": "Inbox"}], "cards": [
+		{"id": "c1", "name": "Already here", "idList": "l1"},
+		{"id": "c2", "name": "` + strings.Repeat("Very long title ", 8) + `", "idList": "l1"},
+		{"id": "c3", "name": "   ", "idList": "l1"},
+		{"id": "c2", "name": "Same card twice", "idList": "l1"}
+	]}`
+
+	repo := new(MockAppImportRepo)
+	repo.On("GetExternalIDs", 1, []string{"trello:c1", "trello:c2"}).Return([]string{"trello:c1"}, nil)
+	service := services.NewAppImportService(repo, new(MockWorkspaceRepo), appImportConfig)
+
+	report, err := service.Import(strings.NewReader(board), models.AppImportRequest{
+		UserID: 1, Source: models.ImportSourceTrello, FileName: "board.json", DryRun: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Tasks)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 2, report.Duplicates)
+	assert.Equal(t, []string{
+		`title of task "Very long title Very long title Very long title Very long title Very long title Very long title V…" shortened`,
+		`task "" skipped: field 'title': cannot be blank`,
+	}, report.Warnings)
+	repo.AssertNotCalled(t, "ImportAppTasks", mock.Anything, mock.Anything)
+}
+
+func TestAppImportErrors(t *testing.T) {
+	service := services.NewAppImportService(new(MockAppImportRepo), new(MockWorkspaceRepo), appImportConfig)
+
+	_, err := service.Import(strings.NewReader("{}"), models.AppImportRequest{UserID: 1, Source: "asana"})
+	assert.ErrorIs(t, err, services.ErrUnknownImportSource)
+
+	_, err = service.Import(strings.NewReader(`{"lists": []}`), models.AppImportRequest{UserID: 1, Source: models.ImportSourceTrello})
+	assert.ErrorAs(t, err, &baseErr)
+
+	_, err = service.Import(strings.NewReader("Name,Due\nx,y\n"), models.AppImportRequest{UserID: 1, Source: models.ImportSourceTodoist, FileName: "x.csv"})
+	assert.ErrorAs(t, err, &baseErr)
+}
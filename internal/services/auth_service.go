@@ -1,15 +1,23 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/logger"
 	"github.com/daioru/todo-app/internal/models"
 	"github.com/daioru/todo-app/internal/repository"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/rs/zerolog"
-	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionTTL    = time.Hour * 72
+	mfaPendingTTL = time.Minute * 5
 )
 
 type IUserRepository interface {
@@ -17,21 +25,65 @@ type IUserRepository interface {
 	CreateUser(user *models.User) error
 	GetUserByID(id int) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
+	UpdatePasswordHash(userID int, passwordHash string) error
+	EmailExists(email string, excludeUserID int) (bool, error)
+}
+
+type IEmailVerifier interface {
+	SendVerification(user *models.User) error
+}
+
+type IPasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+type IMFAVerifier interface {
+	Enabled(userID int) (bool, error)
+	Verify(userID int, code string) error
+}
+
+type ILoginThrottler interface {
+	Check(keys ...string) error
+	Fail(keys ...string) error
+	Reset(keys ...string) error
 }
 
 type AuthService struct {
-	repo IUserRepository
-	log  zerolog.Logger
+	repo     IUserRepository
+	mfa      IMFAVerifier
+	throttle ILoginThrottler
+	hasher   IPasswordHasher
+	policy   config.PasswordPolicy
+	verifier IEmailVerifier
+	log      zerolog.Logger
 }
 
-func NewAuthService(repo IUserRepository) *AuthService {
+func NewAuthService(
+	repo IUserRepository,
+	mfa IMFAVerifier,
+	throttle ILoginThrottler,
+	hasher IPasswordHasher,
+	policy config.PasswordPolicy,
+	verifier IEmailVerifier,
+) *AuthService {
 	return &AuthService{
-		repo: repo,
-		log:  logger.GetLogger(),
+		repo:     repo,
+		mfa:      mfa,
+		throttle: throttle,
+		hasher:   hasher,
+		policy:   policy,
+		verifier: verifier,
+		log:      logger.GetLogger(),
 	}
 }
 
 func (s *AuthService) RegisterUser(user *models.User) error {
+	if err := helpers.ValidatePassword(s.policy, user.Username, user.Password); err != nil {
+		return err
+	}
+
 	exists, err := s.repo.UserExists(user)
 	if err != nil {
 		s.log.Error().
@@ -44,29 +96,218 @@ func (s *AuthService) RegisterUser(user *models.User) error {
 		return repository.ErrUniqueUser
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if user.Email != "" {
+		if err := helpers.ValidateEmail(user.Email); err != nil {
+			return err
+		}
+
+		exists, err := s.repo.EmailExists(user.Email, 0)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return repository.ErrUniqueEmail
+		}
+	}
+
+	hashedPassword, err := s.hasher.Hash(user.Password)
 	if err != nil {
 		return err
 	}
-	user.PasswordHash = string(hashedPassword)
-	return s.repo.CreateUser(user)
+	user.PasswordHash = hashedPassword
+
+	if err := s.repo.CreateUser(user); err != nil {
+		return err
+	}
+
+	if user.Email != "" {
+		if err := s.verifier.SendVerification(user); err != nil {
+			s.log.Error().Err(err).Int("user_id", user.ID).Msg("Failed to send verification email")
+		}
+	}
+
+	return nil
 }
 
-func (s *AuthService) LoginUser(username, password string) (string, error) {
+// LoginUser returns a session token. If the user has 2FA enabled it returns
+// a short-lived mfa pending token together with ErrMFARequired instead.
+// Failed attempts are throttled per username and per client ip
+func (s *AuthService) LoginUser(username, password, ip string) (string, error) {
+	keys := []string{UsernameKey(username), IPKey(ip)}
+	if err := s.throttle.Check(keys...); err != nil {
+		return "", err
+	}
+
 	user, err := s.repo.GetUserByUsername(username)
 	if err != nil {
+		s.registerFailure(keys...)
 		return "", repository.ErrUserNotFound
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		s.log.Error().Err(err).Int("user_id", user.ID).Msg("Failed to verify password hash")
+		return "", err
+	}
+	if !ok {
+		s.registerFailure(keys...)
 		return "", ErrInvalidCredentials
 	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	if err := s.throttle.Reset(UsernameKey(username)); err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to reset login attempts")
+	}
+
+	s.upgradeHash(user, password)
+
+	return s.StartSession(user.ID)
+}
+
+// StartSession returns a session token for an authenticated user, or an mfa
+// pending token together with ErrMFARequired if the user has 2FA enabled
+func (s *AuthService) StartSession(userID int) (string, error) {
+	enabled, err := s.mfa.Enabled(userID)
+	if err != nil {
+		s.log.Error().Err(err).Int("user_id", userID).Msg("Failed to check mfa status")
+		return "", err
+	}
+
+	if enabled {
+		token, err := s.signToken(jwt.MapClaims{
+			"mfa_user_id": userID,
+			"exp":         time.Now().Add(mfaPendingTTL).Unix(),
+		})
+		if err != nil {
+			return "", err
+		}
+		return token, ErrMFARequired
+	}
+
+	return s.IssueSession(userID)
+}
+
+// LoginMFA exchanges an mfa pending token and a valid code for a session token
+func (s *AuthService) LoginMFA(mfaToken, code, ip string) (string, error) {
+	token, err := jwt.Parse(mfaToken, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("JWTSECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return "", ErrInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", ErrInvalidMFAToken
+	}
+
+	userID, ok := claims["mfa_user_id"].(float64)
+	if !ok {
+		return "", ErrInvalidMFAToken
+	}
+
+	keys := []string{MFAKey(int(userID)), IPKey(ip)}
+	if err := s.throttle.Check(keys...); err != nil {
+		return "", err
 	}
 
+	if err := s.mfa.Verify(int(userID), code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.registerFailure(keys...)
+		}
+		return "", err
+	}
+
+	if err := s.throttle.Reset(MFAKey(int(userID))); err != nil {
+		s.log.Error().Err(err).Float64("user_id", userID).Msg("Failed to reset mfa attempts")
+	}
+
+	return s.IssueSession(int(userID))
+}
+
+// ChangePassword replaces the password after checking the current one
+func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+
+	key := UsernameKey(user.Username)
+	if err := s.throttle.Check(key); err != nil {
+		return err
+	}
+
+	ok, err := s.hasher.Verify(user.PasswordHash, currentPassword)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.registerFailure(key)
+		return ErrInvalidCredentials
+	}
+
+	if err := helpers.ValidatePassword(s.policy, user.Username, newPassword); err != nil {
+		return err
+	}
+	if newPassword == currentPassword {
+		return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("new_password", "must differ from the current password"))
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePasswordHash(userID, hash); err != nil {
+		return err
+	}
+
+	s.log.Info().Int("user_id", userID).Msg("Password changed")
+	return nil
+}
+
+// upgradeHash transparently rehashes the password after a successful login
+// when the configured algorithm or cost changed. Failures don't block login
+func (s *AuthService) upgradeHash(user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.log.Error().Err(err).Int("user_id", user.ID).Msg("Failed to rehash password")
+		return
+	}
+
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		s.log.Error().Err(err).Int("user_id", user.ID).Msg("Failed to store upgraded password hash")
+		return
+	}
+
+	user.PasswordHash = hash
+	s.log.Info().Int("user_id", user.ID).Msg("Password hash upgraded")
+}
+
+func (s *AuthService) registerFailure(keys ...string) {
+	if err := s.throttle.Fail(keys...); err != nil {
+		s.log.Error().Err(err).Strs("keys", keys).Msg("Failed to register login failure")
+	}
+}
+
+// IssueSession signs a session token for the user
+func (s *AuthService) IssueSession(userID int) (string, error) {
+	return s.signToken(jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(sessionTTL).Unix(),
+	})
+}
+
+func (s *AuthService) signToken(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	signedToken, err := token.SignedString([]byte(os.Getenv("JWTSECRET")))
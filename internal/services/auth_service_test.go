@@ -3,11 +3,17 @@ package services_test
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/password"
 	"github.com/daioru/todo-app/internal/repository"
 	"github.com/daioru/todo-app/internal/services"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
@@ -37,12 +43,116 @@ func (m *MockUserRepo) GetUserByUsername(username string) (*models.User, error)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepo) UpdatePasswordHash(userID int, passwordHash string) error {
+	args := m.Called(userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) EmailExists(email string, excludeUserID int) (bool, error) {
+	args := m.Called(email, excludeUserID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepo) GetUserByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepo) SetEmail(userID int, email string) error {
+	args := m.Called(userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) MarkEmailVerified(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) UpdateUser(userID int, updates map[string]interface{}) error {
+	args := m.Called(userID, updates)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) GetUsersByUsernames(usernames []string) ([]models.User, error) {
+	args := m.Called(usernames)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserRepo) DeleteUser(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockEmailVerifier struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerifier) SendVerification(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+var testHasher = password.NewHasher(config.PasswordHashing{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.DefaultCost})
+
+var testPolicy = config.PasswordPolicy{}
+
+var strictPolicy = config.PasswordPolicy{
+	MinLength:      8,
+	MaxLength:      72,
+	RequireUpper:   true,
+	RequireLower:   true,
+	RequireDigit:   true,
+	RejectUsername: true,
+	RejectCommon:   true,
+}
+
+type MockMFAVerifier struct {
+	mock.Mock
+}
+
+func (m *MockMFAVerifier) Enabled(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAVerifier) Verify(userID int, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+type MockLoginThrottler struct {
+	mock.Mock
+}
+
+func (m *MockLoginThrottler) Check(keys ...string) error {
+	args := m.Called(keys)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottler) Fail(keys ...string) error {
+	args := m.Called(keys)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottler) Reset(keys ...string) error {
+	args := m.Called(keys)
+	return args.Error(0)
+}
+
+func newAllowingThrottler() *MockLoginThrottler {
+	throttle := new(MockLoginThrottler)
+	throttle.On("Check", mock.Anything).Return(nil)
+	throttle.On("Fail", mock.Anything).Return(nil)
+	throttle.On("Reset", mock.Anything).Return(nil)
+	return throttle
+}
+
 func TestRegisterUser(t *testing.T) {
 	user := &models.User{Username: "test username", Password: "test password"}
 
 	t.Run("User already exists", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		mockRepo.On("UserExists", user).Return(true, nil)
 
@@ -55,7 +165,7 @@ func TestRegisterUser(t *testing.T) {
 
 	t.Run("Successful registration", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		mockRepo.On("UserExists", user).Return(false, nil)
 		mockRepo.On("CreateUser", user).Return(nil)
@@ -69,7 +179,7 @@ func TestRegisterUser(t *testing.T) {
 
 	t.Run("Error checking UserExists", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		mockRepo.On("UserExists", user).Return(false, errors.New("some error"))
 
@@ -82,7 +192,7 @@ func TestRegisterUser(t *testing.T) {
 
 	t.Run("Error creating user", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		mockRepo.On("UserExists", user).Return(false, nil)
 		mockRepo.On("CreateUser", user).Return(errors.New("failed to create user"))
@@ -98,11 +208,11 @@ func TestRegisterUser(t *testing.T) {
 func TestLoginUser(t *testing.T) {
 	t.Run("User not found", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		mockRepo.On("GetUserByUsername", "nonexistent").Return((*models.User)(nil), errors.New("user not found"))
 
-		token, err := service.LoginUser("nonexistent", "password")
+		token, err := service.LoginUser("nonexistent", "password", "127.0.0.1")
 		assert.Empty(t, token)
 		assert.ErrorIs(t, err, repository.ErrUserNotFound)
 
@@ -111,14 +221,14 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Invalid password", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.DefaultCost)
 		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(hashedPassword)}
 
 		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
 
-		token, err := service.LoginUser("testuser", "wrong_password")
+		token, err := service.LoginUser("testuser", "wrong_password", "127.0.0.1")
 		assert.Empty(t, token)
 		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
 
@@ -127,19 +237,414 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Successful login", func(t *testing.T) {
 		mockRepo := new(MockUserRepo)
-		service := services.NewAuthService(mockRepo)
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(mockRepo, mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
 
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.DefaultCost)
 		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(hashedPassword)}
 
 		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
 
 		os.Setenv("JWTSECRET", "testsecret") // Устанавливаем секретный ключ
 
-		token, err := service.LoginUser("testuser", "correct_password")
+		token, err := service.LoginUser("testuser", "correct_password", "127.0.0.1")
+		assert.NotEmpty(t, token)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Second factor required", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(mockRepo, mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.DefaultCost)
+		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(hashedPassword)}
+
+		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(true, nil)
+
+		os.Setenv("JWTSECRET", "testsecret")
+
+		token, err := service.LoginUser("testuser", "correct_password", "127.0.0.1")
+		assert.ErrorIs(t, err, services.ErrMFARequired)
+		assert.NotEmpty(t, token)
+
+		claims := jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return []byte("testsecret"), nil })
+		assert.NoError(t, err)
+		assert.NotContains(t, claims, "user_id")
+		assert.Equal(t, float64(1), claims["mfa_user_id"])
+
+		mockRepo.AssertExpectations(t)
+		mockMFA.AssertExpectations(t)
+	})
+}
+
+func TestLoginMFA(t *testing.T) {
+	os.Setenv("JWTSECRET", "testsecret")
+
+	pendingToken := func(t *testing.T, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("testsecret"))
+		assert.NoError(t, err)
+		return token
+	}
+
+	t.Run("Valid code", func(t *testing.T) {
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(new(MockUserRepo), mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		mockMFA.On("Verify", 1, "123456").Return(nil)
+
+		token, err := service.LoginMFA(pendingToken(t, jwt.MapClaims{
+			"mfa_user_id": 1,
+			"exp":         time.Now().Add(time.Minute).Unix(),
+		}), "123456", "127.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+
+		mockMFA.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(new(MockUserRepo), mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		mockMFA.On("Verify", 1, "000000").Return(services.ErrInvalidMFACode)
+
+		token, err := service.LoginMFA(pendingToken(t, jwt.MapClaims{
+			"mfa_user_id": 1,
+			"exp":         time.Now().Add(time.Minute).Unix(),
+		}), "000000", "127.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+		assert.Empty(t, token)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(new(MockUserRepo), mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		_, err := service.LoginMFA(pendingToken(t, jwt.MapClaims{
+			"mfa_user_id": 1,
+			"exp":         time.Now().Add(-time.Minute).Unix(),
+		}), "123456", "127.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidMFAToken)
+		mockMFA.AssertNotCalled(t, "Verify")
+	})
+
+	t.Run("Session token is not accepted", func(t *testing.T) {
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(new(MockUserRepo), mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		_, err := service.LoginMFA(pendingToken(t, jwt.MapClaims{
+			"user_id": 1,
+			"exp":     time.Now().Add(time.Minute).Unix(),
+		}), "123456", "127.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidMFAToken)
+		mockMFA.AssertNotCalled(t, "Verify")
+	})
+}
+
+func TestLoginThrottling(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.DefaultCost)
+	user := &models.User{ID: 1, Username: "TestUser", PasswordHash: string(hashedPassword)}
+	keys := []string{"user:testuser", "ip:10.0.0.1"}
+
+	t.Run("Locked out", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		throttle := new(MockLoginThrottler)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), throttle, testHasher, testPolicy, new(MockEmailVerifier))
+
+		throttle.On("Check", keys).Return(&services.LockedError{RetryAfter: time.Minute})
+
+		token, err := service.LoginUser("TestUser", "correct_password", "10.0.0.1")
+		assert.Empty(t, token)
+		assert.ErrorIs(t, err, services.ErrTooManyAttempts)
+
+		mockRepo.AssertNotCalled(t, "GetUserByUsername")
+		throttle.AssertNotCalled(t, "Fail")
+	})
+
+	t.Run("Wrong password registers failure", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		throttle := new(MockLoginThrottler)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), throttle, testHasher, testPolicy, new(MockEmailVerifier))
+
+		mockRepo.On("GetUserByUsername", "TestUser").Return(user, nil)
+		throttle.On("Check", keys).Return(nil)
+		throttle.On("Fail", keys).Return(nil)
+
+		_, err := service.LoginUser("TestUser", "wrong_password", "10.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+
+		throttle.AssertExpectations(t)
+	})
+
+	t.Run("Successful login resets username counter only", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		mockMFA := new(MockMFAVerifier)
+		throttle := new(MockLoginThrottler)
+		service := services.NewAuthService(mockRepo, mockMFA, throttle, testHasher, testPolicy, new(MockEmailVerifier))
+
+		os.Setenv("JWTSECRET", "testsecret")
+
+		mockRepo.On("GetUserByUsername", "TestUser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+		throttle.On("Check", keys).Return(nil)
+		throttle.On("Reset", []string{"user:testuser"}).Return(nil)
+
+		token, err := service.LoginUser("TestUser", "correct_password", "10.0.0.1")
+		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
+
+		throttle.AssertExpectations(t)
+		throttle.AssertNotCalled(t, "Fail")
+	})
+
+	t.Run("Wrong second factor registers failure", func(t *testing.T) {
+		mockMFA := new(MockMFAVerifier)
+		throttle := new(MockLoginThrottler)
+		service := services.NewAuthService(new(MockUserRepo), mockMFA, throttle, testHasher, testPolicy, new(MockEmailVerifier))
+
+		os.Setenv("JWTSECRET", "testsecret")
+		pending, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"mfa_user_id": 1,
+			"exp":         time.Now().Add(time.Minute).Unix(),
+		}).SignedString([]byte("testsecret"))
+
+		mfaKeys := []string{"mfa:1", "ip:10.0.0.1"}
+		mockMFA.On("Verify", 1, "000000").Return(services.ErrInvalidMFACode)
+		throttle.On("Check", mfaKeys).Return(nil)
+		throttle.On("Fail", mfaKeys).Return(nil)
+
+		_, err := service.LoginMFA(pending, "000000", "10.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+
+		throttle.AssertExpectations(t)
+	})
+}
+
+func TestRegisterPasswordPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"Too short", "Ab1", false},
+		{"No uppercase", "lowercase123", false},
+		{"No lowercase", "UPPERCASE123", false},
+		{"No digit", "NoDigitsHere", false},
+		{"Contains username", "Alice2024xyz", false},
+		{"Common password", "Password123", false},
+		{"Too long", "Aa1" + string(make([]byte, 80)), false},
+		{"Strong password", "Correct7Horse", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepo)
+			service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, strictPolicy, new(MockEmailVerifier))
+
+			user := &models.User{Username: "alice", Password: tc.password}
+			if tc.valid {
+				mockRepo.On("UserExists", user).Return(false, nil)
+				mockRepo.On("CreateUser", user).Return(nil)
+			}
+
+			err := service.RegisterUser(user)
+			if tc.valid {
+				assert.NoError(t, err)
+				mockRepo.AssertExpectations(t)
+				return
+			}
+
+			assert.ErrorAs(t, err, &baseErr)
+			mockRepo.AssertNotCalled(t, "CreateUser")
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Current1pass"), bcrypt.DefaultCost)
+	user := &models.User{ID: 1, Username: "alice", PasswordHash: string(hashedPassword)}
+
+	t.Run("Successful change", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, strictPolicy, new(MockEmailVerifier))
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockRepo.On("UpdatePasswordHash", 1, mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("Brand7NewPass")) == nil
+		})).Return(nil)
+
+		err := service.ChangePassword(1, "Current1pass", "Brand7NewPass")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		throttle := new(MockLoginThrottler)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), throttle, testHasher, strictPolicy, new(MockEmailVerifier))
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		throttle.On("Check", []string{"user:alice"}).Return(nil)
+		throttle.On("Fail", []string{"user:alice"}).Return(nil)
+
+		err := service.ChangePassword(1, "Wrong1pass", "Brand7NewPass")
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "UpdatePasswordHash")
+		throttle.AssertExpectations(t)
+	})
+
+	t.Run("New password violates policy", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, strictPolicy, new(MockEmailVerifier))
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+
+		err := service.ChangePassword(1, "Current1pass", "weak")
+		assert.ErrorAs(t, err, &baseErr)
+		mockRepo.AssertNotCalled(t, "UpdatePasswordHash")
+	})
+
+	t.Run("Same password", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, strictPolicy, new(MockEmailVerifier))
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+
+		err := service.ChangePassword(1, "Current1pass", "Current1pass")
+		var specificErr *helpers.SpecificValidationError
+		assert.ErrorAs(t, err, &specificErr)
+		mockRepo.AssertNotCalled(t, "UpdatePasswordHash")
+	})
+}
+
+func TestLoginRehash(t *testing.T) {
+	os.Setenv("JWTSECRET", "testsecret")
+
+	t.Run("Bcrypt cost upgrade", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(mockRepo, mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		weakHash, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.MinCost)
+		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(weakHash)}
+
+		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+		mockRepo.On("UpdatePasswordHash", 1, mock.MatchedBy(func(hash string) bool {
+			cost, err := bcrypt.Cost([]byte(hash))
+			return err == nil && cost == bcrypt.DefaultCost
+		})).Return(nil)
+
+		_, err := service.LoginUser("testuser", "correct_password", "127.0.0.1")
 		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Algorithm upgrade to argon2id", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		mockMFA := new(MockMFAVerifier)
+		argonHasher := password.NewHasher(config.PasswordHashing{
+			Algorithm: password.AlgorithmArgon2id,
+			Argon2:    config.Argon2{Time: 1, Memory: 1024, Threads: 1},
+		})
+		service := services.NewAuthService(mockRepo, mockMFA, newAllowingThrottler(), argonHasher, testPolicy, new(MockEmailVerifier))
+
+		bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.MinCost)
+		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(bcryptHash)}
 
+		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+		mockRepo.On("UpdatePasswordHash", 1, mock.MatchedBy(func(hash string) bool {
+			ok, err := argonHasher.Verify(hash, "correct_password")
+			return err == nil && ok && strings.HasPrefix(hash, "$argon2id$")
+		})).Return(nil)
+
+		_, err := service.LoginUser("testuser", "correct_password", "127.0.0.1")
+		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Rehash failure doesn't block login", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		mockMFA := new(MockMFAVerifier)
+		service := services.NewAuthService(mockRepo, mockMFA, newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		weakHash, _ := bcrypt.GenerateFromPassword([]byte("correct_password"), bcrypt.MinCost)
+		user := &models.User{ID: 1, Username: "testuser", PasswordHash: string(weakHash)}
+
+		mockRepo.On("GetUserByUsername", "testuser").Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+		mockRepo.On("UpdatePasswordHash", 1, mock.Anything).Return(errors.New("db error"))
+
+		token, err := service.LoginUser("testuser", "correct_password", "127.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+	})
+}
+
+func TestRegisterWithEmail(t *testing.T) {
+	t.Run("Sends verification", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		verifier := new(MockEmailVerifier)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, verifier)
+
+		user := &models.User{Username: "alice", Email: "alice@example.com", Password: "secret"}
+		mockRepo.On("UserExists", user).Return(false, nil)
+		mockRepo.On("EmailExists", "alice@example.com", 0).Return(false, nil)
+		mockRepo.On("CreateUser", user).Return(nil)
+		verifier.On("SendVerification", user).Return(nil)
+
+		err := service.RegisterUser(user)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		verifier.AssertExpectations(t)
+	})
+
+	t.Run("Email taken", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		verifier := new(MockEmailVerifier)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, verifier)
+
+		user := &models.User{Username: "alice", Email: "alice@example.com", Password: "secret"}
+		mockRepo.On("UserExists", user).Return(false, nil)
+		mockRepo.On("EmailExists", "alice@example.com", 0).Return(true, nil)
+
+		err := service.RegisterUser(user)
+		assert.ErrorIs(t, err, repository.ErrUniqueEmail)
+		mockRepo.AssertNotCalled(t, "CreateUser")
+		verifier.AssertNotCalled(t, "SendVerification")
+	})
+
+	t.Run("Invalid email", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, new(MockEmailVerifier))
+
+		user := &models.User{Username: "alice", Email: "not an email", Password: "secret"}
+		mockRepo.On("UserExists", user).Return(false, nil)
+
+		err := service.RegisterUser(user)
+		assert.ErrorAs(t, err, &baseErr)
+		mockRepo.AssertNotCalled(t, "CreateUser")
+	})
+
+	t.Run("Mail failure doesn't fail registration", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		verifier := new(MockEmailVerifier)
+		service := services.NewAuthService(mockRepo, new(MockMFAVerifier), newAllowingThrottler(), testHasher, testPolicy, verifier)
+
+		user := &models.User{Username: "alice", Email: "alice@example.com", Password: "secret"}
+		mockRepo.On("UserExists", user).Return(false, nil)
+		mockRepo.On("EmailExists", "alice@example.com", 0).Return(false, nil)
+		mockRepo.On("CreateUser", user).Return(nil)
+		verifier.On("SendVerification", user).Return(errors.New("smtp down"))
+
+		err := service.RegisterUser(user)
+		assert.NoError(t, err)
+	})
 }
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+type ICalDAVTaskService interface {
+	CreateTask(task *models.Task) error
+	GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error)
+	UpdateTask(updates map[string]interface{}) error
+	DeleteTask(taskID, userID int) error
+}
+
+type ICalDAVWorkspaceRepository interface {
+	GetWorkspacesByUserID(userID int) ([]models.Workspace, error)
+}
+
+type ICalDAVTaskRepository interface {
+	GetExternalIDs(userID int, externalIDs []string) ([]string, error)
+}
+
+// CalDAVService maps the tasks of a user to CalDAV calendar collections: one
+// for the personal tasks and one per workspace. Every task is a resource
+// holding a single VTODO, changes go through TaskService so workspace roles
+// apply as they do for the REST API
+type CalDAVService struct {
+	tasks      ICalDAVTaskService
+	workspaces ICalDAVWorkspaceRepository
+	repo       ICalDAVTaskRepository
+}
+
+func NewCalDAVService(tasks ICalDAVTaskService, workspaces ICalDAVWorkspaceRepository, repo ICalDAVTaskRepository) *CalDAVService {
+	return &CalDAVService{tasks: tasks, workspaces: workspaces, repo: repo}
+}
+
+// Calendars returns the calendar collections of the user
+func (s *CalDAVService) Calendars(userID int) ([]models.DAVCalendar, error) {
+	workspaces, err := s.workspaces.GetWorkspacesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []models.DAVCalendar{{ID: models.DAVPersonalCalendar, Name: "Personal"}}
+	for _, workspace := range workspaces {
+		calendars = append(calendars, workspaceCalendar(workspace))
+	}
+
+	for i := range calendars {
+		objects, err := s.objects(userID, &calendars[i])
+		if err != nil {
+			return nil, err
+		}
+		calendars[i].CTag = calendarCTag(objects)
+	}
+
+	return calendars, nil
+}
+
+// Calendar returns a calendar collection of the user with its resources
+func (s *CalDAVService) Calendar(userID int, calendarID string) (*models.DAVCalendar, []models.DAVObject, error) {
+	calendar, err := s.calendar(userID, calendarID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objects, err := s.objects(userID, calendar)
+	if err != nil {
+		return nil, nil, err
+	}
+	calendar.CTag = calendarCTag(objects)
+
+	return calendar, objects, nil
+}
+
+// Object returns one resource of a calendar
+func (s *CalDAVService) Object(userID int, calendarID, name string) (*models.DAVObject, error) {
+	_, objects, err := s.Calendar(userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	object := findObject(objects, name)
+	if object == nil {
+		return nil, ErrDAVNotFound
+	}
+	return object, nil
+}
+
+// PutObject stores the VTODO of an iCalendar body as the named resource. The
+// resource name must be the UID of the to-do with the .ics extension. An
+// existing resource updates its task, a new one creates a task in the
+// calendar; created reports which of the two happened
+func (s *CalDAVService) PutObject(userID int, calendarID, name string, r io.Reader, conds models.DAVConditions) (bool, error) {
+	calendar, objects, err := s.Calendar(userID, calendarID)
+	if err != nil {
+		return false, err
+	}
+	if calendar.ReadOnly {
+		return false, ErrWorkspaceForbidden
+	}
+
+	task, err := davTask(r, name)
+	if err != nil {
+		return false, err
+	}
+
+	existing := findObject(objects, name)
+	if err := checkDAVConditions(existing, conds); err != nil {
+		return false, err
+	}
+
+	if existing != nil {
+		updates := map[string]interface{}{
+			"id":          existing.TaskID,
+			"user_id":     userID,
+			"title":       task.Title,
+			"description": task.Description,
+			"status":      task.Status,
+			"priority":    task.Priority,
+			"recurrence":  task.Recurrence,
+			"due_at":      nil,
+		}
+		if task.DueAt != nil {
+			updates["due_at"] = time.Time(*task.DueAt).Format(time.RFC3339)
+		}
+		return false, davTaskError(s.tasks.UpdateTask(updates))
+	}
+
+	taken, err := s.repo.GetExternalIDs(userID, []string{*task.ExternalID})
+	if err != nil {
+		return false, err
+	}
+	if len(taken) > 0 {
+		return false, ErrDAVUIDConflict
+	}
+
+	task.UserID = userID
+	task.WorkspaceID = calendar.WorkspaceID
+	if err := s.tasks.CreateTask(task); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteObject deletes the task of a resource, ifMatch is the If-Match header
+// of the request
+func (s *CalDAVService) DeleteObject(userID int, calendarID, name, ifMatch string) error {
+	object, err := s.Object(userID, calendarID, name)
+	if err != nil {
+		return err
+	}
+	if err := checkDAVConditions(object, models.DAVConditions{IfMatch: ifMatch}); err != nil {
+		return err
+	}
+
+	return davTaskError(s.tasks.DeleteTask(object.TaskID, userID))
+}
+
+// davTaskError reports a task removed since its calendar was read as a missing
+// resource
+func davTaskError(err error) error {
+	if errors.Is(err, repository.ErrNoRowsUpdated) {
+		return ErrDAVNotFound
+	}
+	return err
+}
+
+// calendar resolves a calendar id of the user
+func (s *CalDAVService) calendar(userID int, calendarID string) (*models.DAVCalendar, error) {
+	if calendarID == models.DAVPersonalCalendar {
+		return &models.DAVCalendar{ID: models.DAVPersonalCalendar, Name: "Personal"}, nil
+	}
+
+	raw, ok := strings.CutPrefix(calendarID, "workspace-")
+	if !ok {
+		return nil, ErrDAVNotFound
+	}
+	workspaceID, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, ErrDAVNotFound
+	}
+
+	workspaces, err := s.workspaces.GetWorkspacesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, workspace := range workspaces {
+		if workspace.ID == workspaceID {
+			calendar := workspaceCalendar(workspace)
+			return &calendar, nil
+		}
+	}
+
+	return nil, ErrDAVNotFound
+}
+
+// objects renders every task of a calendar as a resource
+func (s *CalDAVService) objects(userID int, calendar *models.DAVCalendar) ([]models.DAVObject, error) {
+	filter := models.TaskFilter{WorkspaceID: calendar.WorkspaceID, Personal: calendar.WorkspaceID == nil}
+	tasks, err := s.tasks.GetTasks(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]models.DAVObject, 0, len(tasks))
+	for i := range tasks {
+		object, err := davObject(&tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func workspaceCalendar(workspace models.Workspace) models.DAVCalendar {
+	id := workspace.ID
+	return models.DAVCalendar{
+		ID:          fmt.Sprintf("workspace-%d", id),
+		Name:        workspace.Name,
+		WorkspaceID: &id,
+		ReadOnly:    !workspace.Role.CanWrite(),
+	}
+}
+
+// davObject renders a task as a calendar of its own. The creation time is used
+// as DTSTAMP so the data, and with it the ETag, only changes with the task
+func davObject(task *models.Task) (models.DAVObject, error) {
+	var b strings.Builder
+	encoder := newICSTaskEncoder(&b, time.Time(task.CreatedAt), "", false)
+	if err := encoder.begin(); err != nil {
+		return models.DAVObject{}, err
+	}
+	if err := encoder.encode(task); err != nil {
+		return models.DAVObject{}, err
+	}
+	if err := encoder.end(); err != nil {
+		return models.DAVObject{}, err
+	}
+
+	data := b.String()
+	sum := sha256.Sum256([]byte(data))
+	return models.DAVObject{
+		Name:   taskUID(task) + ".ics",
+		ETag:   `"` + hex.EncodeToString(sum[:]) + `"`,
+		Data:   data,
+		TaskID: task.ID,
+	}, nil
+}
+
+// davTask reads the task of a resource body, which must hold exactly one VTODO
+// whose UID matches the resource name
+func davTask(r io.Reader, name string) (*models.Task, error) {
+	var records []map[string]string
+	err := decodeICalendar(r, []string{"VTODO"}, func(record map[string]string) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) != 1 {
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("calendar-data", "must hold exactly one VTODO"))
+	}
+
+	uid := records[0]["external_id"]
+	if uid == "" {
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("UID", "cannot be empty"))
+	}
+	if name != uid+".ics" {
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("UID", "must match the resource name"))
+	}
+
+	mapping, err := importMapping(nil)
+	if err != nil {
+		return nil, err
+	}
+	return importTask(records[0], mapping)
+}
+
+func findObject(objects []models.DAVObject, name string) *models.DAVObject {
+	for i := range objects {
+		if objects[i].Name == name {
+			return &objects[i]
+		}
+	}
+	return nil
+}
+
+// checkDAVConditions evaluates If-Match and If-None-Match against the current
+// resource, nil when it does not exist yet
+func checkDAVConditions(object *models.DAVObject, conds models.DAVConditions) error {
+	if conds.IfMatch != "" {
+		if object == nil || (conds.IfMatch != "*" && !etagListed(conds.IfMatch, object.ETag)) {
+			return ErrDAVPreconditionFailed
+		}
+	}
+	if conds.IfNoneMatch != "" && object != nil {
+		if conds.IfNoneMatch == "*" || etagListed(conds.IfNoneMatch, object.ETag) {
+			return ErrDAVPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// etagListed reports whether a comma separated list of entity tags holds etag,
+// weak tags compare equal to their strong form
+func etagListed(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// calendarCTag summarizes the resources of a calendar, it changes whenever one
+// is added, changed or removed
+func calendarCTag(objects []models.DAVObject) string {
+	h := sha256.New()
+	for _, object := range objects {
+		io.WriteString(h, object.Name)
+		io.WriteString(h, object.ETag)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
@@ -0,0 +1,239 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCalDAVTasks struct {
+	mock.Mock
+}
+
+func (m *MockCalDAVTasks) CreateTask(task *models.Task) error {
+	args := m.Called(task)
+	return args.Error(0)
+}
+
+func (m *MockCalDAVTasks) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockCalDAVTasks) UpdateTask(updates map[string]interface{}) error {
+	args := m.Called(updates)
+	return args.Error(0)
+}
+
+func (m *MockCalDAVTasks) DeleteTask(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+var personalFilter = models.TaskFilter{Personal: true}
+
+func davTasks() []models.Task {
+	created := models.JSONTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	return []models.Task{
+		{ID: 1, UserID: 1, Title: "Buy milk", Status: "pending", CreatedAt: created},
+		{ID: 2, UserID: 1, Title: "Call back", Status: "done", CreatedAt: created},
+	}
+}
+
+// davTodo is a to-do as a client sends it when it creates or edits a task
+func davTodo(uid, summary string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Client//EN\r\n" +
+		"BEGIN:VTODO\r\nUID:" + uid + "\r\nSUMMARY:" + summary + "\r\nPRIORITY:1\r\nDUE:20261020T090000Z\r\nSTATUS:NEEDS-ACTION\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\n" +
+		"END:VTODO\r\nEND:VCALENDAR\r\n"
+}
+
+func TestCalDAVCalendars(t *testing.T) {
+	tasks := new(MockCalDAVTasks)
+	workspaces := new(MockWorkspaceRepo)
+	service := services.NewCalDAVService(tasks, workspaces, new(MockTransferRepo))
+
+	workspaceID := 7
+	workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 7, Name: "Team", Role: models.RoleViewer}}, nil)
+	tasks.On("GetTasks", 1, personalFilter).Return(davTasks(), nil)
+	tasks.On("GetTasks", 1, models.TaskFilter{WorkspaceID: &workspaceID}).Return([]models.Task{}, nil)
+
+	calendars, err := service.Calendars(1)
+	require.NoError(t, err)
+	require.Len(t, calendars, 2)
+
+	assert.Equal(t, models.DAVPersonalCalendar, calendars[0].ID)
+	assert.False(t, calendars[0].ReadOnly)
+	assert.Equal(t, "workspace-7", calendars[1].ID)
+	assert.Equal(t, "Team", calendars[1].Name)
+	assert.True(t, calendars[1].ReadOnly, "viewers cannot change workspace tasks")
+	assert.NotEqual(t, calendars[0].CTag, calendars[1].CTag)
+}
+
+func TestCalDAVObjects(t *testing.T) {
+	tasks := new(MockCalDAVTasks)
+	service := services.NewCalDAVService(tasks, new(MockWorkspaceRepo), new(MockTransferRepo))
+
+	changed := davTasks()
+	changed[0].Title = "Buy oat milk"
+	tasks.On("GetTasks", 1, personalFilter).Return(davTasks(), nil).Twice()
+	tasks.On("GetTasks", 1, personalFilter).Return(changed, nil).Once()
+
+	calendar, objects, err := service.Calendar(1, models.DAVPersonalCalendar)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "task-1@todo-app.ics", objects[0].Name)
+	assert.Equal(t, 1, objects[0].TaskID)
+	assert.Contains(t, objects[0].Data, "SUMMARY:Buy milk\r\n")
+	assert.Contains(t, objects[0].Data, "DTSTAMP:20261001T120000Z\r\n")
+
+	// rendering is stable, only a changed task gets a new ETag and CTag
+	again, objectsAgain, err := service.Calendar(1, models.DAVPersonalCalendar)
+	require.NoError(t, err)
+	assert.Equal(t, objects, objectsAgain)
+	assert.Equal(t, calendar.CTag, again.CTag)
+
+	edited, objectsEdited, err := service.Calendar(1, models.DAVPersonalCalendar)
+	require.NoError(t, err)
+	assert.NotEqual(t, objects[0].ETag, objectsEdited[0].ETag)
+	assert.Equal(t, objects[1].ETag, objectsEdited[1].ETag)
+	assert.NotEqual(t, calendar.CTag, edited.CTag)
+}
+
+func TestCalDAVUnknownCalendar(t *testing.T) {
+	workspaces := new(MockWorkspaceRepo)
+	service := services.NewCalDAVService(new(MockCalDAVTasks), workspaces, new(MockTransferRepo))
+
+	workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 7, Name: "Team", Role: models.RoleMember}}, nil)
+
+	for _, id := range []string{"workspace-8", "workspace-x", "inbox"} {
+		_, _, err := service.Calendar(1, id)
+		assert.ErrorIs(t, err, services.ErrDAVNotFound, id)
+	}
+}
+
+func TestCalDAVPutObject(t *testing.T) {
+	t.Run("Create", func(t *testing.T) {
+		tasks := new(MockCalDAVTasks)
+		repo := new(MockTransferRepo)
+		workspaces := new(MockWorkspaceRepo)
+		service := services.NewCalDAVService(tasks, workspaces, repo)
+
+		workspaceID := 7
+		workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 7, Name: "Team", Role: models.RoleMember}}, nil)
+		tasks.On("GetTasks", 1, models.TaskFilter{WorkspaceID: &workspaceID}).Return([]models.Task{}, nil)
+		repo.On("GetExternalIDs", 1, []string{"abc-123"}).Return([]string{}, nil)
+		tasks.On("CreateTask", mock.MatchedBy(func(task *models.Task) bool {
+			return task.UserID == 1 && *task.WorkspaceID == 7 && *task.ExternalID == "abc-123" &&
+				task.Title == "Pay rent" && task.Status == "pending" && task.Priority == models.TaskPriorityHigh &&
+				task.DueAt != nil && time.Time(*task.DueAt).Equal(time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC))
+		})).Return(nil)
+
+		created, err := service.PutObject(1, "workspace-7", "abc-123.ics", strings.NewReader(davTodo("abc-123", "Pay rent")),
+			models.DAVConditions{IfNoneMatch: "*"})
+		require.NoError(t, err)
+		assert.True(t, created)
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		tasks := new(MockCalDAVTasks)
+		service := services.NewCalDAVService(tasks, new(MockWorkspaceRepo), new(MockTransferRepo))
+
+		tasks.On("GetTasks", 1, personalFilter).Return(davTasks(), nil).Twice()
+		_, objects, err := service.Calendar(1, models.DAVPersonalCalendar)
+		require.NoError(t, err)
+
+		tasks.On("UpdateTask", map[string]interface{}{
+			"id": 1, "user_id": 1, "title": "Buy oat milk", "description": "", "status": "pending",
+			"priority": models.TaskPriorityHigh, "recurrence": "", "due_at": "2026-10-20T09:00:00Z",
+		}).Return(nil)
+
+		created, err := service.PutObject(1, models.DAVPersonalCalendar, "task-1@todo-app.ics",
+			strings.NewReader(davTodo("task-1@todo-app", "Buy oat milk")), models.DAVConditions{IfMatch: objects[0].ETag})
+		require.NoError(t, err)
+		assert.False(t, created)
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tasks := new(MockCalDAVTasks)
+		repo := new(MockTransferRepo)
+		workspaces := new(MockWorkspaceRepo)
+		service := services.NewCalDAVService(tasks, workspaces, repo)
+
+		workspaceID := 7
+		workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 7, Name: "Team", Role: models.RoleViewer}}, nil)
+		tasks.On("GetTasks", 1, personalFilter).Return(davTasks(), nil)
+		tasks.On("GetTasks", 1, models.TaskFilter{WorkspaceID: &workspaceID}).Return([]models.Task{}, nil)
+		repo.On("GetExternalIDs", 1, []string{"taken"}).Return([]string{"taken"}, nil)
+
+		for _, tc := range []struct {
+			name     string
+			calendar string
+			resource string
+			body     string
+			conds    models.DAVConditions
+			want     error
+		}{
+			{"stale etag", models.DAVPersonalCalendar, "task-1@todo-app.ics", davTodo("task-1@todo-app", "Buy milk"),
+				models.DAVConditions{IfMatch: `"stale"`}, services.ErrDAVPreconditionFailed},
+			{"exists", models.DAVPersonalCalendar, "task-1@todo-app.ics", davTodo("task-1@todo-app", "Buy milk"),
+				models.DAVConditions{IfNoneMatch: "*"}, services.ErrDAVPreconditionFailed},
+			{"missing", models.DAVPersonalCalendar, "new.ics", davTodo("new", "New"),
+				models.DAVConditions{IfMatch: "*"}, services.ErrDAVPreconditionFailed},
+			{"uid in another calendar", models.DAVPersonalCalendar, "taken.ics", davTodo("taken", "Taken"),
+				models.DAVConditions{}, services.ErrDAVUIDConflict},
+			{"viewer", "workspace-7", "new.ics", davTodo("new", "New"), models.DAVConditions{}, services.ErrWorkspaceForbidden},
+		} {
+			_, err := service.PutObject(1, tc.calendar, tc.resource, strings.NewReader(tc.body), tc.conds)
+			assert.ErrorIs(t, err, tc.want, tc.name)
+		}
+
+		for _, tc := range []struct {
+			name     string
+			resource string
+			body     string
+		}{
+			{"name differs from uid", "other.ics", davTodo("new", "New")},
+			{"no title", "new.ics", davTodo("new", "")},
+			{"no to-do", "new.ics", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:new\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"},
+			{"two to-dos", "new.ics", davTodo("new", "New") + davTodo("new", "New")},
+		} {
+			_, err := service.PutObject(1, models.DAVPersonalCalendar, tc.resource, strings.NewReader(tc.body), models.DAVConditions{})
+			assert.ErrorContains(t, err, "validation failed", tc.name)
+		}
+
+		tasks.AssertNotCalled(t, "CreateTask", mock.Anything)
+		tasks.AssertNotCalled(t, "UpdateTask", mock.Anything)
+	})
+}
+
+func TestCalDAVDeleteObject(t *testing.T) {
+	tasks := new(MockCalDAVTasks)
+	service := services.NewCalDAVService(tasks, new(MockWorkspaceRepo), new(MockTransferRepo))
+
+	tasks.On("GetTasks", 1, personalFilter).Return(davTasks(), nil)
+	tasks.On("DeleteTask", 2, 1).Return(nil).Once()
+	tasks.On("DeleteTask", 1, 1).Return(repository.ErrNoRowsUpdated).Once()
+
+	err := service.DeleteObject(1, models.DAVPersonalCalendar, "task-2@todo-app.ics", "")
+	require.NoError(t, err)
+
+	err = service.DeleteObject(1, models.DAVPersonalCalendar, "task-2@todo-app.ics", `"stale"`)
+	assert.ErrorIs(t, err, services.ErrDAVPreconditionFailed)
+
+	err = service.DeleteObject(1, models.DAVPersonalCalendar, "task-1@todo-app.ics", "")
+	assert.ErrorIs(t, err, services.ErrDAVNotFound, "deleted meanwhile")
+
+	err = service.DeleteObject(1, models.DAVPersonalCalendar, "task-9@todo-app.ics", "")
+	assert.ErrorIs(t, err, services.ErrDAVNotFound)
+	tasks.AssertExpectations(t)
+}
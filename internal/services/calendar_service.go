@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"io"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+type ICalendarTaskRepository interface {
+	StreamTasks(userID int, filter models.TaskFilter, fn func(task *models.Task) error) error
+}
+
+// CalendarService publishes the tasks of a user with a due date as an
+// iCalendar feed behind a secret URL. The feed token is stored hashed among the
+// user tokens, issuing a new one revokes the previous URL
+type CalendarService struct {
+	tokens ITokenRepository
+	tasks  ICalendarTaskRepository
+	cfg    config.Calendar
+	clock  clock.Clock
+}
+
+func NewCalendarService(tokens ITokenRepository, tasks ICalendarTaskRepository, cfg config.Calendar, clock clock.Clock) *CalendarService {
+	return &CalendarService{tokens: tokens, tasks: tasks, cfg: cfg, clock: clock}
+}
+
+// CreateFeed issues a new feed URL for the user
+func (s *CalendarService) CreateFeed(userID int) (*models.CalendarFeed, error) {
+	expiresAt := s.clock.Now().Add(s.cfg.TokenTTL)
+	token, err := issueUserToken(s.tokens, userID, models.TokenPurposeCalendarFeed, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CalendarFeed{URL: buildLink(s.cfg.FeedURL, token), ExpiresAt: expiresAt}, nil
+}
+
+// RevokeFeed stops the feed URL of the user from working
+func (s *CalendarService) RevokeFeed(userID int) error {
+	return s.tokens.DeleteTokens(userID, models.TokenPurposeCalendarFeed)
+}
+
+// Feed writes the calendar of the feed token to w: a VTODO for every task
+// visible to its owner that has a due date and, with events, a VEVENT at the
+// due time as well
+func (s *CalendarService) Feed(w io.Writer, token string, events bool) error {
+	feedToken, err := s.tokens.FindToken(hashToken(token), models.TokenPurposeCalendarFeed, s.clock.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrCalendarFeedNotFound
+		}
+		return err
+	}
+
+	encoder := newICSTaskEncoder(w, s.clock.Now(), "Tasks", events)
+	if err := encoder.begin(); err != nil {
+		return err
+	}
+
+	err = s.tasks.StreamTasks(feedToken.UserID, models.TaskFilter{}, func(task *models.Task) error {
+		if task.DueAt == nil {
+			return nil
+		}
+		return encoder.encode(task)
+	})
+	if err != nil {
+		return err
+	}
+
+	return encoder.end()
+}
@@ -0,0 +1,258 @@
+package services_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var calendarConfig = config.Calendar{FeedURL: "https://todo.example.com/api/calendar/{token}.ics", TokenTTL: 24 * time.Hour}
+
+// calendarTasks covers every field a calendar file carries, with text that
+// needs escaping and a line long enough to be folded inside a UTF-8 sequence
+func calendarTasks() []models.Task {
+	due := models.JSONTime(time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC))
+	completed := models.JSONTime(time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC))
+	created := models.JSONTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	externalID := "A-1@tracker.example.com"
+
+	return []models.Task{
+		{ID: 1, UserID: 1, Title: "Standup; notes, draft", Description: "line one\nline two \\ end", Status: "pending",
+			Priority: models.TaskPriorityHigh, Recurrence: "FREQ=WEEKLY;BYDAY=MO,WE", DueAt: &due, CreatedAt: created},
+		{ID: 2, UserID: 1, ExternalID: &externalID, Title: "Отчёт за квартал", Description: strings.Repeat("Длинное описание задачи ", 8),
+			Status: models.TaskStatusDone, Priority: models.TaskPriorityLow, DueAt: &due, CompletedAt: &completed, CreatedAt: created},
+		{ID: 3, UserID: 1, Title: "Waiting", Status: "blocked by review", Priority: models.TaskPriorityMedium, DueAt: &due, CreatedAt: created},
+		{ID: 4, UserID: 1, Title: "Someday", Status: "pending", CreatedAt: created},
+	}
+}
+
+func TestCreateCalendarFeed(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tokens := new(MockTokenRepo)
+	service := services.NewCalendarService(tokens, new(MockTransferRepo), calendarConfig, clock)
+
+	tokens.On("DeleteTokens", 1, models.TokenPurposeCalendarFeed).Return(nil)
+	tokens.On("CreateToken", mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.UserID == 1 && token.Purpose == models.TokenPurposeCalendarFeed &&
+			token.ExpiresAt.Equal(clock.now.Add(calendarConfig.TokenTTL))
+	})).Return(nil)
+
+	feed, err := service.CreateFeed(1)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(feed.URL, "https://todo.example.com/api/calendar/"))
+	assert.True(t, strings.HasSuffix(feed.URL, ".ics"))
+	assert.Equal(t, clock.now.Add(calendarConfig.TokenTTL), feed.ExpiresAt)
+
+	// the URL carries the token, only its hash is stored
+	stored := tokens.Calls[1].Arguments.Get(0).(*models.UserToken)
+	assert.NotContains(t, feed.URL, stored.TokenHash)
+	tokens.AssertExpectations(t)
+}
+
+func TestCalendarFeed(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+
+	t.Run("Tasks with a due date", func(t *testing.T) {
+		tokens, repo := new(MockTokenRepo), &MockTransferRepo{tasks: calendarTasks()}
+		service := services.NewCalendarService(tokens, repo, calendarConfig, clock)
+
+		tokens.On("FindToken", mock.Anything, models.TokenPurposeCalendarFeed, clock.now).Return(&models.UserToken{UserID: 1}, nil)
+		repo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+		var out bytes.Buffer
+		require.NoError(t, service.Feed(&out, "secret", false))
+
+		feed := out.String()
+		assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:"))
+		assert.Contains(t, feed, "X-WR-CALNAME:Tasks\r\n")
+		assert.Contains(t, feed, "DTSTAMP:20261019T080000Z\r\n")
+		assert.Equal(t, 3, strings.Count(feed, "BEGIN:VTODO"))
+		assert.NotContains(t, feed, "Someday")
+		assert.NotContains(t, feed, "BEGIN:VEVENT")
+		assert.Contains(t, feed, "STATUS:COMPLETED\r\nCOMPLETED:20261018T170000Z\r\n")
+		assert.Contains(t, feed, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n")
+
+		for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+			assert.LessOrEqual(t, len(line), 75, line)
+		}
+	})
+
+	t.Run("With events", func(t *testing.T) {
+		tokens, repo := new(MockTokenRepo), &MockTransferRepo{tasks: calendarTasks()}
+		service := services.NewCalendarService(tokens, repo, calendarConfig, clock)
+
+		tokens.On("FindToken", mock.Anything, models.TokenPurposeCalendarFeed, clock.now).Return(&models.UserToken{UserID: 1}, nil)
+		repo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+		var out bytes.Buffer
+		require.NoError(t, service.Feed(&out, "secret", true))
+
+		assert.Equal(t, 3, strings.Count(out.String(), "BEGIN:VEVENT"))
+		assert.Contains(t, out.String(), "UID:event-task-1@todo-app\r\nDTSTAMP:20261019T080000Z\r\n")
+		assert.Contains(t, out.String(), "DTSTART:20261020T093000Z\r\n")
+		assert.Contains(t, out.String(), "RELATED-TO:task-1@todo-app\r\n")
+	})
+
+	t.Run("Line breaks in stored values", func(t *testing.T) {
+		// written before they were rejected, they must not add properties
+		externalID := "A-1\r\nBEGIN:VEVENT"
+		due := models.JSONTime(time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC))
+		tasks := []models.Task{{ID: 1, UserID: 1, ExternalID: &externalID, Title: "Injected", Status: "pending",
+			Recurrence: "FREQ=DAILY\nATTENDEE:mailto:x@example.com", DueAt: &due}}
+		tokens, repo := new(MockTokenRepo), &MockTransferRepo{tasks: tasks}
+		service := services.NewCalendarService(tokens, repo, calendarConfig, clock)
+
+		tokens.On("FindToken", mock.Anything, models.TokenPurposeCalendarFeed, clock.now).Return(&models.UserToken{UserID: 1}, nil)
+		repo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+		var out bytes.Buffer
+		require.NoError(t, service.Feed(&out, "secret", false))
+
+		feed := out.String()
+		assert.Contains(t, feed, "UID:A-1BEGIN:VEVENT\r\n")
+		assert.Contains(t, feed, "RRULE:FREQ=DAILYATTENDEE:mailto:x@example.com\r\n")
+		assert.NotContains(t, feed, "\r\nBEGIN:VEVENT")
+		assert.NotContains(t, feed, "\r\nATTENDEE")
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		tokens, repo := new(MockTokenRepo), new(MockTransferRepo)
+		service := services.NewCalendarService(tokens, repo, calendarConfig, clock)
+
+		tokens.On("FindToken", mock.Anything, models.TokenPurposeCalendarFeed, clock.now).Return((*models.UserToken)(nil), repository.ErrTokenNotFound)
+
+		var out bytes.Buffer
+		err := service.Feed(&out, "revoked", false)
+		assert.ErrorIs(t, err, services.ErrCalendarFeedNotFound)
+		assert.Zero(t, out.Len())
+		repo.AssertNotCalled(t, "StreamTasks", mock.Anything, mock.Anything)
+	})
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	original := calendarTasks()
+
+	export := &MockTransferRepo{tasks: original}
+	export.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+	var file bytes.Buffer
+	err := services.NewTaskTransferService(export, new(MockWorkspaceRepo), transferUsers(""), importConfig).
+		Export(&file, 1, models.TaskFilter{}, models.TransferFormatICS)
+	require.NoError(t, err)
+
+	var imported []models.Task
+	repo := new(MockTransferRepo)
+	repo.On("GetExternalIDs", 2, mock.Anything).Return([]string{}, nil)
+	repo.On("ImportTasks", mock.Anything, 10).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.Task)
+	}).Return(4, nil)
+
+	service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), config.Import{MaxRows: 10, BatchSize: 10})
+	report, err := service.Import(&file, models.ImportRequest{UserID: 2, Format: models.TransferFormatICS})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Created)
+	require.Len(t, imported, len(original))
+
+	for i, want := range original {
+		got := imported[i]
+		uid := fmt.Sprintf("task-%d@todo-app", want.ID)
+		if want.ExternalID != nil {
+			uid = *want.ExternalID
+		}
+
+		assert.Equal(t, uid, *got.ExternalID)
+		assert.Equal(t, 2, got.UserID)
+		assert.Equal(t, want.Title, got.Title)
+		assert.Equal(t, want.Description, got.Description)
+		assert.Equal(t, want.Status, got.Status)
+		assert.Equal(t, want.Priority, got.Priority)
+		assert.Equal(t, want.Recurrence, got.Recurrence)
+		assert.Equal(t, want.DueAt, got.DueAt)
+		assert.Equal(t, want.CompletedAt, got.CompletedAt)
+	}
+}
+
+func TestImportICS(t *testing.T) {
+	t.Run("Feed with events", func(t *testing.T) {
+		tokens, feedRepo := new(MockTokenRepo), &MockTransferRepo{tasks: calendarTasks()}
+		tokens.On("FindToken", mock.Anything, models.TokenPurposeCalendarFeed, mock.Anything).Return(&models.UserToken{UserID: 1}, nil)
+		feedRepo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+		var feed bytes.Buffer
+		clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+		require.NoError(t, services.NewCalendarService(tokens, feedRepo, calendarConfig, clock).Feed(&feed, "secret", true))
+
+		repo := new(MockTransferRepo)
+		repo.On("GetExternalIDs", 1, mock.Anything).Return([]string{}, nil)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		// the events of the feed describe its to-dos and are not imported again
+		report, err := service.Import(&feed, models.ImportRequest{UserID: 1, Format: models.TransferFormatICS, DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Total)
+		assert.Equal(t, 3, report.Valid)
+	})
+
+	t.Run("Calendar from another app", func(t *testing.T) {
+		file := "BEGIN:VCALENDAR\r\n" +
+			"VERSION:2.0\r\n" +
+			"PRODID:-//Other//App//EN\r\n" +
+			"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nEND:VTIMEZONE\r\n" +
+			"BEGIN:VTODO\r\n" +
+			"UID:todo-1\r\n" +
+			"SUMMARY:Renew pass\r\n" +
+			" port\r\n" +
+			"DUE;TZID=Europe/Berlin:20261105T100000\r\n" +
+			"PRIORITY:3\r\n" +
+			"STATUS:IN-PROCESS\r\n" +
+			"BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Alarm\r\nEND:VALARM\r\n" +
+			"END:VTODO\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"UID:event-1\r\n" +
+			"SUMMARY:Dentist\r\n" +
+			"DTSTART;VALUE=DATE:20261107\r\n" +
+			"STATUS:CONFIRMED\r\n" +
+			"END:VEVENT\r\n" +
+			"END:VCALENDAR\r\n"
+
+		var imported []models.Task
+		repo := new(MockTransferRepo)
+		repo.On("GetExternalIDs", 1, []string{"todo-1", "event-1"}).Return([]string{}, nil)
+		repo.On("ImportTasks", mock.Anything, importConfig.BatchSize).Run(func(args mock.Arguments) {
+			imported = args.Get(0).([]models.Task)
+		}).Return(2, nil)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		_, err := service.Import(strings.NewReader(file), models.ImportRequest{UserID: 1, Format: models.TransferFormatICS})
+		require.NoError(t, err)
+		require.Len(t, imported, 2)
+
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+		assert.Equal(t, "Renew passport", imported[0].Title)
+		assert.Equal(t, "", imported[0].Description)
+		assert.Equal(t, "in progress", imported[0].Status)
+		assert.Equal(t, models.TaskPriorityHigh, imported[0].Priority)
+		assert.True(t, time.Date(2026, 11, 5, 10, 0, 0, 0, berlin).Equal(time.Time(*imported[0].DueAt)))
+
+		assert.Equal(t, "Dentist", imported[1].Title)
+		assert.Equal(t, "pending", imported[1].Status)
+		assert.True(t, time.Date(2026, 11, 7, 0, 0, 0, 0, time.Local).Equal(time.Time(*imported[1].DueAt)))
+	})
+
+	t.Run("Not a calendar", func(t *testing.T) {
+		service := services.NewTaskTransferService(new(MockTransferRepo), new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		_, err := service.Import(strings.NewReader("title,status\nOne,pending\n"), models.ImportRequest{UserID: 1, Format: models.TransferFormatICS})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+}
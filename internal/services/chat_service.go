@@ -0,0 +1,384 @@
+package services
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// chatProvider is the identity provider of linked chat accounts, the subject
+// is the team and the user ID of the chat
+const chatProvider = "chat"
+
+const chatLinkCodeSize = 10
+
+type IChatIdentityRepository interface {
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	DeleteIdentity(provider, subject string) error
+}
+
+type IChatUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+type IChatTaskService interface {
+	CreateTask(task *models.Task) error
+	GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error)
+	GetTask(taskID, userID int) (*models.Task, error)
+	UpdateTask(updates map[string]interface{}) error
+}
+
+// ChatService answers the slash commands of the team chat. A chat account acts
+// as the user it is linked to: the user creates a link code in the app and
+// sends it with the link command
+type ChatService struct {
+	tokens     ITokenRepository
+	identities IChatIdentityRepository
+	users      IChatUserRepository
+	tasks      IChatTaskService
+	cfg        config.Chat
+	clock      clock.Clock
+	log        zerolog.Logger
+}
+
+func NewChatService(
+	tokens ITokenRepository,
+	identities IChatIdentityRepository,
+	users IChatUserRepository,
+	tasks IChatTaskService,
+	cfg config.Chat,
+	clock clock.Clock,
+) *ChatService {
+	return &ChatService{
+		tokens:     tokens,
+		identities: identities,
+		users:      users,
+		tasks:      tasks,
+		cfg:        cfg,
+		clock:      clock,
+		log:        logger.GetLogger(),
+	}
+}
+
+// SignChatRequest returns the signature of a slash command request: "v0=" and
+// the hex HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret
+func SignChatRequest(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest checks the signature of a request and that its Unix timestamp
+// is within the allowed skew, so a captured request can't be replayed later
+func (s *ChatService) VerifyRequest(timestamp, signature string, body []byte) error {
+	if s.cfg.SigningSecret == "" {
+		return ErrChatDisabled
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidChatSignature
+	}
+	if skew := s.clock.Now().Sub(time.Unix(seconds, 0)).Abs(); skew > s.cfg.MaxSkew {
+		return ErrInvalidChatSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(SignChatRequest(s.cfg.SigningSecret, timestamp, body))) {
+		return ErrInvalidChatSignature
+	}
+	return nil
+}
+
+// CreateLinkCode issues a code that links a chat account to the user, an
+// earlier code stops working
+func (s *ChatService) CreateLinkCode(userID int) (*models.ChatLinkCode, error) {
+	buf := make([]byte, chatLinkCodeSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	code := inboundTokenEncoding.EncodeToString(buf)
+
+	expiresAt := s.clock.Now().Add(s.cfg.LinkTTL)
+	if err := storeUserToken(s.tokens, userID, models.TokenPurposeChatLink, code, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.ChatLinkCode{Code: code, Command: s.cfg.Command + " link " + code, ExpiresAt: expiresAt}, nil
+}
+
+// HandleCommand runs a slash command. Mistakes of the chat user are answered
+// in the reply, an error means the command could not be run
+func (s *ChatService) HandleCommand(cmd models.ChatCommand) (*models.ChatReply, error) {
+	verb, arg, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	verb, arg = strings.ToLower(verb), strings.TrimSpace(arg)
+
+	switch verb {
+	case "", "help":
+		return s.help(), nil
+	case "link":
+		return s.link(cmd, arg)
+	case "unlink":
+		return s.unlink(cmd)
+	}
+
+	identity, err := s.identities.GetIdentity(chatProvider, chatSubject(cmd))
+	if err != nil {
+		return nil, err
+	}
+	if identity == nil {
+		return chatReply(fmt.Sprintf("Your chat account is not linked yet. Create a link code in the app and send `%s link <code>`", s.cfg.Command)), nil
+	}
+
+	user, err := s.users.GetUserByID(identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch verb {
+	case "add":
+		return s.add(user, arg)
+	case "list":
+		return s.list(user)
+	case "done":
+		return s.done(user, arg)
+	default:
+		reply := s.help()
+		reply.Blocks = append([]models.ChatBlock{chatSection(fmt.Sprintf("Unknown command `%s`", chatEscape(verb)))}, reply.Blocks...)
+		return reply, nil
+	}
+}
+
+func (s *ChatService) help() *models.ChatReply {
+	command := s.cfg.Command
+	return chatReply(
+		"*Commands*",
+		fmt.Sprintf("`%s add Buy milk tomorrow #home !high` adds a task, a date and time, #tags, a +project, a !priority and a repetition such as every week are optional", command),
+		fmt.Sprintf("`%s list` shows your open tasks", command),
+		fmt.Sprintf("`%s done 42` completes task 42", command),
+		fmt.Sprintf("`%s link <code>` links your chat account with a code from the app, `%s unlink` removes the link", command, command),
+	)
+}
+
+func (s *ChatService) link(cmd models.ChatCommand, code string) (*models.ChatReply, error) {
+	if code == "" {
+		return chatReply(fmt.Sprintf("Send the code from the app: `%s link <code>`", s.cfg.Command)), nil
+	}
+
+	userID, err := s.tokens.ConsumeToken(hashToken(strings.ToLower(code)), models.TokenPurposeChatLink, s.clock.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return chatReply("The link code is invalid or expired, create a new one in the app"), nil
+		}
+		return nil, err
+	}
+
+	subject := chatSubject(cmd)
+	identity, err := s.identities.GetIdentity(chatProvider, subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil && identity.UserID != userID {
+		return chatReply(fmt.Sprintf("Your chat account is linked to another user, send `%s unlink` first", s.cfg.Command)), nil
+	}
+
+	if identity == nil {
+		now := s.clock.Now()
+		if err := s.identities.CreateIdentity(&models.UserIdentity{UserID: userID, Provider: chatProvider, Subject: subject, LastLoginAt: &now}); err != nil {
+			return nil, err
+		}
+		s.log.Info().Int("user_id", userID).Str("subject", subject).Msg("Chat account linked")
+	}
+
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return chatReply(fmt.Sprintf("Linked to *%s*", chatEscape(user.Username))), nil
+}
+
+func (s *ChatService) unlink(cmd models.ChatCommand) (*models.ChatReply, error) {
+	if err := s.identities.DeleteIdentity(chatProvider, chatSubject(cmd)); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return chatReply("Your chat account is not linked"), nil
+		}
+		return nil, err
+	}
+
+	return chatReply("Your chat account is unlinked"), nil
+}
+
+func (s *ChatService) add(user *models.User, text string) (*models.ChatReply, error) {
+	task, _, err := parseQuickAdd(text, s.clock.Now().In(userLocation(user)))
+	if err == nil {
+		task.UserID = user.ID
+		err = s.tasks.CreateTask(&task)
+	}
+	if err != nil {
+		var fieldErr *helpers.SpecificValidationError
+		if errors.As(err, &fieldErr) {
+			return chatReply(fmt.Sprintf("The task was not added: %s", chatEscape(rowError(err)))), nil
+		}
+		return nil, err
+	}
+
+	reply := chatReply("Added " + chatTaskText(task))
+	if details := chatTaskDetails(task, userLocation(user)); details != "" {
+		reply.Blocks = append(reply.Blocks, chatContext(details))
+	}
+	return reply, nil
+}
+
+func (s *ChatService) list(user *models.User) (*models.ChatReply, error) {
+	tasks, err := s.tasks.GetTasks(user.ID, models.TaskFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	tasks = slices.DeleteFunc(tasks, func(task models.Task) bool { return task.Status == models.TaskStatusDone })
+	if len(tasks) == 0 {
+		return chatReply("You have no open tasks"), nil
+	}
+
+	// tasks with a due date first, the earliest on top
+	slices.SortStableFunc(tasks, func(a, b models.Task) int {
+		if (a.DueAt == nil) != (b.DueAt == nil) {
+			if a.DueAt == nil {
+				return 1
+			}
+			return -1
+		}
+		if a.DueAt != nil {
+			if c := time.Time(*a.DueAt).Compare(time.Time(*b.DueAt)); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	reply := chatReply(fmt.Sprintf("*%d open tasks*", len(tasks)))
+	shown := tasks
+	if s.cfg.ListLimit > 0 && len(shown) > s.cfg.ListLimit {
+		shown = shown[:s.cfg.ListLimit]
+	}
+
+	loc := userLocation(user)
+	for _, task := range shown {
+		reply.Blocks = append(reply.Blocks, chatSection(chatTaskText(task)))
+		if details := chatTaskDetails(task, loc); details != "" {
+			reply.Blocks = append(reply.Blocks, chatContext(details))
+		}
+	}
+	if hidden := len(tasks) - len(shown); hidden > 0 {
+		reply.Blocks = append(reply.Blocks, models.ChatBlock{Type: models.ChatBlockDivider}, chatContext(fmt.Sprintf("and %d more in the app", hidden)))
+	}
+
+	return reply, nil
+}
+
+func (s *ChatService) done(user *models.User, arg string) (*models.ChatReply, error) {
+	taskID, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		return chatReply(fmt.Sprintf("Send the number of the task: `%s done 42`", s.cfg.Command)), nil
+	}
+
+	task, err := s.tasks.GetTask(taskID, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return chatReply(fmt.Sprintf("Task %d not found", taskID)), nil
+		}
+		return nil, err
+	}
+	if task.Status == models.TaskStatusDone {
+		return chatReply(chatTaskText(*task) + " is already done"), nil
+	}
+
+	err = s.tasks.UpdateTask(map[string]interface{}{"id": task.ID, "user_id": user.ID, "status": models.TaskStatusDone})
+	if err != nil {
+		if errors.Is(err, ErrWorkspaceForbidden) {
+			return chatReply(fmt.Sprintf("You can't change task %d", taskID)), nil
+		}
+		return nil, err
+	}
+
+	return chatReply("Completed " + chatTaskText(*task)), nil
+}
+
+// chatSubject identifies a chat account, user IDs are unique within a team
+func chatSubject(cmd models.ChatCommand) string {
+	if cmd.TeamID == "" {
+		return cmd.UserID
+	}
+	return cmd.TeamID + ":" + cmd.UserID
+}
+
+// userLocation is the time zone of the user's profile, UTC when it is unset
+func userLocation(user *models.User) *time.Location {
+	if loc, err := time.LoadLocation(user.Timezone); err == nil && user.Timezone != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+// chatReply is a reply to the chat user only with a section for each line
+func chatReply(lines ...string) *models.ChatReply {
+	reply := &models.ChatReply{ResponseType: models.ChatResponseEphemeral, Text: strings.Join(lines, "\n"), Blocks: []models.ChatBlock{}}
+	for _, line := range lines {
+		reply.Blocks = append(reply.Blocks, chatSection(line))
+	}
+	return reply
+}
+
+func chatSection(text string) models.ChatBlock {
+	return models.ChatBlock{Type: models.ChatBlockSection, Text: &models.ChatText{Type: models.ChatTextMarkdown, Text: text}}
+}
+
+func chatContext(text string) models.ChatBlock {
+	return models.ChatBlock{Type: models.ChatBlockContext, Elements: []models.ChatText{{Type: models.ChatTextMarkdown, Text: text}}}
+}
+
+func chatTaskText(task models.Task) string {
+	return fmt.Sprintf("*%d* %s", task.ID, chatEscape(task.Title))
+}
+
+// chatTaskDetails is the due date, priority and tags of a task on one line
+func chatTaskDetails(task models.Task, loc *time.Location) string {
+	var details []string
+	if task.DueAt != nil {
+		due := time.Time(*task.DueAt).In(loc)
+		if due.Hour() == 0 && due.Minute() == 0 {
+			details = append(details, "due "+due.Format("Mon, 2 Jan 2006"))
+		} else {
+			details = append(details, "due "+due.Format("Mon, 2 Jan 2006 15:04"))
+		}
+	}
+	if task.Priority != "" {
+		details = append(details, task.Priority+" priority")
+	}
+	for _, tag := range task.Tags {
+		details = append(details, "#"+chatEscape(tag))
+	}
+	return strings.Join(details, " · ")
+}
+
+// chatEscape keeps user text from being read as chat markup
+func chatEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,288 @@
+package services_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockChatTasks struct {
+	mock.Mock
+}
+
+func (m *MockChatTasks) CreateTask(task *models.Task) error {
+	args := m.Called(task)
+	task.ID = 42
+	return args.Error(0)
+}
+
+func (m *MockChatTasks) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockChatTasks) GetTask(taskID, userID int) (*models.Task, error) {
+	args := m.Called(taskID, userID)
+	return args.Get(0).(*models.Task), args.Error(1)
+}
+
+func (m *MockChatTasks) UpdateTask(updates map[string]interface{}) error {
+	args := m.Called(updates)
+	return args.Error(0)
+}
+
+var chatConfig = config.Chat{SigningSecret: "s3cret", Command: "/todo", MaxSkew: 5 * time.Minute, LinkTTL: 10 * time.Minute, ListLimit: 2}
+
+// chatText is the text of every block of a reply
+func chatText(reply *models.ChatReply) string {
+	var lines []string
+	for _, block := range reply.Blocks {
+		if block.Text != nil {
+			lines = append(lines, block.Text.Text)
+		}
+		for _, element := range block.Elements {
+			lines = append(lines, element.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestVerifyChatRequest(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	service := services.NewChatService(new(MockTokenRepo), new(MockIdentityRepo), new(MockUserRepo), new(MockChatTasks), chatConfig, clock)
+
+	body := []byte("team_id=T1&user_id=U1&command=%2Ftodo&text=list")
+	now := strconv.FormatInt(clock.now.Unix(), 10)
+	late := strconv.FormatInt(clock.now.Add(-10*time.Minute).Unix(), 10)
+
+	assert.NoError(t, service.VerifyRequest(now, services.SignChatRequest("s3cret", now, body), body))
+	assert.ErrorIs(t, service.VerifyRequest(now, services.SignChatRequest("guess", now, body), body), services.ErrInvalidChatSignature)
+	assert.ErrorIs(t, service.VerifyRequest(now, services.SignChatRequest("s3cret", now, body), []byte("user_id=U2&text=list")), services.ErrInvalidChatSignature)
+	assert.ErrorIs(t, service.VerifyRequest(late, services.SignChatRequest("s3cret", late, body), body), services.ErrInvalidChatSignature)
+	assert.ErrorIs(t, service.VerifyRequest("", "", body), services.ErrInvalidChatSignature)
+
+	disabled := services.NewChatService(new(MockTokenRepo), new(MockIdentityRepo), new(MockUserRepo), new(MockChatTasks), config.Chat{}, clock)
+	assert.ErrorIs(t, disabled.VerifyRequest(now, services.SignChatRequest("", now, body), body), services.ErrChatDisabled)
+}
+
+func TestCreateChatLinkCode(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tokens := new(MockTokenRepo)
+	service := services.NewChatService(tokens, new(MockIdentityRepo), new(MockUserRepo), new(MockChatTasks), chatConfig, clock)
+
+	tokens.On("DeleteTokens", 1, models.TokenPurposeChatLink).Return(nil)
+	tokens.On("CreateToken", mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.UserID == 1 && token.Purpose == models.TokenPurposeChatLink && token.ExpiresAt.Equal(clock.now.Add(10*time.Minute))
+	})).Return(nil)
+
+	code, err := service.CreateLinkCode(1)
+	require.NoError(t, err)
+	assert.Len(t, code.Code, 16)
+	assert.Equal(t, "/todo link "+code.Code, code.Command)
+
+	stored := tokens.Calls[1].Arguments.Get(0).(*models.UserToken)
+	assert.Equal(t, sha256Hex(code.Code), stored.TokenHash)
+}
+
+func TestChatCommand(t *testing.T) {
+	// a Monday, 10:00 in Berlin
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	alice := &models.User{ID: 1, Username: "alice", Timezone: "Europe/Berlin"}
+	linked := models.ChatCommand{TeamID: "T1", UserID: "U1", Command: "/todo"}
+	stranger := models.ChatCommand{TeamID: "T1", UserID: "U9", Command: "/todo"}
+
+	dueAt := func(t time.Time) *models.JSONTime {
+		due := models.JSONTime(t)
+		return &due
+	}
+
+	tests := []struct {
+		name  string
+		cmd   models.ChatCommand
+		text  string
+		setup func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks)
+		reply []string
+	}{
+		{
+			name:  "Help",
+			cmd:   stranger,
+			reply: []string{"`/todo add Buy milk tomorrow #home !high`", "`/todo list`", "`/todo done 42`"},
+		},
+		{
+			name:  "Not linked",
+			cmd:   stranger,
+			text:  "list",
+			reply: []string{"not linked yet", "`/todo link <code>`"},
+		},
+		{
+			name: "Link",
+			cmd:  stranger,
+			text: "link ABCDEFGH",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tokens.On("ConsumeToken", sha256Hex("abcdefgh"), models.TokenPurposeChatLink, clock.now).Return(1, nil)
+				identities.On("GetIdentity", "chat", "T1:U9").Return((*models.UserIdentity)(nil), nil)
+				identities.On("CreateIdentity", mock.MatchedBy(func(identity *models.UserIdentity) bool {
+					return identity.UserID == 1 && identity.Provider == "chat" && identity.Subject == "T1:U9"
+				})).Return(nil)
+			},
+			reply: []string{"Linked to *alice*"},
+		},
+		{
+			name: "Link with an expired code",
+			cmd:  stranger,
+			text: "link abcdefgh",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tokens.On("ConsumeToken", sha256Hex("abcdefgh"), models.TokenPurposeChatLink, clock.now).Return(0, repository.ErrTokenNotFound)
+			},
+			reply: []string{"invalid or expired"},
+		},
+		{
+			name: "Link to another user",
+			cmd:  linked,
+			text: "link abcdefgh",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tokens.On("ConsumeToken", sha256Hex("abcdefgh"), models.TokenPurposeChatLink, clock.now).Return(2, nil)
+			},
+			reply: []string{"linked to another user", "`/todo unlink`"},
+		},
+		{
+			name: "Unlink",
+			cmd:  linked,
+			text: "unlink",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				identities.On("DeleteIdentity", "chat", "T1:U1").Return(nil)
+			},
+			reply: []string{"unlinked"},
+		},
+		{
+			name: "Add",
+			cmd:  linked,
+			text: "add Buy milk tomorrow #home !HIGH",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("CreateTask", mock.MatchedBy(func(task *models.Task) bool {
+					return task.UserID == 1 && task.Title == "Buy milk" && task.Status == "pending" &&
+						task.Priority == models.TaskPriorityHigh && assert.ObjectsAreEqual(models.StringList{"home"}, task.Tags) &&
+						task.DueAt != nil && time.Time(*task.DueAt).Equal(time.Date(2026, 10, 20, 0, 0, 0, 0, berlin)) &&
+						time.Time(*task.DueAt).Location() == time.UTC
+				})).Return(nil)
+			},
+			reply: []string{"Added *42* Buy milk", "due Tue, 20 Oct 2026 · high priority · #home"},
+		},
+		{
+			name: "Add on a weekday",
+			cmd:  linked,
+			text: "add Call <Sam> on friday",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("CreateTask", mock.MatchedBy(func(task *models.Task) bool {
+					return task.Title == "Call <Sam>" && time.Time(*task.DueAt).Equal(time.Date(2026, 10, 23, 0, 0, 0, 0, berlin))
+				})).Return(nil)
+			},
+			reply: []string{"Added *42* Call &lt;Sam&gt;", "due Fri, 23 Oct 2026"},
+		},
+		{
+			name: "Add without a title",
+			cmd:  linked,
+			text: "add #home",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("CreateTask", mock.Anything).Return(fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("title", "cannot be blank")))
+			},
+			reply: []string{"The task was not added: field 'title': cannot be blank"},
+		},
+		{
+			name:  "Add too long",
+			cmd:   linked,
+			text:  "add " + strings.Repeat("word ", 1000),
+			reply: []string{"The task was not added: field 'text': must be at most 2000 bytes"},
+		},
+		{
+			name: "List",
+			cmd:  linked,
+			text: "list",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("GetTasks", 1, models.TaskFilter{}).Return([]models.Task{
+					{ID: 1, Title: "Someday", Status: "pending"},
+					{ID: 2, Title: "Finished", Status: models.TaskStatusDone},
+					{ID: 3, Title: "Dentist", Status: "pending", DueAt: dueAt(time.Date(2026, 10, 21, 13, 30, 0, 0, time.UTC))},
+					{ID: 4, Title: "Taxes", Status: "pending", Tags: models.StringList{"home"}, DueAt: dueAt(time.Date(2026, 10, 20, 0, 0, 0, 0, berlin))},
+				}, nil)
+			},
+			reply: []string{"*3 open tasks*", "*4* Taxes", "due Tue, 20 Oct 2026 · #home", "*3* Dentist", "due Wed, 21 Oct 2026 15:30", "and 1 more in the app"},
+		},
+		{
+			name: "Done",
+			cmd:  linked,
+			text: "done #3",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("GetTask", 3, 1).Return(&models.Task{ID: 3, Title: "Dentist", Status: "pending"}, nil)
+				tasks.On("UpdateTask", map[string]interface{}{"id": 3, "user_id": 1, "status": models.TaskStatusDone}).Return(nil)
+			},
+			reply: []string{"Completed *3* Dentist"},
+		},
+		{
+			name: "Done with an unknown task",
+			cmd:  linked,
+			text: "done 99",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("GetTask", 99, 1).Return((*models.Task)(nil), repository.ErrNoRowsUpdated)
+			},
+			reply: []string{"Task 99 not found"},
+		},
+		{
+			name: "Done in a read-only workspace",
+			cmd:  linked,
+			text: "done 5",
+			setup: func(tokens *MockTokenRepo, identities *MockIdentityRepo, tasks *MockChatTasks) {
+				tasks.On("GetTask", 5, 1).Return(&models.Task{ID: 5, Title: "Shared", Status: "pending"}, nil)
+				tasks.On("UpdateTask", mock.Anything).Return(services.ErrWorkspaceForbidden)
+			},
+			reply: []string{"You can't change task 5"},
+		},
+		{
+			name:  "Unknown command",
+			cmd:   linked,
+			text:  "dance",
+			reply: []string{"Unknown command `dance`", "*Commands*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, identities, users, tasks := new(MockTokenRepo), new(MockIdentityRepo), new(MockUserRepo), new(MockChatTasks)
+			service := services.NewChatService(tokens, identities, users, tasks, chatConfig, clock)
+
+			if tt.setup != nil {
+				tt.setup(tokens, identities, tasks)
+			}
+			identities.On("GetIdentity", "chat", "T1:U1").Return(&models.UserIdentity{UserID: 1, Provider: "chat", Subject: "T1:U1"}, nil)
+			identities.On("GetIdentity", "chat", "T1:U9").Return((*models.UserIdentity)(nil), nil)
+			users.On("GetUserByID", 1).Return(alice, nil)
+
+			cmd := tt.cmd
+			cmd.Text = tt.text
+			reply, err := service.HandleCommand(cmd)
+			require.NoError(t, err)
+
+			assert.Equal(t, models.ChatResponseEphemeral, reply.ResponseType)
+			text := chatText(reply)
+			for _, want := range tt.reply {
+				assert.Contains(t, text, want)
+			}
+			tokens.AssertExpectations(t)
+			tasks.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+// mentionExcerptLength limits the comment text copied into a mention notification
+const mentionExcerptLength = 200
+
+type ICommentRepository interface {
+	CreateComment(comment *models.Comment, notifications []models.Notification) error
+	GetComment(id int) (*models.Comment, error)
+	GetCommentsByTaskID(taskID int) ([]models.Comment, error)
+	UpdateComment(comment *models.Comment, authorID int, notifications []models.Notification) error
+	DeleteComment(id, authorID int) error
+}
+
+type ICommentTaskRepository interface {
+	GetVisibleTask(taskID, userID int) (*models.Task, error)
+}
+
+type ICommentUserRepository interface {
+	GetUsersByUsernames(usernames []string) ([]models.User, error)
+}
+
+// CommentService manages task discussions. Everyone who can see a task,
+// viewers included, may comment on it; only authors change their comments
+type CommentService struct {
+	comments ICommentRepository
+	tasks    ICommentTaskRepository
+	users    ICommentUserRepository
+	clock    clock.Clock
+}
+
+func NewCommentService(comments ICommentRepository, tasks ICommentTaskRepository, users ICommentUserRepository, clock clock.Clock) *CommentService {
+	return &CommentService{
+		comments: comments,
+		tasks:    tasks,
+		users:    users,
+		clock:    clock,
+	}
+}
+
+func (s *CommentService) ListComments(taskID, userID int) ([]models.Comment, error) {
+	if _, err := s.visibleTask(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.comments.GetCommentsByTaskID(taskID)
+}
+
+func (s *CommentService) CreateComment(taskID, userID int, req models.CommentRequest) (*models.Comment, error) {
+	body, err := helpers.ValidateCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.visibleTask(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.comments.GetComment(*req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.TaskID != taskID {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("parent_id", "comment not found on this task"))
+		}
+	}
+
+	notifications, err := s.mentionNotifications(taskID, userID, body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		TaskID:   taskID,
+		AuthorID: userID,
+		ParentID: req.ParentID,
+		Body:     body,
+	}
+	if err := s.comments.CreateComment(comment, notifications); err != nil {
+		return nil, err
+	}
+
+	return s.comments.GetComment(comment.ID)
+}
+
+// UpdateComment replaces the body, only users mentioned for the first time are notified
+func (s *CommentService) UpdateComment(taskID, commentID, userID int, body string) (*models.Comment, error) {
+	body, err := helpers.ValidateCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.authoredComment(taskID, commentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := s.mentionNotifications(taskID, userID, body, helpers.ParseMentions(comment.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+	if err := s.comments.UpdateComment(comment, userID, notifications); err != nil {
+		if err == repository.ErrNoRowsUpdated {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// DeleteComment removes the comment, the replies to it stay without a parent
+func (s *CommentService) DeleteComment(taskID, commentID, userID int) error {
+	if _, err := s.authoredComment(taskID, commentID, userID); err != nil {
+		return err
+	}
+
+	if err := s.comments.DeleteComment(commentID, userID); err != nil {
+		if err == repository.ErrNoRowsUpdated {
+			return ErrCommentNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *CommentService) visibleTask(taskID, userID int) (*models.Task, error) {
+	task, err := s.tasks.GetVisibleTask(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, repository.ErrNoRowsUpdated
+	}
+	return task, nil
+}
+
+func (s *CommentService) authoredComment(taskID, commentID, userID int) (*models.Comment, error) {
+	if _, err := s.visibleTask(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments.GetComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.TaskID != taskID {
+		return nil, ErrCommentNotFound
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrNotCommentAuthor
+	}
+
+	return comment, nil
+}
+
+// mentionNotifications resolves @mentions of body into notifications. The author,
+// users already mentioned before and users who can't see the task are skipped
+func (s *CommentService) mentionNotifications(taskID, authorID int, body string, previous []string) ([]models.Notification, error) {
+	skip := make(map[string]bool, len(previous))
+	for _, name := range previous {
+		skip[name] = true
+	}
+
+	var names []string
+	for _, name := range helpers.ParseMentions(body) {
+		if !skip[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.users.GetUsersByUsernames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	excerpt := body
+	if utf8.RuneCountInString(excerpt) > mentionExcerptLength {
+		excerpt = string([]rune(excerpt)[:mentionExcerptLength]) + "…"
+	}
+
+	var notifications []models.Notification
+	for _, user := range users {
+		if user.ID == authorID {
+			continue
+		}
+
+		visible, err := s.tasks.GetVisibleTask(taskID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if visible == nil {
+			continue
+		}
+
+		notifications = append(notifications, models.Notification{
+			UserID:  user.ID,
+			Type:    models.NotificationCommentMention,
+			TaskID:  &taskID,
+			ActorID: &authorID,
+			Message: excerpt,
+		})
+	}
+
+	return notifications, nil
+}
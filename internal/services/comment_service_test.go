@@ -0,0 +1,215 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCommentRepo struct {
+	mock.Mock
+}
+
+func (m *MockCommentRepo) CreateComment(comment *models.Comment, notifications []models.Notification) error {
+	args := m.Called(comment, notifications)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepo) GetComment(id int) (*models.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetCommentsByTaskID(taskID int) ([]models.Comment, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetCommentsByAuthorID(authorID int) ([]models.Comment, error) {
+	args := m.Called(authorID)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) UpdateComment(comment *models.Comment, authorID int, notifications []models.Notification) error {
+	args := m.Called(comment, authorID, notifications)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepo) DeleteComment(id, authorID int) error {
+	args := m.Called(id, authorID)
+	return args.Error(0)
+}
+
+type commentFixture struct {
+	comments *MockCommentRepo
+	tasks    *MockTaskRepo
+	users    *MockUserRepo
+	service  *services.CommentService
+	now      time.Time
+}
+
+// newCommentFixture sets up task 42 of teamWorkspace, visible to every team
+// member and hidden from outsiderID
+func newCommentFixture() *commentFixture {
+	f := &commentFixture{
+		comments: new(MockCommentRepo),
+		tasks:    new(MockTaskRepo),
+		users:    new(MockUserRepo),
+		now:      time.Unix(1700000000, 0),
+	}
+	f.service = services.NewCommentService(f.comments, f.tasks, f.users, &FakeClock{now: f.now})
+
+	workspaceID := teamWorkspace
+	task := &models.Task{ID: 42, UserID: ownerID, WorkspaceID: &workspaceID}
+	for _, userID := range []int{ownerID, adminID, memberID, viewerID} {
+		f.tasks.On("GetVisibleTask", 42, userID).Return(task, nil).Maybe()
+	}
+	f.tasks.On("GetVisibleTask", 42, outsiderID).Return((*models.Task)(nil), nil).Maybe()
+	return f
+}
+
+func TestCreateComment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Mentions notify users who can see the task", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+
+		f.users.On("GetUsersByUsernames", []string{"viewer", "outsider", "member"}).Return([]models.User{
+			{ID: viewerID, Username: "viewer"},
+			{ID: outsiderID, Username: "outsider"},
+			{ID: memberID, Username: "member"},
+		}, nil)
+		f.comments.On("CreateComment", mock.MatchedBy(func(c *models.Comment) bool {
+			return c.TaskID == 42 && c.AuthorID == memberID && c.Body == "@viewer please check, cc @outsider @member"
+		}), mock.MatchedBy(func(n []models.Notification) bool {
+			return len(n) == 1 && n[0].UserID == viewerID && n[0].Type == models.NotificationCommentMention &&
+				*n[0].ActorID == memberID && *n[0].TaskID == 42
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.Comment).ID = 7
+		}).Return(nil)
+		f.comments.On("GetComment", 7).Return(&models.Comment{ID: 7, TaskID: 42, AuthorUsername: "member"}, nil)
+
+		comment, err := f.service.CreateComment(42, memberID, models.CommentRequest{Body: " @viewer please check, cc @outsider @member "})
+		require.NoError(t, err)
+		assert.Equal(t, "member", comment.AuthorUsername)
+		f.comments.AssertExpectations(t)
+	})
+
+	t.Run("Viewers may comment", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		f.comments.On("CreateComment", mock.Anything, []models.Notification(nil)).Return(nil)
+		f.comments.On("GetComment", 0).Return(&models.Comment{}, nil)
+
+		_, err := f.service.CreateComment(42, viewerID, models.CommentRequest{Body: "Looks good"})
+		assert.NoError(t, err)
+		f.users.AssertNotCalled(t, "GetUsersByUsernames", mock.Anything)
+	})
+
+	t.Run("Reply to a comment of another task", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		parentID := 3
+		f.comments.On("GetComment", parentID).Return(&models.Comment{ID: parentID, TaskID: 99}, nil)
+
+		_, err := f.service.CreateComment(42, memberID, models.CommentRequest{Body: "Reply", ParentID: &parentID})
+		assert.ErrorAs(t, err, &baseErr)
+		f.comments.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Hidden task", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+
+		_, err := f.service.CreateComment(42, outsiderID, models.CommentRequest{Body: "Hi"})
+		assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+		_, err = f.service.ListComments(42, outsiderID)
+		assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+		f.comments.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything)
+		f.comments.AssertNotCalled(t, "GetCommentsByTaskID", mock.Anything)
+	})
+
+	t.Run("Blank body", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+
+		_, err := f.service.CreateComment(42, memberID, models.CommentRequest{Body: "  \n "})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Long body is shortened in the notification", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		body := "@viewer " + strings.Repeat("x", 500)
+
+		f.users.On("GetUsersByUsernames", []string{"viewer"}).Return([]models.User{{ID: viewerID}}, nil)
+		f.comments.On("CreateComment", mock.Anything, mock.MatchedBy(func(n []models.Notification) bool {
+			return len(n) == 1 && len([]rune(n[0].Message)) == 201
+		})).Return(nil)
+		f.comments.On("GetComment", 0).Return(&models.Comment{}, nil)
+
+		_, err := f.service.CreateComment(42, memberID, models.CommentRequest{Body: body})
+		assert.NoError(t, err)
+		f.comments.AssertExpectations(t)
+	})
+}
+
+func TestUpdateComment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Only new mentions notify", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		f.comments.On("GetComment", 7).Return(&models.Comment{ID: 7, TaskID: 42, AuthorID: memberID, Body: "ping @viewer"}, nil)
+		f.users.On("GetUsersByUsernames", []string{"admin"}).Return([]models.User{{ID: adminID}}, nil)
+		f.comments.On("UpdateComment", mock.MatchedBy(func(c *models.Comment) bool {
+			return c.Body == "ping @viewer and @admin" && c.EditedAt.Equal(f.now)
+		}), memberID, mock.MatchedBy(func(n []models.Notification) bool {
+			return len(n) == 1 && n[0].UserID == adminID
+		})).Return(nil)
+
+		comment, err := f.service.UpdateComment(42, 7, memberID, "ping @viewer and @admin")
+		require.NoError(t, err)
+		assert.NotNil(t, comment.EditedAt)
+		f.comments.AssertExpectations(t)
+	})
+
+	t.Run("Only the author edits", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		f.comments.On("GetComment", 7).Return(&models.Comment{ID: 7, TaskID: 42, AuthorID: memberID}, nil)
+
+		_, err := f.service.UpdateComment(42, 7, ownerID, "mine now")
+		assert.ErrorIs(t, err, services.ErrNotCommentAuthor)
+		assert.ErrorIs(t, f.service.DeleteComment(42, 7, adminID), services.ErrNotCommentAuthor)
+		f.comments.AssertNotCalled(t, "UpdateComment", mock.Anything, mock.Anything, mock.Anything)
+		f.comments.AssertNotCalled(t, "DeleteComment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Comment of another task", func(t *testing.T) {
+		t.Parallel()
+		f := newCommentFixture()
+		f.comments.On("GetComment", 7).Return(&models.Comment{ID: 7, TaskID: 99, AuthorID: memberID}, nil)
+
+		_, err := f.service.UpdateComment(42, 7, memberID, "edit")
+		assert.ErrorIs(t, err, services.ErrCommentNotFound)
+	})
+}
+
+func TestDeleteComment(t *testing.T) {
+	t.Parallel()
+	f := newCommentFixture()
+	f.comments.On("GetComment", 7).Return(&models.Comment{ID: 7, TaskID: 42, AuthorID: viewerID}, nil)
+	f.comments.On("DeleteComment", 7, viewerID).Return(nil)
+
+	assert.NoError(t, f.service.DeleteComment(42, 7, viewerID))
+	f.comments.AssertExpectations(t)
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+//go:embed templates/digest.txt.tmpl templates/digest.html.tmpl
+var digestTemplates embed.FS
+
+// digestSection is one titled list of tasks in the digest templates
+type digestSection struct {
+	Title string
+	Tasks []models.Task
+}
+
+var digestFuncs = map[string]any{
+	"section": func(title string, tasks []models.Task) digestSection {
+		return digestSection{Title: title, Tasks: tasks}
+	},
+	"date": func(t *models.JSONTime) string {
+		return time.Time(*t).Format("Mon, 02 Jan 15:04")
+	},
+}
+
+var (
+	digestText = template.Must(template.New("digest.txt.tmpl").Funcs(digestFuncs).ParseFS(digestTemplates, "templates/digest.txt.tmpl"))
+	digestHTML = htmltemplate.Must(htmltemplate.New("digest.html.tmpl").Funcs(digestFuncs).ParseFS(digestTemplates, "templates/digest.html.tmpl"))
+)
+
+type IDigestRepository interface {
+	GetDigestSettings(userID int) (*models.DigestSettings, error)
+	SaveDigestSettings(settings *models.DigestSettings) error
+	ClaimDueDigests(now time.Time, limit int) ([]models.DueDigest, error)
+	GetOverdueTasks(userID int, before time.Time) ([]models.Task, error)
+	GetTasksDue(userID int, from, to time.Time) ([]models.Task, error)
+	GetCompletedTasks(userID int, from, to time.Time) ([]models.Task, error)
+	GetAssignedTasks(userID int, from, to time.Time) ([]models.Task, error)
+}
+
+type IDigestUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+type IDigestNotifier interface {
+	Notify(notification *models.Notification) error
+}
+
+// DigestService builds the daily summary of a user's tasks and sends it to the
+// users who opted in, into the inbox and to a verified email address
+type DigestService struct {
+	repo     IDigestRepository
+	users    IDigestUserRepository
+	notifier IDigestNotifier
+	mailer   mailer.Mailer
+	cfg      config.Digest
+	clock    clock.Clock
+	log      zerolog.Logger
+}
+
+func NewDigestService(repo IDigestRepository, users IDigestUserRepository, notifier IDigestNotifier, mailer mailer.Mailer, cfg config.Digest, clock clock.Clock) *DigestService {
+	return &DigestService{
+		repo:     repo,
+		users:    users,
+		notifier: notifier,
+		mailer:   mailer,
+		cfg:      cfg,
+		clock:    clock,
+		log:      logger.GetLogger(),
+	}
+}
+
+// GetSettings returns the digest settings of the user, disabled with the
+// configured send time until it changes them
+func (s *DigestService) GetSettings(userID int) (*models.DigestSettings, error) {
+	settings, err := s.repo.GetDigestSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		settings = &models.DigestSettings{UserID: userID, SendTime: s.cfg.DefaultSendTime}
+	}
+	return settings, nil
+}
+
+// UpdateSettings applies the given fields to the digest settings of the user
+func (s *DigestService) UpdateSettings(userID int, update models.DigestSettingsUpdate) (*models.DigestSettings, error) {
+	settings, err := s.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Enabled != nil {
+		settings.Enabled = *update.Enabled
+	}
+	if update.SendTime != nil {
+		settings.SendTime = strings.TrimSpace(*update.SendTime)
+	}
+	if update.Timezone != nil {
+		settings.Timezone = strings.TrimSpace(*update.Timezone)
+	}
+
+	if err := helpers.ValidateDigestSettings(settings); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveDigestSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// Preview renders the digest the user would receive now, also when it did not
+// opt in or there is nothing to report
+func (s *DigestService) Preview(userID int) (*models.RenderedDigest, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	settings, err := s.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone := settings.Timezone
+	if timezone == "" {
+		timezone = user.Timezone
+	}
+
+	digest, err := s.BuildDigest(userID, timezone, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return RenderDigest(user, digest)
+}
+
+// BuildDigest collects the digest of the user for the local day of now in the
+// time zone, UTC when the zone is unknown
+func (s *DigestService) BuildDigest(userID int, timezone string, now time.Time) (*models.Digest, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		loc, timezone = time.UTC, "UTC"
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	tomorrow, yesterday := today.AddDate(0, 0, 1), today.AddDate(0, 0, -1)
+
+	digest := &models.Digest{Date: today.Format(time.DateOnly), Timezone: timezone}
+
+	if digest.Overdue, err = s.repo.GetOverdueTasks(userID, today.UTC()); err != nil {
+		return nil, err
+	}
+	if digest.DueToday, err = s.repo.GetTasksDue(userID, today.UTC(), tomorrow.UTC()); err != nil {
+		return nil, err
+	}
+	if digest.CompletedYesterday, err = s.repo.GetCompletedTasks(userID, yesterday.UTC(), today.UTC()); err != nil {
+		return nil, err
+	}
+	if digest.NewAssignments, err = s.repo.GetAssignedTasks(userID, now.Add(-24*time.Hour).UTC(), now.UTC()); err != nil {
+		return nil, err
+	}
+
+	for _, tasks := range [][]models.Task{digest.Overdue, digest.DueToday, digest.CompletedYesterday, digest.NewAssignments} {
+		for i := range tasks {
+			localizeTask(&tasks[i], loc)
+		}
+	}
+
+	return digest, nil
+}
+
+// Run sends the due digests until ctx is done
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SendDue(ctx)
+		}
+	}
+}
+
+// SendDue claims the users whose send time passed today and sends their
+// digests. A digest is claimed once a day, a delivery that fails is logged and
+// not retried
+func (s *DigestService) SendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := s.repo.ClaimDueDigests(s.clock.Now(), s.cfg.BatchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to claim due digests")
+			return
+		}
+
+		for _, d := range due {
+			if err := s.send(d); err != nil {
+				s.log.Error().Err(err).Int("user_id", d.UserID).Msg("Failed to send digest")
+			}
+		}
+
+		if len(due) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// send delivers the digest of a user unless there is nothing to report
+func (s *DigestService) send(due models.DueDigest) error {
+	user, err := s.users.GetUserByID(due.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	digest, err := s.BuildDigest(due.UserID, due.Timezone, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if digest.Empty() {
+		return nil
+	}
+
+	rendered, err := RenderDigest(user, digest)
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifier.Notify(&models.Notification{
+		UserID:  user.ID,
+		Type:    models.NotificationDailyDigest,
+		Message: rendered.Subject,
+	}); err != nil {
+		return err
+	}
+
+	if !user.EmailVerified() {
+		return nil
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+	})
+}
+
+// RenderDigest renders the subject and the text and HTML bodies of a digest
+func RenderDigest(user *models.User, digest *models.Digest) (*models.RenderedDigest, error) {
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+
+	data := struct {
+		Name   string
+		Date   string
+		Digest *models.Digest
+	}{Name: name, Date: digest.Date, Digest: digest}
+
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := digestHTML.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &models.RenderedDigest{
+		Digest:  digest,
+		Subject: digestSubject(digest),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func digestSubject(digest *models.Digest) string {
+	counts := []struct {
+		n     int
+		label string
+	}{
+		{len(digest.Overdue), "overdue"},
+		{len(digest.DueToday), "due today"},
+		{len(digest.CompletedYesterday), "completed yesterday"},
+		{len(digest.NewAssignments), "new assignments"},
+	}
+
+	var parts []string
+	for _, c := range counts {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "nothing to do")
+	}
+
+	return fmt.Sprintf("Daily digest for %s: %s", digest.Date, strings.Join(parts, ", "))
+}
+
+// localizeTask shows the times of a task in the time zone of the digest
+func localizeTask(task *models.Task, loc *time.Location) {
+	for _, t := range []**models.JSONTime{&task.DueAt, &task.CompletedAt, &task.AssignedAt} {
+		if *t != nil {
+			local := models.JSONTime(time.Time(**t).In(loc))
+			*t = &local
+		}
+	}
+}
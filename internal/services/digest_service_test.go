@@ -0,0 +1,184 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDigestRepo struct {
+	mock.Mock
+}
+
+func (m *MockDigestRepo) GetDigestSettings(userID int) (*models.DigestSettings, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.DigestSettings), args.Error(1)
+}
+
+func (m *MockDigestRepo) SaveDigestSettings(settings *models.DigestSettings) error {
+	args := m.Called(settings)
+	return args.Error(0)
+}
+
+func (m *MockDigestRepo) ClaimDueDigests(now time.Time, limit int) ([]models.DueDigest, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]models.DueDigest), args.Error(1)
+}
+
+func (m *MockDigestRepo) GetOverdueTasks(userID int, before time.Time) ([]models.Task, error) {
+	args := m.Called(userID, before)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockDigestRepo) GetTasksDue(userID int, from, to time.Time) ([]models.Task, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockDigestRepo) GetCompletedTasks(userID int, from, to time.Time) ([]models.Task, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockDigestRepo) GetAssignedTasks(userID int, from, to time.Time) ([]models.Task, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+var digestConfig = config.Digest{
+	PollInterval:    time.Minute,
+	BatchSize:       10,
+	DefaultSendTime: "08:00",
+}
+
+func newDigestService(now time.Time) (*services.DigestService, *MockDigestRepo, *MockUserRepo, *MockNotifier, *MockMailer) {
+	repo, users, notifier, mail := new(MockDigestRepo), new(MockUserRepo), new(MockNotifier), new(MockMailer)
+	return services.NewDigestService(repo, users, notifier, mail, digestConfig, &FakeClock{now: now}), repo, users, notifier, mail
+}
+
+// expectDigest answers every section query, the Berlin day of now starts at 22:00 UTC
+func expectDigest(repo *MockDigestRepo, userID int, overdue, dueToday, completed, assigned []models.Task) {
+	repo.On("GetOverdueTasks", userID, mock.Anything).Return(overdue, nil)
+	repo.On("GetTasksDue", userID, mock.Anything, mock.Anything).Return(dueToday, nil)
+	repo.On("GetCompletedTasks", userID, mock.Anything, mock.Anything).Return(completed, nil)
+	repo.On("GetAssignedTasks", userID, mock.Anything, mock.Anything).Return(assigned, nil)
+}
+
+func TestBuildDigestLocalDay(t *testing.T) {
+	// 01:30 in Berlin on the 19th while it is still the 18th in UTC
+	now := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+	service, repo, _, _, _ := newDigestService(now)
+
+	today := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+	// task times are stored in UTC, the bounds are passed in UTC
+	sameUTC := func(want time.Time) interface{} {
+		return mock.MatchedBy(func(got time.Time) bool { return got.Equal(want) && got.Location() == time.UTC })
+	}
+	due := models.JSONTime(today.Add(10 * time.Hour))
+	repo.On("GetOverdueTasks", 1, sameUTC(today)).Return([]models.Task{}, nil)
+	repo.On("GetTasksDue", 1, sameUTC(today), sameUTC(today.Add(24*time.Hour))).
+		Return([]models.Task{{ID: 3, Title: "Release", DueAt: &due}}, nil)
+	repo.On("GetCompletedTasks", 1, sameUTC(today.Add(-24*time.Hour)), sameUTC(today)).
+		Return([]models.Task{}, nil)
+	repo.On("GetAssignedTasks", 1, sameUTC(now.Add(-24*time.Hour)), mock.Anything).Return([]models.Task{}, nil)
+
+	digest, err := service.BuildDigest(1, "Europe/Berlin", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-19", digest.Date)
+	assert.Equal(t, "Europe/Berlin", digest.Timezone)
+	require.Len(t, digest.DueToday, 1)
+	assert.Equal(t, "Europe/Berlin", time.Time(*digest.DueToday[0].DueAt).Location().String())
+	assert.False(t, digest.Empty())
+	repo.AssertExpectations(t)
+}
+
+func TestRenderDigest(t *testing.T) {
+	due := models.JSONTime(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	digest := &models.Digest{
+		Date:           "2026-10-19",
+		Overdue:        []models.Task{{Title: "Pay <invoice>", DueAt: &due}},
+		NewAssignments: []models.Task{{Title: "Review"}},
+	}
+
+	rendered, err := services.RenderDigest(&models.User{Username: "alice", DisplayName: "Alice"}, digest)
+	require.NoError(t, err)
+	assert.Equal(t, "Daily digest for 2026-10-19: 1 overdue, 1 new assignments", rendered.Subject)
+	assert.Contains(t, rendered.Text, "Hi Alice,")
+	assert.Contains(t, rendered.Text, "Overdue (1):\n- Pay <invoice> (due Mon, 19 Oct 12:00)")
+	assert.Contains(t, rendered.Text, "New assignments (1):\n- Review")
+	assert.NotContains(t, rendered.Text, "Due today")
+	assert.Contains(t, rendered.HTML, "<li>Pay &lt;invoice&gt; <small>due Mon, 19 Oct 12:00</small></li>")
+	assert.NotContains(t, rendered.HTML, "Completed yesterday")
+}
+
+func TestUpdateDigestSettings(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		service, repo, _, _, _ := newDigestService(time.Now())
+
+		repo.On("GetDigestSettings", 1).Return((*models.DigestSettings)(nil), nil)
+		repo.On("SaveDigestSettings", &models.DigestSettings{UserID: 1, Enabled: true, SendTime: "08:00"}).Return(nil)
+
+		enabled := true
+		settings, err := service.UpdateSettings(1, models.DigestSettingsUpdate{Enabled: &enabled})
+		require.NoError(t, err)
+		assert.True(t, settings.Enabled)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid send time", func(t *testing.T) {
+		service, repo, _, _, _ := newDigestService(time.Now())
+
+		repo.On("GetDigestSettings", 1).Return(&models.DigestSettings{UserID: 1, SendTime: "08:00"}, nil)
+
+		sendTime := "24:00"
+		_, err := service.UpdateSettings(1, models.DigestSettingsUpdate{SendTime: &sendTime})
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "SaveDigestSettings", mock.Anything)
+	})
+
+	t.Run("Unknown time zone", func(t *testing.T) {
+		service, repo, _, _, _ := newDigestService(time.Now())
+
+		repo.On("GetDigestSettings", 1).Return(&models.DigestSettings{UserID: 1, SendTime: "08:00"}, nil)
+
+		timezone := "Mars/Olympus"
+		_, err := service.UpdateSettings(1, models.DigestSettingsUpdate{Timezone: &timezone})
+		assert.ErrorContains(t, err, "unknown time zone")
+	})
+}
+
+func TestSendDueDigests(t *testing.T) {
+	now := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	service, repo, users, notifier, mail := newDigestService(now)
+
+	verifiedAt := now.Add(-time.Hour)
+	repo.On("ClaimDueDigests", now, digestConfig.BatchSize).Return([]models.DueDigest{
+		{UserID: 1, Timezone: "UTC"},
+		{UserID: 2, Timezone: "UTC"},
+	}, nil)
+	users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice", Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+	users.On("GetUserByID", 2).Return(&models.User{ID: 2, Username: "bob"}, nil)
+	expectDigest(repo, 1, []models.Task{{Title: "Pay invoice"}}, []models.Task{}, []models.Task{}, []models.Task{})
+	expectDigest(repo, 2, []models.Task{}, []models.Task{}, []models.Task{}, []models.Task{})
+
+	notifier.On("Notify", mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == 1 && n.Type == models.NotificationDailyDigest && n.Message == "Daily digest for 2026-10-19: 1 overdue"
+	})).Return(nil)
+	mail.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "alice@example.com" && msg.Subject == "Daily digest for 2026-10-19: 1 overdue" && msg.HTML != ""
+	})).Return(nil)
+
+	service.SendDue(context.Background())
+
+	notifier.AssertNumberOfCalls(t, "Notify", 1)
+	mail.AssertNumberOfCalls(t, "Send", 1)
+	repo.AssertExpectations(t)
+}
@@ -2,6 +2,77 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var ErrInvalidCredentials = errors.New("invalid credentials")
+
+var ErrMFARequired = errors.New("second factor required")
+var ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+var ErrMFANotEnrolled = errors.New("two-factor authentication not enrolled")
+var ErrInvalidMFACode = errors.New("invalid two-factor code")
+var ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+var ErrNoEmail = errors.New("no email address set")
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+var ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+var ErrInvalidOIDCState = errors.New("invalid or expired login state")
+var ErrIdentityAlreadyLinked = errors.New("external identity is linked to another user")
+var ErrOIDCProvisioningDisabled = errors.New("no account linked to this identity")
+var ErrOIDCDomainNotAllowed = errors.New("email domain not allowed")
+
+var ErrNoLocalPassword = errors.New("account has no password, enable two-factor authentication or set one via password reset first")
+
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+var ErrWorkspaceForbidden = errors.New("insufficient workspace role")
+var ErrMemberNotFound = errors.New("member not found")
+var ErrOwnerImmutable = errors.New("the workspace owner cannot be changed or removed")
+var ErrOwnsWorkspace = errors.New("the account owns a workspace, delete the workspace first")
+var ErrAlreadyMember = errors.New("user is already a member of the workspace")
+var ErrInvitationPending = errors.New("user already has a pending invitation to the workspace")
+var ErrInvitationNotFound = errors.New("invitation not found or already answered")
+
+var ErrInvalidAssignee = errors.New("assignee has no access to the task")
+
+var ErrCommentNotFound = errors.New("comment not found")
+var ErrNotCommentAuthor = errors.New("only the author can change a comment")
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+var ErrReminderNotFound = errors.New("reminder not found")
+
+var ErrImportInvalid = errors.New("import has invalid rows, nothing was imported")
+
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found or revoked")
+
+var ErrAccessTokenNotFound = errors.New("access token not found")
+
+var ErrDAVNotFound = errors.New("calendar resource not found")
+var ErrDAVPreconditionFailed = errors.New("calendar resource changed, precondition failed")
+var ErrDAVUIDConflict = errors.New("a task with this UID already exists in another calendar")
+
+var ErrUnknownImportSource = errors.New("unknown import source")
+
+var ErrInboundAddressNotFound = errors.New("inbound address not found or revoked")
+
+var ErrChatDisabled = errors.New("chat commands are disabled")
+var ErrInvalidChatSignature = errors.New("invalid or expired request signature")
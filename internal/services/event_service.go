@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/rs/zerolog"
+)
+
+// listenRetryDelay is the pause before the event listener reconnects to the database
+const listenRetryDelay = time.Second
+
+type IEventRepository interface {
+	CreateEvent(event *models.TaskEvent) error
+	GetEvent(id int64) (*models.TaskEvent, error)
+	GetEventsAfter(lastID int64, userID int, limit int) ([]models.TaskEvent, error)
+	GetOldestEventID() (int64, error)
+	DeleteEventsBefore(before time.Time) (int64, error)
+	Listen(ctx context.Context, handle func(eventID int64)) error
+}
+
+type IEventMembership interface {
+	GetMembers(workspaceID int) ([]models.WorkspaceMember, error)
+}
+
+// Subscription receives the task events of one user on this instance. Events
+// is closed when the subscriber falls behind or the instance lost events, the
+// client then reconnects and resumes from its last event id
+type Subscription struct {
+	UserID int
+	Events <-chan models.TaskEvent
+	events chan models.TaskEvent
+}
+
+// EventService fans task changes out to connected clients. Changes are written
+// to the task_events log, every instance learns about them through Postgres
+// LISTEN/NOTIFY and delivers them to its own subscribers
+type EventService struct {
+	repo    IEventRepository
+	members IEventMembership
+	cfg     config.Events
+	clock   clock.Clock
+	log     zerolog.Logger
+
+	mu          sync.Mutex
+	subscribers map[int]map[*Subscription]struct{}
+}
+
+func NewEventService(repo IEventRepository, members IEventMembership, cfg config.Events, clock clock.Clock) *EventService {
+	return &EventService{
+		repo:        repo,
+		members:     members,
+		cfg:         cfg,
+		clock:       clock,
+		log:         logger.GetLogger(),
+		subscribers: make(map[int]map[*Subscription]struct{}),
+	}
+}
+
+// HandleOutbox records a task change in the event log, which notifies every
+// instance about it. Deleted tasks are sent without the task
+func (s *EventService) HandleOutbox(msg *models.OutboxMessage) error {
+	event := &models.TaskEvent{
+		Type:        msg.Type,
+		TaskID:      msg.TaskID,
+		UserID:      msg.UserID,
+		WorkspaceID: msg.WorkspaceID,
+		ActorID:     &msg.ActorID,
+		OutboxID:    &msg.ID,
+	}
+	if msg.Type != models.TaskEventDeleted {
+		event.Payload = msg.Payload
+	}
+
+	return s.repo.CreateEvent(event)
+}
+
+func (s *EventService) Subscribe(userID int) *Subscription {
+	events := make(chan models.TaskEvent, s.cfg.SubscriberBuffer)
+	sub := &Subscription{UserID: userID, Events: events, events: events}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	s.subscribers[userID][sub] = struct{}{}
+
+	return sub
+}
+
+func (s *EventService) Unsubscribe(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drop(sub)
+}
+
+// Replay returns the retained events after lastID the user can see. When
+// events after lastID are no longer retained, or there are more than the
+// replay limit, reset is true and the client has to reload its tasks instead
+func (s *EventService) Replay(userID int, lastID int64) (events []models.TaskEvent, reset bool, err error) {
+	oldest, err := s.repo.GetOldestEventID()
+	if err != nil {
+		return nil, false, err
+	}
+	if oldest > lastID+1 {
+		return nil, true, nil
+	}
+
+	events, err = s.repo.GetEventsAfter(lastID, userID, s.cfg.ReplayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > s.cfg.ReplayLimit {
+		return nil, true, nil
+	}
+
+	return events, false, nil
+}
+
+// Run listens for task events and prunes the event log until ctx is done
+func (s *EventService) Run(ctx context.Context) {
+	go s.prune(ctx)
+
+	for {
+		err := s.repo.Listen(ctx, s.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		s.log.Error().Err(err).Msg("Task event listener stopped, reconnecting")
+
+		// notifications sent while reconnecting are lost, subscribers catch up
+		// by reconnecting with their last event id
+		s.dropAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (s *EventService) dispatch(eventID int64) {
+	event, err := s.repo.GetEvent(eventID)
+	if err != nil || event == nil {
+		return
+	}
+
+	recipients := []int{event.UserID}
+	if event.WorkspaceID != nil {
+		members, err := s.members.GetMembers(*event.WorkspaceID)
+		if err != nil {
+			s.log.Error().Err(err).Int64("event_id", eventID).Msg("Failed to load event recipients")
+			return
+		}
+
+		recipients = recipients[:0]
+		for _, member := range members {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userID := range recipients {
+		for sub := range s.subscribers[userID] {
+			select {
+			case sub.events <- *event:
+			default:
+				s.log.Warn().Int("user_id", userID).Msg("Dropping slow event subscriber")
+				s.drop(sub)
+			}
+		}
+	}
+}
+
+func (s *EventService) prune(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.repo.DeleteEventsBefore(s.clock.Now().Add(-s.cfg.Retention)); err != nil {
+				s.log.Error().Err(err).Msg("Failed to prune task events")
+			}
+		}
+	}
+}
+
+// drop removes the subscription and closes its channel, s.mu must be held
+func (s *EventService) drop(sub *Subscription) {
+	subs, ok := s.subscribers[sub.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subscribers, sub.UserID)
+	}
+	close(sub.events)
+}
+
+func (s *EventService) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			s.drop(sub)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockEventRepo struct {
+	mock.Mock
+}
+
+func (m *MockEventRepo) CreateEvent(event *models.TaskEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockEventRepo) GetEvent(id int64) (*models.TaskEvent, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.TaskEvent), args.Error(1)
+}
+
+func (m *MockEventRepo) GetEventsAfter(lastID int64, userID int, limit int) ([]models.TaskEvent, error) {
+	args := m.Called(lastID, userID, limit)
+	return args.Get(0).([]models.TaskEvent), args.Error(1)
+}
+
+func (m *MockEventRepo) GetOldestEventID() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEventRepo) DeleteEventsBefore(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Listen delivers the ids given to the mock, closes the dispatched channel and
+// then blocks until ctx is done, like a connection that stays healthy
+func (m *MockEventRepo) Listen(ctx context.Context, handle func(eventID int64)) error {
+	args := m.Called()
+	for _, id := range args.Get(0).([]int64) {
+		handle(id)
+	}
+	close(args.Get(1).(chan struct{}))
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+var eventsConfig = config.Events{Retention: 15 * time.Minute, Heartbeat: time.Second, ReplayLimit: 2, SubscriberBuffer: 1}
+
+func newEventService() (*services.EventService, *MockEventRepo, *MockWorkspaceRepo) {
+	repo := new(MockEventRepo)
+	workspaces := new(MockWorkspaceRepo)
+	return services.NewEventService(repo, workspaces, eventsConfig, &FakeClock{now: time.Unix(1700000000, 0)}), repo, workspaces
+}
+
+func receive(t *testing.T, sub *services.Subscription) models.TaskEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event delivered")
+		return models.TaskEvent{}
+	}
+}
+
+// runEventService starts the service with a listener that delivers ids and
+// waits until they are dispatched
+func runEventService(t *testing.T, service *services.EventService, repo *MockEventRepo, ids ...int64) {
+	t.Helper()
+	dispatched := make(chan struct{})
+	repo.On("Listen").Return(ids, dispatched).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go service.Run(ctx)
+	<-dispatched
+}
+
+func TestEventFanOut(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Personal task reaches only its owner", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		repo.On("GetEvent", int64(1)).Return(&models.TaskEvent{ID: 1, Type: models.TaskEventCreated, TaskID: 7, UserID: ownerID}, nil)
+
+		owner := service.Subscribe(ownerID)
+		other := service.Subscribe(memberID)
+		runEventService(t, service, repo, 1)
+
+		assert.Equal(t, int64(1), receive(t, owner).ID)
+		assert.Empty(t, other.Events)
+	})
+
+	t.Run("Workspace task reaches every member", func(t *testing.T) {
+		t.Parallel()
+		service, repo, workspaces := newEventService()
+		workspaceID := teamWorkspace
+		repo.On("GetEvent", int64(2)).Return(&models.TaskEvent{ID: 2, Type: models.TaskEventDeleted, TaskID: 8, UserID: ownerID, WorkspaceID: &workspaceID}, nil)
+		workspaces.On("GetMembers", teamWorkspace).Return([]models.WorkspaceMember{{UserID: ownerID}, {UserID: viewerID}}, nil)
+
+		viewer := service.Subscribe(viewerID)
+		secondTab := service.Subscribe(viewerID)
+		outsider := service.Subscribe(outsiderID)
+		runEventService(t, service, repo, 2)
+
+		assert.Equal(t, models.TaskEventDeleted, receive(t, viewer).Type)
+		assert.Equal(t, int64(2), receive(t, secondTab).ID)
+		assert.Empty(t, outsider.Events)
+	})
+
+	t.Run("Slow subscriber is dropped", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		for _, id := range []int64{1, 2} {
+			repo.On("GetEvent", id).Return(&models.TaskEvent{ID: id, UserID: ownerID}, nil)
+		}
+
+		slow := service.Subscribe(ownerID)
+		runEventService(t, service, repo, 1, 2)
+
+		assert.Equal(t, int64(1), receive(t, slow).ID)
+		_, ok := <-slow.Events
+		assert.False(t, ok)
+		service.Unsubscribe(slow)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Missed events", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		repo.On("GetOldestEventID").Return(int64(5), nil)
+		repo.On("GetEventsAfter", int64(6), memberID, 3).Return([]models.TaskEvent{{ID: 9}}, nil)
+
+		events, reset, err := service.Replay(memberID, 6)
+		require.NoError(t, err)
+		assert.False(t, reset)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("Events pruned", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		repo.On("GetOldestEventID").Return(int64(50), nil)
+
+		events, reset, err := service.Replay(memberID, 6)
+		require.NoError(t, err)
+		assert.True(t, reset)
+		assert.Empty(t, events)
+		repo.AssertNotCalled(t, "GetEventsAfter", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Too many events", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		repo.On("GetOldestEventID").Return(int64(5), nil)
+		repo.On("GetEventsAfter", int64(6), memberID, 3).Return([]models.TaskEvent{{ID: 7}, {ID: 8}, {ID: 9}}, nil)
+
+		_, reset, err := service.Replay(memberID, 6)
+		require.NoError(t, err)
+		assert.True(t, reset)
+	})
+}
+
+func TestEventOutboxHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Update is recorded with the task", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		workspaceID := 10
+		msg := &models.OutboxMessage{ID: 42, Type: models.TaskEventUpdated, TaskID: 5, UserID: ownerID, WorkspaceID: &workspaceID, ActorID: memberID, Payload: []byte(`{"id":5}`)}
+		repo.On("CreateEvent", mock.MatchedBy(func(e *models.TaskEvent) bool {
+			return e.Type == models.TaskEventUpdated && e.TaskID == 5 && *e.WorkspaceID == workspaceID &&
+				*e.ActorID == memberID && *e.OutboxID == 42 && string(e.Payload) == `{"id":5}`
+		})).Return(nil).Once()
+
+		require.NoError(t, service.HandleOutbox(msg))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Deletion is recorded without the task", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newEventService()
+		msg := &models.OutboxMessage{ID: 43, Type: models.TaskEventDeleted, TaskID: 5, UserID: ownerID, ActorID: ownerID, Payload: []byte(`{"id":5}`)}
+		repo.On("CreateEvent", mock.MatchedBy(func(e *models.TaskEvent) bool {
+			return e.Type == models.TaskEventDeleted && e.Payload == nil && *e.OutboxID == 43
+		})).Return(nil).Once()
+
+		require.NoError(t, service.HandleOutbox(msg))
+		repo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+const exportFormatVersion = 1
+
+type IExportUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+type IExportTaskRepository interface {
+	GetTasksByUserID(userID int) ([]models.Task, error)
+}
+
+type IExportIdentityRepository interface {
+	GetIdentitiesByUserID(userID int) ([]models.UserIdentity, error)
+}
+
+type IExportMFARepository interface {
+	GetTOTP(userID int) (*models.UserTOTP, error)
+}
+
+type IExportAuditRepository interface {
+	GetRecordsByUserID(userID int) ([]models.AuditRecord, error)
+}
+
+type IExportWorkspaceRepository interface {
+	GetWorkspacesByUserID(userID int) ([]models.Workspace, error)
+}
+
+type IExportCommentRepository interface {
+	GetCommentsByAuthorID(authorID int) ([]models.Comment, error)
+}
+
+type IExportNotificationRepository interface {
+	GetNotificationsByUserID(userID int) ([]models.Notification, error)
+	GetPreferences(userID int) ([]models.NotificationPreference, error)
+}
+
+type IExportWebhookRepository interface {
+	GetWebhooks(userID int) ([]models.Webhook, error)
+	GetDeliveriesByUserID(userID int) ([]models.WebhookDelivery, error)
+}
+
+type IExportReminderRepository interface {
+	GetRemindersByUserID(userID int) ([]models.Reminder, error)
+}
+
+type IExportDigestRepository interface {
+	GetDigestSettings(userID int) (*models.DigestSettings, error)
+}
+
+type IExportAccessTokenRepository interface {
+	GetAccessTokens(userID int) ([]models.PersonalAccessToken, error)
+}
+
+type IExportTokenRepository interface {
+	GetTokens(userID int, purpose string) ([]models.UserToken, error)
+}
+
+// exportSection produces one JSON file of the archive
+type exportSection struct {
+	name  string
+	fetch func(user *models.User) (any, error)
+}
+
+// ExportService collects everything stored about a user for a data export.
+// Secrets such as password hashes, TOTP secrets and token hashes are left out
+type ExportService struct {
+	users    IExportUserRepository
+	sections []exportSection
+	clock    clock.Clock
+}
+
+func NewExportService(
+	users IExportUserRepository,
+	tasks IExportTaskRepository,
+	identities IExportIdentityRepository,
+	mfa IExportMFARepository,
+	audit IExportAuditRepository,
+	workspaces IExportWorkspaceRepository,
+	comments IExportCommentRepository,
+	notifications IExportNotificationRepository,
+	webhooks IExportWebhookRepository,
+	reminders IExportReminderRepository,
+	digests IExportDigestRepository,
+	accessTokens IExportAccessTokenRepository,
+	tokens IExportTokenRepository,
+	clock clock.Clock,
+) *ExportService {
+	return &ExportService{
+		users: users,
+		clock: clock,
+		sections: []exportSection{
+			{"profile.json", func(user *models.User) (any, error) {
+				totp, err := mfa.GetTOTP(user.ID)
+				if err != nil {
+					return nil, err
+				}
+				return models.NewProfile(user, totp.Confirmed()), nil
+			}},
+			{"tasks.json", func(user *models.User) (any, error) {
+				return nonNil(tasks.GetTasksByUserID(user.ID))
+			}},
+			{"comments.json", func(user *models.User) (any, error) {
+				return nonNil(comments.GetCommentsByAuthorID(user.ID))
+			}},
+			{"workspaces.json", func(user *models.User) (any, error) {
+				return nonNil(workspaces.GetWorkspacesByUserID(user.ID))
+			}},
+			{"identities.json", func(user *models.User) (any, error) {
+				// linked chat accounts are identities of the chat provider
+				return nonNil(identities.GetIdentitiesByUserID(user.ID))
+			}},
+			{"security.json", func(user *models.User) (any, error) {
+				totp, err := mfa.GetTOTP(user.ID)
+				if err != nil {
+					return nil, err
+				}
+				security := map[string]any{
+					"has_password":      user.PasswordHash != "",
+					"email_verified_at": user.EmailVerifiedAt,
+				}
+				if totp.Confirmed() {
+					security["mfa_confirmed_at"] = totp.ConfirmedAt
+				}
+				return security, nil
+			}},
+			{"audit_log.json", func(user *models.User) (any, error) {
+				return nonNil(audit.GetRecordsByUserID(user.ID))
+			}},
+			{"notifications.json", func(user *models.User) (any, error) {
+				return nonNil(notifications.GetNotificationsByUserID(user.ID))
+			}},
+			{"notification_preferences.json", func(user *models.User) (any, error) {
+				return nonNil(notifications.GetPreferences(user.ID))
+			}},
+			{"webhooks.json", func(user *models.User) (any, error) {
+				items, err := webhooks.GetWebhooks(user.ID)
+				for i := range items {
+					items[i].Secret = ""
+				}
+				return nonNil(items, err)
+			}},
+			{"webhook_deliveries.json", func(user *models.User) (any, error) {
+				return nonNil(webhooks.GetDeliveriesByUserID(user.ID))
+			}},
+			{"reminders.json", func(user *models.User) (any, error) {
+				return nonNil(reminders.GetRemindersByUserID(user.ID))
+			}},
+			{"digest_settings.json", func(user *models.User) (any, error) {
+				// null when the user never changed them
+				return digests.GetDigestSettings(user.ID)
+			}},
+			{"access_tokens.json", func(user *models.User) (any, error) {
+				return nonNil(accessTokens.GetAccessTokens(user.ID))
+			}},
+			{"inbound_addresses.json", func(user *models.User) (any, error) {
+				// only the hash of an address is stored, its times are exported
+				stored, err := tokens.GetTokens(user.ID, models.TokenPurposeInboundMail)
+				if err != nil {
+					return nil, err
+				}
+				addresses := []map[string]any{}
+				for _, token := range stored {
+					addresses = append(addresses, map[string]any{
+						"created_at": token.CreatedAt,
+						"expires_at": token.ExpiresAt,
+					})
+				}
+				return addresses, nil
+			}},
+		},
+	}
+}
+
+// BuildExport gathers all sections up front so failures surface before
+// anything is written to the client
+func (s *ExportService) BuildExport(userID int) (*models.DataExport, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	export := &models.DataExport{
+		Username:   user.Username,
+		ExportedAt: s.clock.Now().UTC(),
+	}
+
+	for _, section := range s.sections {
+		data, err := section.fetch(user)
+		if err != nil {
+			return nil, err
+		}
+		export.Files = append(export.Files, models.ExportFile{Name: section.name, Data: data})
+	}
+
+	return export, nil
+}
+
+// WriteArchive streams the export as a ZIP with one JSON file per section and a manifest
+func (s *ExportService) WriteArchive(w io.Writer, export *models.DataExport) error {
+	zw := zip.NewWriter(w)
+
+	names := make([]string, 0, len(export.Files))
+	for _, file := range export.Files {
+		if err := writeJSONFile(zw, file.Name, export.ExportedAt, file.Data); err != nil {
+			return err
+		}
+		names = append(names, file.Name)
+	}
+
+	manifest := map[string]any{
+		"format_version": exportFormatVersion,
+		"username":       export.Username,
+		"exported_at":    export.ExportedAt,
+		"files":          names,
+	}
+	if err := writeJSONFile(zw, "manifest.json", export.ExportedAt, manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeJSONFile(zw *zip.Writer, name string, modified time.Time, data any) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// nonNil turns a nil slice into an empty one so it's exported as [] rather than null
+func nonNil[T any](items []T, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return items, nil
+}
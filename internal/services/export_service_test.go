@@ -0,0 +1,145 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exportFixture struct {
+	users         *MockUserRepo
+	tasks         *MockTaskRepo
+	identities    *MockIdentityRepo
+	mfa           *MockMFARepo
+	audit         *MockAuditRepo
+	workspaces    *MockWorkspaceRepo
+	comments      *MockCommentRepo
+	notifications *MockNotificationRepo
+	webhooks      *MockWebhookRepo
+	reminders     *MockReminderRepo
+	digests       *MockDigestRepo
+	accessTokens  *MockAccessTokenRepo
+	tokens        *MockTokenRepo
+	service       *services.ExportService
+}
+
+func newExportFixture() *exportFixture {
+	f := &exportFixture{
+		users:         new(MockUserRepo),
+		tasks:         new(MockTaskRepo),
+		identities:    new(MockIdentityRepo),
+		mfa:           new(MockMFARepo),
+		audit:         new(MockAuditRepo),
+		workspaces:    new(MockWorkspaceRepo),
+		comments:      new(MockCommentRepo),
+		notifications: new(MockNotificationRepo),
+		webhooks:      new(MockWebhookRepo),
+		reminders:     new(MockReminderRepo),
+		digests:       new(MockDigestRepo),
+		accessTokens:  new(MockAccessTokenRepo),
+		tokens:        new(MockTokenRepo),
+	}
+	f.service = services.NewExportService(f.users, f.tasks, f.identities, f.mfa, f.audit, f.workspaces, f.comments,
+		f.notifications, f.webhooks, f.reminders, f.digests, f.accessTokens, f.tokens, &FakeClock{now: time.Unix(1700000000, 0)})
+	return f
+}
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestExport(t *testing.T) {
+	f := newExportFixture()
+
+	confirmedAt := time.Unix(1690000000, 0)
+	f.users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice", PasswordHash: "secret-hash"}, nil)
+	f.mfa.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: "TOTPSECRET", ConfirmedAt: &confirmedAt}, nil)
+	f.tasks.On("GetTasksByUserID", 1).Return([]models.Task{{ID: 5, UserID: 1, Title: "Buy milk"}}, nil)
+	f.comments.On("GetCommentsByAuthorID", 1).Return([]models.Comment{{ID: 4, TaskID: 5, Body: "On it"}}, nil)
+	f.workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 3, Name: "Team", Role: models.RoleAdmin}}, nil)
+	f.identities.On("GetIdentitiesByUserID", 1).Return([]models.UserIdentity{{ID: 9, UserID: 1, Provider: "chat", Subject: "T1:U1"}}, nil)
+	f.audit.On("GetRecordsByUserID", 1).Return([]models.AuditRecord{{ID: 2, Event: models.AuditEventLoginLockout}}, nil)
+	f.notifications.On("GetNotificationsByUserID", 1).Return([]models.Notification{{ID: 6, UserID: 1, Type: models.NotificationTaskAssigned, Message: "Release"}}, nil)
+	f.webhooks.On("GetWebhooks", 1).Return([]models.Webhook{{ID: 5, UserID: 1, URL: "https://ci.example.com/hook", Secret: "WEBHOOKSECRET"}}, nil)
+	f.webhooks.On("GetDeliveriesByUserID", 1).Return([]models.WebhookDelivery{{ID: 7, WebhookID: 5, Status: models.DeliverySucceeded, Secret: "WEBHOOKSECRET"}}, nil)
+	f.reminders.On("GetRemindersByUserID", 1).Return([]models.Reminder{{ID: 8, TaskID: 5, UserID: 1, Status: models.ReminderDismissed}}, nil)
+	f.digests.On("GetDigestSettings", 1).Return(&models.DigestSettings{UserID: 1, Enabled: true, SendTime: "08:30", Timezone: "Europe/Berlin"}, nil)
+	f.accessTokens.On("GetAccessTokens", 1).Return([]models.PersonalAccessToken{{ID: 3, UserID: 1, Name: "DAVx5", TokenHash: "TOKENHASH"}}, nil)
+	f.tokens.On("GetTokens", 1, models.TokenPurposeInboundMail).Return([]models.UserToken{{ID: 4, UserID: 1, Purpose: models.TokenPurposeInboundMail, TokenHash: "TOKENHASH",
+		ExpiresAt: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil)
+	f.notifications.On("GetPreferences", 1).Return([]models.NotificationPreference{{Type: models.NotificationDailyDigest, Enabled: false}}, nil)
+
+	export, err := f.service.BuildExport(1)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.service.WriteArchive(&buf, export))
+
+	files := readArchive(t, buf.Bytes())
+	for _, name := range []string{"manifest.json", "profile.json", "tasks.json", "comments.json", "workspaces.json", "identities.json", "security.json", "audit_log.json",
+		"notifications.json", "notification_preferences.json", "webhooks.json", "webhook_deliveries.json",
+		"reminders.json", "digest_settings.json", "access_tokens.json",
+		"inbound_addresses.json"} {
+		assert.Contains(t, files, name)
+	}
+
+	var profile models.Profile
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "alice", profile.Username)
+	assert.True(t, profile.MFAEnabled)
+
+	var tasks []map[string]any
+	require.NoError(t, json.Unmarshal(files["tasks.json"], &tasks))
+	assert.Equal(t, "Buy milk", tasks[0]["title"])
+
+	assert.Contains(t, string(files["workspaces.json"]), `"role": "admin"`)
+	assert.Contains(t, string(files["identities.json"]), `"provider": "chat"`)
+	assert.Contains(t, string(files["notifications.json"]), `"message": "Release"`)
+	assert.JSONEq(t, `[{"type": "daily_digest", "enabled": false}]`, string(files["notification_preferences.json"]))
+	assert.Contains(t, string(files["webhooks.json"]), `"url": "https://ci.example.com/hook"`)
+	assert.Contains(t, string(files["webhook_deliveries.json"]), `"status": "succeeded"`)
+	assert.Contains(t, string(files["reminders.json"]), `"status": "dismissed"`)
+	assert.JSONEq(t, `{"enabled": true, "send_time": "08:30", "timezone": "Europe/Berlin"}`, string(files["digest_settings.json"]))
+	assert.Contains(t, string(files["access_tokens.json"]), `"name": "DAVx5"`)
+	assert.JSONEq(t, `[{"created_at": "2026-01-01T00:00:00Z", "expires_at": "2027-01-01T00:00:00Z"}]`, string(files["inbound_addresses.json"]))
+
+	for name, content := range files {
+		assert.NotContains(t, string(content), "secret-hash", name)
+		assert.NotContains(t, string(content), "TOTPSECRET", name)
+		assert.NotContains(t, string(content), "WEBHOOKSECRET", name)
+		assert.NotContains(t, string(content), "TOKENHASH", name)
+	}
+}
+
+func TestExportFailsBeforeWriting(t *testing.T) {
+	f := newExportFixture()
+
+	f.users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice"}, nil)
+	f.mfa.On("GetTOTP", 1).Return((*models.UserTOTP)(nil), nil)
+	f.tasks.On("GetTasksByUserID", 1).Return([]models.Task(nil), errors.New("db down"))
+
+	export, err := f.service.BuildExport(1)
+	assert.Error(t, err)
+	assert.Nil(t, export)
+}
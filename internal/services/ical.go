@@ -0,0 +1,429 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+const (
+	icalProdID     = "-//todo-app//Tasks//EN"
+	icalTimeFormat = "20060102T150405Z"
+	// icalLineLimit is the length of a content line in octets before it is folded
+	icalLineLimit = 75
+)
+
+// icalStatuses maps task statuses to VTODO statuses. Other statuses are sent
+// as NEEDS-ACTION with the task status in X-TODO-STATUS, so they survive a
+// round trip through a file
+var icalStatuses = map[string]string{
+	"pending":             "NEEDS-ACTION",
+	"in progress":         "IN-PROCESS",
+	models.TaskStatusDone: "COMPLETED",
+	"cancelled":           "CANCELLED",
+}
+
+// icalPriorities maps task priorities to the RFC 5545 scale where 1 is the
+// highest and 9 the lowest priority
+var icalPriorities = map[string]int{
+	models.TaskPriorityHigh:   1,
+	models.TaskPriorityMedium: 5,
+	models.TaskPriorityLow:    9,
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsTaskEncoder writes tasks as the VTODO components of a VCALENDAR. With
+// events a task with a due date is also written as a VEVENT at that time,
+// related to its VTODO
+type icsTaskEncoder struct {
+	w      io.Writer
+	stamp  time.Time
+	name   string
+	events bool
+	err    error
+}
+
+func newICSTaskEncoder(w io.Writer, stamp time.Time, name string, events bool) *icsTaskEncoder {
+	return &icsTaskEncoder{w: w, stamp: stamp.UTC(), name: name, events: events}
+}
+
+func (e *icsTaskEncoder) begin() error {
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", icalProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	if e.name != "" {
+		e.line("X-WR-CALNAME", icalEscaper.Replace(e.name))
+	}
+	return e.err
+}
+
+func (e *icsTaskEncoder) encode(task *models.Task) error {
+	uid := taskUID(task)
+
+	e.line("BEGIN", "VTODO")
+	e.line("UID", uid)
+	e.line("DTSTAMP", e.stamp.Format(icalTimeFormat))
+	if !time.Time(task.CreatedAt).IsZero() {
+		e.line("CREATED", icalTime(task.CreatedAt))
+	}
+	e.line("SUMMARY", icalEscaper.Replace(task.Title))
+	if task.Description != "" {
+		e.line("DESCRIPTION", icalEscaper.Replace(task.Description))
+	}
+	if task.DueAt != nil {
+		e.line("DUE", icalTime(*task.DueAt))
+	}
+	if status, ok := icalStatuses[task.Status]; ok {
+		e.line("STATUS", status)
+	} else {
+		e.line("STATUS", "NEEDS-ACTION")
+		e.line("X-TODO-STATUS", icalEscaper.Replace(task.Status))
+	}
+	if task.CompletedAt != nil {
+		e.line("COMPLETED", icalTime(*task.CompletedAt))
+	}
+	if priority, ok := icalPriorities[task.Priority]; ok {
+		e.line("PRIORITY", strconv.Itoa(priority))
+	}
+	if task.Recurrence != "" {
+		e.line("RRULE", task.Recurrence)
+	}
+	e.line("END", "VTODO")
+
+	if e.events && task.DueAt != nil {
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", "event-"+uid)
+		e.line("DTSTAMP", e.stamp.Format(icalTimeFormat))
+		e.line("SUMMARY", icalEscaper.Replace(task.Title))
+		if task.Description != "" {
+			e.line("DESCRIPTION", icalEscaper.Replace(task.Description))
+		}
+		e.line("DTSTART", icalTime(*task.DueAt))
+		if task.Status == "cancelled" {
+			e.line("STATUS", "CANCELLED")
+		}
+		if task.Recurrence != "" {
+			e.line("RRULE", task.Recurrence)
+		}
+		e.line("RELATED-TO", uid)
+		e.line("END", "VEVENT")
+	}
+
+	return e.err
+}
+
+func (e *icsTaskEncoder) end() error {
+	e.line("END", "VCALENDAR")
+	return e.err
+}
+
+// line writes a content line ended by CRLF, folding it into continuation lines
+// starting with a space so that no line is longer than 75 octets. A line is
+// never split inside a UTF-8 sequence. Control characters other than tab are
+// left out, a line break in a value would start a property of its own
+func (e *icsTaskEncoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+
+	value = strings.Map(func(r rune) rune {
+		if r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+
+	var b strings.Builder
+	rest := name + ":" + value
+	limit := icalLineLimit
+	for len(rest) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(rest[cut]) {
+			cut--
+		}
+		b.WriteString(rest[:cut])
+		b.WriteString("\r\n ")
+		rest = rest[cut:]
+		limit = icalLineLimit - 1
+	}
+	b.WriteString(rest)
+	b.WriteString("\r\n")
+
+	_, e.err = io.WriteString(e.w, b.String())
+}
+
+// taskUID identifies a task in calendar files, an imported task keeps the id
+// it had in the system it came from
+func taskUID(task *models.Task) string {
+	if task.ExternalID != nil {
+		return *task.ExternalID
+	}
+	return fmt.Sprintf("task-%d@todo-app", task.ID)
+}
+
+func icalTime(t models.JSONTime) string {
+	return time.Time(t).UTC().Format(icalTimeFormat)
+}
+
+// icalProperty is one unfolded content line of an iCalendar file
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// decodeICSRecords reads the VTODO and VEVENT components of an iCalendar file
+// as import records
+func decodeICSRecords(r io.Reader, add func(map[string]string) error) error {
+	return decodeICalendar(r, []string{"VTODO", "VEVENT"}, add)
+}
+
+// decodeICalendar reads the given components of an iCalendar file as import
+// records. Events related to a to-do, as written by the feed, are skipped and
+// nested components such as alarms are ignored
+func decodeICalendar(r io.Reader, components []string, add func(map[string]string) error) error {
+	var (
+		calendar  bool
+		component string
+		nested    int
+		props     []icalProperty
+	)
+
+	err := readICalLines(r, func(line string) error {
+		prop, ok := parseICalProperty(line)
+		if !ok {
+			return invalidFile(fmt.Errorf("invalid content line %q", line))
+		}
+
+		switch {
+		case prop.name == "BEGIN" && component != "":
+			nested++
+		case prop.name == "END" && nested > 0:
+			nested--
+		case nested > 0:
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR"):
+			calendar = true
+		case prop.name == "BEGIN" && calendar && slices.Contains(components, strings.ToUpper(prop.value)):
+			component, props = strings.ToUpper(prop.value), nil
+		case prop.name == "END" && component != "" && strings.EqualFold(prop.value, component):
+			record := icalRecord(component, props)
+			component = ""
+			if record != nil {
+				return add(record)
+			}
+		case component != "":
+			props = append(props, prop)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !calendar {
+		return invalidFile(errors.New("no VCALENDAR found"))
+	}
+	return nil
+}
+
+// icalRecord turns the properties of a component into an import record, nil
+// for an event that belongs to a to-do
+func icalRecord(component string, props []icalProperty) map[string]string {
+	record := make(map[string]string)
+	icalStatus := ""
+
+	for _, prop := range props {
+		switch prop.name {
+		case "UID":
+			record["external_id"] = prop.value
+		case "SUMMARY":
+			record["title"] = icalUnescape(prop.value)
+		case "DESCRIPTION":
+			record["description"] = icalUnescape(prop.value)
+		case "STATUS":
+			icalStatus = strings.ToUpper(prop.value)
+		case "X-TODO-STATUS":
+			record["status"] = icalUnescape(prop.value)
+		case "PRIORITY":
+			record["priority"] = taskPriority(prop.value)
+		case "RRULE":
+			record["recurrence"] = prop.value
+		case "COMPLETED":
+			record["completed_at"] = icalRecordTime(prop)
+		case "DUE":
+			if component == "VTODO" {
+				record["due_at"] = icalRecordTime(prop)
+			}
+		case "DTSTART":
+			if component == "VEVENT" {
+				record["due_at"] = icalRecordTime(prop)
+			}
+		case "RELATED-TO":
+			if component == "VEVENT" {
+				return nil
+			}
+		}
+	}
+
+	if _, ok := record["status"]; !ok {
+		for status, value := range icalStatuses {
+			if value == icalStatus && (component == "VTODO" || value == "CANCELLED") {
+				record["status"] = status
+			}
+		}
+	}
+
+	return record
+}
+
+// taskPriority maps an RFC 5545 priority to a task priority, 1 to 4 is high,
+// 5 medium and 6 to 9 low. Other values are kept to fail validation
+func taskPriority(value string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || n < 0 || n > 9:
+		return value
+	case n == 0:
+		return ""
+	case n < 5:
+		return models.TaskPriorityHigh
+	case n == 5:
+		return models.TaskPriorityMedium
+	default:
+		return models.TaskPriorityLow
+	}
+}
+
+// icalRecordTime converts a DATE or DATE-TIME value to RFC 3339. Floating times
+// and dates are read in their TZID, or in the server time zone without one.
+// Values that do not parse are kept to fail validation
+func icalRecordTime(prop icalProperty) string {
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+
+	var (
+		t   time.Time
+		err error
+	)
+	switch {
+	case len(prop.value) == len("20060102"):
+		t, err = time.ParseInLocation("20060102", prop.value, loc)
+	case strings.HasSuffix(prop.value, "Z"):
+		t, err = time.Parse(icalTimeFormat, prop.value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", prop.value, loc)
+	}
+	if err != nil {
+		return prop.value
+	}
+	return t.Format(time.RFC3339)
+}
+
+// readICalLines calls fn with every content line of r, continuation lines are
+// unfolded into the line they belong to. A file that cannot be read gives a
+// validation error
+func readICalLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var current strings.Builder
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+		line := current.String()
+		current.Reset()
+		return fn(line)
+	}
+
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			current.WriteString(line[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		current.WriteString(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return invalidFile(err)
+	}
+
+	return flush()
+}
+
+// parseICalProperty splits a content line into its name, parameters and value
+func parseICalProperty(line string) (icalProperty, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return icalProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icalProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return prop, true
+}
+
+// icalUnescape reverses the escaping of a TEXT value
+func icalUnescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxMailParts bounds the MIME parts read from one message
+const maxMailParts = 100
+
+// inboundMail is what a task is made of: the subject, the body as plain text
+// and the names of the attachments
+type inboundMail struct {
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []string
+	MessageID   string
+	// To lists the addresses of the To, Cc, Delivered-To and X-Original-To headers
+	To []string
+}
+
+// parseInboundMail reads an RFC 822 message. Of a multipart message the first
+// text/plain part is the body, the first text/html part is kept for messages
+// without one. Transfer encodings and charsets are decoded
+func parseInboundMail(r io.Reader) (*inboundMail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, invalidMessage(err)
+	}
+
+	result := &inboundMail{}
+	decoder := &mime.WordDecoder{CharsetReader: charsetReader}
+	if subject, err := decoder.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		result.Subject = subject
+	} else {
+		result.Subject = msg.Header.Get("Subject")
+	}
+
+	result.MessageID = strings.TrimSpace(msg.Header.Get("Message-Id"))
+
+	for _, key := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		for _, value := range msg.Header[key] {
+			addresses, err := mail.ParseAddressList(value)
+			if err != nil {
+				continue
+			}
+			for _, address := range addresses {
+				result.To = append(result.To, address.Address)
+			}
+		}
+	}
+
+	parts := 0
+	if err := result.readPart(textproto.MIMEHeader(msg.Header), msg.Body, decoder, &parts); err != nil {
+		return nil, invalidMessage(err)
+	}
+	return result, nil
+}
+
+func (m *inboundMail) readPart(header textproto.MIMEHeader, body io.Reader, decoder *mime.WordDecoder, parts *int) error {
+	if *parts++; *parts > maxMailParts {
+		return fmt.Errorf("more than %d parts", maxMailParts)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.readPart(part.Header, part, decoder, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dispositionParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if disposition == "attachment" || name != "" || (mediaType != "text/plain" && mediaType != "text/html") {
+		if name == "" {
+			name = mediaType
+		} else if decoded, err := decoder.DecodeHeader(name); err == nil {
+			name = decoded
+		}
+		m.Attachments = append(m.Attachments, name)
+		return nil
+	}
+
+	if (mediaType == "text/plain" && m.Text != "") || (mediaType == "text/html" && m.HTML != "") {
+		return nil
+	}
+
+	text, err := readMailText(header.Get("Content-Transfer-Encoding"), params["charset"], body)
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/html" {
+		m.HTML = text
+	} else {
+		m.Text = text
+	}
+	return nil
+}
+
+// readMailText decodes the transfer encoding and the charset of a text part
+func readMailText(encoding, charset string, body io.Reader) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	if reader, err := charsetReader(charset, body); err == nil {
+		body = reader
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(raw), "�"), nil
+}
+
+// charsetReader decodes the charsets known to web browsers, UTF-8 and unknown
+// charsets are read as they are
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return input, nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unknown charset %q", charset)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+func invalidMessage(err error) error {
+	return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("message", err.Error()))
+}
+
+var (
+	// htmlSkipped matches the elements whose content is not text
+	htmlSkipped = regexp.MustCompile(`(?is)<(head|style|script)\b.*?</(head|style|script)\s*>`)
+	// htmlBreak matches the tags that end a line
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6]|blockquote)\s*>`)
+	// htmlListItem matches the start of a list item
+	htmlListItem = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	// blankLines matches runs of more than one empty line
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText strips the tags of an HTML body and keeps its line breaks
+func htmlToText(body string) string {
+	body = htmlSkipped.ReplaceAllString(body, "")
+	body = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(body)
+	body = htmlBreak.ReplaceAllString(body, "\n")
+	body = htmlListItem.ReplaceAllString(body, "- ")
+	body = html.UnescapeString(htmlTag.ReplaceAllString(body, ""))
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+// mailReplyPrefix matches the reply and forward markers of a subject
+var mailReplyPrefix = regexp.MustCompile(`(?i)^((re|fwd?|aw|wg)\s*(\[\d+\])?\s*:\s*)+`)
+
+// mailTask makes the task of a message. Subject words #tag add a tag and
+// !low, !medium or !high set the priority, the rest is the title. The body
+// without the signature is the description
+func mailTask(message *inboundMail) models.Task {
+	task := models.Task{Status: "pending", Tags: models.StringList{}}
+
+	var words []string
+	subject := mailReplyPrefix.ReplaceAllString(strings.TrimSpace(message.Subject), "")
+	for _, word := range strings.Fields(subject) {
+		switch {
+		case len(word) > 1 && word[0] == '#':
+			if tag := importedTag(word[1:]); tag != "" && !slices.Contains(task.Tags, tag) && len(task.Tags) < maxImportedTags {
+				task.Tags = append(task.Tags, tag)
+			}
+		case len(word) > 1 && word[0] == '!' && slices.Contains(models.TaskPriorities, strings.ToLower(word[1:])):
+			task.Priority = strings.ToLower(word[1:])
+		default:
+			words = append(words, word)
+		}
+	}
+
+	task.Title = strings.Join(words, " ")
+	if task.Title == "" {
+		task.Title = "(no subject)"
+	}
+	if message.MessageID != "" {
+		// hashed, a Message-ID may be longer than an external id
+		sum := sha256.Sum256([]byte(message.MessageID))
+		externalID := "mail:" + hex.EncodeToString(sum[:])
+		task.ExternalID = &externalID
+	}
+	if len(task.Title) > maxImportedTitle {
+		task.Title = truncateBytes(task.Title, maxImportedTitle-len("…")) + "…"
+	}
+
+	body := message.Text
+	if body == "" && message.HTML != "" {
+		body = htmlToText(message.HTML)
+	}
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	if i := strings.Index("\n"+body, "\n-- \n"); i >= 0 {
+		body = body[:max(i-1, 0)]
+	}
+	task.Description = strings.TrimSpace(body)
+
+	return task
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// inboundTokenSize is shorter than tokenSize so the address fits the 64
+// characters of an email local part
+const inboundTokenSize = 20
+
+// inboundTokenEncoding is lower case, mail servers may change the case of an
+// address
+var inboundTokenEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+type ITaskCreator interface {
+	CreateTask(task *models.Task) error
+}
+
+type IInboundTaskRepository interface {
+	GetExternalIDs(userID int, externalIDs []string) ([]string, error)
+}
+
+// InboundMailService turns mail sent to the secret address of a user into
+// tasks. The address is <localPart>+<token>@<domain>, the token is stored
+// hashed among the user tokens and issuing a new address revokes the previous one
+type InboundMailService struct {
+	tokens ITokenRepository
+	tasks  ITaskCreator
+	repo   IInboundTaskRepository
+	cfg    config.Inbound
+	clock  clock.Clock
+	log    zerolog.Logger
+}
+
+func NewInboundMailService(tokens ITokenRepository, tasks ITaskCreator, repo IInboundTaskRepository, cfg config.Inbound, clock clock.Clock) *InboundMailService {
+	return &InboundMailService{tokens: tokens, tasks: tasks, repo: repo, cfg: cfg, clock: clock, log: logger.GetLogger()}
+}
+
+// CreateAddress issues a new inbound address for the user
+func (s *InboundMailService) CreateAddress(userID int) (*models.InboundAddress, error) {
+	buf := make([]byte, inboundTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := inboundTokenEncoding.EncodeToString(buf)
+
+	expiresAt := s.clock.Now().Add(s.cfg.AddressTTL)
+	if err := storeUserToken(s.tokens, userID, models.TokenPurposeInboundMail, token, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.InboundAddress{Address: s.cfg.LocalPart + "+" + token + "@" + s.cfg.Domain, ExpiresAt: expiresAt}, nil
+}
+
+// RevokeAddress stops the inbound address of the user from accepting mail
+func (s *InboundMailService) RevokeAddress(userID int) error {
+	return s.tokens.DeleteTokens(userID, models.TokenPurposeInboundMail)
+}
+
+// Recipient returns the user of an inbound address
+func (s *InboundMailService) Recipient(address string) (int, error) {
+	address = strings.ToLower(strings.Trim(strings.TrimSpace(address), "<>"))
+	at := strings.LastIndex(address, "@")
+	if at < 0 || address[at+1:] != strings.ToLower(s.cfg.Domain) {
+		return 0, ErrInboundAddressNotFound
+	}
+
+	token, ok := strings.CutPrefix(address[:at], strings.ToLower(s.cfg.LocalPart)+"+")
+	if !ok || token == "" {
+		return 0, ErrInboundAddressNotFound
+	}
+
+	found, err := s.tokens.FindToken(hashToken(token), models.TokenPurposeInboundMail, s.clock.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return 0, ErrInboundAddressNotFound
+		}
+		return 0, err
+	}
+	return found.UserID, nil
+}
+
+// Receive creates a task from an RFC 822 message for every user it is
+// addressed to. The recipients are those of the SMTP envelope, without them
+// the recipient headers of the message are read. The Message-ID is kept as
+// the external id of the tasks, so a redelivered message, also after a failure
+// part way through the recipients, only creates the tasks that are missing
+func (s *InboundMailService) Receive(r io.Reader, recipients []string) (*models.InboundMailResult, error) {
+	message, err := parseInboundMail(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		recipients = message.To
+	}
+
+	var users []int
+	for _, recipient := range recipients {
+		userID, err := s.Recipient(recipient)
+		if errors.Is(err, ErrInboundAddressNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(users, userID) {
+			users = append(users, userID)
+		}
+	}
+	if len(users) == 0 {
+		return nil, ErrInboundAddressNotFound
+	}
+
+	result := &models.InboundMailResult{Tasks: []models.Task{}, IgnoredAttachments: []string{}}
+	result.IgnoredAttachments = append(result.IgnoredAttachments, message.Attachments...)
+
+	for _, userID := range users {
+		task := mailTask(message)
+		task.UserID = userID
+
+		if task.ExternalID != nil {
+			existing, err := s.repo.GetExternalIDs(userID, []string{*task.ExternalID})
+			if err != nil {
+				return nil, err
+			}
+			if len(existing) > 0 {
+				result.Duplicates++
+				continue
+			}
+		}
+
+		if err := s.tasks.CreateTask(&task); err != nil {
+			return nil, err
+		}
+
+		s.log.Info().
+			Int("user_id", userID).
+			Int("task_id", task.ID).
+			Int("ignored_attachments", len(message.Attachments)).
+			Msg("Task created from email")
+		result.Tasks = append(result.Tasks, task)
+	}
+
+	return result, nil
+}
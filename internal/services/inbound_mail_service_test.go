@@ -0,0 +1,236 @@
+package services_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTaskCreator struct {
+	mock.Mock
+}
+
+func (m *MockTaskCreator) CreateTask(task *models.Task) error {
+	args := m.Called(task)
+	return args.Error(0)
+}
+
+type MockInboundTaskRepo struct {
+	mock.Mock
+}
+
+func (m *MockInboundTaskRepo) GetExternalIDs(userID int, externalIDs []string) ([]string, error) {
+	args := m.Called(userID, externalIDs)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+var inboundConfig = config.Inbound{Domain: "todo.example.com", LocalPart: "tasks", AddressTTL: 24 * time.Hour}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// inboundTokens knows the address tokens alice and bob of the users 1 and 2
+func inboundTokens(now time.Time) *MockTokenRepo {
+	tokens := new(MockTokenRepo)
+	tokens.On("FindToken", sha256Hex("alice"), models.TokenPurposeInboundMail, now).Return(&models.UserToken{UserID: 1}, nil)
+	tokens.On("FindToken", sha256Hex("bob"), models.TokenPurposeInboundMail, now).Return(&models.UserToken{UserID: 2}, nil)
+	tokens.On("FindToken", mock.Anything, models.TokenPurposeInboundMail, now).Return((*models.UserToken)(nil), repository.ErrTokenNotFound)
+	return tokens
+}
+
+func TestCreateInboundAddress(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tokens := new(MockTokenRepo)
+	service := services.NewInboundMailService(tokens, new(MockTaskCreator), new(MockInboundTaskRepo), inboundConfig, clock)
+
+	tokens.On("DeleteTokens", 1, models.TokenPurposeInboundMail).Return(nil)
+	tokens.On("CreateToken", mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.UserID == 1 && token.Purpose == models.TokenPurposeInboundMail &&
+			token.ExpiresAt.Equal(clock.now.Add(inboundConfig.AddressTTL))
+	})).Return(nil)
+
+	address, err := service.CreateAddress(1)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^tasks\+[a-z2-7]{32}@todo\.example\.com$`), address.Address)
+	assert.Equal(t, clock.now.Add(inboundConfig.AddressTTL), address.ExpiresAt)
+
+	// mail servers may change the case of the address
+	token := strings.TrimSuffix(strings.TrimPrefix(address.Address, "tasks+"), "@todo.example.com")
+	stored := tokens.Calls[1].Arguments.Get(0).(*models.UserToken)
+	assert.Equal(t, sha256Hex(token), stored.TokenHash)
+
+	tokens.On("FindToken", stored.TokenHash, models.TokenPurposeInboundMail, clock.now).Return(&models.UserToken{UserID: 1}, nil)
+	userID, err := service.Recipient("<" + strings.ToUpper(address.Address) + ">")
+	require.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	for _, other := range []string{"tasks@todo.example.com", "other+" + token + "@todo.example.com", "tasks+" + token + "@example.com"} {
+		_, err := service.Recipient(other)
+		assert.ErrorIs(t, err, services.ErrInboundAddressNotFound, other)
+	}
+}
+
+func TestReceiveMail(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name        string
+		message     string
+		recipients  []string
+		users       []int
+		title       string
+		description string
+		priority    string
+		tags        models.StringList
+		attachments []string
+	}{
+		{
+			name: "Plain text with hints",
+			message: "From: Sam <sam@example.com>\r\n" +
+				"To: tasks+alice@todo.example.com\r\n" +
+				"Subject: Fwd: Renew the #office lease !HIGH #legal\r\n" +
+				"\r\n" +
+				"The landlord needs an answer by Friday.\r\n" +
+				"\r\n" +
+				"-- \r\n" +
+				"Sam\r\n",
+			users:       []int{1},
+			title:       "Renew the lease",
+			description: "The landlord needs an answer by Friday.",
+			priority:    models.TaskPriorityHigh,
+			tags:        models.StringList{"office", "legal"},
+			attachments: []string{},
+		},
+		{
+			name: "Text part preferred",
+			message: "To: tasks+alice@todo.example.com\r\n" +
+				"Subject: =?UTF-8?B?0J7RgtGH0ZHRgg==?= for Q3\r\n" +
+				"MIME-Version: 1.0\r\n" +
+				"Content-Type: multipart/mixed; boundary=outer\r\n" +
+				"\r\n" +
+				"--outer\r\n" +
+				"Content-Type: multipart/alternative; boundary=inner\r\n" +
+				"\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/plain; charset=utf-8\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n" +
+				"\r\n" +
+				"Numbers are in the sheet =E2=80=94 see attached.\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/html; charset=utf-8\r\n" +
+				"\r\n" +
+				"<p>Numbers are in the <b>sheet</b></p>\r\n" +
+				"--inner--\r\n" +
+				"--outer\r\n" +
+				"Content-Type: application/pdf; name=\"q3.pdf\"\r\n" +
+				"Content-Disposition: attachment; filename=\"q3.pdf\"\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"JVBERi0xLjQK\r\n" +
+				"--outer--\r\n",
+			users:       []int{1},
+			title:       "Отчёт for Q3",
+			description: "Numbers are in the sheet — see attached.",
+			tags:        models.StringList{},
+			attachments: []string{"q3.pdf"},
+		},
+		{
+			name: "HTML only",
+			message: "To: Team <team@example.com>\r\n" +
+				"Subject: =?ISO-8859-1?Q?Caf=E9?= order #food\r\n" +
+				"Content-Type: text/html; charset=iso-8859-1\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"PGh0bWw+PGhlYWQ+PHN0eWxlPnAge2NvbG9yOiByZWR9PC9zdHlsZT48L2hlYWQ+PGJvZHk+\r\n" +
+				"PHA+Q2Fm6SBsYXR0ZSAmYW1wOyBjcm9pc3NhbnQ8L3A+PHVsPjxsaT5Ud288L2xpPjxsaT5I\r\n" +
+				"ZWF0ZWQ8L2xpPjwvdWw+PC9ib2R5PjwvaHRtbD4=\r\n",
+			recipients:  []string{"tasks+bob@todo.example.com", "tasks+alice@todo.example.com", "TASKS+BOB@todo.example.com"},
+			users:       []int{2, 1},
+			title:       "Café order",
+			description: "Café latte & croissant\n- Two\n- Heated",
+			tags:        models.StringList{"food"},
+			attachments: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := new(MockTaskCreator)
+			service := services.NewInboundMailService(inboundTokens(clock.now), tasks, new(MockInboundTaskRepo), inboundConfig, clock)
+			tasks.On("CreateTask", mock.Anything).Return(nil)
+
+			result, err := service.Receive(strings.NewReader(tt.message), tt.recipients)
+			require.NoError(t, err)
+			assert.Equal(t, tt.attachments, result.IgnoredAttachments)
+			require.Len(t, result.Tasks, len(tt.users))
+
+			for i, task := range result.Tasks {
+				assert.Equal(t, tt.users[i], task.UserID)
+				assert.Equal(t, tt.title, task.Title)
+				assert.Equal(t, tt.description, task.Description)
+				assert.Equal(t, "pending", task.Status)
+				assert.Equal(t, tt.priority, task.Priority)
+				assert.Equal(t, tt.tags, task.Tags)
+			}
+		})
+	}
+
+	t.Run("Unknown address", func(t *testing.T) {
+		tasks := new(MockTaskCreator)
+		service := services.NewInboundMailService(inboundTokens(clock.now), tasks, new(MockInboundTaskRepo), inboundConfig, clock)
+
+		_, err := service.Receive(strings.NewReader("To: tasks+eve@todo.example.com\r\nSubject: Hi\r\n\r\nHello\r\n"), nil)
+		assert.ErrorIs(t, err, services.ErrInboundAddressNotFound)
+		tasks.AssertNotCalled(t, "CreateTask", mock.Anything)
+	})
+
+	t.Run("Not a message", func(t *testing.T) {
+		service := services.NewInboundMailService(inboundTokens(clock.now), new(MockTaskCreator), new(MockInboundTaskRepo), inboundConfig, clock)
+
+		_, err := service.Receive(strings.NewReader("no header here"), []string{"tasks+alice@todo.example.com"})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Redelivered message", func(t *testing.T) {
+		tasks, repo := new(MockTaskCreator), new(MockInboundTaskRepo)
+		service := services.NewInboundMailService(inboundTokens(clock.now), tasks, repo, inboundConfig, clock)
+
+		externalID := "mail:" + sha256Hex("<1234@mail.example.com>")
+		// alice got the task before the first delivery failed for bob
+		repo.On("GetExternalIDs", 1, []string{externalID}).Return([]string{externalID}, nil)
+		repo.On("GetExternalIDs", 2, []string{externalID}).Return([]string{}, nil)
+		tasks.On("CreateTask", mock.MatchedBy(func(task *models.Task) bool {
+			return task.UserID == 2 && *task.ExternalID == externalID
+		})).Return(nil).Once()
+
+		message := "Message-ID: <1234@mail.example.com>\r\nSubject: Hi\r\n\r\nHello\r\n"
+		result, err := service.Receive(strings.NewReader(message), []string{"tasks+alice@todo.example.com", "tasks+bob@todo.example.com"})
+		require.NoError(t, err)
+		assert.Len(t, result.Tasks, 1)
+		assert.Equal(t, 1, result.Duplicates)
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Task not created", func(t *testing.T) {
+		tasks := new(MockTaskCreator)
+		service := services.NewInboundMailService(inboundTokens(clock.now), tasks, new(MockInboundTaskRepo), inboundConfig, clock)
+		tasks.On("CreateTask", mock.Anything).Return(errors.New("db down"))
+
+		_, err := service.Receive(strings.NewReader("Subject: Hi\r\n\r\nHello\r\n"), []string{"tasks+alice@todo.example.com"})
+		assert.EqualError(t, err, "db down")
+	})
+}
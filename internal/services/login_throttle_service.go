@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/rs/zerolog"
+)
+
+const ipKeyPrefix = "ip:"
+
+type ILoginAttemptRepository interface {
+	GetAttempt(key string) (*models.LoginAttempt, error)
+	RegisterFailure(key string, now, resetBefore time.Time) (int, error)
+	Block(key string, until time.Time) error
+	DeleteAttempt(key string) error
+}
+
+type IAuditRepository interface {
+	CreateRecord(record *models.AuditRecord) error
+}
+
+// LoginThrottleService tracks failed logins per key in the database so limits
+// are shared between app instances
+type LoginThrottleService struct {
+	repo  ILoginAttemptRepository
+	audit IAuditRepository
+	cfg   config.Lockout
+	clock clock.Clock
+	log   zerolog.Logger
+}
+
+func NewLoginThrottleService(repo ILoginAttemptRepository, audit IAuditRepository, cfg config.Lockout, clock clock.Clock) *LoginThrottleService {
+	return &LoginThrottleService{
+		repo:  repo,
+		audit: audit,
+		cfg:   cfg,
+		clock: clock,
+		log:   logger.GetLogger(),
+	}
+}
+
+func UsernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func IPKey(ip string) string {
+	return ipKeyPrefix + ip
+}
+
+func MFAKey(userID int) string {
+	return "mfa:" + strconv.Itoa(userID)
+}
+
+// Check returns *LockedError if any of the keys is currently blocked
+func (s *LoginThrottleService) Check(keys ...string) error {
+	now := s.clock.Now()
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		attempt, err := s.repo.GetAttempt(key)
+		if err != nil {
+			return err
+		}
+		if attempt == nil || attempt.BlockedUntil == nil {
+			continue
+		}
+		if wait := attempt.BlockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// Fail registers a failed attempt for every key. Each failure delays the next
+// attempt exponentially, reaching the limit locks the key for LockoutDuration
+func (s *LoginThrottleService) Fail(keys ...string) error {
+	now := s.clock.Now()
+
+	for _, key := range keys {
+		failures, err := s.repo.RegisterFailure(key, now, now.Add(-s.cfg.ResetAfter))
+		if err != nil {
+			return err
+		}
+
+		limit := s.cfg.MaxAttempts
+		if strings.HasPrefix(key, ipKeyPrefix) {
+			limit = s.cfg.IPMaxAttempts
+		}
+
+		if limit > 0 && failures >= limit {
+			until := now.Add(s.cfg.LockoutDuration)
+			if err := s.repo.Block(key, until); err != nil {
+				return err
+			}
+			s.recordLockout(key, failures, until)
+			continue
+		}
+
+		if err := s.repo.Block(key, now.Add(s.backoff(failures))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *LoginThrottleService) Reset(keys ...string) error {
+	for _, key := range keys {
+		if err := s.repo.DeleteAttempt(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LoginThrottleService) backoff(failures int) time.Duration {
+	delay := s.cfg.BaseDelay
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if s.cfg.MaxDelay > 0 && delay >= s.cfg.MaxDelay {
+			return s.cfg.MaxDelay
+		}
+	}
+	return delay
+}
+
+func (s *LoginThrottleService) recordLockout(key string, failures int, until time.Time) {
+	s.log.Warn().
+		Str("key", key).
+		Int("failures", failures).
+		Time("locked_until", until).
+		Msg("Login locked out")
+
+	err := s.audit.CreateRecord(&models.AuditRecord{
+		Event:   models.AuditEventLoginLockout,
+		Subject: key,
+		Details: fmt.Sprintf("failures=%d locked_until=%s", failures, until.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		s.log.Error().Err(err).Str("key", key).Msg("Failed to write lockout audit record")
+	}
+}
@@ -0,0 +1,192 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginAttemptRepo struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepo) GetAttempt(key string) (*models.LoginAttempt, error) {
+	args := m.Called(key)
+	return args.Get(0).(*models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepo) RegisterFailure(key string, now, resetBefore time.Time) (int, error) {
+	args := m.Called(key, now, resetBefore)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepo) Block(key string, until time.Time) error {
+	args := m.Called(key, until)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepo) DeleteAttempt(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+type MockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepo) CreateRecord(record *models.AuditRecord) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepo) GetRecordsByUserID(userID int) ([]models.AuditRecord, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.AuditRecord), args.Error(1)
+}
+
+var lockoutConfig = config.Lockout{
+	MaxAttempts:     5,
+	IPMaxAttempts:   20,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutDuration: 15 * time.Minute,
+	ResetAfter:      time.Hour,
+}
+
+func TestThrottleCheck(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("No attempts", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		service := services.NewLoginThrottleService(mockRepo, new(MockAuditRepo), lockoutConfig, clock)
+
+		mockRepo.On("GetAttempt", "user:alice").Return((*models.LoginAttempt)(nil), nil)
+		mockRepo.On("GetAttempt", "ip:10.0.0.1").Return((*models.LoginAttempt)(nil), nil)
+
+		assert.NoError(t, service.Check("user:alice", "ip:10.0.0.1"))
+	})
+
+	t.Run("Expired block", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		service := services.NewLoginThrottleService(mockRepo, new(MockAuditRepo), lockoutConfig, clock)
+
+		until := clock.now.Add(-time.Second)
+		mockRepo.On("GetAttempt", "user:alice").Return(&models.LoginAttempt{Failures: 3, BlockedUntil: &until}, nil)
+
+		assert.NoError(t, service.Check("user:alice"))
+	})
+
+	t.Run("Longest block wins", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		service := services.NewLoginThrottleService(mockRepo, new(MockAuditRepo), lockoutConfig, clock)
+
+		userUntil := clock.now.Add(4 * time.Second)
+		ipUntil := clock.now.Add(10 * time.Minute)
+		mockRepo.On("GetAttempt", "user:alice").Return(&models.LoginAttempt{BlockedUntil: &userUntil}, nil)
+		mockRepo.On("GetAttempt", "ip:10.0.0.1").Return(&models.LoginAttempt{BlockedUntil: &ipUntil}, nil)
+
+		err := service.Check("user:alice", "ip:10.0.0.1")
+		var locked *services.LockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.ErrorIs(t, err, services.ErrTooManyAttempts)
+		assert.Equal(t, 10*time.Minute, locked.RetryAfter)
+	})
+}
+
+func TestThrottleFail(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+	resetBefore := clock.now.Add(-lockoutConfig.ResetAfter)
+
+	backoff := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+	}
+
+	for _, tc := range backoff {
+		mockRepo := new(MockLoginAttemptRepo)
+		mockAudit := new(MockAuditRepo)
+		service := services.NewLoginThrottleService(mockRepo, mockAudit, lockoutConfig, clock)
+
+		mockRepo.On("RegisterFailure", "user:alice", clock.now, resetBefore).Return(tc.failures, nil)
+		mockRepo.On("Block", "user:alice", clock.now.Add(tc.delay)).Return(nil)
+
+		assert.NoError(t, service.Fail("user:alice"))
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertNotCalled(t, "CreateRecord")
+	}
+
+	t.Run("Backoff is capped", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		service := services.NewLoginThrottleService(mockRepo, new(MockAuditRepo), lockoutConfig, clock)
+
+		mockRepo.On("RegisterFailure", "ip:10.0.0.1", clock.now, resetBefore).Return(15, nil)
+		mockRepo.On("Block", "ip:10.0.0.1", clock.now.Add(time.Minute)).Return(nil)
+
+		assert.NoError(t, service.Fail("ip:10.0.0.1"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Lockout is audited", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		mockAudit := new(MockAuditRepo)
+		service := services.NewLoginThrottleService(mockRepo, mockAudit, lockoutConfig, clock)
+
+		mockRepo.On("RegisterFailure", "user:alice", clock.now, resetBefore).Return(5, nil)
+		mockRepo.On("Block", "user:alice", clock.now.Add(15*time.Minute)).Return(nil)
+		mockAudit.On("CreateRecord", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Event == models.AuditEventLoginLockout && r.Subject == "user:alice"
+		})).Return(nil)
+
+		assert.NoError(t, service.Fail("user:alice"))
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("IP has its own limit", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockLoginAttemptRepo)
+		mockAudit := new(MockAuditRepo)
+		service := services.NewLoginThrottleService(mockRepo, mockAudit, lockoutConfig, clock)
+
+		mockRepo.On("RegisterFailure", "ip:10.0.0.1", clock.now, resetBefore).Return(5, nil)
+		mockRepo.On("Block", "ip:10.0.0.1", clock.now.Add(16*time.Second)).Return(nil)
+
+		assert.NoError(t, service.Fail("ip:10.0.0.1"))
+		mockAudit.AssertNotCalled(t, "CreateRecord")
+	})
+}
+
+func TestThrottleReset(t *testing.T) {
+	t.Parallel()
+	mockRepo := new(MockLoginAttemptRepo)
+	service := services.NewLoginThrottleService(mockRepo, new(MockAuditRepo), lockoutConfig, &FakeClock{})
+
+	mockRepo.On("DeleteAttempt", "user:alice").Return(nil)
+
+	assert.NoError(t, service.Reset("user:alice"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestThrottleKeys(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user:alice", services.UsernameKey("Alice"))
+	assert.Equal(t, "ip:10.0.0.1", services.IPKey("10.0.0.1"))
+	assert.Equal(t, "mfa:7", services.MFAKey(7))
+}
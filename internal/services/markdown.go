@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// markdownTaskEncoder writes tasks as a GitHub-flavored markdown checklist,
+// one "- [ ] Title #tag @context due:2026-10-20" item per task. The description
+// is indented below its task, so the checklist the app importers put there
+// shows as nested subtasks. Done tasks are checked, other statuses and
+// priorities are left out. Title words that would read as tags are escaped
+// with a backslash, due dates are those of the user's time zone
+type markdownTaskEncoder struct {
+	w   io.Writer
+	loc *time.Location
+}
+
+func (e *markdownTaskEncoder) begin() error {
+	return nil
+}
+
+func (e *markdownTaskEncoder) encode(task *models.Task) error {
+	_, err := io.WriteString(e.w, markdownItem(task, e.loc))
+	return err
+}
+
+func (e *markdownTaskEncoder) end() error {
+	return nil
+}
+
+func markdownItem(task *models.Task, loc *time.Location) string {
+	mark := " "
+	if task.Status == models.TaskStatusDone {
+		mark = "x"
+	}
+
+	parts := []string{"- [" + mark + "]"}
+	for _, word := range strings.Fields(task.Title) {
+		if strings.HasPrefix(word, "#") || strings.HasPrefix(word, "@") {
+			word = `\` + word
+		}
+		parts = append(parts, word)
+	}
+	for _, tag := range task.Tags {
+		if strings.HasPrefix(tag, "@") {
+			parts = append(parts, tag)
+		} else {
+			parts = append(parts, "#"+tag)
+		}
+	}
+	if task.DueAt != nil {
+		parts = append(parts, "due:"+todoTxtDate(time.Time(*task.DueAt), loc))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(parts, " "))
+	b.WriteString("\n")
+
+	if description := strings.TrimRight(strings.ReplaceAll(task.Description, "\r\n", "\n"), " \n"); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			if strings.TrimSpace(line) != "" {
+				b.WriteString("  " + line)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// markdownCheckbox matches a checklist item, the groups are the indentation,
+// the check mark and the text
+var markdownCheckbox = regexp.MustCompile(`^( *)[-*+] \[([ xX])\](?: +(.*))?$`)
+
+// decodeMarkdownRecords reads the outermost checklist items of a markdown
+// document as import records. Lines indented below an item, nested items
+// included, become its description with their relative indentation, other
+// lines such as headings and paragraphs are skipped
+func decodeMarkdownRecords(r io.Reader, loc *time.Location, add func(map[string]string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var record map[string]string
+	var description []string
+	indent := 0
+
+	flush := func() error {
+		if record == nil {
+			return nil
+		}
+		record["description"] = strings.TrimRight(strings.Join(description, "\n"), "\n")
+		err := add(record)
+		record, description = nil, nil
+		return err
+	}
+
+	first := true
+	for scanner.Scan() {
+		line := strings.ReplaceAll(strings.TrimRight(scanner.Text(), " \t"), "\t", "    ")
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+		lead := len(line) - len(strings.TrimLeft(line, " "))
+
+		if record != nil && (line == "" || lead > indent) {
+			if line != "" {
+				// content of an item starts after the "- " of its marker
+				line = line[min(lead, indent+2):]
+			}
+			if line != "" || len(description) > 0 {
+				description = append(description, line)
+			}
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		if match := markdownCheckbox.FindStringSubmatch(line); match != nil {
+			record = markdownRecord(match[2], match[3], loc)
+			indent = len(match[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return invalidFile(err)
+	}
+	return flush()
+}
+
+// markdownRecord splits the text of a checklist item into the fields of a
+// task, #tag and @context words become tags and due:YYYY-MM-DD the due date
+// at the start of that day in loc
+func markdownRecord(mark, text string, loc *time.Location) map[string]string {
+	record := map[string]string{"status": "pending"}
+	if mark != " " {
+		record["status"] = models.TaskStatusDone
+	}
+
+	var words []string
+	tags := []string{}
+	for _, word := range strings.Fields(text) {
+		key, value, _ := strings.Cut(word, ":")
+		switch {
+		case strings.HasPrefix(word, `\#`) || strings.HasPrefix(word, `\@`):
+			words = append(words, word[1:])
+		case len(word) > 1 && word[0] == '#':
+			tags = append(tags, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			tags = append(tags, word)
+		case key == "due" && isTodoTxtDate(value):
+			record["due_at"] = todoTxtRecordTime(value, loc)
+		default:
+			words = append(words, word)
+		}
+	}
+
+	record["title"] = strings.Join(words, " ")
+	if len(tags) > 0 {
+		raw, _ := json.Marshal(tags)
+		record["tags"] = string(raw)
+	}
+	return record
+}
@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	// due dates are the user's days, midnight in Berlin is the day before in UTC
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	original := todoTxtTasks(berlin)
+	original[1].Title = "Write #1 report for @boss"
+	original[1].Description = "Numbers of the third quarter\n\n- [x] Collect sales numbers\n  - [ ] Ask finance"
+
+	export := &MockTransferRepo{tasks: original}
+	export.On("StreamTasks", 1, models.TaskFilter{Tag: "work"}).Return(nil)
+
+	var file bytes.Buffer
+	err = services.NewTaskTransferService(export, new(MockWorkspaceRepo), transferUsers("Europe/Berlin"), importConfig).
+		Export(&file, 1, models.TaskFilter{Tag: "work"}, models.TransferFormatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "- [x] Call the plumber #house @phone due:2026-10-20\n"+
+		"- [ ] Write \\#1 report for \\@boss #work\n"+
+		"  Numbers of the third quarter\n"+
+		"\n"+
+		"  - [x] Collect sales numbers\n"+
+		"    - [ ] Ask finance\n"+
+		"- [ ] Отчёт за квартал due:2026-10-31\n"+
+		"- [x] Someday\n", file.String())
+
+	var imported []models.Task
+	repo := new(MockTransferRepo)
+	repo.On("ImportTasks", mock.Anything, 10).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.Task)
+	}).Return(4, nil)
+
+	service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers("Europe/Berlin"), config.Import{MaxRows: 10, BatchSize: 10})
+	report, err := service.Import(&file, models.ImportRequest{UserID: 2, Format: models.TransferFormatMarkdown})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Created)
+	require.Len(t, imported, len(original))
+
+	for i, want := range original {
+		got := imported[i]
+
+		assert.Equal(t, want.Title, got.Title)
+		assert.Equal(t, want.Description, got.Description)
+		assert.Equal(t, want.Status, got.Status)
+		assert.Equal(t, want.Tags, got.Tags)
+		assertSameDay(t, want.DueAt, got.DueAt)
+	}
+}
+
+func TestImportMarkdown(t *testing.T) {
+	file := "# Release\n" +
+		"\n" +
+		"Things to do before Friday.\n" +
+		"\n" +
+		"* [X] Tag the build\n" +
+		"- [ ] Update the docs #docs\n" +
+		"\t- [ ] API reference\n" +
+		"\t\t- [x] Examples\n" +
+		"\n" +
+		"## Later\n" +
+		"- plain item\n" +
+		"   - [ ] Indented by three\n" +
+		"      - [ ] Child\n"
+
+	var imported []models.Task
+	repo := new(MockTransferRepo)
+	repo.On("ImportTasks", mock.Anything, 10).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.Task)
+	}).Return(3, nil)
+
+	service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), config.Import{MaxRows: 10, BatchSize: 10})
+	report, err := service.Import(strings.NewReader(file), models.ImportRequest{UserID: 1, Format: models.TransferFormatMarkdown})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	require.Len(t, imported, 3)
+
+	assert.Equal(t, "Tag the build", imported[0].Title)
+	assert.Equal(t, models.TaskStatusDone, imported[0].Status)
+	assert.Empty(t, imported[0].Description)
+
+	assert.Equal(t, "Update the docs", imported[1].Title)
+	assert.Equal(t, "pending", imported[1].Status)
+	assert.Equal(t, models.StringList{"docs"}, imported[1].Tags)
+	assert.Equal(t, "  - [ ] API reference\n      - [x] Examples", imported[1].Description)
+
+	assert.Equal(t, "Indented by three", imported[2].Title)
+	assert.Equal(t, " - [ ] Child", imported[2].Description)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/pkg/totp"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeLength = 10
+	totpSkew           = 1
+	qrCodeSize         = 256
+)
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+type IMFARepository interface {
+	GetTOTP(userID int) (*models.UserTOTP, error)
+	SaveTOTP(totp *models.UserTOTP) error
+	ConfirmTOTP(userID int, step int64, codeHashes []string) error
+	UseTOTPStep(userID int, step int64) error
+	UseRecoveryCode(userID int, codeHash string) error
+}
+
+type MFAService struct {
+	repo     IMFARepository
+	userRepo IUserRepository
+	issuer   string
+	clock    clock.Clock
+	log      zerolog.Logger
+}
+
+func NewMFAService(repo IMFARepository, userRepo IUserRepository, issuer string, clock clock.Clock) *MFAService {
+	return &MFAService{
+		repo:     repo,
+		userRepo: userRepo,
+		issuer:   issuer,
+		clock:    clock,
+		log:      logger.GetLogger(),
+	}
+}
+
+// Enroll generates a new pending secret. It is not used for login until confirmed
+func (s *MFAService) Enroll(userID int) (*models.MFAEnrollment, error) {
+	current, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Confirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveTOTP(&models.UserTOTP{UserID: userID, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	uri := totp.ProvisioningURI(s.issuer, user.Username, secret)
+	png, err := s.qrCode(userID, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          png,
+	}, nil
+}
+
+// PendingQRCode renders the QR code of the secret started by Enroll, so the
+// image matches the secret returned there
+func (s *MFAService) PendingQRCode(userID int) ([]byte, error) {
+	current, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if current.Confirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return s.qrCode(userID, totp.ProvisioningURI(s.issuer, user.Username, current.Secret))
+}
+
+func (s *MFAService) qrCode(userID int, uri string) ([]byte, error) {
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		s.log.Error().Err(err).Int("user_id", userID).Msg("Failed to encode QR code")
+		return nil, err
+	}
+	return png, nil
+}
+
+// Confirm enables 2FA after the first valid code and returns plain recovery codes.
+// The codes are shown once, only their hashes are stored
+func (s *MFAService) Confirm(userID int, code string) ([]string, error) {
+	current, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if current.Confirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(current.Secret, code, s.clock.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.repo.ConfirmTOTP(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *MFAService) Enabled(userID int) (bool, error) {
+	current, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return false, err
+	}
+	return current.Confirmed(), nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code
+func (s *MFAService) Verify(userID int, code string) error {
+	current, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if !current.Confirmed() {
+		return ErrMFANotEnrolled
+	}
+
+	if step, ok := totp.Validate(current.Secret, code, s.clock.Now(), totpSkew); ok {
+		err := s.repo.UseTOTPStep(userID, step)
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	err = s.repo.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if errors.Is(err, repository.ErrNoRowsUpdated) {
+		return ErrInvalidMFACode
+	}
+	if err == nil {
+		s.log.Info().Int("user_id", userID).Msg("Recovery code used")
+	}
+	return err
+}
+
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i, b := range buf {
+		if i == recoveryCodeLength/2 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+
+	return sb.String(), nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
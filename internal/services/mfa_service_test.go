@@ -0,0 +1,258 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/totp"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+type FakeClock struct {
+	now time.Time
+}
+
+func (c *FakeClock) Now() time.Time {
+	return c.now
+}
+
+type MockMFARepo struct {
+	mock.Mock
+}
+
+func (m *MockMFARepo) GetTOTP(userID int) (*models.UserTOTP, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.UserTOTP), args.Error(1)
+}
+
+func (m *MockMFARepo) SaveTOTP(totp *models.UserTOTP) error {
+	args := m.Called(totp)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) ConfirmTOTP(userID int, step int64, codeHashes []string) error {
+	args := m.Called(userID, step, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) UseTOTPStep(userID int, step int64) error {
+	args := m.Called(userID, step)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) UseRecoveryCode(userID int, codeHash string) error {
+	args := m.Called(userID, codeHash)
+	return args.Error(0)
+}
+
+func codeAt(t *testing.T, now time.Time) string {
+	code, err := totp.GenerateCode(testTOTPSecret, totp.Step(now))
+	assert.NoError(t, err)
+	return code
+}
+
+func TestMFAEnroll(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Successful enrollment", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		mockUsers := new(MockUserRepo)
+		service := services.NewMFAService(mockRepo, mockUsers, "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return((*models.UserTOTP)(nil), nil)
+		mockUsers.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice"}, nil)
+		mockRepo.On("SaveTOTP", mock.MatchedBy(func(t *models.UserTOTP) bool {
+			return t.UserID == 1 && t.Secret != ""
+		})).Return(nil)
+
+		enrollment, err := service.Enroll(1)
+		assert.NoError(t, err)
+		assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/TODO%20App:alice")
+		assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+		assert.Equal(t, []byte("\x89PNG"), enrollment.QRCode[:4])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		confirmed := time.Now()
+		mockRepo.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &confirmed}, nil)
+
+		_, err := service.Enroll(1)
+		assert.ErrorIs(t, err, services.ErrMFAAlreadyEnabled)
+		mockRepo.AssertNotCalled(t, "SaveTOTP")
+	})
+}
+
+func TestMFAPendingQRCode(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Pending secret", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		mockUsers := new(MockUserRepo)
+		service := services.NewMFAService(mockRepo, mockUsers, "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+		mockUsers.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice"}, nil)
+
+		png, err := service.PendingQRCode(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("\x89PNG"), png[:4])
+		mockRepo.AssertNotCalled(t, "SaveTOTP", mock.Anything)
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return((*models.UserTOTP)(nil), nil)
+
+		_, err := service.PendingQRCode(1)
+		assert.ErrorIs(t, err, services.ErrMFANotEnrolled)
+	})
+}
+
+func TestMFAConfirm(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+
+	t.Run("Valid code returns recovery codes", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+		mockRepo.On("ConfirmTOTP", 1, totp.Step(clock.now), mock.MatchedBy(func(hashes []string) bool {
+			return len(hashes) == 10
+		})).Return(nil)
+
+		codes, err := service.Confirm(1, codeAt(t, clock.now))
+		assert.NoError(t, err)
+		assert.Len(t, codes, 10)
+		for _, code := range codes {
+			assert.Len(t, code, 11)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Code from stale window", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+
+		_, err := service.Confirm(1, codeAt(t, clock.now.Add(-5*time.Minute)))
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+		mockRepo.AssertNotCalled(t, "ConfirmTOTP")
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return((*models.UserTOTP)(nil), nil)
+
+		_, err := service.Confirm(1, "123456")
+		assert.ErrorIs(t, err, services.ErrMFANotEnrolled)
+	})
+}
+
+func TestMFAVerify(t *testing.T) {
+	t.Parallel()
+	clock := &FakeClock{now: time.Unix(1700000000, 0)}
+	confirmed := clock.now.Add(-time.Hour)
+	enrolled := &models.UserTOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &confirmed}
+
+	t.Run("Valid TOTP code", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(enrolled, nil)
+		mockRepo.On("UseTOTPStep", 1, totp.Step(clock.now)).Return(nil)
+
+		err := service.Verify(1, codeAt(t, clock.now))
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Replayed TOTP code", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(enrolled, nil)
+		mockRepo.On("UseTOTPStep", 1, totp.Step(clock.now)).Return(repository.ErrNoRowsUpdated)
+
+		err := service.Verify(1, codeAt(t, clock.now))
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(enrolled, nil)
+		mockRepo.On("UseRecoveryCode", 1, mock.AnythingOfType("string")).Return(nil)
+
+		err := service.Verify(1, "abcde-fghjk")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Recovery code is normalized before hashing", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		var hashes []string
+		mockRepo.On("GetTOTP", 1).Return(enrolled, nil)
+		mockRepo.On("UseRecoveryCode", 1, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { hashes = append(hashes, args.String(1)) }).
+			Return(nil)
+
+		assert.NoError(t, service.Verify(1, "abcde-fghjk"))
+		assert.NoError(t, service.Verify(1, "ABCDE FGHJK"))
+		assert.Equal(t, hashes[0], hashes[1])
+	})
+
+	t.Run("Unknown code", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(enrolled, nil)
+		mockRepo.On("UseRecoveryCode", 1, mock.AnythingOfType("string")).Return(repository.ErrNoRowsUpdated)
+
+		err := service.Verify(1, "000000")
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	})
+
+	t.Run("Unconfirmed enrollment", func(t *testing.T) {
+		t.Parallel()
+		mockRepo := new(MockMFARepo)
+		service := services.NewMFAService(mockRepo, new(MockUserRepo), "TODO App", clock)
+
+		mockRepo.On("GetTOTP", 1).Return(&models.UserTOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+
+		err := service.Verify(1, codeAt(t, clock.now))
+		assert.ErrorIs(t, err, services.ErrMFANotEnrolled)
+	})
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// MSTodoImporter reads Microsoft To Do lists as returned by the Microsoft Graph
+// API, {"lists": [...]} with the tasks of every list under "tasks". Lists and
+// categories become tags, steps become checklist items. To Do has no comments
+type MSTodoImporter struct{}
+
+type msTodoDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type msTodoExport struct {
+	Lists []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+		Tasks       []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+			Body  struct {
+				Content     string `json:"content"`
+				ContentType string `json:"contentType"`
+			} `json:"body"`
+			Importance        string          `json:"importance"`
+			Status            string          `json:"status"`
+			Categories        []string        `json:"categories"`
+			CreatedDateTime   string          `json:"createdDateTime"`
+			DueDateTime       *msTodoDateTime `json:"dueDateTime"`
+			CompletedDateTime *msTodoDateTime `json:"completedDateTime"`
+			ChecklistItems    []struct {
+				DisplayName string `json:"displayName"`
+				IsChecked   bool   `json:"isChecked"`
+			} `json:"checklistItems"`
+			Recurrence *struct {
+				Pattern struct {
+					Type       string   `json:"type"`
+					Interval   int      `json:"interval"`
+					DaysOfWeek []string `json:"daysOfWeek"`
+				} `json:"pattern"`
+			} `json:"recurrence"`
+		} `json:"tasks"`
+	} `json:"lists"`
+}
+
+// msTodoStatuses maps the task statuses of To Do, completed tasks are done
+var msTodoStatuses = map[string]string{
+	"notStarted":      "pending",
+	"inProgress":      "in progress",
+	"waitingOnOthers": "waiting",
+	"deferred":        "deferred",
+	"completed":       models.TaskStatusDone,
+}
+
+var msTodoFrequencies = map[string]string{
+	"daily":           "DAILY",
+	"weekly":          "WEEKLY",
+	"absoluteMonthly": "MONTHLY",
+	"relativeMonthly": "MONTHLY",
+	"absoluteYearly":  "YEARLY",
+	"relativeYearly":  "YEARLY",
+}
+
+// htmlTag matches the tags of an HTML task body
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func (MSTodoImporter) Parse(r io.Reader, fileName string) (*models.AppImport, error) {
+	var export msTodoExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, invalidFile(err)
+	}
+	if export.Lists == nil {
+		return nil, invalidFile(fmt.Errorf("not a Microsoft To Do export, no lists"))
+	}
+
+	result := &models.AppImport{}
+	labels := make(map[string]bool)
+
+	for _, list := range export.Lists {
+		if len(list.Tasks) > 0 {
+			result.Projects++
+		}
+
+		for _, item := range list.Tasks {
+			description := item.Body.Content
+			if strings.EqualFold(item.Body.ContentType, "html") {
+				description = html.UnescapeString(htmlTag.ReplaceAllString(description, ""))
+			}
+
+			task := models.ImportedTask{Task: models.Task{
+				Title:       item.Title,
+				Description: description,
+				Status:      msTodoStatuses[item.Status],
+				Tags:        models.StringList{list.DisplayName},
+			}}
+			externalID := "mstodo:" + item.ID
+			task.ExternalID = &externalID
+
+			switch item.Importance {
+			case "high":
+				task.Priority = models.TaskPriorityHigh
+			case "low":
+				task.Priority = models.TaskPriorityLow
+			}
+
+			for _, category := range item.Categories {
+				task.Tags = append(task.Tags, category)
+				labels[category] = true
+			}
+
+			if created, ok := parseAppTime(item.CreatedDateTime, time.UTC); ok {
+				task.CreatedAt = models.JSONTime(created)
+			}
+			if due, ok := msTodoTime(item.DueDateTime); ok {
+				task.DueAt = appJSONTime(due)
+			}
+			if completed, ok := msTodoTime(item.CompletedDateTime); ok && task.Status == models.TaskStatusDone {
+				task.CompletedAt = appJSONTime(completed)
+			}
+
+			if item.Recurrence != nil {
+				pattern := item.Recurrence.Pattern
+				if freq, ok := msTodoFrequencies[pattern.Type]; ok {
+					task.Recurrence = "FREQ=" + freq
+					if pattern.Interval > 1 {
+						task.Recurrence += fmt.Sprintf(";INTERVAL=%d", pattern.Interval)
+					}
+					if freq == "WEEKLY" && len(pattern.DaysOfWeek) > 0 {
+						days := make([]string, 0, len(pattern.DaysOfWeek))
+						for _, day := range pattern.DaysOfWeek {
+							if len(day) >= 2 {
+								days = append(days, strings.ToUpper(day[:2]))
+							}
+						}
+						task.Recurrence += ";BYDAY=" + strings.Join(days, ",")
+					}
+				} else {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("recurrence %q of task %q is not supported", pattern.Type, item.Title))
+				}
+			}
+
+			for _, step := range item.ChecklistItems {
+				task.Checklist = append(task.Checklist, models.ChecklistItem{Text: step.DisplayName, Checked: step.IsChecked})
+			}
+
+			result.Tasks = append(result.Tasks, task)
+		}
+	}
+
+	result.Labels = len(labels)
+	return result, nil
+}
+
+// msTodoTime reads a Graph dateTimeTimeZone, zones Go does not know, such as
+// Windows zone names, are read as UTC which Graph uses by default
+func msTodoTime(dt *msTodoDateTime) (time.Time, bool) {
+	if dt == nil || dt.DateTime == "" {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(dt.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return parseAppTime(dt.DateTime, loc)
+}
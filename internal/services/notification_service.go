@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+type INotificationRepository interface {
+	CreateNotification(notification *models.Notification) error
+	GetNotifications(userID int, filter models.NotificationFilter) ([]models.Notification, error)
+	CountUnread(userID int) (int, error)
+	MarkRead(id, userID int, readAt time.Time) error
+	MarkAllRead(userID int, readAt time.Time) (int64, error)
+	GetPreferences(userID int) ([]models.NotificationPreference, error)
+	SetPreferences(userID int, preferences []models.NotificationPreference) error
+}
+
+// NotificationService is the in-app inbox. Other services emit into it through
+// Notify, users read it and choose which event types they receive
+type NotificationService struct {
+	repo  INotificationRepository
+	clock clock.Clock
+}
+
+func NewNotificationService(repo INotificationRepository, clock clock.Clock) *NotificationService {
+	return &NotificationService{repo: repo, clock: clock}
+}
+
+// Notify stores a notification unless the recipient switched its type off
+func (s *NotificationService) Notify(notification *models.Notification) error {
+	return s.repo.CreateNotification(notification)
+}
+
+// SendReminder puts a fired reminder into the inbox of its user, reminders
+// relative to the due date arrive as task_due_soon
+func (s *NotificationService) SendReminder(reminder *models.Reminder) error {
+	notificationType := models.NotificationTaskReminder
+	if reminder.BeforeDueMinutes != nil {
+		notificationType = models.NotificationTaskDueSoon
+	}
+	return s.Notify(&models.Notification{
+		UserID:  reminder.UserID,
+		Type:    notificationType,
+		TaskID:  &reminder.TaskID,
+		Message: ReminderMessage(reminder),
+	})
+}
+
+func (s *NotificationService) ListNotifications(userID int, filter models.NotificationFilter) (*models.NotificationPage, error) {
+	limit, err := helpers.ValidatePage(filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra row tells whether another page follows
+	filter.Limit = limit + 1
+	notifications, err := s.repo.GetNotifications(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.NotificationPage{
+		Notifications: notifications,
+		UnreadCount:   unread,
+		Limit:         limit,
+		Offset:        filter.Offset,
+	}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		page.HasMore = true
+	}
+	if page.Notifications == nil {
+		page.Notifications = []models.Notification{}
+	}
+
+	return page, nil
+}
+
+func (s *NotificationService) MarkRead(notificationID, userID int) error {
+	err := s.repo.MarkRead(notificationID, userID, s.clock.Now())
+	if errors.Is(err, repository.ErrNoRowsUpdated) {
+		return ErrNotificationNotFound
+	}
+
+	return err
+}
+
+func (s *NotificationService) MarkAllRead(userID int) (int64, error) {
+	return s.repo.MarkAllRead(userID, s.clock.Now())
+}
+
+// GetPreferences returns a preference for every notification type, types the
+// user never changed are enabled
+func (s *NotificationService) GetPreferences(userID int) ([]models.NotificationPreference, error) {
+	stored, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preference := models.NotificationPreference{Type: notificationType, Enabled: true}
+		if i := slices.IndexFunc(stored, func(p models.NotificationPreference) bool { return p.Type == notificationType }); i >= 0 {
+			preference.Enabled = stored[i].Enabled
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, nil
+}
+
+// UpdatePreferences switches the given types on or off, other types keep their setting
+func (s *NotificationService) UpdatePreferences(userID int, updates map[string]bool) ([]models.NotificationPreference, error) {
+	preferences := make([]models.NotificationPreference, 0, len(updates))
+	for notificationType, enabled := range updates {
+		if err := helpers.ValidateNotificationType(notificationType); err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, models.NotificationPreference{Type: notificationType, Enabled: enabled})
+	}
+
+	if len(preferences) > 0 {
+		if err := s.repo.SetPreferences(userID, preferences); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetPreferences(userID)
+}
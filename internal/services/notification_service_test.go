@@ -0,0 +1,255 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNotificationRepo struct {
+	mock.Mock
+}
+
+func (m *MockNotificationRepo) CreateNotification(notification *models.Notification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepo) GetNotifications(userID int, filter models.NotificationFilter) ([]models.Notification, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]models.Notification), args.Error(1)
+}
+
+func (m *MockNotificationRepo) GetNotificationsByUserID(userID int) ([]models.Notification, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Notification), args.Error(1)
+}
+
+func (m *MockNotificationRepo) CountUnread(userID int) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationRepo) MarkRead(id, userID int, readAt time.Time) error {
+	args := m.Called(id, userID, readAt)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepo) MarkAllRead(userID int, readAt time.Time) (int64, error) {
+	args := m.Called(userID, readAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationRepo) GetPreferences(userID int) ([]models.NotificationPreference, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.NotificationPreference), args.Error(1)
+}
+
+func (m *MockNotificationRepo) SetPreferences(userID int, preferences []models.NotificationPreference) error {
+	args := m.Called(userID, preferences)
+	return args.Error(0)
+}
+
+// MockNotifier records notifications emitted by other services
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(notification *models.Notification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func newNotificationService() (*services.NotificationService, *MockNotificationRepo, time.Time) {
+	repo := new(MockNotificationRepo)
+	now := time.Unix(1700000000, 0)
+	return services.NewNotificationService(repo, &FakeClock{now: now}), repo, now
+}
+
+func TestListNotifications(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default page with more to come", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		notifications := make([]models.Notification, 21)
+		repo.On("GetNotifications", 1, models.NotificationFilter{UnreadOnly: true, Limit: 21}).Return(notifications, nil)
+		repo.On("CountUnread", 1).Return(30, nil)
+
+		page, err := service.ListNotifications(1, models.NotificationFilter{UnreadOnly: true})
+		require.NoError(t, err)
+		assert.Len(t, page.Notifications, 20)
+		assert.True(t, page.HasMore)
+		assert.Equal(t, 20, page.Limit)
+		assert.Equal(t, 30, page.UnreadCount)
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		repo.On("GetNotifications", 1, models.NotificationFilter{Limit: 6, Offset: 10}).Return([]models.Notification(nil), nil)
+		repo.On("CountUnread", 1).Return(0, nil)
+
+		page, err := service.ListNotifications(1, models.NotificationFilter{Limit: 5, Offset: 10})
+		require.NoError(t, err)
+		assert.NotNil(t, page.Notifications)
+		assert.False(t, page.HasMore)
+	})
+
+	t.Run("Invalid page", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		_, err := service.ListNotifications(1, models.NotificationFilter{Limit: 500})
+		assert.ErrorAs(t, err, &baseErr)
+		_, err = service.ListNotifications(1, models.NotificationFilter{Offset: -1})
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "GetNotifications", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMarkRead(t *testing.T) {
+	t.Parallel()
+	service, repo, now := newNotificationService()
+
+	repo.On("MarkRead", 7, 1, now).Return(nil)
+	repo.On("MarkRead", 8, 1, now).Return(repository.ErrNoRowsUpdated)
+
+	assert.NoError(t, service.MarkRead(7, 1))
+	assert.ErrorIs(t, service.MarkRead(8, 1), services.ErrNotificationNotFound)
+}
+
+func TestSendReminderNotificationType(t *testing.T) {
+	t.Parallel()
+	service, repo, _ := newNotificationService()
+	before := 60
+
+	repo.On("CreateNotification", mock.MatchedBy(func(n *models.Notification) bool {
+		return n.TaskID != nil && *n.TaskID == 5 && n.Type == models.NotificationTaskReminder
+	})).Return(nil).Once()
+	repo.On("CreateNotification", mock.MatchedBy(func(n *models.Notification) bool {
+		return n.TaskID != nil && *n.TaskID == 6 && n.Type == models.NotificationTaskDueSoon
+	})).Return(nil).Once()
+
+	require.NoError(t, service.SendReminder(&models.Reminder{UserID: 1, TaskID: 5, TaskTitle: "Release"}))
+	require.NoError(t, service.SendReminder(&models.Reminder{UserID: 1, TaskID: 6, TaskTitle: "Release", BeforeDueMinutes: &before}))
+	repo.AssertExpectations(t)
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Unset types are enabled", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		repo.On("GetPreferences", 1).Return([]models.NotificationPreference{
+			{Type: models.NotificationCommentMention, Enabled: false},
+		}, nil)
+
+		preferences, err := service.GetPreferences(1)
+		require.NoError(t, err)
+		assert.Len(t, preferences, len(models.NotificationTypes))
+		for _, p := range preferences {
+			assert.Equal(t, p.Type != models.NotificationCommentMention, p.Enabled, p.Type)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		repo.On("SetPreferences", 1, []models.NotificationPreference{{Type: models.NotificationTaskDueSoon, Enabled: false}}).Return(nil)
+		repo.On("GetPreferences", 1).Return([]models.NotificationPreference{{Type: models.NotificationTaskDueSoon, Enabled: false}}, nil)
+
+		preferences, err := service.UpdatePreferences(1, map[string]bool{models.NotificationTaskDueSoon: false})
+		require.NoError(t, err)
+		assert.Contains(t, preferences, models.NotificationPreference{Type: models.NotificationTaskDueSoon, Enabled: false})
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newNotificationService()
+
+		_, err := service.UpdatePreferences(1, map[string]bool{"newsletter": false})
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "SetPreferences", mock.Anything, mock.Anything)
+	})
+}
+
+func TestStatusChangeNotifiesWatchers(t *testing.T) {
+	t.Parallel()
+	tasks := new(MockTaskRepo)
+	notifier := new(MockNotifier)
+	service := services.NewTaskService(tasks, new(MockWorkspaceRepo), notifier)
+
+	msg := &models.OutboxMessage{
+		ID:       42,
+		Type:     models.TaskEventUpdated,
+		TaskID:   5,
+		UserID:   1,
+		ActorID:  1,
+		Payload:  []byte(`{"id":5,"user_id":1,"title":"Release","status":"done"}`),
+		Previous: []byte(`{"id":5,"user_id":1,"title":"Release","status":"in progress"}`),
+	}
+	tasks.On("GetWatchers", 5).Return([]models.TaskWatcher{{TaskID: 5, UserID: 1}, {TaskID: 5, UserID: 2}, {TaskID: 5, UserID: 3}}, nil)
+	tasks.On("GetVisibleTask", 5, 2).Return(&models.Task{ID: 5}, nil)
+	tasks.On("GetVisibleTask", 5, 3).Return((*models.Task)(nil), nil)
+	notifier.On("Notify", mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == 2 && n.Type == models.NotificationTaskStatusChanged && n.Message == "Release: in progress → done" &&
+			n.OutboxID != nil && *n.OutboxID == 42
+	})).Return(nil).Once()
+
+	require.NoError(t, service.HandleOutbox(msg))
+	notifier.AssertExpectations(t)
+
+	t.Run("Same status", func(t *testing.T) {
+		tasks := new(MockTaskRepo)
+		notifier := new(MockNotifier)
+		service := services.NewTaskService(tasks, new(MockWorkspaceRepo), notifier)
+
+		msg := &models.OutboxMessage{
+			ID:       43,
+			Type:     models.TaskEventUpdated,
+			TaskID:   5,
+			ActorID:  1,
+			Payload:  []byte(`{"id":5,"title":"Release 2","status":"done"}`),
+			Previous: []byte(`{"id":5,"title":"Release","status":"done"}`),
+		}
+
+		require.NoError(t, service.HandleOutbox(msg))
+		tasks.AssertNotCalled(t, "GetWatchers", mock.Anything)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything)
+	})
+
+	t.Run("Failed notification is retried", func(t *testing.T) {
+		tasks := new(MockTaskRepo)
+		notifier := new(MockNotifier)
+		service := services.NewTaskService(tasks, new(MockWorkspaceRepo), notifier)
+
+		tasks.On("GetWatchers", 5).Return([]models.TaskWatcher{{TaskID: 5, UserID: 2}}, nil)
+		tasks.On("GetVisibleTask", 5, 2).Return(&models.Task{ID: 5}, nil)
+		notifier.On("Notify", mock.Anything).Return(errors.New("connection refused"))
+
+		assert.Error(t, service.HandleOutbox(msg))
+	})
+
+	t.Run("Watchers unavailable", func(t *testing.T) {
+		tasks := new(MockTaskRepo)
+		service := services.NewTaskService(tasks, new(MockWorkspaceRepo), new(MockNotifier))
+
+		tasks.On("GetWatchers", 5).Return([]models.TaskWatcher(nil), errors.New("connection refused"))
+
+		assert.Error(t, service.HandleOutbox(msg))
+	})
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultOIDCStateTTL = 10 * time.Minute
+	maxUsernameLength   = 32
+	usernameAttempts    = 20
+)
+
+var unsafeUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type IOIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error)
+}
+
+type IIdentityRepository interface {
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+	TouchIdentity(id int, email string, now time.Time) error
+}
+
+type IOIDCUserRepository interface {
+	UserExists(user *models.User) (bool, error)
+	EmailExists(email string, excludeUserID int) (bool, error)
+}
+
+type ISessionIssuer interface {
+	StartSession(userID int) (string, error)
+}
+
+// OIDCProvider pairs a provider client with its settings from config.yml
+type OIDCProvider struct {
+	Client   IOIDCProvider
+	Settings config.OIDCProvider
+}
+
+// OIDCService signs users in through external OpenID Connect providers.
+// The flow state, nonce and PKCE verifier travel in a short-lived signed
+// token that the handler keeps in a cookie between redirect and callback
+type OIDCService struct {
+	providers map[string]OIDCProvider
+	repo      IIdentityRepository
+	users     IOIDCUserRepository
+	sessions  ISessionIssuer
+	stateTTL  time.Duration
+	clock     clock.Clock
+	log       zerolog.Logger
+}
+
+func NewOIDCService(
+	providers []OIDCProvider,
+	repo IIdentityRepository,
+	users IOIDCUserRepository,
+	sessions ISessionIssuer,
+	stateTTL time.Duration,
+	clock clock.Clock,
+) *OIDCService {
+	if stateTTL == 0 {
+		stateTTL = defaultOIDCStateTTL
+	}
+
+	byName := make(map[string]OIDCProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Settings.Name] = p
+	}
+
+	return &OIDCService{
+		providers: byName,
+		repo:      repo,
+		users:     users,
+		sessions:  sessions,
+		stateTTL:  stateTTL,
+		clock:     clock,
+		log:       logger.GetLogger(),
+	}
+}
+
+// Providers returns the names of the configured providers
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Begin starts a login, or links the provider to linkUserID when it isn't 0.
+// It returns the provider's login page and the signed flow state
+func (s *OIDCService) Begin(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oidc.NewVerifier()
+
+	authURL, err := provider.Client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	stateToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"oidc_provider":     providerName,
+		"oidc_state":        state,
+		"oidc_nonce":        nonce,
+		"oidc_verifier":     verifier,
+		"oidc_link_user_id": linkUserID,
+		"exp":               s.clock.Now().Add(s.stateTTL).Unix(),
+	}).SignedString([]byte(os.Getenv("JWTSECRET")))
+	if err != nil {
+		s.log.Error().Err(err).Msg("SignedString error")
+		return "", "", err
+	}
+
+	return authURL, stateToken, nil
+}
+
+// Complete finishes the flow started by Begin and returns a session token. Users
+// with 2FA enabled get an mfa pending token together with ErrMFARequired instead
+func (s *OIDCService) Complete(ctx context.Context, providerName, stateToken, state, code string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownOIDCProvider
+	}
+
+	flow, err := s.parseState(stateToken)
+	if err != nil {
+		return "", err
+	}
+	if flow.provider != providerName || subtle.ConstantTimeCompare([]byte(flow.state), []byte(state)) != 1 {
+		return "", ErrInvalidOIDCState
+	}
+
+	claims, err := provider.Client.Exchange(ctx, code, flow.verifier, flow.nonce)
+	if err != nil {
+		return "", err
+	}
+
+	userID, err := s.resolveUser(provider.Settings, claims, flow.linkUserID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.sessions.StartSession(userID)
+}
+
+func (s *OIDCService) resolveUser(settings config.OIDCProvider, claims *oidc.Claims, linkUserID int) (int, error) {
+	now := s.clock.Now()
+
+	identity, err := s.repo.GetIdentity(settings.Name, claims.Subject)
+	if err != nil {
+		return 0, err
+	}
+
+	if identity != nil {
+		if linkUserID != 0 && identity.UserID != linkUserID {
+			return 0, ErrIdentityAlreadyLinked
+		}
+		if err := s.repo.TouchIdentity(identity.ID, claims.Email, now); err != nil {
+			s.log.Error().Err(err).Int("identity_id", identity.ID).Msg("Failed to record identity login")
+		}
+		return identity.UserID, nil
+	}
+
+	identity = &models.UserIdentity{
+		UserID:      linkUserID,
+		Provider:    settings.Name,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}
+
+	if linkUserID != 0 {
+		if err := s.repo.CreateIdentity(identity); err != nil {
+			return 0, err
+		}
+		s.log.Info().Int("user_id", linkUserID).Str("provider", settings.Name).Msg("External identity linked")
+		return linkUserID, nil
+	}
+
+	if !settings.AutoProvision {
+		return 0, ErrOIDCProvisioningDisabled
+	}
+	if !domainAllowed(settings.AllowedDomains, claims) {
+		return 0, ErrOIDCDomainNotAllowed
+	}
+
+	user, err := s.newUser(settings.Name, claims, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.repo.CreateUserWithIdentity(user, identity); err != nil {
+		return 0, err
+	}
+
+	s.log.Info().Int("user_id", user.ID).Str("provider", settings.Name).Msg("User provisioned from external identity")
+	return user.ID, nil
+}
+
+// newUser prepares an account without a local password. The email is kept
+// only when the provider verified it and no other account uses it
+func (s *OIDCService) newUser(providerName string, claims *oidc.Claims, now time.Time) (*models.User, error) {
+	username, err := s.freeUsername(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: username}
+	if claims.Email != "" && claims.EmailVerified {
+		exists, err := s.users.EmailExists(claims.Email, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			user.Email = claims.Email
+			user.EmailVerifiedAt = &now
+		}
+	}
+
+	return user, nil
+}
+
+func (s *OIDCService) freeUsername(providerName string, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = unsafeUsernameChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = providerName + "-user"
+	}
+	if len(base) > maxUsernameLength-4 {
+		base = base[:maxUsernameLength-4]
+	}
+
+	candidate := base
+	for i := 2; i <= usernameAttempts+1; i++ {
+		exists, err := s.users.UserExists(&models.User{Username: candidate})
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	suffix, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + strings.ToLower(suffix[:6]), nil
+}
+
+type oidcFlow struct {
+	provider   string
+	state      string
+	nonce      string
+	verifier   string
+	linkUserID int
+}
+
+func (s *OIDCService) parseState(stateToken string) (*oidcFlow, error) {
+	token, err := jwt.Parse(stateToken, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("JWTSECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidOIDCState
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidOIDCState
+	}
+
+	flow := &oidcFlow{}
+	flow.provider, _ = claims["oidc_provider"].(string)
+	flow.state, _ = claims["oidc_state"].(string)
+	flow.nonce, _ = claims["oidc_nonce"].(string)
+	flow.verifier, _ = claims["oidc_verifier"].(string)
+	linkUserID, _ := claims["oidc_link_user_id"].(float64)
+	flow.linkUserID = int(linkUserID)
+
+	if flow.state == "" || flow.nonce == "" || flow.verifier == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	return flow, nil
+}
+
+func domainAllowed(domains []string, claims *oidc.Claims) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	if !claims.EmailVerified {
+		return false
+	}
+
+	at := strings.LastIndex(claims.Email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(claims.Email[at+1:])
+	for _, allowed := range domains {
+		if strings.ToLower(allowed) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,273 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/oidc"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOIDCProvider struct {
+	mock.Mock
+}
+
+func (m *MockOIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	args := m.Called(state, nonce, verifier)
+	return "https://sso.example.com/authorize?" + url.Values{"state": {state}}.Encode(), args.Error(0)
+}
+
+func (m *MockOIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error) {
+	args := m.Called(code, verifier, nonce)
+	return args.Get(0).(*oidc.Claims), args.Error(1)
+}
+
+type MockIdentityRepo struct {
+	mock.Mock
+}
+
+func (m *MockIdentityRepo) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	args := m.Called(provider, subject)
+	return args.Get(0).(*models.UserIdentity), args.Error(1)
+}
+
+func (m *MockIdentityRepo) CreateIdentity(identity *models.UserIdentity) error {
+	args := m.Called(identity)
+	return args.Error(0)
+}
+
+func (m *MockIdentityRepo) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	args := m.Called(user, identity)
+	return args.Error(0)
+}
+
+func (m *MockIdentityRepo) GetIdentitiesByUserID(userID int) ([]models.UserIdentity, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.UserIdentity), args.Error(1)
+}
+
+func (m *MockIdentityRepo) TouchIdentity(id int, email string, now time.Time) error {
+	args := m.Called(id, email, now)
+	return args.Error(0)
+}
+
+func (m *MockIdentityRepo) DeleteIdentity(provider, subject string) error {
+	args := m.Called(provider, subject)
+	return args.Error(0)
+}
+
+type MockSessionIssuer struct {
+	mock.Mock
+}
+
+func (m *MockSessionIssuer) StartSession(userID int) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+type oidcFixture struct {
+	provider *MockOIDCProvider
+	repo     *MockIdentityRepo
+	users    *MockUserRepo
+	sessions *MockSessionIssuer
+	service  *services.OIDCService
+	clock    *FakeClock
+}
+
+func newOIDCFixture(t *testing.T, settings config.OIDCProvider) *oidcFixture {
+	t.Setenv("JWTSECRET", "test-secret")
+
+	f := &oidcFixture{
+		provider: new(MockOIDCProvider),
+		repo:     new(MockIdentityRepo),
+		users:    new(MockUserRepo),
+		sessions: new(MockSessionIssuer),
+		clock:    &FakeClock{now: time.Now()},
+	}
+	settings.Name = "company"
+	f.service = services.NewOIDCService(
+		[]services.OIDCProvider{{Client: f.provider, Settings: settings}},
+		f.repo, f.users, f.sessions, time.Minute, f.clock,
+	)
+	return f
+}
+
+// begin runs the redirect step and returns the state sent to the provider
+// together with the signed flow state kept in the cookie
+func (f *oidcFixture) begin(t *testing.T, linkUserID int) (string, string) {
+	f.provider.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	authURL, stateToken, err := f.service.Begin(context.Background(), "company", linkUserID)
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	return u.Query().Get("state"), stateToken
+}
+
+var companyClaims = &oidc.Claims{
+	Subject:           "ext-1",
+	Email:             "alice@example.com",
+	EmailVerified:     true,
+	PreferredUsername: "alice",
+}
+
+func TestOIDCLoginExistingIdentity(t *testing.T) {
+	f := newOIDCFixture(t, config.OIDCProvider{})
+	state, stateToken := f.begin(t, 0)
+
+	f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+	f.repo.On("GetIdentity", "company", "ext-1").Return(&models.UserIdentity{ID: 3, UserID: 7}, nil)
+	f.repo.On("TouchIdentity", 3, "alice@example.com", f.clock.now).Return(nil)
+	f.sessions.On("StartSession", 7).Return("session", nil)
+
+	token, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+	assert.NoError(t, err)
+	assert.Equal(t, "session", token)
+	f.repo.AssertExpectations(t)
+}
+
+func TestOIDCLoginSecondFactor(t *testing.T) {
+	f := newOIDCFixture(t, config.OIDCProvider{})
+	state, stateToken := f.begin(t, 0)
+
+	f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+	f.repo.On("GetIdentity", "company", "ext-1").Return(&models.UserIdentity{ID: 3, UserID: 7}, nil)
+	f.repo.On("TouchIdentity", 3, "alice@example.com", f.clock.now).Return(nil)
+	f.sessions.On("StartSession", 7).Return("mfa-token", services.ErrMFARequired)
+
+	token, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+	assert.ErrorIs(t, err, services.ErrMFARequired)
+	assert.Equal(t, "mfa-token", token)
+}
+
+func TestOIDCStateValidation(t *testing.T) {
+	f := newOIDCFixture(t, config.OIDCProvider{})
+	state, stateToken := f.begin(t, 0)
+
+	t.Run("State mismatch", func(t *testing.T) {
+		_, err := f.service.Complete(context.Background(), "company", stateToken, "forged", "code")
+		assert.ErrorIs(t, err, services.ErrInvalidOIDCState)
+	})
+
+	t.Run("Tampered state token", func(t *testing.T) {
+		_, err := f.service.Complete(context.Background(), "company", stateToken+"x", state, "code")
+		assert.ErrorIs(t, err, services.ErrInvalidOIDCState)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		_, err := f.service.Complete(context.Background(), "other", stateToken, state, "code")
+		assert.ErrorIs(t, err, services.ErrUnknownOIDCProvider)
+	})
+
+	f.provider.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCPKCEParametersReachExchange(t *testing.T) {
+	f := newOIDCFixture(t, config.OIDCProvider{})
+
+	var sentNonce, sentVerifier string
+	f.provider.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sentNonce, sentVerifier = args.String(1), args.String(2)
+	}).Return(nil)
+
+	authURL, stateToken, err := f.service.Begin(context.Background(), "company", 0)
+	require.NoError(t, err)
+	u, _ := url.Parse(authURL)
+
+	f.provider.On("Exchange", "code", sentVerifier, sentNonce).Return(companyClaims, nil)
+	f.repo.On("GetIdentity", "company", "ext-1").Return(&models.UserIdentity{ID: 3, UserID: 7}, nil)
+	f.repo.On("TouchIdentity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	f.sessions.On("StartSession", 7).Return("session", nil)
+
+	_, err = f.service.Complete(context.Background(), "company", stateToken, u.Query().Get("state"), "code")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sentVerifier)
+	f.provider.AssertExpectations(t)
+}
+
+func TestOIDCAutoProvision(t *testing.T) {
+	t.Run("Provisions user with free username and verified email", func(t *testing.T) {
+		f := newOIDCFixture(t, config.OIDCProvider{AutoProvision: true})
+		state, stateToken := f.begin(t, 0)
+
+		f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+		f.repo.On("GetIdentity", "company", "ext-1").Return((*models.UserIdentity)(nil), nil)
+		f.users.On("UserExists", &models.User{Username: "alice"}).Return(true, nil)
+		f.users.On("UserExists", &models.User{Username: "alice-2"}).Return(false, nil)
+		f.users.On("EmailExists", "alice@example.com", 0).Return(false, nil)
+		f.repo.On("CreateUserWithIdentity", mock.MatchedBy(func(user *models.User) bool {
+			return user.Username == "alice-2" && user.EmailVerified() && user.PasswordHash == ""
+		}), mock.MatchedBy(func(identity *models.UserIdentity) bool {
+			return identity.Provider == "company" && identity.Subject == "ext-1"
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.User).ID = 11
+		}).Return(nil)
+		f.sessions.On("StartSession", 11).Return("session", nil)
+
+		token, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+		assert.NoError(t, err)
+		assert.Equal(t, "session", token)
+		f.repo.AssertExpectations(t)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		f := newOIDCFixture(t, config.OIDCProvider{AutoProvision: false})
+		state, stateToken := f.begin(t, 0)
+
+		f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+		f.repo.On("GetIdentity", "company", "ext-1").Return((*models.UserIdentity)(nil), nil)
+
+		_, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+		assert.ErrorIs(t, err, services.ErrOIDCProvisioningDisabled)
+		f.repo.AssertNotCalled(t, "CreateUserWithIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Domain not allowed", func(t *testing.T) {
+		f := newOIDCFixture(t, config.OIDCProvider{AutoProvision: true, AllowedDomains: []string{"corp.example.com"}})
+		state, stateToken := f.begin(t, 0)
+
+		f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+		f.repo.On("GetIdentity", "company", "ext-1").Return((*models.UserIdentity)(nil), nil)
+
+		_, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+		assert.ErrorIs(t, err, services.ErrOIDCDomainNotAllowed)
+	})
+}
+
+func TestOIDCLink(t *testing.T) {
+	t.Run("Links identity to current user", func(t *testing.T) {
+		f := newOIDCFixture(t, config.OIDCProvider{})
+		state, stateToken := f.begin(t, 5)
+
+		f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+		f.repo.On("GetIdentity", "company", "ext-1").Return((*models.UserIdentity)(nil), nil)
+		f.repo.On("CreateIdentity", mock.MatchedBy(func(identity *models.UserIdentity) bool {
+			return identity.UserID == 5 && identity.Subject == "ext-1"
+		})).Return(nil)
+		f.sessions.On("StartSession", 5).Return("session", nil)
+
+		_, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+		assert.NoError(t, err)
+		f.repo.AssertExpectations(t)
+	})
+
+	t.Run("Identity of another user", func(t *testing.T) {
+		f := newOIDCFixture(t, config.OIDCProvider{})
+		state, stateToken := f.begin(t, 5)
+
+		f.provider.On("Exchange", "code", mock.Anything, mock.Anything).Return(companyClaims, nil)
+		f.repo.On("GetIdentity", "company", "ext-1").Return(&models.UserIdentity{ID: 3, UserID: 7}, nil)
+
+		_, err := f.service.Complete(context.Background(), "company", stateToken, state, "code")
+		assert.ErrorIs(t, err, services.ErrIdentityAlreadyLinked)
+		f.sessions.AssertNotCalled(t, "StartSession", mock.Anything)
+	})
+}
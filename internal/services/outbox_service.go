@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/rs/zerolog"
+)
+
+type IOutboxRepository interface {
+	ProcessBatch(now time.Time, limit int, handle func(msg *models.OutboxMessage) error) (int, error)
+	GetConsumers(messageID int64) ([]string, error)
+	MarkConsumed(consumer string, messageID int64, now time.Time) error
+	DeleteProcessedBefore(before time.Time) (int64, error)
+}
+
+// OutboxHandler consumes an outbox message. Messages are delivered at least
+// once, a handler may see the same message again after a failure and should
+// use the message id to avoid repeating its effect
+type OutboxHandler func(msg *models.OutboxMessage) error
+
+type outboxConsumer struct {
+	name   string
+	handle OutboxHandler
+}
+
+// OutboxRelay hands the task changes stored in the outbox to the registered
+// consumers. A message is retried with backoff until every consumer handled it,
+// consumers that succeeded are recorded and skipped on the retries
+type OutboxRelay struct {
+	repo      IOutboxRepository
+	cfg       config.Outbox
+	clock     clock.Clock
+	log       zerolog.Logger
+	consumers []outboxConsumer
+}
+
+func NewOutboxRelay(repo IOutboxRepository, cfg config.Outbox, clock clock.Clock) *OutboxRelay {
+	return &OutboxRelay{
+		repo:  repo,
+		cfg:   cfg,
+		clock: clock,
+		log:   logger.GetLogger(),
+	}
+}
+
+// Register adds a consumer, the name is its idempotency key and must stay the
+// same between releases. Consumers are registered before Run
+func (r *OutboxRelay) Register(name string, handle OutboxHandler) {
+	r.consumers = append(r.consumers, outboxConsumer{name: name, handle: handle})
+}
+
+// Run relays pending messages and prunes processed ones until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	pruneTicker := time.NewTicker(time.Minute)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RelayPending(ctx)
+		case <-pruneTicker.C:
+			if _, err := r.repo.DeleteProcessedBefore(r.clock.Now().Add(-r.cfg.Retention)); err != nil {
+				r.log.Error().Err(err).Msg("Failed to prune outbox")
+			}
+		}
+	}
+}
+
+// RelayPending processes batches of pending messages until none is left
+func (r *OutboxRelay) RelayPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.repo.ProcessBatch(r.clock.Now(), r.cfg.BatchSize, r.dispatch)
+		if err != nil {
+			r.log.Error().Err(err).Msg("Failed to process outbox batch")
+			return
+		}
+
+		if n < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) dispatch(msg *models.OutboxMessage) error {
+	done, err := r.repo.GetConsumers(msg.ID)
+	if err != nil {
+		return r.fail(msg, err)
+	}
+
+	var errs []error
+	for _, consumer := range r.consumers {
+		if slices.Contains(done, consumer.name) {
+			continue
+		}
+
+		if err := consumer.handle(msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", consumer.name, err))
+			continue
+		}
+
+		if err := r.repo.MarkConsumed(consumer.name, msg.ID, r.clock.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", consumer.name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return r.fail(msg, errors.Join(errs...))
+	}
+	return nil
+}
+
+// fail schedules the next attempt of the message. After the last attempt the
+// message is given up: it is stored as processed with the error kept
+func (r *OutboxRelay) fail(msg *models.OutboxMessage, err error) error {
+	msg.Attempts++
+	message := err.Error()
+	msg.LastError = &message
+
+	if msg.Attempts >= r.cfg.MaxAttempts {
+		r.log.Error().Err(err).Int64("message_id", msg.ID).Int("attempts", msg.Attempts).Msg("Giving up outbox message")
+		return nil
+	}
+
+	r.log.Warn().Err(err).Int64("message_id", msg.ID).Int("attempts", msg.Attempts).Msg("Outbox message failed, retrying")
+	msg.AvailableAt = r.clock.Now().Add(backoff(msg.Attempts, r.cfg.RetryBaseDelay, r.cfg.RetryMaxDelay))
+	return err
+}
+
+// backoff returns the delay before the next attempt: the base delay doubled
+// for every failed attempt after the first, capped at the maximum delay
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOutboxRepo struct {
+	mock.Mock
+}
+
+// ProcessBatch passes the messages given to the mock to handle and records the
+// handle results in the second return value
+func (m *MockOutboxRepo) ProcessBatch(now time.Time, limit int, handle func(msg *models.OutboxMessage) error) (int, error) {
+	args := m.Called(now, limit)
+	messages := args.Get(0).([]*models.OutboxMessage)
+	results := args.Get(1).(*[]error)
+	for _, msg := range messages {
+		*results = append(*results, handle(msg))
+	}
+	return len(messages), args.Error(2)
+}
+
+func (m *MockOutboxRepo) GetConsumers(messageID int64) ([]string, error) {
+	args := m.Called(messageID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockOutboxRepo) MarkConsumed(consumer string, messageID int64, now time.Time) error {
+	args := m.Called(consumer, messageID, now)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepo) DeleteProcessedBefore(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+var outboxConfig = config.Outbox{
+	PollInterval:   time.Second,
+	BatchSize:      10,
+	MaxAttempts:    3,
+	RetryBaseDelay: 5 * time.Second,
+	RetryMaxDelay:  time.Minute,
+	Retention:      24 * time.Hour,
+}
+
+func TestRelayPending(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+
+	t.Run("Every consumer handles the message", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockOutboxRepo)
+		relay := services.NewOutboxRelay(repo, outboxConfig, &FakeClock{now: now})
+
+		var handled []string
+		relay.Register("task_events", func(msg *models.OutboxMessage) error {
+			handled = append(handled, "task_events")
+			return nil
+		})
+		relay.Register("webhooks", func(msg *models.OutboxMessage) error {
+			handled = append(handled, "webhooks")
+			return nil
+		})
+
+		var results []error
+		repo.On("ProcessBatch", now, 10).Return([]*models.OutboxMessage{{ID: 42}}, &results, nil)
+		repo.On("GetConsumers", int64(42)).Return([]string(nil), nil)
+		repo.On("MarkConsumed", "task_events", int64(42), now).Return(nil).Once()
+		repo.On("MarkConsumed", "webhooks", int64(42), now).Return(nil).Once()
+
+		relay.RelayPending(context.Background())
+		assert.Equal(t, []string{"task_events", "webhooks"}, handled)
+		assert.Equal(t, []error{nil}, results)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Failed consumer is retried alone", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockOutboxRepo)
+		relay := services.NewOutboxRelay(repo, outboxConfig, &FakeClock{now: now})
+
+		relay.Register("task_events", func(msg *models.OutboxMessage) error {
+			t.Error("consumer that already handled the message is called again")
+			return nil
+		})
+		relay.Register("webhooks", func(msg *models.OutboxMessage) error {
+			return errors.New("db down")
+		})
+
+		var results []error
+		msg := &models.OutboxMessage{ID: 42, Attempts: 1}
+		repo.On("ProcessBatch", now, 10).Return([]*models.OutboxMessage{msg}, &results, nil)
+		repo.On("GetConsumers", int64(42)).Return([]string{"task_events"}, nil)
+
+		relay.RelayPending(context.Background())
+		require.Len(t, results, 1)
+		assert.Error(t, results[0])
+		assert.Equal(t, 2, msg.Attempts)
+		assert.Equal(t, "webhooks: db down", *msg.LastError)
+		assert.Equal(t, now.Add(10*time.Second), msg.AvailableAt)
+		repo.AssertNotCalled(t, "MarkConsumed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Message is given up after the last attempt", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockOutboxRepo)
+		relay := services.NewOutboxRelay(repo, outboxConfig, &FakeClock{now: now})
+
+		relay.Register("webhooks", func(msg *models.OutboxMessage) error {
+			return errors.New("db down")
+		})
+
+		var results []error
+		msg := &models.OutboxMessage{ID: 42, Attempts: 2}
+		repo.On("ProcessBatch", now, 10).Return([]*models.OutboxMessage{msg}, &results, nil)
+		repo.On("GetConsumers", int64(42)).Return([]string(nil), nil)
+
+		relay.RelayPending(context.Background())
+		assert.Equal(t, []error{nil}, results)
+		assert.Equal(t, 3, msg.Attempts)
+		assert.NotNil(t, msg.LastError)
+	})
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+// presenceBuffer is the number of presence updates queued for a session
+const presenceBuffer = 16
+
+type IPresenceUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+type PresenceUpdate struct {
+	TaskID  int
+	Viewers []models.Viewer
+}
+
+// PresenceSession is one connection of a user. Updates receives the viewers of
+// the tasks the session joined whenever they change and is closed on Disconnect
+type PresenceSession struct {
+	Viewer  models.Viewer
+	Updates <-chan PresenceUpdate
+	updates chan PresenceUpdate
+	tasks   map[int]struct{}
+}
+
+// PresenceService tracks which users are viewing which task. The state lives in
+// memory of the instance, so with several instances a user sees the viewers
+// connected to the same instance
+type PresenceService struct {
+	users IPresenceUserRepository
+
+	mu    sync.Mutex
+	tasks map[int]map[*PresenceSession]struct{}
+}
+
+func NewPresenceService(users IPresenceUserRepository) *PresenceService {
+	return &PresenceService{
+		users: users,
+		tasks: make(map[int]map[*PresenceSession]struct{}),
+	}
+}
+
+func (s *PresenceService) Connect(userID int) (*PresenceSession, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	updates := make(chan PresenceUpdate, presenceBuffer)
+	return &PresenceSession{
+		Viewer:  models.Viewer{UserID: user.ID, Username: user.Username},
+		Updates: updates,
+		updates: updates,
+		tasks:   make(map[int]struct{}),
+	}, nil
+}
+
+// Join marks the session as viewing the task and tells every viewer of the
+// task, the session included, who is viewing it now
+func (s *PresenceService) Join(session *PresenceSession, taskID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := session.tasks[taskID]; ok {
+		return
+	}
+	session.tasks[taskID] = struct{}{}
+
+	if s.tasks[taskID] == nil {
+		s.tasks[taskID] = make(map[*PresenceSession]struct{})
+	}
+	s.tasks[taskID][session] = struct{}{}
+
+	s.broadcast(taskID)
+}
+
+func (s *PresenceService) Leave(session *PresenceSession, taskID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leave(session, taskID)
+}
+
+// Disconnect removes the session from every task and closes its updates
+func (s *PresenceService) Disconnect(session *PresenceSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for taskID := range session.tasks {
+		s.leave(session, taskID)
+	}
+	close(session.updates)
+}
+
+// Viewers returns the users viewing the task, each user once
+func (s *PresenceService) Viewers(taskID int) []models.Viewer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.viewers(taskID)
+}
+
+func (s *PresenceService) leave(session *PresenceSession, taskID int) {
+	if _, ok := session.tasks[taskID]; !ok {
+		return
+	}
+	delete(session.tasks, taskID)
+
+	delete(s.tasks[taskID], session)
+	if len(s.tasks[taskID]) == 0 {
+		delete(s.tasks, taskID)
+		return
+	}
+
+	s.broadcast(taskID)
+}
+
+// broadcast queues the current viewers for every session of the task. A
+// session that fell behind misses the update, the next one carries the full list
+func (s *PresenceService) broadcast(taskID int) {
+	update := PresenceUpdate{TaskID: taskID, Viewers: s.viewers(taskID)}
+	for session := range s.tasks[taskID] {
+		select {
+		case session.updates <- update:
+		default:
+		}
+	}
+}
+
+func (s *PresenceService) viewers(taskID int) []models.Viewer {
+	seen := make(map[int]models.Viewer)
+	for session := range s.tasks[taskID] {
+		seen[session.Viewer.UserID] = session.Viewer
+	}
+
+	viewers := make([]models.Viewer, 0, len(seen))
+	for _, viewer := range seen {
+		viewers = append(viewers, viewer)
+	}
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].UserID < viewers[j].UserID })
+
+	return viewers
+}
@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPresenceService() *services.PresenceService {
+	users := new(MockUserRepo)
+	users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice"}, nil)
+	users.On("GetUserByID", 2).Return(&models.User{ID: 2, Username: "bob"}, nil)
+	users.On("GetUserByID", 3).Return((*models.User)(nil), nil)
+	return services.NewPresenceService(users)
+}
+
+func TestPresence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Join and leave are broadcast", func(t *testing.T) {
+		t.Parallel()
+		service := newPresenceService()
+
+		alice, err := service.Connect(1)
+		require.NoError(t, err)
+		bob, err := service.Connect(2)
+		require.NoError(t, err)
+
+		service.Join(alice, 42)
+		update := <-alice.Updates
+		assert.Equal(t, 42, update.TaskID)
+		assert.Equal(t, []models.Viewer{{UserID: 1, Username: "alice"}}, update.Viewers)
+
+		service.Join(bob, 42)
+		both := []models.Viewer{{UserID: 1, Username: "alice"}, {UserID: 2, Username: "bob"}}
+		assert.Equal(t, both, (<-alice.Updates).Viewers)
+		assert.Equal(t, both, (<-bob.Updates).Viewers)
+
+		service.Leave(bob, 42)
+		assert.Equal(t, []models.Viewer{{UserID: 1, Username: "alice"}}, (<-alice.Updates).Viewers)
+		assert.Len(t, bob.Updates, 0)
+	})
+
+	t.Run("User with several connections is listed once", func(t *testing.T) {
+		t.Parallel()
+		service := newPresenceService()
+
+		first, err := service.Connect(1)
+		require.NoError(t, err)
+		second, err := service.Connect(1)
+		require.NoError(t, err)
+
+		service.Join(first, 42)
+		service.Join(second, 42)
+		service.Join(second, 42)
+
+		assert.Equal(t, []models.Viewer{{UserID: 1, Username: "alice"}}, service.Viewers(42))
+	})
+
+	t.Run("Disconnect leaves every task", func(t *testing.T) {
+		t.Parallel()
+		service := newPresenceService()
+
+		alice, err := service.Connect(1)
+		require.NoError(t, err)
+		bob, err := service.Connect(2)
+		require.NoError(t, err)
+
+		service.Join(alice, 42)
+		service.Join(alice, 43)
+		service.Join(bob, 42)
+		<-bob.Updates
+
+		service.Disconnect(alice)
+
+		assert.Equal(t, []models.Viewer{{UserID: 2, Username: "bob"}}, (<-bob.Updates).Viewers)
+		assert.Empty(t, service.Viewers(43))
+
+		for range alice.Updates {
+		}
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		t.Parallel()
+		service := newPresenceService()
+
+		session, err := service.Connect(3)
+		assert.ErrorIs(t, err, repository.ErrUserNotFound)
+		assert.Nil(t, session)
+	})
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+type IProfileRepository interface {
+	GetUserByID(id int) (*models.User, error)
+	UserExists(user *models.User) (bool, error)
+	UpdateUser(userID int, updates map[string]interface{}) error
+	DeleteUser(userID int) error
+}
+
+type IProfileWorkspaceRepository interface {
+	GetWorkspacesByUserID(userID int) ([]models.Workspace, error)
+}
+
+type ProfileService struct {
+	repo       IProfileRepository
+	workspaces IProfileWorkspaceRepository
+	mfa        IMFAVerifier
+	hasher     IPasswordHasher
+	throttle   ILoginThrottler
+	log        zerolog.Logger
+}
+
+func NewProfileService(repo IProfileRepository, workspaces IProfileWorkspaceRepository, mfa IMFAVerifier, hasher IPasswordHasher, throttle ILoginThrottler) *ProfileService {
+	return &ProfileService{
+		repo:       repo,
+		workspaces: workspaces,
+		mfa:        mfa,
+		hasher:     hasher,
+		throttle:   throttle,
+		log:        logger.GetLogger(),
+	}
+}
+
+func (s *ProfileService) GetProfile(userID int) (*models.Profile, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfa.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewProfile(user, enabled), nil
+}
+
+// UpdateProfile applies the allowed fields of updates and returns the new profile
+func (s *ProfileService) UpdateProfile(userID int, updates map[string]interface{}) (*models.Profile, error) {
+	updates, err := helpers.ValidateProfileUpdates(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if username, ok := updates["username"].(string); ok {
+		if username == user.Username {
+			delete(updates, "username")
+		} else {
+			exists, err := s.repo.UserExists(&models.User{Username: username})
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, repository.ErrUniqueUser
+			}
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.repo.UpdateUser(userID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetProfile(userID)
+}
+
+// DeleteAccount removes the user and everything owned by it after checking
+// the password. Wrong passwords count as failed logins. Accounts created
+// through single sign-on have no password and confirm with a current TOTP or
+// recovery code instead. Owners of a workspace have to delete it first, the
+// workspace would be left without an owner
+func (s *ProfileService) DeleteAccount(userID int, req models.DeleteAccountRequest) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordHash == "" {
+		err = s.confirmWithCode(userID, req.Code)
+	} else {
+		err = s.confirmWithPassword(user, req.Password)
+	}
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := s.workspaces.GetWorkspacesByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, workspace := range workspaces {
+		if workspace.Role == models.RoleOwner {
+			return ErrOwnsWorkspace
+		}
+	}
+
+	if err := s.repo.DeleteUser(userID); err != nil {
+		return err
+	}
+
+	s.log.Info().Int("user_id", userID).Msg("Account deleted")
+	return nil
+}
+
+func (s *ProfileService) confirmWithPassword(user *models.User, password string) error {
+	key := UsernameKey(user.Username)
+	if err := s.throttle.Check(key); err != nil {
+		return err
+	}
+
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := s.throttle.Fail(key); err != nil {
+			s.log.Error().Err(err).Str("key", key).Msg("Failed to register login failure")
+		}
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// confirmWithCode checks a second factor for accounts without a password,
+// without 2FA there is nothing left to confirm the deletion with
+func (s *ProfileService) confirmWithCode(userID int, code string) error {
+	enabled, err := s.mfa.Enabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNoLocalPassword
+	}
+
+	key := MFAKey(userID)
+	if err := s.throttle.Check(key); err != nil {
+		return err
+	}
+
+	if err := s.mfa.Verify(userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.throttle.Fail(key); err != nil {
+				s.log.Error().Err(err).Str("key", key).Msg("Failed to register mfa failure")
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ProfileService) getUser(userID int) (*models.User, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
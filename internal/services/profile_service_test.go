@@ -0,0 +1,193 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetProfile(t *testing.T) {
+	mockRepo, mockMFA := new(MockUserRepo), new(MockMFAVerifier)
+	service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), mockMFA, testHasher, newAllowingThrottler())
+
+	mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice", Timezone: "UTC", Locale: "en", PasswordHash: "hash"}, nil)
+	mockMFA.On("Enabled", 1).Return(true, nil)
+
+	profile, err := service.GetProfile(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", profile.Username)
+	assert.True(t, profile.MFAEnabled)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	user := &models.User{ID: 1, Username: "alice", Timezone: "UTC", Locale: "en"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, mockMFA := new(MockUserRepo), new(MockMFAVerifier)
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), mockMFA, testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockRepo.On("UserExists", &models.User{Username: "alice2"}).Return(false, nil)
+		mockRepo.On("UpdateUser", 1, map[string]interface{}{
+			"username":     "alice2",
+			"display_name": "Alice",
+			"timezone":     "Europe/Moscow",
+			"locale":       "ru-RU",
+		}).Return(nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+
+		_, err := service.UpdateProfile(1, map[string]interface{}{
+			"username":     "alice2",
+			"display_name": " Alice ",
+			"timezone":     "Europe/Moscow",
+			"locale":       "ru-ru",
+		})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Username taken", func(t *testing.T) {
+		mockRepo := new(MockUserRepo)
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), new(MockMFAVerifier), testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockRepo.On("UserExists", &models.User{Username: "bob"}).Return(true, nil)
+
+		_, err := service.UpdateProfile(1, map[string]interface{}{"username": "bob"})
+		assert.ErrorIs(t, err, repository.ErrUniqueUser)
+		mockRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Same username is not an update", func(t *testing.T) {
+		mockRepo, mockMFA := new(MockUserRepo), new(MockMFAVerifier)
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), mockMFA, testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+
+		_, err := service.UpdateProfile(1, map[string]interface{}{"username": "alice"})
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "UserExists", mock.Anything)
+		mockRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	invalid := []struct {
+		name    string
+		updates map[string]interface{}
+	}{
+		{"Empty body", map[string]interface{}{}},
+		{"Unknown field", map[string]interface{}{"password_hash": "x"}},
+		{"Not a string", map[string]interface{}{"timezone": 3}},
+		{"Unknown timezone", map[string]interface{}{"timezone": "Mars/Olympus"}},
+		{"Invalid locale", map[string]interface{}{"locale": "not a locale"}},
+		{"Blank username", map[string]interface{}{"username": "  "}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			service := services.NewProfileService(new(MockUserRepo), new(MockWorkspaceRepo), new(MockMFAVerifier), testHasher, newAllowingThrottler())
+
+			_, err := service.UpdateProfile(1, tt.updates)
+			assert.ErrorAs(t, err, &baseErr)
+		})
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	hash, _ := testHasher.Hash("secret")
+	user := &models.User{ID: 1, Username: "alice", PasswordHash: hash}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, workspaces := new(MockUserRepo), new(MockWorkspaceRepo)
+		service := services.NewProfileService(mockRepo, workspaces, new(MockMFAVerifier), testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 3, Role: models.RoleMember}}, nil)
+		mockRepo.On("DeleteUser", 1).Return(nil)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Password: "secret"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Workspace owner", func(t *testing.T) {
+		mockRepo, workspaces := new(MockUserRepo), new(MockWorkspaceRepo)
+		service := services.NewProfileService(mockRepo, workspaces, new(MockMFAVerifier), testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{{ID: 3, Role: models.RoleMember}, {ID: 4, Role: models.RoleOwner}}, nil)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Password: "secret"})
+		assert.ErrorIs(t, err, services.ErrOwnsWorkspace)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockRepo, throttle := new(MockUserRepo), newAllowingThrottler()
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), new(MockMFAVerifier), testHasher, throttle)
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Password: "wrong"})
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+		throttle.AssertCalled(t, "Fail", []string{services.UsernameKey("alice")})
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		mockRepo, throttle := new(MockUserRepo), new(MockLoginThrottler)
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), new(MockMFAVerifier), testHasher, throttle)
+
+		mockRepo.On("GetUserByID", 1).Return(user, nil)
+		throttle.On("Check", mock.Anything).Return(&services.LockedError{})
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Password: "secret"})
+		assert.ErrorIs(t, err, services.ErrTooManyAttempts)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+	})
+
+	t.Run("Account without password or 2FA", func(t *testing.T) {
+		mockRepo, mockMFA := new(MockUserRepo), new(MockMFAVerifier)
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), mockMFA, testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "sso-user"}, nil)
+		mockMFA.On("Enabled", 1).Return(false, nil)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Code: "123456"})
+		assert.ErrorIs(t, err, services.ErrNoLocalPassword)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+	})
+
+	t.Run("Single sign-on account with 2FA code", func(t *testing.T) {
+		mockRepo, workspaces, mockMFA := new(MockUserRepo), new(MockWorkspaceRepo), new(MockMFAVerifier)
+		service := services.NewProfileService(mockRepo, workspaces, mockMFA, testHasher, newAllowingThrottler())
+
+		mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "sso-user"}, nil)
+		mockMFA.On("Enabled", 1).Return(true, nil)
+		mockMFA.On("Verify", 1, "123456").Return(nil)
+		workspaces.On("GetWorkspacesByUserID", 1).Return([]models.Workspace{}, nil)
+		mockRepo.On("DeleteUser", 1).Return(nil)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Code: "123456"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockMFA.AssertExpectations(t)
+	})
+
+	t.Run("Single sign-on account with wrong code", func(t *testing.T) {
+		mockRepo, mockMFA, throttle := new(MockUserRepo), new(MockMFAVerifier), newAllowingThrottler()
+		service := services.NewProfileService(mockRepo, new(MockWorkspaceRepo), mockMFA, testHasher, throttle)
+
+		mockRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "sso-user"}, nil)
+		mockMFA.On("Enabled", 1).Return(true, nil)
+		mockMFA.On("Verify", 1, "000000").Return(services.ErrInvalidMFACode)
+
+		err := service.DeleteAccount(1, models.DeleteAccountRequest{Code: "000000"})
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+		throttle.AssertCalled(t, "Fail", []string{services.MFAKey(1)})
+	})
+}
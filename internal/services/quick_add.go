@@ -0,0 +1,529 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// quickAddWeekdays maps the names of the days, "sat" and "sun" are ordinary
+// words too and only read as days after next, this, on or every
+var quickAddWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var quickAddMonths = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var quickAddNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// quickAddConnectors may precede a due date or time and are left out of the
+// title with it, as in "on friday" or "at 5pm"
+var quickAddConnectors = []string{"on", "at", "by", "due"}
+
+var quickAddPriorities = map[string]string{
+	"!high": models.TaskPriorityHigh, "!1": models.TaskPriorityHigh,
+	"!medium": models.TaskPriorityMedium, "!2": models.TaskPriorityMedium,
+	"!low": models.TaskPriorityLow, "!3": models.TaskPriorityLow,
+}
+
+var rruleDays = map[time.Weekday]string{
+	time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE", time.Thursday: "TH",
+	time.Friday: "FR", time.Saturday: "SA", time.Sunday: "SU",
+}
+
+// maxQuickAddText is the longest text read as a task, in bytes
+const maxQuickAddText = 2000
+
+var (
+	quickAddClock    = regexp.MustCompile(`^([01]?\d|2[0-3]):([0-5]\d)$`)
+	quickAddMeridiem = regexp.MustCompile(`^(1[0-2]|0?[1-9])(?::([0-5]\d))?(am|pm)$`)
+	quickAddOrdinal  = regexp.MustCompile(`^([1-9]|[12]\d|3[01])(st|nd|rd|th)?$`)
+)
+
+type quickAddToken struct {
+	text string
+	// word is the text in lower case without trailing punctuation
+	word       string
+	start, end int
+}
+
+// quickAddParser reads a task from a line of text, see parseQuickAdd
+type quickAddParser struct {
+	tokens []quickAddToken
+	now    time.Time
+	today  time.Time
+
+	date       *time.Time
+	clock      *time.Duration
+	recurrence string
+	firstDays  []time.Weekday
+}
+
+// parseQuickAdd reads a task from a line of text such as "Submit report next
+// Friday 5pm !high #work +reports every 2 weeks" and returns it with the spans
+// it recognized. #tag words add tags and +project words the tag of a project,
+// like the projects of todo.txt. !high, !medium and !low or !1 to !3 set the
+// priority. The first date, time of day and repetition set the due time and
+// the recurrence, dates are read in the location of now and a date without a
+// time is due at midnight, the due time is returned in UTC as it is stored.
+// The other words are the title
+func parseQuickAdd(text string, now time.Time) (models.Task, []models.QuickAddSpan, error) {
+	if len(text) > maxQuickAddText {
+		return models.Task{}, nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("text", fmt.Sprintf("must be at most %d bytes", maxQuickAddText)))
+	}
+
+	runes := []rune(text)
+	p := &quickAddParser{
+		tokens: quickAddTokens(runes),
+		now:    now,
+		today:  time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+	}
+	task := models.Task{Status: "pending", Tags: models.StringList{}}
+	spans := []models.QuickAddSpan{}
+
+	var words []string
+	for i := 0; i < len(p.tokens); {
+		n, kind, value := p.match(i, &task)
+		if n == 0 {
+			words = append(words, p.tokens[i].text)
+			i++
+			continue
+		}
+
+		first, last := p.tokens[i], p.tokens[i+n-1]
+		spans = append(spans, models.QuickAddSpan{
+			Kind:  kind,
+			Text:  string(runes[first.start:last.end]),
+			Value: value,
+			Start: first.start,
+			End:   last.end,
+		})
+		i += n
+	}
+
+	task.Title = strings.TrimRightFunc(strings.Join(words, " "), func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(",;:-", r)
+	})
+	if len(task.Title) > maxImportedTitle {
+		task.Title = truncateBytes(task.Title, maxImportedTitle-len("…")) + "…"
+	}
+	task.Recurrence = p.recurrence
+
+	if due, ok := p.due(); ok {
+		dueAt := models.JSONTime(due.UTC())
+		task.DueAt = &dueAt
+	}
+
+	return task, spans, nil
+}
+
+// quickAddTokens splits text at white space, offsets count characters
+func quickAddTokens(text []rune) []quickAddToken {
+	var tokens []quickAddToken
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i == len(text) || unicode.IsSpace(text[i]) {
+			if start >= 0 {
+				token := string(text[start:i])
+				word := strings.ToLower(strings.TrimRight(token, ",.;!?"))
+				tokens = append(tokens, quickAddToken{text: token, word: word, start: start, end: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return tokens
+}
+
+// word is the word of token i, empty past the end
+func (p *quickAddParser) word(i int) string {
+	if i < len(p.tokens) {
+		return p.tokens[i].word
+	}
+	return ""
+}
+
+// match reads the tokens from i and returns how many it used, the kind of the
+// span and its value
+func (p *quickAddParser) match(i int, task *models.Task) (int, string, string) {
+	token := p.tokens[i]
+
+	if name, ok := strings.CutPrefix(token.text, "#"); ok && quickAddTagName(name) {
+		if tag := importedTag(name); !slices.Contains(task.Tags, tag) && len(task.Tags) < maxImportedTags {
+			task.Tags = append(task.Tags, tag)
+		}
+		return 1, models.QuickAddTag, importedTag(name)
+	}
+	if name, ok := strings.CutPrefix(token.text, "+"); ok && quickAddTagName(name) {
+		if tag := importedTag(name); !slices.Contains(task.Tags, tag) && len(task.Tags) < maxImportedTags {
+			task.Tags = append(task.Tags, tag)
+		}
+		return 1, models.QuickAddProject, importedTag(name)
+	}
+	if priority, ok := quickAddPriorities[token.word]; ok && task.Priority == "" {
+		task.Priority = priority
+		return 1, models.QuickAddPriority, priority
+	}
+
+	if p.recurrence == "" {
+		if n, rule, days := p.matchRecurrence(i); n > 0 {
+			p.recurrence, p.firstDays = rule, days
+			return n, models.QuickAddRecurrence, rule
+		}
+	}
+
+	// a connector is only used together with the date or time after it
+	skip := 0
+	if slices.Contains(quickAddConnectors, token.word) {
+		skip = 1
+	}
+
+	if p.date == nil {
+		if n, date, clock := p.matchDate(i + skip); n > 0 && (clock == nil || p.clock == nil) {
+			p.date, p.clock = &date, clock
+			if clock != nil {
+				return skip + n, models.QuickAddDue, p.at(date).Format("2006-01-02T15:04")
+			}
+			return skip + n, models.QuickAddDue, date.Format(time.DateOnly)
+		}
+	}
+	if p.clock == nil {
+		if n, clock := p.matchClock(i + skip); n > 0 {
+			p.clock = &clock
+			return skip + n, models.QuickAddDue, fmt.Sprintf("%02d:%02d", int(clock.Hours()), int(clock.Minutes())%60)
+		}
+	}
+
+	return 0, "", ""
+}
+
+// quickAddTagName tells tags from numbers such as the #42 of an issue
+func quickAddTagName(name string) bool {
+	return strings.IndexFunc(name, unicode.IsLetter) >= 0
+}
+
+// matchDate reads a day from token i. Relative times such as "in 2 hours"
+// return the time of day as well
+func (p *quickAddParser) matchDate(i int) (int, time.Time, *time.Duration) {
+	w := p.word(i)
+
+	switch w {
+	case "today":
+		return 1, p.today, nil
+	case "tomorrow", "tmrw":
+		return 1, p.today.AddDate(0, 0, 1), nil
+	case "day":
+		if p.word(i+1) == "after" && p.word(i+2) == "tomorrow" {
+			return 3, p.today.AddDate(0, 0, 2), nil
+		}
+	case "weekend":
+		return 1, p.nextWeekday(time.Saturday), nil
+	case "this":
+		if weekday, ok := quickAddWeekdays[p.word(i+1)]; ok {
+			return 2, p.nextWeekday(weekday), nil
+		}
+		if p.word(i+1) == "weekend" {
+			return 2, p.nextWeekday(time.Saturday), nil
+		}
+	case "next":
+		return p.matchNext(i + 1)
+	case "in":
+		return p.matchIn(i + 1)
+	}
+
+	if weekday, ok := quickAddWeekdays[w]; ok && (w != "sat" && w != "sun" || i > 0 && p.word(i-1) == "on") {
+		return 1, p.nextWeekday(weekday), nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, w, p.now.Location()); err == nil {
+		return 1, date, nil
+	}
+
+	// Oct 30, October 30th 2027, 30 Oct or 30th of October
+	if month, ok := quickAddMonths[w]; ok {
+		if day, ok := quickAddDay(p.word(i + 1)); ok {
+			n, date := p.matchYear(i+2, month, day)
+			return 2 + n, date, nil
+		}
+	}
+	if day, ok := quickAddDay(w); ok {
+		of := 0
+		if p.word(i+1) == "of" {
+			of = 1
+		}
+		if month, ok := quickAddMonths[p.word(i+1+of)]; ok {
+			n, date := p.matchYear(i+2+of, month, day)
+			return 2 + of + n, date, nil
+		}
+	}
+
+	return 0, time.Time{}, nil
+}
+
+// matchNext reads what follows "next": a weekday of the following week
+// starting on Monday, or the start of the next week, month or year
+func (p *quickAddParser) matchNext(i int) (int, time.Time, *time.Duration) {
+	w := p.word(i)
+	monday := p.today.AddDate(0, 0, -(int(p.today.Weekday())+6)%7+7)
+
+	if weekday, ok := quickAddWeekdays[w]; ok {
+		return 2, monday.AddDate(0, 0, (int(weekday)+6)%7), nil
+	}
+
+	switch w {
+	case "week":
+		return 2, monday, nil
+	case "weekend":
+		return 2, monday.AddDate(0, 0, 5), nil
+	case "month":
+		return 2, time.Date(p.today.Year(), p.today.Month()+1, 1, 0, 0, 0, 0, p.today.Location()), nil
+	case "year":
+		return 2, time.Date(p.today.Year()+1, time.January, 1, 0, 0, 0, 0, p.today.Location()), nil
+	}
+	return 0, time.Time{}, nil
+}
+
+// matchIn reads what follows "in": a number and a unit. Minutes and hours
+// count from now, longer units from today
+func (p *quickAddParser) matchIn(i int) (int, time.Time, *time.Duration) {
+	count, ok := quickAddCount(p.word(i))
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+
+	unit := strings.TrimSuffix(p.word(i+1), "s")
+	switch unit {
+	case "minute", "min", "hour", "hr":
+		step := time.Minute
+		if unit == "hour" || unit == "hr" {
+			step = time.Hour
+		}
+		due := p.now.Add(time.Duration(count) * step).Truncate(time.Minute)
+		day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, due.Location())
+		clock := time.Duration(due.Hour())*time.Hour + time.Duration(due.Minute())*time.Minute
+		return 3, day, &clock
+	case "day":
+		return 3, p.today.AddDate(0, 0, count), nil
+	case "week":
+		return 3, p.today.AddDate(0, 0, 7*count), nil
+	case "month":
+		return 3, p.today.AddDate(0, count, 0), nil
+	case "year":
+		return 3, p.today.AddDate(count, 0, 0), nil
+	}
+	return 0, time.Time{}, nil
+}
+
+// matchYear reads an optional year after a day and month, without it the
+// date is the next one from today
+func (p *quickAddParser) matchYear(i int, month time.Month, day int) (int, time.Time) {
+	if year, err := strconv.Atoi(p.word(i)); err == nil && year >= 2000 && year <= 2100 {
+		return 1, time.Date(year, month, day, 0, 0, 0, 0, p.today.Location())
+	}
+
+	date := time.Date(p.today.Year(), month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return 0, date
+}
+
+// matchClock reads a time of day: 17:30, 5pm, 5:30 pm or noon
+func (p *quickAddParser) matchClock(i int) (int, time.Duration) {
+	w := p.word(i)
+
+	if w == "noon" {
+		return 1, 12 * time.Hour
+	}
+
+	// the meridiem may follow as a word of its own, "6:30 pm"
+	if next := p.word(i + 1); next == "am" || next == "pm" {
+		if m := quickAddMeridiem.FindStringSubmatch(w + next); m != nil {
+			return 2, quickAddMeridiemClock(m)
+		}
+	}
+	if m := quickAddMeridiem.FindStringSubmatch(w); m != nil {
+		return 1, quickAddMeridiemClock(m)
+	}
+	if m := quickAddClock.FindStringSubmatch(w); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		return 1, time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	}
+	return 0, 0
+}
+
+func quickAddMeridiemClock(m []string) time.Duration {
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	hours %= 12
+	if m[3] == "pm" {
+		hours += 12
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
+
+// matchRecurrence reads a repetition as an RRULE value: daily, every 2 weeks,
+// every other month, every weekday or every monday and thursday. The weekdays
+// of a rule are returned to find the first due date
+func (p *quickAddParser) matchRecurrence(i int) (int, string, []time.Weekday) {
+	switch p.word(i) {
+	case "daily":
+		return 1, "FREQ=DAILY", nil
+	case "weekly":
+		return 1, "FREQ=WEEKLY", nil
+	case "monthly":
+		return 1, "FREQ=MONTHLY", nil
+	case "yearly", "annually":
+		return 1, "FREQ=YEARLY", nil
+	case "every":
+	default:
+		return 0, "", nil
+	}
+
+	n, interval := 1, 1
+	if w := p.word(i + 1); w == "other" {
+		n, interval = 2, 2
+	} else if count, ok := quickAddCount(w); ok && w != "a" && w != "an" {
+		n, interval = 2, count
+	}
+
+	freq := map[string]string{"day": "DAILY", "week": "WEEKLY", "month": "MONTHLY", "year": "YEARLY"}[strings.TrimSuffix(p.word(i+n), "s")]
+	if freq != "" {
+		rule := "FREQ=" + freq
+		if interval > 1 {
+			rule += ";INTERVAL=" + strconv.Itoa(interval)
+		}
+		return n + 1, rule, nil
+	}
+	if n > 1 && interval != 2 {
+		return 0, "", nil
+	}
+
+	var days []time.Weekday
+	used := 0
+	switch p.word(i + n) {
+	case "weekday":
+		days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		used = n + 1
+	case "weekend":
+		days = []time.Weekday{time.Saturday, time.Sunday}
+		used = n + 1
+	default:
+		// a list of days, the last may follow an "and"
+		for j := i + n; ; j++ {
+			weekday, ok := quickAddWeekdays[p.word(j)]
+			if !ok {
+				break
+			}
+			days = append(days, weekday)
+			used = j - i + 1
+			if p.word(j+1) == "and" {
+				j++
+			}
+		}
+	}
+	if len(days) == 0 {
+		return 0, "", nil
+	}
+
+	byDay := make([]string, len(days))
+	for k, day := range days {
+		byDay[k] = rruleDays[day]
+	}
+	rule := "FREQ=WEEKLY"
+	if interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(interval)
+	}
+	return used, rule + ";BYDAY=" + strings.Join(byDay, ","), days
+}
+
+// due combines the date and the time of day that were read. Without a date
+// the task is due on the first day from today that is one of the days of the
+// repetition and, with a time, when the time is still ahead
+func (p *quickAddParser) due() (time.Time, bool) {
+	if p.date != nil {
+		return p.at(*p.date), true
+	}
+	if p.clock == nil && len(p.firstDays) == 0 {
+		return time.Time{}, false
+	}
+
+	for days := 0; ; days++ {
+		day := p.today.AddDate(0, 0, days)
+		if len(p.firstDays) > 0 && !slices.Contains(p.firstDays, day.Weekday()) {
+			continue
+		}
+		if p.clock != nil && !p.at(day).After(p.now) {
+			continue
+		}
+		return p.at(day), true
+	}
+}
+
+// at is the time of day that was read on the day, midnight without one. It
+// is the wall clock time, also on days when the clocks change
+func (p *quickAddParser) at(day time.Time) time.Time {
+	var minutes int
+	if p.clock != nil {
+		minutes = int(p.clock.Minutes())
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// nextWeekday is the next day of the weekday, today when it is today
+func (p *quickAddParser) nextWeekday(weekday time.Weekday) time.Time {
+	return p.today.AddDate(0, 0, (int(weekday)-int(p.today.Weekday())+7)%7)
+}
+
+// quickAddDay reads the day of a month such as 30 or 30th
+func quickAddDay(word string) (int, bool) {
+	m := quickAddOrdinal.FindStringSubmatch(word)
+	if m == nil {
+		return 0, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	return day, true
+}
+
+// quickAddCount reads a small count written in digits or words
+func quickAddCount(word string) (int, bool) {
+	if count, ok := quickAddNumbers[word]; ok {
+		return count, true
+	}
+	count, err := strconv.Atoi(word)
+	if err != nil || count < 1 || count > 999 {
+		return 0, false
+	}
+	return count, true
+}
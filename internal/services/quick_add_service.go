@@ -0,0 +1,48 @@
+package services
+
+import (
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+)
+
+type IQuickAddUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+// QuickAddService creates a task from a single line of text, see parseQuickAdd.
+// Dates are read in the time zone of the user's profile
+type QuickAddService struct {
+	users IQuickAddUserRepository
+	tasks ITaskCreator
+	clock clock.Clock
+}
+
+func NewQuickAddService(users IQuickAddUserRepository, tasks ITaskCreator, clock clock.Clock) *QuickAddService {
+	return &QuickAddService{users: users, tasks: tasks, clock: clock}
+}
+
+// QuickAdd parses the text and creates the task unless it is a dry run
+func (s *QuickAddService) QuickAdd(userID int, req models.QuickAddRequest) (*models.QuickAddResult, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, spans, err := parseQuickAdd(req.Text, s.clock.Now().In(userLocation(user)))
+	if err != nil {
+		return nil, err
+	}
+	task.UserID = userID
+	task.WorkspaceID = req.WorkspaceID
+
+	if req.DryRun {
+		if err := helpers.ValidateTaskFields(&task); err != nil {
+			return nil, err
+		}
+	} else if err := s.tasks.CreateTask(&task); err != nil {
+		return nil, err
+	}
+
+	return &models.QuickAddResult{Task: task, Spans: spans}, nil
+}
@@ -0,0 +1,246 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickAddParse(t *testing.T) {
+	// a Monday, 10:00 in Berlin. Summer time ends on Sunday the 25th
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		text       string
+		title      string
+		due        string // wall clock time in Berlin
+		priority   string
+		tags       models.StringList
+		recurrence string
+		spans      []models.QuickAddSpan
+	}{
+		{
+			text:       "Submit report next Friday 5pm !high #work every 2 weeks",
+			title:      "Submit report",
+			due:        "2026-10-30 17:00",
+			priority:   models.TaskPriorityHigh,
+			tags:       models.StringList{"work"},
+			recurrence: "FREQ=WEEKLY;INTERVAL=2",
+			spans: []models.QuickAddSpan{
+				{Kind: models.QuickAddDue, Text: "next Friday", Value: "2026-10-30", Start: 14, End: 25},
+				{Kind: models.QuickAddDue, Text: "5pm", Value: "17:00", Start: 26, End: 29},
+				{Kind: models.QuickAddPriority, Text: "!high", Value: "high", Start: 30, End: 35},
+				{Kind: models.QuickAddTag, Text: "#work", Value: "work", Start: 36, End: 41},
+				{Kind: models.QuickAddRecurrence, Text: "every 2 weeks", Value: "FREQ=WEEKLY;INTERVAL=2", Start: 42, End: 55},
+			},
+		},
+		{
+			text:  "Call mom tomorrow at 6:30 pm",
+			title: "Call mom",
+			due:   "2026-10-20 18:30",
+			spans: []models.QuickAddSpan{
+				{Kind: models.QuickAddDue, Text: "tomorrow", Value: "2026-10-20", Start: 9, End: 17},
+				{Kind: models.QuickAddDue, Text: "at 6:30 pm", Value: "18:30", Start: 18, End: 28},
+			},
+		},
+		{
+			text:  "Позвонить Ивану friday #работа",
+			title: "Позвонить Ивану",
+			due:   "2026-10-23 00:00",
+			tags:  models.StringList{"работа"},
+			spans: []models.QuickAddSpan{
+				{Kind: models.QuickAddDue, Text: "friday", Value: "2026-10-23", Start: 16, End: 22},
+				{Kind: models.QuickAddTag, Text: "#работа", Value: "работа", Start: 23, End: 30},
+			},
+		},
+		{
+			text:       "Pay rent Nov 1st +home monthly",
+			title:      "Pay rent",
+			due:        "2026-11-01 00:00",
+			tags:       models.StringList{"home"},
+			recurrence: "FREQ=MONTHLY",
+			spans: []models.QuickAddSpan{
+				{Kind: models.QuickAddDue, Text: "Nov 1st", Value: "2026-11-01", Start: 9, End: 16},
+				{Kind: models.QuickAddProject, Text: "+home", Value: "home", Start: 17, End: 22},
+				{Kind: models.QuickAddRecurrence, Text: "monthly", Value: "FREQ=MONTHLY", Start: 23, End: 30},
+			},
+		},
+		{
+			text:       "Standup every weekday at 9:15",
+			title:      "Standup",
+			due:        "2026-10-20 09:15",
+			recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		{
+			text:       "Gym every monday and thursday 7am",
+			title:      "Gym",
+			due:        "2026-10-22 07:00",
+			recurrence: "FREQ=WEEKLY;BYDAY=MO,TH",
+		},
+		{
+			text:       "Water plants every other sat",
+			title:      "Water plants",
+			due:        "2026-10-24 00:00",
+			recurrence: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA",
+		},
+		{
+			text:       "Clean up daily",
+			title:      "Clean up",
+			recurrence: "FREQ=DAILY",
+		},
+		{
+			text:  "Dentist in 3 days",
+			title: "Dentist",
+			due:   "2026-10-22 00:00",
+		},
+		{
+			text:  "Call back in 2 hours",
+			title: "Call back",
+			due:   "2026-10-19 12:00",
+			spans: []models.QuickAddSpan{
+				{Kind: models.QuickAddDue, Text: "in 2 hours", Value: "2026-10-19T12:00", Start: 10, End: 20},
+			},
+		},
+		{
+			text:  "Lunch with Sam at noon",
+			title: "Lunch with Sam",
+			due:   "2026-10-19 12:00",
+		},
+		{
+			text:  "Stretch at 9am",
+			title: "Stretch",
+			due:   "2026-10-20 09:00",
+		},
+		{
+			text:     "Fix bug #42 by 2026-11-03 !1",
+			title:    "Fix bug #42",
+			due:      "2026-11-03 00:00",
+			priority: models.TaskPriorityHigh,
+		},
+		{
+			text:  "Buy sun cream on sat",
+			title: "Buy sun cream",
+			due:   "2026-10-24 00:00",
+		},
+		{
+			text:     "Book flights 30 October 2027, #travel !low",
+			title:    "Book flights",
+			due:      "2027-10-30 00:00",
+			priority: models.TaskPriorityLow,
+			tags:     models.StringList{"travel"},
+		},
+		{
+			text:  "Send wishes March 3",
+			title: "Send wishes",
+			due:   "2027-03-03 00:00",
+		},
+		{
+			text:  "Plan offsite next week",
+			title: "Plan offsite",
+			due:   "2026-10-26 00:00",
+		},
+		{
+			text:  "Renew passport next month",
+			title: "Renew passport",
+			due:   "2026-11-01 00:00",
+		},
+		{
+			text:  "Ask if Sam may come to the next meeting",
+			title: "Ask if Sam may come to the next meeting",
+			spans: []models.QuickAddSpan{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			users := new(MockUserRepo)
+			tasks := new(MockTaskCreator)
+			service := services.NewQuickAddService(users, tasks, clock)
+			users.On("GetUserByID", 1).Return(&models.User{ID: 1, Timezone: "Europe/Berlin"}, nil)
+
+			result, err := service.QuickAdd(1, models.QuickAddRequest{Text: tt.text, DryRun: true})
+			require.NoError(t, err)
+
+			task := result.Task
+			assert.Equal(t, 1, task.UserID)
+			assert.Equal(t, "pending", task.Status)
+			assert.Equal(t, tt.title, task.Title)
+			assert.Equal(t, tt.priority, task.Priority)
+			assert.Equal(t, tt.recurrence, task.Recurrence)
+			if tt.tags == nil {
+				tt.tags = models.StringList{}
+			}
+			assert.Equal(t, tt.tags, task.Tags)
+
+			if tt.due == "" {
+				assert.Nil(t, task.DueAt)
+			} else if assert.NotNil(t, task.DueAt) {
+				assert.Equal(t, tt.due, time.Time(*task.DueAt).In(berlin).Format("2006-01-02 15:04"))
+			}
+			if tt.spans != nil {
+				assert.Equal(t, tt.spans, result.Spans)
+			}
+			tasks.AssertNotCalled(t, "CreateTask", mock.Anything)
+		})
+	}
+}
+
+func TestQuickAdd(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+
+	t.Run("Created in the user's time zone", func(t *testing.T) {
+		users, tasks := new(MockUserRepo), new(MockTaskCreator)
+		service := services.NewQuickAddService(users, tasks, clock)
+		workspaceID := 3
+
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1, Timezone: "America/New_York"}, nil)
+		tasks.On("CreateTask", mock.MatchedBy(func(task *models.Task) bool {
+			// 04:00 in New York is still Monday
+			return task.Title == "Pay invoice" && *task.WorkspaceID == 3 &&
+				time.Time(*task.DueAt).Equal(time.Date(2026, 10, 19, 21, 0, 0, 0, time.UTC)) &&
+				time.Time(*task.DueAt).Location() == time.UTC
+		})).Return(nil)
+
+		result, err := service.QuickAdd(1, models.QuickAddRequest{Text: "Pay invoice today 5pm", WorkspaceID: &workspaceID})
+		require.NoError(t, err)
+		assert.Len(t, result.Spans, 2)
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Without a title", func(t *testing.T) {
+		users := new(MockUserRepo)
+		service := services.NewQuickAddService(users, new(MockTaskCreator), clock)
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+
+		_, err := service.QuickAdd(1, models.QuickAddRequest{Text: "tomorrow #home", DryRun: true})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		users := new(MockUserRepo)
+		service := services.NewQuickAddService(users, new(MockTaskCreator), clock)
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+
+		_, err := service.QuickAdd(1, models.QuickAddRequest{Text: strings.Repeat("a ", 1001)})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Not created", func(t *testing.T) {
+		users, tasks := new(MockUserRepo), new(MockTaskCreator)
+		service := services.NewQuickAddService(users, tasks, clock)
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+		tasks.On("CreateTask", mock.Anything).Return(services.ErrWorkspaceForbidden)
+
+		_, err := service.QuickAdd(1, models.QuickAddRequest{Text: "Pay invoice"})
+		assert.True(t, errors.Is(err, services.ErrWorkspaceForbidden))
+	})
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+type IReminderRepository interface {
+	CreateReminder(reminder *models.Reminder) error
+	GetReminder(id, userID int) (*models.Reminder, error)
+	GetReminders(userID int, taskID *int) ([]models.Reminder, error)
+	SnoozeReminder(id, userID int, until time.Time) error
+	DismissReminder(id, userID int, now time.Time) error
+	DeleteReminder(id, userID int) error
+	ClaimDueReminders(now time.Time, limit int) ([]models.Reminder, error)
+}
+
+type IReminderTaskRepository interface {
+	GetVisibleTask(taskID, userID int) (*models.Task, error)
+}
+
+// ReminderChannel delivers a fired reminder to its user, see NotificationService,
+// WebhookService and EmailReminderChannel
+type ReminderChannel interface {
+	SendReminder(reminder *models.Reminder) error
+}
+
+// ReminderChannels maps the channel names of models.ReminderChannels to their
+// implementation, reminders can only use the configured channels
+type ReminderChannels map[string]ReminderChannel
+
+// ReminderService manages the reminders users set on tasks they can see and
+// fires them from a background worker
+type ReminderService struct {
+	repo     IReminderRepository
+	tasks    IReminderTaskRepository
+	channels ReminderChannels
+	cfg      config.Reminders
+	clock    clock.Clock
+	log      zerolog.Logger
+}
+
+func NewReminderService(repo IReminderRepository, tasks IReminderTaskRepository, channels ReminderChannels, cfg config.Reminders, clock clock.Clock) *ReminderService {
+	return &ReminderService{
+		repo:     repo,
+		tasks:    tasks,
+		channels: channels,
+		cfg:      cfg,
+		clock:    clock,
+		log:      logger.GetLogger(),
+	}
+}
+
+// CreateReminder stores a reminder of the user on a task it can see. Without
+// channels the reminder goes to the in-app inbox, a reminder relative to the due
+// date needs a task with a due date
+func (s *ReminderService) CreateReminder(reminder *models.Reminder) error {
+	if len(reminder.Channels) == 0 {
+		reminder.Channels = models.StringList{models.ReminderChannelInApp}
+	}
+
+	if err := helpers.ValidateReminder(reminder); err != nil {
+		return err
+	}
+	for _, channel := range reminder.Channels {
+		if _, ok := s.channels[channel]; !ok {
+			return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("channels", fmt.Sprintf("channel %q is not available", channel)))
+		}
+	}
+
+	task, err := s.tasks.GetVisibleTask(reminder.TaskID, reminder.UserID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return repository.ErrNoRowsUpdated
+	}
+	if reminder.BeforeDueMinutes != nil && task.DueAt == nil {
+		return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("before_due_minutes", "task has no due date"))
+	}
+
+	reminder.CreatedAt = s.clock.Now()
+	if err := s.repo.CreateReminder(reminder); err != nil {
+		return err
+	}
+
+	return s.reload(reminder)
+}
+
+// GetReminders lists the user's reminders that are not dismissed, of one task
+// when taskID is given
+func (s *ReminderService) GetReminders(userID int, taskID *int) ([]models.Reminder, error) {
+	if taskID != nil {
+		task, err := s.tasks.GetVisibleTask(*taskID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if task == nil {
+			return nil, repository.ErrNoRowsUpdated
+		}
+	}
+
+	reminders, err := s.repo.GetReminders(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reminders == nil {
+		reminders = []models.Reminder{}
+	}
+	return reminders, nil
+}
+
+// SnoozeReminder postpones a reminder by the given minutes from now, a
+// reminder that already fired fires again
+func (s *ReminderService) SnoozeReminder(id, userID, minutes int) (*models.Reminder, error) {
+	maxMinutes := int(s.cfg.MaxSnooze.Minutes())
+	if minutes < 1 || minutes > maxMinutes {
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("minutes", fmt.Sprintf("must be between 1 and %d", maxMinutes)))
+	}
+
+	until := s.clock.Now().Add(time.Duration(minutes) * time.Minute)
+	if err := s.repo.SnoozeReminder(id, userID, until); err != nil {
+		if err == repository.ErrNoRowsUpdated {
+			return nil, ErrReminderNotFound
+		}
+		return nil, err
+	}
+
+	return s.getReminder(id, userID)
+}
+
+func (s *ReminderService) DismissReminder(id, userID int) error {
+	err := s.repo.DismissReminder(id, userID, s.clock.Now())
+	if err == repository.ErrNoRowsUpdated {
+		return ErrReminderNotFound
+	}
+	return err
+}
+
+func (s *ReminderService) DeleteReminder(id, userID int) error {
+	err := s.repo.DeleteReminder(id, userID)
+	if err == repository.ErrNoRowsUpdated {
+		return ErrReminderNotFound
+	}
+	return err
+}
+
+// Run fires due reminders until ctx is done
+func (s *ReminderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.FireDue(ctx)
+		}
+	}
+}
+
+// FireDue claims the reminders due now and sends them through their channels.
+// A reminder is claimed once, a channel that fails is logged and not retried
+func (s *ReminderService) FireDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		reminders, err := s.repo.ClaimDueReminders(s.clock.Now(), s.cfg.BatchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to claim due reminders")
+			return
+		}
+
+		for i := range reminders {
+			s.fire(&reminders[i])
+		}
+
+		if len(reminders) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *ReminderService) fire(reminder *models.Reminder) {
+	for _, name := range reminder.Channels {
+		channel, ok := s.channels[name]
+		if !ok {
+			s.log.Warn().Int("reminder_id", reminder.ID).Str("channel", name).Msg("Reminder channel not configured")
+			continue
+		}
+
+		if err := channel.SendReminder(reminder); err != nil {
+			s.log.Error().Err(err).Int("reminder_id", reminder.ID).Str("channel", name).Msg("Failed to send reminder")
+		}
+	}
+}
+
+func (s *ReminderService) reload(reminder *models.Reminder) error {
+	stored, err := s.getReminder(reminder.ID, reminder.UserID)
+	if err != nil {
+		return err
+	}
+
+	*reminder = *stored
+	return nil
+}
+
+func (s *ReminderService) getReminder(id, userID int) (*models.Reminder, error) {
+	reminder, err := s.repo.GetReminder(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reminder == nil {
+		return nil, ErrReminderNotFound
+	}
+	return reminder, nil
+}
+
+// ReminderMessage is the text of a fired reminder shared by the channels
+func ReminderMessage(reminder *models.Reminder) string {
+	if reminder.TaskDueAt == nil {
+		return fmt.Sprintf("Reminder: %s", reminder.TaskTitle)
+	}
+	return fmt.Sprintf("Reminder: %s, due %s", reminder.TaskTitle, reminder.TaskDueAt.Format(time.RFC1123))
+}
+
+type IReminderUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+// EmailReminderChannel mails reminders to users with a verified email address,
+// users without one are skipped
+type EmailReminderChannel struct {
+	users  IReminderUserRepository
+	mailer mailer.Mailer
+}
+
+func NewEmailReminderChannel(users IReminderUserRepository, mailer mailer.Mailer) *EmailReminderChannel {
+	return &EmailReminderChannel{users: users, mailer: mailer}
+}
+
+func (c *EmailReminderChannel) SendReminder(reminder *models.Reminder) error {
+	user, err := c.users.GetUserByID(reminder.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.EmailVerified() {
+		return nil
+	}
+
+	return c.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: ReminderMessage(reminder),
+		Text:    fmt.Sprintf("Hi %s,\n\n%s\n", user.Username, ReminderMessage(reminder)),
+	})
+}
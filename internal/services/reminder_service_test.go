@@ -0,0 +1,251 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/mailer"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReminderRepo struct {
+	mock.Mock
+}
+
+func (m *MockReminderRepo) CreateReminder(reminder *models.Reminder) error {
+	args := m.Called(reminder)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepo) GetReminder(id, userID int) (*models.Reminder, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).(*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepo) GetReminders(userID int, taskID *int) ([]models.Reminder, error) {
+	args := m.Called(userID, taskID)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepo) GetRemindersByUserID(userID int) ([]models.Reminder, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepo) SnoozeReminder(id, userID int, until time.Time) error {
+	args := m.Called(id, userID, until)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepo) DismissReminder(id, userID int, now time.Time) error {
+	args := m.Called(id, userID, now)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepo) DeleteReminder(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepo) ClaimDueReminders(now time.Time, limit int) ([]models.Reminder, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+type MockReminderChannel struct {
+	mock.Mock
+}
+
+func (m *MockReminderChannel) SendReminder(reminder *models.Reminder) error {
+	args := m.Called(reminder)
+	return args.Error(0)
+}
+
+var reminderConfig = config.Reminders{
+	PollInterval: time.Second,
+	BatchSize:    10,
+	MaxSnooze:    24 * time.Hour,
+}
+
+func newReminderService(now time.Time) (*services.ReminderService, *MockReminderRepo, *MockTaskRepo, *MockReminderChannel, *MockReminderChannel) {
+	repo := new(MockReminderRepo)
+	tasks := new(MockTaskRepo)
+	inApp := new(MockReminderChannel)
+	email := new(MockReminderChannel)
+	service := services.NewReminderService(repo, tasks, services.ReminderChannels{
+		models.ReminderChannelInApp: inApp,
+		models.ReminderChannelEmail: email,
+	}, reminderConfig, &FakeClock{now: now})
+	return service, repo, tasks, inApp, email
+}
+
+func TestCreateReminder(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	due := models.JSONTime(now.Add(24 * time.Hour))
+	beforeDue := 60
+
+	t.Run("Defaults to the inbox", func(t *testing.T) {
+		t.Parallel()
+		service, repo, tasks, _, _ := newReminderService(now)
+
+		remindAt := now.Add(time.Hour)
+		tasks.On("GetVisibleTask", 42, 1).Return(&models.Task{ID: 42}, nil)
+		repo.On("CreateReminder", mock.MatchedBy(func(r *models.Reminder) bool {
+			return len(r.Channels) == 1 && r.Channels[0] == models.ReminderChannelInApp && r.CreatedAt.Equal(now)
+		})).Run(func(args mock.Arguments) { args.Get(0).(*models.Reminder).ID = 7 }).Return(nil)
+		repo.On("GetReminder", 7, 1).Return(&models.Reminder{ID: 7, FireAt: &remindAt, Status: models.ReminderPending}, nil)
+
+		reminder := &models.Reminder{TaskID: 42, UserID: 1, RemindAt: &remindAt}
+		require.NoError(t, service.CreateReminder(reminder))
+		assert.Equal(t, remindAt, *reminder.FireAt)
+	})
+
+	t.Run("Before due of a task with a due date", func(t *testing.T) {
+		t.Parallel()
+		service, repo, tasks, _, _ := newReminderService(now)
+
+		tasks.On("GetVisibleTask", 42, 1).Return(&models.Task{ID: 42, DueAt: &due}, nil)
+		repo.On("CreateReminder", mock.Anything).Return(nil)
+		repo.On("GetReminder", 0, 1).Return(&models.Reminder{}, nil)
+
+		require.NoError(t, service.CreateReminder(&models.Reminder{TaskID: 42, UserID: 1, BeforeDueMinutes: &beforeDue}))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Before due of a task without a due date", func(t *testing.T) {
+		t.Parallel()
+		service, repo, tasks, _, _ := newReminderService(now)
+
+		tasks.On("GetVisibleTask", 42, 1).Return(&models.Task{ID: 42}, nil)
+
+		err := service.CreateReminder(&models.Reminder{TaskID: 42, UserID: 1, BeforeDueMinutes: &beforeDue})
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "CreateReminder", mock.Anything)
+	})
+
+	t.Run("Channel not configured", func(t *testing.T) {
+		t.Parallel()
+		service, _, tasks, _, _ := newReminderService(now)
+
+		err := service.CreateReminder(&models.Reminder{TaskID: 42, UserID: 1, BeforeDueMinutes: &beforeDue, Channels: models.StringList{models.ReminderChannelWebhook}})
+		assert.ErrorAs(t, err, &baseErr)
+		tasks.AssertNotCalled(t, "GetVisibleTask", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Both times", func(t *testing.T) {
+		t.Parallel()
+		service, _, _, _, _ := newReminderService(now)
+
+		err := service.CreateReminder(&models.Reminder{TaskID: 42, UserID: 1, RemindAt: &now, BeforeDueMinutes: &beforeDue})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+
+	t.Run("Invisible task", func(t *testing.T) {
+		t.Parallel()
+		service, _, tasks, _, _ := newReminderService(now)
+
+		tasks.On("GetVisibleTask", 42, 1).Return((*models.Task)(nil), nil)
+
+		err := service.CreateReminder(&models.Reminder{TaskID: 42, UserID: 1, RemindAt: &now})
+		assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+	})
+}
+
+func TestSnoozeReminder(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _, _, _ := newReminderService(now)
+
+		repo.On("SnoozeReminder", 7, 1, now.Add(15*time.Minute)).Return(nil)
+		repo.On("GetReminder", 7, 1).Return(&models.Reminder{ID: 7, Status: models.ReminderPending}, nil)
+
+		reminder, err := service.SnoozeReminder(7, 1, 15)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReminderPending, reminder.Status)
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _, _, _ := newReminderService(now)
+
+		_, err := service.SnoozeReminder(7, 1, 25*60)
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "SnoozeReminder", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Dismissed or foreign", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _, _, _ := newReminderService(now)
+
+		repo.On("SnoozeReminder", 7, 1, mock.Anything).Return(repository.ErrNoRowsUpdated)
+
+		_, err := service.SnoozeReminder(7, 1, 15)
+		assert.ErrorIs(t, err, services.ErrReminderNotFound)
+	})
+}
+
+func TestFireDueReminders(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	service, repo, _, inApp, email := newReminderService(now)
+
+	reminders := []models.Reminder{
+		{ID: 7, UserID: 1, TaskID: 42, Channels: models.StringList{models.ReminderChannelInApp, models.ReminderChannelEmail}},
+		{ID: 8, UserID: 2, TaskID: 43, Channels: models.StringList{models.ReminderChannelEmail, models.ReminderChannelWebhook}},
+	}
+	repo.On("ClaimDueReminders", now, 10).Return(reminders, nil).Once()
+	inApp.On("SendReminder", mock.MatchedBy(func(r *models.Reminder) bool { return r.ID == 7 })).Return(nil).Once()
+	email.On("SendReminder", mock.MatchedBy(func(r *models.Reminder) bool { return r.ID == 7 })).Return(errors.New("smtp down")).Once()
+	email.On("SendReminder", mock.MatchedBy(func(r *models.Reminder) bool { return r.ID == 8 })).Return(nil).Once()
+
+	service.FireDue(context.Background())
+	inApp.AssertExpectations(t)
+	email.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "ClaimDueReminders", 1)
+}
+
+func TestEmailReminderChannel(t *testing.T) {
+	t.Parallel()
+	verified := time.Now()
+	due := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	reminder := &models.Reminder{UserID: 1, TaskTitle: "Release", TaskDueAt: &due}
+
+	t.Run("Verified email", func(t *testing.T) {
+		t.Parallel()
+		users := new(MockUserRepo)
+		mail := new(MockMailer)
+		channel := services.NewEmailReminderChannel(users, mail)
+
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1, Username: "alice", Email: "alice@example.com", EmailVerifiedAt: &verified}, nil)
+		mail.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+			return msg.To == "alice@example.com" && msg.Subject == "Reminder: Release, due Tue, 20 Oct 2026 09:00:00 UTC"
+		})).Return(nil).Once()
+
+		require.NoError(t, channel.SendReminder(reminder))
+		mail.AssertExpectations(t)
+	})
+
+	t.Run("No verified email", func(t *testing.T) {
+		t.Parallel()
+		users := new(MockUserRepo)
+		mail := new(MockMailer)
+		channel := services.NewEmailReminderChannel(users, mail)
+
+		users.On("GetUserByID", 1).Return(&models.User{ID: 1, Email: "alice@example.com"}, nil)
+
+		require.NoError(t, channel.SendReminder(reminder))
+		mail.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
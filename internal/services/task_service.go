@@ -1,24 +1,48 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
 	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
 )
 
 type ITaskRepository interface {
 	CreateTask(task *models.Task) error
 	GetTaskByID(id int) (*models.Task, error)
-	GetTasksByUserID(userID int) ([]models.Task, error)
+	GetVisibleTask(taskID, userID int) (*models.Task, error)
+	GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error)
 	DeleteTask(taskID, userID int) error
 	UpdateTask(updates map[string]interface{}) error
+	AssignTask(taskID, userID int, assigneeID *int, notification *models.Notification) error
+	AddWatcher(taskID, userID int) error
+	RemoveWatcher(taskID, userID int) error
+	GetWatchers(taskID int) ([]models.TaskWatcher, error)
+}
+
+// INotifier emits in-app notifications, see NotificationService
+type INotifier interface {
+	Notify(notification *models.Notification) error
 }
 
 type TaskService struct {
-	taskRepo ITaskRepository
+	taskRepo   ITaskRepository
+	workspaces IWorkspaceMembership
+	notifier   INotifier
+	log        zerolog.Logger
 }
 
-func NewTaskService(taskRepo ITaskRepository) *TaskService {
-	return &TaskService{taskRepo: taskRepo}
+func NewTaskService(taskRepo ITaskRepository, workspaces IWorkspaceMembership, notifier INotifier) *TaskService {
+	return &TaskService{
+		taskRepo:   taskRepo,
+		workspaces: workspaces,
+		notifier:   notifier,
+		log:        logger.GetLogger(),
+	}
 }
 
 func (s *TaskService) CreateTask(task *models.Task) error {
@@ -27,11 +51,29 @@ func (s *TaskService) CreateTask(task *models.Task) error {
 		return err
 	}
 
+	if task.WorkspaceID != nil {
+		if _, err := authorizeWorkspace(s.workspaces, *task.WorkspaceID, task.UserID, models.RoleMember); err != nil {
+			return err
+		}
+	}
+
 	return s.taskRepo.CreateTask(task)
 }
 
-func (s *TaskService) GetTasksByUserID(userID int) ([]models.Task, error) {
-	return s.taskRepo.GetTasksByUserID(userID)
+// GetTasks returns personal tasks of the user and tasks of the user's workspaces
+func (s *TaskService) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	if filter.WorkspaceID != nil {
+		if _, err := authorizeWorkspace(s.workspaces, *filter.WorkspaceID, userID, models.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.taskRepo.GetTasks(userID, filter)
+}
+
+// GetTask returns the task if the user may see it
+func (s *TaskService) GetTask(taskID, userID int) (*models.Task, error) {
+	return s.authorizeRead(taskID, userID)
 }
 
 func (s *TaskService) UpdateTask(updates map[string]interface{}) error {
@@ -40,9 +82,169 @@ func (s *TaskService) UpdateTask(updates map[string]interface{}) error {
 		return err
 	}
 
+	if _, err := s.authorizeWrite(updates["id"].(int), updates["user_id"].(int)); err != nil {
+		return err
+	}
+
 	return s.taskRepo.UpdateTask(updates)
 }
 
 func (s *TaskService) DeleteTask(taskID, userID int) error {
+	if _, err := s.authorizeWrite(taskID, userID); err != nil {
+		return err
+	}
+
 	return s.taskRepo.DeleteTask(taskID, userID)
 }
+
+// AssignTask makes assigneeID responsible for the task. The assignee must be able
+// to see the task and is notified unless it assigns itself
+func (s *TaskService) AssignTask(taskID, userID, assigneeID int) error {
+	task, err := s.authorizeWrite(taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	visible, err := s.taskRepo.GetVisibleTask(taskID, assigneeID)
+	if err != nil {
+		return err
+	}
+	if visible == nil {
+		return ErrInvalidAssignee
+	}
+
+	var notification *models.Notification
+	if assigneeID != userID {
+		notification = &models.Notification{
+			UserID:  assigneeID,
+			Type:    models.NotificationTaskAssigned,
+			TaskID:  &task.ID,
+			ActorID: &userID,
+			Message: task.Title,
+		}
+	}
+
+	return s.taskRepo.AssignTask(taskID, userID, &assigneeID, notification)
+}
+
+func (s *TaskService) UnassignTask(taskID, userID int) error {
+	if _, err := s.authorizeWrite(taskID, userID); err != nil {
+		return err
+	}
+
+	return s.taskRepo.AssignTask(taskID, userID, nil, nil)
+}
+
+// WatchTask subscribes the user to a task it can see, viewers included
+func (s *TaskService) WatchTask(taskID, userID int) error {
+	if _, err := s.authorizeRead(taskID, userID); err != nil {
+		return err
+	}
+
+	return s.taskRepo.AddWatcher(taskID, userID)
+}
+
+func (s *TaskService) UnwatchTask(taskID, userID int) error {
+	return s.taskRepo.RemoveWatcher(taskID, userID)
+}
+
+func (s *TaskService) GetWatchers(taskID, userID int) ([]models.TaskWatcher, error) {
+	if _, err := s.authorizeRead(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.taskRepo.GetWatchers(taskID)
+}
+
+// HandleOutbox tells the watchers of a task, except the one who changed it,
+// about a new status. Notifications carry the message id, so when a failed
+// watcher makes the relay retry, the others are not notified twice
+func (s *TaskService) HandleOutbox(msg *models.OutboxMessage) error {
+	if msg.Type != models.TaskEventUpdated {
+		return nil
+	}
+
+	previous, err := msg.PreviousTask()
+	if err != nil || previous == nil {
+		return err
+	}
+	task, err := msg.Task()
+	if err != nil {
+		return err
+	}
+	if task.Status == previous.Status {
+		return nil
+	}
+
+	watchers, err := s.taskRepo.GetWatchers(task.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, watcher := range watchers {
+		if watcher.UserID == msg.ActorID {
+			continue
+		}
+
+		// a watcher who has left the workspace no longer sees the task
+		visible, err := s.taskRepo.GetVisibleTask(task.ID, watcher.UserID)
+		if err != nil {
+			return err
+		}
+		if visible == nil {
+			continue
+		}
+
+		err = s.notifier.Notify(&models.Notification{
+			UserID:   watcher.UserID,
+			Type:     models.NotificationTaskStatusChanged,
+			TaskID:   &task.ID,
+			ActorID:  &msg.ActorID,
+			Message:  fmt.Sprintf("%s: %s → %s", task.Title, previous.Status, task.Status),
+			OutboxID: &msg.ID,
+		})
+		if err != nil {
+			s.log.Error().Err(err).Int("task_id", task.ID).Int("user_id", watcher.UserID).Msg("Failed to notify watcher")
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// authorizeRead returns the task if the user may see it
+func (s *TaskService) authorizeRead(taskID, userID int) (*models.Task, error) {
+	task, err := s.taskRepo.GetVisibleTask(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, repository.ErrNoRowsUpdated
+	}
+
+	return task, nil
+}
+
+// authorizeWrite returns the task if the user may modify it. Tasks the user can't
+// see are reported like missing ones
+func (s *TaskService) authorizeWrite(taskID, userID int) (*models.Task, error) {
+	task, err := s.authorizeRead(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.WorkspaceID == nil {
+		return task, nil
+	}
+
+	role, err := s.workspaces.GetMemberRole(*task.WorkspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanWrite() {
+		return nil, ErrWorkspaceForbidden
+	}
+
+	return task, nil
+}
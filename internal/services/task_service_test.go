@@ -5,6 +5,7 @@ import (
 
 	"github.com/daioru/todo-app/internal/helpers"
 	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
 	"github.com/daioru/todo-app/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -31,6 +32,16 @@ func (m *MockTaskRepo) GetTasksByUserID(userID int) ([]models.Task, error) {
 	return args.Get(0).([]models.Task), args.Error(1)
 }
 
+func (m *MockTaskRepo) GetVisibleTask(taskID, userID int) (*models.Task, error) {
+	args := m.Called(taskID, userID)
+	return args.Get(0).(*models.Task), args.Error(1)
+}
+
+func (m *MockTaskRepo) GetTasks(userID int, filter models.TaskFilter) ([]models.Task, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
 func (m *MockTaskRepo) DeleteTask(taskID, userID int) error {
 	args := m.Called(taskID, userID)
 	return args.Error(0)
@@ -41,12 +52,32 @@ func (m *MockTaskRepo) UpdateTask(updates map[string]interface{}) error {
 	return args.Error(0)
 }
 
+func (m *MockTaskRepo) AssignTask(taskID, userID int, assigneeID *int, notification *models.Notification) error {
+	args := m.Called(taskID, userID, assigneeID, notification)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepo) AddWatcher(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepo) RemoveWatcher(taskID, userID int) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepo) GetWatchers(taskID int) ([]models.TaskWatcher, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]models.TaskWatcher), args.Error(1)
+}
+
 func TestCreateTask(t *testing.T) {
 	t.Parallel()
 	t.Run("Successful creation", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		task := &models.Task{
 			UserID:      1,
@@ -65,7 +96,7 @@ func TestCreateTask(t *testing.T) {
 	t.Run("Blank title", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		task := &models.Task{
 			UserID:      1,
@@ -83,7 +114,7 @@ func TestCreateTask(t *testing.T) {
 	t.Run("Title too long", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		task := &models.Task{
 			UserID:      1,
@@ -101,7 +132,7 @@ func TestCreateTask(t *testing.T) {
 	t.Run("Status empty", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		task := &models.Task{
 			UserID:      1,
@@ -119,7 +150,7 @@ func TestCreateTask(t *testing.T) {
 	t.Run("Status too long", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		task := &models.Task{
 			UserID:      1,
@@ -135,19 +166,39 @@ func TestCreateTask(t *testing.T) {
 	})
 }
 
+func TestCreateTaskControlCharacters(t *testing.T) {
+	t.Parallel()
+	externalID := "A-1\r\nBEGIN:VEVENT"
+
+	for name, task := range map[string]*models.Task{
+		"Recurrence":  {UserID: 1, Title: "Title", Status: "pending", Recurrence: "FREQ=DAILY\r\nATTENDEE:mailto:x@example.com"},
+		"External id": {UserID: 1, Title: "Title", Status: "pending", ExternalID: &externalID},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			mockRepo := new(MockTaskRepo)
+			service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
+
+			err := service.CreateTask(task)
+			assert.ErrorAs(t, err, &baseErr)
+			mockRepo.AssertNotCalled(t, "CreateTask")
+		})
+	}
+}
+
 func TestGetTasksByUser(t *testing.T) {
 	t.Parallel()
 	mockRepo := new(MockTaskRepo)
-	service := services.NewTaskService(mockRepo)
+	service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 	tasks := []models.Task{
 		{ID: 1, Title: "Task 1", UserID: 1},
 		{ID: 2, Title: "Task 2", UserID: 1},
 	}
 
-	mockRepo.On("GetTasksByUserID", 1).Return(tasks, nil)
+	mockRepo.On("GetTasks", 1, models.TaskFilter{}).Return(tasks, nil)
 
-	result, err := service.GetTasksByUserID(1)
+	result, err := service.GetTasks(1, models.TaskFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
 	mockRepo.AssertExpectations(t)
@@ -158,7 +209,7 @@ func TestUpdateTask(t *testing.T) {
 	t.Run("Successful update", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		updates := map[string]interface{}{
 			"id":          1,
@@ -168,6 +219,7 @@ func TestUpdateTask(t *testing.T) {
 			"status":      "completed",
 		}
 
+		mockRepo.On("GetVisibleTask", 1, 1).Return(&models.Task{ID: 1, UserID: 1}, nil)
 		mockRepo.On("UpdateTask", updates).Return(nil)
 
 		err := service.UpdateTask(updates)
@@ -179,7 +231,7 @@ func TestUpdateTask(t *testing.T) {
 	t.Run("UserID not specified", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		updates := map[string]interface{}{
 			"id":          1,
@@ -197,7 +249,7 @@ func TestUpdateTask(t *testing.T) {
 	t.Run("TaskID not specified", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		updates := map[string]interface{}{
 			"user_id":     1,
@@ -215,7 +267,7 @@ func TestUpdateTask(t *testing.T) {
 	t.Run("No fields to update", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		updates := map[string]interface{}{
 			"id":      1,
@@ -231,7 +283,7 @@ func TestUpdateTask(t *testing.T) {
 	t.Run("Unexpected field", func(t *testing.T) {
 		t.Parallel()
 		mockRepo := new(MockTaskRepo)
-		service := services.NewTaskService(mockRepo)
+		service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
 		updates := map[string]interface{}{
 			"id":               1,
@@ -249,11 +301,109 @@ func TestUpdateTask(t *testing.T) {
 func TestDeleteTask(t *testing.T) {
 	t.Parallel()
 	mockRepo := new(MockTaskRepo)
-	service := services.NewTaskService(mockRepo)
+	service := services.NewTaskService(mockRepo, new(MockWorkspaceRepo), new(MockNotifier))
 
+	mockRepo.On("GetVisibleTask", 1, 1).Return(&models.Task{ID: 1, UserID: 1}, nil)
 	mockRepo.On("DeleteTask", 1, 1).Return(nil)
 
 	err := service.DeleteTask(1, 1)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestAssignTask(t *testing.T) {
+	t.Parallel()
+	workspaceID := teamWorkspace
+	shared := &models.Task{ID: 42, UserID: ownerID, WorkspaceID: &workspaceID, Title: "Ship release"}
+
+	newService := func() (*services.TaskService, *MockTaskRepo) {
+		workspaces := new(MockWorkspaceRepo)
+		withTeam(workspaces)
+		tasks := new(MockTaskRepo)
+		return services.NewTaskService(tasks, workspaces, new(MockNotifier)), tasks
+	}
+
+	t.Run("Assignee is notified", func(t *testing.T) {
+		t.Parallel()
+		service, tasks := newService()
+		tasks.On("GetVisibleTask", 42, memberID).Return(shared, nil)
+		tasks.On("GetVisibleTask", 42, viewerID).Return(shared, nil)
+		tasks.On("AssignTask", 42, memberID, mock.MatchedBy(func(id *int) bool { return *id == viewerID }),
+			mock.MatchedBy(func(n *models.Notification) bool {
+				return n.UserID == viewerID && n.Type == models.NotificationTaskAssigned &&
+					*n.TaskID == 42 && *n.ActorID == memberID && n.Message == "Ship release"
+			})).Return(nil)
+
+		assert.NoError(t, service.AssignTask(42, memberID, viewerID))
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Self assignment is not notified", func(t *testing.T) {
+		t.Parallel()
+		service, tasks := newService()
+		tasks.On("GetVisibleTask", 42, memberID).Return(shared, nil)
+		tasks.On("AssignTask", 42, memberID, mock.Anything, (*models.Notification)(nil)).Return(nil)
+
+		assert.NoError(t, service.AssignTask(42, memberID, memberID))
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Assignee outside the workspace", func(t *testing.T) {
+		t.Parallel()
+		service, tasks := newService()
+		tasks.On("GetVisibleTask", 42, memberID).Return(shared, nil)
+		tasks.On("GetVisibleTask", 42, outsiderID).Return((*models.Task)(nil), nil)
+
+		assert.ErrorIs(t, service.AssignTask(42, memberID, outsiderID), services.ErrInvalidAssignee)
+		tasks.AssertNotCalled(t, "AssignTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Viewer cannot assign", func(t *testing.T) {
+		t.Parallel()
+		service, tasks := newService()
+		tasks.On("GetVisibleTask", 42, viewerID).Return(shared, nil)
+
+		assert.ErrorIs(t, service.AssignTask(42, viewerID, viewerID), services.ErrWorkspaceForbidden)
+		assert.ErrorIs(t, service.UnassignTask(42, viewerID), services.ErrWorkspaceForbidden)
+		tasks.AssertNotCalled(t, "AssignTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unassign", func(t *testing.T) {
+		t.Parallel()
+		service, tasks := newService()
+		tasks.On("GetVisibleTask", 42, adminID).Return(shared, nil)
+		tasks.On("AssignTask", 42, adminID, (*int)(nil), (*models.Notification)(nil)).Return(nil)
+
+		assert.NoError(t, service.UnassignTask(42, adminID))
+		tasks.AssertExpectations(t)
+	})
+}
+
+func TestWatchTask(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Viewer may watch", func(t *testing.T) {
+		t.Parallel()
+		workspaceID := teamWorkspace
+		tasks := new(MockTaskRepo)
+		service := services.NewTaskService(tasks, new(MockWorkspaceRepo), new(MockNotifier))
+		tasks.On("GetVisibleTask", 42, viewerID).Return(&models.Task{ID: 42, WorkspaceID: &workspaceID}, nil)
+		tasks.On("AddWatcher", 42, viewerID).Return(nil)
+
+		assert.NoError(t, service.WatchTask(42, viewerID))
+		tasks.AssertExpectations(t)
+	})
+
+	t.Run("Hidden task", func(t *testing.T) {
+		t.Parallel()
+		tasks := new(MockTaskRepo)
+		service := services.NewTaskService(tasks, new(MockWorkspaceRepo), new(MockNotifier))
+		tasks.On("GetVisibleTask", 42, outsiderID).Return((*models.Task)(nil), nil)
+
+		assert.ErrorIs(t, service.WatchTask(42, outsiderID), repository.ErrNoRowsUpdated)
+		_, err := service.GetWatchers(42, outsiderID)
+		assert.ErrorIs(t, err, repository.ErrNoRowsUpdated)
+		tasks.AssertNotCalled(t, "AddWatcher", mock.Anything, mock.Anything)
+		tasks.AssertNotCalled(t, "GetWatchers", mock.Anything)
+	})
+}
@@ -0,0 +1,643 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+)
+
+// taskCSVHeader is the header of CSV exports, imports read it with the default mapping
+var taskCSVHeader = []string{
+	"id", "external_id", "title", "description", "status", "priority", "recurrence", "tags", "workspace_id", "assignee_id",
+	"due_at", "completed_at", "created_at",
+}
+
+type IExternalIDRepository interface {
+	GetExternalIDs(userID int, externalIDs []string) ([]string, error)
+}
+
+type ITaskTransferRepository interface {
+	IExternalIDRepository
+	StreamTasks(userID int, filter models.TaskFilter, fn func(task *models.Task) error) error
+	ImportTasks(tasks []models.Task, batchSize int) (int, error)
+}
+
+type ITransferUserRepository interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
+// TaskTransferService moves tasks in and out of the app as files
+type TaskTransferService struct {
+	repo       ITaskTransferRepository
+	workspaces IWorkspaceMembership
+	users      ITransferUserRepository
+	cfg        config.Import
+}
+
+func NewTaskTransferService(repo ITaskTransferRepository, workspaces IWorkspaceMembership, users ITransferUserRepository, cfg config.Import) *TaskTransferService {
+	return &TaskTransferService{repo: repo, workspaces: workspaces, users: users, cfg: cfg}
+}
+
+// ExportContentType returns the content type of an export format, empty for
+// unknown formats
+func ExportContentType(format string) string {
+	switch format {
+	case models.TransferFormatCSV:
+		return "text/csv; charset=utf-8"
+	case models.TransferFormatJSON:
+		return "application/json"
+	case models.TransferFormatNDJSON:
+		return "application/x-ndjson"
+	case models.TransferFormatICS:
+		return "text/calendar; charset=utf-8"
+	case models.TransferFormatTodoTxt:
+		return "text/plain; charset=utf-8"
+	case models.TransferFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return ""
+	}
+}
+
+// ExportExtension returns the file name extension of an export format
+func ExportExtension(format string) string {
+	if format == models.TransferFormatMarkdown {
+		return "md"
+	}
+	return format
+}
+
+// ImportFormat returns the import format of a file name extension
+func ImportFormat(extension string) string {
+	switch extension = strings.ToLower(strings.TrimPrefix(extension, ".")); extension {
+	case "md":
+		return models.TransferFormatMarkdown
+	default:
+		return extension
+	}
+}
+
+// Export writes the tasks visible to the user narrowed by the filter to w as
+// they are read from the database
+func (s *TaskTransferService) Export(w io.Writer, userID int, filter models.TaskFilter, format string) error {
+	if filter.WorkspaceID != nil {
+		if _, err := authorizeWorkspace(s.workspaces, *filter.WorkspaceID, userID, models.RoleViewer); err != nil {
+			return err
+		}
+	}
+
+	loc, err := s.location(userID)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := newTaskEncoder(w, format, loc)
+	if err != nil {
+		return err
+	}
+
+	if err := encoder.begin(); err != nil {
+		return err
+	}
+	if err := s.repo.StreamTasks(userID, filter, encoder.encode); err != nil {
+		return err
+	}
+	return encoder.end()
+}
+
+// Import reads the tasks of the file and validates every row. Nothing is stored
+// on a dry run or when a row is invalid, otherwise the valid rows are inserted
+// in one transaction. Rows with an external id seen before in the file or
+// already imported by the user are skipped as duplicates
+func (s *TaskTransferService) Import(r io.Reader, req models.ImportRequest) (*models.ImportReport, error) {
+	mapping, err := importMapping(req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.WorkspaceID != nil {
+		if _, err := authorizeWorkspace(s.workspaces, *req.WorkspaceID, req.UserID, models.RoleMember); err != nil {
+			return nil, err
+		}
+	}
+
+	loc, err := s.location(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := decodeTaskRecords(r, req.Format, s.cfg.MaxRows, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{DryRun: req.DryRun, Total: len(records), Errors: []models.ImportRowError{}}
+	tasks := make([]models.Task, 0, len(records))
+	seen := make(map[string]bool)
+	for i, record := range records {
+		task, err := importTask(record, mapping)
+		if err != nil {
+			report.Invalid++
+			report.Errors = append(report.Errors, models.ImportRowError{Row: i + 1, ExternalID: record[mapping["external_id"]], Error: rowError(err)})
+			continue
+		}
+
+		if task.ExternalID != nil {
+			if seen[*task.ExternalID] {
+				report.Duplicates++
+				continue
+			}
+			seen[*task.ExternalID] = true
+		}
+
+		task.UserID = req.UserID
+		task.WorkspaceID = req.WorkspaceID
+		tasks = append(tasks, *task)
+	}
+
+	tasks, duplicates, err := skipImported(s.repo, req.UserID, tasks, func(task models.Task) *string { return task.ExternalID })
+	if err != nil {
+		return nil, err
+	}
+	report.Duplicates += duplicates
+	report.Valid = len(tasks)
+
+	if report.Invalid > 0 {
+		return report, ErrImportInvalid
+	}
+	if req.DryRun || len(tasks) == 0 {
+		return report, nil
+	}
+
+	created, err := s.repo.ImportTasks(tasks, s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// rows imported concurrently since the check are skipped by the insert
+	report.Duplicates += len(tasks) - created
+	report.Created = created
+	return report, nil
+}
+
+// skipImported drops the tasks whose external id the user imported before and
+// returns how many it dropped
+func skipImported[T any](repo IExternalIDRepository, userID int, tasks []T, externalID func(T) *string) ([]T, int, error) {
+	var externalIDs []string
+	for _, task := range tasks {
+		if id := externalID(task); id != nil {
+			externalIDs = append(externalIDs, *id)
+		}
+	}
+	if len(externalIDs) == 0 {
+		return tasks, 0, nil
+	}
+
+	existing, err := repo.GetExternalIDs(userID, externalIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skipped := 0
+	return slices.DeleteFunc(tasks, func(task T) bool {
+		if id := externalID(task); id != nil && slices.Contains(existing, *id) {
+			skipped++
+			return true
+		}
+		return false
+	}), skipped, nil
+}
+
+// importMapping completes the mapping of an import with the default source
+// column of every unmapped field
+func importMapping(mapping map[string]string) (map[string]string, error) {
+	complete := make(map[string]string, len(models.ImportFields))
+	for _, field := range models.ImportFields {
+		complete[field] = field
+	}
+
+	for field, column := range mapping {
+		if !slices.Contains(models.ImportFields, field) {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("mapping", fmt.Sprintf("unknown field %q", field)))
+		}
+		if strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("mapping", fmt.Sprintf("no column for field %q", field)))
+		}
+		complete[field] = column
+	}
+
+	return complete, nil
+}
+
+// importTask builds a task from one source record, a missing status means
+// pending. The completion time is only kept for done tasks, tags are separated
+// by spaces or commas or given as a JSON array
+func importTask(record map[string]string, mapping map[string]string) (*models.Task, error) {
+	task := &models.Task{
+		Title:       strings.TrimSpace(record[mapping["title"]]),
+		Description: record[mapping["description"]],
+		Status:      strings.TrimSpace(record[mapping["status"]]),
+		Priority:    strings.ToLower(strings.TrimSpace(record[mapping["priority"]])),
+		Recurrence:  strings.TrimSpace(record[mapping["recurrence"]]),
+	}
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+
+	if externalID := strings.TrimSpace(record[mapping["external_id"]]); externalID != "" {
+		if len(externalID) > 255 {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("external_id", "field too long"))
+		}
+		task.ExternalID = &externalID
+	}
+
+	if raw := strings.TrimSpace(record[mapping["due_at"]]); raw != "" {
+		due, err := models.ParseJSONTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("due_at", "invalid time, use RFC 3339 or RFC 1123"))
+		}
+		dueAt := models.JSONTime(due)
+		task.DueAt = &dueAt
+	}
+
+	if raw := strings.TrimSpace(record[mapping["completed_at"]]); raw != "" && task.Status == models.TaskStatusDone {
+		completed, err := models.ParseJSONTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("completed_at", "invalid time, use RFC 3339 or RFC 1123"))
+		}
+		completedAt := models.JSONTime(completed)
+		task.CompletedAt = &completedAt
+	}
+
+	if raw := strings.TrimSpace(record[mapping["created_at"]]); raw != "" {
+		created, err := models.ParseJSONTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("created_at", "invalid time, use RFC 3339 or RFC 1123"))
+		}
+		task.CreatedAt = models.JSONTime(created)
+	}
+
+	tags, err := importTags(record[mapping["tags"]])
+	if err != nil {
+		return nil, err
+	}
+	task.Tags = tags
+
+	if err := helpers.ValidateTaskFields(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func importTags(raw string) (models.StringList, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") {
+		var tags models.StringList
+		if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+			return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("tags", "must be a list of strings"))
+		}
+		return tags, nil
+	}
+
+	tags := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// location returns the time zone of the user, formats with dates but no time
+// zone are written and read in it
+func (s *TaskTransferService) location(userID int) (*time.Location, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return userLocation(user), nil
+}
+
+// decodeTaskRecords reads the rows of an import as column to value maps
+func decodeTaskRecords(r io.Reader, format string, maxRows int, loc *time.Location) ([]map[string]string, error) {
+	var records []map[string]string
+	add := func(record map[string]string) error {
+		if len(records) == maxRows {
+			return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("file", fmt.Sprintf("more than %d rows", maxRows)))
+		}
+		records = append(records, record)
+		return nil
+	}
+
+	var err error
+	switch format {
+	case models.TransferFormatCSV:
+		err = decodeCSVRecords(r, add)
+	case models.TransferFormatJSON:
+		err = decodeJSONRecords(r, add)
+	case models.TransferFormatNDJSON:
+		err = decodeNDJSONRecords(r, add)
+	case models.TransferFormatICS:
+		err = decodeICSRecords(r, add)
+	case models.TransferFormatTodoTxt:
+		err = decodeTodoTxtRecords(r, loc, add)
+	case models.TransferFormatMarkdown:
+		err = decodeMarkdownRecords(r, loc, add)
+	default:
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("format", "must be csv, json, ndjson, ics, txt or markdown"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func decodeCSVRecords(r io.Reader, add func(map[string]string) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return invalidFile(err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return invalidFile(err)
+		}
+
+		record := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(column)] = unescapeCSVCell(row[i])
+			}
+		}
+		if err := add(record); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeJSONRecords(r io.Reader, add func(map[string]string) error) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return invalidFile(err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return invalidFile(errors.New("expected an array of objects"))
+	}
+
+	for decoder.More() {
+		record, err := decodeJSONRecord(decoder)
+		if err != nil {
+			return err
+		}
+		if err := add(record); err != nil {
+			return err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return invalidFile(err)
+	}
+	return nil
+}
+
+func decodeNDJSONRecords(r io.Reader, add func(map[string]string) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	decoder.UseNumber()
+
+	for decoder.More() {
+		record, err := decodeJSONRecord(decoder)
+		if err != nil {
+			return err
+		}
+		if err := add(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeJSONRecord reads one object, scalars become strings and nested values
+// their JSON text
+func decodeJSONRecord(decoder *json.Decoder) (map[string]string, error) {
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, invalidFile(err)
+	}
+
+	record := make(map[string]string, len(object))
+	for key, value := range object {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			record[key] = v
+		case json.Number:
+			record[key] = v.String()
+		case bool:
+			record[key] = strconv.FormatBool(v)
+		default:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, invalidFile(err)
+			}
+			record[key] = string(raw)
+		}
+	}
+	return record, nil
+}
+
+// rowError is the message of a row validation error without the common prefix
+func rowError(err error) string {
+	var fieldErr *helpers.SpecificValidationError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.Error()
+	}
+	return err.Error()
+}
+
+func invalidFile(err error) error {
+	return fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("file", err.Error()))
+}
+
+// taskEncoder writes an export one task at a time
+type taskEncoder interface {
+	begin() error
+	encode(task *models.Task) error
+	end() error
+}
+
+func newTaskEncoder(w io.Writer, format string, loc *time.Location) (taskEncoder, error) {
+	switch format {
+	case models.TransferFormatCSV:
+		return &csvTaskEncoder{w: csv.NewWriter(w)}, nil
+	case models.TransferFormatJSON:
+		return &jsonTaskEncoder{w: w}, nil
+	case models.TransferFormatNDJSON:
+		return &ndjsonTaskEncoder{encoder: json.NewEncoder(w)}, nil
+	case models.TransferFormatICS:
+		return newICSTaskEncoder(w, time.Now(), "", false), nil
+	case models.TransferFormatTodoTxt:
+		return &todoTxtTaskEncoder{w: w, loc: loc}, nil
+	case models.TransferFormatMarkdown:
+		return &markdownTaskEncoder{w: w, loc: loc}, nil
+	default:
+		return nil, fmt.Errorf("validation failed: %w", helpers.NewSpecificValidationError("format", "must be csv, json, ndjson, ics, txt or markdown"))
+	}
+}
+
+type csvTaskEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvTaskEncoder) begin() error {
+	return e.w.Write(taskCSVHeader)
+}
+
+func (e *csvTaskEncoder) encode(task *models.Task) error {
+	record := []string{
+		strconv.Itoa(task.ID),
+		optionalString(task.ExternalID),
+		task.Title,
+		task.Description,
+		task.Status,
+		task.Priority,
+		task.Recurrence,
+		strings.Join(task.Tags, " "),
+		optionalInt(task.WorkspaceID),
+		optionalInt(task.AssigneeID),
+		optionalTime(task.DueAt),
+		optionalTime(task.CompletedAt),
+		time.Time(task.CreatedAt).Format(time.RFC3339),
+	}
+	for i, cell := range record {
+		record[i] = escapeCSVCell(cell)
+	}
+	return e.w.Write(record)
+}
+
+// csvFormulaPrefixes make spreadsheet apps read a cell as a formula. The quote
+// is among them so a quote of the cell itself survives the round trip
+const csvFormulaPrefixes = "=+-@\t\r'"
+
+// escapeCSVCell prefixes a cell that would be read as a formula with a quote,
+// spreadsheet apps show it as text
+func escapeCSVCell(cell string) string {
+	if cell != "" && strings.IndexByte(csvFormulaPrefixes, cell[0]) >= 0 {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeCSVCell undoes escapeCSVCell
+func unescapeCSVCell(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.IndexByte(csvFormulaPrefixes, cell[1]) >= 0 {
+		return cell[1:]
+	}
+	return cell
+}
+
+func (e *csvTaskEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonTaskEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonTaskEncoder) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonTaskEncoder) encode(task *models.Task) error {
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	_, err = e.w.Write(raw)
+	return err
+}
+
+func (e *jsonTaskEncoder) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+type ndjsonTaskEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonTaskEncoder) begin() error {
+	return nil
+}
+
+func (e *ndjsonTaskEncoder) encode(task *models.Task) error {
+	return e.encoder.Encode(task)
+}
+
+func (e *ndjsonTaskEncoder) end() error {
+	return nil
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func optionalTime(value *models.JSONTime) string {
+	if value == nil {
+		return ""
+	}
+	return time.Time(*value).Format(time.RFC3339)
+}
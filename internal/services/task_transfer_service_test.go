@@ -0,0 +1,208 @@
+package services_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTransferRepo struct {
+	mock.Mock
+	tasks []models.Task
+}
+
+func (m *MockTransferRepo) StreamTasks(userID int, filter models.TaskFilter, fn func(task *models.Task) error) error {
+	args := m.Called(userID, filter)
+	for i := range m.tasks {
+		if err := fn(&m.tasks[i]); err != nil {
+			return err
+		}
+	}
+	return args.Error(0)
+}
+
+func (m *MockTransferRepo) GetExternalIDs(userID int, externalIDs []string) ([]string, error) {
+	args := m.Called(userID, externalIDs)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTransferRepo) ImportTasks(tasks []models.Task, batchSize int) (int, error) {
+	args := m.Called(tasks, batchSize)
+	return args.Int(0), args.Error(1)
+}
+
+var importConfig = config.Import{MaxBytes: 1 << 20, MaxRows: 3, BatchSize: 2}
+
+// transferUsers returns users living in the time zone, UTC when it is empty
+func transferUsers(timezone string) *MockUserRepo {
+	users := new(MockUserRepo)
+	users.On("GetUserByID", mock.Anything).Return(&models.User{ID: 1, Timezone: timezone}, nil)
+	return users
+}
+
+func TestExportTasks(t *testing.T) {
+	due := models.JSONTime(time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC))
+	created := models.JSONTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	externalID := "T-1"
+	tasks := []models.Task{
+		{ID: 1, UserID: 1, ExternalID: &externalID, Title: "Ship, release", Status: "pending", Priority: "high", Recurrence: "FREQ=DAILY",
+			Tags: models.StringList{"release", "@desk"}, DueAt: &due, CreatedAt: created},
+		{ID: 2, UserID: 1, Title: "Write notes", Description: "line one\nline two", Status: "done", CreatedAt: created},
+	}
+
+	for _, tc := range []struct {
+		format string
+		want   string
+	}{
+		{models.TransferFormatCSV, "id,external_id,title,description,status,priority,recurrence,tags,workspace_id,assignee_id,due_at,completed_at,created_at\n" +
+			"1,T-1,\"Ship, release\",,pending,high,FREQ=DAILY,release @desk,,,2026-10-20T09:00:00Z,,2026-10-01T12:00:00Z\n" +
+			"2,,Write notes,\"line one\nline two\",done,,,,,,,,2026-10-01T12:00:00Z\n"},
+		{models.TransferFormatJSON, "[{\"id\":1,"},
+		{models.TransferFormatNDJSON, "{\"id\":1,"},
+		{models.TransferFormatICS, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"},
+		{models.TransferFormatTodoTxt, "(A) 2026-10-01 Ship, release +release @desk due:2026-10-20T09:00 rec:FREQ%3DDAILY\n"},
+		{models.TransferFormatMarkdown, "- [ ] Ship, release #release @desk due:2026-10-20\n- [x] Write notes\n  line one\n  line two\n"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			repo := &MockTransferRepo{tasks: tasks}
+			service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+			repo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+			var out bytes.Buffer
+			err := service.Export(&out, 1, models.TaskFilter{}, tc.format)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(out.String(), tc.want), out.String())
+
+			switch tc.format {
+			case models.TransferFormatJSON:
+				assert.True(t, strings.HasSuffix(out.String(), "}]\n"))
+				assert.Equal(t, 1, strings.Count(out.String(), "},\n{"))
+			case models.TransferFormatNDJSON:
+				assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+			case models.TransferFormatICS:
+				assert.Equal(t, 2, strings.Count(out.String(), "BEGIN:VTODO"))
+				assert.Contains(t, out.String(), "UID:task-2@todo-app\r\n")
+				assert.Contains(t, out.String(), "SUMMARY:Ship\\, release\r\n")
+				assert.Contains(t, out.String(), "DESCRIPTION:line one\\nline two\r\n")
+				assert.Contains(t, out.String(), "PRIORITY:1\r\n")
+				assert.True(t, strings.HasSuffix(out.String(), "END:VCALENDAR\r\n"))
+			}
+		})
+	}
+
+	t.Run("Formulas in CSV", func(t *testing.T) {
+		externalID := "@risk"
+		repo := &MockTransferRepo{tasks: []models.Task{
+			{ID: 3, UserID: 1, ExternalID: &externalID, Title: `=HYPERLINK("http://example.com")`, Description: "'+quoted", Status: "pending", CreatedAt: created},
+		}}
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+		repo.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+		var out bytes.Buffer
+		require.NoError(t, service.Export(&out, 1, models.TaskFilter{}, models.TransferFormatCSV))
+		assert.Contains(t, out.String(), `3,'@risk,"'=HYPERLINK(""http://example.com"")",''+quoted,pending`)
+
+		// the export imports back unchanged
+		repo.On("GetExternalIDs", 1, []string{"@risk"}).Return([]string{}, nil)
+		repo.On("ImportTasks", mock.MatchedBy(func(tasks []models.Task) bool {
+			return len(tasks) == 1 && *tasks[0].ExternalID == "@risk" &&
+				tasks[0].Title == `=HYPERLINK("http://example.com")` && tasks[0].Description == "'+quoted"
+		}), importConfig.BatchSize).Return(1, nil)
+
+		_, err := service.Import(&out, models.ImportRequest{UserID: 1, Format: models.TransferFormatCSV})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		repo := new(MockTransferRepo)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		err := service.Export(&bytes.Buffer{}, 1, models.TaskFilter{}, "xml")
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "StreamTasks", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImportTasks(t *testing.T) {
+	csvFile := "Key,Name,State,Deadline\n" +
+		"A-1,Pay invoice,,2026-10-20T09:00:00Z\n" +
+		"A-2,Call bank,done,\n" +
+		"A-1,Pay invoice again,,\n"
+
+	t.Run("Mapped CSV", func(t *testing.T) {
+		repo := new(MockTransferRepo)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		repo.On("GetExternalIDs", 1, []string{"A-1", "A-2"}).Return([]string{"A-2"}, nil)
+		repo.On("ImportTasks", mock.MatchedBy(func(tasks []models.Task) bool {
+			return len(tasks) == 1 && *tasks[0].ExternalID == "A-1" && tasks[0].Title == "Pay invoice" &&
+				tasks[0].Status == "pending" && tasks[0].UserID == 1 && tasks[0].DueAt != nil
+		}), importConfig.BatchSize).Return(1, nil)
+
+		report, err := service.Import(strings.NewReader(csvFile), models.ImportRequest{
+			UserID:  1,
+			Format:  models.TransferFormatCSV,
+			Mapping: map[string]string{"external_id": "Key", "title": "Name", "status": "State", "due_at": "Deadline"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &models.ImportReport{Total: 3, Valid: 1, Duplicates: 2, Created: 1, Errors: []models.ImportRowError{}}, report)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		repo := new(MockTransferRepo)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		repo.On("GetExternalIDs", 1, []string{"x"}).Return([]string{}, nil)
+
+		report, err := service.Import(strings.NewReader(`[{"title": "Imported", "external_id": "x"}]`), models.ImportRequest{
+			UserID: 1,
+			Format: models.TransferFormatJSON,
+			DryRun: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Valid)
+		assert.Equal(t, 0, report.Created)
+		repo.AssertNotCalled(t, "ImportTasks", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid rows", func(t *testing.T) {
+		repo := new(MockTransferRepo)
+		service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		ndjson := `{"title": "Fine", "id": 7}` + "\n" + `{"title": "", "external_id": 12}` + "\n" + `{"title": "Late", "due_at": "tomorrow"}` + "\n"
+		report, err := service.Import(strings.NewReader(ndjson), models.ImportRequest{UserID: 1, Format: models.TransferFormatNDJSON})
+		assert.ErrorIs(t, err, services.ErrImportInvalid)
+		assert.Equal(t, 2, report.Invalid)
+		assert.Equal(t, 1, report.Valid)
+		assert.Equal(t, []models.ImportRowError{
+			{Row: 2, ExternalID: "12", Error: "field 'title': cannot be blank"},
+			{Row: 3, Error: "field 'due_at': invalid time, use RFC 3339 or RFC 1123"},
+		}, report.Errors)
+		repo.AssertNotCalled(t, "ImportTasks", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Too many rows", func(t *testing.T) {
+		service := services.NewTaskTransferService(new(MockTransferRepo), new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		_, err := service.Import(strings.NewReader(csvFile+"A-3,One more,,\n"), models.ImportRequest{UserID: 1, Format: models.TransferFormatCSV})
+		assert.ErrorContains(t, err, "more than 3 rows")
+	})
+
+	t.Run("Unknown mapping field", func(t *testing.T) {
+		service := services.NewTaskTransferService(new(MockTransferRepo), new(MockWorkspaceRepo), transferUsers(""), importConfig)
+
+		_, err := service.Import(strings.NewReader(csvFile), models.ImportRequest{UserID: 1, Format: models.TransferFormatCSV, Mapping: map[string]string{"owner": "Who"}})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+}
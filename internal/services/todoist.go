@@ -0,0 +1,384 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// TodoistImporter reads the CSV export of a Todoist project or a JSON backup
+// in the shape of the Todoist Sync API. Subtasks become checklist items of
+// their top-level task, sections and projects become tags
+type TodoistImporter struct{}
+
+func (TodoistImporter) Parse(r io.Reader, fileName string) (*models.AppImport, error) {
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		return parseTodoistJSON(r)
+	}
+	return parseTodoistCSV(r, strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)))
+}
+
+// todoistRecurrences maps the simple recurring due strings of Todoist to RRULEs
+var todoistRecurrences = map[string]string{
+	"every day":     "FREQ=DAILY",
+	"daily":         "FREQ=DAILY",
+	"every weekday": "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+	"every week":    "FREQ=WEEKLY",
+	"weekly":        "FREQ=WEEKLY",
+	"every month":   "FREQ=MONTHLY",
+	"monthly":       "FREQ=MONTHLY",
+	"every year":    "FREQ=YEARLY",
+	"yearly":        "FREQ=YEARLY",
+}
+
+// todoistLabel matches the @label words of a task content in a CSV export
+var todoistLabel = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// todoistAuthor matches the "Name (id)" author column of a CSV export
+var todoistAuthor = regexp.MustCompile(`^(.*?)\s*\(\d+\)$`)
+
+// parseTodoistCSV reads a project exported as CSV. The export has no task ids,
+// tasks are identified by a hash of the project, section and content, so an
+// export with tasks added or moved still matches the tasks imported before
+func parseTodoistCSV(r io.Reader, project string) (*models.AppImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalidFile(err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	if _, ok := columns["TYPE"]; !ok {
+		return nil, invalidFile(errors.New("not a Todoist export, no TYPE column"))
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return nil, invalidFile(errors.New("not a Todoist export, no CONTENT column"))
+	}
+
+	result := &models.AppImport{}
+	labels := make(map[string]bool)
+	section := ""
+	var parent *models.ImportedTask
+	occurrences := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidFile(err)
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		switch strings.ToLower(get("TYPE")) {
+		case "section":
+			section = get("CONTENT")
+		case "task":
+			content := get("CONTENT")
+			indent, _ := strconv.Atoi(get("INDENT"))
+			if indent > 1 && parent != nil {
+				parent.Checklist = append(parent.Checklist, models.ChecklistItem{Text: todoistLabel.ReplaceAllString(content, "")})
+				continue
+			}
+
+			task := models.ImportedTask{Task: models.Task{
+				Title:       todoistLabel.ReplaceAllString(content, ""),
+				Description: get("DESCRIPTION"),
+				Priority:    todoistCSVPriority(get("PRIORITY")),
+				Tags:        models.StringList{project},
+			}}
+			// identical tasks of a section are told apart by their order
+			key := project + "\x00" + section + "\x00" + content
+			occurrences[key]++
+			sum := sha256.Sum256([]byte(key + "\x00" + strconv.Itoa(occurrences[key])))
+			externalID := "todoist:csv:" + hex.EncodeToString(sum[:16])
+			task.ExternalID = &externalID
+			if section != "" {
+				task.Tags = append(task.Tags, section)
+			}
+			for _, match := range todoistLabel.FindAllStringSubmatch(content, -1) {
+				task.Tags = append(task.Tags, match[1])
+				labels[match[1]] = true
+			}
+
+			loc := time.Local
+			if tz, err := time.LoadLocation(get("TIMEZONE")); err == nil && get("TIMEZONE") != "" {
+				loc = tz
+			}
+			if warning := setTodoistDue(&task, get("DATE"), get("DATE"), loc); warning != "" {
+				result.Warnings = append(result.Warnings, warning)
+			}
+
+			result.Tasks = append(result.Tasks, task)
+			parent = &result.Tasks[len(result.Tasks)-1]
+		case "note":
+			if parent == nil {
+				continue
+			}
+			author := get("AUTHOR")
+			if match := todoistAuthor.FindStringSubmatch(author); match != nil {
+				author = match[1]
+			}
+			comment := models.ImportedComment{Author: author, Body: get("CONTENT")}
+			if posted, ok := parseAppTime(get("DATE"), time.Local); ok {
+				comment.CreatedAt = &posted
+			}
+			parent.Comments = append(parent.Comments, comment)
+		}
+	}
+
+	if len(result.Tasks) > 0 {
+		result.Projects = 1
+	}
+	result.Labels = len(labels)
+	return result, nil
+}
+
+// todoistCSVPriority converts the priority of a CSV export, 1 is the highest
+func todoistCSVPriority(raw string) string {
+	switch raw {
+	case "1":
+		return models.TaskPriorityHigh
+	case "2":
+		return models.TaskPriorityMedium
+	case "3":
+		return models.TaskPriorityLow
+	default:
+		return ""
+	}
+}
+
+// todoistAPIPriority converts the priority of the API, 4 is the highest
+func todoistAPIPriority(priority int) string {
+	switch priority {
+	case 4:
+		return models.TaskPriorityHigh
+	case 3:
+		return models.TaskPriorityMedium
+	case 2:
+		return models.TaskPriorityLow
+	default:
+		return ""
+	}
+}
+
+// setTodoistDue sets the due time from a date and the recurrence from the due
+// string of Todoist, the CSV export has both in one column. A warning is
+// returned for a due date that is neither
+func setTodoistDue(task *models.ImportedTask, date, recurring string, loc *time.Location) string {
+	if date == "" {
+		return ""
+	}
+
+	if rule, ok := todoistRecurrences[strings.ToLower(strings.TrimSpace(recurring))]; ok {
+		task.Recurrence = rule
+	}
+	if due, ok := parseAppTime(date, loc); ok {
+		task.DueAt = appJSONTime(due)
+		return ""
+	}
+	if task.Recurrence != "" {
+		return ""
+	}
+
+	task.Description = strings.TrimSpace(task.Description + "\n\nDue: " + date)
+	return fmt.Sprintf("due date %q of task %q is not a date, kept in the description", date, task.Title)
+}
+
+type todoistBackup struct {
+	Projects []struct {
+		ID   json.RawMessage `json:"id"`
+		Name string          `json:"name"`
+	} `json:"projects"`
+	Sections []struct {
+		ID   json.RawMessage `json:"id"`
+		Name string          `json:"name"`
+	} `json:"sections"`
+	Items []struct {
+		ID          json.RawMessage `json:"id"`
+		ProjectID   json.RawMessage `json:"project_id"`
+		SectionID   json.RawMessage `json:"section_id"`
+		ParentID    json.RawMessage `json:"parent_id"`
+		Content     string          `json:"content"`
+		Description string          `json:"description"`
+		Priority    int             `json:"priority"`
+		Labels      []string        `json:"labels"`
+		Checked     bool            `json:"checked"`
+		CompletedAt string          `json:"completed_at"`
+		AddedAt     string          `json:"added_at"`
+		Due         *struct {
+			Date        string `json:"date"`
+			Timezone    string `json:"timezone"`
+			String      string `json:"string"`
+			IsRecurring bool   `json:"is_recurring"`
+		} `json:"due"`
+	} `json:"items"`
+	Notes []struct {
+		ItemID    json.RawMessage `json:"item_id"`
+		Content   string          `json:"content"`
+		PostedAt  string          `json:"posted_at"`
+		PostedUID json.RawMessage `json:"posted_uid"`
+	} `json:"notes"`
+	Collaborators []struct {
+		ID       json.RawMessage `json:"id"`
+		FullName string          `json:"full_name"`
+	} `json:"collaborators"`
+}
+
+// todoistID reads an id the API writes as a string or, in older versions, a number
+func todoistID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+func parseTodoistJSON(r io.Reader) (*models.AppImport, error) {
+	var backup todoistBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, invalidFile(err)
+	}
+
+	projects := make(map[string]string, len(backup.Projects))
+	for _, p := range backup.Projects {
+		projects[todoistID(p.ID)] = p.Name
+	}
+	sections := make(map[string]string, len(backup.Sections))
+	for _, s := range backup.Sections {
+		sections[todoistID(s.ID)] = s.Name
+	}
+	people := make(map[string]string, len(backup.Collaborators))
+	for _, c := range backup.Collaborators {
+		people[todoistID(c.ID)] = c.FullName
+	}
+
+	result := &models.AppImport{}
+	parents := make(map[string]string)
+	index := make(map[string]int)
+	usedProjects := make(map[string]bool)
+	labels := make(map[string]bool)
+
+	for _, item := range backup.Items {
+		id := todoistID(item.ID)
+		if parentID := todoistID(item.ParentID); parentID != "" {
+			parents[id] = parentID
+			continue
+		}
+
+		task := models.ImportedTask{Task: models.Task{
+			Title:       item.Content,
+			Description: item.Description,
+			Priority:    todoistAPIPriority(item.Priority),
+		}}
+		externalID := "todoist:" + id
+		task.ExternalID = &externalID
+
+		if project, ok := projects[todoistID(item.ProjectID)]; ok {
+			task.Tags = append(task.Tags, project)
+			usedProjects[project] = true
+		}
+		if section, ok := sections[todoistID(item.SectionID)]; ok {
+			task.Tags = append(task.Tags, section)
+		}
+		for _, label := range item.Labels {
+			task.Tags = append(task.Tags, label)
+			labels[label] = true
+		}
+
+		if item.Checked {
+			task.Status = models.TaskStatusDone
+			if completed, ok := parseAppTime(item.CompletedAt, time.UTC); ok {
+				task.CompletedAt = appJSONTime(completed)
+			}
+		}
+		if added, ok := parseAppTime(item.AddedAt, time.UTC); ok {
+			task.CreatedAt = models.JSONTime(added)
+		}
+
+		if item.Due != nil {
+			loc := time.Local
+			if tz, err := time.LoadLocation(item.Due.Timezone); err == nil && item.Due.Timezone != "" {
+				loc = tz
+			}
+			recurring := ""
+			if item.Due.IsRecurring {
+				recurring = item.Due.String
+			}
+			if warning := setTodoistDue(&task, item.Due.Date, recurring, loc); warning != "" {
+				result.Warnings = append(result.Warnings, warning)
+			}
+			if item.Due.IsRecurring && task.Recurrence == "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("recurrence %q of task %q is not supported", item.Due.String, task.Title))
+			}
+		}
+
+		index[id] = len(result.Tasks)
+		result.Tasks = append(result.Tasks, task)
+	}
+
+	// subtasks of any depth are listed on their top-level task
+	topLevel := func(id string) (int, bool) {
+		for depth := 0; depth < len(parents)+1; depth++ {
+			if i, ok := index[id]; ok {
+				return i, true
+			}
+			parent, ok := parents[id]
+			if !ok {
+				break
+			}
+			id = parent
+		}
+		return 0, false
+	}
+
+	for _, item := range backup.Items {
+		id := todoistID(item.ID)
+		if _, ok := parents[id]; !ok {
+			continue
+		}
+		if i, ok := topLevel(id); ok {
+			result.Tasks[i].Checklist = append(result.Tasks[i].Checklist, models.ChecklistItem{Text: item.Content, Checked: item.Checked})
+		}
+	}
+
+	for _, note := range backup.Notes {
+		i, ok := topLevel(todoistID(note.ItemID))
+		if !ok {
+			continue
+		}
+		comment := models.ImportedComment{Author: people[todoistID(note.PostedUID)], Body: note.Content}
+		if posted, ok := parseAppTime(note.PostedAt, time.UTC); ok {
+			comment.CreatedAt = &posted
+		}
+		result.Tasks[i].Comments = append(result.Tasks[i].Comments, comment)
+	}
+
+	result.Projects = len(usedProjects)
+	result.Labels = len(labels)
+	return result, nil
+}
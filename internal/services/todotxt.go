@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// todoTxtDateFormat is the format of the dates of a todo.txt line, they are
+// read and written in the time zone of the user
+const todoTxtDateFormat = "2006-01-02"
+
+// todoTxtDueTimeFormat is the format of a due date that is not at midnight
+const todoTxtDueTimeFormat = "2006-01-02T15:04"
+
+// todoTxtPriorities maps task priorities to todo.txt priority letters. When
+// read, B is medium and every letter after it low
+var todoTxtPriorities = map[string]string{
+	models.TaskPriorityHigh:   "A",
+	models.TaskPriorityMedium: "B",
+	models.TaskPriorityLow:    "C",
+}
+
+// todoTxtTaskEncoder writes tasks as todo.txt lines:
+//
+//	x 2026-10-18 2026-10-01 Call the plumber +house @phone due:2026-10-20 pri:A
+//	(B) 2026-10-01 Write report +work status:in+progress desc:Q3+numbers%0Aand+charts
+//	(A) 2026-10-01 Standup due:2026-10-20T17:00 rec:FREQ%3DWEEKLY%3BINTERVAL%3D2
+//
+// Tags starting with @ are contexts, the others projects. Done tasks carry
+// their priority as the pri extension as the format asks to drop the (A)
+// prefix on completion. A due date keeps its time unless it is midnight.
+// Statuses other than done and pending, descriptions and recurrence rules have
+// no place in the format, they are kept as the status, desc and rec extensions
+// with the value query escaped
+type todoTxtTaskEncoder struct {
+	w   io.Writer
+	loc *time.Location
+}
+
+func (e *todoTxtTaskEncoder) begin() error {
+	return nil
+}
+
+func (e *todoTxtTaskEncoder) encode(task *models.Task) error {
+	_, err := io.WriteString(e.w, todoTxtLine(task, e.loc)+"\n")
+	return err
+}
+
+func (e *todoTxtTaskEncoder) end() error {
+	return nil
+}
+
+func todoTxtLine(task *models.Task, loc *time.Location) string {
+	var parts []string
+	letter := todoTxtPriorities[task.Priority]
+	created := time.Time(task.CreatedAt)
+
+	if task.Status == models.TaskStatusDone {
+		parts = append(parts, "x")
+		// a single date after the x is the completion date
+		if task.CompletedAt != nil {
+			parts = append(parts, todoTxtDate(time.Time(*task.CompletedAt), loc))
+			if !created.IsZero() {
+				parts = append(parts, todoTxtDate(created, loc))
+			}
+		}
+	} else {
+		if letter != "" {
+			parts = append(parts, "("+letter+")")
+		}
+		if !created.IsZero() {
+			parts = append(parts, todoTxtDate(created, loc))
+		}
+	}
+
+	parts = append(parts, strings.Fields(task.Title)...)
+	for _, tag := range task.Tags {
+		if strings.HasPrefix(tag, "@") {
+			parts = append(parts, tag)
+		} else {
+			parts = append(parts, "+"+tag)
+		}
+	}
+	if task.DueAt != nil {
+		parts = append(parts, "due:"+todoTxtDue(time.Time(*task.DueAt), loc))
+	}
+	if task.Status == models.TaskStatusDone && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+	if task.Status != models.TaskStatusDone && task.Status != "pending" && task.Status != "" {
+		parts = append(parts, "status:"+url.QueryEscape(task.Status))
+	}
+	if task.Description != "" {
+		parts = append(parts, "desc:"+url.QueryEscape(task.Description))
+	}
+	if task.Recurrence != "" {
+		parts = append(parts, "rec:"+url.QueryEscape(task.Recurrence))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func todoTxtDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(todoTxtDateFormat)
+}
+
+func todoTxtDue(t time.Time, loc *time.Location) string {
+	if local := t.In(loc); local.Hour() != 0 || local.Minute() != 0 {
+		return local.Format(todoTxtDueTimeFormat)
+	}
+	return todoTxtDate(t, loc)
+}
+
+// decodeTodoTxtRecords reads every non-blank line of a todo.txt file as an
+// import record
+func decodeTodoTxtRecords(r io.Reader, loc *time.Location, add func(map[string]string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if err := add(todoTxtRecord(line, loc)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return invalidFile(err)
+	}
+	return nil
+}
+
+// todoTxtRecord splits a todo.txt line into the fields of a task. Projects
+// and contexts become tags and are removed from the title together with the
+// due, pri, status, desc and rec extensions, other key:value pairs stay in the
+// title
+func todoTxtRecord(line string, loc *time.Location) map[string]string {
+	fields := strings.Fields(line)
+	record := map[string]string{"status": "pending"}
+	i := 0
+
+	if i < len(fields) && fields[i] == "x" {
+		record["status"] = models.TaskStatusDone
+		i++
+		if i < len(fields) && isTodoTxtDate(fields[i]) {
+			record["completed_at"] = todoTxtRecordTime(fields[i], loc)
+			i++
+			if i < len(fields) && isTodoTxtDate(fields[i]) {
+				record["created_at"] = todoTxtRecordTime(fields[i], loc)
+				i++
+			}
+		}
+	} else {
+		if i < len(fields) && isTodoTxtPriority(fields[i]) {
+			record["priority"] = todoTxtPriority(fields[i][1:2])
+			i++
+		}
+		if i < len(fields) && isTodoTxtDate(fields[i]) {
+			record["created_at"] = todoTxtRecordTime(fields[i], loc)
+			i++
+		}
+	}
+
+	var words []string
+	tags := []string{}
+	for _, field := range fields[i:] {
+		key, value, _ := strings.Cut(field, ":")
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			tags = append(tags, field[1:])
+		case len(field) > 1 && field[0] == '@':
+			tags = append(tags, field)
+		case key == "due" && isTodoTxtDue(value):
+			record["due_at"] = todoTxtRecordDue(value, loc)
+		case key == "pri" && len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z':
+			if _, ok := record["priority"]; !ok {
+				record["priority"] = todoTxtPriority(value)
+			}
+		case (key == "status" || key == "desc" || key == "rec") && isTodoTxtEscaped(value):
+			unescaped, _ := url.QueryUnescape(value)
+			switch {
+			case key == "desc":
+				record["description"] = unescaped
+			case key == "rec":
+				record["recurrence"] = unescaped
+			case record["status"] != models.TaskStatusDone:
+				// the x prefix wins over the extension
+				record["status"] = unescaped
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+
+	record["title"] = strings.Join(words, " ")
+	if len(tags) > 0 {
+		raw, _ := json.Marshal(tags)
+		record["tags"] = string(raw)
+	}
+	return record
+}
+
+func isTodoTxtPriority(field string) bool {
+	return len(field) == 3 && field[0] == '(' && field[1] >= 'A' && field[1] <= 'Z' && field[2] == ')'
+}
+
+func isTodoTxtEscaped(value string) bool {
+	_, err := url.QueryUnescape(value)
+	return value != "" && err == nil
+}
+
+func isTodoTxtDue(value string) bool {
+	_, err := time.Parse(todoTxtDueTimeFormat, value)
+	return err == nil || isTodoTxtDate(value)
+}
+
+func isTodoTxtDate(field string) bool {
+	_, err := time.Parse(todoTxtDateFormat, field)
+	return err == nil
+}
+
+func todoTxtPriority(letter string) string {
+	switch letter {
+	case "A":
+		return models.TaskPriorityHigh
+	case "B":
+		return models.TaskPriorityMedium
+	default:
+		return models.TaskPriorityLow
+	}
+}
+
+// todoTxtRecordTime converts a date to the start of that day in loc in RFC 3339
+func todoTxtRecordTime(date string, loc *time.Location) string {
+	t, _ := time.ParseInLocation(todoTxtDateFormat, date, loc)
+	return t.Format(time.RFC3339)
+}
+
+// todoTxtRecordDue converts a due date with or without a time in loc to RFC 3339
+func todoTxtRecordDue(due string, loc *time.Location) string {
+	if t, err := time.ParseInLocation(todoTxtDueTimeFormat, due, loc); err == nil {
+		return t.Format(time.RFC3339)
+	}
+	return todoTxtRecordTime(due, loc)
+}
@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// todoTxtTasks are tasks with dates at the start of a day in loc, the dates
+// both the todo.txt and the markdown exports keep
+func todoTxtTasks(loc *time.Location) []models.Task {
+	day := func(d int) *models.JSONTime {
+		t := models.JSONTime(time.Date(2026, 10, d, 0, 0, 0, 0, loc))
+		return &t
+	}
+
+	return []models.Task{
+		{ID: 1, UserID: 1, Title: "Call the plumber", Status: models.TaskStatusDone, Priority: models.TaskPriorityHigh,
+			Tags: models.StringList{"house", "@phone"}, DueAt: day(20), CompletedAt: day(18), CreatedAt: *day(1)},
+		{ID: 2, UserID: 1, Title: "Write report url:https://example.com/q3", Status: "pending", Priority: models.TaskPriorityMedium,
+			Tags: models.StringList{"work"}, CreatedAt: *day(2)},
+		{ID: 3, UserID: 1, Title: "Отчёт за квартал", Status: "pending", Priority: models.TaskPriorityLow, DueAt: day(31), CreatedAt: *day(3)},
+		{ID: 4, UserID: 1, Title: "Someday", Status: models.TaskStatusDone, CompletedAt: day(5), CreatedAt: *day(4)},
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	// the user's midnight is the evening before in UTC, dates are those of the user
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	created := models.JSONTime(time.Date(2026, 10, 6, 0, 0, 0, 0, berlin))
+	due := models.JSONTime(time.Date(2026, 10, 20, 17, 0, 0, 0, berlin))
+	original := append(todoTxtTasks(berlin),
+		models.Task{ID: 5, UserID: 1, Title: "Review draft", Description: "Q3 numbers: 100% + charts\nsee @bob",
+			Status: "in progress", Priority: models.TaskPriorityHigh, CreatedAt: created},
+		models.Task{ID: 6, UserID: 1, Title: "Standup", Status: "pending", Priority: models.TaskPriorityHigh,
+			Recurrence: "FREQ=WEEKLY;INTERVAL=2", DueAt: &due, CreatedAt: created})
+
+	export := &MockTransferRepo{tasks: original}
+	export.On("StreamTasks", 1, models.TaskFilter{}).Return(nil)
+
+	var file bytes.Buffer
+	err = services.NewTaskTransferService(export, new(MockWorkspaceRepo), transferUsers("Europe/Berlin"), importConfig).
+		Export(&file, 1, models.TaskFilter{}, models.TransferFormatTodoTxt)
+	require.NoError(t, err)
+	assert.Equal(t, "x 2026-10-18 2026-10-01 Call the plumber +house @phone due:2026-10-20 pri:A\n"+
+		"(B) 2026-10-02 Write report url:https://example.com/q3 +work\n"+
+		"(C) 2026-10-03 Отчёт за квартал due:2026-10-31\n"+
+		"x 2026-10-05 2026-10-04 Someday\n"+
+		"(A) 2026-10-06 Review draft status:in+progress desc:Q3+numbers%3A+100%25+%2B+charts%0Asee+%40bob\n"+
+		"(A) 2026-10-06 Standup due:2026-10-20T17:00 rec:FREQ%3DWEEKLY%3BINTERVAL%3D2\n", file.String())
+
+	var imported []models.Task
+	repo := new(MockTransferRepo)
+	repo.On("ImportTasks", mock.Anything, 10).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.Task)
+	}).Return(6, nil)
+
+	service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers("Europe/Berlin"), config.Import{MaxRows: 10, BatchSize: 10})
+	report, err := service.Import(&file, models.ImportRequest{UserID: 2, Format: models.TransferFormatTodoTxt})
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Created)
+	require.Len(t, imported, len(original))
+
+	for i, want := range original {
+		got := imported[i]
+
+		assert.Equal(t, 2, got.UserID)
+		assert.Equal(t, want.Title, got.Title)
+		assert.Equal(t, want.Description, got.Description)
+		assert.Equal(t, want.Recurrence, got.Recurrence)
+		assert.Equal(t, want.Status, got.Status)
+		assert.Equal(t, want.Priority, got.Priority)
+		assert.Equal(t, want.Tags, got.Tags)
+		assertSameDay(t, want.DueAt, got.DueAt)
+		assertSameDay(t, want.CompletedAt, got.CompletedAt)
+		assertSameDay(t, &want.CreatedAt, &got.CreatedAt)
+	}
+}
+
+// assertSameDay compares optional times as instants, parsed times carry
+// another *time.Location than the expected ones
+func assertSameDay(t *testing.T, want, got *models.JSONTime) {
+	t.Helper()
+	if want == nil {
+		assert.Nil(t, got)
+		return
+	}
+	require.NotNil(t, got)
+	assert.True(t, time.Time(*want).Equal(time.Time(*got)), "want %v, got %v", time.Time(*want), time.Time(*got))
+}
+
+func TestImportTodoTxt(t *testing.T) {
+	file := "\uFEFF(D) Buy milk @store +errands\n" +
+		"\n" +
+		"x Pay rent due:2026-11-01 status:blocked\n" +
+		"2026-10-01 Renew passport due:soon desc:%zz\n"
+
+	var imported []models.Task
+	repo := new(MockTransferRepo)
+	repo.On("ImportTasks", mock.Anything, 10).Run(func(args mock.Arguments) {
+		imported = args.Get(0).([]models.Task)
+	}).Return(3, nil)
+
+	service := services.NewTaskTransferService(repo, new(MockWorkspaceRepo), transferUsers("Europe/Berlin"), config.Import{MaxRows: 10, BatchSize: 10})
+	report, err := service.Import(strings.NewReader(file), models.ImportRequest{UserID: 1, Format: models.TransferFormatTodoTxt})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	require.Len(t, imported, 3)
+
+	assert.Equal(t, "Buy milk", imported[0].Title)
+	assert.Equal(t, models.TaskPriorityLow, imported[0].Priority)
+	assert.Equal(t, models.StringList{"@store", "errands"}, imported[0].Tags)
+	assert.True(t, time.Time(imported[0].CreatedAt).IsZero())
+
+	// the x prefix wins over the status extension
+	assert.Equal(t, "Pay rent", imported[1].Title)
+	assert.Equal(t, models.TaskStatusDone, imported[1].Status)
+	assert.Nil(t, imported[1].CompletedAt)
+	require.NotNil(t, imported[1].DueAt)
+	// midnight in Berlin
+	assert.True(t, time.Time(*imported[1].DueAt).Equal(time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)))
+
+	// a due value that is not a date and a badly escaped desc stay in the title
+	assert.Equal(t, "Renew passport due:soon desc:%zz", imported[2].Title)
+	assert.Empty(t, imported[2].Description)
+	assert.Equal(t, "pending", imported[2].Status)
+	assert.Nil(t, imported[2].DueAt)
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+)
+
+// TrelloImporter reads the JSON export of a Trello board. The board and the
+// list of a card become its tags together with the labels, cards with a
+// completed due date are done. Archived cards and lists are left out
+type TrelloImporter struct{}
+
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID               string `json:"id"`
+		Name             string `json:"name"`
+		Desc             string `json:"desc"`
+		IDList           string `json:"idList"`
+		Closed           bool   `json:"closed"`
+		Due              string `json:"due"`
+		DueComplete      bool   `json:"dueComplete"`
+		DateLastActivity string `json:"dateLastActivity"`
+		Labels           []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+		Date string `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+		MemberCreator struct {
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
+	} `json:"actions"`
+}
+
+func (TrelloImporter) Parse(r io.Reader, fileName string) (*models.AppImport, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, invalidFile(err)
+	}
+	if board.Cards == nil {
+		return nil, invalidFile(fmt.Errorf("not a Trello board export, no cards"))
+	}
+
+	lists := make(map[string]string, len(board.Lists))
+	for _, list := range board.Lists {
+		if !list.Closed {
+			lists[list.ID] = list.Name
+		}
+	}
+
+	result := &models.AppImport{}
+	index := make(map[string]int, len(board.Cards))
+	usedLists := make(map[string]bool)
+	labels := make(map[string]bool)
+	archived := 0
+
+	for _, card := range board.Cards {
+		list, ok := lists[card.IDList]
+		if card.Closed || !ok {
+			archived++
+			continue
+		}
+
+		task := models.ImportedTask{Task: models.Task{
+			Title:       card.Name,
+			Description: card.Desc,
+			Tags:        models.StringList{board.Name, list},
+		}}
+		externalID := "trello:" + card.ID
+		task.ExternalID = &externalID
+		usedLists[list] = true
+
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			task.Tags = append(task.Tags, name)
+			labels[name] = true
+		}
+
+		if card.Due != "" {
+			if due, ok := parseAppTime(card.Due, time.UTC); ok {
+				task.DueAt = appJSONTime(due)
+			}
+		}
+		if card.DueComplete {
+			task.Status = models.TaskStatusDone
+			if done, ok := parseAppTime(card.DateLastActivity, time.UTC); ok {
+				task.CompletedAt = appJSONTime(done)
+			}
+		}
+
+		index[card.ID] = len(result.Tasks)
+		result.Tasks = append(result.Tasks, task)
+	}
+
+	checklists := slices.Clone(board.Checklists)
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+	for _, checklist := range checklists {
+		i, ok := index[checklist.IDCard]
+		if !ok {
+			continue
+		}
+		items := slices.Clone(checklist.CheckItems)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			result.Tasks[i].Checklist = append(result.Tasks[i].Checklist, models.ChecklistItem{Text: item.Name, Checked: item.State == "complete"})
+		}
+	}
+
+	// actions are exported newest first
+	for j := len(board.Actions) - 1; j >= 0; j-- {
+		action := board.Actions[j]
+		i, ok := index[action.Data.Card.ID]
+		if action.Type != "commentCard" || !ok {
+			continue
+		}
+		comment := models.ImportedComment{Author: action.MemberCreator.FullName, Body: action.Data.Text}
+		if posted, ok := parseAppTime(action.Date, time.UTC); ok {
+			comment.CreatedAt = &posted
+		}
+		result.Tasks[i].Comments = append(result.Tasks[i].Comments, comment)
+	}
+
+	if archived > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d archived cards left out", archived))
+	}
+	result.Projects = len(usedLists)
+	result.Labels = len(labels)
+	return result, nil
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/rs/zerolog"
+)
+
+const (
+	webhookSecretSize = 32
+	// deliveryLogLimit is the number of deliveries returned by the delivery log
+	deliveryLogLimit = 50
+	// maxErrorLength caps the stored error message of a failed attempt
+	maxErrorLength = 500
+)
+
+type IWebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) error
+	GetWebhooks(userID int) ([]models.Webhook, error)
+	GetWebhook(id, userID int) (*models.Webhook, error)
+	UpdateWebhook(webhook *models.Webhook) error
+	DeleteWebhook(id, userID int) error
+	EnqueueDeliveries(outboxID int64, eventType string, payload types.JSONText, ownerID int, workspaceID *int, now time.Time) (int64, error)
+	EnqueueUserDeliveries(eventType string, payload types.JSONText, userID int, now time.Time) (int64, error)
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	ClaimDeliveries(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	RecordAttempt(delivery *models.WebhookDelivery, succeeded bool, disableAfter int, now time.Time) (bool, error)
+	GetDeliveries(webhookID, userID, limit int) ([]models.WebhookDelivery, error)
+}
+
+// WebhookService posts task events to user-defined URLs. Events are queued in
+// webhook_deliveries and sent by a background worker, failed attempts are
+// retried with exponential backoff and webhooks failing too often are disabled
+type WebhookService struct {
+	repo   IWebhookRepository
+	cfg    config.Webhooks
+	clock  clock.Clock
+	client *http.Client
+	log    zerolog.Logger
+}
+
+func NewWebhookService(repo IWebhookRepository, cfg config.Webhooks, clock clock.Clock) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		cfg:    cfg,
+		clock:  clock,
+		client: newWebhookClient(cfg),
+		log:    logger.GetLogger(),
+	}
+}
+
+// newWebhookClient returns the client webhooks are sent with. Unless private
+// networks are allowed, the dialer refuses addresses that aren't public after
+// the name is resolved, so a webhook can't reach the internal network, and
+// redirects are not followed
+func newWebhookClient(cfg config.Webhooks) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addr.Addr()) {
+				return fmt.Errorf("%w: %s", errWebhookAddress, addr.Addr())
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// a redirect could lead anywhere, its response is recorded as a failure
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+var errWebhookAddress = errors.New("webhook address not allowed")
+
+// cgnatPrefix is the shared address space of carrier-grade NAT, RFC 6598
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnatPrefix.Contains(addr)
+}
+
+// CreateWebhook stores a new webhook of the user. Without a secret a random one
+// is generated, the returned webhook is the only place it is shown
+func (s *WebhookService) CreateWebhook(webhook *models.Webhook) error {
+	if err := helpers.ValidateWebhookURL(webhook.URL); err != nil {
+		return err
+	}
+	if err := helpers.ValidateWebhookEventTypes(webhook.EventTypes); err != nil {
+		return err
+	}
+
+	if webhook.Secret == "" {
+		buf := make([]byte, webhookSecretSize)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		webhook.Secret = hex.EncodeToString(buf)
+	}
+	webhook.Enabled = true
+
+	return s.repo.CreateWebhook(webhook)
+}
+
+func (s *WebhookService) GetWebhooks(userID int) ([]models.Webhook, error) {
+	webhooks, err := s.repo.GetWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(id, userID int) (*models.Webhook, error) {
+	webhook, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// UpdateWebhook changes the given fields. Enabling a webhook clears its
+// failure streak, so a webhook disabled after failures can be switched back on
+func (s *WebhookService) UpdateWebhook(id, userID int, update models.WebhookUpdate) (*models.Webhook, error) {
+	webhook, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		if err := helpers.ValidateWebhookURL(*update.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *update.URL
+	}
+
+	if update.EventTypes != nil {
+		if err := helpers.ValidateWebhookEventTypes(update.EventTypes); err != nil {
+			return nil, err
+		}
+		webhook.EventTypes = update.EventTypes
+	}
+
+	if update.Enabled != nil && *update.Enabled != webhook.Enabled {
+		webhook.Enabled = *update.Enabled
+		webhook.ConsecutiveFailures = 0
+		webhook.DisabledAt = nil
+		if !webhook.Enabled {
+			now := s.clock.Now()
+			webhook.DisabledAt = &now
+		}
+	}
+
+	if err := s.repo.UpdateWebhook(webhook); err != nil {
+		if err == repository.ErrNoRowsUpdated {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(id, userID int) error {
+	err := s.repo.DeleteWebhook(id, userID)
+	if err == repository.ErrNoRowsUpdated {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func (s *WebhookService) GetDeliveries(id, userID int) ([]models.WebhookDelivery, error) {
+	if _, err := s.getWebhook(id, userID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.GetDeliveries(id, userID, deliveryLogLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// SendTestEvent sends a webhook.test event right away and returns the
+// recorded delivery. A failed test is retried like any other delivery
+func (s *WebhookService) SendTestEvent(id, userID int) (*models.WebhookDelivery, error) {
+	webhook, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(models.WebhookPayload{
+		Event:      models.WebhookEventTest,
+		ActorID:    userID,
+		OccurredAt: s.clock.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the delivery is created already claimed, so the worker doesn't send it
+	// at the same time
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventType:     models.WebhookEventTest,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: s.leaseUntil(),
+		URL:           webhook.URL,
+		Secret:        webhook.Secret,
+	}
+	if err := s.repo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliver(context.Background(), delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// HandleOutbox queues a task change for the webhooks subscribed to it, the
+// message id keeps a webhook from getting the same change twice
+func (s *WebhookService) HandleOutbox(msg *models.OutboxMessage) error {
+	body := models.WebhookPayload{
+		Event:      msg.Type,
+		TaskID:     msg.TaskID,
+		ActorID:    msg.ActorID,
+		OccurredAt: msg.CreatedAt,
+	}
+	if msg.Type != models.TaskEventDeleted {
+		body.Task = msg.Payload
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.EnqueueDeliveries(msg.ID, msg.Type, payload, msg.UserID, msg.WorkspaceID, s.clock.Now())
+	return err
+}
+
+// SendReminder queues a task.reminder event for the webhooks of the reminder's
+// user subscribed to it
+func (s *WebhookService) SendReminder(reminder *models.Reminder) error {
+	now := s.clock.Now()
+	payload, err := json.Marshal(models.WebhookPayload{
+		Event:      models.WebhookEventReminder,
+		TaskID:     reminder.TaskID,
+		OccurredAt: now,
+		Reminder:   reminder,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.EnqueueUserDeliveries(models.WebhookEventReminder, payload, reminder.UserID, now)
+	return err
+}
+
+// Run sends due deliveries until ctx is done
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.DeliverDue(ctx)
+		}
+	}
+}
+
+// DeliverDue claims the deliveries due now and sends them one after another.
+// The lease of a batch lasts until its last delivery timed out, a crashed
+// instance's deliveries are picked up again when the lease runs out
+func (s *WebhookService) DeliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := s.repo.ClaimDeliveries(s.clock.Now(), s.leaseUntil(), s.cfg.BatchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to claim webhook deliveries")
+			return
+		}
+
+		for i := range deliveries {
+			if err := s.deliver(ctx, &deliveries[i]); err != nil {
+				s.log.Error().Err(err).Int64("delivery_id", deliveries[i].ID).Msg("Failed to record webhook delivery")
+			}
+		}
+
+		if len(deliveries) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	code, sendErr := s.send(ctx, delivery)
+
+	now := s.clock.Now()
+	delivery.Attempts++
+	delivery.ResponseCode = nil
+	delivery.Error = nil
+	if code != 0 {
+		delivery.ResponseCode = &code
+	}
+
+	succeeded := sendErr == nil
+	switch {
+	case succeeded:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= s.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts, s.cfg.RetryBaseDelay, s.cfg.RetryMaxDelay))
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		delivery.Error = &message
+	}
+
+	enabled, err := s.repo.RecordAttempt(delivery, succeeded, s.cfg.DisableAfter, now)
+	if err != nil {
+		return err
+	}
+	if !enabled && !succeeded {
+		s.log.Warn().Int("webhook_id", delivery.WebhookID).Msg("Webhook disabled after repeated failures")
+	}
+
+	return nil
+}
+
+// send posts the payload signed with the webhook secret. Any 2xx response is a
+// success, the response code is returned whenever there was a response
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Signature", Sign(delivery.Secret, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// leaseUntil gives every delivery of a batch the full request timeout and one
+// more timeout for recording the attempts
+func (s *WebhookService) leaseUntil() time.Time {
+	return s.clock.Now().Add(time.Duration(s.cfg.BatchSize+1) * s.cfg.Timeout)
+}
+
+func (s *WebhookService) getWebhook(id, userID int) (*models.Webhook, error) {
+	webhook, err := s.repo.GetWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// Sign returns the X-Signature header value of a webhook body: the hex
+// HMAC-SHA256 of the body keyed with the webhook secret, prefixed with "sha256="
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
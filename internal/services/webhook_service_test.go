@@ -0,0 +1,426 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/config"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookRepo struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepo) CreateWebhook(webhook *models.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) GetWebhooks(userID int) ([]models.Webhook, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepo) GetWebhook(id, userID int) (*models.Webhook, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepo) UpdateWebhook(webhook *models.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) DeleteWebhook(id, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) EnqueueDeliveries(outboxID int64, eventType string, payload types.JSONText, ownerID int, workspaceID *int, now time.Time) (int64, error) {
+	args := m.Called(outboxID, eventType, payload, ownerID, workspaceID, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWebhookRepo) EnqueueUserDeliveries(eventType string, payload types.JSONText, userID int, now time.Time) (int64, error) {
+	args := m.Called(eventType, payload, userID, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWebhookRepo) CreateDelivery(delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) ClaimDeliveries(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(now, leaseUntil, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepo) RecordAttempt(delivery *models.WebhookDelivery, succeeded bool, disableAfter int, now time.Time) (bool, error) {
+	args := m.Called(delivery, succeeded, disableAfter, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepo) GetDeliveries(webhookID, userID, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(webhookID, userID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepo) GetDeliveriesByUserID(userID int) ([]models.WebhookDelivery, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+var webhookConfig = config.Webhooks{
+	Timeout:        5 * time.Second,
+	PollInterval:   time.Second,
+	BatchSize:      10,
+	MaxAttempts:    3,
+	RetryBaseDelay: 30 * time.Second,
+	RetryMaxDelay:  time.Hour,
+	DisableAfter:   5,
+	// the receivers listen on loopback
+	AllowPrivateNetworks: true,
+}
+
+// webhookReceiver is an httptest server answering with status and recording
+// the requests it got
+type webhookReceiver struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	receiver := &webhookReceiver{status: status}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		receiver.mu.Unlock()
+
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func TestCreateWebhook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Secret is generated", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockWebhookRepo)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+		webhook := &models.Webhook{UserID: 1, URL: "https://ci.example.com/hook", EventTypes: models.StringList{models.TaskEventCreated}}
+		repo.On("CreateWebhook", webhook).Return(nil)
+
+		err := service.CreateWebhook(webhook)
+		assert.NoError(t, err)
+		assert.Len(t, webhook.Secret, 64)
+		assert.True(t, webhook.Enabled)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockWebhookRepo)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+		err := service.CreateWebhook(&models.Webhook{UserID: 1, URL: "ftp://ci.example.com", EventTypes: models.StringList{models.TaskEventCreated}})
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "CreateWebhook", mock.Anything)
+	})
+
+	t.Run("Unknown event type", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockWebhookRepo)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+		err := service.CreateWebhook(&models.Webhook{UserID: 1, URL: "https://ci.example.com/hook", EventTypes: models.StringList{"task.archived"}})
+		assert.ErrorAs(t, err, &baseErr)
+	})
+}
+
+func TestGetWebhooksHidesSecret(t *testing.T) {
+	t.Parallel()
+	repo := new(MockWebhookRepo)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+	repo.On("GetWebhooks", 1).Return([]models.Webhook{{ID: 5, UserID: 1, Secret: "s3cret"}}, nil)
+
+	webhooks, err := service.GetWebhooks(1)
+	assert.NoError(t, err)
+	assert.Empty(t, webhooks[0].Secret)
+}
+
+func TestEnableWebhookResetsFailures(t *testing.T) {
+	t.Parallel()
+	repo := new(MockWebhookRepo)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+	disabledAt := time.Now().Add(-time.Hour)
+	repo.On("GetWebhook", 5, 1).Return(&models.Webhook{ID: 5, UserID: 1, Enabled: false, ConsecutiveFailures: 5, DisabledAt: &disabledAt}, nil)
+	repo.On("UpdateWebhook", mock.MatchedBy(func(w *models.Webhook) bool {
+		return w.Enabled && w.ConsecutiveFailures == 0 && w.DisabledAt == nil
+	})).Return(nil)
+
+	enabled := true
+	webhook, err := service.UpdateWebhook(5, 1, models.WebhookUpdate{Enabled: &enabled})
+	assert.NoError(t, err)
+	assert.True(t, webhook.Enabled)
+	repo.AssertExpectations(t)
+}
+
+func TestForeignWebhook(t *testing.T) {
+	t.Parallel()
+	repo := new(MockWebhookRepo)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: time.Now()})
+
+	repo.On("GetWebhook", 5, 2).Return((*models.Webhook)(nil), nil)
+
+	_, err := service.SendTestEvent(5, 2)
+	assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+}
+
+func TestWebhookOutboxHandler(t *testing.T) {
+	t.Parallel()
+	repo := new(MockWebhookRepo)
+	now := time.Unix(1700000000, 0)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+	workspaceID := 10
+	var payload types.JSONText
+	repo.On("EnqueueDeliveries", int64(7), models.TaskEventUpdated, mock.Anything, 1, &workspaceID, now).
+		Run(func(args mock.Arguments) { payload = args.Get(2).(types.JSONText) }).
+		Return(int64(2), nil)
+
+	require.NoError(t, service.HandleOutbox(&models.OutboxMessage{
+		ID:          7,
+		Type:        models.TaskEventUpdated,
+		TaskID:      42,
+		UserID:      1,
+		WorkspaceID: &workspaceID,
+		ActorID:     3,
+		Payload:     []byte(`{"id":42,"title":"Release"}`),
+		CreatedAt:   now,
+	}))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(payload, &body))
+	assert.Equal(t, models.TaskEventUpdated, body["event"])
+	assert.Equal(t, float64(3), body["actor_id"])
+	assert.Equal(t, "Release", body["task"].(map[string]any)["title"])
+}
+
+func TestDeliverDue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Signed delivery succeeds", func(t *testing.T) {
+		t.Parallel()
+		receiver := newWebhookReceiver(t, http.StatusNoContent)
+		repo := new(MockWebhookRepo)
+		now := time.Unix(1700000000, 0)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+		payload := types.JSONText(`{"event":"task.created","task_id":42}`)
+		repo.On("ClaimDeliveries", now, now.Add(55*time.Second), 10).Return([]models.WebhookDelivery{
+			{ID: 7, WebhookID: 5, EventType: models.TaskEventCreated, Payload: payload, Status: models.DeliveryPending, URL: receiver.URL, Secret: "s3cret"},
+		}, nil)
+		repo.On("RecordAttempt", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			return d.Status == models.DeliverySucceeded && d.Attempts == 1 && *d.ResponseCode == http.StatusNoContent && d.DeliveredAt != nil
+		}), true, 5, now).Return(true, nil)
+
+		service.DeliverDue(context.Background())
+
+		require.Len(t, receiver.requests, 1)
+		request := receiver.requests[0]
+		assert.Equal(t, services.Sign("s3cret", payload), request.Header.Get("X-Signature"))
+		assert.Equal(t, models.TaskEventCreated, request.Header.Get("X-Webhook-Event"))
+		assert.Equal(t, "7", request.Header.Get("X-Webhook-Delivery"))
+		assert.JSONEq(t, string(payload), string(receiver.bodies[0]))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Failure is retried with backoff", func(t *testing.T) {
+		t.Parallel()
+		receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+		repo := new(MockWebhookRepo)
+		now := time.Unix(1700000000, 0)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+		repo.On("ClaimDeliveries", now, mock.Anything, 10).Return([]models.WebhookDelivery{
+			{ID: 7, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, Attempts: 1, URL: receiver.URL, Secret: "s3cret"},
+		}, nil)
+		repo.On("RecordAttempt", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			// second attempt failed: 30s doubled once
+			return d.Status == models.DeliveryPending && d.Attempts == 2 &&
+				d.NextAttemptAt.Equal(now.Add(time.Minute)) && *d.ResponseCode == http.StatusInternalServerError && d.Error != nil
+		}), false, 5, now).Return(true, nil)
+
+		service.DeliverDue(context.Background())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Last attempt fails the delivery", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockWebhookRepo)
+		now := time.Unix(1700000000, 0)
+		service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+		repo.On("ClaimDeliveries", now, mock.Anything, 10).Return([]models.WebhookDelivery{
+			{ID: 7, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, Attempts: 2, URL: "http://127.0.0.1:1/unreachable"},
+		}, nil)
+		repo.On("RecordAttempt", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			return d.Status == models.DeliveryFailed && d.Attempts == 3 && d.ResponseCode == nil && d.Error != nil
+		}), false, 5, now).Return(false, nil)
+
+		service.DeliverDue(context.Background())
+		repo.AssertExpectations(t)
+	})
+}
+
+// steppingClock is a clock tests move forward while the code under test runs
+type steppingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *steppingClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func TestDeliverDueSlowReceivers(t *testing.T) {
+	t.Parallel()
+	cfg := webhookConfig
+	cfg.BatchSize = 3
+	now := time.Unix(1700000000, 0)
+	clock := &steppingClock{now: now}
+
+	// every receiver answers just before the request timeout
+	var answered []time.Time
+	var mu sync.Mutex
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		answered = append(answered, clock.Advance(cfg.Timeout))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	var leaseUntil time.Time
+	repo := new(MockWebhookRepo)
+	repo.On("ClaimDeliveries", now, mock.Anything, 3).Run(func(args mock.Arguments) {
+		leaseUntil = args.Get(1).(time.Time)
+	}).Return([]models.WebhookDelivery{
+		{ID: 7, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, URL: receiver.URL},
+		{ID: 8, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, URL: receiver.URL},
+		{ID: 9, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, URL: receiver.URL},
+	}, nil).Once()
+	repo.On("ClaimDeliveries", mock.Anything, mock.Anything, 3).Return([]models.WebhookDelivery(nil), nil)
+	repo.On("RecordAttempt", mock.Anything, true, 5, mock.Anything).Return(true, nil)
+
+	services.NewWebhookService(repo, cfg, clock).DeliverDue(context.Background())
+
+	// no delivery may be claimed again by another instance while the batch is sent
+	require.Len(t, answered, 3)
+	for _, at := range answered {
+		assert.True(t, at.Before(leaseUntil), "answered at %v, lease until %v", at, leaseUntil)
+	}
+	repo.AssertNumberOfCalls(t, "RecordAttempt", 3)
+}
+
+func TestSendTestEvent(t *testing.T) {
+	t.Parallel()
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	repo := new(MockWebhookRepo)
+	now := time.Unix(1700000000, 0)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+	repo.On("GetWebhook", 5, 1).Return(&models.Webhook{ID: 5, UserID: 1, URL: receiver.URL, Secret: "s3cret"}, nil)
+	repo.On("CreateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.EventType == models.WebhookEventTest && d.NextAttemptAt.After(now)
+	})).Return(nil)
+	repo.On("RecordAttempt", mock.Anything, true, 5, now).Return(true, nil)
+
+	delivery, err := service.SendTestEvent(5, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, http.StatusOK, *delivery.ResponseCode)
+	require.Len(t, receiver.requests, 1)
+	assert.Equal(t, models.WebhookEventTest, receiver.requests[0].Header.Get("X-Webhook-Event"))
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+
+	for _, url := range []string{"http://127.0.0.1:8080/", "http://localhost:5432/", "http://10.0.0.1/", "http://169.254.169.254/latest/meta-data", "http://[::1]/", "http://0.0.0.0/"} {
+		t.Run(url, func(t *testing.T) {
+			t.Parallel()
+			cfg := webhookConfig
+			cfg.AllowPrivateNetworks = false
+			repo := new(MockWebhookRepo)
+			service := services.NewWebhookService(repo, cfg, &FakeClock{now: now})
+
+			repo.On("ClaimDeliveries", now, mock.Anything, 10).Return([]models.WebhookDelivery{
+				{ID: 7, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, URL: url},
+			}, nil)
+			repo.On("RecordAttempt", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+				return d.ResponseCode == nil && d.Error != nil && strings.Contains(*d.Error, "webhook address not allowed")
+			}), false, 5, now).Return(true, nil)
+
+			service.DeliverDue(context.Background())
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	t.Parallel()
+	target := newWebhookReceiver(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+
+	repo := new(MockWebhookRepo)
+	now := time.Unix(1700000000, 0)
+	service := services.NewWebhookService(repo, webhookConfig, &FakeClock{now: now})
+
+	repo.On("ClaimDeliveries", now, mock.Anything, 10).Return([]models.WebhookDelivery{
+		{ID: 7, WebhookID: 5, Payload: types.JSONText(`{}`), Status: models.DeliveryPending, URL: redirect.URL},
+	}, nil)
+	repo.On("RecordAttempt", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return *d.ResponseCode == http.StatusTemporaryRedirect && d.Error != nil
+	}), false, 5, now).Return(true, nil)
+
+	service.DeliverDue(context.Background())
+	assert.Empty(t, target.requests)
+	repo.AssertExpectations(t)
+}
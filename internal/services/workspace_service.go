@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/daioru/todo-app/internal/helpers"
+	"github.com/daioru/todo-app/internal/logger"
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/pkg/clock"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// IWorkspaceMembership resolves the role of a user in a workspace,
+// an empty role means the user is not a member
+type IWorkspaceMembership interface {
+	GetMemberRole(workspaceID, userID int) (models.WorkspaceRole, error)
+}
+
+type IWorkspaceRepository interface {
+	IWorkspaceMembership
+	CreateWorkspace(workspace *models.Workspace, ownerID int) error
+	GetWorkspace(id int) (*models.Workspace, error)
+	GetWorkspacesByUserID(userID int) ([]models.Workspace, error)
+	RenameWorkspace(id int, name string) error
+	DeleteWorkspace(id int) error
+	GetMembers(workspaceID int) ([]models.WorkspaceMember, error)
+	UpdateMemberRole(workspaceID, userID int, role models.WorkspaceRole) error
+	RemoveMember(workspaceID, userID int) error
+	CreateInvitation(invitation *models.WorkspaceInvitation) error
+	GetInvitation(id int) (*models.WorkspaceInvitation, error)
+	GetPendingInvitation(workspaceID, inviteeID int) (*models.WorkspaceInvitation, error)
+	GetPendingInvitationsByUserID(userID int) ([]models.WorkspaceInvitation, error)
+	AcceptInvitation(invitation *models.WorkspaceInvitation, now time.Time) error
+	DeclineInvitation(id int, now time.Time) error
+}
+
+type IWorkspaceUserRepository interface {
+	GetUserByUsername(username string) (*models.User, error)
+}
+
+type WorkspaceService struct {
+	repo  IWorkspaceRepository
+	users IWorkspaceUserRepository
+	clock clock.Clock
+	log   zerolog.Logger
+}
+
+func NewWorkspaceService(repo IWorkspaceRepository, users IWorkspaceUserRepository, clock clock.Clock) *WorkspaceService {
+	return &WorkspaceService{
+		repo:  repo,
+		users: users,
+		clock: clock,
+		log:   logger.GetLogger(),
+	}
+}
+
+func (s *WorkspaceService) CreateWorkspace(userID int, name string) (*models.Workspace, error) {
+	name, err := helpers.ValidateWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &models.Workspace{Name: name}
+	if err := s.repo.CreateWorkspace(workspace, userID); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+func (s *WorkspaceService) ListWorkspaces(userID int) ([]models.Workspace, error) {
+	return s.repo.GetWorkspacesByUserID(userID)
+}
+
+func (s *WorkspaceService) GetWorkspace(workspaceID, userID int) (*models.Workspace, error) {
+	role, err := s.authorize(workspaceID, userID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.repo.GetWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	workspace.Role = role
+	return workspace, nil
+}
+
+func (s *WorkspaceService) RenameWorkspace(workspaceID, userID int, name string) (*models.Workspace, error) {
+	name, err := helpers.ValidateWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authorize(workspaceID, userID, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RenameWorkspace(workspaceID, name); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	return s.GetWorkspace(workspaceID, userID)
+}
+
+// DeleteWorkspace removes the workspace with all its tasks, only the owner may do it
+func (s *WorkspaceService) DeleteWorkspace(workspaceID, userID int) error {
+	if _, err := s.authorize(workspaceID, userID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteWorkspace(workspaceID); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrWorkspaceNotFound
+		}
+		return err
+	}
+
+	s.log.Info().Int("workspace_id", workspaceID).Int("user_id", userID).Msg("Workspace deleted")
+	return nil
+}
+
+func (s *WorkspaceService) ListMembers(workspaceID, userID int) ([]models.WorkspaceMember, error) {
+	if _, err := s.authorize(workspaceID, userID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMembers(workspaceID)
+}
+
+// UpdateMemberRole changes the role of a member. Admins manage members and
+// viewers, only the owner may grant or take away the admin role
+func (s *WorkspaceService) UpdateMemberRole(workspaceID, actorID, memberID int, role models.WorkspaceRole) error {
+	if err := helpers.ValidateGrantedRole(role); err != nil {
+		return err
+	}
+
+	actorRole, err := s.authorize(workspaceID, actorID, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.memberRole(workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if (current == models.RoleAdmin || role == models.RoleAdmin) && actorRole != models.RoleOwner {
+		return ErrWorkspaceForbidden
+	}
+
+	if err := s.repo.UpdateMemberRole(workspaceID, memberID, role); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RemoveMember removes a member from the workspace. Every member may leave,
+// removing others follows the same rules as changing their role
+func (s *WorkspaceService) RemoveMember(workspaceID, actorID, memberID int) error {
+	actorRole, err := s.authorize(workspaceID, actorID, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.memberRole(workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if actorID != memberID {
+		if !actorRole.CanManage() {
+			return ErrWorkspaceForbidden
+		}
+		if current == models.RoleAdmin && actorRole != models.RoleOwner {
+			return ErrWorkspaceForbidden
+		}
+	}
+
+	if err := s.repo.RemoveMember(workspaceID, memberID); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Invite invites a user by username, the role defaults to member
+func (s *WorkspaceService) Invite(workspaceID, actorID int, username string, role models.WorkspaceRole) (*models.WorkspaceInvitation, error) {
+	if role == "" {
+		role = models.RoleMember
+	}
+	if err := helpers.ValidateGrantedRole(role); err != nil {
+		return nil, err
+	}
+
+	actorRole, err := s.authorize(workspaceID, actorID, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.RoleAdmin && actorRole != models.RoleOwner {
+		return nil, ErrWorkspaceForbidden
+	}
+
+	invitee, err := s.users.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if invitee == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	existing, err := s.repo.GetMemberRole(workspaceID, invitee.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" {
+		return nil, ErrAlreadyMember
+	}
+
+	pending, err := s.repo.GetPendingInvitation(workspaceID, invitee.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, ErrInvitationPending
+	}
+
+	invitation := &models.WorkspaceInvitation{
+		WorkspaceID: workspaceID,
+		InviterID:   &actorID,
+		InviteeID:   invitee.ID,
+		Role:        role,
+	}
+	if err := s.repo.CreateInvitation(invitation); err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// ListInvitations returns the pending invitations addressed to the user
+func (s *WorkspaceService) ListInvitations(userID int) ([]models.WorkspaceInvitation, error) {
+	return s.repo.GetPendingInvitationsByUserID(userID)
+}
+
+func (s *WorkspaceService) AcceptInvitation(invitationID, userID int) error {
+	invitation, err := s.pendingInvitation(invitationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.AcceptInvitation(invitation, s.clock.Now()); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *WorkspaceService) DeclineInvitation(invitationID, userID int) error {
+	if _, err := s.pendingInvitation(invitationID, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeclineInvitation(invitationID, s.clock.Now()); err != nil {
+		if errors.Is(err, repository.ErrNoRowsUpdated) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// authorize returns the user's role in the workspace if it's at least min.
+// Non-members get ErrWorkspaceNotFound so workspace ids of other teams don't leak
+func (s *WorkspaceService) authorize(workspaceID, userID int, min models.WorkspaceRole) (models.WorkspaceRole, error) {
+	return authorizeWorkspace(s.repo, workspaceID, userID, min)
+}
+
+func (s *WorkspaceService) memberRole(workspaceID, userID int) (models.WorkspaceRole, error) {
+	role, err := s.repo.GetMemberRole(workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrMemberNotFound
+	}
+	if role == models.RoleOwner {
+		return "", ErrOwnerImmutable
+	}
+	return role, nil
+}
+
+// pendingInvitation loads an invitation that the user may still answer. Invitations
+// of other users are reported as missing
+func (s *WorkspaceService) pendingInvitation(invitationID, userID int) (*models.WorkspaceInvitation, error) {
+	invitation, err := s.repo.GetInvitation(invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.InviteeID != userID || invitation.Status != models.InvitationPending {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, nil
+}
+
+func authorizeWorkspace(membership IWorkspaceMembership, workspaceID, userID int, min models.WorkspaceRole) (models.WorkspaceRole, error) {
+	role, err := membership.GetMemberRole(workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrWorkspaceNotFound
+	}
+	if !role.AtLeast(min) {
+		return "", ErrWorkspaceForbidden
+	}
+	return role, nil
+}
@@ -0,0 +1,557 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daioru/todo-app/internal/models"
+	"github.com/daioru/todo-app/internal/repository"
+	"github.com/daioru/todo-app/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWorkspaceRepo struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceRepo) CreateWorkspace(workspace *models.Workspace, ownerID int) error {
+	args := m.Called(workspace, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) GetWorkspace(id int) (*models.Workspace, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) GetWorkspacesByUserID(userID int) ([]models.Workspace, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) RenameWorkspace(id int, name string) error {
+	args := m.Called(id, name)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) DeleteWorkspace(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) GetMemberRole(workspaceID, userID int) (models.WorkspaceRole, error) {
+	args := m.Called(workspaceID, userID)
+	return args.Get(0).(models.WorkspaceRole), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) GetMembers(workspaceID int) ([]models.WorkspaceMember, error) {
+	args := m.Called(workspaceID)
+	return args.Get(0).([]models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) UpdateMemberRole(workspaceID, userID int, role models.WorkspaceRole) error {
+	args := m.Called(workspaceID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) RemoveMember(workspaceID, userID int) error {
+	args := m.Called(workspaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) CreateInvitation(invitation *models.WorkspaceInvitation) error {
+	args := m.Called(invitation)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) GetInvitation(id int) (*models.WorkspaceInvitation, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) GetPendingInvitation(workspaceID, inviteeID int) (*models.WorkspaceInvitation, error) {
+	args := m.Called(workspaceID, inviteeID)
+	return args.Get(0).(*models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) GetPendingInvitationsByUserID(userID int) ([]models.WorkspaceInvitation, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceRepo) AcceptInvitation(invitation *models.WorkspaceInvitation, now time.Time) error {
+	args := m.Called(invitation, now)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepo) DeclineInvitation(id int, now time.Time) error {
+	args := m.Called(id, now)
+	return args.Error(0)
+}
+
+const (
+	teamWorkspace  = 10
+	otherWorkspace = 20
+
+	ownerID    = 1
+	adminID    = 2
+	memberID   = 3
+	viewerID   = 4
+	outsiderID = 5
+)
+
+// withTeam makes the mock answer membership lookups for a workspace with one user
+// per role. outsiderID belongs only to otherWorkspace
+func withTeam(repo *MockWorkspaceRepo) {
+	roles := map[int]models.WorkspaceRole{
+		ownerID:  models.RoleOwner,
+		adminID:  models.RoleAdmin,
+		memberID: models.RoleMember,
+		viewerID: models.RoleViewer,
+	}
+	for userID, role := range roles {
+		repo.On("GetMemberRole", teamWorkspace, userID).Return(role, nil).Maybe()
+		repo.On("GetMemberRole", otherWorkspace, userID).Return(models.WorkspaceRole(""), nil).Maybe()
+	}
+	repo.On("GetMemberRole", teamWorkspace, outsiderID).Return(models.WorkspaceRole(""), nil).Maybe()
+	repo.On("GetMemberRole", otherWorkspace, outsiderID).Return(models.RoleOwner, nil).Maybe()
+}
+
+func newWorkspaceService() (*services.WorkspaceService, *MockWorkspaceRepo, *MockUserRepo) {
+	repo := new(MockWorkspaceRepo)
+	users := new(MockUserRepo)
+	withTeam(repo)
+	return services.NewWorkspaceService(repo, users, &FakeClock{now: time.Unix(1700000000, 0)}), repo, users
+}
+
+func TestCreateWorkspace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Creator becomes owner", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+
+		repo.On("CreateWorkspace", mock.MatchedBy(func(w *models.Workspace) bool { return w.Name == "Team" }), ownerID).
+			Run(func(args mock.Arguments) {
+				w := args.Get(0).(*models.Workspace)
+				w.ID = teamWorkspace
+				w.Role = models.RoleOwner
+			}).Return(nil)
+
+		workspace, err := service.CreateWorkspace(ownerID, "  Team ")
+		require.NoError(t, err)
+		assert.Equal(t, teamWorkspace, workspace.ID)
+		assert.Equal(t, models.RoleOwner, workspace.Role)
+	})
+
+	t.Run("Blank name", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+
+		_, err := service.CreateWorkspace(ownerID, "  ")
+		assert.ErrorAs(t, err, &baseErr)
+		repo.AssertNotCalled(t, "CreateWorkspace", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorkspaceRoleChecks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		actor   int
+		wantErr error
+	}{
+		{"Owner", ownerID, nil},
+		{"Admin", adminID, nil},
+		{"Member", memberID, services.ErrWorkspaceForbidden},
+		{"Viewer", viewerID, services.ErrWorkspaceForbidden},
+		{"Outsider", outsiderID, services.ErrWorkspaceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			service, repo, _ := newWorkspaceService()
+
+			repo.On("RenameWorkspace", teamWorkspace, "Renamed").Return(nil).Maybe()
+			repo.On("GetWorkspace", teamWorkspace).Return(&models.Workspace{ID: teamWorkspace, Name: "Renamed"}, nil).Maybe()
+
+			_, err := service.RenameWorkspace(teamWorkspace, tt.actor, "Renamed")
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "RenameWorkspace", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDeleteWorkspaceOwnerOnly(t *testing.T) {
+	t.Parallel()
+
+	for _, actor := range []int{adminID, memberID, viewerID} {
+		service, repo, _ := newWorkspaceService()
+		err := service.DeleteWorkspace(teamWorkspace, actor)
+		assert.ErrorIs(t, err, services.ErrWorkspaceForbidden)
+		repo.AssertNotCalled(t, "DeleteWorkspace", mock.Anything)
+	}
+
+	service, repo, _ := newWorkspaceService()
+	repo.On("DeleteWorkspace", teamWorkspace).Return(nil)
+	assert.NoError(t, service.DeleteWorkspace(teamWorkspace, ownerID))
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		actor   int
+		member  int
+		role    models.WorkspaceRole
+		wantErr error
+	}{
+		{"Admin demotes member", adminID, memberID, models.RoleViewer, nil},
+		{"Owner promotes to admin", ownerID, memberID, models.RoleAdmin, nil},
+		{"Admin cannot promote to admin", adminID, memberID, models.RoleAdmin, services.ErrWorkspaceForbidden},
+		{"Admin cannot demote admin", adminID, adminID, models.RoleMember, services.ErrWorkspaceForbidden},
+		{"Owner is immutable", adminID, ownerID, models.RoleMember, services.ErrOwnerImmutable},
+		{"Member cannot manage", memberID, viewerID, models.RoleMember, services.ErrWorkspaceForbidden},
+		{"Not a member", adminID, outsiderID, models.RoleMember, services.ErrMemberNotFound},
+		{"Outsider", outsiderID, memberID, models.RoleViewer, services.ErrWorkspaceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			service, repo, _ := newWorkspaceService()
+			repo.On("UpdateMemberRole", teamWorkspace, tt.member, tt.role).Return(nil).Maybe()
+
+			err := service.UpdateMemberRole(teamWorkspace, tt.actor, tt.member, tt.role)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				repo.AssertCalled(t, "UpdateMemberRole", teamWorkspace, tt.member, tt.role)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOwnershipIsNotGranted(t *testing.T) {
+	t.Parallel()
+	service, repo, _ := newWorkspaceService()
+
+	err := service.UpdateMemberRole(teamWorkspace, ownerID, memberID, models.RoleOwner)
+	assert.ErrorAs(t, err, &baseErr)
+
+	_, err = service.Invite(teamWorkspace, ownerID, "bob", models.RoleOwner)
+	assert.ErrorAs(t, err, &baseErr)
+
+	repo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "CreateInvitation", mock.Anything)
+}
+
+func TestRemoveMember(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		actor   int
+		member  int
+		wantErr error
+	}{
+		{"Viewer leaves", viewerID, viewerID, nil},
+		{"Admin removes member", adminID, memberID, nil},
+		{"Owner removes admin", ownerID, adminID, nil},
+		{"Not a member", adminID, adminID + 100, services.ErrMemberNotFound},
+		{"Member cannot remove others", memberID, viewerID, services.ErrWorkspaceForbidden},
+		{"Owner cannot leave", ownerID, ownerID, services.ErrOwnerImmutable},
+		{"Outsider", outsiderID, viewerID, services.ErrWorkspaceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			service, repo, _ := newWorkspaceService()
+			repo.On("GetMemberRole", teamWorkspace, adminID+100).Return(models.WorkspaceRole(""), nil).Maybe()
+			repo.On("RemoveMember", teamWorkspace, tt.member).Return(nil).Maybe()
+
+			err := service.RemoveMember(teamWorkspace, tt.actor, tt.member)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Admin cannot remove another admin", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockWorkspaceRepo)
+		repo.On("GetMemberRole", teamWorkspace, 7).Return(models.RoleAdmin, nil)
+		repo.On("GetMemberRole", teamWorkspace, 8).Return(models.RoleAdmin, nil)
+		service := services.NewWorkspaceService(repo, new(MockUserRepo), &FakeClock{})
+
+		err := service.RemoveMember(teamWorkspace, 7, 8)
+		assert.ErrorIs(t, err, services.ErrWorkspaceForbidden)
+		repo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+	})
+}
+
+func TestInvite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Admin invites with default role", func(t *testing.T) {
+		t.Parallel()
+		service, repo, users := newWorkspaceService()
+		users.On("GetUserByUsername", "bob").Return(&models.User{ID: 9, Username: "bob"}, nil)
+		repo.On("GetMemberRole", teamWorkspace, 9).Return(models.WorkspaceRole(""), nil)
+		repo.On("GetPendingInvitation", teamWorkspace, 9).Return((*models.WorkspaceInvitation)(nil), nil)
+		repo.On("CreateInvitation", mock.MatchedBy(func(i *models.WorkspaceInvitation) bool {
+			return i.InviteeID == 9 && i.Role == models.RoleMember && *i.InviterID == adminID
+		})).Return(nil)
+
+		invitation, err := service.Invite(teamWorkspace, adminID, "bob", "")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleMember, invitation.Role)
+	})
+
+	t.Run("Only owner invites admins", func(t *testing.T) {
+		t.Parallel()
+		service, repo, users := newWorkspaceService()
+
+		_, err := service.Invite(teamWorkspace, adminID, "bob", models.RoleAdmin)
+		assert.ErrorIs(t, err, services.ErrWorkspaceForbidden)
+		users.AssertNotCalled(t, "GetUserByUsername", mock.Anything)
+		repo.AssertNotCalled(t, "CreateInvitation", mock.Anything)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		t.Parallel()
+		service, _, users := newWorkspaceService()
+		users.On("GetUserByUsername", "ghost").Return((*models.User)(nil), nil)
+
+		_, err := service.Invite(teamWorkspace, ownerID, "ghost", models.RoleViewer)
+		assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	})
+
+	t.Run("Already a member", func(t *testing.T) {
+		t.Parallel()
+		service, repo, users := newWorkspaceService()
+		users.On("GetUserByUsername", "viewer").Return(&models.User{ID: viewerID}, nil)
+
+		_, err := service.Invite(teamWorkspace, ownerID, "viewer", models.RoleMember)
+		assert.ErrorIs(t, err, services.ErrAlreadyMember)
+		repo.AssertNotCalled(t, "CreateInvitation", mock.Anything)
+	})
+
+	t.Run("Pending invitation exists", func(t *testing.T) {
+		t.Parallel()
+		service, repo, users := newWorkspaceService()
+		users.On("GetUserByUsername", "bob").Return(&models.User{ID: 9}, nil)
+		repo.On("GetMemberRole", teamWorkspace, 9).Return(models.WorkspaceRole(""), nil)
+		repo.On("GetPendingInvitation", teamWorkspace, 9).Return(&models.WorkspaceInvitation{ID: 1}, nil)
+
+		_, err := service.Invite(teamWorkspace, ownerID, "bob", models.RoleMember)
+		assert.ErrorIs(t, err, services.ErrInvitationPending)
+		repo.AssertNotCalled(t, "CreateInvitation", mock.Anything)
+	})
+}
+
+func TestRespondInvitation(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	pending := &models.WorkspaceInvitation{ID: 7, WorkspaceID: teamWorkspace, InviteeID: 9, Role: models.RoleViewer, Status: models.InvitationPending}
+
+	t.Run("Invitee accepts", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+		repo.On("GetInvitation", 7).Return(pending, nil)
+		repo.On("AcceptInvitation", pending, now).Return(nil)
+
+		assert.NoError(t, service.AcceptInvitation(7, 9))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Invitee declines", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+		repo.On("GetInvitation", 7).Return(pending, nil)
+		repo.On("DeclineInvitation", 7, now).Return(nil)
+
+		assert.NoError(t, service.DeclineInvitation(7, 9))
+	})
+
+	t.Run("Someone else's invitation", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+		repo.On("GetInvitation", 7).Return(pending, nil)
+
+		assert.ErrorIs(t, service.AcceptInvitation(7, outsiderID), services.ErrInvitationNotFound)
+		assert.ErrorIs(t, service.DeclineInvitation(7, outsiderID), services.ErrInvitationNotFound)
+		repo.AssertNotCalled(t, "AcceptInvitation", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "DeclineInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already answered", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+		answered := *pending
+		answered.Status = models.InvitationDeclined
+		repo.On("GetInvitation", 7).Return(&answered, nil)
+
+		assert.ErrorIs(t, service.AcceptInvitation(7, 9), services.ErrInvitationNotFound)
+	})
+
+	t.Run("Lost race with another response", func(t *testing.T) {
+		t.Parallel()
+		service, repo, _ := newWorkspaceService()
+		repo.On("GetInvitation", 7).Return(pending, nil)
+		repo.On("AcceptInvitation", pending, now).Return(repository.ErrNoRowsUpdated)
+
+		assert.ErrorIs(t, service.AcceptInvitation(7, 9), services.ErrInvitationNotFound)
+	})
+}
+
+// TestCrossTenantIsolation checks that a user never reaches another workspace
+// through any entry point, and that the answer doesn't reveal that the workspace exists
+func TestCrossTenantIsolation(t *testing.T) {
+	t.Parallel()
+
+	workspaceCalls := map[string]func(s *services.WorkspaceService) error{
+		"GetWorkspace": func(s *services.WorkspaceService) error {
+			_, err := s.GetWorkspace(otherWorkspace, memberID)
+			return err
+		},
+		"RenameWorkspace": func(s *services.WorkspaceService) error {
+			_, err := s.RenameWorkspace(otherWorkspace, ownerID, "Mine now")
+			return err
+		},
+		"DeleteWorkspace": func(s *services.WorkspaceService) error {
+			return s.DeleteWorkspace(otherWorkspace, ownerID)
+		},
+		"ListMembers": func(s *services.WorkspaceService) error {
+			_, err := s.ListMembers(otherWorkspace, viewerID)
+			return err
+		},
+		"UpdateMemberRole": func(s *services.WorkspaceService) error {
+			return s.UpdateMemberRole(otherWorkspace, ownerID, outsiderID, models.RoleViewer)
+		},
+		"RemoveMember": func(s *services.WorkspaceService) error {
+			return s.RemoveMember(otherWorkspace, ownerID, outsiderID)
+		},
+		"Invite": func(s *services.WorkspaceService) error {
+			_, err := s.Invite(otherWorkspace, adminID, "friend", models.RoleMember)
+			return err
+		},
+	}
+
+	for name, call := range workspaceCalls {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			service, repo, users := newWorkspaceService()
+
+			err := call(service)
+			assert.ErrorIs(t, err, services.ErrWorkspaceNotFound)
+			for _, method := range []string{"GetWorkspace", "GetMembers", "RenameWorkspace", "DeleteWorkspace", "CreateInvitation"} {
+				for _, c := range repo.Calls {
+					assert.NotEqual(t, method, c.Method)
+				}
+			}
+			users.AssertNotCalled(t, "GetUserByUsername", mock.Anything)
+		})
+	}
+
+	taskCalls := map[string]func(s *services.TaskService) error{
+		"CreateTask": func(s *services.TaskService) error {
+			workspaceID := otherWorkspace
+			return s.CreateTask(&models.Task{UserID: ownerID, WorkspaceID: &workspaceID, Title: "Sneaky", Status: "pending"})
+		},
+		"GetTasks": func(s *services.TaskService) error {
+			workspaceID := otherWorkspace
+			_, err := s.GetTasks(ownerID, models.TaskFilter{WorkspaceID: &workspaceID})
+			return err
+		},
+	}
+
+	for name, call := range taskCalls {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			workspaces := new(MockWorkspaceRepo)
+			withTeam(workspaces)
+			tasks := new(MockTaskRepo)
+
+			err := call(services.NewTaskService(tasks, workspaces, new(MockNotifier)))
+			assert.ErrorIs(t, err, services.ErrWorkspaceNotFound)
+			assert.Empty(t, tasks.Calls)
+		})
+	}
+
+	t.Run("Tasks of other workspaces look missing", func(t *testing.T) {
+		t.Parallel()
+		workspaces := new(MockWorkspaceRepo)
+		tasks := new(MockTaskRepo)
+		tasks.On("GetVisibleTask", 42, ownerID).Return((*models.Task)(nil), nil)
+		service := services.NewTaskService(tasks, workspaces, new(MockNotifier))
+
+		assert.ErrorIs(t, service.DeleteTask(42, ownerID), repository.ErrNoRowsUpdated)
+		assert.ErrorIs(t, service.UpdateTask(map[string]interface{}{"id": 42, "user_id": ownerID, "title": "x"}), repository.ErrNoRowsUpdated)
+		tasks.AssertNotCalled(t, "DeleteTask", mock.Anything, mock.Anything)
+		tasks.AssertNotCalled(t, "UpdateTask", mock.Anything)
+	})
+}
+
+func TestWorkspaceTaskPermissions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		userID  int
+		wantErr error
+	}{
+		{"Owner", ownerID, nil},
+		{"Admin", adminID, nil},
+		{"Member", memberID, nil},
+		{"Viewer", viewerID, services.ErrWorkspaceForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			workspaces := new(MockWorkspaceRepo)
+			withTeam(workspaces)
+			tasks := new(MockTaskRepo)
+			service := services.NewTaskService(tasks, workspaces, new(MockNotifier))
+
+			workspaceID := teamWorkspace
+			shared := &models.Task{ID: 42, UserID: ownerID, WorkspaceID: &workspaceID}
+			tasks.On("GetVisibleTask", 42, tt.userID).Return(shared, nil)
+			tasks.On("DeleteTask", 42, tt.userID).Return(nil).Maybe()
+			tasks.On("CreateTask", mock.Anything).Return(nil).Maybe()
+			tasks.On("GetTasks", tt.userID, models.TaskFilter{WorkspaceID: &workspaceID}).Return([]models.Task{*shared}, nil)
+
+			listed, err := service.GetTasks(tt.userID, models.TaskFilter{WorkspaceID: &workspaceID})
+			require.NoError(t, err, "every role may read")
+			assert.Len(t, listed, 1)
+
+			createErr := service.CreateTask(&models.Task{UserID: tt.userID, WorkspaceID: &workspaceID, Title: "New", Status: "pending"})
+			deleteErr := service.DeleteTask(42, tt.userID)
+			if tt.wantErr == nil {
+				assert.NoError(t, createErr)
+				assert.NoError(t, deleteErr)
+				return
+			}
+			assert.ErrorIs(t, createErr, tt.wantErr)
+			assert.ErrorIs(t, deleteErr, tt.wantErr)
+			tasks.AssertNotCalled(t, "CreateTask", mock.Anything)
+			tasks.AssertNotCalled(t, "DeleteTask", mock.Anything, mock.Anything)
+		})
+	}
+}
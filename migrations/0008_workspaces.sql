@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members(user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    inviter_id INT REFERENCES users(id) ON DELETE SET NULL,
+    invitee_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('admin', 'member', 'viewer')),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS workspace_invitations_pending_idx
+    ON workspace_invitations(workspace_id, invitee_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS workspace_invitations_invitee_id_idx ON workspace_invitations(invitee_id);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS tasks_workspace_id_idx ON tasks(workspace_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS tasks_workspace_id_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE workspace_invitations;
+DROP TABLE workspace_members;
+DROP TABLE workspaces;
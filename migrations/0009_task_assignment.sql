@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assignee_id INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_id_idx ON tasks(assignee_id);
+
+CREATE TABLE IF NOT EXISTS task_watchers (
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_watchers_user_id_idx ON task_watchers(user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    task_id INT REFERENCES tasks(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications(user_id, id);
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE task_watchers;
+DROP INDEX IF EXISTS tasks_assignee_id_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS assignee_id;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS task_comments (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INT REFERENCES task_comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS task_comments_task_id_idx ON task_comments(task_id, created_at);
+CREATE INDEX IF NOT EXISTS task_comments_author_id_idx ON task_comments(author_id);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS comment_id INT REFERENCES task_comments(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN IF EXISTS comment_id;
+DROP TABLE task_comments;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications(user_id, id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS notifications_unread_idx;
+DROP TABLE notification_preferences;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- task_events is a short-lived log of task changes that backs the SSE stream.
+-- Rows outlive the tasks they describe, so there are no foreign keys
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    workspace_id INT,
+    actor_id INT,
+    payload JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS task_events_created_at_idx ON task_events(created_at);
+
+-- +goose Down
+DROP TABLE task_events;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- outbox holds task changes written in the same transaction as the change,
+-- the relay hands them to in-process consumers at least once
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    workspace_id INT,
+    actor_id INT NOT NULL,
+    payload JSONB NOT NULL,
+    previous JSONB,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(id) WHERE processed_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_processed_at_idx ON outbox(processed_at);
+
+-- outbox_consumers records which consumer already handled which message, a
+-- retried message skips the consumers that succeeded before
+CREATE TABLE IF NOT EXISTS outbox_consumers (
+    consumer VARCHAR(64) NOT NULL,
+    message_id BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+    processed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer, message_id)
+);
+
+-- consumers store the message id with what they produce, so a message
+-- handled twice doesn't produce twice
+ALTER TABLE task_events ADD COLUMN IF NOT EXISTS outbox_id BIGINT UNIQUE;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS outbox_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_outbox_idx ON webhook_deliveries(webhook_id, outbox_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS webhook_deliveries_outbox_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS outbox_id;
+ALTER TABLE task_events DROP COLUMN IF EXISTS outbox_id;
+DROP TABLE outbox_consumers;
+DROP TABLE outbox;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+
+-- a reminder fires at remind_at, or before_due_minutes before the due date of
+-- the task, or at snoozed_until once snoozed
+CREATE TABLE IF NOT EXISTS reminders (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remind_at TIMESTAMP,
+    before_due_minutes INT,
+    channels JSONB NOT NULL DEFAULT '["in_app"]',
+    snoozed_until TIMESTAMP,
+    fired_at TIMESTAMP,
+    dismissed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((remind_at IS NULL) <> (before_due_minutes IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS reminders_user_id_idx ON reminders(user_id);
+CREATE INDEX IF NOT EXISTS reminders_pending_idx ON reminders(task_id) WHERE fired_at IS NULL AND dismissed_at IS NULL;
+
+-- +goose Down
+DROP TABLE reminders;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_at;
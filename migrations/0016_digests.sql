@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP;
+
+UPDATE tasks SET completed_at = created_at WHERE status = 'done' AND completed_at IS NULL;
+
+-- a user receives the digest once a day after send_time in its time zone,
+-- the profile time zone when timezone is NULL
+CREATE TABLE IF NOT EXISTS digest_settings (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    send_time VARCHAR(5) NOT NULL DEFAULT '08:00',
+    timezone VARCHAR(64),
+    last_sent_on DATE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS digest_settings_enabled_idx ON digest_settings(user_id) WHERE enabled;
+
+-- +goose Down
+DROP TABLE digest_settings;
+ALTER TABLE tasks DROP COLUMN IF EXISTS assigned_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS completed_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- external_id identifies a task in the system it was imported from, an import
+-- skips the ids its user already imported
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_user_external_id_idx ON tasks(user_id, external_id) WHERE external_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS tasks_user_external_id_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- priority is empty, low, medium or high and recurrence an RFC 5545 RRULE value,
+-- both are kept as given for calendar clients and not expanded by the app
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS priority;
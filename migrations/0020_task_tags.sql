@@ -0,0 +1,6 @@
+-- +goose Up
+-- tags are free-form labels of a task, todo.txt contexts keep their @ prefix
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN IF EXISTS tags;
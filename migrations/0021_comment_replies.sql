@@ -0,0 +1,10 @@
+-- +goose Up
+-- deleting a comment keeps the replies to it, they lose their parent
+ALTER TABLE task_comments DROP CONSTRAINT IF EXISTS task_comments_parent_id_fkey;
+ALTER TABLE task_comments ADD CONSTRAINT task_comments_parent_id_fkey
+    FOREIGN KEY (parent_id) REFERENCES task_comments(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE task_comments DROP CONSTRAINT IF EXISTS task_comments_parent_id_fkey;
+ALTER TABLE task_comments ADD CONSTRAINT task_comments_parent_id_fkey
+    FOREIGN KEY (parent_id) REFERENCES task_comments(id) ON DELETE CASCADE;
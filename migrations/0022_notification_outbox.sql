@@ -0,0 +1,8 @@
+-- +goose Up
+-- a relayed outbox message notifies each user once, even when it is retried
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS outbox_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_outbox_idx ON notifications(user_id, outbox_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS notifications_outbox_idx;
+ALTER TABLE notifications DROP COLUMN IF EXISTS outbox_id;